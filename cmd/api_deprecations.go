@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+)
+
+// scanReleaseAPIs checks the manifests of the installed releases of the configured charts
+func scanReleaseAPIs(deployCfg *config.DeploymentConfig, target deprecations.Version) ([]deprecations.Finding, error) {
+	helmMgr, err := helm.NewManager(&deployCfg.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []deprecations.Finding
+	for _, chart := range deployCfg.Helm.Charts {
+		namespace := chart.Namespace
+		if namespace == "" {
+			namespace = deployCfg.Kubernetes.Namespace
+		}
+		manifest, err := helmMgr.GetManifest(chart.Name, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the manifest of release %s: %w", chart.Name, err)
+		}
+		if manifest == nil {
+			continue
+		}
+		chartFindings, err := deprecations.ScanManifest(manifest, chart.Name, target)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, chartFindings...)
+	}
+	return findings, nil
+}
+
+// scanLiveAPIs checks the objects applied to the cluster with kubectl, which no release manifest
+// records, by the API version of their last-applied configuration
+func scanLiveAPIs(k8sMgr *k8s.Manager, target deprecations.Version) ([]deprecations.Finding, error) {
+	var findings []deprecations.Finding
+	for _, kind := range deprecations.Kinds() {
+		list, err := k8sMgr.List(kind, "", "")
+		if err != nil {
+			// Kinds whose every version is gone, like PodSecurityPolicy, no longer resolve
+			if k8s.IsNotFound(err) || strings.Contains(err.Error(), "doesn't have a resource type") {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s objects: %w", kind, err)
+		}
+		kindFindings, err := deprecations.ScanLiveObjects(list, target)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, kindFindings...)
+	}
+	return findings, nil
+}
+
+// printDeprecationFindings shows each object using a deprecated or removed API and its replacement
+func printDeprecationFindings(findings []deprecations.Finding) error {
+	tableData := pterm.TableData{{"Chart", "Object", "API Version", "Status", "Replacement"}}
+	for _, finding := range findings {
+		status := "deprecated in " + finding.DeprecatedIn
+		if finding.Removed {
+			status = "removed in " + finding.RemovedIn
+		}
+		replacement := finding.Replacement
+		if replacement == "" {
+			replacement = "none"
+		}
+		tableData = append(tableData, []string{finding.Source, findingObject(finding), finding.APIVersion, status, replacement})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// reportAPIFindings prints the findings and fails when any API is removed in the target
+func reportAPIFindings(findings []deprecations.Finding, target deprecations.Version) error {
+	if len(findings) == 0 {
+		pterm.Success.Printf("No objects use APIs deprecated in %s\n", target)
+		return nil
+	}
+	if err := printDeprecationFindings(findings); err != nil {
+		return err
+	}
+	if err := deprecationError(findings, target); err != nil {
+		return err
+	}
+	pterm.Warning.Printf("%d object(s) use APIs deprecated in %s, update them before they are removed\n", len(findings), target)
+	return nil
+}
+
+// deprecationError names the charts to update before the cluster can run the target, nil if none
+func deprecationError(findings []deprecations.Finding, target deprecations.Version) error {
+	blocking := deprecations.BlockingSources(findings)
+	if len(blocking) == 0 {
+		return nil
+	}
+
+	var charts, parts []string
+	applied := false
+	for _, source := range blocking {
+		if source == deprecations.LiveSource {
+			applied = true
+			continue
+		}
+		charts = append(charts, source)
+	}
+	if len(charts) > 0 {
+		parts = append(parts, "charts "+strings.Join(charts, ", "))
+	}
+	if applied {
+		parts = append(parts, "objects applied with kubectl")
+	}
+	return fmt.Errorf("%d object(s) use APIs removed in %s; update %s first",
+		len(deprecations.Removed(findings)), target, strings.Join(parts, " and "))
+}
+
+func findingObject(finding deprecations.Finding) string {
+	object := finding.Kind + "/" + finding.Name
+	if finding.Namespace != "" {
+		object = finding.Namespace + "/" + object
+	}
+	return object
+}
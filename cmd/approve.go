@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	approveConfigFile string
+	approveStateFile  string
+	approveRun        string
+	approveGate       string
+	approveComment    string
+)
+
+// approveCmd approves a gate of a paused installation without resuming it
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve a gate an installation is paused at",
+	Long: `Approve an approval gate of a paused installation, for example from a release
+manager's machine or a chat-ops job, while the pipeline that paused resumes it later
+with 'install --resume'.
+
+The run ID is printed when the installation pauses, posted to the gate's webhook and
+listed by 'report list'; the approval only applies to that run. The approver is the user
+of the kubeconfig as the cluster knows them, who needs RBAC permission to approve the gate:
+the approve verb on approvalgates.e2e-k8s-installer.io named after the gate. Gates that
+name approvers accept approvals from those users and members of their groups only. The
+approver, time and comment are recorded in the installation state, the audit log and
+the installation report.
+
+Examples:
+  e2e-k8s-installer approve --run install-20250301-101500-ab12 --gate pre-prod-migrate
+  e2e-k8s-installer approve --run install-20250301-101500-ab12 --gate pre-prod-migrate --comment "Backup verified"`,
+	Args: cobra.NoArgs,
+	RunE: runApprove,
+}
+
+func init() {
+	approveCmd.Flags().StringVarP(&approveConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	approveCmd.Flags().StringVar(&approveStateFile, "state-file", "", "Path to installation state file")
+	approveCmd.Flags().StringVar(&approveRun, "run", "", "ID of the paused installation run (required)")
+	approveCmd.Flags().StringVar(&approveGate, "gate", "", "Approval gate to approve (required)")
+	approveCmd.Flags().StringVar(&approveComment, "comment", "", "Comment recorded with the approval")
+	approveCmd.MarkFlagRequired("run")
+	approveCmd.MarkFlagRequired("gate")
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, approveConfigFile))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(cfg.Installer.Gates, func(gate config.ApprovalGate) bool { return gate.Name == approveGate })
+	if i < 0 {
+		return fmt.Errorf("%s is not an approval gate in the configuration", approveGate)
+	}
+	gate := cfg.Installer.Gates[i]
+	approver, err := authorizeApprover(&cfg.Kubernetes, gate)
+	if err != nil {
+		return err
+	}
+
+	dir := selectedWorkspace(cfg.Installer.Workspace)
+	stateFile := approveStateFile
+	if stateFile == "" {
+		stateFile = filepath.Join(dir, "install-state.json")
+	}
+
+	// A running installation holds the lock and passes its gates itself
+	lock, err := workspace.Acquire(dir, "approve")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := audit.InitGlobalAuditLog(dir, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no installation state in %s, nothing is waiting for approval", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read installation state: %w", err)
+	}
+	var state config.InstallState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse installation state %s: %w", stateFile, err)
+	}
+
+	switch {
+	case state.Status != installStatusPaused:
+		return fmt.Errorf("the last installation in %s is %s, not paused at an approval gate", dir, state.Status)
+	case state.RunID != approveRun:
+		return fmt.Errorf("run %s is not the paused installation, %s is", approveRun, state.RunID)
+	case slices.ContainsFunc(state.Approvals, func(approval config.GateApproval) bool { return approval.Gate == gate.Name }):
+		pterm.Info.Printf("Gate %s of run %s is already approved\n", gate.Name, approveRun)
+		return nil
+	}
+
+	recordApproval(&state, config.GateApproval{
+		Gate:       gate.Name,
+		ApprovedBy: approver,
+		Method:     approvalByCommand,
+		Comment:    approveComment,
+		RunID:      approveRun,
+	})
+	if state.PausedAt == gate.Name {
+		state.PausedAt = ""
+	}
+
+	if err := workspace.UpdateState(stateFile, func(sections map[string]json.RawMessage) error {
+		return workspace.ReplaceState(sections, state)
+	}); err != nil {
+		return fmt.Errorf("failed to write installation state: %w", err)
+	}
+
+	pterm.Success.Printf("Gate %s of run %s approved by %s\n", gate.Name, approveRun, approver)
+	pterm.Info.Println("Resume the installation with: e2e-k8s-installer install --resume")
+	return nil
+}
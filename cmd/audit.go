@@ -0,0 +1,568 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/compliance"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/healthcheck"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	pkglogger "github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/snapshot"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditConfigFile string
+	auditOutput     string
+	auditMinScore   float64
+)
+
+// auditReportName is the report each audit leaves in the reports directory
+const auditReportName = "audit-report.json"
+
+// auditDriftExamples is how many changes of a drifted release the deviation names
+const auditDriftExamples = 3
+
+// auditCmd verifies a deployed environment against its configuration without changing it
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Verify an environment against its configuration without changing anything",
+	Long: `Audit the environment the configuration deploys to and score how closely it
+matches. Nothing is installed, upgraded or written to the cluster; the command only
+reads, so it can run on a schedule against every environment of a fleet.
+
+Checks by category:
+  releases    every configured chart is installed, deployed and at the configured version
+  drift       the installed manifests match the charts rendered from the configuration,
+              and the pods run the images of their release
+  images      running images come from the client registry with the digests package-pull synced
+  migrations  the last db-migrate run succeeded and ran the currently synced scripts
+  security    no privileged containers or host namespaces, no deprecated or removed APIs
+  health      every workload has its desired replicas ready, chart health URLs answer
+
+Each check is weighted by its severity, from critical to low, and the compliance score
+is the weighted share of passed checks. Checks that cannot be made, like migrations
+when the database is disabled, are listed as skipped and not scored. The report is
+written to reports/audit-report.json; with --min-score the command exits with code ` + fmt.Sprint(exitcode.Noncompliant) + `
+when the score is lower.
+
+Examples:
+  e2e-k8s-installer audit
+  e2e-k8s-installer audit --workspace prod --min-score 90
+  e2e-k8s-installer audit --output json`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "table", "Output format (table, json)")
+	auditCmd.Flags().Float64Var(&auditMinScore, "min-score", 100, "Exit with an error when the compliance score is below this, from 0 to 100")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	if auditMinScore < 0 || auditMinScore > 100 {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid --min-score %g, expected 0 to 100", auditMinScore))
+	}
+	configFile := workspaceConfigFile(cmd, auditConfigFile)
+	installerCfg, err := loadInstallConfig(configFile)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(installerCfg); err != nil {
+		return err
+	}
+	deployCfg, err := loadDeployConfig(configFile)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+
+	var spinner *pterm.SpinnerPrinter
+	if auditOutput != "json" {
+		spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Auditing %s against %s...", valueOr(deployCfg.Kubernetes.Context, "the current context"), configFile))
+	}
+	report, err := auditEnvironment(installerCfg, deployCfg)
+	if err != nil {
+		if spinner != nil {
+			spinner.Fail("Audit failed")
+		}
+		return err
+	}
+	if spinner != nil {
+		spinner.Success(fmt.Sprintf("%d checks made, %d deviations", len(report.Checks), len(report.Deviations())))
+	}
+
+	if err := writeReport(filepath.Join(reportsDir(), auditReportName), report); err != nil {
+		return err
+	}
+	if auditOutput == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printAudit(report)
+	}
+
+	if report.Score < auditMinScore {
+		return exitcode.Wrap(exitcode.Noncompliant, fmt.Errorf("compliance score %.1f is below %g, %d deviations", report.Score, auditMinScore, len(report.Deviations())))
+	}
+	return nil
+}
+
+// environmentAudit holds what the checks of an audit share
+type environmentAudit struct {
+	report    *compliance.Report
+	installer *config.InstallerConfig
+	manager   *DeploymentManager
+	k8sMgr    *k8s.Manager
+	helmMgr   *helm.Manager
+	target    *deprecations.Version // server version, nil when it could not be parsed
+
+	digests  map[string]string // synced image digests by destination
+	registry string            // client registry host, "" when none is configured
+}
+
+// auditEnvironment runs every check of the configured charts, then of the database
+func auditEnvironment(installerCfg *config.InstallerConfig, deployCfg *config.DeploymentConfig) (*compliance.Report, error) {
+	logger := zerolog.New(pkglogger.Journal()).With().Timestamp().Str("component", "audit").Logger()
+	manager, err := NewDeploymentManager(deployCfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize deployment manager: %w", err)
+	}
+	manager.installer = installerCfg
+
+	// Charts are rendered as deploy would: with the label policy, the parameters of the
+	// installation and the infrastructure outputs
+	if err := labels.InitGlobalPolicy(deployCfg.Labels, manager.GetRunID()); err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid label policy: %w", err))
+	}
+	if err := params.InitGlobalStore(filepath.Join(installerCfg.Installer.Workspace, workspace.StateFileName), true); err != nil {
+		return nil, fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+	deployOutputsFile = filepath.Join(installerCfg.Installer.Workspace, "reports", "infra-report-latest.json")
+	if err := manager.RenderValues(); err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to render chart values: %w", err))
+	}
+
+	k8sMgr, err := k8s.NewManager(&deployCfg.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+	serverVersion, err := k8sMgr.ServerVersion()
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Preflight, fmt.Errorf("cluster not reachable: %w", err))
+	}
+	helmMgr, err := helm.NewManager(&deployCfg.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &environmentAudit{
+		report:    compliance.NewReport(deployCfg.Labels.Environment, deployCfg.Kubernetes.Context, installerCfg.Installer.Workspace),
+		installer: installerCfg,
+		manager:   manager,
+		k8sMgr:    k8sMgr,
+		helmMgr:   helmMgr,
+		registry:  registryHost(valueOr(installerCfg.Artifacts.Images.Client.Registry, installerCfg.Artifacts.Images.Client.URL)),
+	}
+	audit.report.KubernetesVersion = serverVersion
+	if target, err := deprecations.ParseVersion(serverVersion); err == nil {
+		audit.target = &target
+	}
+	if audit.digests, err = syncedDigests(installerCfg.Installer.Workspace); err != nil {
+		return nil, err
+	}
+
+	for _, chart := range manager.configuredCharts() {
+		if err := audit.chart(chart); err != nil {
+			return nil, fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+	}
+	audit.migrations()
+
+	audit.report.Finish()
+	return audit.report, nil
+}
+
+// chart audits the release of one chart
+func (a *environmentAudit) chart(chart config.DeployChart) error {
+	report := a.report
+	release := chart.Namespace + "/" + chart.Name
+
+	live, err := a.helmMgr.GetManifest(chart.Name, chart.Namespace)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		report.Fail(compliance.CategoryReleases, htmlreport.SeverityCritical, release+" installed", "installed", "not installed",
+			"release %s is not installed", release)
+		for _, category := range []string{compliance.CategoryDrift, compliance.CategoryHealth} {
+			report.Skip(category, release, "release is not installed")
+		}
+		return nil
+	}
+	report.Pass(compliance.CategoryReleases, htmlreport.SeverityCritical, release+" installed", "installed", "installed")
+
+	status, err := a.helmMgr.Status(chart.Name, chart.Namespace)
+	if err != nil {
+		return err
+	}
+	if status.Status == "deployed" {
+		report.Pass(compliance.CategoryReleases, htmlreport.SeverityHigh, release+" status", "deployed", status.Status)
+	} else {
+		report.Fail(compliance.CategoryReleases, htmlreport.SeverityHigh, release+" status", "deployed", status.Status,
+			"revision %d of %s is %s", status.Revision, release, status.Status)
+	}
+	if expected, _ := localChartVersion(chart.Path); expected == "unknown" {
+		report.Skip(compliance.CategoryReleases, release+" version", "no local chart at %s to read the version from", chart.Path)
+	} else if expected == status.ChartVersion {
+		report.Pass(compliance.CategoryReleases, htmlreport.SeverityHigh, release+" version", expected, status.ChartVersion)
+	} else {
+		report.Fail(compliance.CategoryReleases, htmlreport.SeverityHigh, release+" version", expected, status.ChartVersion,
+			"%s runs chart %s %s, the configuration deploys %s", release, status.Chart, status.ChartVersion, expected)
+	}
+
+	if err := a.drift(chart, release, live); err != nil {
+		return err
+	}
+	a.deprecatedAPIs(chart, release, live)
+
+	workloads, err := a.k8sMgr.ReleaseWorkloads(chart.Namespace, chart.Name)
+	if err != nil {
+		return err
+	}
+	a.workloads(release, status.Images, workloads)
+	a.healthURL(chart, release)
+	return nil
+}
+
+// drift compares the installed manifest of a release with the chart rendered from the configuration
+func (a *environmentAudit) drift(chart config.DeployChart, release string, live []byte) error {
+	if !remoteChart(chart.Path) && !isChart(chart.Path) {
+		a.report.Skip(compliance.CategoryDrift, release+" manifest", "no chart at %s to render, sync the charts with package-pull first", chart.Path)
+		return nil
+	}
+	rendered, err := a.manager.renderChart(a.helmMgr, chart)
+	if err != nil {
+		return err
+	}
+	changes, err := snapshot.DiffManifests(live, rendered)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		a.report.Pass(compliance.CategoryDrift, htmlreport.SeverityHigh, release+" manifest", "as configured", "as configured")
+		return nil
+	}
+
+	examples := make([]string, 0, auditDriftExamples)
+	for _, change := range changes {
+		if len(examples) == auditDriftExamples {
+			examples = append(examples, "...")
+			break
+		}
+		examples = append(examples, strings.TrimSuffix(fmt.Sprintf("%s %s %s", change.Type, change.Object, change.Field), " "))
+	}
+	a.report.Fail(compliance.CategoryDrift, htmlreport.SeverityHigh, release+" manifest", "as configured", fmt.Sprintf("%d changes", len(changes)),
+		"%s differs from the configuration: %s; 'deploy --diff' lists every change", release, strings.Join(examples, ", "))
+	return nil
+}
+
+// deprecatedAPIs scans the installed manifest of a release for APIs the cluster deprecated or removed
+func (a *environmentAudit) deprecatedAPIs(chart config.DeployChart, release string, live []byte) {
+	name := release + " APIs"
+	if a.target == nil {
+		a.report.Skip(compliance.CategorySecurity, name, "server version not known")
+		return
+	}
+	findings, err := deprecations.ScanManifest(live, chart.Name, *a.target)
+	if err != nil {
+		a.report.Skip(compliance.CategorySecurity, name, "%v", err)
+		return
+	}
+	if len(findings) == 0 {
+		a.report.Pass(compliance.CategorySecurity, htmlreport.SeverityMedium, name, "current APIs", "current APIs")
+		return
+	}
+	for _, finding := range findings {
+		severity, state := htmlreport.SeverityMedium, "deprecated in "+finding.DeprecatedIn
+		if finding.Removed {
+			severity, state = htmlreport.SeverityHigh, "removed in "+finding.RemovedIn
+		}
+		a.report.Fail(compliance.CategorySecurity, severity, name, valueOr(finding.Replacement, "a current API"), finding.APIVersion,
+			"%s %s uses %s, %s", finding.Kind, finding.Name, finding.APIVersion, state)
+	}
+}
+
+// workloads checks the readiness, images and security settings of the workloads of a release
+func (a *environmentAudit) workloads(release string, releaseImages []string, workloads []k8s.ReleaseWorkload) {
+	report := a.report
+	if len(workloads) == 0 {
+		report.Skip(compliance.CategoryHealth, release+" workloads", "release runs no deployments, statefulsets or daemonsets")
+		return
+	}
+	inRelease := make(map[string]bool, len(releaseImages))
+	for _, image := range releaseImages {
+		inRelease[image] = true
+	}
+
+	checked := make(map[string]bool)
+	for _, workload := range workloads {
+		name := fmt.Sprintf("%s/%s/%s", workload.Namespace, strings.ToLower(workload.Kind), workload.Name)
+		expected, actual := fmt.Sprintf("%d ready", workload.Desired), fmt.Sprintf("%d ready", workload.Ready)
+		if workload.Ready >= workload.Desired {
+			report.Pass(compliance.CategoryHealth, htmlreport.SeverityHigh, name+" ready", expected, actual)
+		} else {
+			report.Fail(compliance.CategoryHealth, htmlreport.SeverityHigh, name+" ready", expected, actual,
+				"%s has %d of %d replicas ready", name, workload.Ready, workload.Desired)
+		}
+
+		var privileged, hostAccess, foreign []string
+		for _, container := range workload.Containers {
+			if container.Privileged {
+				privileged = append(privileged, container.Name)
+			}
+			if len(container.HostAccess) > 0 {
+				hostAccess = container.HostAccess
+			}
+			if len(inRelease) > 0 && !inRelease[container.Image] && !slices.Contains(foreign, container.Image) {
+				foreign = append(foreign, container.Image)
+			}
+			key := container.Image + "@" + container.Digest
+			if !checked[key] {
+				checked[key] = true
+				a.image(container)
+			}
+		}
+		if len(privileged) == 0 {
+			report.Pass(compliance.CategorySecurity, htmlreport.SeverityHigh, name+" unprivileged", "unprivileged", "unprivileged")
+		} else {
+			report.Fail(compliance.CategorySecurity, htmlreport.SeverityHigh, name+" unprivileged", "unprivileged", "privileged",
+				"%s runs privileged containers: %s", name, strings.Join(privileged, ", "))
+		}
+		if len(hostAccess) == 0 {
+			report.Pass(compliance.CategorySecurity, htmlreport.SeverityHigh, name+" host namespaces", "none", "none")
+		} else {
+			report.Fail(compliance.CategorySecurity, htmlreport.SeverityHigh, name+" host namespaces", "none", strings.Join(hostAccess, ", "),
+				"%s shares the host's %s namespaces", name, strings.Join(hostAccess, ", "))
+		}
+		if len(inRelease) > 0 {
+			if len(foreign) == 0 {
+				report.Pass(compliance.CategoryDrift, htmlreport.SeverityMedium, name+" images", "release images", "release images")
+			} else {
+				report.Fail(compliance.CategoryDrift, htmlreport.SeverityMedium, name+" images", "release images", strings.Join(foreign, ", "),
+					"%s runs images its release does not install, it was changed outside Helm", name)
+			}
+		}
+	}
+}
+
+// image checks a running image against the registry and digest package-pull synced it to
+func (a *environmentAudit) image(container k8s.ContainerState) {
+	image, _, _ := strings.Cut(container.Image, "@")
+	if a.registry != "" {
+		if strings.HasPrefix(image, a.registry+"/") {
+			a.report.Pass(compliance.CategoryImages, htmlreport.SeverityMedium, image+" registry", a.registry, a.registry)
+		} else {
+			a.report.Fail(compliance.CategoryImages, htmlreport.SeverityMedium, image+" registry", a.registry, strings.SplitN(image, "/", 2)[0],
+				"%s is not pulled from the client registry %s", image, a.registry)
+		}
+	}
+
+	expected, synced := a.digests[image]
+	switch {
+	case !synced:
+		a.report.Skip(compliance.CategoryImages, image+" digest", "not synced by package-pull")
+	case container.Digest == "":
+		a.report.Skip(compliance.CategoryImages, image+" digest", "container %s of pod %s has not started", container.Name, container.Pod)
+	case container.Digest == expected:
+		a.report.Pass(compliance.CategoryImages, htmlreport.SeverityCritical, image+" digest", expected, container.Digest)
+	default:
+		a.report.Fail(compliance.CategoryImages, htmlreport.SeverityCritical, image+" digest", expected, container.Digest,
+			"pod %s runs %s with digest %s, package-pull synced %s", container.Pod, image, container.Digest, expected)
+	}
+}
+
+// healthURL probes the health check URL of a chart, retrying once rather than as often as
+// deploy waits for it
+func (a *environmentAudit) healthURL(chart config.DeployChart, release string) {
+	if chart.HealthCheck.URL == "" {
+		return
+	}
+	name := release + " health check"
+	if simulateFlag {
+		a.report.Skip(compliance.CategoryHealth, name, "health URLs are not probed in a simulated run")
+		return
+	}
+	check := chart.HealthCheck
+	prober, err := healthcheck.NewProber(config.HealthCheckConfig{Timeout: check.Timeout, Interval: "2s", Retries: 1})
+	if err != nil {
+		a.report.Skip(compliance.CategoryHealth, name, "%v", err)
+		return
+	}
+	result := prober.HTTP(chart.Name, check.URL, check)
+	if result.Passed {
+		a.report.Pass(compliance.CategoryHealth, htmlreport.SeverityHigh, name, "healthy", valueOr(result.Detail, "healthy"))
+	} else {
+		a.report.Fail(compliance.CategoryHealth, htmlreport.SeverityHigh, name, "healthy", "unhealthy", "%s: %s", check.URL, result.Error)
+	}
+}
+
+// migrationReportData is the part of the db-migrate report the audit reads
+type migrationReportData struct {
+	Timestamp         time.Time `json:"timestamp"`
+	MigrationTool     string    `json:"migration_tool"`
+	MigrationsApplied int       `json:"migrations_applied"`
+	DryRun            bool      `json:"dry_run"`
+	Status            string    `json:"status"`
+}
+
+// migrations checks the last db-migrate run applied the database scripts currently synced
+func (a *environmentAudit) migrations() {
+	report := a.report
+	if !a.installer.Database.Enabled {
+		report.Skip(compliance.CategoryMigrations, "database migrations", "database is not enabled")
+		return
+	}
+	var migration migrationReportData
+	if !readLatestReport([]string{filepath.Join(a.installer.Installer.Workspace, "reports"), reportsDir()}, "migration-report.json", &migration) {
+		report.Fail(compliance.CategoryMigrations, htmlreport.SeverityCritical, "database migrations", "applied", "never run",
+			"no db-migrate report found, the database schema was not migrated by the installer")
+		return
+	}
+	if migration.Status != "success" || migration.DryRun {
+		actual := migration.Status
+		if migration.DryRun {
+			actual = "dry run"
+		}
+		report.Fail(compliance.CategoryMigrations, htmlreport.SeverityCritical, "database migrations", "applied", actual,
+			"the last db-migrate run on %s did not apply the migrations", migration.Timestamp.Format(time.RFC3339))
+		return
+	}
+	report.Pass(compliance.CategoryMigrations, htmlreport.SeverityCritical, "database migrations", "applied",
+		fmt.Sprintf("%d applied with %s", migration.MigrationsApplied, valueOr(migration.MigrationTool, "scripts")))
+
+	lock, err := artifacts.LoadLockfile(filepath.Join(a.installer.Installer.Workspace, "artifacts", artifacts.LockfileName))
+	if err != nil {
+		report.Skip(compliance.CategoryMigrations, "database scripts", "%v", err)
+		return
+	}
+	scripts, synced := lock.Repos["db-scripts"]
+	if !synced {
+		report.Skip(compliance.CategoryMigrations, "database scripts", "no synced database scripts recorded")
+		return
+	}
+	if migration.Timestamp.Before(scripts.SyncedAt) {
+		report.Fail(compliance.CategoryMigrations, htmlreport.SeverityHigh, "database scripts", shortCommit(scripts.Commit), "older scripts",
+			"database scripts were synced at %s, after the last migration on %s; run db-migrate", scripts.SyncedAt.Format(time.RFC3339), migration.Timestamp.Format(time.RFC3339))
+		return
+	}
+	report.Pass(compliance.CategoryMigrations, htmlreport.SeverityHigh, "database scripts", shortCommit(scripts.Commit), shortCommit(scripts.Commit))
+}
+
+// syncedDigests returns the digests images were synced with by destination: from the
+// lockfile, then from the last image sync report
+func syncedDigests(workspaceDir string) (map[string]string, error) {
+	lock, err := artifacts.LoadLockfile(filepath.Join(workspaceDir, "artifacts", artifacts.LockfileName))
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]string)
+	for _, image := range lock.Images {
+		if image.Destination != "" && image.Digest != "" {
+			digests[image.Destination] = image.Digest
+		}
+	}
+	var images imageSyncReportData
+	readLatestReport([]string{filepath.Join(workspaceDir, "reports"), reportsDir()}, "image-sync-report-latest.json", &images)
+	for _, image := range images.Images {
+		if image.Destination != "" && image.DestinationDigest != "" {
+			digests[image.Destination] = image.DestinationDigest
+		}
+	}
+	return digests, nil
+}
+
+// registryHost strips the scheme and path separators from a registry URL
+func registryHost(registry string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		registry = strings.TrimPrefix(registry, scheme)
+	}
+	return strings.TrimSuffix(registry, "/")
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return valueOr(commit, "synced")
+}
+
+func printAudit(report *compliance.Report) {
+	if deviations := report.Deviations(); len(deviations) > 0 {
+		tableData := pterm.TableData{{"Severity", "Category", "Check", "Expected", "Actual", "Deviation"}}
+		for _, check := range deviations {
+			severity := check.Severity
+			switch severity {
+			case htmlreport.SeverityCritical, htmlreport.SeverityHigh:
+				severity = pterm.Red(severity)
+			case htmlreport.SeverityMedium:
+				severity = pterm.Yellow(severity)
+			}
+			tableData = append(tableData, []string{severity, check.Category, check.Name, check.Expected, check.Actual, check.Message})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		pterm.Println()
+	}
+
+	tableData := pterm.TableData{{"Category", "Passed", "Failed", "Skipped", "Score"}}
+	for _, category := range compliance.Categories() {
+		score, ok := report.Categories[category]
+		if !ok {
+			continue
+		}
+		tableData = append(tableData, []string{category, fmt.Sprint(score.Passed), fmt.Sprint(score.Failed), fmt.Sprint(score.Skipped), fmt.Sprintf("%.1f", score.Score)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if verbose {
+		var skipped []string
+		for _, check := range report.Checks {
+			if check.Status == compliance.StatusSkip {
+				skipped = append(skipped, fmt.Sprintf("%s %s: %s", check.Category, check.Name, check.Message))
+			}
+		}
+		sort.Strings(skipped)
+		for _, line := range skipped {
+			pterm.Info.Println("Skipped " + line)
+		}
+	}
+
+	message := fmt.Sprintf("Compliance score %.1f, run %s", report.Score, history.RunID())
+	if report.Score == 100 {
+		pterm.Success.Println(message)
+	} else {
+		pterm.Warning.Println(message)
+	}
+	pterm.Info.Printf("Report written to %s\n", filepath.Join(reportsDir(), auditReportName))
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+)
+
+// applyBranding white-labels the output with the branding in a configuration file. It runs
+// before the configuration is loaded, so a missing or invalid file keeps the default
+// branding and is reported by the load itself.
+func applyBranding(configFile string) {
+	if configFile == "" {
+		return
+	}
+	branding, err := config.LoadBranding(configFile)
+	if err != nil {
+		return
+	}
+	logo := branding.Logo
+	if branding.LogoFile != "" {
+		data, err := os.ReadFile(branding.LogoFile)
+		if err != nil {
+			logger.Warn("Ignoring the configured logo").Err(err).Send()
+		} else {
+			logo = string(data)
+		}
+	}
+	progress.SetBranding(progress.Branding{
+		ProductName: branding.ProductName,
+		Edition:     branding.Edition,
+		Tagline:     branding.Tagline,
+		Logo:        logo,
+		SupportURL:  branding.SupportURL,
+		Version:     branding.Version,
+	})
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/cassette"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/pterm/pterm"
+)
+
+var (
+	recordFlag string
+	replayFlag string
+
+	recorder *cassette.Recorder
+	player   *cassette.Player
+)
+
+// startCassette records the external calls of the run into the --record cassette, or answers
+// them from the --replay cassette
+func startCassette() error {
+	switch {
+	case recordFlag != "" && replayFlag != "":
+		return fmt.Errorf("--record and --replay cannot be combined")
+	case (recordFlag != "" || replayFlag != "") && simulateFlag:
+		return fmt.Errorf("--simulate cannot be combined with --record or --replay")
+	case recordFlag != "":
+		recorder = cassette.Record(recordFlag, history.RunID())
+		pterm.Info.Printf("Recording external calls into %s\n", recordFlag)
+	case replayFlag != "":
+		p, err := cassette.Replay(replayFlag)
+		if err != nil {
+			return err
+		}
+		player = p
+		pterm.Info.Printf("Replaying external calls from %s: no tool or service is contacted\n", replayFlag)
+	}
+	return nil
+}
+
+// stopCassette saves the cassette being recorded, or ends the replay
+func stopCassette() {
+	if recorder != nil {
+		if err := recorder.Stop(); err != nil {
+			logger.Warn("Failed to save the cassette").Err(err).Send()
+		} else {
+			pterm.Info.Printf("Cassette saved: %s (replay with --replay %s)\n", recordFlag, recordFlag)
+		}
+		recorder = nil
+	}
+	if player != nil {
+		player.Stop()
+		player = nil
+	}
+}
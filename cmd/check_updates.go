@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkUpdatesConfigFile string
+	checkUpdatesFailOn     string
+	checkUpdatesOutput     string
+)
+
+// checkUpdatesCmd compares the configured artifact versions with the vendor sources
+var checkUpdatesCmd = &cobra.Command{
+	Use:   "check-updates",
+	Short: "List newer versions of the configured images, charts and Terraform modules",
+	Long: `Compare the versions pinned in the configuration with the newest ones the vendor
+sources offer, without pulling anything.
+
+Image tags are compared with the tags of the vendor registry repositories, and the
+tags the Helm chart and Terraform module repositories are synced at with their
+remote tags. Chart versions are read from Chart.yaml at the newest chart repository
+tag, or at the configured branch. Only semantic versions are compared; pre-releases
+are skipped unless the pinned version is one.
+
+Each update is rated by the first version component that changes: patch, minor or
+major. With --fail-on the command exits with code ` + fmt.Sprint(exitcode.Updates) + ` when an update of that
+severity or above is available, so scheduled jobs can alert on it. Sources that cannot
+be queried are reported but do not fail the check.
+
+Examples:
+  e2e-k8s-installer check-updates
+  e2e-k8s-installer check-updates --fail-on minor
+  e2e-k8s-installer check-updates --output json`,
+	Args: cobra.NoArgs,
+	RunE: runCheckUpdates,
+}
+
+func init() {
+	checkUpdatesCmd.Flags().StringVarP(&checkUpdatesConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	checkUpdatesCmd.Flags().StringVar(&checkUpdatesFailOn, "fail-on", "", "Exit with an error when an update of this severity or above is available (patch, minor, major)")
+	checkUpdatesCmd.Flags().StringVarP(&checkUpdatesOutput, "output", "o", "table", "Output format (table, json)")
+}
+
+func runCheckUpdates(cmd *cobra.Command, args []string) error {
+	if checkUpdatesFailOn != "" && !artifacts.ValidSeverity(checkUpdatesFailOn) {
+		return fmt.Errorf("invalid --fail-on %q, expected one of %s", checkUpdatesFailOn, strings.Join(artifacts.Severities(), ", "))
+	}
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, checkUpdatesConfigFile))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+
+	var check *artifacts.UpdateCheck
+	if checkUpdatesOutput == "json" {
+		check = artifacts.NewManager(cfg, false).CheckUpdates()
+		data, err := json.MarshalIndent(check, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal updates: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		spinner, _ := pterm.DefaultSpinner.Start("Checking vendor sources for updates...")
+		check = artifacts.NewManager(cfg, false).CheckUpdates()
+		spinner.Success(fmt.Sprintf("%d artifacts checked, %d updates available", check.Checked, len(check.Updates)))
+		printUpdates(check)
+	}
+
+	if checkUpdatesFailOn != "" && check.AtLeast(checkUpdatesFailOn) {
+		return exitcode.Wrap(exitcode.Updates, fmt.Errorf("updates of severity %s or above are available", checkUpdatesFailOn))
+	}
+	return nil
+}
+
+func printUpdates(check *artifacts.UpdateCheck) {
+	if len(check.Updates) > 0 {
+		tableData := pterm.TableData{{"Kind", "Name", "Current", "Latest", "Severity", "Source"}}
+		for _, update := range check.Updates {
+			severity := update.Severity
+			switch severity {
+			case artifacts.SeverityMajor:
+				severity = pterm.Red(severity)
+			case artifacts.SeverityMinor:
+				severity = pterm.Yellow(severity)
+			}
+			tableData = append(tableData, []string{update.Kind, update.Name, update.Current, update.Latest, severity, update.Source})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	}
+	for _, message := range check.Errors {
+		pterm.Warning.Println(message)
+	}
+}
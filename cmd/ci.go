@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/ci"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/pterm/pterm"
+)
+
+var ciFlag string
+
+// ciSummaryName is the job summary a run in CI mode leaves in the reports directory
+const ciSummaryName = "ci-summary.md"
+
+// startCI switches to the workflow commands of --ci, or of E2E_INSTALLER_CI. Styling is
+// turned off as well: CI logs are no terminal, spinners would log every frame.
+func startCI() error {
+	name := valueOr(ciFlag, os.Getenv(ci.Env))
+	if name == "" {
+		return nil
+	}
+	if err := ci.Enable(name); err != nil {
+		return err
+	}
+	pterm.DisableStyling()
+	return nil
+}
+
+// finishCI annotates a failed run and publishes the job summary: the sections the command
+// added, or else an outline of the run with the reports it wrote
+func finishCI(command string, duration time.Duration, run *history.Run, err error) {
+	if !ci.Enabled() {
+		return
+	}
+	if err != nil {
+		ci.Error(fmt.Sprintf("%s failed (exit code %d)", command, exitcode.Code(err)), err.Error())
+	}
+	if !ci.HasSummary() {
+		status := "✅ succeeded"
+		if err != nil {
+			status = "❌ failed"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "## `%s` %s\n\nRun `%s` took %s.\n", command, status, history.RunID(), duration.Round(time.Second))
+		if err != nil {
+			fmt.Fprintf(&b, "\n**Error:** %s\n", err)
+			if remediation := exitcode.Remediation(exitcode.Code(err)); remediation != "" {
+				fmt.Fprintf(&b, "\n**Hint:** %s\n", remediation)
+			}
+		}
+		if run != nil && len(run.Reports) > 0 {
+			b.WriteString("\n**Reports:**\n\n")
+			for _, report := range run.Reports {
+				fmt.Fprintf(&b, "- `%s`\n", report)
+			}
+		}
+		ci.AddSummary(b.String())
+	}
+	if summaryErr := ci.WriteSummary(filepath.Join(reportsDir(), ciSummaryName)); summaryErr != nil {
+		logger.Warn("Failed to publish the CI job summary").Err(summaryErr).Send()
+	}
+}
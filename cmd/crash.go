@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"atomicgo.dev/cursor"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// crashJournalEntries is how many of the last journal entries a crash report keeps
+const crashJournalEntries = 50
+
+// CrashReport is written to the reports directory when the installer panics, for support
+type CrashReport struct {
+	Time       string                      `json:"time"`
+	RunID      string                      `json:"runId"`
+	Command    string                      `json:"command"`
+	Flags      []string                    `json:"flags,omitempty"` // names of the flags given; values may hold secrets
+	Version    string                      `json:"version"`
+	GoVersion  string                      `json:"goVersion"`
+	Platform   string                      `json:"platform"`
+	Panic      string                      `json:"panic"`
+	Stack      string                      `json:"stack"`
+	Operations []progress.OperationSummary `json:"operations,omitempty"`
+	Journal    []json.RawMessage           `json:"journal,omitempty"` // last entries of the run's journal
+}
+
+// executeRecovering runs the command, turning a panic on the command's goroutine into an
+// error once the terminal is restored and a crash report is written. Panics of other
+// goroutines still end the process.
+func executeRecovering(target *cobra.Command) (executed *cobra.Command, err error) {
+	var terminalState *term.State
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		terminalState, _ = term.GetState(fd)
+	}
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		stack := debug.Stack()
+		operations := progress.GetProgressManager().Operations()
+		restoreTerminal(terminalState)
+
+		executed = target
+		err = fmt.Errorf("the installer crashed: %v", recovered)
+		path, writeErr := writeCrashReport(target, recovered, stack, operations)
+		if writeErr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n%s\n", err, stack)
+			logger.Error("Failed to write crash report").Err(writeErr).Send()
+			return
+		}
+		history.AddReport(path)
+		logger.Error("Installer crashed").Str("panic", fmt.Sprint(recovered)).Str("report", path).Send()
+
+		instructions := "Please share this file with support"
+		if url := progress.CurrentBranding().SupportURL; url != "" {
+			instructions += " at " + url
+		}
+		pterm.Error.WithWriter(os.Stderr).Printf("The installer crashed. A crash report was written to:\n%s\n%s, along with the tool logs of the run in %s.\n",
+			path, instructions, toolLogsDir())
+	}()
+	return rootCmd.ExecuteC()
+}
+
+// restoreTerminal stops live areas and spinners, shows the cursor again and leaves the raw
+// mode an interrupted prompt may have left the terminal in
+func restoreTerminal(state *term.State) {
+	progress.GetProgressManager().StopAll()
+	if state != nil {
+		term.Restore(int(os.Stdin.Fd()), state)
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		cursor.Show()
+		fmt.Fprintln(os.Stdout)
+	}
+}
+
+func writeCrashReport(target *cobra.Command, recovered interface{}, stack []byte, operations []progress.OperationSummary) (string, error) {
+	report := CrashReport{
+		Time:       time.Now().UTC().Format(time.RFC3339),
+		RunID:      history.RunID(),
+		Version:    version.Version,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Panic:      fmt.Sprint(recovered),
+		Stack:      string(stack),
+		Operations: operations,
+		Journal:    journalTail(filepath.Join(toolLogsDir(), logger.JournalFile), crashJournalEntries),
+	}
+	if target != nil {
+		report.Command = target.CommandPath()
+		target.Flags().Visit(func(flag *pflag.Flag) {
+			report.Flags = append(report.Flags, flag.Name)
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal crash report: %w", err)
+	}
+	dir := reportsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-report_%s.json", time.Now().Format("2006-01-02_15-04-05")))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// journalTail returns the last entries of a journal, none when it cannot be read
+func journalTail(path string, entries int) []json.RawMessage {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) > entries {
+		lines = lines[len(lines)-entries:]
+	}
+	var tail []json.RawMessage
+	for _, line := range lines {
+		if json.Valid(line) {
+			tail = append(tail, json.RawMessage(line))
+		}
+	}
+	return tail
+}
@@ -1,18 +1,42 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
+	pkglogger "github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/postrender"
 	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/releasenotes"
+	"github.com/judebantony/e2e-k8s-installer/pkg/rollback"
+	"github.com/judebantony/e2e-k8s-installer/pkg/snapshot"
+	"github.com/judebantony/e2e-k8s-installer/pkg/tuning"
+	"github.com/judebantony/e2e-k8s-installer/pkg/values"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
 	"github.com/pterm/pterm"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -26,8 +50,30 @@ var (
 	deployCreateNS        bool
 	deploySkipHealthCheck bool
 	deployChartsOnly      []string
+	deployRenderValues    bool
+	deployStrictValues    bool
+	deployOutputsFile     string
+	deployForceCRDs       bool
+	deployMaxParallel     int
+	deployRunTests        bool
+	deployTestTimeout     time.Duration
+	deployPrune           bool
+	deployDiff            bool
+	deployCheckAPIs       string
+	deployPrePull         bool
+	deployOverrideFreeze  string
+	deployHistory         string
+	deployRollbackTo      string
+	deployAckBreaking     []string
+	deployProfile         string
 )
 
+// defaultOutputsFile is the infrastructure report whose outputs feed values templates
+const defaultOutputsFile = "./workspace/reports/infra-report-latest.json"
+
+// defaultSnapshotsDir is where deployment snapshots are stored for later diffs
+const defaultSnapshotsDir = "./reports/snapshots"
+
 // deployCmd represents the deploy command
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
@@ -60,8 +106,61 @@ Examples:
   e2e-k8s-installer deploy --timeout 15m --wait
 
   # Deploy atomically (rollback on failure)
-  e2e-k8s-installer deploy --atomic`,
-	RunE: runDeploy,
+  e2e-k8s-installer deploy --atomic
+
+  # Deploy up to 8 independent charts of a tier at once
+  e2e-k8s-installer deploy --max-parallel 8
+
+  # Run each release's helm tests once it is healthy
+  e2e-k8s-installer deploy --run-tests --test-timeout 10m
+
+  # Show, then remove, releases of charts dropped from the configuration
+  e2e-k8s-installer deploy --prune --dry-run
+  e2e-k8s-installer deploy --prune
+
+  # Apply CRD upgrades even if they drop versions that are still in use
+  e2e-k8s-installer deploy --force-crds
+
+  # Size every chart for a large customer with the preset of deployment.helm.profiles
+  e2e-k8s-installer deploy --profile large
+
+  # Print chart values after resolving templates, without deploying
+  e2e-k8s-installer deploy --render-values --strict-values
+
+  # Compare the post-rendered manifests of each chart with the live releases
+  e2e-k8s-installer deploy --diff
+
+  # Check that every chart renders only APIs served by Kubernetes 1.30
+  e2e-k8s-installer deploy --check-apis 1.30
+
+  # Browse the revisions of a release, then roll it back to one of them
+  e2e-k8s-installer deploy --history backend
+  e2e-k8s-installer deploy --rollback-to backend=3
+
+Chart values and values files may use Go templates with sprig functions, for
+example {{ .Outputs.database_endpoint }}, {{ env "REGION" }},
+{{ param "database.host" }} or {{ .Installer.namespace | upper }}. Outputs are
+read from the latest provision-infra report and parameters from the shared
+installation state.
+
+Templates can also adapt charts to the target cluster through .Capabilities,
+detected before values are rendered: {{ .Capabilities.HasIngressClass "nginx" }},
+{{ .Capabilities.HasAPI "monitoring.coreos.com/v1" }},
+{{ .Capabilities.MetricsServer }} or {{ .Capabilities.PodSecurity }} ("psa",
+"psp" or "none"). Without a reachable cluster every capability reads as absent.
+
+CRDs in each chart's crds/ directory are applied server-side before any release
+is installed, since Helm never upgrades them. The deploy stops if an upgrade
+would remove or stop serving a CRD version that existing resources still use.
+
+Charts may list postRenderers, run in order on the rendered manifests before
+the label policy is applied: a kustomize overlay directory, built in-process
+with the chart output added to its resources as helm-rendered.yaml, or an
+external binary reading manifests on stdin and writing them to stdout.
+
+Namespaces frozen with 'freeze set' are refused before anything changes. Pass
+--override-freeze with a reason to deploy anyway; the override is audited.`,
+	RunE: withExitCode(exitcode.Deploy, runDeploy),
 }
 
 func init() {
@@ -75,11 +174,27 @@ func init() {
 	deployCmd.Flags().BoolVar(&deployCreateNS, "create-namespace", true, "Create namespace if it doesn't exist")
 	deployCmd.Flags().BoolVar(&deploySkipHealthCheck, "skip-health-check", false, "Skip health checks after deployment")
 	deployCmd.Flags().StringSliceVar(&deployChartsOnly, "charts-only", []string{}, "Deploy only specified charts (comma-separated)")
+	deployCmd.Flags().BoolVar(&deployRenderValues, "render-values", false, "Print rendered chart values and exit")
+	deployCmd.Flags().BoolVar(&deployStrictValues, "strict-values", false, "Fail on undefined keys in values templates")
+	deployCmd.Flags().StringVar(&deployOutputsFile, "outputs-file", defaultOutputsFile, "Infrastructure report providing outputs for values templates")
+	deployCmd.Flags().IntVar(&deployMaxParallel, "max-parallel", 0, "Maximum concurrent Helm operations within a dependency tier (default from config, else 4, capped to the host's CPUs and memory)")
+	deployCmd.Flags().BoolVar(&deployRunTests, "run-tests", false, "Run helm test for each release after deployment")
+	deployCmd.Flags().DurationVar(&deployTestTimeout, "test-timeout", 0, "Timeout for each release's helm test (default from config, else 5m)")
+	deployCmd.Flags().BoolVar(&deployPrune, "prune", false, "Uninstall installer-managed releases no longer in the chart list (list only with --dry-run)")
+	deployCmd.Flags().BoolVar(&deployForceCRDs, "force-crds", false, "Apply CRD changes that remove versions still used by existing resources")
+	deployCmd.Flags().BoolVar(&deployDiff, "diff", false, "Show how each release's rendered manifests differ from the cluster and exit")
+	deployCmd.Flags().BoolVar(&deployPrePull, "pre-pull", false, "Pull the images of every chart onto the nodes before installing releases (default from config)")
+	deployCmd.Flags().StringVar(&deployCheckAPIs, "check-apis", "", "Scan rendered manifests for APIs deprecated or removed in this Kubernetes version and exit")
+	deployCmd.Flags().StringVar(&deployOverrideFreeze, "override-freeze", "", "Deploy into frozen namespaces, giving the reason recorded in the audit log")
+	deployCmd.Flags().StringVar(&deployHistory, "history", "", "List the revisions of a chart's release with the values each changed and exit")
+	deployCmd.Flags().StringSliceVar(&deployAckBreaking, "ack-breaking", []string{}, "Acknowledge the breaking changes in the release notes of these charts (comma-separated, or all)")
+	deployCmd.Flags().StringVar(&deployProfile, "profile", "", "Sizing profile of deployment.helm.profiles to overlay chart values with (default from config)")
+	deployCmd.Flags().StringVar(&deployRollbackTo, "rollback-to", "", "Roll a chart's release back to a revision, as <chart>=<revision>, and exit")
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
 	// Initialize logger
-	logger := zerolog.New(os.Stderr).With().
+	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, pkglogger.Journal())).With().
 		Timestamp().
 		Str("component", "deploy").
 		Logger()
@@ -89,7 +204,8 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show enterprise banner
-	progress.ShowEnterpriseWelcome("1.0.0", "Production")
+	applyBranding(workspaceConfigFile(cmd, deployConfigPath))
+	progress.ShowEnterpriseWelcome(version.Version, "Production")
 
 	// Initialize enterprise progress manager
 	pm := progress.GetProgressManager()
@@ -97,16 +213,25 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	startTime := time.Now()
 
+	deployOutputsFile = workspaceDefault(cmd, "outputs-file", deployOutputsFile, "reports/infra-report-latest.json")
+
 	// Load configuration
 	spinner, _ := pterm.DefaultSpinner.Start("🔧 Loading deployment configuration...")
-	config, err := loadDeployConfig(deployConfigPath)
+	config, err := loadDeployConfig(workspaceConfigFile(cmd, deployConfigPath))
+	if err == nil {
+		err = checkChartPaths(config.Helm.Charts)
+	}
 	if err != nil {
 		spinner.Fail("Failed to load configuration")
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
 	}
 	spinner.Success("✅ Configuration loaded successfully")
 	logger.Info().Msg("Deployment configuration loaded successfully")
 
+	if err := requireLicense(nil, license.ModuleDeploy); err != nil {
+		return err
+	}
+
 	// Create deployment manager
 	manager, err := NewDeploymentManager(config, logger)
 	if err != nil {
@@ -114,7 +239,71 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	}
 
 	// Override configuration with command line flags
-	manager.ApplyCommandLineOverrides()
+	manager.ApplyCommandLineOverrides(cmd)
+
+	// Parameters of earlier steps and the rollback journal are in the state of the workspace,
+	// and the installer configuration sizes node pools when the charts do not fit the cluster
+	configFile := workspaceConfigFile(cmd, deployConfigPath)
+	installerCfg, err := loadInstallConfig(configFile)
+	if err == nil {
+		err = applyWorkspace(installerCfg)
+	}
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := initWorkspaceParameters(installerCfg.Installer.Workspace); err != nil {
+		return err
+	}
+	// Chart values read the generated credentials from the parameter store
+	if len(installerCfg.Security.Credentials.Items) > 0 && !deployDryRun {
+		if _, err := publishCredentials(installerCfg, installerCfg.Installer.Workspace); err != nil {
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+	if configFile != "" {
+		manager.installer = installerCfg
+	}
+
+	// Everything this run creates carries the label policy, tagged with the run ID
+	if err := labels.InitGlobalPolicy(config.Labels, manager.GetRunID()); err != nil {
+		return fmt.Errorf("invalid label policy: %w", err)
+	}
+
+	// Resolve values templates up front so strict-mode errors fail fast
+	if err := manager.RenderValues(); err != nil {
+		return fmt.Errorf("failed to render chart values: %w", err)
+	}
+
+	if err := manager.ValidatePostRenderers(); err != nil {
+		return err
+	}
+
+	if deployRenderValues {
+		return manager.PrintRenderedValues()
+	}
+
+	if deployDiff {
+		return manager.PrintDiff()
+	}
+
+	if deployCheckAPIs != "" {
+		return manager.CheckAPIs(deployCheckAPIs)
+	}
+
+	if deployHistory != "" {
+		return manager.PrintHistory(deployHistory)
+	}
+
+	// Refuse frozen namespaces before anything changes
+	releaseFreeze, err := checkNamespaceFreeze(&config.Kubernetes, manager.targetNamespaces(), "deploy", deployOverrideFreeze, deployDryRun)
+	if err != nil {
+		return err
+	}
+	defer releaseFreeze()
+
+	if deployRollbackTo != "" {
+		return manager.RollbackTo(deployRollbackTo)
+	}
 
 	// Define deployment steps with detailed tracking
 	steps := []struct {
@@ -141,18 +330,54 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 			action:      manager.ResolveDependencies,
 			weight:      20,
 		},
+		{
+			name:        "release-notes",
+			description: "Collecting release notes of the versions being installed",
+			action:      manager.CollectReleaseNotes,
+			weight:      5,
+		},
+		{
+			name:        "apply-crds",
+			description: "Installing and upgrading CustomResourceDefinitions",
+			action:      manager.ApplyCRDs,
+			weight:      5,
+		},
+		{
+			name:        "check-capacity",
+			description: "Forecasting cluster capacity for the charts",
+			action:      manager.CheckCapacity,
+			weight:      5,
+		},
+		{
+			name:        "pre-pull-images",
+			description: "Pulling chart images onto the nodes",
+			action:      manager.PrePullImages,
+			weight:      10,
+		},
 		{
 			name:        "deploy-charts",
 			description: "Deploying Helm charts and applications",
 			action:      manager.DeployCharts,
 			weight:      40,
 		},
+		{
+			name:        "prune-releases",
+			description: "Pruning releases removed from configuration",
+			action:      manager.PruneReleases,
+			weight:      5,
+		},
 		{
 			name:        "health-check",
 			description: "Performing comprehensive health checks",
 			action:      manager.PerformHealthChecks,
 			weight:      10,
 		},
+		{
+			name:        "helm-test",
+			description: "Running Helm release tests",
+			action:      manager.RunReleaseTests,
+			weight:      5,
+		},
 		{
 			name:        "validate-deployment",
 			description: "Validating deployment status and connectivity",
@@ -169,6 +394,12 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	// Start enterprise progress tracking
 	pm.StartOperation("deployment", "Application Deployment", "Deploying enterprise applications to Kubernetes", totalWeight)
+	pm.SetMetadata("deployment", "namespace", manager.namespace)
+	pm.SetMetadata("deployment", "charts", len(manager.getChartsToDeployment()))
+	pm.SetMetadata("deployment", "runId", manager.GetRunID())
+
+	// Nodes added for the deployment are removed once it no longer needs them
+	defer manager.ScaleBack()
 
 	// Execute deployment steps with enhanced progress tracking
 	currentWeight := 0
@@ -177,6 +408,7 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	for i, step := range steps {
 		// Start step operation
 		pm.StartOperation(step.name, step.description, fmt.Sprintf("Step %d/%d", i+1, len(steps)), step.weight)
+		pkglogger.SetToolStep(step.name)
 
 		logger.Info().
 			Str("step", step.name).
@@ -198,7 +430,7 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 				Msg("Deployment step failed")
 
 			// Attempt rollback if atomic deployment
-			if deployAtomic && !deployDryRun {
+			if manager.helmAtomic && !deployDryRun {
 				pterm.Warning.Println("🔄 Attempting automatic rollback...")
 				if rollbackErr := manager.Rollback(); rollbackErr != nil {
 					logger.Error().Err(rollbackErr).Msg("Rollback failed")
@@ -208,6 +440,11 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 				}
 			}
 
+			// Record which charts failed and whether a hook or the release itself was at fault
+			if err := manager.GenerateReport(); err != nil {
+				logger.Warn().Err(err).Msg("Failed to generate deployment report")
+			}
+
 			// Update overall deployment status
 			pm.CompleteOperation("deployment", progress.StatusFailed, fmt.Sprintf("Deployment failed at step: %s", step.name))
 			return fmt.Errorf("deployment failed at step '%s': %w", step.name, err)
@@ -239,14 +476,26 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		logger.Warn().Err(err).Msg("Failed to generate deployment report")
 	}
 
+	// Capture a snapshot of deployed resources for run-to-run diffs
+	if !deployDryRun {
+		if snapshotPath, err := manager.CaptureSnapshot(filepath.Join(reportsDir(), "snapshots")); err != nil {
+			logger.Warn().Err(err).Msg("Failed to capture deployment snapshot")
+		} else {
+			pterm.Info.Printf("📸 Deployment snapshot saved: %s (run %s)\n", snapshotPath, manager.GetRunID())
+		}
+	}
+
 	// Show enhanced enterprise summary
 	duration := time.Since(startTime)
 	progress.ShowSummary([]string{
 		"Environment Validation",
 		"Namespace Preparation",
 		"Dependency Resolution",
+		"CRD Installation",
 		"Chart Deployment",
+		"Release Pruning",
 		"Health Verification",
+		"Release Tests",
 		"Deployment Validation",
 	}, stepResults, duration)
 
@@ -260,7 +509,7 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		{"Total Duration", progress.FormatDuration(duration)},
 		{"Health Checks", fmt.Sprintf("%d/%.0f passed", manager.GetHealthChecksPassed(), float64(len(deployedCharts)))},
 		{"Deployment Mode", map[bool]string{true: "DRY RUN", false: "LIVE DEPLOYMENT"}[deployDryRun]},
-		{"Atomic Rollback", map[bool]string{true: "ENABLED", false: "DISABLED"}[deployAtomic]},
+		{"Atomic Rollback", map[bool]string{true: "ENABLED", false: "DISABLED"}[manager.helmAtomic]},
 	}
 
 	pterm.DefaultTable.WithHasHeader().WithData(
@@ -271,7 +520,7 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	if len(deployedCharts) > 0 {
 		pterm.DefaultSection.Println("🚀 Deployed Applications")
 
-		chartData := [][]string{{"Application", "Namespace", "Status", "Version", "Health"}}
+		chartData := [][]string{{"Application", "Namespace", "Status", "Chart Version", "App Version", "Images", "Health"}}
 		for _, chart := range deployedCharts {
 			healthStatus := "✅ Healthy"
 			if chart.Status != "deployed" {
@@ -282,7 +531,9 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 				chart.Name,
 				chart.Namespace,
 				fmt.Sprintf("📦 %s", chart.Status),
-				fmt.Sprintf("v%s", chart.Version),
+				chart.Version,
+				valueOr(chart.AppVersion, "-"),
+				valueOr(strings.Join(chart.Images, ", "), "-"),
 				healthStatus,
 			})
 		}
@@ -330,25 +581,66 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 type ChartDeploymentStatus struct {
 	Name      string
 	Namespace string
-	Status    string
-	Version   string
+	Status    string // Helm status of the release, e.g. deployed, or failed
+	Version   string // chart version of the installed release
 	Order     int
+
+	// Read back from the installed release, so upgrades can be verified at a glance
+	AppVersion string   `json:",omitempty"`
+	Revision   int      `json:",omitempty"`
+	Images     []string `json:",omitempty"`
+
+	// Outcomes of the chart's own pre and post deploy hooks
+	ChartHooks []ChartHookResult `json:",omitempty"`
+
+	// Result of helm test, when release tests were run
+	Test *helm.TestResult `json:",omitempty"`
+
+	// Set when the chart failed; FailureKind is "pre-hook", "hook" (a Helm hook), "release" or "post-hook"
+	FailureKind string        `json:",omitempty"`
+	Error       string        `json:",omitempty"`
+	LogTail     []string      `json:",omitempty"` // last helm output lines of a failed release
+	Hooks       []k8s.HookJob `json:",omitempty"`
 }
 
+// defaultReleaseTestTimeout applies to helm test when neither flag nor config set a timeout
+const defaultReleaseTestTimeout = 5 * time.Minute
+
+// hookLogTailLines is the number of log lines kept from a failed hook Job
+const hookLogTailLines = 20
+
 // DeploymentManager handles application deployment operations
 type DeploymentManager struct {
 	config             *config.DeploymentConfig
 	logger             zerolog.Logger
+	runID              string
 	namespace          string
 	deployedCharts     []ChartDeploymentStatus
+	renderedValues     map[string]map[string]interface{}
 	healthChecksPassed int
 	kubeConfigPath     string
 	helmTimeout        time.Duration
+	helmWait           bool
+	helmAtomic         bool
+	helmFlags          map[string]bool // helm settings given on the command line, over those of charts
+	tiers              [][]config.DeployChart
+	mutex              sync.Mutex
+
+	// installer is the installer configuration, when one is found, for infrastructure.nodeScaling
+	installer *config.InstallerConfig
+	manifests map[string][]byte // rendered charts, by chart name
+	forecast  *k8s.CapacityForecast
+	scalePlan *NodeScalePlan
+
+	capabilities *k8s.Capabilities // detected once, for values templates
+	releaseNotes []releasenotes.Notes
+	profile      string            // sizing profile overlaying chart values, "" for none
+	rollbacks    []ReleaseRollback // releases reverted after the deployment failed
 }
 
 // NewDeploymentManager creates a new deployment manager
 func NewDeploymentManager(config *config.DeploymentConfig, logger zerolog.Logger) (*DeploymentManager, error) {
-	timeout, err := time.ParseDuration(deployTimeout)
+	timeout, err := time.ParseDuration(valueOr(config.Helm.Timeout, deployTimeout))
 	if err != nil {
 		timeout = 10 * time.Minute
 	}
@@ -356,26 +648,331 @@ func NewDeploymentManager(config *config.DeploymentConfig, logger zerolog.Logger
 	manager := &DeploymentManager{
 		config:         config,
 		logger:         logger,
+		runID:          history.RunID(),
 		namespace:      config.Kubernetes.Namespace,
 		deployedCharts: []ChartDeploymentStatus{},
+		renderedValues: make(map[string]map[string]interface{}),
 		helmTimeout:    timeout,
+		helmWait:       config.Helm.Wait,
+		helmAtomic:     config.Helm.Atomic,
 		kubeConfigPath: config.Kubernetes.ConfigPath,
+		profile:        config.Helm.Profile,
 	}
 
 	return manager, nil
 }
 
-// ApplyCommandLineOverrides applies command line flag overrides
-func (m *DeploymentManager) ApplyCommandLineOverrides() {
+// ApplyCommandLineOverrides applies command line flag overrides. --timeout, --wait and --atomic
+// override the helm settings of the configuration, and of every chart, only when given.
+func (m *DeploymentManager) ApplyCommandLineOverrides(cmd *cobra.Command) {
 	if deployNamespace != "" {
 		m.namespace = deployNamespace
 	}
+	if deployProfile != "" {
+		m.profile = deployProfile
+	}
 
-	if deployTimeout != "" {
+	m.helmFlags = make(map[string]bool)
+	for _, name := range []string{"timeout", "wait", "atomic"} {
+		m.helmFlags[name] = cmd.Flags().Changed(name)
+	}
+	if m.helmFlags["timeout"] {
 		if timeout, err := time.ParseDuration(deployTimeout); err == nil {
 			m.helmTimeout = timeout
 		}
 	}
+	if m.helmFlags["wait"] {
+		m.helmWait = deployWait
+	}
+	if m.helmFlags["atomic"] {
+		m.helmAtomic = deployAtomic
+	}
+}
+
+// releaseOptions are the effective Helm install/upgrade settings of one chart
+type releaseOptions struct {
+	Timeout       time.Duration
+	Wait          bool
+	Atomic        bool
+	CleanupOnFail bool
+}
+
+// chartReleaseOptions resolves a chart's Helm settings: flags given on the command line, then
+// the chart's own timeout, wait and atomic, then the deployment's helm settings
+func (m *DeploymentManager) chartReleaseOptions(chart config.DeployChart) releaseOptions {
+	options := releaseOptions{
+		Timeout:       m.helmTimeout,
+		Wait:          m.helmWait,
+		Atomic:        m.helmAtomic,
+		CleanupOnFail: m.config.Helm.CleanupOnFail,
+	}
+	if chart.Timeout != "" && !m.helmFlags["timeout"] {
+		if timeout, err := time.ParseDuration(chart.Timeout); err == nil {
+			options.Timeout = timeout
+		}
+	}
+	if chart.Wait != nil && !m.helmFlags["wait"] {
+		options.Wait = *chart.Wait
+	}
+	if chart.Atomic != nil && !m.helmFlags["atomic"] {
+		options.Atomic = *chart.Atomic
+	}
+	// --atomic implies --wait in Helm
+	options.Wait = options.Wait || options.Atomic
+	return options
+}
+
+// RenderValues resolves templates in each chart's values file and inline values
+func (m *DeploymentManager) RenderValues() error {
+	outputs, err := values.LoadOutputs(deployOutputsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		m.logger.Debug().Str("path", deployOutputsFile).Msg("No infrastructure outputs available for values templates")
+		outputs = make(map[string]interface{})
+	}
+
+	renderer := values.NewRenderer(values.Context{
+		Outputs:      outputs,
+		Params:       params.GetStore().Values(),
+		Capabilities: m.detectCapabilities(),
+		Installer: map[string]interface{}{
+			"namespace": m.namespace,
+			"runId":     m.runID,
+			"context":   m.config.Kubernetes.Context,
+			"dryRun":    deployDryRun,
+		},
+	}, deployStrictValues)
+
+	profile, err := m.sizingProfile()
+	if err != nil {
+		return err
+	}
+
+	for _, chart := range m.getChartsToDeployment() {
+		chartRenderer := renderer.WithChart(map[string]interface{}{
+			"name":      chart.Name,
+			"namespace": chart.Namespace,
+			"path":      chart.Path,
+		})
+
+		rendered := make(map[string]interface{})
+		if chart.ValuesFile != "" {
+			fileValues, err := chartRenderer.RenderFile(chart.ValuesFile)
+			if err != nil {
+				return fmt.Errorf("chart %s: %w", chart.Name, err)
+			}
+			rendered = values.Merge(rendered, fileValues)
+		}
+
+		if overlay, ok := profile.Charts[chart.Name]; ok {
+			if overlay.ValuesFile != "" {
+				fileValues, err := chartRenderer.RenderFile(overlay.ValuesFile)
+				if err != nil {
+					return fmt.Errorf("chart %s: profile %s: %w", chart.Name, m.profile, err)
+				}
+				rendered = values.Merge(rendered, fileValues)
+			}
+			profileValues, err := chartRenderer.RenderValues(chart.Name, overlay.Values)
+			if err != nil {
+				return fmt.Errorf("chart %s: profile %s: %w", chart.Name, m.profile, err)
+			}
+			rendered = values.Merge(rendered, profileValues)
+		}
+
+		inlineValues, err := chartRenderer.RenderValues(chart.Name, chart.Values)
+		if err != nil {
+			return fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+		m.renderedValues[chart.Name] = values.Merge(rendered, inlineValues)
+	}
+
+	m.logger.Info().Int("charts", len(m.renderedValues)).Bool("strict", deployStrictValues).Str("profile", m.profile).Msg("Chart values rendered")
+	return nil
+}
+
+// sizingProfile returns the selected sizing profile, an empty one when none is selected. Charts
+// the profile names but the configuration does not are only warned about, so one set of
+// profiles can serve configurations deploying a subset of the charts.
+func (m *DeploymentManager) sizingProfile() (config.SizingProfile, error) {
+	if m.profile == "" {
+		return config.SizingProfile{}, nil
+	}
+	profile, ok := m.config.Helm.Profiles[m.profile]
+	if !ok {
+		names := make([]string, 0, len(m.config.Helm.Profiles))
+		for name := range m.config.Helm.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return profile, exitcode.Wrap(exitcode.Config, fmt.Errorf("unknown sizing profile %q, deployment.helm.profiles defines %v", m.profile, names))
+	}
+	for chart := range profile.Charts {
+		if _, err := m.configuredChart(chart); err != nil {
+			m.logger.Warn().Str("profile", m.profile).Str("chart", chart).Msg("Sizing profile overlays a chart that is not configured")
+		}
+	}
+	return profile, nil
+}
+
+// detectCapabilities queries the cluster for the capabilities values templates can test. Without
+// a reachable cluster every capability reads as absent.
+func (m *DeploymentManager) detectCapabilities() k8s.Capabilities {
+	if m.capabilities == nil {
+		m.capabilities = &k8s.Capabilities{}
+		k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+		if err == nil {
+			var caps *k8s.Capabilities
+			if caps, err = k8sMgr.DetectCapabilities(); err == nil {
+				m.capabilities = caps
+			}
+		}
+		if err != nil {
+			m.logger.Warn().Err(err).Msg("Cluster capabilities not detected, values templates see none")
+		} else {
+			m.logger.Info().
+				Str("kube_version", m.capabilities.KubeVersion).
+				Strs("ingress_classes", m.capabilities.IngressClasses).
+				Bool("metrics_server", m.capabilities.MetricsServer).
+				Str("pod_security", m.capabilities.PodSecurity()).
+				Msg("Cluster capabilities detected")
+		}
+	}
+	return *m.capabilities
+}
+
+// PrintRenderedValues prints the resolved values of every chart as YAML, with sensitive parameters masked
+func (m *DeploymentManager) PrintRenderedValues() error {
+	secrets := params.GetStore().SensitiveValues()
+
+	for _, chart := range m.getChartsToDeployment() {
+		data, err := yaml.Marshal(values.Redact(m.renderedValues[chart.Name], secrets))
+		if err != nil {
+			return fmt.Errorf("failed to marshal values for chart %s: %w", chart.Name, err)
+		}
+
+		pterm.DefaultSection.Printf("%s (%s)\n", chart.Name, chart.Namespace)
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+// ValidatePostRenderers checks the overlays and binaries of every chart's post-renderers
+func (m *DeploymentManager) ValidatePostRenderers() error {
+	for _, chart := range m.getChartsToDeployment() {
+		if err := postrender.Validate(chart.PostRenderers); err != nil {
+			return fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+	}
+	return nil
+}
+
+// PrintDiff renders every chart with its post-renderers and prints how the result differs
+// from the manifest of the installed release
+func (m *DeploymentManager) PrintDiff() error {
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, chart := range m.getChartsToDeployment() {
+		namespace := m.chartNamespace(chart)
+		rendered, err := m.renderChart(helmMgr, chart)
+		if err != nil {
+			return err
+		}
+		live, err := helmMgr.GetManifest(chart.Name, namespace)
+		if err != nil {
+			return err
+		}
+
+		changes, err := snapshot.DiffManifests(live, rendered)
+		if err != nil {
+			return fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+
+		pterm.DefaultSection.Printf("%s (%s)\n", chart.Name, namespace)
+		if len(chart.PostRenderers) > 0 {
+			names := make([]string, len(chart.PostRenderers))
+			for i, renderer := range chart.PostRenderers {
+				names[i] = renderer.Type + ":" + renderer.Path
+			}
+			pterm.Info.Printf("Post-renderers: %s\n", strings.Join(names, ", "))
+		}
+		if live == nil {
+			pterm.Info.Println("Release is not installed, every object will be created")
+		}
+		if len(changes) == 0 {
+			pterm.Success.Println("No changes")
+			continue
+		}
+
+		tableData := [][]string{{"Change", "Object", "Field", "Live", "Rendered"}}
+		for _, change := range changes {
+			tableData = append(tableData, []string{string(change.Type), change.Object, change.Field, change.OldValue, change.NewValue})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		total += len(changes)
+	}
+
+	pterm.Info.Printf("%d changes across %d charts\n", total, len(m.getChartsToDeployment()))
+	return nil
+}
+
+// CheckAPIs scans the post-rendered manifests of every chart for APIs deprecated or removed in
+// the target Kubernetes version, failing if a chart could not be installed on it
+func (m *DeploymentManager) CheckAPIs(version string) error {
+	target, err := deprecations.ParseVersion(version)
+	if err != nil {
+		return err
+	}
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	var findings []deprecations.Finding
+	for _, chart := range m.getChartsToDeployment() {
+		rendered, err := m.renderChart(helmMgr, chart)
+		if err != nil {
+			return err
+		}
+		chartFindings, err := deprecations.ScanManifest(rendered, chart.Name, target)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, chartFindings...)
+	}
+
+	pterm.Info.Printf("Checked %d charts against Kubernetes %s\n", len(m.getChartsToDeployment()), target)
+	return reportAPIFindings(findings, target)
+}
+
+// renderChart renders a chart with its resolved values and post-renderers
+func (m *DeploymentManager) renderChart(helmMgr *helm.Manager, chart config.DeployChart) ([]byte, error) {
+	chartValues, err := yaml.Marshal(m.renderedValues[chart.Name])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values for chart %s: %w", chart.Name, err)
+	}
+	postRender, err := postRendererArgs(chart)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := helmMgr.Template(chart.Name, chart.Path, m.chartNamespace(chart), chartValues, postRender...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", chart.Name, err)
+	}
+	return rendered, nil
+}
+
+func (m *DeploymentManager) chartNamespace(chart config.DeployChart) string {
+	if chart.Namespace != "" {
+		return chart.Namespace
+	}
+	return m.namespace
 }
 
 // ValidateEnvironment validates the Kubernetes environment with enhanced progress tracking
@@ -409,6 +1006,23 @@ func (m *DeploymentManager) ValidateEnvironment() error {
 	validationSteps := []string{"kubectl-connectivity", "cluster-access", "helm-installation", "resource-availability"}
 
 	for _, step := range validationSteps {
+		if step == "cluster-access" {
+			if err := m.validatePermissions(); err != nil {
+				pm.UpdateSubStep("validate-environment", step, 0, progress.StatusFailed)
+				return err
+			}
+			pm.UpdateSubStep("validate-environment", step, 4, progress.StatusCompleted)
+			continue
+		}
+		if step == "resource-availability" {
+			if err := m.validateLicensedNodes(); err != nil {
+				pm.UpdateSubStep("validate-environment", step, 0, progress.StatusFailed)
+				return err
+			}
+			pm.UpdateSubStep("validate-environment", step, 4, progress.StatusCompleted)
+			continue
+		}
+
 		time.Sleep(500 * time.Millisecond) // Simulate validation work
 		pm.UpdateSubStep("validate-environment", step, 4, progress.StatusCompleted)
 	}
@@ -417,6 +1031,57 @@ func (m *DeploymentManager) ValidateEnvironment() error {
 	return nil
 }
 
+// validatePermissions verifies the current identity holds every permission deploy needs
+func (m *DeploymentManager) validatePermissions() error {
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Skipping RBAC preflight, kubectl unavailable")
+		return nil
+	}
+
+	checks, err := k8sMgr.CheckPermissions([]string{"deploy"}, m.namespace)
+	if err != nil {
+		return fmt.Errorf("failed to verify cluster permissions: %w", err)
+	}
+
+	if missing := k8s.MissingPermissions(checks); len(missing) > 0 {
+		printPermissionReport(missing)
+		pterm.Info.Println("Run 'e2e-k8s-installer preflight rbac' to generate a least-privilege ClusterRole")
+		return fmt.Errorf("%d required permissions are missing for deploy", len(missing))
+	}
+
+	return nil
+}
+
+// validateLicensedNodes fails when the cluster has more nodes than the license allows
+func (m *DeploymentManager) validateLicensedNodes() error {
+	lic := license.Current()
+	if lic == nil || lic.Entitlements.MaxNodes == 0 {
+		return nil
+	}
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Skipping licensed node check, kubectl unavailable")
+		return nil
+	}
+	nodes, err := k8sMgr.NodeCount()
+	if err != nil {
+		return fmt.Errorf("failed to count cluster nodes: %w", err)
+	}
+	return lic.AllowsNodes(nodes)
+}
+
+// targetNamespaces returns the deployment namespace and those of the charts to deploy
+func (m *DeploymentManager) targetNamespaces() []string {
+	namespaces := []string{m.namespace}
+	for _, chart := range m.getChartsToDeployment() {
+		if chart.Namespace != "" && !slices.Contains(namespaces, chart.Namespace) {
+			namespaces = append(namespaces, chart.Namespace)
+		}
+	}
+	return namespaces
+}
+
 // PrepareNamespace prepares the deployment namespace
 func (m *DeploymentManager) PrepareNamespace() error {
 	m.logger.Info().Str("namespace", m.namespace).Msg("Preparing deployment namespace")
@@ -427,14 +1092,24 @@ func (m *DeploymentManager) PrepareNamespace() error {
 	}
 
 	if deployCreateNS {
-		// TODO: Implement namespace creation
-		// This would typically involve:
-		// 1. Checking if namespace exists
-		// 2. Creating namespace if it doesn't exist
-		// 3. Applying namespace labels and annotations
-		// 4. Setting up RBAC permissions
+		k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+		if err != nil {
+			return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+		}
 
-		m.logger.Info().Str("namespace", m.namespace).Msg("Namespace created/validated")
+		// Applying rather than creating keeps existing namespaces and brings their labels in line with the policy
+		for _, namespace := range m.targetNamespaces() {
+			_, getErr := k8sMgr.Get("namespaces", "", namespace)
+			manifest := fmt.Sprintf("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", namespace)
+			if err := k8sMgr.ApplyManifest(manifest); err != nil {
+				return fmt.Errorf("failed to create namespace %s: %w", namespace, err)
+			}
+			// Only namespaces the installer created are deleted by rollback
+			if k8s.IsNotFound(getErr) {
+				rollback.Record(config.RollbackAction{Kind: rollback.KindNamespace, Name: namespace})
+			}
+			m.logger.Info().Str("namespace", namespace).Msg("Namespace created/validated")
+		}
 	}
 
 	return nil
@@ -444,15 +1119,133 @@ func (m *DeploymentManager) PrepareNamespace() error {
 func (m *DeploymentManager) ResolveDependencies() error {
 	m.logger.Info().Msg("Resolving chart dependencies")
 
-	// TODO: Implement dependency resolution
-	// This would typically involve:
-	// 1. Analyzing chart dependencies
-	// 2. Building deployment order graph
-	// 3. Validating dependency constraints
-	// 4. Downloading dependent charts
+	tiers, err := planDeploymentTiers(m.getChartsToDeployment())
+	if err != nil {
+		return err
+	}
+	m.tiers = tiers
+
+	if err := m.buildChartDependencies(); err != nil {
+		return err
+	}
+
+	for i, tier := range tiers {
+		names := make([]string, len(tier))
+		for j, chart := range tier {
+			names[j] = chart.Name
+		}
+		m.logger.Debug().Int("tier", i+1).Strs("charts", names).Msg("Deployment tier planned")
+	}
 
-	time.Sleep(2 * time.Second)
-	m.logger.Info().Msg("Chart dependencies resolved successfully")
+	m.logger.Info().Int("tiers", len(tiers)).Msg("Chart dependencies resolved successfully")
+	return nil
+}
+
+// buildChartDependencies downloads the dependencies local chart directories declare but
+// have not vendored, which Helm refuses to install without
+func (m *DeploymentManager) buildChartDependencies() error {
+	var helmMgr *helm.Manager
+	for _, chart := range m.getChartsToDeployment() {
+		if info, err := os.Stat(chart.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		missing, err := helm.MissingDependencies(chart.Path)
+		if err != nil {
+			return fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+		if !missing {
+			continue
+		}
+		if deployDryRun {
+			m.logger.Info().Str("chart", chart.Name).Str("path", chart.Path).Msg("Would build the missing chart dependencies")
+			continue
+		}
+		if helmMgr == nil {
+			if helmMgr, err = helm.NewManager(&m.config.Kubernetes); err != nil {
+				return err
+			}
+		}
+		if err := helmMgr.BuildDependencies(context.Background(), chart.Path); err != nil {
+			return fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+		m.logger.Info().Str("chart", chart.Name).Str("path", chart.Path).Msg("Chart dependencies built")
+	}
+	return nil
+}
+
+// ApplyCRDs applies the CRDs shipped with the charts before any release is installed
+func (m *DeploymentManager) ApplyCRDs() error {
+	var crds []k8s.CRD
+	seen := make(map[string]string)
+	for _, chart := range m.getChartsToDeployment() {
+		// Helm installs the CRDs of the charts it fetches with the release
+		if chart.Path == "" || remoteChart(chart.Path) {
+			continue
+		}
+		chartCRDs, err := k8s.ExtractCRDs(chart.Path)
+		if err != nil {
+			return err
+		}
+		for _, crd := range chartCRDs {
+			if owner, ok := seen[crd.Name]; ok {
+				m.logger.Warn().Str("crd", crd.Name).Str("chart", chart.Name).Str("first_chart", owner).
+					Msg("CRD shipped by several charts, using the first definition")
+				continue
+			}
+			seen[crd.Name] = chart.Name
+			crds = append(crds, crd)
+		}
+	}
+
+	if len(crds) == 0 {
+		m.logger.Info().Msg("No CRDs shipped with charts")
+		return nil
+	}
+
+	if deployDryRun {
+		for _, crd := range crds {
+			m.logger.Info().Str("crd", crd.Name).Str("source", crd.Source).Msg("DRY RUN: CRD would be applied")
+		}
+		return nil
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+
+	var breaking []k8s.CRDBreakingChange
+	for _, crd := range crds {
+		changes, err := k8sMgr.CheckCRDCompatibility(crd)
+		if err != nil {
+			return fmt.Errorf("failed to check CRD %s: %w", crd.Name, err)
+		}
+		breaking = append(breaking, changes...)
+	}
+
+	if len(breaking) > 0 {
+		data := [][]string{{"CRD", "Version", "Change", "Stored", "Objects"}}
+		for _, change := range breaking {
+			data = append(data, []string{change.CRD, change.Version, change.Change,
+				fmt.Sprintf("%t", change.StoredVersion), fmt.Sprintf("%d", change.Objects)})
+		}
+		pterm.Warning.Println("Breaking CRD changes detected")
+		pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+
+		if !deployForceCRDs {
+			return fmt.Errorf("%d breaking CRD change(s) would strand existing resources, migrate them or use --force-crds", len(breaking))
+		}
+		m.logger.Warn().Int("changes", len(breaking)).Msg("Applying breaking CRD changes as requested")
+	}
+
+	if err := k8sMgr.ApplyCRDs(crds); err != nil {
+		return fmt.Errorf("failed to apply CRDs: %w", err)
+	}
+	if err := k8sMgr.WaitForCRDs(crds, m.helmTimeout); err != nil {
+		return err
+	}
+
+	m.logger.Info().Int("crds", len(crds)).Msg("CRDs established")
 	return nil
 }
 
@@ -464,17 +1257,20 @@ func (m *DeploymentManager) DeployCharts() error {
 
 	if deployDryRun {
 		m.logger.Info().Msg("DRY RUN: Chart deployment simulated")
+		m.printReleasePlan()
 		// Simulate deployed charts for display
-		m.deployedCharts = []ChartDeploymentStatus{
-			{Name: "database", Namespace: m.namespace, Status: "deployed", Version: "1.0.0", Order: 1},
-			{Name: "backend-api", Namespace: m.namespace, Status: "deployed", Version: "1.2.0", Order: 2},
-			{Name: "frontend-web", Namespace: m.namespace, Status: "deployed", Version: "2.1.0", Order: 3},
-			{Name: "monitoring", Namespace: m.namespace, Status: "deployed", Version: "1.5.0", Order: 4},
+		m.deployedCharts = nil
+		for _, chart := range m.getChartsToDeployment() {
+			status := ChartDeploymentStatus{Name: chart.Name, Namespace: chart.Namespace, Status: "deployed", Order: chart.Order}
+			status.Version, status.AppVersion = localChartVersion(chart.Path)
+			m.deployedCharts = append(m.deployedCharts, status)
 		}
 
 		// Simulate deployment progress
 		for _, chart := range m.deployedCharts {
 			pm.AddSubStep("deploy-charts", chart.Name, fmt.Sprintf("Deploying %s chart", chart.Name), 10)
+			pm.SetSubStepMetadata("deploy-charts", chart.Name, "namespace", chart.Namespace)
+			pm.SetSubStepMetadata("deploy-charts", chart.Name, "version", chart.Version)
 			time.Sleep(400 * time.Millisecond)
 			pm.UpdateSubStep("deploy-charts", chart.Name, 10, progress.StatusCompleted)
 		}
@@ -482,46 +1278,307 @@ func (m *DeploymentManager) DeployCharts() error {
 		return nil
 	}
 
-	// Get charts to deploy (filtered if charts-only is specified)
-	chartsToDeployment := m.getChartsToDeployment()
+	// Tiers are planned by ResolveDependencies; plan here if the step was skipped
+	if m.tiers == nil {
+		if err := m.ResolveDependencies(); err != nil {
+			return err
+		}
+	}
 
-	// Sort charts by deployment order
-	sort.Slice(chartsToDeployment, func(i, j int) bool {
-		return chartsToDeployment[i].Order < chartsToDeployment[j].Order
-	})
+	// Hook Jobs are observed through kubectl; without it failures are reported without hook detail
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Helm hook jobs will not be monitored")
+		k8sMgr = nil
+	}
 
-	// Deploy each chart with progress tracking
-	for _, chart := range chartsToDeployment {
-		pm.AddSubStep("deploy-charts", chart.Name, fmt.Sprintf("Deploying %s to %s", chart.Name, chart.Namespace), 10)
+	// Releases are installed with the Helm SDK and read back through helm
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("charts cannot be deployed: %w", err)
+	}
 
-		m.logger.Info().
-			Str("chart", chart.Name).
-			Str("namespace", chart.Namespace).
-			Int("order", chart.Order).
-			Msg("Deploying chart")
+	maxParallel := m.maxParallel()
+
+	// Deploy tier by tier; charts within a tier are independent and run concurrently
+	pm.SetMetadata("deploy-charts", "tiers", len(m.tiers))
+	pm.SetMetadata("deploy-charts", "maxParallel", maxParallel)
+	for i, tier := range m.tiers {
+		m.logger.Info().Int("tier", i+1).Int("charts", len(tier)).Int("max_parallel", maxParallel).Msg("Deploying tier")
+
+		var wg sync.WaitGroup
+		errs := make([]error, len(tier))
+		slots := make(chan struct{}, maxParallel)
+		reported := len(m.deployedCharts)
+
+		// Concurrent charts each get their own line instead of writing over each other
+		panes := pm.StartPanes(fmt.Sprintf("Tier %d/%d: %d charts, %d at a time", i+1, len(m.tiers), len(tier), min(maxParallel, len(tier))))
+		for j, chart := range tier {
+			pane := panes.Add(chart.Name)
+			wg.Add(1)
+			go func(j int, chart config.DeployChart) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				pane.Run(fmt.Sprintf("installing into %s", chart.Namespace))
+				errs[j] = m.deployTierChart(k8sMgr, helmMgr, chart, pane)
+				if errs[j] != nil {
+					pane.Done(progress.StatusFailed, errs[j].Error())
+				} else {
+					pane.Done(progress.StatusCompleted, "")
+				}
+			}(j, chart)
+		}
+		wg.Wait()
+		panes.Stop()
+
+		// Failure details are printed once the panes no longer own the terminal
+		m.mutex.Lock()
+		for _, status := range m.deployedCharts[reported:] {
+			if status.Status == "failed" {
+				printChartFailure(status)
+			}
+		}
+		m.mutex.Unlock()
 
-		if err := m.deployChart(chart); err != nil {
-			pm.UpdateSubStep("deploy-charts", chart.Name, 0, progress.StatusFailed)
-			return fmt.Errorf("failed to deploy chart %s: %w", chart.Name, err)
+		// Let the whole tier finish so every failure is reported, then stop before dependents
+		if err := errors.Join(errs...); err != nil {
+			return err
 		}
+	}
+
+	m.logger.Info().
+		Int("charts_deployed", len(m.deployedCharts)).
+		Msg("All charts deployed successfully")
+	return nil
+}
+
+// deployTierChart deploys one chart of a tier with its own progress sub-step and hook watcher,
+// whose output goes to the chart's pane
+func (m *DeploymentManager) deployTierChart(k8sMgr *k8s.Manager, helmMgr *helm.Manager, chart config.DeployChart, pane *progress.Pane) error {
+	pm := progress.GetProgressManager()
+	pm.AddSubStep("deploy-charts", chart.Name, fmt.Sprintf("Deploying %s to %s", chart.Name, chart.Namespace), 10)
+	pm.SetSubStepMetadata("deploy-charts", chart.Name, "namespace", chart.Namespace)
+
+	m.logger.Info().
+		Str("chart", chart.Name).
+		Str("namespace", chart.Namespace).
+		Int("order", chart.Order).
+		Msg("Deploying chart")
+
+	hookResults, err := m.runChartHooks(k8sMgr, chart, "pre")
+	if err != nil {
+		pm.UpdateSubStep("deploy-charts", chart.Name, 0, progress.StatusFailed)
+		return m.recordChartHookFailure(chart, "pre-hook", hookResults, err)
+	}
 
-		pm.UpdateSubStep("deploy-charts", chart.Name, 10, progress.StatusCompleted)
+	// Allow for clock skew between this host and the API server when matching new hook Jobs
+	started := time.Now().Add(-30 * time.Second)
 
-		m.deployedCharts = append(m.deployedCharts, ChartDeploymentStatus{
+	var watcher *k8s.HookWatcher
+	if k8sMgr != nil {
+		watcher = k8sMgr.NewHookWatcher(chart.Namespace, chart.Name, started, pane.Writer())
+		watcher.Start()
+	}
+
+	// The revision the release had before, journalled so rollback can return to it
+	namespace := valueOr(chart.Namespace, m.namespace)
+	previous, revisionErr := helmMgr.Revision(chart.Name, namespace)
+	if revisionErr != nil {
+		m.logger.Warn().Err(revisionErr).Str("chart", chart.Name).Msg("Release revision unknown, the release is not journalled for rollback")
+	}
+
+	release, err := m.deployChart(helmMgr, chart)
+	if watcher != nil {
+		watcher.Stop()
+	}
+	audit.Record("helm.upgrade-install", chart.Namespace+"/"+chart.Name, map[string]interface{}{
+		"chart": chart.Path,
+		"runId": m.runID,
+	}, err)
+	// An atomic release that failed was already rolled back by helm itself
+	if revisionErr == nil && (err == nil || !m.chartReleaseOptions(chart).Atomic) {
+		rollback.Record(config.RollbackAction{
+			Kind:      rollback.KindHelmRelease,
 			Name:      chart.Name,
-			Namespace: chart.Namespace,
-			Status:    "deployed",
-			Version:   "1.0.0", // TODO: Get actual version
-			Order:     chart.Order,
+			Namespace: namespace,
+			Revision:  previous,
+			Details:   map[string]string{"chart": chart.Path},
 		})
 	}
+	if err != nil {
+		pm.UpdateSubStep("deploy-charts", chart.Name, 0, progress.StatusFailed)
+		return m.recordChartFailure(k8sMgr, chart, started, hookResults, err)
+	}
 
-	m.logger.Info().
-		Int("charts_deployed", len(m.deployedCharts)).
-		Msg("All charts deployed successfully")
+	postResults, err := m.runChartHooks(k8sMgr, chart, "post")
+	hookResults = append(hookResults, postResults...)
+	if err != nil {
+		pm.UpdateSubStep("deploy-charts", chart.Name, 0, progress.StatusFailed)
+		return m.recordChartHookFailure(chart, "post-hook", hookResults, err)
+	}
+
+	status := ChartDeploymentStatus{
+		Name:       chart.Name,
+		Namespace:  chart.Namespace,
+		Status:     "deployed",
+		Order:      chart.Order,
+		ChartHooks: hookResults,
+	}
+	setReleaseStatus(&status, release)
+	pm.SetSubStepMetadata("deploy-charts", chart.Name, "version", status.Version)
+	if status.AppVersion != "" {
+		pm.SetSubStepMetadata("deploy-charts", chart.Name, "appVersion", status.AppVersion)
+	}
+	if len(hookResults) > 0 {
+		pm.SetSubStepMetadata("deploy-charts", chart.Name, "hooks", len(hookResults))
+	}
+	pm.UpdateSubStep("deploy-charts", chart.Name, 10, progress.StatusCompleted)
+
+	m.mutex.Lock()
+	m.deployedCharts = append(m.deployedCharts, status)
+	m.mutex.Unlock()
 	return nil
 }
 
+// printReleasePlan shows the charts a deployment would install with the effective Helm
+// settings of each
+func (m *DeploymentManager) printReleasePlan() {
+	tableData := [][]string{{"Chart", "Namespace", "Order", "Timeout", "Wait", "Atomic", "Cleanup On Fail"}}
+	for _, chart := range m.getChartsToDeployment() {
+		options := m.chartReleaseOptions(chart)
+		tableData = append(tableData, []string{chart.Name, chart.Namespace, strconv.Itoa(chart.Order),
+			options.Timeout.String(), strconv.FormatBool(options.Wait), strconv.FormatBool(options.Atomic),
+			strconv.FormatBool(options.CleanupOnFail)})
+	}
+	pterm.DefaultSection.Println("Release plan")
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	if m.profile != "" {
+		pterm.Info.Printf("Chart values sized with the %s profile\n", m.profile)
+	}
+}
+
+// setReleaseStatus fills in the status, chart version, appVersion, revision and images of a
+// release
+func setReleaseStatus(status *ChartDeploymentStatus, release *helm.ReleaseStatus) {
+	status.Status = valueOr(release.Status, status.Status)
+	status.Version = release.ChartVersion
+	status.AppVersion = release.AppVersion
+	status.Revision = release.Revision
+	status.Images = release.Images
+}
+
+// localChartVersion returns the version and appVersion declared in a local chart's Chart.yaml
+func localChartVersion(path string) (string, string) {
+	info, err := helm.LocalChart(path)
+	if err != nil || info.Version == "" {
+		return "unknown", ""
+	}
+	return info.Version, info.AppVersion
+}
+
+// maxParallel returns the concurrency limit for Helm operations within a tier. Only
+// --max-parallel may exceed the Helm workers the host's CPUs and memory support.
+func (m *DeploymentManager) maxParallel() int {
+	if deployMaxParallel > 0 {
+		return deployMaxParallel
+	}
+	maxParallel := defaultMaxParallelCharts
+	if m.config.Helm.MaxParallel > 0 {
+		maxParallel = m.config.Helm.MaxParallel
+	}
+	return min(maxParallel, tuning.Current().HelmWorkers)
+}
+
+// recordChartFailure classifies a failed chart as a hook or release failure and reports it
+func (m *DeploymentManager) recordChartFailure(k8sMgr *k8s.Manager, chart config.DeployChart, started time.Time, hookResults []ChartHookResult, deployErr error) error {
+	status := ChartDeploymentStatus{
+		Name:        chart.Name,
+		Namespace:   chart.Namespace,
+		Status:      "failed",
+		Order:       chart.Order,
+		ChartHooks:  hookResults,
+		FailureKind: "release",
+		Error:       deployErr.Error(),
+	}
+	if tail := logtail.Of(deployErr); tail != nil {
+		status.LogTail = tail.LogTail
+	}
+
+	if k8sMgr != nil {
+		hooks, err := k8sMgr.FailedHooks(chart.Namespace, chart.Name, started, hookLogTailLines)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("chart", chart.Name).Msg("Failed to inspect Helm hook jobs")
+		}
+		if len(hooks) > 0 {
+			status.FailureKind = "hook"
+			status.Hooks = hooks
+		}
+	}
+
+	m.mutex.Lock()
+	m.deployedCharts = append(m.deployedCharts, status)
+	m.mutex.Unlock()
+
+	if status.FailureKind == "hook" {
+		hook := status.Hooks[0]
+		return fmt.Errorf("chart %s: %s hook job %s failed (%s): %w", chart.Name, hook.Hook, hook.Name, hook.Reason, deployErr)
+	}
+	return fmt.Errorf("chart %s: release failed: %w", chart.Name, deployErr)
+}
+
+// recordChartHookFailure reports a chart whose own pre or post deploy hook failed
+func (m *DeploymentManager) recordChartHookFailure(chart config.DeployChart, kind string, hookResults []ChartHookResult, hookErr error) error {
+	status := ChartDeploymentStatus{
+		Name:        chart.Name,
+		Namespace:   chart.Namespace,
+		Status:      "failed",
+		Order:       chart.Order,
+		ChartHooks:  hookResults,
+		FailureKind: kind,
+		Error:       hookErr.Error(),
+	}
+
+	m.mutex.Lock()
+	m.deployedCharts = append(m.deployedCharts, status)
+	m.mutex.Unlock()
+
+	return fmt.Errorf("chart %s: %w", chart.Name, hookErr)
+}
+
+// printChartFailure shows the failed hook, Helm hook Jobs included, with its last log lines
+func printChartFailure(status ChartDeploymentStatus) {
+	if status.FailureKind == "pre-hook" || status.FailureKind == "post-hook" {
+		pterm.Error.Printf("Chart %s/%s %s failed: %s\n", status.Namespace, status.Name, status.FailureKind, status.Error)
+		if last := status.ChartHooks[len(status.ChartHooks)-1]; len(last.LogTail) > 0 {
+			pterm.DefaultBox.WithTitle(fmt.Sprintf("Last %d output lines of hook %s", len(last.LogTail), last.Name)).
+				Println(strings.Join(last.LogTail, "\n"))
+		}
+		return
+	}
+
+	if status.FailureKind != "hook" {
+		pterm.Error.Printf("Release %s/%s failed: %s\n", status.Namespace, status.Name, status.Error)
+		return
+	}
+
+	pterm.Error.Printf("Helm hook failed for release %s/%s\n", status.Namespace, status.Name)
+
+	data := [][]string{{"Job", "Hook", "Failed Pods", "Reason", "Message"}}
+	for _, hook := range status.Hooks {
+		data = append(data, []string{hook.Name, hook.Hook, fmt.Sprintf("%d", hook.Failed), hook.Reason, hook.Message})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+
+	for _, hook := range status.Hooks {
+		if len(hook.LogTail) == 0 {
+			continue
+		}
+		pterm.DefaultBox.WithTitle(fmt.Sprintf("Last %d log lines of job %s", len(hook.LogTail), hook.Name)).
+			Println(strings.Join(hook.LogTail, "\n"))
+	}
+}
+
 // PerformHealthChecks performs health checks on deployed applications
 func (m *DeploymentManager) PerformHealthChecks() error {
 	if deploySkipHealthCheck {
@@ -591,6 +1648,155 @@ func (m *DeploymentManager) PerformHealthChecks() error {
 	return nil
 }
 
+// PruneReleases uninstalls installer-managed releases whose chart is no longer configured.
+// Only namespaces the configuration deploys to are inspected.
+func (m *DeploymentManager) PruneReleases() error {
+	if !deployPrune {
+		return nil
+	}
+
+	configured := make(map[string]bool)
+	namespaces := map[string]bool{m.namespace: true}
+	for _, chart := range m.configuredCharts() {
+		configured[chart.Namespace+"/"+chart.Name] = true
+		namespaces[chart.Namespace] = true
+	}
+
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	var orphans []helm.Release
+	for namespace := range namespaces {
+		releases, err := helmMgr.ListManaged(namespace)
+		if err != nil {
+			return fmt.Errorf("failed to list releases in %s: %w", namespace, err)
+		}
+		for _, release := range releases {
+			if !configured[release.Namespace+"/"+release.Name] {
+				orphans = append(orphans, release)
+			}
+		}
+	}
+
+	if len(orphans) == 0 {
+		m.logger.Info().Msg("No orphaned releases to prune")
+		return nil
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Namespace+"/"+orphans[i].Name < orphans[j].Namespace+"/"+orphans[j].Name
+	})
+
+	data := [][]string{{"Release", "Namespace", "Chart", "Status", "Updated"}}
+	for _, release := range orphans {
+		data = append(data, []string{release.Name, release.Namespace, release.Chart, release.Status, release.Updated})
+	}
+	if deployDryRun {
+		pterm.Info.Println("DRY RUN: releases that would be pruned")
+	} else {
+		pterm.Warning.Println("Pruning releases no longer in the configuration")
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+
+	if deployDryRun {
+		return nil
+	}
+
+	for _, release := range orphans {
+		if err := helmMgr.Uninstall(release.Name, release.Namespace, m.helmTimeout); err != nil {
+			return fmt.Errorf("failed to prune release %s/%s: %w", release.Namespace, release.Name, err)
+		}
+		m.logger.Info().Str("release", release.Name).Str("namespace", release.Namespace).Msg("Release pruned")
+	}
+	return nil
+}
+
+// RunReleaseTests runs helm test for each deployed release. Failures of optional
+// charts are reported as warnings; any other failure fails the deployment.
+func (m *DeploymentManager) RunReleaseTests() error {
+	if !deployRunTests && !m.config.Helm.RunTests {
+		m.logger.Info().Msg("Release tests not enabled")
+		return nil
+	}
+
+	if deployDryRun {
+		m.logger.Info().Int("releases", len(m.deployedCharts)).Msg("DRY RUN: helm test would run for each release")
+		return nil
+	}
+
+	timeout := deployTestTimeout
+	if timeout == 0 {
+		timeout = defaultReleaseTestTimeout
+		if parsed, err := time.ParseDuration(m.config.Helm.TestTimeout); err == nil {
+			timeout = parsed
+		}
+	}
+
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	optional := make(map[string]bool)
+	for _, chart := range m.getChartsToDeployment() {
+		optional[chart.Name] = chart.Optional
+	}
+
+	pm := progress.GetProgressManager()
+	var failed []string
+
+	for i := range m.deployedCharts {
+		chart := &m.deployedCharts[i]
+		if chart.Status != "deployed" {
+			continue
+		}
+
+		pm.AddSubStep("helm-test", chart.Name, fmt.Sprintf("Testing %s", chart.Name), 10)
+
+		result, err := helmMgr.Test(chart.Name, chart.Namespace, timeout, hookLogTailLines)
+		chart.Test = result
+		if err == nil {
+			pm.UpdateSubStep("helm-test", chart.Name, 10, progress.StatusCompleted)
+			m.logger.Info().Str("chart", chart.Name).Int("suites", len(result.Suites)).Msg("Release tests passed")
+			continue
+		}
+
+		pm.UpdateSubStep("helm-test", chart.Name, 0, progress.StatusFailed)
+		printTestFailure(result)
+
+		if optional[chart.Name] {
+			m.logger.Warn().Err(err).Str("chart", chart.Name).Msg("Release tests failed for optional chart")
+			continue
+		}
+		failed = append(failed, chart.Name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("release tests failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// printTestFailure shows the test suites of a release and the tail of their pod logs
+func printTestFailure(result *helm.TestResult) {
+	pterm.Error.Printf("helm test failed for release %s/%s\n", result.Namespace, result.Release)
+
+	if len(result.Suites) > 0 {
+		data := [][]string{{"Test Suite", "Phase"}}
+		for _, suite := range result.Suites {
+			data = append(data, []string{suite.Name, suite.Phase})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	}
+
+	if len(result.LogTail) > 0 {
+		pterm.DefaultBox.WithTitle(fmt.Sprintf("Last %d lines of test logs", len(result.LogTail))).
+			Println(strings.Join(result.LogTail, "\n"))
+	}
+}
+
 // ValidateDeployment validates the overall deployment status
 func (m *DeploymentManager) ValidateDeployment() error {
 	m.logger.Info().Msg("Validating deployment status")
@@ -613,48 +1819,234 @@ func (m *DeploymentManager) ValidateDeployment() error {
 	return nil
 }
 
-// Rollback performs deployment rollback
+// ReleaseRollback is how one release of a failed deployment was reverted
+type ReleaseRollback struct {
+	Name      string
+	Namespace string
+	Order     int
+	Action    string // how the release is reverted, e.g. "uninstall"
+	Status    string // reverted, failed or not reached
+	Error     string `json:",omitempty"`
+}
+
+// Rollback reverts the releases this run installed or upgraded, highest order first: an
+// upgraded release returns to the revision it had before the run and a new one is
+// uninstalled. It stops at the first release that cannot be reverted, since the releases
+// below it may depend on it. The outcomes are journalled, so 'rollback' does not undo the
+// releases again.
 func (m *DeploymentManager) Rollback() error {
 	m.logger.Info().Msg("Performing deployment rollback")
 
-	// TODO: Implement deployment rollback
-	// This would typically involve:
-	// 1. Rolling back Helm releases in reverse order
-	// 2. Restoring previous configurations
-	// 3. Validating rollback success
-	// 4. Cleaning up failed resources
+	charts := make(map[string]config.DeployChart)
+	for _, chart := range m.getChartsToDeployment() {
+		charts[chart.Name] = chart
+	}
 
-	time.Sleep(2 * time.Second)
-	m.logger.Info().Msg("Deployment rollback completed")
+	stateFile := rollback.JournalFile()
+	actions, err := rollback.Load(stateFile)
+	if err != nil {
+		return err
+	}
+	var plan []config.RollbackAction
+	for _, action := range actions {
+		if action.Kind == rollback.KindHelmRelease && action.RunID == m.runID &&
+			(action.Status == rollback.StatusApplied || action.Status == rollback.StatusFailed) {
+			plan = append(plan, action)
+		}
+	}
+	sort.SliceStable(plan, func(i, j int) bool {
+		if order := charts[plan[i].Name].Order - charts[plan[j].Name].Order; order != 0 {
+			return order > 0
+		}
+		return plan[i].Sequence > plan[j].Sequence
+	})
+
+	// Atomic releases that failed were reverted by helm before the deployment stopped
+	m.rollbacks = nil
+	for _, status := range m.deployedCharts {
+		if status.Status == "failed" && (status.FailureKind == "release" || status.FailureKind == "hook") &&
+			m.chartReleaseOptions(charts[status.Name]).Atomic {
+			m.rollbacks = append(m.rollbacks, ReleaseRollback{Name: status.Name, Namespace: valueOr(status.Namespace, m.namespace),
+				Order: status.Order, Action: "helm --atomic", Status: rollback.StatusReverted})
+		}
+	}
+
+	var runErr error
+	if len(plan) > 0 {
+		helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+		if err != nil {
+			return fmt.Errorf("releases cannot be rolled back: %w", err)
+		}
+		engine := rollback.NewEngine(stateFile)
+		engine.Handle(rollback.KindHelmRelease, func(action config.RollbackAction) error {
+			if action.Revision == 0 {
+				return helmMgr.Uninstall(action.Name, action.Namespace, m.helmTimeout)
+			}
+			return helmMgr.Rollback(action.Name, action.Namespace, action.Revision, m.helmTimeout, m.helmWait)
+		})
+
+		reached := 0
+		runErr = engine.Run(plan, func(action config.RollbackAction) {
+			reached++
+			m.rollbacks = append(m.rollbacks, ReleaseRollback{Name: action.Name, Namespace: action.Namespace,
+				Order: charts[action.Name].Order, Action: rollbackDescription(action), Status: action.Status, Error: action.Error})
+		})
+		for _, action := range plan[reached:] {
+			m.rollbacks = append(m.rollbacks, ReleaseRollback{Name: action.Name, Namespace: action.Namespace,
+				Order: charts[action.Name].Order, Action: rollbackDescription(action), Status: "not reached"})
+		}
+	}
+
+	reverted := 0
+	for _, result := range m.rollbacks {
+		if result.Status == rollback.StatusReverted {
+			reverted++
+		}
+	}
+	audit.Record("deploy.rollback", m.namespace, map[string]interface{}{
+		"runId":    m.runID,
+		"releases": len(m.rollbacks),
+		"reverted": reverted,
+	}, runErr)
+	m.printRollbacks(reverted)
+	if runErr != nil {
+		return runErr
+	}
+	m.logger.Info().Int("reverted", reverted).Msg("Deployment rollback completed")
 	return nil
 }
 
+// printRollbacks shows how each release of the failed deployment was reverted
+func (m *DeploymentManager) printRollbacks(reverted int) {
+	if len(m.rollbacks) == 0 {
+		pterm.Info.Println("No release was changed by this deployment, nothing to roll back")
+		return
+	}
+	tableData := pterm.TableData{{"Release", "Namespace", "Order", "Rollback", "Status", "Error"}}
+	for _, result := range m.rollbacks {
+		status := result.Status
+		switch status {
+		case rollback.StatusReverted:
+			status = pterm.Green(status)
+		case rollback.StatusFailed:
+			status = pterm.Red(status)
+		}
+		tableData = append(tableData, []string{result.Name, result.Namespace, strconv.Itoa(result.Order),
+			result.Action, status, result.Error})
+	}
+	pterm.DefaultSection.Println("Rollback")
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Printf("Reverted %d of %d releases\n", reverted, len(m.rollbacks))
+}
+
 // GenerateReport generates deployment report
 func (m *DeploymentManager) GenerateReport() error {
-	reportPath := filepath.Join(".", "reports", "deployment-report.json")
+	reportPath := filepath.Join(reportsDir(), "deployment-report.json")
 
 	// Create reports directory
 	if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
 		return fmt.Errorf("failed to create reports directory: %w", err)
 	}
 
+	status := "success"
+	var failures []map[string]interface{}
+	for _, chart := range m.deployedCharts {
+		if chart.Status != "failed" {
+			continue
+		}
+		status = "failed"
+		failures = append(failures, map[string]interface{}{
+			"chart":        chart.Name,
+			"failure_kind": chart.FailureKind,
+			"error":        chart.Error,
+			"log_tail":     chart.LogTail,
+			"hooks":        chart.Hooks,
+		})
+	}
+
 	report := map[string]interface{}{
 		"timestamp":            time.Now().UTC().Format(time.RFC3339),
 		"namespace":            m.namespace,
 		"charts_deployed":      len(m.deployedCharts),
 		"health_checks_passed": m.healthChecksPassed,
 		"dry_run":              deployDryRun,
-		"status":               "success",
+		"status":               status,
 		"deployed_charts":      m.deployedCharts,
+		"release_notes":        m.releaseNotes,
+		"max_parallel":         m.maxParallel(),
+		"resources":            tuning.Current(),
+		"profile":              m.profile,
+		"failures":             failures,
+		"operations":           progress.GetProgressManager().Operations(),
+	}
+	if info := license.ReportInfo(); info != nil {
+		report["license"] = info
+	}
+	if len(m.rollbacks) > 0 {
+		report["rollback"] = m.rollbacks
+	}
+	if m.forecast != nil {
+		report["capacity"] = map[string]interface{}{
+			"forecast":    m.forecast,
+			"nodeScaling": m.scalePlan,
+		}
 	}
 
-	// TODO: Write actual report to file
+	if err := writeReport(reportPath, report); err != nil {
+		return err
+	}
 	m.logger.Info().Interface("report", report).Str("report_path", reportPath).Msg("Deployment report generated")
 	return nil
 }
 
+// CaptureSnapshot records the normalized specs of deployed resources for this run
+func (m *DeploymentManager) CaptureSnapshot(dir string) (string, error) {
+	snap := snapshot.New(m.runID)
+
+	chartValues := make(map[string]config.DeployChart)
+	for _, chart := range m.config.Helm.Charts {
+		chartValues[chart.Name] = chart
+	}
+
+	// Snapshots are kept in plain files, so keep credentials out of them
+	secrets := params.GetStore().SensitiveValues()
+
+	for _, chart := range m.deployedCharts {
+		snap.AddRelease(snapshot.ReleaseSnapshot{
+			Name:      chart.Name,
+			Namespace: chart.Namespace,
+			Chart:     chartValues[chart.Name].Path,
+			Version:   chart.Version,
+			Values:    values.Redact(m.renderedValues[chart.Name], secrets),
+		})
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Cluster resources not captured in snapshot")
+	} else {
+		for _, namespace := range snap.Namespaces {
+			if err := snap.CaptureNamespace(k8sMgr, namespace); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	path, err := snap.Save(dir)
+	if err != nil {
+		return "", err
+	}
+
+	m.logger.Info().Str("run_id", m.runID).Str("path", path).Msg("Deployment snapshot captured")
+	return path, nil
+}
+
 // Helper methods
 
+func (m *DeploymentManager) GetRunID() string {
+	return m.runID
+}
+
 func (m *DeploymentManager) GetNamespace() string {
 	return m.namespace
 }
@@ -668,15 +2060,7 @@ func (m *DeploymentManager) GetHealthChecksPassed() int {
 }
 
 func (m *DeploymentManager) getChartsToDeployment() []config.DeployChart {
-	// Enhanced chart configuration with more realistic enterprise applications
-	charts := []config.DeployChart{
-		{Name: "postgresql-ha", Namespace: m.namespace, Order: 1},
-		{Name: "redis-cluster", Namespace: m.namespace, Order: 2},
-		{Name: "backend-api", Namespace: m.namespace, Order: 3},
-		{Name: "auth-service", Namespace: m.namespace, Order: 4},
-		{Name: "frontend-web", Namespace: m.namespace, Order: 5},
-		{Name: "monitoring-stack", Namespace: m.namespace, Order: 6},
-	}
+	charts := m.configuredCharts()
 
 	// Filter charts if charts-only is specified
 	if len(deployChartsOnly) > 0 {
@@ -697,10 +2081,39 @@ func (m *DeploymentManager) getChartsToDeployment() []config.DeployChart {
 	return charts
 }
 
-func (m *DeploymentManager) deployChart(chart config.DeployChart) error {
-	// Enhanced chart deployment simulation with realistic timing
-	time.Sleep(1500 * time.Millisecond) // Simulate more realistic deployment time
-	return nil
+// configuredCharts returns every configured chart in deployment order, regardless of
+// --charts-only. Charts without a namespace go to the deployment namespace.
+func (m *DeploymentManager) configuredCharts() []config.DeployChart {
+	charts := slices.Clone(m.config.Helm.Charts)
+	for i := range charts {
+		charts[i].Namespace = m.chartNamespace(charts[i])
+	}
+	sort.SliceStable(charts, func(i, j int) bool {
+		return charts[i].Order < charts[j].Order
+	})
+	return charts
+}
+
+// deployChart installs or upgrades a chart's release with its rendered values and its
+// effective wait, atomic and timeout settings, through the Helm SDK. Releases carry
+// helm.ManagedLabel so prune can find them later, and are post-rendered so the label policy
+// reaches every object in the chart.
+func (m *DeploymentManager) deployChart(helmMgr *helm.Manager, chart config.DeployChart) (*helm.ReleaseStatus, error) {
+	options := m.chartReleaseOptions(chart)
+	m.logger.Debug().Str("chart", chart.Name).Str("timeout", options.Timeout.String()).
+		Bool("wait", options.Wait).Bool("atomic", options.Atomic).Msg("Helm release")
+
+	// Give the release slightly longer than its own timeout so an atomic release can roll back
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout+time.Minute)
+	defer cancel()
+	return helmMgr.Deploy(ctx, chart.Name, chart.Path, m.chartNamespace(chart), m.renderedValues[chart.Name], helm.DeployOptions{
+		Timeout:       options.Timeout,
+		Wait:          options.Wait,
+		Atomic:        options.Atomic,
+		CleanupOnFail: options.CleanupOnFail,
+		Labels:        helm.ManagedLabels(),
+		PostRender:    releasePostRenderer(chart),
+	})
 }
 
 func (m *DeploymentManager) performChartHealthCheck(chart ChartDeploymentStatus) error {
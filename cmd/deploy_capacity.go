@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/clusterupgrade"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/pterm/pterm"
+)
+
+// defaultNodeCountVariable is the Terraform variable holding the node pool size
+const defaultNodeCountVariable = "node_count"
+
+// defaultNodeScalingTimeout bounds the wait for added nodes to become ready
+const defaultNodeScalingTimeout = 15 * time.Minute
+
+// NodeScalePlan is a node pool resize made for a deployment
+type NodeScalePlan struct {
+	NodePool string `json:"nodePool"`
+	Method   string `json:"method"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Variable string `json:"variable,omitempty"` // terraform method
+	Applied  bool   `json:"applied"`
+
+	selector map[string]string
+	perNode  k8s.Resources
+}
+
+// chartManifests renders every chart to deploy once and returns the manifests by chart name
+func (m *DeploymentManager) chartManifests() (map[string][]byte, error) {
+	if m.manifests != nil {
+		return m.manifests, nil
+	}
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+	manifests := make(map[string][]byte)
+	for _, chart := range m.getChartsToDeployment() {
+		rendered, err := m.renderChart(helmMgr, chart)
+		if err != nil {
+			return nil, err
+		}
+		manifests[chart.Name] = rendered
+	}
+	m.manifests = manifests
+	return manifests, nil
+}
+
+// CheckCapacity predicts whether the charts fit the free capacity of the cluster. When they
+// do not and infrastructure.nodeScaling is enabled, the node pool is scaled up first; the
+// forecast is advisory otherwise, since pods that do not fit only stay Pending.
+func (m *DeploymentManager) CheckCapacity() error {
+	manifests, err := m.chartManifests()
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Capacity forecast skipped, charts could not be rendered")
+		return nil
+	}
+	var workloads []k8s.Workload
+	for _, chart := range m.getChartsToDeployment() {
+		chartWorkloads, err := k8s.ManifestWorkloads(manifests[chart.Name], m.chartNamespace(chart))
+		if err != nil {
+			return fmt.Errorf("failed to read workloads of chart %s: %w", chart.Name, err)
+		}
+		workloads = append(workloads, chartWorkloads...)
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Capacity forecast skipped, kubectl unavailable")
+		return nil
+	}
+	forecast, err := k8sMgr.ForecastCapacity(workloads)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Capacity forecast skipped")
+		return nil
+	}
+	m.forecast = forecast
+	m.logger.Info().
+		Int("nodes", forecast.Nodes).
+		Str("required", forecast.Required.String()).
+		Str("free", forecast.Free.String()).
+		Msg("Capacity forecast")
+	if !forecast.Short() {
+		return nil
+	}
+
+	scaling := m.nodeScaling()
+	if scaling == nil {
+		pterm.Warning.Printf("The charts request %s more than the %s free on %d nodes; pods may stay Pending (enable infrastructure.nodeScaling to add nodes)\n",
+			forecast.Shortfall, forecast.Free, forecast.Nodes)
+		return nil
+	}
+
+	plan, err := m.planScaleUp(k8sMgr, forecast, *scaling)
+	if err != nil {
+		return err
+	}
+	m.scalePlan = plan
+	printScalePlan(plan, forecast, scaling.KeepScaled)
+
+	if deployDryRun {
+		m.logger.Info().Str("pool", plan.NodePool).Int("from", plan.From).Int("to", plan.To).Msg("DRY RUN: node pool would be scaled up")
+		return nil
+	}
+	if err := m.scaleNodePool(plan, plan.To); err != nil {
+		return err
+	}
+	plan.Applied = true
+
+	timeout := defaultNodeScalingTimeout
+	if parsed, err := time.ParseDuration(scaling.Timeout); err == nil {
+		timeout = parsed
+	}
+	if err := k8sMgr.WaitForPoolNodes(plan.selector, plan.To, timeout); err != nil {
+		return fmt.Errorf("node pool %s did not scale to %d nodes: %w", plan.NodePool, plan.To, err)
+	}
+	m.logger.Info().Str("pool", plan.NodePool).Int("nodes", plan.To).Msg("Node pool scaled up")
+	return nil
+}
+
+// nodeScaling returns the node pool scaling of the installer configuration, nil when disabled
+func (m *DeploymentManager) nodeScaling() *config.NodePoolScaling {
+	if m.installer == nil || !m.installer.Infrastructure.NodeScaling.Enabled {
+		return nil
+	}
+	return &m.installer.Infrastructure.NodeScaling
+}
+
+// planScaleUp sizes the node pool to cover the forecast shortfall, up to maxNodes
+func (m *DeploymentManager) planScaleUp(k8sMgr *k8s.Manager, forecast *k8s.CapacityForecast, scaling config.NodePoolScaling) (*NodeScalePlan, error) {
+	cfg := m.installer
+	plan := &NodeScalePlan{
+		NodePool: scaling.NodePool,
+		Method:   scaling.Method,
+		selector: scaling.NodeSelector,
+	}
+	if plan.Method == "" {
+		plan.Method = clusterupgrade.MethodCLI
+		if cfg.Infrastructure.Terraform.Enabled {
+			plan.Method = clusterupgrade.MethodTerraform
+		}
+	}
+	if plan.Method == clusterupgrade.MethodCLI && plan.NodePool == "" {
+		return nil, fmt.Errorf("infrastructure.nodeScaling.nodePool is required to scale with the cli method")
+	}
+	if plan.selector == nil {
+		plan.selector = k8s.NodePoolSelector(cfg.Cloud.Provider, plan.NodePool)
+	}
+
+	ready, perNode, err := k8sMgr.PoolNodes(plan.selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node pool %s: %w", plan.NodePool, err)
+	}
+	if ready == 0 {
+		return nil, fmt.Errorf("no ready nodes match the node pool %s, so its node size is unknown", plan.NodePool)
+	}
+	plan.From, plan.perNode = ready, perNode
+
+	if plan.Method == clusterupgrade.MethodTerraform {
+		plan.Variable = scaling.CountVariable
+		if plan.Variable == "" {
+			plan.Variable = defaultNodeCountVariable
+		}
+		if value, ok := cfg.Infrastructure.Terraform.Variables[plan.Variable]; ok {
+			if count, err := strconv.Atoi(value); err == nil {
+				plan.From = count
+			}
+		}
+	}
+
+	plan.To = plan.From + forecast.NodesToAdd(perNode)
+	if scaling.MaxNodes > 0 && plan.To > scaling.MaxNodes {
+		pterm.Warning.Printf("Node pool %s needs %d nodes but infrastructure.nodeScaling.maxNodes is %d\n", plan.NodePool, plan.To, scaling.MaxNodes)
+		plan.To = scaling.MaxNodes
+	}
+	if plan.To <= plan.From {
+		return nil, fmt.Errorf("node pool %s is already at infrastructure.nodeScaling.maxNodes (%d) and the charts need %s more", plan.NodePool, scaling.MaxNodes, forecast.Shortfall)
+	}
+	return plan, nil
+}
+
+// scaleNodePool resizes the node pool of plan to count nodes
+func (m *DeploymentManager) scaleNodePool(plan *NodeScalePlan, count int) error {
+	cfg := m.installer
+	if plan.Method == clusterupgrade.MethodCLI {
+		mgr, err := clusterupgrade.NewManager(cfg.Infrastructure.Upgrade, cfg.Cloud)
+		if err != nil {
+			return err
+		}
+		return mgr.ScaleNodePool(plan.NodePool, count)
+	}
+
+	infraCfg := cfg.Infrastructure
+	variables := make(map[string]string, len(infraCfg.Terraform.Variables)+1)
+	for name, value := range infraCfg.Terraform.Variables {
+		variables[name] = value
+	}
+	variables[plan.Variable] = strconv.Itoa(count)
+	infraCfg.Terraform.Variables = variables
+
+	tfMgr, err := terraform.NewManager(&infraCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create terraform manager: %w", err)
+	}
+	tfMgr.SetStateBackupDir(terraformBackupDir(cfg.Installer.Workspace))
+	if err := tfMgr.Init(); err != nil {
+		return err
+	}
+	if _, err := tfMgr.Plan(false); err != nil {
+		return err
+	}
+	if err := tfMgr.Apply(false); err != nil {
+		return fmt.Errorf("failed to scale node pool with %s=%d: %w", plan.Variable, count, err)
+	}
+	return nil
+}
+
+// ScaleBack removes the nodes CheckCapacity added once the deployed workloads no longer need
+// them, keeping as many as the free capacity cannot do without
+func (m *DeploymentManager) ScaleBack() {
+	plan := m.scalePlan
+	if plan == nil || !plan.Applied {
+		return
+	}
+	if scaling := m.nodeScaling(); scaling != nil && scaling.KeepScaled {
+		pterm.Info.Printf("Keeping node pool %s at %d nodes (infrastructure.nodeScaling.keepScaled)\n", plan.NodePool, plan.To)
+		return
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Node pool not scaled back")
+		return
+	}
+	forecast, err := k8sMgr.ForecastCapacity(nil)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Node pool not scaled back")
+		return
+	}
+	removable := int(math.Min(forecast.Free.CPU/plan.perNode.CPU, forecast.Free.Memory/plan.perNode.Memory))
+	target := plan.To - min(max(removable, 0), plan.To-plan.From)
+	if target == plan.To {
+		pterm.Warning.Printf("Keeping node pool %s at %d nodes: the deployed workloads use the added capacity\n", plan.NodePool, plan.To)
+		return
+	}
+
+	if err := m.scaleNodePool(plan, target); err != nil {
+		m.logger.Warn().Err(err).Str("pool", plan.NodePool).Msg("Failed to scale the node pool back")
+		pterm.Warning.Printf("Node pool %s is still at %d nodes: %v\n", plan.NodePool, plan.To, err)
+		return
+	}
+	pterm.Success.Printf("Node pool %s scaled back from %d to %d nodes\n", plan.NodePool, plan.To, target)
+	if plan.Method == clusterupgrade.MethodTerraform && target != plan.From {
+		pterm.Info.Printf("Set infrastructure.terraform.variables.%s to %d in the configuration so later applies keep it\n", plan.Variable, target)
+	}
+}
+
+// printScalePlan shows the forecast shortfall and the node pool resize covering it
+func printScalePlan(plan *NodeScalePlan, forecast *k8s.CapacityForecast, keepScaled bool) {
+	pterm.DefaultSection.Println("Node Pool Scaling")
+	change := fmt.Sprintf("%d → %d nodes", plan.From, plan.To)
+	if plan.Variable != "" {
+		change += fmt.Sprintf(" (%s)", plan.Variable)
+	}
+	scaleBack := "after deployment, nodes no longer needed"
+	if keepScaled {
+		scaleBack = "no (keepScaled)"
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Node Pool", "Method", "Change", "Shortfall", "Scale Back"},
+		{valueOr(plan.NodePool, "-"), plan.Method, change, forecast.Shortfall.String(), scaleBack},
+	}).Render()
+}
@@ -1,33 +1,113 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
 )
 
+// loadDeployConfig loads the deployment section of the installer configuration, or of the
+// sample configuration without a file. Chart paths are resolved to the charts package-pull synced
+// into the workspace where they are found there; checkChartPaths reports the others.
 func loadDeployConfig(configPath string) (*config.DeploymentConfig, error) {
-	config := &config.DeploymentConfig{
-		Helm: config.HelmDeployment{
-			Charts: []config.DeployChart{
-				{Name: "backend", Path: "./charts/backend", Namespace: "app", Order: 1},
-				{Name: "frontend", Path: "./charts/frontend", Namespace: "app", Order: 2},
-			},
-			CreateNamespace: true,
-			Wait:            true,
-			Timeout:         "10m",
-			Atomic:          true,
-			CleanupOnFail:   true,
-		},
-		Kubernetes: config.K8sConfig{
-			Namespace:   "default",
-			Timeout:     "5m",
-			WaitTimeout: "10m",
-		},
-		Validation: config.DeployValidation{
-			PodHealth:     true,
-			ServiceHealth: true,
-			Timeout:       "5m",
-			RetryInterval: "30s",
-		},
-	}
-	return config, nil
+	installerCfg, err := loadInstallConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWorkspace(installerCfg); err != nil {
+		return nil, err
+	}
+
+	deployment := installerCfg.Deployment
+	if len(deployment.Helm.Charts) == 0 {
+		return nil, fmt.Errorf("no charts configured in deployment.helm.charts")
+	}
+
+	chartsDir := filepath.Join(installerCfg.Installer.Workspace, "artifacts", "helm")
+	baseDir := "."
+	if configPath != "" {
+		baseDir = filepath.Dir(configPath)
+	}
+	names := make(map[string]bool, len(deployment.Helm.Charts))
+	for i := range deployment.Helm.Charts {
+		chart := &deployment.Helm.Charts[i]
+		if names[chart.Name] {
+			return nil, fmt.Errorf("chart %s is configured more than once", chart.Name)
+		}
+		names[chart.Name] = true
+		chart.Path = resolveChartPath(chart.Path, chartsDir, baseDir)
+		chart.ReleaseNotes = resolveReleaseNotesPath(chart.ReleaseNotes, chartsDir, baseDir)
+	}
+	return &deployment, nil
+}
+
+// resolveChartPath finds a chart in the synced charts, or next to the configuration file,
+// returning the path unchanged when it is in neither
+func resolveChartPath(path, chartsDir, baseDir string) string {
+	if chartURL(path) || filepath.IsAbs(path) {
+		return path
+	}
+	for _, candidate := range []string{filepath.Join(chartsDir, path), filepath.Join(baseDir, path)} {
+		if isChart(candidate) {
+			return candidate
+		}
+	}
+	return path
+}
+
+// checkChartPaths fails on the first chart whose path holds no chart. Charts in OCI
+// registries and chart repositories are left to Helm to fetch.
+func checkChartPaths(charts []config.DeployChart) error {
+	for _, chart := range charts {
+		if !remoteChart(chart.Path) && !isChart(chart.Path) {
+			return fmt.Errorf("chart %s: no chart at %s, sync the charts with package-pull first", chart.Name, chart.Path)
+		}
+	}
+	return nil
+}
+
+// remoteChart reports whether path is a chart Helm fetches: an OCI or chart URL, or a
+// repo/chart reference to a chart repository Helm has configured that names nothing on disk
+func remoteChart(path string) bool {
+	return chartURL(path) || repositoryChart(path)
+}
+
+func chartURL(path string) bool {
+	for _, prefix := range []string{"oci://", "http://", "https://"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func repositoryChart(path string) bool {
+	repository, name, ok := strings.Cut(path, "/")
+	if !ok || repository == "" || repository == "." || repository == ".." || name == "" || strings.Contains(name, "/") {
+		return false
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return false
+	}
+	// Otherwise a chart missing from a relative directory, e.g. charts/api, would pass the check
+	repositories, err := repo.LoadFile(cli.New().RepositoryConfig)
+	return err == nil && repositories.Has(repository)
+}
+
+// isChart reports whether path is a chart directory or a packaged chart
+func isChart(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return strings.HasSuffix(path, ".tgz")
+	}
+	_, err = os.Stat(filepath.Join(path, "Chart.yaml"))
+	return err == nil
 }
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/snapshot"
+	"github.com/pterm/pterm"
+)
+
+// RollbackResult is the outcome of deploy --rollback-to, written to the rollback report
+type RollbackResult struct {
+	Chart     string `json:"chart"`
+	Namespace string `json:"namespace"`
+	From      int    `json:"fromRevision"`
+	To        int    `json:"toRevision"`
+	Revision  int    `json:"revision,omitempty"` // the new revision the rollback created
+	DryRun    bool   `json:"dryRun,omitempty"`
+	Status    string `json:"status"`
+	Duration  string `json:"duration"`
+	Error     string `json:"error,omitempty"`
+}
+
+// configuredChart returns the configured chart of a name, with its namespace resolved
+func (m *DeploymentManager) configuredChart(name string) (config.DeployChart, error) {
+	for _, chart := range m.configuredCharts() {
+		if chart.Name == name {
+			return chart, nil
+		}
+	}
+	return config.DeployChart{}, fmt.Errorf("chart %s is not configured", name)
+}
+
+// PrintHistory lists the revisions of a chart's release with the values each one changed
+// from the revision before it
+func (m *DeploymentManager) PrintHistory(chartName string) error {
+	chart, err := m.configuredChart(chartName)
+	if err != nil {
+		return err
+	}
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+	revisions, err := helmMgr.History(chart.Name, chart.Namespace)
+	if err != nil {
+		return err
+	}
+
+	pterm.DefaultSection.Printf("%s (%s)\n", chart.Name, chart.Namespace)
+	tableData := [][]string{{"Revision", "Updated", "Status", "Chart", "App Version", "Description"}}
+	for _, revision := range revisions {
+		tableData = append(tableData, []string{strconv.Itoa(revision.Revision), revision.Updated, revision.Status,
+			revision.Chart, revision.AppVersion, revision.Description})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	var previous map[string]interface{}
+	for i, revision := range revisions {
+		current, err := helmMgr.Values(chart.Name, chart.Namespace, revision.Revision)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			printValuesChanges(revisions[i-1].Revision, revision.Revision, previous, current)
+		}
+		previous = current
+	}
+	return nil
+}
+
+// printValuesChanges prints how the user-supplied values changed between two revisions
+func printValuesChanges(from, to int, oldValues, newValues map[string]interface{}) {
+	changes := snapshot.DiffValues(fmt.Sprintf("revision/%d", to), oldValues, newValues)
+	if len(changes) == 0 {
+		pterm.Info.Printf("Revision %d → %d: values unchanged\n", from, to)
+		return
+	}
+	pterm.Info.Printf("Revision %d → %d: %d values changed\n", from, to, len(changes))
+	tableData := [][]string{{"Change", "Field", "Before", "After"}}
+	for _, change := range changes {
+		tableData = append(tableData, []string{string(change.Type), change.Field, change.OldValue, change.NewValue})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// parseRollbackTarget splits a --rollback-to value of the form chart=revision
+func parseRollbackTarget(spec string) (string, int, error) {
+	name, number, ok := strings.Cut(spec, "=")
+	revision, err := strconv.Atoi(strings.TrimSpace(number))
+	if !ok || strings.TrimSpace(name) == "" || err != nil || revision < 1 {
+		return "", 0, fmt.Errorf("invalid --rollback-to %q, expected <chart>=<revision>", spec)
+	}
+	return strings.TrimSpace(name), revision, nil
+}
+
+// RollbackTo rolls a chart's release back to a revision of its history, tracked as a progress
+// operation and recorded in the rollback report
+func (m *DeploymentManager) RollbackTo(spec string) error {
+	name, target, err := parseRollbackTarget(spec)
+	if err != nil {
+		return err
+	}
+	chart, err := m.configuredChart(name)
+	if err != nil {
+		return err
+	}
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+	revisions, err := helmMgr.History(chart.Name, chart.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("release %s/%s has no revisions", chart.Namespace, chart.Name)
+	}
+	current := revisions[len(revisions)-1].Revision
+	found := false
+	for _, revision := range revisions {
+		found = found || revision.Revision == target
+	}
+	if !found {
+		return fmt.Errorf("release %s/%s has no revision %d", chart.Namespace, chart.Name, target)
+	}
+
+	result := RollbackResult{Chart: chart.Name, Namespace: chart.Namespace, From: current, To: target, DryRun: deployDryRun}
+	pm := progress.GetProgressManager()
+	pm.StartOperation("rollback", fmt.Sprintf("Rolling back %s", chart.Name),
+		fmt.Sprintf("Revision %d → %d in %s", current, target, chart.Namespace), 1)
+	started := time.Now()
+
+	if deployDryRun {
+		pm.CompleteOperation("rollback", progress.StatusCompleted, fmt.Sprintf("DRY RUN: would roll back to revision %d", target))
+		result.Status = "planned"
+	} else if err = helmMgr.Rollback(chart.Name, chart.Namespace, target, m.helmTimeout, deployWait); err != nil {
+		pm.CompleteOperation("rollback", progress.StatusFailed, err.Error())
+		result.Status = "failed"
+		result.Error = err.Error()
+	} else {
+		pm.CompleteOperation("rollback", progress.StatusCompleted, fmt.Sprintf("Rolled back to revision %d", target))
+		result.Status = "rolled-back"
+		if status, statusErr := helmMgr.Status(chart.Name, chart.Namespace); statusErr == nil {
+			result.Revision = status.Revision
+		}
+	}
+	result.Duration = progress.FormatDuration(time.Since(started))
+
+	m.logger.Info().
+		Str("chart", chart.Name).
+		Int("from", current).
+		Int("to", target).
+		Str("status", result.Status).
+		Msg("Release rollback")
+	if reportErr := writeRollbackReport(result); reportErr != nil {
+		m.logger.Warn().Err(reportErr).Msg("Failed to write rollback report")
+	}
+	if err != nil {
+		return fmt.Errorf("rollback of %s to revision %d failed: %w", chart.Name, target, err)
+	}
+	if !deployDryRun {
+		pterm.Success.Printf("%s rolled back from revision %d to %d (now revision %d)\n", chart.Name, current, target, result.Revision)
+	}
+	return nil
+}
+
+func writeRollbackReport(result RollbackResult) error {
+	reportPath := filepath.Join(reportsDir(), "rollback-report.json")
+	if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"rollback":  result,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath, data, 0644)
+}
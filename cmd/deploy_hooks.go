@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+// defaultChartHookTimeout applies to chart hooks that set no timeout
+const defaultChartHookTimeout = 5 * time.Minute
+
+// ChartHookResult records the outcome of a per-chart pre or post deploy hook
+type ChartHookResult struct {
+	Name     string
+	Phase    string
+	Type     string
+	Status   string // succeeded, failed or warned
+	Duration string
+	Error    string   `json:",omitempty"`
+	LogTail  []string `json:",omitempty"`
+}
+
+var invalidJobNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// runChartHooks runs the chart's hooks for a phase in declaration order.
+// A failing hook with the warn policy is recorded and the remaining hooks still run.
+func (m *DeploymentManager) runChartHooks(k8sMgr *k8s.Manager, chart config.DeployChart, phase string) ([]ChartHookResult, error) {
+	var results []ChartHookResult
+
+	for _, hook := range chart.Hooks {
+		if hook.Phase != phase {
+			continue
+		}
+
+		timeout := defaultChartHookTimeout
+		if hook.Timeout != "" {
+			if parsed, err := time.ParseDuration(hook.Timeout); err == nil {
+				timeout = parsed
+			}
+		}
+
+		m.logger.Info().
+			Str("chart", chart.Name).
+			Str("hook", hook.Name).
+			Str("phase", phase).
+			Str("type", hook.Type).
+			Msg("Running chart hook")
+
+		start := time.Now()
+		var logTail []string
+		var err error
+		switch hook.Type {
+		case "job":
+			logTail, err = runJobHook(k8sMgr, chart, hook, timeout)
+		default:
+			logTail, err = runScriptHook(chart, hook, timeout)
+		}
+
+		result := ChartHookResult{
+			Name:     hook.Name,
+			Phase:    phase,
+			Type:     hook.Type,
+			Status:   "succeeded",
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+			LogTail:  logTail,
+		}
+
+		audit.Record("chart.hook", chart.Namespace+"/"+chart.Name, map[string]interface{}{
+			"hook":  hook.Name,
+			"phase": phase,
+			"type":  hook.Type,
+			"runId": m.runID,
+		}, err)
+
+		if err != nil {
+			result.Error = err.Error()
+			if hook.FailurePolicy == "warn" {
+				result.Status = "warned"
+				results = append(results, result)
+				m.logger.Warn().Err(err).Str("chart", chart.Name).Str("hook", hook.Name).Msg("Chart hook failed, continuing")
+				continue
+			}
+			result.Status = "failed"
+			results = append(results, result)
+			return results, fmt.Errorf("%s-deploy hook %s failed: %w", phase, hook.Name, err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// runScriptHook runs a local command with the chart and shared parameters in its environment
+func runScriptHook(chart config.DeployChart, hook config.ChartHook, timeout time.Duration) ([]string, error) {
+	cmd := execx.Command(hook.Command[0], hook.Command[1:]...)
+	cmd.Dir = hook.WorkingDir
+	cmd.Timeout = timeout
+	cmd.Tag = "hook " + hook.Name
+	cmd.Secrets = params.GetStore().SensitiveValues()
+	for key, value := range hookEnv(chart, hook, true) {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	sort.Strings(cmd.Env)
+
+	output, err := cmd.Run(context.Background())
+	return tailLines(execx.Mask(string(output), cmd.Secrets), hookLogTailLines), err
+}
+
+// runJobHook runs the hook as a Job in the cluster and waits for it to finish
+func runJobHook(k8sMgr *k8s.Manager, chart config.DeployChart, hook config.ChartHook, timeout time.Duration) ([]string, error) {
+	if k8sMgr == nil {
+		return nil, fmt.Errorf("kubectl is required to run job hooks")
+	}
+
+	namespace := hook.Namespace
+	if namespace == "" {
+		namespace = chart.Namespace
+	}
+
+	name := invalidJobNameChars.ReplaceAllString(strings.ToLower(chart.Name+"-"+hook.Phase+"-"+hook.Name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	name = strings.Trim(name, "-")
+
+	job, err := k8sMgr.RunJob(k8s.JobSpec{
+		Name:           name,
+		Namespace:      namespace,
+		Image:          hook.Image,
+		Command:        hook.Command,
+		Env:            hookEnv(chart, hook, false),
+		ServiceAccount: hook.ServiceAccount,
+		Labels: map[string]string{
+			"app.kubernetes.io/instance": chart.Name,
+			"e2e-k8s-installer/hook":     hook.Name,
+		},
+	}, timeout, hookLogTailLines)
+	if job == nil {
+		return nil, err
+	}
+	return job.LogTail, err
+}
+
+// hookEnv returns the variables passed to hooks: shared parameters, chart details, then the hook's own env.
+// Job specs are readable in the cluster, so parameters (which may hold credentials) only go to local scripts.
+func hookEnv(chart config.DeployChart, hook config.ChartHook, withParams bool) map[string]string {
+	env := make(map[string]string)
+	if withParams {
+		env = params.GetStore().Environ()
+	}
+	env["CHART_NAME"] = chart.Name
+	env["CHART_NAMESPACE"] = chart.Namespace
+	env["CHART_PATH"] = chart.Path
+	env["HOOK_PHASE"] = hook.Phase
+	for key, value := range hook.Env {
+		env[key] = value
+	}
+	return env
+}
+
+func tailLines(text string, n int) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/releasenotes"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// CollectReleaseNotes reads the changelogs of the charts for the versions being installed or
+// upgraded to, and stops upgrades with unacknowledged breaking changes when
+// deployment.helm.requireBreakingAck is set
+func (m *DeploymentManager) CollectReleaseNotes() error {
+	// Installed versions are read through helm; without it every chart counts as a new install
+	helmMgr, err := helm.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Installed versions unknown, release notes cover the new versions only")
+		helmMgr = nil
+	}
+
+	m.releaseNotes = nil
+	for _, chart := range m.getChartsToDeployment() {
+		m.releaseNotes = append(m.releaseNotes, m.chartReleaseNotes(helmMgr, chart)...)
+	}
+	for _, notes := range m.releaseNotes {
+		printReleaseNotes(notes)
+	}
+	return m.checkBreakingChanges()
+}
+
+// chartReleaseNotes returns the notes of the chart's own changelog, matched against chart
+// versions, and of its configured releaseNotes file, matched against app versions
+func (m *DeploymentManager) chartReleaseNotes(helmMgr *helm.Manager, chart config.DeployChart) []releasenotes.Notes {
+	info, err := helm.LocalChart(chart.Path)
+	if err != nil {
+		m.logger.Debug().Err(err).Str("chart", chart.Name).Msg("No local chart to read release notes of")
+		return nil
+	}
+	var installed helm.ReleaseStatus
+	if helmMgr != nil {
+		if status, err := helmMgr.Status(chart.Name, chart.Namespace); err == nil {
+			installed = *status
+		}
+	}
+
+	sources := []struct{ path, from, to string }{
+		{releasenotes.Find(chart.Path), installed.ChartVersion, info.Version},
+		{chart.ReleaseNotes, installed.AppVersion, info.AppVersion},
+	}
+	var all []releasenotes.Notes
+	for _, source := range sources {
+		if source.path == "" || source.to == "" || source.from == source.to {
+			continue
+		}
+		notes, err := releasenotes.Load(chart.Name, source.path, source.from, source.to)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("chart", chart.Name).Msg("Release notes not read")
+			continue
+		}
+		if len(notes.Entries) > 0 {
+			all = append(all, *notes)
+		}
+	}
+	return all
+}
+
+func printReleaseNotes(notes releasenotes.Notes) {
+	title := fmt.Sprintf("%s %s", notes.Chart, notes.To)
+	if notes.From != "" {
+		title = fmt.Sprintf("%s %s → %s", notes.Chart, notes.From, notes.To)
+	}
+	pterm.DefaultSection.Printf("Release notes: %s\n", title)
+	versions := make([]string, len(notes.Entries))
+	for i, entry := range notes.Entries {
+		versions[i] = entry.Version
+	}
+	pterm.Info.Printf("%d versions in %s: %v\n", len(notes.Entries), notes.Source, versions)
+	for _, line := range notes.Breaking() {
+		pterm.Warning.Printf("Breaking change in %s\n", line)
+	}
+}
+
+// checkBreakingChanges requires every upgrade with breaking changes to be acknowledged, with
+// --ack-breaking naming the chart (or all) or at a prompt. New installs need no acknowledgment.
+func (m *DeploymentManager) checkBreakingChanges() error {
+	if !m.config.Helm.RequireBreakingAck {
+		return nil
+	}
+	for _, notes := range m.releaseNotes {
+		breaking := notes.Breaking()
+		if notes.From == "" || len(breaking) == 0 {
+			continue
+		}
+		acknowledgedBy := ""
+		switch {
+		case slices.Contains(deployAckBreaking, notes.Chart) || slices.Contains(deployAckBreaking, "all"):
+			acknowledgedBy = "flag"
+		case deployDryRun:
+			pterm.Warning.Printf("DRY RUN: %s would need its breaking changes acknowledged\n", notes.Chart)
+			continue
+		case term.IsTerminal(int(os.Stdin.Fd())):
+			confirmed, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(false).
+				Show(fmt.Sprintf("Upgrade %s from %s to %s despite %d breaking changes?", notes.Chart, notes.From, notes.To, len(breaking)))
+			if err != nil {
+				return fmt.Errorf("failed to read acknowledgment for %s: %w", notes.Chart, err)
+			}
+			if confirmed {
+				acknowledgedBy = "interactive"
+			}
+		}
+		if acknowledgedBy == "" {
+			return fmt.Errorf("upgrade of %s from %s to %s has %d unacknowledged breaking changes, review them and rerun with --ack-breaking %s",
+				notes.Chart, notes.From, notes.To, len(breaking), notes.Chart)
+		}
+		audit.Record("release-notes.acknowledge", notes.Chart, map[string]interface{}{
+			"from":     notes.From,
+			"to":       notes.To,
+			"breaking": breaking,
+			"by":       acknowledgedBy,
+			"runId":    m.runID,
+		}, nil)
+	}
+	return nil
+}
+
+// resolveReleaseNotesPath finds a configured release notes file in the synced charts, or next
+// to the configuration file, returning the path unchanged when it is in neither
+func resolveReleaseNotesPath(path, chartsDir, baseDir string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	for _, candidate := range []string{filepath.Join(chartsDir, path), filepath.Join(baseDir, path)} {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
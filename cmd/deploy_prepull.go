@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/pterm/pterm"
+)
+
+// defaultPrePullTimeout bounds the image pre-pull when helm.prePull.timeout is not set
+const defaultPrePullTimeout = 15 * time.Minute
+
+// prePullDaemonSet is the name of the temporary DaemonSet pulling the images
+const prePullDaemonSet = "e2e-installer-image-prepull"
+
+// PrePullImages pulls the images of every chart onto the nodes before any release is
+// installed. Images that cannot be pulled fail the step, since their releases would not
+// become ready; nodes still pulling at the timeout only delay the rollout.
+func (m *DeploymentManager) PrePullImages() error {
+	prePull := m.config.Helm.PrePull
+	if !deployPrePull && !prePull.Enabled {
+		m.logger.Info().Msg("Image pre-pull not enabled")
+		return nil
+	}
+
+	images, err := m.chartImages()
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		m.logger.Info().Msg("No images to pre-pull")
+		return nil
+	}
+
+	if deployDryRun {
+		m.logger.Info().Int("images", len(images)).Strs("images", images).Msg("DRY RUN: images would be pulled onto every node")
+		return nil
+	}
+
+	timeout := defaultPrePullTimeout
+	if parsed, err := time.ParseDuration(prePull.Timeout); err == nil {
+		timeout = parsed
+	}
+	namespace := prePull.Namespace
+	if namespace == "" {
+		namespace = m.namespace
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	pm := progress.GetProgressManager()
+	pm.SetMetadata("pre-pull-images", "images", len(images))
+	pm.SetMetadata("pre-pull-images", "namespace", namespace)
+	reported := make(map[string]int)
+	onProgress := func(nodes []k8s.NodePull) {
+		for _, node := range nodes {
+			last, seen := reported[node.Node]
+			if !seen {
+				pm.AddSubStep("pre-pull-images", node.Node, fmt.Sprintf("Pulling %d images on %s", node.Total, node.Node), node.Total)
+				pm.SetSubStepMetadata("pre-pull-images", node.Node, "images", node.Total)
+			}
+			if seen && last == node.Pulled && !node.Done() {
+				continue
+			}
+			reported[node.Node] = node.Pulled
+
+			status := progress.StatusRunning
+			switch {
+			case len(node.Failed) > 0 && node.Done():
+				status = progress.StatusFailed
+			case node.Done():
+				status = progress.StatusCompleted
+			}
+			if len(node.Failed) > 0 {
+				pm.SetSubStepMetadata("pre-pull-images", node.Node, "failed", len(node.Failed))
+			}
+			pm.UpdateSubStep("pre-pull-images", node.Node, node.Pulled, status)
+		}
+	}
+
+	m.logger.Info().Int("images", len(images)).Str("namespace", namespace).Msg("Pre-pulling images onto the nodes")
+	nodes, err := k8sMgr.PrePullImages(k8s.PrePullSpec{
+		Name:         prePullDaemonSet,
+		Namespace:    namespace,
+		Images:       images,
+		NodeSelector: prePull.NodeSelector,
+		PullSecrets:  prePull.PullSecrets,
+	}, timeout, onProgress)
+	if nodes == nil && err != nil {
+		return err
+	}
+
+	failed := make(map[string]string)
+	for _, node := range nodes {
+		for image, reason := range node.Failed {
+			failed[image] = fmt.Sprintf("%s (%s)", reason, node.Node)
+		}
+	}
+	if len(failed) > 0 {
+		printPullFailures(failed)
+		return fmt.Errorf("%d images could not be pulled onto the nodes", len(failed))
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Image pre-pull incomplete; the remaining images are pulled by the releases")
+		return nil
+	}
+
+	m.logger.Info().Int("images", len(images)).Int("nodes", len(nodes)).Msg("Images pre-pulled")
+	return nil
+}
+
+// chartImages returns the images of the rendered charts with helm.prePull.images
+func (m *DeploymentManager) chartImages() ([]string, error) {
+	manifests, err := m.chartManifests()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, image := range m.config.Helm.PrePull.Images {
+		seen[image] = true
+	}
+	for name, manifest := range manifests {
+		chartImages, err := k8s.ManifestImages(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read images of chart %s: %w", name, err)
+		}
+		for _, image := range chartImages {
+			seen[image] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen)), nil
+}
+
+// printPullFailures lists the images the kubelet could not pull
+func printPullFailures(failed map[string]string) {
+	data := [][]string{{"Image", "Error"}}
+	for _, image := range slices.Sorted(maps.Keys(failed)) {
+		data = append(data, []string{image, strings.TrimSpace(failed[image])})
+	}
+	pterm.Error.Println("Image pre-pull failed")
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// defaultMaxParallelCharts bounds concurrent Helm operations when neither flag nor config set it
+const defaultMaxParallelCharts = 4
+
+// planDeploymentTiers groups charts into tiers that can be deployed concurrently.
+// A chart's dependencies come from dependsOn; charts without dependsOn depend on
+// every chart with a lower order, so plain order-based configs keep deploying in
+// sequence and only charts sharing an order run together.
+func planDeploymentTiers(charts []config.DeployChart) ([][]config.DeployChart, error) {
+	byName := make(map[string]config.DeployChart, len(charts))
+	for _, chart := range charts {
+		byName[chart.Name] = chart
+	}
+
+	deps := make(map[string][]string, len(charts))
+	for _, chart := range charts {
+		if len(chart.DependsOn) > 0 {
+			for _, dep := range chart.DependsOn {
+				// Dependencies outside the selected charts are assumed to be deployed already
+				if _, ok := byName[dep]; ok {
+					deps[chart.Name] = append(deps[chart.Name], dep)
+				}
+			}
+			continue
+		}
+		for _, other := range charts {
+			if other.Order < chart.Order {
+				deps[chart.Name] = append(deps[chart.Name], other.Name)
+			}
+		}
+	}
+
+	tierOf := make(map[string]int, len(charts))
+	visiting := make(map[string]bool)
+
+	var resolve func(name string, path []string) (int, error)
+	resolve = func(name string, path []string) (int, error) {
+		if tier, ok := tierOf[name]; ok {
+			return tier, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("dependency cycle between charts: %s", strings.Join(append(path, name), " -> "))
+		}
+		visiting[name] = true
+
+		tier := 0
+		for _, dep := range deps[name] {
+			depTier, err := resolve(dep, append(path, name))
+			if err != nil {
+				return 0, err
+			}
+			if depTier+1 > tier {
+				tier = depTier + 1
+			}
+		}
+
+		visiting[name] = false
+		tierOf[name] = tier
+		return tier, nil
+	}
+
+	var tiers [][]config.DeployChart
+	for _, chart := range charts {
+		tier, err := resolve(chart.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for len(tiers) <= tier {
+			tiers = append(tiers, nil)
+		}
+		tiers[tier] = append(tiers[tier], chart)
+	}
+
+	for _, tier := range tiers {
+		sort.SliceStable(tier, func(i, j int) bool {
+			if tier[i].Order != tier[j].Order {
+				return tier[i].Order < tier[j].Order
+			}
+			return tier[i].Name < tier[j].Name
+		})
+	}
+	return tiers, nil
+}
@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
 	"github.com/pterm/pterm"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -65,11 +68,11 @@ Examples:
 
   # Dry run to preview test execution plan
   e2e-k8s-installer e2e-test --dry-run`,
-	RunE: runE2ETest,
+	RunE: withExitCode(exitcode.Test, runE2ETest),
 }
 
 func init() {
-	e2eTestCmd.Flags().StringVar(&e2eConfigPath, "config", "", "Path to E2E test configuration file")
+	e2eTestCmd.Flags().StringVar(&e2eConfigPath, "config", "", "Path to the installer configuration file")
 	e2eTestCmd.Flags().BoolVarP(&e2eVerbose, "verbose", "v", false, "Enable verbose test output")
 	e2eTestCmd.Flags().BoolVar(&e2eDryRun, "dry-run", false, "Preview test execution plan without running")
 	e2eTestCmd.Flags().StringVar(&e2eFramework, "framework", "", "Test framework to use (pytest, junit, go-test, custom)")
@@ -101,10 +104,10 @@ func runE2ETest(cmd *cobra.Command, args []string) error {
 	startTime := time.Now()
 
 	// Load configuration
-	config, err := loadE2EConfig(e2eConfigPath)
+	config, err := loadE2EConfig(workspaceConfigFile(cmd, e2eConfigPath))
 	if err != nil {
 		spinner.Fail("Failed to load configuration")
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
 	}
 
 	spinner.Success("Configuration loaded")
@@ -351,7 +354,12 @@ func (m *E2ETestManager) ApplyCommandLineOverrides() {
 		case "junit":
 			ext = "xml"
 		}
-		m.reportPath = filepath.Join(".", "reports", fmt.Sprintf("e2e-results.%s", ext))
+		m.reportPath = filepath.Join(reportsDir(), fmt.Sprintf("e2e-results.%s", ext))
+	}
+
+	// Expose parameters from earlier steps, letting explicit --environment values win
+	for key, value := range params.GetStore().Environ() {
+		m.environment[key] = value
 	}
 
 	// Parse environment variables
@@ -571,6 +579,7 @@ func (m *E2ETestManager) GenerateReport() error {
 	// TODO: Write actual report to file based on format
 	_ = report
 
+	history.AddReport(m.reportPath)
 	m.logger.Info().Str("report_path", m.reportPath).Msg("Test report generated")
 	return nil
 }
@@ -659,27 +668,24 @@ func (m *E2ETestManager) GetReportPath() string {
 	return m.reportPath
 }
 
+// loadE2EConfig loads the validation.e2e section of the installer configuration, or of the
+// sample configuration without a file
 func loadE2EConfig(configPath string) (*config.E2EConfig, error) {
-	// Load configuration from file or use defaults
-	// For now, return a default configuration
-
-	config := &config.E2EConfig{
-		Enabled:   true,
-		TestSuite: "./tests/e2e",
-		Framework: "go-test",
-		Config: config.E2ETestConfig{
-			Parallel: false,
-			Workers:  1,
-			Retries:  1,
-		},
-		Reporting: config.ReportConfig{
-			Format:  "junit",
-			Output:  "./reports/e2e-results.xml",
-			Archive: true,
-		},
-		Timeout: "30m",
+	installerCfg, err := loadInstallConfig(configPath)
+	if err != nil {
+		return nil, err
 	}
-
-	// TODO: Implement actual configuration loading from file
-	return config, nil
+	if err := applyWorkspace(installerCfg); err != nil {
+		return nil, err
+	}
+	if err := initWorkspaceParameters(installerCfg.Installer.Workspace); err != nil {
+		return nil, err
+	}
+	// Tests log in with the generated credentials from the parameter store
+	if len(installerCfg.Security.Credentials.Items) > 0 && !e2eDryRun {
+		if _, err := publishCredentials(installerCfg, installerCfg.Installer.Workspace); err != nil {
+			return nil, fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+	return &installerCfg.Validation.E2E, nil
 }
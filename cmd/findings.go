@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// rbacPreflightReportName is the report preflight rbac writes for later aggregation
+const rbacPreflightReportName = "rbac-preflight-report.json"
+
+// findingRule is a kind of security, policy or misconfiguration finding the installer reports
+type findingRule struct {
+	ID          string
+	Name        string
+	Description string
+	Help        string
+	Severity    string
+	Tags        []string
+}
+
+var (
+	ruleRemovedAPI = findingRule{
+		ID:          "E2E001",
+		Name:        "RemovedKubernetesAPI",
+		Description: "Object uses a Kubernetes API removed in the target version",
+		Help:        "Migrate the manifest to the replacement API before upgrading the cluster.",
+		Severity:    htmlreport.SeverityHigh,
+		Tags:        []string{"kubernetes", "misconfiguration"},
+	}
+	ruleDeprecatedAPI = findingRule{
+		ID:          "E2E002",
+		Name:        "DeprecatedKubernetesAPI",
+		Description: "Object uses a deprecated Kubernetes API",
+		Help:        "Migrate the manifest to the replacement API before it is removed.",
+		Severity:    htmlreport.SeverityMedium,
+		Tags:        []string{"kubernetes", "misconfiguration"},
+	}
+	ruleImageDigestMismatch = findingRule{
+		ID:          "E2E003",
+		Name:        "ImageDigestMismatch",
+		Description: "Image in the client registry differs from the vendor image",
+		Help:        "The copied image does not match the vendor's manifest digest; re-pull it and check the client registry for tampering.",
+		Severity:    htmlreport.SeverityCritical,
+		Tags:        []string{"security", "supply-chain"},
+	}
+	ruleImageUnverified = findingRule{
+		ID:          "E2E004",
+		Name:        "ImageUnverified",
+		Description: "Image copy was not verified against the vendor registry",
+		Help:        "Re-run package-pull with access to the vendor registry to verify the copied digests.",
+		Severity:    htmlreport.SeverityLow,
+		Tags:        []string{"security", "supply-chain"},
+	}
+	ruleMissingPermission = findingRule{
+		ID:          "E2E005",
+		Name:        "MissingInstallerPermission",
+		Description: "Installer identity lacks a permission an installation step needs",
+		Help:        "Grant the ClusterRole generated by 'preflight rbac --role-out' to the installer identity.",
+		Severity:    htmlreport.SeverityHigh,
+		Tags:        []string{"security", "rbac"},
+	}
+)
+
+// scanFinding is one finding from the reports of earlier runs
+type scanFinding struct {
+	Rule    findingRule
+	Source  string
+	Object  string
+	Message string
+	File    string // file the finding is attributed to, for SARIF consumers
+}
+
+type rbacPreflightReportData struct {
+	Missing []k8s.PermissionCheck `json:"missing"`
+}
+
+// collectFindings gathers the findings recorded by the latest post-validate, package-pull and
+// preflight rbac reports in dirs
+func collectFindings(dirs []string) []scanFinding {
+	var findings []scanFinding
+
+	var validation struct {
+		DeprecatedAPIs []deprecations.Finding `json:"deprecated_apis"`
+	}
+	if readLatestReport(dirs, "post-validation-report.json", &validation) {
+		chartPaths := make(map[string]string)
+		if deployCfg, err := loadDeployConfig(""); err == nil {
+			for _, chart := range deployCfg.Helm.Charts {
+				chartPaths[chart.Name] = chart.Path
+			}
+		}
+		for _, finding := range validation.DeprecatedAPIs {
+			rule, message := ruleDeprecatedAPI, fmt.Sprintf("%s is deprecated since %s and removed in %s", finding.APIVersion, finding.DeprecatedIn, finding.RemovedIn)
+			if finding.Removed {
+				rule, message = ruleRemovedAPI, fmt.Sprintf("%s is removed in %s", finding.APIVersion, finding.RemovedIn)
+			}
+			if finding.Replacement != "" {
+				message += ", use " + finding.Replacement
+			}
+			file := chartPaths[finding.Source]
+			switch {
+			case finding.Source == deprecations.LiveSource:
+				file = installerConfigFile
+			case file == "":
+				file = filepath.Join("charts", finding.Source)
+			}
+			findings = append(findings, scanFinding{
+				Rule:    rule,
+				Source:  finding.Source,
+				Object:  findingObject(finding),
+				Message: fmt.Sprintf("%s: %s", findingObject(finding), message),
+				File:    file,
+			})
+		}
+	}
+
+	var images imageSyncReportData
+	readLatestReport(dirs, "image-sync-report-latest.json", &images)
+	for _, image := range images.Images {
+		switch image.Verification {
+		case artifacts.VerificationMismatch:
+			findings = append(findings, scanFinding{
+				Rule:    ruleImageDigestMismatch,
+				Source:  "package-pull",
+				Object:  image.Destination,
+				Message: fmt.Sprintf("%s has digest %s but the vendor image %s has %s", image.Destination, image.DestinationDigest, image.Source, image.SourceDigest),
+				File:    installerConfigFile,
+			})
+		case artifacts.VerificationUnverified:
+			findings = append(findings, scanFinding{
+				Rule:    ruleImageUnverified,
+				Source:  "package-pull",
+				Object:  image.Source,
+				Message: fmt.Sprintf("copy of %s was not verified against the vendor registry", image.Source),
+				File:    installerConfigFile,
+			})
+		}
+	}
+
+	var rbac rbacPreflightReportData
+	readLatestReport(dirs, rbacPreflightReportName, &rbac)
+	for _, check := range rbac.Missing {
+		scope := check.Namespace
+		if scope == "" {
+			scope = "cluster"
+		}
+		group := check.APIGroup
+		if group == "" {
+			group = "core"
+		}
+		findings = append(findings, scanFinding{
+			Rule:    ruleMissingPermission,
+			Source:  "preflight " + check.Step,
+			Object:  fmt.Sprintf("%s %s.%s", check.Verb, check.Resource, group),
+			Message: fmt.Sprintf("step %s cannot %s %s (%s) in %s", check.Step, check.Verb, check.Resource, group, scope),
+			File:    installerConfigFile,
+		})
+	}
+	return findings
+}
+
+// findingsDirs are where standalone commands look up the reports of earlier runs: the
+// reports directory, then the reports of the installation in the workspace
+func findingsDirs() []string {
+	return []string{reportsDir(), filepath.Join(selectedWorkspace("./workspace"), "reports")}
+}
+
+// installerConfigFile is where findings that concern the installation as a whole are attributed
+const installerConfigFile = "installer-config.json"
+
+// readLatestReport decodes the first report called name found in dirs
+func readLatestReport(dirs []string, name string, v interface{}) bool {
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			logger.Warn("Ignoring unreadable report").Str("report", path).Err(err).Send()
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// Thresholds of --fail-on, named after the SARIF levels the severities map to
+const (
+	failOnWarning  = "warning"
+	failOnError    = "error"
+	failOnCritical = "critical"
+)
+
+// findingsFailOn is the --fail-on threshold of the command being run
+var findingsFailOn string
+
+// addFailOnFlag registers --fail-on on commands that gate on findings
+func addFailOnFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&findingsFailOn, "fail-on", "", "Exit with the validation exit code when findings reach this level (warning, error, critical)")
+}
+
+// validateFailOn rejects unknown --fail-on values before anything runs
+func validateFailOn() error {
+	switch findingsFailOn {
+	case "", failOnWarning, failOnError, failOnCritical:
+		return nil
+	}
+	return exitcode.Wrap(exitcode.Config, fmt.Errorf("unsupported --fail-on level %q, use %s, %s or %s", findingsFailOn, failOnWarning, failOnError, failOnCritical))
+}
+
+// severityMeetsFailOn reports whether a finding severity reaches the --fail-on threshold
+func severityMeetsFailOn(severity, threshold string) bool {
+	switch threshold {
+	case failOnCritical:
+		return severity == htmlreport.SeverityCritical
+	case failOnError:
+		return severity == htmlreport.SeverityCritical || severity == htmlreport.SeverityHigh
+	case failOnWarning:
+		return severity != htmlreport.SeverityLow
+	}
+	return false
+}
+
+// checkFailOn fails when findings reach the --fail-on threshold
+func checkFailOn(findings []scanFinding) error {
+	if findingsFailOn == "" {
+		return nil
+	}
+	blocking := 0
+	for _, finding := range findings {
+		if severityMeetsFailOn(finding.Rule.Severity, findingsFailOn) {
+			blocking++
+		}
+	}
+	if blocking == 0 {
+		return nil
+	}
+	return exitcode.Wrap(exitcode.Validation, fmt.Errorf("%d findings at or above the --fail-on level %s", blocking, findingsFailOn))
+}
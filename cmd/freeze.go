@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	freezeKubeconfig string
+	freezeContext    string
+	freezeReason     string
+	freezeAdmission  bool
+	freezeOutput     string
+)
+
+// errNamespaceFrozen refuses a change to a frozen namespace
+var errNamespaceFrozen = errors.New("namespace frozen")
+
+// freezeCmd groups the namespace freeze commands
+var freezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "Freeze namespaces against deploys and upgrades",
+	Long: `Flag namespaces as frozen, for example during a release freeze or an incident, so
+that deploy and uninstall refuse to change them. A frozen namespace carries the
+e2e-k8s-installer.io/frozen label and annotations with the reason, who froze it and
+when. Deploy and uninstall continue with --override-freeze "<reason>", which is
+recorded in the audit log.
+
+With --admission, a ValidatingAdmissionPolicy also denies changes by anyone else:
+kubectl, other pipelines or Helm run by hand. Control plane components and nodes
+are exempt so running workloads keep healing.`,
+}
+
+// freezeSetCmd freezes namespaces
+var freezeSetCmd = &cobra.Command{
+	Use:   "set NAMESPACE...",
+	Short: "Freeze namespaces",
+	Long: `Freeze namespaces so the installer refuses to deploy into or uninstall from them.
+
+Examples:
+  e2e-k8s-installer freeze set production --reason "Black Friday change freeze"
+  e2e-k8s-installer freeze set production payments --reason "INC-4711" --admission`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFreezeSet,
+}
+
+// freezeLiftCmd lifts freezes
+var freezeLiftCmd = &cobra.Command{
+	Use:   "lift NAMESPACE...",
+	Short: "Lift the freeze of namespaces",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runFreezeLift,
+}
+
+// freezeListCmd lists frozen namespaces
+var freezeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List frozen namespaces",
+	Args:  cobra.NoArgs,
+	RunE:  runFreezeList,
+}
+
+func init() {
+	freezeCmd.PersistentFlags().StringVar(&freezeKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	freezeCmd.PersistentFlags().StringVar(&freezeContext, "context", "", "Kubernetes context to use")
+
+	freezeSetCmd.Flags().StringVar(&freezeReason, "reason", "", "Why the namespaces are frozen, shown when a change is refused (required)")
+	freezeSetCmd.Flags().BoolVar(&freezeAdmission, "admission", false, "Also install an admission policy denying changes to frozen namespaces cluster-wide")
+	freezeListCmd.Flags().StringVarP(&freezeOutput, "output", "o", "table", "Output format (table, json)")
+
+	freezeCmd.AddCommand(freezeSetCmd)
+	freezeCmd.AddCommand(freezeLiftCmd)
+	freezeCmd.AddCommand(freezeListCmd)
+}
+
+func freezeManager() (*k8s.Manager, error) {
+	if err := initWorkspaceParameters(selectedWorkspace("./workspace")); err != nil {
+		return nil, err
+	}
+	return k8s.NewManager(&config.K8sConfig{
+		ConfigPath: freezeKubeconfig,
+		Context:    freezeContext,
+	})
+}
+
+func runFreezeSet(cmd *cobra.Command, args []string) error {
+	if strings.TrimSpace(freezeReason) == "" {
+		return fmt.Errorf("--reason is required")
+	}
+	k8sMgr, err := freezeManager()
+	if err != nil {
+		return err
+	}
+
+	if freezeAdmission {
+		if err := k8sMgr.ApplyManifest(k8s.FreezePolicyManifest()); err != nil {
+			return fmt.Errorf("failed to install the freeze admission policy: %w", err)
+		}
+		pterm.Success.Printf("Admission policy %s installed\n", k8s.FreezePolicyName)
+	}
+
+	by := ""
+	if u, err := user.Current(); err == nil {
+		by = u.Username
+	}
+	for _, namespace := range args {
+		if err := k8sMgr.FreezeNamespace(k8s.NamespaceFreeze{Namespace: namespace, Reason: freezeReason, By: by}); err != nil {
+			return err
+		}
+		pterm.Success.Printf("🧊 Namespace %s frozen: %s\n", namespace, freezeReason)
+	}
+	return nil
+}
+
+func runFreezeLift(cmd *cobra.Command, args []string) error {
+	k8sMgr, err := freezeManager()
+	if err != nil {
+		return err
+	}
+	for _, namespace := range args {
+		if err := k8sMgr.UnfreezeNamespace(namespace); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Namespace %s is no longer frozen\n", namespace)
+	}
+	return nil
+}
+
+func runFreezeList(cmd *cobra.Command, args []string) error {
+	k8sMgr, err := freezeManager()
+	if err != nil {
+		return err
+	}
+	freezes, err := k8sMgr.NamespaceFreezes(nil)
+	if err != nil {
+		return fmt.Errorf("failed to list frozen namespaces: %w", err)
+	}
+
+	switch freezeOutput {
+	case "json":
+		data, err := json.MarshalIndent(freezes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal frozen namespaces: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		if len(freezes) == 0 {
+			pterm.Info.Println("No namespace is frozen")
+			return nil
+		}
+		printFreezes(freezes)
+		if !k8sMgr.FreezePolicyInstalled() {
+			pterm.Info.Println("The admission policy is not installed: only the installer respects these freezes")
+		}
+	default:
+		return fmt.Errorf("unsupported output format: %s", freezeOutput)
+	}
+	return nil
+}
+
+func printFreezes(freezes []k8s.NamespaceFreeze) {
+	data := [][]string{{"Namespace", "Reason", "Frozen By", "Frozen At"}}
+	for _, freeze := range freezes {
+		at := ""
+		if !freeze.At.IsZero() {
+			at = freeze.At.Local().Format(time.RFC3339)
+		}
+		data = append(data, []string{freeze.Namespace, freeze.Reason, freeze.By, at})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// checkNamespaceFreeze refuses an operation on frozen namespaces unless override gives a
+// reason to go ahead. An override is written to the audit log and, when the admission policy
+// enforces freezes, annotated on the namespaces so the run's changes are admitted; the
+// returned function removes the annotation again. A dry run only warns.
+func checkNamespaceFreeze(cfg *config.K8sConfig, namespaces []string, operation, override string, dryRun bool) (func(), error) {
+	release := func() {}
+	k8sMgr, err := k8s.NewManager(cfg)
+	if err != nil {
+		logger.Warn("Skipping namespace freeze check, kubectl unavailable").Err(err).Send()
+		return release, nil
+	}
+	freezes, err := k8sMgr.NamespaceFreezes(namespaces)
+	if err != nil {
+		logger.Warn("Skipping namespace freeze check").Err(err).Send()
+		return release, nil
+	}
+	if len(freezes) == 0 {
+		return release, nil
+	}
+
+	names := make([]string, 0, len(freezes))
+	for _, freeze := range freezes {
+		names = append(names, freeze.Namespace)
+	}
+	if dryRun {
+		pterm.Warning.Printf("🧊 DRY RUN: %s would be refused, frozen namespaces: %s\n", operation, strings.Join(names, ", "))
+		return release, nil
+	}
+	if strings.TrimSpace(override) == "" {
+		pterm.Error.Printf("🧊 %s refused: %d target namespaces are frozen\n", operation, len(freezes))
+		printFreezes(freezes)
+		frozen := make([]string, 0, len(freezes))
+		for _, freeze := range freezes {
+			frozen = append(frozen, fmt.Sprintf("%s (%s)", freeze.Namespace, freeze.Reason))
+		}
+		return release, exitcode.Wrap(exitcode.Frozen, fmt.Errorf("%w: %s refused for %s; lift the freeze with 'freeze lift' or pass --override-freeze \"<reason>\"",
+			errNamespaceFrozen, operation, strings.Join(frozen, ", ")))
+	}
+
+	enforced := k8sMgr.FreezePolicyInstalled()
+	var overridden []string
+	release = func() {
+		for _, namespace := range overridden {
+			if err := k8sMgr.ClearFreezeOverride(namespace); err != nil {
+				logger.Warn("Failed to end the freeze override").Str("namespace", namespace).Err(err).Send()
+			}
+		}
+	}
+	for _, freeze := range freezes {
+		audit.Record("namespace.freeze.override", freeze.Namespace, map[string]interface{}{
+			"operation":    operation,
+			"reason":       override,
+			"frozenReason": freeze.Reason,
+			"frozenBy":     freeze.By,
+		}, nil)
+		if enforced {
+			if err := k8sMgr.SetFreezeOverride(freeze.Namespace, override); err != nil {
+				release()
+				return func() {}, err
+			}
+			overridden = append(overridden, freeze.Namespace)
+		}
+		pterm.Warning.Printf("🧊 Overriding the freeze of %s (%s): %s\n", freeze.Namespace, freeze.Reason, override)
+	}
+	return release, nil
+}
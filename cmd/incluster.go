@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+)
+
+var (
+	inClusterFlag      bool
+	workspaceStoreFlag string
+
+	// inCluster is set once the run was switched to the in-cluster mode
+	inCluster bool
+	// workspaceStore is the object store the workspace is pushed back to, nil without one
+	workspaceStore *workspace.Store
+)
+
+// inClusterEnv turns on the in-cluster mode without --in-cluster: true requires a pod, auto
+// switches only inside one. The container image sets auto.
+const inClusterEnv = "E2E_INSTALLER_IN_CLUSTER"
+
+// inClusterWorkspace is the workspace of in-cluster runs without --workspace, where the
+// installer Job mounts its volume
+const inClusterWorkspace = "/workspace"
+
+// terminationLog is the file Kubernetes shows as the termination message of a container
+const terminationLog = "/dev/termination-log"
+
+// terminationLimit is the most Kubernetes keeps of a termination message
+const terminationLimit = 4096
+
+// startInCluster prepares a run inside a pod of a management cluster: the workspace volume
+// is selected, the workspace store pulled, and the tools pointed at the cluster through the
+// pod's ServiceAccount unless a kubeconfig was given. Styling is turned off, pod logs are no
+// terminal.
+func startInCluster() error {
+	mode := strings.ToLower(os.Getenv(inClusterEnv))
+	if inClusterFlag {
+		mode = "true"
+	}
+	switch mode {
+	case "", "false":
+	case "auto":
+		inCluster = k8s.InCluster()
+	case "true":
+		if !k8s.InCluster() {
+			return fmt.Errorf("--in-cluster: no ServiceAccount credentials are mounted, the installer is not running in a pod")
+		}
+		inCluster = true
+	default:
+		return fmt.Errorf("invalid %s %q, use true, false or auto", inClusterEnv, mode)
+	}
+
+	if inCluster {
+		pterm.DisableStyling()
+		if workspaceFlag == "" {
+			workspaceFlag = inClusterWorkspace
+		}
+		// Job retries run in new pods, a lock taken by a pod that is gone is stale
+		workspace.HostAlive = podAlive
+	}
+	if err := pullWorkspace(); err != nil {
+		return err
+	}
+	if !inCluster || simulateFlag || replayFlag != "" {
+		return nil
+	}
+
+	kubeconfig := inClusterKubeconfigPath()
+	if err := k8s.WriteInClusterKubeconfig(kubeconfig); err != nil {
+		return err
+	}
+	if os.Getenv("KUBECONFIG") == "" {
+		os.Setenv("KUBECONFIG", kubeconfig)
+	}
+	// The kubernetes and helm terraform providers read their own variable
+	if os.Getenv("KUBE_CONFIG_PATH") == "" {
+		os.Setenv("KUBE_CONFIG_PATH", os.Getenv("KUBECONFIG"))
+	}
+	pterm.Info.Printf("Running in cluster as ServiceAccount of namespace %s, workspace %s\n", k8s.PodNamespace(), selectedWorkspace(inClusterWorkspace))
+	return nil
+}
+
+// inClusterKubeconfigPath is the kubeconfig written for the cluster the pod runs in. It stays
+// in the workspace and references the mounted token, so it holds no secret.
+func inClusterKubeconfigPath() string {
+	return filepath.Join(selectedWorkspace(inClusterWorkspace), ".kube", "in-cluster.kubeconfig")
+}
+
+// pullWorkspace downloads the workspace from --workspace-store, or E2E_INSTALLER_WORKSPACE_STORE,
+// before the run
+func pullWorkspace() error {
+	raw := valueOr(workspaceStoreFlag, os.Getenv(workspace.StoreEnv))
+	if raw == "" {
+		return nil
+	}
+	store, err := workspace.ParseStore(raw)
+	if err != nil {
+		return err
+	}
+	// Simulated and replayed runs answer from inside the process, the store is never used
+	if simulateFlag || replayFlag != "" {
+		pterm.Info.Println("Workspace store not synced in a simulated or replayed run")
+		return nil
+	}
+	dir := selectedWorkspace(config.GenerateDefaultConfig().Installer.Workspace)
+	if err := store.Pull(dir); err != nil {
+		return err
+	}
+	workspaceStore = store
+	pterm.Info.Printf("Workspace %s pulled from %s\n", dir, store.URL)
+	return nil
+}
+
+// finishInCluster pushes the workspace back to its store with the state, reports and logs of
+// the run, and leaves the outcome as the termination message of the installer container
+func finishInCluster(command string, err error) {
+	if workspaceStore != nil {
+		dir := selectedWorkspace(config.GenerateDefaultConfig().Installer.Workspace)
+		if pushErr := workspaceStore.Push(dir); pushErr != nil {
+			logger.Warn("Failed to push the workspace").Str("store", workspaceStore.URL).Err(pushErr).Send()
+		} else {
+			pterm.Info.Printf("Workspace %s pushed to %s\n", dir, workspaceStore.URL)
+		}
+	}
+	if !inCluster {
+		return
+	}
+
+	message := fmt.Sprintf("%s succeeded, run %s", command, history.RunID())
+	if err != nil {
+		message = fmt.Sprintf("%s failed with exit code %d, run %s: %v", command, exitcode.Code(err), history.RunID(), err)
+	}
+	if len(message) > terminationLimit {
+		message = message[:terminationLimit]
+	}
+	// Kubernetes creates the file; outside a pod's container there is nothing to write to
+	file, openErr := os.OpenFile(terminationLog, os.O_WRONLY|os.O_TRUNC, 0)
+	if openErr != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(message)
+}
+
+// podAlive reports whether the pod host that took a workspace lock still exists. When that
+// cannot be told the lock is honored.
+func podAlive(host string) bool {
+	manager, err := k8s.NewManager(&config.K8sConfig{ConfigPath: inClusterKubeconfigPath()})
+	if err != nil {
+		return true
+	}
+	exists, err := manager.PodExists(k8s.PodNamespace(), host)
+	if err != nil {
+		logger.Warn("Failed to look up the pod holding the workspace lock").Str("pod", host).Err(err).Send()
+		return true
+	}
+	return exists
+}
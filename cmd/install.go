@@ -2,30 +2,60 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/changeticket"
+	"github.com/judebantony/e2e-k8s-installer/pkg/ci"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/credentials"
+	"github.com/judebantony/e2e-k8s-installer/pkg/events"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/rollback"
+	"github.com/judebantony/e2e-k8s-installer/pkg/telemetry"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
 	"github.com/pterm/pterm"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
 var (
-	installConfigPath      string
-	installVerbose         bool
-	installDryRun          bool
-	installResume          bool
-	installSkipSteps       []string
-	installStepsOnly       []string
-	installStateFile       string
-	installParallel        bool
-	installContinueOnError bool
-	installWorkspace       string
+	installConfigPath       string
+	installVerbose          bool
+	installDryRun           bool
+	installResume           bool
+	installSkipSteps        []string
+	installStepsOnly        []string
+	installStateFile        string
+	installParallel         bool
+	installContinueOnError  bool
+	installInteractive      bool
+	installApprove          []string
+	installReportFormat     string
+	installEnvSet           []string
+	installMaxParallel      int
+	installSkipConnectivity bool
+	installSkipClockCheck   bool
+	installChangeTicket     string
 )
 
+// eventsFlushTimeout bounds how long the end of an install waits for undelivered events
+const eventsFlushTimeout = 15 * time.Second
+
 // installCmd represents the install command (main orchestrator)
 var installCmd = &cobra.Command{
 	Use:   "install",
@@ -70,8 +100,23 @@ Examples:
   # Continue installation even if non-critical steps fail
   e2e-k8s-installer install --continue-on-error
 
+  # Decide at the terminal whether to retry, skip or debug a failed step
+  e2e-k8s-installer install --interactive
+
+  # Resume an installation paused at the db-migrate-prod approval gate
+  e2e-k8s-installer install --approve db-migrate-prod
+
+  # Roll out to two customer regions at once, each from its own workspace
+  e2e-k8s-installer install --env-set prod-eu,prod-us
+
+  # Run db-migrate alongside deploy, and post-validate alongside e2e-test
+  e2e-k8s-installer install --parallel
+
   # Dry run to preview installation plan
-  e2e-k8s-installer install --dry-run`,
+  e2e-k8s-installer install --dry-run
+
+  # Also write a self-contained HTML executive summary for the change ticket
+  e2e-k8s-installer install --report-format html`,
 	RunE: runInstall,
 }
 
@@ -83,14 +128,22 @@ func init() {
 	installCmd.Flags().StringSliceVar(&installSkipSteps, "skip-steps", []string{}, "Skip specified installation steps")
 	installCmd.Flags().StringSliceVar(&installStepsOnly, "steps-only", []string{}, "Run only specified installation steps")
 	installCmd.Flags().StringVar(&installStateFile, "state-file", "", "Path to installation state file")
-	installCmd.Flags().BoolVar(&installParallel, "parallel", false, "Enable parallel execution where possible")
+	installCmd.Flags().BoolVar(&installParallel, "parallel", false, "Run steps as soon as their dependencies completed, e.g. db-migrate alongside deploy")
 	installCmd.Flags().BoolVar(&installContinueOnError, "continue-on-error", false, "Continue installation if non-critical steps fail")
-	installCmd.Flags().StringVar(&installWorkspace, "workspace", "", "Installation workspace directory")
+	installCmd.Flags().StringVar(&installReportFormat, "report-format", installReportJSON, "Final report format (json, html); html also writes the JSON report")
+	installCmd.Flags().BoolVar(&installInteractive, "interactive", false, "Prompt to retry, skip, inspect or abort when a step fails")
+	installCmd.Flags().StringSliceVar(&installApprove, "approve", []string{}, "Approve the named approval gates, resuming an installation paused at one of them")
+	installCmd.Flags().StringSliceVar(&installEnvSet, "env-set", []string{}, "Install the named workspaces concurrently, one installer process per environment")
+	installCmd.Flags().IntVar(&installMaxParallel, "max-parallel", 0, "Maximum environments of --env-set installed at once (0 installs all at once)")
+	installCmd.Flags().BoolVar(&installSkipConnectivity, "skip-connectivity", false, "Start without checking that the external endpoints of the steps are reachable")
+	installCmd.Flags().BoolVar(&installSkipClockCheck, "skip-clock-check", false, "Start without comparing the local clock with NTP, the cloud and the API server")
+	installCmd.Flags().StringVar(&installChangeTicket, "change-ticket", "", "Record the run on this existing change ticket instead of opening one, e.g. CHG0030001")
+	addFailOnFlag(installCmd)
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
 	// Initialize logger
-	logger := zerolog.New(os.Stderr).With().
+	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, logger.Journal())).With().
 		Timestamp().
 		Str("component", "install").
 		Logger()
@@ -99,6 +152,19 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		logger = logger.Level(zerolog.DebugLevel)
 	}
 
+	if err := validateInstallReportFormat(); err != nil {
+		return err
+	}
+	if err := validateFailOn(); err != nil {
+		return err
+	}
+	if len(installEnvSet) > 0 {
+		return runInstallEnvSet(cmd)
+	}
+	if err := validateInteractive(); err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
 	// Create spinner for initialization
 	spinner, _ := pterm.DefaultSpinner.Start("Initializing E2E Kubernetes installation...")
 
@@ -106,10 +172,11 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	startTime := time.Now()
 
 	// Load configuration
-	config, err := loadInstallConfig(installConfigPath)
+	configFile := workspaceConfigFile(cmd, installConfigPath)
+	config, err := loadInstallConfig(configFile)
 	if err != nil {
 		spinner.Fail("Failed to load configuration")
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
 	}
 
 	spinner.Success("Configuration loaded")
@@ -120,17 +187,50 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize installation manager: %w", err)
 	}
+	manager.configFile = configFile
 
 	// Apply command line overrides
 	manager.ApplyCommandLineOverrides()
 
+	if err := labels.InitGlobalPolicy(config.Labels, history.RunID()); err != nil {
+		return fmt.Errorf("invalid label policy: %w", err)
+	}
+
+	// Refuse to run alongside another installer run against the same workspace
+	if !installDryRun {
+		lock, err := workspace.Acquire(manager.GetWorkspace(), "install")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		// Gate approvals are audited like the changes they let through
+		if err := audit.InitGlobalAuditLog(manager.GetWorkspace(), config.Installer.Audit); err != nil {
+			return fmt.Errorf("failed to initialize audit log: %w", err)
+		}
+	}
+
 	// Load or initialize installation state
 	if err := manager.LoadState(); err != nil {
 		return fmt.Errorf("failed to load installation state: %w", err)
 	}
 
-	// Create progress area
-	progressArea, _ := pterm.DefaultArea.Start()
+	// Generate component credentials on first install and reuse them on re-runs
+	if len(config.Security.Credentials.Items) > 0 && !installDryRun {
+		if err := manager.EnsureCredentials(); err != nil {
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+
+	// Serve the offline chart repository for the duration of the install
+	if chartRepo := config.Artifacts.Helm.Client.ChartRepo; chartRepo.Enabled && chartRepo.Type == "local" && !installDryRun {
+		repoURL, stopRepo, err := artifacts.NewManager(config, false).ServeHelmRepo()
+		if err != nil {
+			return fmt.Errorf("failed to serve local Helm repository: %w", err)
+		}
+		defer stopRepo()
+		logger.Info().Str("url", repoURL).Msg("Local Helm repository available")
+	}
 
 	// Define installation steps with their dependencies and configurations
 	steps := []InstallationStep{
@@ -141,6 +241,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     true,
 			Dependencies: []string{},
 			Handler:      manager.RunSetup,
+			ExitCode:     exitcode.Preflight,
 		},
 		{
 			Name:         "package-pull",
@@ -149,6 +250,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     true,
 			Dependencies: []string{"setup"},
 			Handler:      manager.RunPackagePull,
+			ExitCode:     exitcode.Deploy,
 		},
 		{
 			Name:         "provision-infra",
@@ -157,6 +259,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     true,
 			Dependencies: []string{"package-pull"},
 			Handler:      manager.RunProvisionInfra,
+			ExitCode:     exitcode.Deploy,
 		},
 		{
 			Name:         "db-migrate",
@@ -165,6 +268,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     false,
 			Dependencies: []string{"provision-infra"},
 			Handler:      manager.RunDBMigrate,
+			ExitCode:     exitcode.Deploy,
 		},
 		{
 			Name:         "deploy",
@@ -173,6 +277,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     true,
 			Dependencies: []string{"provision-infra"},
 			Handler:      manager.RunDeploy,
+			ExitCode:     exitcode.Deploy,
 		},
 		{
 			Name:         "post-validate",
@@ -181,6 +286,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     false,
 			Dependencies: []string{"deploy"},
 			Handler:      manager.RunPostValidate,
+			ExitCode:     exitcode.Validation,
 		},
 		{
 			Name:         "e2e-test",
@@ -189,12 +295,79 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			Required:     false,
 			Dependencies: []string{"deploy"},
 			Handler:      manager.RunE2ETest,
+			ExitCode:     exitcode.Test,
 		},
 	}
 
-	// Filter steps based on command line flags
+	// Apply the configured order, disabled and replaced steps, then the command line flags
+	steps, err = manager.configureSteps(steps)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
 	steps = manager.FilterSteps(steps)
 
+	// Refuse to start when the license does not cover a step, rather than failing midway
+	if err := manager.CheckLicensedSteps(steps); err != nil {
+		return err
+	}
+	if err := manager.validateGates(steps); err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	// Every external endpoint the steps need must answer before any of them runs
+	if !installDryRun && !installSkipConnectivity {
+		stepNames := make([]string, len(steps))
+		for i, step := range steps {
+			stepNames[i] = step.Name
+		}
+		results, err := checkConnectivity(config, stepNames, defaultConnectivityTimeout, true)
+		if err == nil {
+			err = connectivityError(results)
+		}
+		if err != nil {
+			return fmt.Errorf("%w (check the network or proxy, or pass --skip-connectivity)", err)
+		}
+	}
+
+	// A skewed clock fails certificate and token checks midway with misleading errors
+	if !installDryRun && !installSkipClockCheck && !config.Installer.Clock.Disabled {
+		measurements, err := checkClock(config, true)
+		if err == nil {
+			err = clockError(config, measurements)
+		}
+		if err != nil {
+			return fmt.Errorf("%w (or pass --skip-clock-check)", err)
+		}
+	}
+
+	// Orchestration platforms follow the run and its steps through lifecycle events
+	if !installDryRun {
+		if err := events.InitGlobalEmitter(config.Installer.Events, events.Event{
+			RunID:       history.RunID(),
+			Workspace:   manager.GetWorkspace(),
+			Environment: config.Labels.Environment,
+			Version:     version.Version,
+		}); err != nil {
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid event configuration: %w", err))
+		}
+		defer events.CloseGlobalEmitter(eventsFlushTimeout)
+		stepNames := make([]string, len(steps))
+		for i, step := range steps {
+			stepNames[i] = step.Name
+		}
+		events.Emit(events.Event{Type: events.RunStarted, Metadata: map[string]interface{}{
+			"steps":  stepNames,
+			"resume": manager.state.Resume,
+		}})
+
+		if err := manager.openChangeTicket(steps); err != nil {
+			return err
+		}
+	}
+
+	// Create progress area
+	progressArea, _ := pterm.DefaultArea.Start()
+
 	// Execute installation steps
 	if installParallel {
 		err = manager.ExecuteStepsParallel(ctx, steps, progressArea)
@@ -206,32 +379,78 @@ func runInstall(cmd *cobra.Command, args []string) error {
 
 	// Handle installation result
 	if err != nil {
-		pterm.Error.Printf("❌ Installation failed: %v\n", err)
+		if errors.Is(err, errInstallPaused) {
+			pterm.Warning.Printf("⏸️  %v\n", err)
+			events.Emit(events.Event{Type: events.RunPaused, Error: err.Error(), Metadata: map[string]interface{}{"gate": manager.state.PausedAt}})
+			manager.ticket.Note(fmt.Sprintf("Installation paused: %v", err))
+		} else if errors.Is(err, errOutsideWindow) {
+			pterm.Warning.Printf("🗓️  %v, re-run with --resume inside the window\n", err)
+			events.Emit(events.Event{Type: events.RunPaused, Error: err.Error()})
+			manager.ticket.Note(fmt.Sprintf("Installation paused: %v", err))
+		} else {
+			pterm.Error.Printf("❌ Installation failed: %v\n", err)
+			manager.state.Status, manager.state.LastError = installStatusFailed, err.Error()
+			events.Emit(events.Event{Type: events.RunFailed, Error: err.Error(), ExitCode: exitcode.Code(err),
+				DurationMs: time.Since(startTime).Milliseconds()})
+		}
 
 		// Save state for resume
 		if saveErr := manager.SaveState(); saveErr != nil {
 			logger.Error().Err(saveErr).Msg("Failed to save installation state")
 		}
 
+		// Failed installs are reported too, they are the ones change reviews need most
+		if reportErr := manager.GenerateFinalReport(err); reportErr != nil {
+			logger.Warn().Err(reportErr).Msg("Failed to generate final installation report")
+		} else if manager.GetHTMLReportPath() != "" {
+			pterm.Info.Printf("📊 Installation report: %s\n", manager.GetHTMLReportPath())
+		}
+		if !errors.Is(err, errInstallPaused) && !errors.Is(err, errOutsideWindow) {
+			manager.closeChangeTicket(false, fmt.Sprintf("Installation failed: %v", err))
+		}
+
 		return err
 	}
 
 	// Mark installation as completed
 	manager.MarkCompleted()
+	results := manager.GetInstallationResults()
+	events.Emit(events.Event{Type: events.RunSucceeded, DurationMs: time.Since(startTime).Milliseconds(), Metadata: map[string]interface{}{
+		"completed": results.CompletedSteps,
+		"skipped":   results.SkippedSteps,
+		"failed":    results.FailedSteps,
+	}})
+	if !installDryRun {
+		manager.CollectGettingStarted()
+	}
+
+	// Persist state so parameters remain available to later commands
+	if err := manager.SaveState(); err != nil {
+		logger.Warn().Err(err).Msg("Failed to save installation state")
+	}
 
 	// Generate final installation report
-	if err := manager.GenerateFinalReport(); err != nil {
+	if err := manager.GenerateFinalReport(nil); err != nil {
 		logger.Warn().Err(err).Msg("Failed to generate final installation report")
 	}
 
 	// Success summary
 	duration := time.Since(startTime)
-	pterm.Success.Printf("🎉 E2E Kubernetes installation completed successfully in %v\n", duration.Round(time.Second))
+	failedSteps := results.FailedSteps + manager.triagedSkips()
+	if failedSteps > 0 {
+		manager.closeChangeTicket(false, fmt.Sprintf("Installation completed with %d failed steps in %v", failedSteps, duration.Round(time.Second)))
+	} else {
+		manager.closeChangeTicket(true, fmt.Sprintf("Installation completed successfully in %v", duration.Round(time.Second)))
+	}
+	if failedSteps > 0 {
+		pterm.Warning.Printf("⚠️  E2E Kubernetes installation completed with %d failed steps in %v\n", failedSteps, duration.Round(time.Second))
+	} else {
+		pterm.Success.Printf("🎉 E2E Kubernetes installation completed successfully in %v\n", duration.Round(time.Second))
+	}
 
 	// Display installation summary
 	pterm.DefaultSection.Println("Installation Summary")
 
-	results := manager.GetInstallationResults()
 	info := [][]string{
 		{"Workspace", manager.GetWorkspace()},
 		{"Total Steps", fmt.Sprintf("%d", results.TotalSteps)},
@@ -276,10 +495,15 @@ func runInstall(cmd *cobra.Command, args []string) error {
 
 	pterm.DefaultTable.WithHasHeader().WithData(stepData).Render()
 
+	printGettingStarted(manager.gettingStarted)
+
 	// Display final information
 	if manager.GetReportPath() != "" {
 		pterm.DefaultSection.Println("Installation Report")
 		pterm.Info.Printf("📊 Final installation report: %s\n", manager.GetReportPath())
+		if manager.GetHTMLReportPath() != "" {
+			pterm.Info.Printf("📊 Executive summary: %s\n", manager.GetHTMLReportPath())
+		}
 	}
 
 	if manager.GetStateFile() != "" {
@@ -294,7 +518,10 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		Float64("success_rate", results.SuccessRate).
 		Msg("Installation completed")
 
-	return nil
+	if failedSteps > 0 {
+		return exitcode.Wrap(exitcode.Partial, fmt.Errorf("installation completed with %d failed steps", failedSteps))
+	}
+	return checkFailOn(collectFindings(manager.reportDirs()))
 }
 
 // InstallationStep represents a single installation step
@@ -305,6 +532,8 @@ type InstallationStep struct {
 	Required     bool
 	Dependencies []string
 	Handler      func() error
+	ExitCode     int    // process exit code when the step fails the installation
+	Replaced     string // command installer.steps.replace runs in place of the built-in step
 }
 
 // InstallationResults represents the results of installation execution
@@ -326,26 +555,40 @@ type CompletedStep struct {
 	Failed      bool
 	Skipped     bool
 	Error       string
+	LogTail     []string `json:",omitempty"` // last output lines of the tool the failed step ran
+}
+
+// stepLogTail returns the tool output kept with the error of a failed step
+func stepLogTail(err error) []string {
+	if tail := logtail.Of(err); tail != nil {
+		return tail.LogTail
+	}
+	return nil
 }
 
 // InstallationManager handles the complete installation orchestration
 type InstallationManager struct {
 	config     *config.InstallerConfig
+	configFile string // file the configuration was loaded from, empty for the sample configuration
 	logger     zerolog.Logger
 	workspace  string
 	stateFile  string
 	reportPath string
+	htmlReport string
 	state      *config.InstallState
-	results    InstallationResults
-	completed  []CompletedStep
+	// where the installed applications are reached, looked up once the installation completed
+	gettingStarted *gettingStarted
+	results        InstallationResults
+	completed      []CompletedStep
+	ticket         *changeticket.Ticket // change ticket of the run, nil without one
+	stateSaved     bool                 // the state file holds the state of this run
 }
 
 // NewInstallationManager creates a new installation manager
 func NewInstallationManager(config *config.InstallerConfig, logger zerolog.Logger) (*InstallationManager, error) {
-	workspace := config.Installer.Workspace
-	if installWorkspace != "" {
-		workspace = installWorkspace
-	}
+	workspace := selectedWorkspace(config.Installer.Workspace)
+	// Handlers and the packages they call read the workspace from the configuration
+	config.Installer.Workspace = workspace
 
 	stateFile := installStateFile
 	if stateFile == "" {
@@ -381,38 +624,180 @@ func (m *InstallationManager) ApplyCommandLineOverrides() {
 	}
 }
 
-// LoadState loads installation state from file
+// LoadState loads installation state from file. The previous state is continued with --resume,
+// and with --approve when the last run paused at an approval gate.
 func (m *InstallationManager) LoadState() error {
-	if !installResume {
+	previous, err := m.readState()
+	if err != nil {
+		return err
+	}
+	resume := installResume || (len(installApprove) > 0 && previous != nil && previous.Status == installStatusPaused)
+
+	// Parameters produced by earlier steps are only carried over when resuming
+	if err := params.InitGlobalStore(m.stateFile, resume); err != nil {
+		return fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+
+	if !resume || previous == nil {
+		if resume {
+			m.logger.Warn().Str("state_file", m.stateFile).Msg("No installation state to resume, starting over")
+		} else if step := interruptedStep(previous); step != "" {
+			pterm.Warning.Printf("The last installation was interrupted during %s; starting over, pass --resume to continue it instead\n", step)
+		}
 		// Initialize new state
 		m.state = &config.InstallState{
 			Steps:     []config.StepState{},
 			StartTime: time.Now(),
-			Status:    "running",
+			Status:    installStatusRunning,
 		}
 		return nil
 	}
 
-	// TODO: Implement actual state loading from file
-	// This would typically involve:
-	// 1. Reading state file
-	// 2. Parsing JSON state
-	// 3. Validating state consistency
-	// 4. Preparing for resume
+	m.logger.Info().
+		Str("state_file", m.stateFile).
+		Str("paused_at", previous.PausedAt).
+		Str("interrupted_at", interruptedStep(previous)).
+		Msg("Loading installation state for resume")
+
+	m.state = previous
+	m.state.Status = installStatusRunning
+	m.state.Resume = true
+	m.state.EndTime = nil
+	m.state.LastError = ""
+	return nil
+}
 
-	m.logger.Info().Str("state_file", m.stateFile).Msg("Loading installation state for resume")
+// readState returns the state file of the last installation, nil when there is none
+func (m *InstallationManager) readState() (*config.InstallState, error) {
+	data, err := os.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation state: %w", err)
+	}
+	var state config.InstallState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse installation state %s: %w", m.stateFile, err)
+	}
+	return &state, nil
+}
 
-	// For now, create a new state
-	m.state = &config.InstallState{
-		Steps:     []config.StepState{},
-		StartTime: time.Now(),
-		Status:    "running",
-		Resume:    true,
+// stepCompleted reports whether a resumed installation already completed step
+func (m *InstallationManager) stepCompleted(name string) bool {
+	if !m.state.Resume {
+		return false
+	}
+	for _, step := range m.state.Steps {
+		if step.Name == name {
+			return step.Status == "completed"
+		}
+	}
+	return false
+}
+
+// recordStepState updates the state of step for later resumes and checkpoints the state file,
+// so an installation killed midway resumes after its last finished step. A step recorded as
+// running when the installer died is run again.
+func (m *InstallationManager) recordStepState(name, status string, started time.Time, stepErr error) {
+	state := config.StepState{Name: name, Status: status, StartTime: &started}
+	if status != "running" {
+		now := time.Now()
+		state.EndTime = &now
+	}
+	if stepErr != nil {
+		state.Error = stepErr.Error()
+	}
+	found := false
+	for i := range m.state.Steps {
+		if m.state.Steps[i].Name == name {
+			// Every attempt after the first is a retry, however many states it went through
+			state.Retries = m.state.Steps[i].Retries
+			if status == "running" {
+				state.Retries++
+			}
+			m.state.Steps[i] = state
+			found = true
+			break
+		}
+	}
+	if !found {
+		m.state.Steps = append(m.state.Steps, state)
+	}
+	emitStepEvent(state)
+	if status != "running" {
+		m.ticket.Note(stepNote(state))
+	}
+	// Every completed step is a checkpoint rollback --to returns the environment to
+	if status == "completed" && !m.config.Installer.DryRun {
+		rollback.Checkpoint(name)
 	}
 
+	if err := m.SaveState(); err != nil {
+		m.logger.Warn().Err(err).Str("step", name).Msg("Failed to checkpoint installation state")
+	}
+}
+
+// stepEventTypes maps step states to the lifecycle events announcing them
+var stepEventTypes = map[string]string{
+	"running":   events.StepStarted,
+	"completed": events.StepSucceeded,
+	"failed":    events.StepFailed,
+	"skipped":   events.StepSkipped,
+}
+
+func emitStepEvent(state config.StepState) {
+	event := events.Event{Type: stepEventTypes[state.Status], Step: state.Name, Attempt: state.Retries + 1, Error: state.Error}
+	if state.StartTime != nil && state.EndTime != nil {
+		event.DurationMs = state.EndTime.Sub(*state.StartTime).Milliseconds()
+	}
+	events.Emit(event)
+}
+
+// interruptedStep returns the step a previous installation was running when it died, "" when
+// it ended on its own
+func interruptedStep(state *config.InstallState) string {
+	if state == nil || state.Status != installStatusRunning {
+		return ""
+	}
+	for _, step := range state.Steps {
+		if step.Status == "running" {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// EnsureCredentials generates missing credentials and publishes them to the parameter store
+func (m *InstallationManager) EnsureCredentials() error {
+	result, err := publishCredentials(m.config, m.workspace)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info().
+		Strs("generated", result.Generated).
+		Strs("reused", result.Reused).
+		Msg("Credentials available to charts and tests")
 	return nil
 }
 
+// publishCredentials ensures the configured credentials exist in their backend and
+// publishes them to the global parameter store
+func publishCredentials(cfg *config.InstallerConfig, workspace string) (*credentials.EnsureResult, error) {
+	credMgr, err := credentials.NewManager(cfg.Security.Credentials, workspace, &cfg.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+
+	values, result, err := credMgr.Ensure()
+	if err != nil {
+		return nil, err
+	}
+	credMgr.Publish(params.GetStore(), values)
+	return result, nil
+}
+
 // SaveState saves installation state to file
 func (m *InstallationManager) SaveState() error {
 	// Create workspace directory if it doesn't exist
@@ -420,13 +805,47 @@ func (m *InstallationManager) SaveState() error {
 		return fmt.Errorf("failed to create workspace directory: %w", err)
 	}
 
-	// TODO: Implement actual state saving to file
-	// This would typically involve:
-	// 1. Serializing state to JSON
-	// 2. Writing to state file
-	// 3. Setting appropriate permissions
+	m.state.RunID = history.RunID()
+
+	// Module states, parameters and the rollback journal are written to the file as the steps
+	// make their changes, by the step commands too; they are read under the state file lock
+	// and kept. Parameters of an earlier installation are only kept when resuming it.
+	err := workspace.UpdateState(m.stateFile, func(sections map[string]json.RawMessage) error {
+		if raw, ok := sections["parameters"]; ok && (m.stateSaved || m.state.Resume) {
+			var published map[string]config.Parameter
+			if err := json.Unmarshal(raw, &published); err == nil {
+				params.GetStore().Merge(published)
+			}
+		}
+		m.state.Parameters = params.GetStore().Export()
+		if raw, ok := sections["modules"]; ok {
+			var modules map[string]config.ModuleState
+			if err := json.Unmarshal(raw, &modules); err == nil && len(modules) > 0 {
+				if m.state.Modules == nil {
+					m.state.Modules = make(map[string]config.ModuleState, len(modules))
+				}
+				for name, module := range modules {
+					m.state.Modules[name] = module
+				}
+			}
+		}
+		if raw, ok := sections["actions"]; ok {
+			var actions []config.RollbackAction
+			if err := json.Unmarshal(raw, &actions); err == nil {
+				m.state.Actions = actions
+			}
+		}
+		return workspace.ReplaceState(sections, m.state)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write installation state: %w", err)
+	}
+	m.stateSaved = true
 
-	m.logger.Info().Str("state_file", m.stateFile).Msg("Installation state saved")
+	m.logger.Debug().
+		Str("state_file", m.stateFile).
+		Int("parameters", len(m.state.Parameters)).
+		Msg("Installation state saved")
 	return nil
 }
 
@@ -467,11 +886,64 @@ func (m *InstallationManager) FilterSteps(steps []InstallationStep) []Installati
 	return steps
 }
 
+// CheckLicensedSteps fails when the license does not enable a step that is about to run
+func (m *InstallationManager) CheckLicensedSteps(steps []InstallationStep) error {
+	if _, err := license.InitGlobal(m.config.Installer.License, m.workspace); err != nil {
+		return err
+	}
+	var denied []string
+	for _, step := range steps {
+		// A replaced step runs the operator's command, not the licensed module
+		if step.Name == "setup" || step.Replaced != "" {
+			continue
+		}
+		if err := license.Require(step.Name); err != nil {
+			denied = append(denied, step.Name)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("license %s does not include %s; exclude them with --skip-steps %s",
+			license.Current().ID, strings.Join(denied, ", "), strings.Join(denied, ","))
+	}
+	return nil
+}
+
 // ExecuteStepsSequential executes installation steps sequentially
 func (m *InstallationManager) ExecuteStepsSequential(ctx context.Context, steps []InstallationStep, progressArea *pterm.AreaPrinter) error {
 	for i, step := range steps {
 		stepProgress := fmt.Sprintf("[%d/%d] %s", i+1, len(steps), step.Description)
+
+		if m.stepCompleted(step.Name) {
+			m.logger.Info().Str("step", step.Name).Msg("Step completed before the installation was resumed")
+			m.completed = append(m.completed, CompletedStep{
+				Name:        step.Name,
+				Description: step.Description,
+				Skipped:     true,
+			})
+			m.results.SkippedSteps++
+			m.results.TotalSteps++
+			progressArea.Update(pterm.Sprintf("⏭️  %s (completed in an earlier run)", stepProgress))
+			continue
+		}
+
+		// Gates after a step hold back the rest of the installation
+		if i > 0 {
+			if err := m.passGates(steps[i-1].Name, false, progressArea); err != nil {
+				return err
+			}
+		}
+		if err := m.passGates(step.Name, true, progressArea); err != nil {
+			return err
+		}
+		if !installDryRun {
+			if err := awaitMaintenanceWindow(m.config, step.Name); err != nil {
+				m.state.Status = installStatusScheduled
+				return err
+			}
+		}
 		progressArea.Update(pterm.Sprintf("🔄 %s", stepProgress))
+		ci.StartGroup(stepProgress)
+		logger.SetToolStep(step.Name)
 
 		m.logger.Info().
 			Str("step", step.Name).
@@ -480,6 +952,9 @@ func (m *InstallationManager) ExecuteStepsSequential(ctx context.Context, steps
 			Msg("Starting installation step")
 
 		stepStart := time.Now()
+		if !installDryRun {
+			m.recordStepState(step.Name, "running", stepStart, nil)
+		}
 
 		if installDryRun {
 			m.logger.Info().Str("step", step.Name).Msg("DRY RUN: Step execution skipped")
@@ -494,51 +969,32 @@ func (m *InstallationManager) ExecuteStepsSequential(ctx context.Context, steps
 			})
 			m.results.CompletedSteps++
 		} else {
-			if err := step.Handler(); err != nil {
-				stepDuration := time.Since(stepStart)
-				m.completed = append(m.completed, CompletedStep{
-					Name:        step.Name,
-					Description: step.Description,
-					Duration:    stepDuration,
-					Failed:      true,
-					Skipped:     false,
-					Error:       err.Error(),
-				})
-
-				m.results.FailedSteps++
-				m.logger.Error().
-					Err(err).
-					Str("step", step.Name).
-					Dur("duration", stepDuration).
-					Msg("Installation step failed")
+			skipped, err := m.runStep(step, progressArea)
+			m.recordStepResult(step, stepStart, skipped, err)
+			if skipped {
+				progressArea.Update(pterm.Sprintf("⏭️  %s (failed, skipped by operator)", stepProgress))
+				ci.Warning(fmt.Sprintf("Step %s failed, skipped by the operator", step.Name), err.Error())
+			} else if err != nil {
+				if errors.Is(err, errInstallAborted) {
+					progressArea.Update(pterm.Sprintf("❌ %s", stepProgress))
+					return exitcode.Wrap(step.ExitCode, fmt.Errorf("installation step '%s' failed: %w", step.Name, err))
+				}
 
 				// Check if step is required or if we should continue on error
 				if step.Required && !installContinueOnError {
 					progressArea.Update(pterm.Sprintf("❌ %s", stepProgress))
-					return fmt.Errorf("required installation step '%s' failed: %w", step.Name, err)
+					return exitcode.Wrap(step.ExitCode, fmt.Errorf("required installation step '%s' failed: %w", step.Name, err))
 				}
 
 				// Continue with non-required steps or when continue-on-error is enabled
 				progressArea.Update(pterm.Sprintf("⚠️  %s (failed but continuing)", stepProgress))
+				ci.Warning(fmt.Sprintf("Step %s failed, the installation continues", step.Name), err.Error())
 			} else {
-				stepDuration := time.Since(stepStart)
-				m.completed = append(m.completed, CompletedStep{
-					Name:        step.Name,
-					Description: step.Description,
-					Duration:    stepDuration,
-					Failed:      false,
-					Skipped:     false,
-				})
-
-				m.results.CompletedSteps++
 				progressArea.Update(pterm.Sprintf("✅ %s", stepProgress))
-				m.logger.Info().
-					Str("step", step.Name).
-					Dur("duration", stepDuration).
-					Msg("Installation step completed successfully")
 			}
 		}
 
+		ci.EndGroup()
 		m.results.TotalSteps++
 		time.Sleep(300 * time.Millisecond) // Visual feedback
 	}
@@ -551,30 +1007,68 @@ func (m *InstallationManager) ExecuteStepsSequential(ctx context.Context, steps
 	return nil
 }
 
-// ExecuteStepsParallel executes installation steps in parallel where possible
-func (m *InstallationManager) ExecuteStepsParallel(ctx context.Context, steps []InstallationStep, progressArea *pterm.AreaPrinter) error {
-	// TODO: Implement proper parallel execution with dependency resolution
-	// For now, execute sequentially but with different messaging
-	progressArea.Update("🔄 Executing installation steps with parallelization...")
-
-	return m.ExecuteStepsSequential(ctx, steps, progressArea)
+// recordStepResult books the outcome of a step that ran: failed, skipped by the operator after
+// failing, or completed
+func (m *InstallationManager) recordStepResult(step InstallationStep, stepStart time.Time, skipped bool, err error) {
+	stepDuration := time.Since(stepStart)
+	telemetry.RecordStep(step.Name, stepDuration, err)
+	completed := CompletedStep{
+		Name:        step.Name,
+		Description: step.Description,
+		Duration:    stepDuration,
+		Failed:      err != nil && !skipped,
+		Skipped:     skipped,
+	}
+	if err != nil {
+		completed.Error = err.Error()
+		completed.LogTail = stepLogTail(err)
+	}
+	m.completed = append(m.completed, completed)
+
+	switch {
+	case skipped:
+		m.results.SkippedSteps++
+		m.recordStepState(step.Name, "skipped", stepStart, err)
+	case err != nil:
+		m.results.FailedSteps++
+		m.recordStepState(step.Name, "failed", stepStart, err)
+		m.logger.Error().
+			Err(err).
+			Str("step", step.Name).
+			Dur("duration", stepDuration).
+			Msg("Installation step failed")
+	default:
+		m.results.CompletedSteps++
+		m.recordStepState(step.Name, "completed", stepStart, nil)
+		m.logger.Info().
+			Str("step", step.Name).
+			Dur("duration", stepDuration).
+			Msg("Installation step completed successfully")
+	}
 }
 
 // MarkCompleted marks the installation as completed
 func (m *InstallationManager) MarkCompleted() {
 	now := time.Now()
 	m.results.EndTime = &now
-	m.state.Status = "completed"
+	m.state.Status = installStatusCompleted
 	m.state.EndTime = &now
 }
 
-// GenerateFinalReport generates the final installation report
-func (m *InstallationManager) GenerateFinalReport() error {
+// GenerateFinalReport generates the final installation report, and the HTML executive summary
+// when --report-format html is set. runErr is the error the installation failed with, if any.
+func (m *InstallationManager) GenerateFinalReport(runErr error) error {
 	// Create reports directory
 	if err := os.MkdirAll(filepath.Dir(m.reportPath), 0755); err != nil {
 		return fmt.Errorf("failed to create reports directory: %w", err)
 	}
 
+	// Failed installs never reach MarkCompleted
+	endTime := time.Now()
+	if m.results.EndTime != nil {
+		endTime = *m.results.EndTime
+	}
+
 	report := map[string]interface{}{
 		"timestamp":       time.Now().UTC().Format(time.RFC3339),
 		"workspace":       m.workspace,
@@ -584,68 +1078,99 @@ func (m *InstallationManager) GenerateFinalReport() error {
 		"skipped_steps":   m.results.SkippedSteps,
 		"success_rate":    m.results.SuccessRate,
 		"start_time":      m.results.StartTime.Format(time.RFC3339),
-		"end_time":        m.results.EndTime.Format(time.RFC3339),
+		"end_time":        endTime.Format(time.RFC3339),
 		"duration":        time.Since(m.results.StartTime).String(),
 		"steps":           m.completed,
 		"dry_run":         installDryRun,
 		"resumed":         installResume,
 		"status":          "completed",
 	}
+	if len(m.state.Approvals) > 0 {
+		report["approvals"] = m.state.Approvals
+	}
+	if runErr != nil {
+		report["status"] = "failed"
+		report["error"] = runErr.Error()
+		if tail := logtail.Of(runErr); tail != nil {
+			report["log_tail"] = tail.LogTail
+		}
+		if errors.Is(runErr, errInstallPaused) {
+			report["status"] = installStatusPaused
+			report["paused_at"] = m.state.PausedAt
+		} else if errors.Is(runErr, errOutsideWindow) {
+			report["status"] = installStatusScheduled
+		}
+	}
+	if info := license.ReportInfo(); info != nil {
+		report["license"] = info
+	}
+	if m.gettingStarted != nil {
+		report["getting_started"] = m.gettingStarted
+	}
 
-	// TODO: Write actual report to file
+	if err := writeReport(m.reportPath, report); err != nil {
+		return err
+	}
 	m.logger.Info().Interface("report", report).Str("report_path", m.reportPath).Msg("Final installation report generated")
+
+	if strings.ToLower(installReportFormat) == installReportHTML {
+		path, err := m.writeHTMLReport(runErr)
+		if err != nil {
+			return err
+		}
+		m.htmlReport = path
+		m.logger.Info().Str("report_path", path).Msg("HTML installation report generated")
+	}
+	// Inside CI jobs the summary becomes the job summary
+	if ci.Enabled() {
+		path, err := m.writeMarkdownReport(runErr)
+		if err != nil {
+			return err
+		}
+		m.logger.Info().Str("report_path", path).Msg("Markdown installation report generated")
+	}
 	return nil
 }
 
-// Step handler methods (these would call the actual commands)
+// Step handler methods: setup runs in this process, the other steps run their commands
 
 func (m *InstallationManager) RunSetup() error {
-	// TODO: Call the actual setup command
-	time.Sleep(2 * time.Second) // Simulate setup
-	m.logger.Info().Msg("Setup step completed")
+	if m.config.Installer.DryRun {
+		m.logger.Info().Str("workspace", m.workspace).Msg("DRY RUN: would bootstrap workspace")
+		return nil
+	}
+
+	// The configuration is already loaded, so only the workspace layout and tools are bootstrapped
+	if err := bootstrapWorkspace(m.workspace, m.config.Installer.Tools); err != nil {
+		return err
+	}
+	m.logger.Info().Str("workspace", m.workspace).Msg("Setup step completed")
 	return nil
 }
 
 func (m *InstallationManager) RunPackagePull() error {
-	// TODO: Call the actual package-pull command
-	time.Sleep(3 * time.Second) // Simulate package pull
-	m.logger.Info().Msg("Package pull step completed")
-	return nil
+	return m.commandStep("package-pull")()
 }
 
 func (m *InstallationManager) RunProvisionInfra() error {
-	// TODO: Call the actual provision-infra command
-	time.Sleep(4 * time.Second) // Simulate infrastructure provisioning
-	m.logger.Info().Msg("Infrastructure provisioning step completed")
-	return nil
+	// The installation itself is the approval of the plan
+	return m.commandStep("provision-infra", "--auto-approve")()
 }
 
 func (m *InstallationManager) RunDBMigrate() error {
-	// TODO: Call the actual db-migrate command
-	time.Sleep(2 * time.Second) // Simulate database migration
-	m.logger.Info().Msg("Database migration step completed")
-	return nil
+	return m.commandStep("db-migrate")()
 }
 
 func (m *InstallationManager) RunDeploy() error {
-	// TODO: Call the actual deploy command
-	time.Sleep(3 * time.Second) // Simulate deployment
-	m.logger.Info().Msg("Deployment step completed")
-	return nil
+	return m.commandStep("deploy")()
 }
 
 func (m *InstallationManager) RunPostValidate() error {
-	// TODO: Call the actual post-validate command
-	time.Sleep(2 * time.Second) // Simulate post-validation
-	m.logger.Info().Msg("Post-validation step completed")
-	return nil
+	return m.commandStep("post-validate")()
 }
 
 func (m *InstallationManager) RunE2ETest() error {
-	// TODO: Call the actual e2e-test command
-	time.Sleep(4 * time.Second) // Simulate E2E testing
-	m.logger.Info().Msg("E2E testing step completed")
-	return nil
+	return m.commandStep("e2e-test")()
 }
 
 // Helper methods
@@ -662,6 +1187,10 @@ func (m *InstallationManager) GetReportPath() string {
 	return m.reportPath
 }
 
+func (m *InstallationManager) GetHTMLReportPath() string {
+	return m.htmlReport
+}
+
 func (m *InstallationManager) GetInstallationResults() InstallationResults {
 	return m.results
 }
@@ -671,17 +1200,9 @@ func (m *InstallationManager) GetCompletedSteps() []CompletedStep {
 }
 
 func loadInstallConfig(configPath string) (*config.InstallerConfig, error) {
-	// Load configuration from file or use defaults
-	// For now, return a default configuration
-
-	config := config.GenerateDefaultConfig()
-
-	// TODO: Implement actual configuration loading from file
-	// This would typically involve:
-	// 1. Reading configuration file
-	// 2. Parsing YAML/JSON configuration
-	// 3. Validating configuration structure
-	// 4. Merging with defaults
-
-	return config, nil
+	// Without a configuration file the sample configuration is installed
+	if configPath == "" {
+		return config.GenerateDefaultConfig(), nil
+	}
+	return config.LoadConfig(configPath)
 }
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/credentials"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+)
+
+// gettingStarted is where operators reach the installation after it completed
+type gettingStarted struct {
+	AccessPoints []k8s.AccessPoint      `json:"access_points"`
+	Secrets      []k8s.CredentialSecret `json:"credential_secrets"`
+	Credentials  string                 `json:"installer_credentials,omitempty"` // backend of the credentials the installer generated
+}
+
+// CollectGettingStarted looks up the URLs, monitoring endpoints and credential Secrets of the
+// installed releases for the summary and the report. The lookup is best-effort, an
+// unreachable cluster only leaves it out.
+func (m *InstallationManager) CollectGettingStarted() {
+	started := &gettingStarted{}
+
+	monitoring := m.config.Monitoring
+	if monitoring.Enabled {
+		if monitoring.Grafana.Enabled && monitoring.Grafana.Endpoint != "" {
+			started.AccessPoints = append(started.AccessPoints, configuredEndpoint("grafana", monitoring.Grafana.Namespace, monitoring.Grafana.Endpoint))
+		}
+		if monitoring.Prometheus.Enabled && monitoring.Prometheus.Endpoint != "" {
+			started.AccessPoints = append(started.AccessPoints, configuredEndpoint("prometheus", monitoring.Prometheus.Namespace, monitoring.Prometheus.Endpoint))
+		}
+	}
+
+	if k8sMgr, err := k8s.NewManager(&m.config.Kubernetes); err != nil {
+		m.logger.Warn().Err(err).Msg("Skipping the lookup of application URLs")
+	} else if info, err := k8sMgr.AccessInfo(m.accessNamespaces()); err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to look up application URLs")
+	} else {
+		for _, point := range info.AccessPoints {
+			if !hasAccessURL(started.AccessPoints, point.URL) {
+				started.AccessPoints = append(started.AccessPoints, point)
+			}
+		}
+		started.Secrets = info.Secrets
+	}
+
+	if len(m.config.Security.Credentials.Items) > 0 {
+		credMgr, err := credentials.NewManager(m.config.Security.Credentials, m.workspace, &m.config.Kubernetes)
+		if err == nil {
+			started.Credentials = credMgr.Location()
+		}
+	}
+
+	if len(started.AccessPoints) == 0 && len(started.Secrets) == 0 && started.Credentials == "" {
+		return
+	}
+	m.gettingStarted = started
+}
+
+// accessNamespaces are the namespaces the installation deploys to; none, so every namespace
+// is looked at, when the application namespace is not configured
+func (m *InstallationManager) accessNamespaces() []string {
+	if m.config.Kubernetes.Namespace == "" {
+		return nil
+	}
+	var namespaces []string
+	monitoring := m.config.Monitoring
+	for _, namespace := range []string{
+		m.config.Kubernetes.Namespace, m.config.Kubernetes.Ingress.Namespace,
+		monitoring.Namespace, monitoring.Grafana.Namespace, monitoring.Prometheus.Namespace,
+	} {
+		if namespace != "" && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+func configuredEndpoint(name, namespace, endpoint string) k8s.AccessPoint {
+	return k8s.AccessPoint{
+		Category:  k8s.AccessMonitoring,
+		Release:   name,
+		Namespace: namespace,
+		URL:       endpoint,
+		Source:    "configuration",
+	}
+}
+
+func hasAccessURL(points []k8s.AccessPoint, url string) bool {
+	for _, point := range points {
+		if strings.TrimSuffix(point.URL, "/") == strings.TrimSuffix(url, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// printGettingStarted shows the URLs, admin consoles, monitoring endpoints and credential
+// locations of the installation
+func printGettingStarted(started *gettingStarted) {
+	if started == nil {
+		return
+	}
+	pterm.DefaultSection.Println("Getting Started")
+
+	if len(started.AccessPoints) > 0 {
+		headings := map[string]string{
+			k8s.AccessApplication: "Application",
+			k8s.AccessAdmin:       "Admin Console",
+			k8s.AccessMonitoring:  "Monitoring",
+		}
+		data := [][]string{{"Type", "Release", "Namespace", "URL", "Exposed By"}}
+		for _, point := range started.AccessPoints {
+			data = append(data, []string{headings[point.Category], point.Release, point.Namespace, point.URL, point.Source})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	}
+
+	if len(started.Secrets) > 0 {
+		data := [][]string{{"Secret", "Namespace", "Release", "Keys"}}
+		for _, secret := range started.Secrets {
+			data = append(data, []string{secret.Name, secret.Namespace, secret.Release, strings.Join(secret.Keys, ", ")})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+		pterm.Info.Println("🔑 Read an initial password with: kubectl get secret <secret> -n <namespace> -o jsonpath='{.data.<key>}' | base64 -d")
+	}
+
+	if started.Credentials != "" {
+		pterm.Info.Printf("🔐 Credentials generated by the installer: %s\n", started.Credentials)
+	}
+	for _, point := range started.AccessPoints {
+		if strings.Contains(point.URL, ".svc:") {
+			pterm.Info.Println("🔌 Cluster-internal endpoints are reachable with: e2e-k8s-installer port-forward <release>")
+			break
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/changeticket"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/pterm/pterm"
+)
+
+// installPlanReportName is the plan install writes and attaches to the change ticket
+const installPlanReportName = "install-plan.json"
+
+// openChangeTicket opens the change ticket of the run with the plan attached. A ticket given
+// with --change-ticket, or left open by a paused or interrupted run being resumed, is
+// reported to instead of opening another.
+func (m *InstallationManager) openChangeTicket(steps []InstallationStep) error {
+	cfg := m.config.Installer.ChangeTicket
+	if !changeticket.Applies(cfg, m.config.Labels.Environment) {
+		return nil
+	}
+	// Simulated and replayed runs answer from inside the process, the network is never used
+	if simulateFlag || replayFlag != "" {
+		pterm.Info.Println("Change ticket not opened in a simulated or replayed run")
+		return nil
+	}
+
+	plan := m.installPlan(steps)
+	if err := writeReport(filepath.Join(filepath.Dir(m.reportPath), installPlanReportName), plan); err != nil {
+		pterm.Warning.Printf("Failed to save the installation plan: %v\n", err)
+	}
+	planData, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal installation plan: %w", err)
+	}
+
+	stepNames := make([]string, len(steps))
+	for i, step := range steps {
+		stepNames[i] = step.Name
+	}
+	target := valueOr(m.config.Labels.Environment, filepath.Base(m.workspace))
+	ticket, err := changeticket.Open(*cfg, valueOr(installChangeTicket, m.state.ChangeTicket), changeticket.Change{
+		Summary: fmt.Sprintf("Install %s with e2e-k8s-installer", target),
+		Description: fmt.Sprintf("Installation run %s of %s by e2e-k8s-installer %s, from workspace %s.\nSteps: %s.\nThe plan is attached as %s.",
+			history.RunID(), target, version.Version, m.workspace, strings.Join(stepNames, ", "), installPlanReportName),
+		PlanName: installPlanReportName,
+		Plan:     planData,
+	})
+	if err != nil {
+		if cfg.Required {
+			return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("%w (installer.changeTicket.required is set)", err))
+		}
+		pterm.Warning.Printf("Installing without a change ticket: %v\n", err)
+		return nil
+	}
+	m.ticket = ticket
+	m.state.ChangeTicket = ticket.Number
+	pterm.Info.Printf("📝 Change ticket %s: %s\n", ticket.Number, ticket.URL)
+	return nil
+}
+
+// installPlan describes what the run is about to do: the steps in order, what each of them
+// deploys and the gates that hold them back
+func (m *InstallationManager) installPlan(steps []InstallationStep) map[string]interface{} {
+	planned := make([]map[string]interface{}, 0, len(steps))
+	for _, step := range steps {
+		action := "run"
+		if m.stepCompleted(step.Name) {
+			action = "skip, completed by the run being resumed"
+		}
+		planned = append(planned, map[string]interface{}{
+			"name":        step.Name,
+			"description": step.Description,
+			"dependsOn":   step.Dependencies,
+			"action":      action,
+		})
+	}
+	charts := make([]map[string]interface{}, 0, len(m.config.Deployment.Helm.Charts))
+	for _, chart := range m.config.Deployment.Helm.Charts {
+		charts = append(charts, map[string]interface{}{
+			"name":      chart.Name,
+			"chart":     chart.Path,
+			"namespace": chart.Namespace,
+			"order":     chart.Order,
+		})
+	}
+	return map[string]interface{}{
+		"timestamp":        time.Now().UTC().Format(time.RFC3339),
+		"runId":            history.RunID(),
+		"installerVersion": version.Version,
+		"workspace":        m.workspace,
+		"environment":      m.config.Labels.Environment,
+		"resume":           m.state.Resume,
+		"steps":            planned,
+		"charts":           charts,
+		"gates":            m.config.Installer.Gates,
+	}
+}
+
+// closeChangeTicket closes the change ticket with the final report attached and linked
+func (m *InstallationManager) closeChangeTicket(succeeded bool, notes string) {
+	if m.ticket == nil {
+		return
+	}
+	link := m.reportPath
+	if reportURL := m.config.Installer.ChangeTicket.ReportURL; reportURL != "" {
+		link = strings.ReplaceAll(reportURL, "{runId}", history.RunID())
+	}
+	m.ticket.Close(succeeded, fmt.Sprintf("%s\nFinal report: %s", notes, link), m.reportPath, m.GetHTMLReportPath())
+	pterm.Info.Printf("📝 Change ticket %s closed\n", m.ticket.Number)
+
+	// A later run opens a ticket of its own
+	m.ticket, m.state.ChangeTicket = nil, ""
+	if err := m.SaveState(); err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to save installation state")
+	}
+}
+
+// stepNote is the ticket note of a finished step
+func stepNote(state config.StepState) string {
+	note := fmt.Sprintf("Step %s %s", state.Name, state.Status)
+	if state.StartTime != nil && state.EndTime != nil {
+		note += fmt.Sprintf(" after %v", state.EndTime.Sub(*state.StartTime).Round(time.Second))
+	}
+	if state.Error != "" {
+		note += ": " + state.Error
+	}
+	return note
+}
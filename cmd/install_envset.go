@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envSetReportName is the cross-environment summary of an --env-set run
+const envSetReportName = "env-set-report.json"
+
+// envSetLocalFlags are handled by the parent and never passed on to the per-environment runs
+var envSetLocalFlags = map[string]bool{
+	"env-set": true, "max-parallel": true, "workspace": true, "config": true, "interactive": true, "state-file": true,
+}
+
+// ansiEscape matches terminal colour and cursor sequences in captured output
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// envRun is the installation of one environment of an --env-set run
+type envRun struct {
+	Env       string        `json:"environment"`
+	Workspace string        `json:"workspace"`
+	Status    string        `json:"status"`
+	ExitCode  int           `json:"exit_code"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"` // nanoseconds
+	Completed int           `json:"completed_steps"`
+	Failed    int           `json:"failed_steps"`
+	Log       string        `json:"log"`
+
+	mu       sync.Mutex
+	started  time.Time
+	activity string
+}
+
+func (r *envRun) setActivity(line string) {
+	r.mu.Lock()
+	r.activity = line
+	r.mu.Unlock()
+}
+
+func (r *envRun) snapshot() (status, activity string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elapsed = r.Duration
+	if r.Status == "running" {
+		elapsed = time.Since(r.started)
+	}
+	return r.Status, r.activity, elapsed
+}
+
+// validateEnvSet rejects flags that cannot apply to several environments at once
+func validateEnvSet(cmd *cobra.Command) error {
+	if workspaceFlag != "" {
+		return fmt.Errorf("--env-set selects the workspaces itself and cannot be combined with --workspace")
+	}
+	if cmd.Flags().Changed("config") {
+		return fmt.Errorf("--env-set uses the configuration of each workspace and cannot be combined with --config")
+	}
+	if installStateFile != "" {
+		return fmt.Errorf("--env-set keeps a state file per workspace and cannot be combined with --state-file")
+	}
+	if installInteractive {
+		return fmt.Errorf("--interactive needs a terminal per run and cannot be combined with --env-set")
+	}
+	seen := make(map[string]bool)
+	for _, env := range installEnvSet {
+		if seen[env] {
+			return fmt.Errorf("environment %s is listed twice in --env-set", env)
+		}
+		seen[env] = true
+		if dir := workspace.Resolve(env); !isDir(dir) {
+			return fmt.Errorf("workspace %s does not exist at %s (create it with: setup --workspace %s)", env, dir, env)
+		}
+	}
+	return nil
+}
+
+// runInstallEnvSet installs every environment of --env-set in its own installer process, with
+// its own workspace, state and log, and summarizes the results across environments
+func runInstallEnvSet(cmd *cobra.Command) error {
+	if err := validateEnvSet(cmd); err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the installer binary: %w", err)
+	}
+	forwarded := forwardedInstallArgs(cmd)
+
+	parallel := installMaxParallel
+	if parallel <= 0 || parallel > len(installEnvSet) {
+		parallel = len(installEnvSet)
+	}
+	pterm.Info.Printf("Installing %d environments, %d at a time\n", len(installEnvSet), parallel)
+
+	runs := make([]*envRun, len(installEnvSet))
+	for i, env := range installEnvSet {
+		dir := workspace.Resolve(env)
+		runs[i] = &envRun{
+			Env:       env,
+			Workspace: dir,
+			Status:    "queued",
+			Log:       filepath.Join(dir, "logs", fmt.Sprintf("%s.log", history.RunID())),
+		}
+	}
+
+	area, _ := pterm.DefaultArea.Start()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			area.Update(renderEnvPanes(runs))
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func(run *envRun) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			runEnvInstall(executable, forwarded, run)
+		}(run)
+	}
+	wg.Wait()
+	close(done)
+	area.Update(renderEnvPanes(runs))
+	area.Stop()
+
+	return summarizeEnvSet(runs)
+}
+
+// forwardedInstallArgs rebuilds the install flags the operator set, for the per-environment runs
+func forwardedInstallArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if envSetLocalFlags[flag.Name] {
+			return
+		}
+		value := flag.Value.String()
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			value = strings.Join(slice.GetSlice(), ",")
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+	})
+	return args
+}
+
+// runEnvInstall runs install for one environment and records its outcome
+func runEnvInstall(executable string, forwarded []string, run *envRun) {
+	run.mu.Lock()
+	run.Status, run.started = "running", time.Now()
+	run.mu.Unlock()
+
+	finish := func(status string, code int, err error) {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		run.Status, run.ExitCode, run.Duration = status, code, time.Since(run.started)
+		if err != nil {
+			run.Error = err.Error()
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(run.Log), 0755); err != nil {
+		finish(installStatusFailed, exitcode.Failure, fmt.Errorf("failed to create log directory: %w", err))
+		return
+	}
+	logFile, err := os.Create(run.Log)
+	if err != nil {
+		finish(installStatusFailed, exitcode.Failure, fmt.Errorf("failed to create log: %w", err))
+		return
+	}
+	defer logFile.Close()
+
+	args := append([]string{"install", "--workspace", run.Env}, forwarded...)
+	child := exec.Command(executable, args...)
+	child.Env = os.Environ()
+	reader, writer := io.Pipe()
+	child.Stdout, child.Stderr = writer, writer
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			fmt.Fprintln(logFile, line)
+			if activity := envActivity(line); activity != "" {
+				run.setActivity(activity)
+			}
+		}
+	}()
+
+	runErr := child.Run()
+	writer.Close()
+	<-copied
+
+	var report struct {
+		CompletedSteps int    `json:"completed_steps"`
+		FailedSteps    int    `json:"failed_steps"`
+		Status         string `json:"status"`
+	}
+	// A run that failed early leaves the report of an earlier run in place
+	reportPath := filepath.Join(run.Workspace, "reports", "installation-report.json")
+	if info, err := os.Stat(reportPath); err == nil && !info.ModTime().Before(run.started) {
+		if data, err := os.ReadFile(reportPath); err == nil {
+			json.Unmarshal(data, &report)
+		}
+	}
+	run.Completed, run.Failed = report.CompletedSteps, report.FailedSteps
+
+	code := exitcode.OK
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		code = exitErr.ExitCode()
+		runErr = fmt.Errorf("install exited with code %d, see %s", code, run.Log)
+	} else if runErr != nil {
+		code = exitcode.Failure
+	}
+	status := report.Status
+	switch {
+	case code == exitcode.OK:
+		status = installStatusCompleted
+	case status == "" || status == installStatusCompleted:
+		status = installStatusFailed
+	}
+	finish(status, code, runErr)
+}
+
+// envActivity turns a line of installer output into a short activity description
+func envActivity(line string) string {
+	// Spinners redraw with carriage returns and log entries can follow them on the same line
+	line = ansiEscape.ReplaceAllString(line, "")
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	if i := strings.Index(line, `{"`); i >= 0 {
+		line = line[i:]
+	}
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		var entry struct {
+			Message string `json:"message"`
+			Step    string `json:"step"`
+		}
+		if json.Unmarshal([]byte(line), &entry) != nil || entry.Message == "" {
+			return ""
+		}
+		if entry.Step != "" {
+			return fmt.Sprintf("%s: %s", entry.Step, entry.Message)
+		}
+		return entry.Message
+	}
+	return line
+}
+
+// renderEnvPanes draws one pane line per environment
+func renderEnvPanes(runs []*envRun) string {
+	data := pterm.TableData{{"Environment", "Status", "Elapsed", "Activity"}}
+	for _, run := range runs {
+		status, activity, elapsed := run.snapshot()
+		if len(activity) > 80 {
+			activity = activity[:77] + "..."
+		}
+		data = append(data, []string{run.Env, envStatusLabel(status), elapsed.Round(time.Second).String(), activity})
+	}
+	table, _ := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+	return table
+}
+
+func envStatusLabel(status string) string {
+	switch status {
+	case "queued":
+		return "⏳ Queued"
+	case "running":
+		return "🔄 Running"
+	case installStatusCompleted:
+		return "✅ Completed"
+	case installStatusPaused:
+		return "⏸️  Paused"
+	case installStatusScheduled:
+		return "🗓️  Scheduled"
+	}
+	return "❌ Failed"
+}
+
+// summarizeEnvSet prints and writes the cross-environment summary. The run fails with the
+// environments' exit code when they agree, and as a partial success when only some failed.
+func summarizeEnvSet(runs []*envRun) error {
+	pterm.DefaultSection.Println("Environment Summary")
+	data := pterm.TableData{{"Environment", "Status", "Exit Code", "Duration", "Steps", "Log"}}
+	var failed []string
+	codes := make(map[int]bool)
+	for _, run := range runs {
+		data = append(data, []string{
+			run.Env,
+			envStatusLabel(run.Status),
+			fmt.Sprintf("%d", run.ExitCode),
+			run.Duration.Round(time.Second).String(),
+			fmt.Sprintf("%d completed, %d failed", run.Completed, run.Failed),
+			run.Log,
+		})
+		if run.ExitCode != exitcode.OK {
+			failed = append(failed, fmt.Sprintf("%s (exit %d)", run.Env, run.ExitCode))
+			codes[run.ExitCode] = true
+		}
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+
+	summary := map[string]interface{}{
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		"run":          history.RunID(),
+		"environments": runs,
+		"succeeded":    len(runs) - len(failed),
+		"failed":       len(failed),
+	}
+	path := filepath.Join(reportsDir(), envSetReportName)
+	if err := writeReport(path, summary); err != nil {
+		pterm.Warning.Printf("Failed to write the environment summary: %v\n", err)
+	} else {
+		pterm.Info.Printf("📊 Environment summary: %s\n", path)
+	}
+
+	if len(failed) == 0 {
+		pterm.Success.Printf("🎉 All %d environments installed\n", len(runs))
+		return nil
+	}
+	err := fmt.Errorf("%d of %d environments did not complete: %s", len(failed), len(runs), strings.Join(failed, ", "))
+	switch {
+	case len(codes) == 1 && len(failed) == len(runs):
+		for code := range codes {
+			return exitcode.Wrap(code, err)
+		}
+	case len(failed) < len(runs):
+		return exitcode.Wrap(exitcode.Partial, err)
+	}
+	return err
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// Installation statuses recorded in the state file
+const (
+	installStatusRunning   = "running"
+	installStatusPaused    = "paused"
+	installStatusCompleted = "completed"
+	installStatusFailed    = "failed"
+	installStatusScheduled = "scheduled"
+)
+
+// Approval methods recorded with each gate approval
+const (
+	approvalByFlag        = "flag"
+	approvalByInteractive = "interactive"
+	approvalByCommand     = "command"
+)
+
+// errInstallPaused stops the installation at an approval gate
+var errInstallPaused = errors.New("installation paused for approval")
+
+// gateWebhookTimeout bounds the notification so an unreachable endpoint cannot hold up the pause
+const gateWebhookTimeout = 10 * time.Second
+
+// validateGates rejects gates that refer to steps install does not have
+func (m *InstallationManager) validateGates(steps []InstallationStep) error {
+	known := make(map[string]bool)
+	for _, step := range steps {
+		known[step.Name] = true
+	}
+	for _, gate := range m.config.Installer.Gates {
+		step := gate.Before + gate.After
+		if !known[step] {
+			return fmt.Errorf("approval gate %s refers to unknown step %q", gate.Name, step)
+		}
+	}
+	for _, name := range installApprove {
+		if !slices.ContainsFunc(m.config.Installer.Gates, func(gate config.ApprovalGate) bool { return gate.Name == name }) {
+			return fmt.Errorf("--approve %s does not match an approval gate in the configuration", name)
+		}
+	}
+	return nil
+}
+
+// gatesAt returns the gates that apply before or after step in this environment
+func (m *InstallationManager) gatesAt(step string, before bool) []config.ApprovalGate {
+	var gates []config.ApprovalGate
+	for _, gate := range m.config.Installer.Gates {
+		if (before && gate.Before != step) || (!before && gate.After != step) {
+			continue
+		}
+		if len(gate.Environments) > 0 && !slices.Contains(gate.Environments, m.config.Labels.Environment) {
+			continue
+		}
+		gates = append(gates, gate)
+	}
+	return gates
+}
+
+// passGates lets the installation past the gates before or after step, or pauses it at the
+// first gate nobody has approved
+func (m *InstallationManager) passGates(step string, before bool, progressArea *pterm.AreaPrinter) error {
+	for _, gate := range m.gatesAt(step, before) {
+		if err := m.passGate(gate, progressArea); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *InstallationManager) passGate(gate config.ApprovalGate, progressArea *pterm.AreaPrinter) error {
+	if installDryRun {
+		m.logger.Info().Str("gate", gate.Name).Msg("DRY RUN: would wait for approval")
+		return nil
+	}
+	if m.gateApproved(gate.Name) {
+		return nil
+	}
+	approver, authErr := authorizeApprover(&m.config.Kubernetes, gate)
+	if slices.Contains(installApprove, gate.Name) {
+		if authErr != nil {
+			return authErr
+		}
+		m.approveGate(gate, approvalByFlag, approver)
+		return nil
+	}
+	if authErr != nil {
+		m.logger.Warn().Err(authErr).Msg("Pausing for one of the gate's approvers")
+	}
+
+	if authErr == nil && term.IsTerminal(int(os.Stdin.Fd())) {
+		progressArea.Stop()
+		defer progressArea.Start()
+
+		pterm.DefaultSection.Printf("Approval gate: %s", gate.Name)
+		if gate.Message != "" {
+			pterm.Info.Println(gate.Message)
+		}
+		approved, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(false).Show(fmt.Sprintf("Approve %s and continue the installation?", gate.Name))
+		if err != nil {
+			return fmt.Errorf("failed to read approval for gate %s: %w", gate.Name, err)
+		}
+		if approved {
+			m.approveGate(gate, approvalByInteractive, approver)
+			return nil
+		}
+	}
+
+	m.state.Status = installStatusPaused
+	m.state.PausedAt = gate.Name
+	if gate.Webhook != "" {
+		if err := notifyGateWebhook(gate, m.workspace, m.config.Labels.Environment); err != nil {
+			m.logger.Warn().Err(err).Str("gate", gate.Name).Msg("Failed to notify approval webhook")
+		}
+	}
+	return exitcode.Wrap(exitcode.Paused, fmt.Errorf("%w at gate %s, resume with: e2e-k8s-installer install --approve %s, or approve from elsewhere with: %s",
+		errInstallPaused, gate.Name, gate.Name, approveCommand(gate.Name)))
+}
+
+// approveCommand is the command approving gate for this run without resuming it
+func approveCommand(gate string) string {
+	return fmt.Sprintf("e2e-k8s-installer approve --run %s --gate %s", history.RunID(), gate)
+}
+
+func (m *InstallationManager) gateApproved(name string) bool {
+	for _, approval := range m.state.Approvals {
+		if approval.Gate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// approveGate records the approval in the installation state and the audit log
+func (m *InstallationManager) approveGate(gate config.ApprovalGate, method, approver string) {
+	recordApproval(m.state, config.GateApproval{
+		Gate:       gate.Name,
+		ApprovedBy: approver,
+		Method:     method,
+		RunID:      history.RunID(),
+	})
+	m.state.PausedAt = ""
+	m.logger.Info().Str("gate", gate.Name).Str("approved_by", approver).Str("method", method).Msg("Approval gate passed")
+}
+
+// recordApproval adds an approval to the installation state and the audit log
+func recordApproval(state *config.InstallState, approval config.GateApproval) {
+	approval.ApprovedAt = time.Now().UTC()
+	state.Approvals = append(state.Approvals, approval)
+	details := map[string]interface{}{"method": approval.Method, "runId": approval.RunID, "approvedBy": approval.ApprovedBy}
+	if approval.Comment != "" {
+		details["comment"] = approval.Comment
+	}
+	audit.Record("install.approve", approval.Gate, details, nil)
+}
+
+// authorizeApprover returns who the cluster knows the kubeconfig's user as, from a
+// SelfSubjectReview, or an error when RBAC does not grant them k8s.ApprovalPermission on the
+// gate, or the gate names its approvers and they are neither one of them nor in one of
+// their groups
+func authorizeApprover(k8sConfig *config.K8sConfig, gate config.ApprovalGate) (string, error) {
+	k8sMgr, err := k8s.NewManager(k8sConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to authorize the approval of gate %s: %w", gate.Name, err)
+	}
+	user, err := k8sMgr.CurrentUser()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the approver of gate %s: %w", gate.Name, err)
+	}
+	namespace := valueOr(k8sConfig.Namespace, "default")
+	allowed, reason, err := k8sMgr.CanApprove(gate.Name, namespace)
+	if err != nil {
+		return user.Username, fmt.Errorf("failed to authorize the approval of gate %s: %w", gate.Name, err)
+	}
+	if !allowed {
+		permission := k8s.ApprovalPermission
+		err := fmt.Errorf("%s may not approve gate %s, RBAC does not allow %s on %s.%s/%s in namespace %s",
+			user.Username, gate.Name, permission.Verbs[0], permission.Resources[0], permission.APIGroup, gate.Name, namespace)
+		if reason != "" {
+			err = fmt.Errorf("%w: %s", err, reason)
+		}
+		return user.Username, err
+	}
+	if len(gate.Approvers) == 0 {
+		return user.Username, nil
+	}
+
+	for _, approver := range gate.Approvers {
+		if group, ok := strings.CutPrefix(approver, "group:"); ok {
+			if slices.Contains(user.Groups, group) {
+				return user.Username, nil
+			}
+		} else if approver == user.Username {
+			return user.Username, nil
+		}
+	}
+	return user.Username, fmt.Errorf("%s may not approve gate %s, approvers are %s", user.Username, gate.Name, strings.Join(gate.Approvers, ", "))
+}
+
+// notifyGateWebhook posts the pending approval; the text field renders in Slack and Teams
+// incoming webhooks, the rest is for automation
+func notifyGateWebhook(gate config.ApprovalGate, workspace, environment string) error {
+	resume := fmt.Sprintf("e2e-k8s-installer install --approve %s", gate.Name)
+	payload := map[string]interface{}{
+		"event":       "approval_required",
+		"gate":        gate.Name,
+		"before":      gate.Before,
+		"after":       gate.After,
+		"message":     gate.Message,
+		"environment": environment,
+		"workspace":   workspace,
+		"run":         history.RunID(),
+		"resume":      resume,
+		"approve":     approveCommand(gate.Name),
+		"text":        fmt.Sprintf("Installation in %s is waiting for approval at gate %s. Approve with: %s", valueOr(environment, workspace), gate.Name, resume),
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: gateWebhookTimeout}
+	resp, err := client.Post(gate.Webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/ci"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/pterm/pterm"
+)
+
+// States of a step in a parallel installation
+const (
+	nodePending   = "pending"
+	nodeRunning   = "running"
+	nodeCompleted = "completed" // completed now, in an earlier run, or skipped by the operator
+	nodeFailed    = "failed"
+	nodeBlocked   = "blocked" // never run, a dependency failed
+)
+
+// stepNode is a step of the dependency graph of a parallel installation
+type stepNode struct {
+	step    InstallationStep
+	index   int
+	deps    []string // dependencies among the steps that run
+	status  string
+	started time.Time
+	icon    string // shown before the step on the progress board once it finished
+	note    string // shown after it
+}
+
+// stepGraph orders the steps of a parallel installation by their dependencies
+type stepGraph struct {
+	nodes  []*stepNode
+	byName map[string]*stepNode
+}
+
+// stepOutcome is what the handler of a step returned
+type stepOutcome struct {
+	name string
+	err  error
+}
+
+// newStepGraph builds the dependency graph of steps. Dependencies on steps filtered out of the
+// run count as met; a cycle is an error, its steps would never start.
+func newStepGraph(steps []InstallationStep) (*stepGraph, error) {
+	graph := &stepGraph{byName: make(map[string]*stepNode, len(steps))}
+	for i, step := range steps {
+		node := &stepNode{step: step, index: i, status: nodePending}
+		graph.nodes = append(graph.nodes, node)
+		graph.byName[step.Name] = node
+	}
+	for _, node := range graph.nodes {
+		for _, dep := range node.step.Dependencies {
+			if _, ok := graph.byName[dep]; ok {
+				node.deps = append(node.deps, dep)
+			}
+		}
+	}
+
+	// Kahn's algorithm: whatever cannot be ordered is part of a cycle
+	waiting := make(map[string]int, len(graph.nodes))
+	var ready []string
+	for _, node := range graph.nodes {
+		waiting[node.step.Name] = len(node.deps)
+		if len(node.deps) == 0 {
+			ready = append(ready, node.step.Name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		delete(waiting, name)
+		for _, dependent := range graph.dependents(name) {
+			waiting[dependent.step.Name]--
+			if waiting[dependent.step.Name] == 0 {
+				ready = append(ready, dependent.step.Name)
+			}
+		}
+	}
+	if len(waiting) > 0 {
+		var cycle []string
+		for _, node := range graph.nodes {
+			if _, ok := waiting[node.step.Name]; ok {
+				cycle = append(cycle, node.step.Name)
+			}
+		}
+		return nil, fmt.Errorf("steps %s depend on each other, they cannot run in parallel", strings.Join(cycle, ", "))
+	}
+	return graph, nil
+}
+
+// dependents returns the steps that depend on name directly
+func (g *stepGraph) dependents(name string) []*stepNode {
+	var dependents []*stepNode
+	for _, node := range g.nodes {
+		for _, dep := range node.deps {
+			if dep == name {
+				dependents = append(dependents, node)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// ready reports whether every dependency of node completed
+func (g *stepGraph) ready(node *stepNode) bool {
+	for _, dep := range node.deps {
+		if g.byName[dep].status != nodeCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// ExecuteStepsParallel executes installation steps as soon as their dependencies completed, so
+// independent branches such as db-migrate and deploy run at the same time. A failed step blocks
+// the steps depending on it; when it is required, no further steps start and the installation
+// fails once the running ones have finished.
+func (m *InstallationManager) ExecuteStepsParallel(ctx context.Context, steps []InstallationStep, progressArea *pterm.AreaPrinter) error {
+	graph, err := newStepGraph(steps)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	// CI groups do not nest, the output of steps running together goes into one
+	ci.StartGroup(fmt.Sprintf("Installation steps (%d, in parallel)", len(steps)))
+	defer ci.EndGroup()
+
+	for _, node := range graph.nodes {
+		if m.stepCompleted(node.step.Name) {
+			m.logger.Info().Str("step", node.step.Name).Msg("Step completed before the installation was resumed")
+			m.completed = append(m.completed, CompletedStep{
+				Name:        node.step.Name,
+				Description: node.step.Description,
+				Skipped:     true,
+			})
+			m.results.SkippedSteps++
+			m.results.TotalSteps++
+			node.status, node.icon, node.note = nodeCompleted, "⏭️ ", " (completed in an earlier run)"
+		}
+	}
+
+	outcomes := make(chan stepOutcome, len(graph.nodes))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	done := ctx.Done()
+	gatesPassed := make(map[string]bool)
+	running := 0
+	// stopErr is why no further steps start
+	var stopErr error
+
+	for {
+		if stopErr == nil {
+			var started int
+			started, stopErr = m.startReadySteps(graph, gatesPassed, outcomes, progressArea)
+			running += started
+		}
+		m.renderStepBoard(graph, running, stopErr != nil, progressArea)
+		if running == 0 {
+			break
+		}
+
+		select {
+		case outcome := <-outcomes:
+			running--
+			if err := m.finishParallelStep(graph, outcome, progressArea); err != nil && stopErr == nil {
+				stopErr = err
+			}
+		case <-done:
+			done = nil
+			if stopErr == nil {
+				stopErr = fmt.Errorf("installation interrupted: %w", ctx.Err())
+			}
+		case <-ticker.C:
+		}
+	}
+	if stopErr != nil {
+		return stopErr
+	}
+
+	// Calculate success rate
+	if m.results.TotalSteps > 0 {
+		m.results.SuccessRate = float64(m.results.CompletedSteps) / float64(m.results.TotalSteps) * 100
+	}
+	return nil
+}
+
+// startReadySteps starts every pending step whose dependencies completed, once the gates after
+// its dependencies and before itself are passed and its maintenance window is open. It returns
+// how many steps it started, and the error pausing or scheduling the installation.
+func (m *InstallationManager) startReadySteps(graph *stepGraph, gatesPassed map[string]bool, outcomes chan<- stepOutcome, progressArea *pterm.AreaPrinter) (int, error) {
+	started := 0
+	for _, node := range graph.nodes {
+		if node.status != nodePending || !graph.ready(node) {
+			continue
+		}
+
+		// Gates after a step hold back the steps depending on it
+		for _, dep := range node.deps {
+			if gatesPassed[dep] {
+				continue
+			}
+			if err := m.passGates(dep, false, progressArea); err != nil {
+				return started, err
+			}
+			gatesPassed[dep] = true
+		}
+		if err := m.passGates(node.step.Name, true, progressArea); err != nil {
+			return started, err
+		}
+		if !installDryRun {
+			if err := awaitMaintenanceWindow(m.config, node.step.Name); err != nil {
+				m.state.Status = installStatusScheduled
+				return started, err
+			}
+		}
+
+		m.logger.Info().
+			Str("step", node.step.Name).
+			Str("command", node.step.Command).
+			Bool("required", node.step.Required).
+			Strs("dependencies", node.deps).
+			Msg("Starting installation step")
+
+		node.status, node.started = nodeRunning, time.Now()
+		if !installDryRun {
+			m.recordStepState(node.step.Name, "running", node.started, nil)
+		}
+		started++
+		go func(step InstallationStep) {
+			var err error
+			if installDryRun {
+				m.logger.Info().Str("step", step.Name).Msg("DRY RUN: Step execution skipped")
+			} else {
+				err = step.Handler()
+			}
+			outcomes <- stepOutcome{name: step.Name, err: err}
+		}(node.step)
+	}
+	return started, nil
+}
+
+// finishParallelStep books the outcome of a step and returns the error failing the
+// installation, if any. All bookkeeping happens on the scheduling goroutine, failures are also
+// triaged there in interactive mode.
+func (m *InstallationManager) finishParallelStep(graph *stepGraph, outcome stepOutcome, progressArea *pterm.AreaPrinter) error {
+	node := graph.byName[outcome.name]
+	step := node.step
+	m.results.TotalSteps++
+
+	if installDryRun {
+		m.completed = append(m.completed, CompletedStep{
+			Name:        step.Name,
+			Description: step.Description,
+			Duration:    time.Second,
+		})
+		m.results.CompletedSteps++
+		node.status, node.icon = nodeCompleted, "✅"
+		return nil
+	}
+
+	err, skipped := outcome.err, false
+	if err != nil && installInteractive {
+		skipped, err = m.triageStep(step, err, progressArea)
+	}
+	m.recordStepResult(step, node.started, skipped, err)
+
+	switch {
+	case skipped:
+		node.status, node.icon, node.note = nodeCompleted, "⏭️ ", " (failed, skipped by operator)"
+		ci.Warning(fmt.Sprintf("Step %s failed, skipped by the operator", step.Name), err.Error())
+	case err != nil:
+		node.status, node.icon = nodeFailed, "❌"
+		if errors.Is(err, errInstallAborted) {
+			return exitcode.Wrap(step.ExitCode, fmt.Errorf("installation step '%s' failed: %w", step.Name, err))
+		}
+		if step.Required && !installContinueOnError {
+			return exitcode.Wrap(step.ExitCode, fmt.Errorf("required installation step '%s' failed: %w", step.Name, err))
+		}
+		node.icon, node.note = "⚠️ ", " (failed but continuing)"
+		ci.Warning(fmt.Sprintf("Step %s failed, the installation continues", step.Name), err.Error())
+		return m.blockDependents(graph, node)
+	default:
+		node.status, node.icon = nodeCompleted, "✅"
+	}
+	return nil
+}
+
+// blockDependents skips the steps depending on failed, directly or through other steps. A
+// required step that cannot run fails the installation unless --continue-on-error is set.
+func (m *InstallationManager) blockDependents(graph *stepGraph, failed *stepNode) error {
+	var blockErr error
+	for _, node := range graph.dependents(failed.step.Name) {
+		if node.status != nodePending {
+			continue
+		}
+		err := fmt.Errorf("dependency %s failed", failed.step.Name)
+		now := time.Now()
+		m.completed = append(m.completed, CompletedStep{
+			Name:        node.step.Name,
+			Description: node.step.Description,
+			Skipped:     true,
+			Error:       err.Error(),
+		})
+		m.results.SkippedSteps++
+		m.results.TotalSteps++
+		m.recordStepState(node.step.Name, "skipped", now, err)
+		node.status, node.icon, node.note = nodeBlocked, "⛔", fmt.Sprintf(" (skipped, %s failed)", failed.step.Name)
+		m.logger.Warn().Str("step", node.step.Name).Str("dependency", failed.step.Name).Msg("Installation step skipped, a dependency failed")
+
+		if node.step.Required && !installContinueOnError && blockErr == nil {
+			blockErr = exitcode.Wrap(node.step.ExitCode, fmt.Errorf("required installation step '%s' cannot run: %w", node.step.Name, err))
+		}
+		if err := m.blockDependents(graph, node); err != nil && blockErr == nil {
+			blockErr = err
+		}
+	}
+	return blockErr
+}
+
+// renderStepBoard shows every step of a parallel installation on its own line, so each branch
+// of the graph can be followed
+func (m *InstallationManager) renderStepBoard(graph *stepGraph, running int, stopped bool, progressArea *pterm.AreaPrinter) {
+	finished := 0
+	lines := make([]string, 0, len(graph.nodes)+1)
+	for _, node := range graph.nodes {
+		label := fmt.Sprintf("[%d/%d] %s", node.index+1, len(graph.nodes), node.step.Description)
+		switch node.status {
+		case nodePending:
+			if stopped {
+				lines = append(lines, pterm.Sprintf("⏹️  %s (not started)", label))
+				continue
+			}
+			var waiting []string
+			for _, dep := range node.deps {
+				if graph.byName[dep].status != nodeCompleted {
+					waiting = append(waiting, dep)
+				}
+			}
+			if len(waiting) > 0 {
+				lines = append(lines, pterm.Sprintf("⏳ %s (waiting for %s)", label, strings.Join(waiting, ", ")))
+			} else {
+				lines = append(lines, pterm.Sprintf("⏳ %s", label))
+			}
+		case nodeRunning:
+			lines = append(lines, pterm.Sprintf("🔄 %s (%s)", label, time.Since(node.started).Round(time.Second)))
+		default:
+			finished++
+			lines = append(lines, pterm.Sprintf("%s %s%s", node.icon, label, node.note))
+		}
+	}
+	header := pterm.Sprintf("🔀 Installing %d steps in parallel: %d running, %d finished", len(graph.nodes), running, finished)
+	progressArea.Update(header + "\n" + strings.Join(lines, "\n"))
+}
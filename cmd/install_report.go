@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/ci"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
+	"github.com/judebantony/e2e-k8s-installer/pkg/releasenotes"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+)
+
+// Report formats of the install command
+const (
+	installReportJSON = "json"
+	installReportHTML = "html"
+)
+
+// The HTML and Markdown reports are written next to the JSON installation report
+const (
+	installHTMLReportName     = "installation-report.html"
+	installMarkdownReportName = "installation-report.md"
+)
+
+// The parts of the step reports that the executive summary shows
+type deploymentReportData struct {
+	DeployedCharts []ChartDeploymentStatus `json:"deployed_charts"`
+	ReleaseNotes   []releasenotes.Notes    `json:"release_notes"`
+}
+
+type validationReportData struct {
+	PassedChecks  int                 `json:"passed_checks"`
+	FailedChecks  int                 `json:"failed_checks"`
+	SkippedChecks int                 `json:"skipped_checks"`
+	Failures      []ValidationFailure `json:"failures"`
+}
+
+type testReportData struct {
+	PassedTests  int `json:"passed_tests"`
+	FailedTests  int `json:"failed_tests"`
+	SkippedTests int `json:"skipped_tests"`
+	Failures     []struct {
+		Name  string
+		Error string
+	} `json:"failures"`
+}
+
+type imageSyncReportData struct {
+	Images []artifacts.ImageSyncResult `json:"images"`
+}
+
+// writeHTMLReport renders the executive summary of the installation as HTML
+func (m *InstallationManager) writeHTMLReport(runErr error) (string, error) {
+	path := filepath.Join(filepath.Dir(m.reportPath), installHTMLReportName)
+	if err := htmlreport.Write(path, m.executiveSummary(runErr)); err != nil {
+		return "", err
+	}
+	history.AddReport(path)
+	return path, nil
+}
+
+// writeMarkdownReport renders the executive summary of the installation as Markdown and adds
+// it to the CI job summary
+func (m *InstallationManager) writeMarkdownReport(runErr error) (string, error) {
+	markdown := htmlreport.Markdown(m.executiveSummary(runErr))
+	path := filepath.Join(filepath.Dir(m.reportPath), installMarkdownReportName)
+	if err := os.WriteFile(path, []byte(markdown), 0644); err != nil {
+		return "", fmt.Errorf("failed to write Markdown report: %w", err)
+	}
+	history.AddReport(path)
+	ci.AddSummary(markdown)
+	return path, nil
+}
+
+// executiveSummary collects the installation's steps and the latest reports of the deploy,
+// post-validate, e2e-test and package-pull steps
+func (m *InstallationManager) executiveSummary(runErr error) *htmlreport.Summary {
+	summary := &htmlreport.Summary{
+		Title:     "E2E Kubernetes Installation Report",
+		Generated: time.Now(),
+		Status:    htmlreport.StatusSuccess,
+		DryRun:    installDryRun,
+		Duration:  time.Since(m.results.StartTime),
+		Details: [][2]string{
+			{"Run", history.RunID()},
+			{"Workspace", m.workspace},
+			{"Installer version", version.Version},
+			{"Started", m.results.StartTime.Format("2006-01-02 15:04:05 MST")},
+		},
+	}
+	if runErr != nil {
+		summary.Status = htmlreport.StatusFailed
+		summary.Details = append(summary.Details, [2]string{"Error", runErr.Error()})
+	}
+	for _, approval := range m.state.Approvals {
+		detail := fmt.Sprintf("%s, %s", approval.ApprovedBy, approval.ApprovedAt.Local().Format("2006-01-02 15:04:05 MST"))
+		if approval.Comment != "" {
+			detail += ": " + approval.Comment
+		}
+		summary.Details = append(summary.Details, [2]string{"Approved " + approval.Gate, detail})
+	}
+	if info := license.ReportInfo(); info != nil {
+		summary.Details = append(summary.Details, [2]string{"License", fmt.Sprintf("%s (%s, expires %s)", info.ID, info.Customer, info.Expires.Format("2006-01-02"))})
+	}
+
+	for _, step := range m.completed {
+		status := htmlreport.StatusSuccess
+		if step.Failed {
+			status = htmlreport.StatusFailed
+		} else if step.Skipped {
+			status = htmlreport.StatusSkipped
+		}
+		summary.Steps = append(summary.Steps, htmlreport.Step{
+			Name:        step.Name,
+			Description: step.Description,
+			Status:      status,
+			Duration:    step.Duration,
+			Error:       step.Error,
+			LogTail:     step.LogTail,
+		})
+	}
+
+	var deployment deploymentReportData
+	if m.readStepReport("deployment-report.json", &deployment) {
+		for _, chart := range deployment.DeployedCharts {
+			summary.Charts = append(summary.Charts, htmlreport.Chart{
+				Name:       chart.Name,
+				Namespace:  chart.Namespace,
+				Version:    chart.Version,
+				AppVersion: chart.AppVersion,
+				Images:     chart.Images,
+				Status:     chart.Status,
+				Error:      chart.Error,
+				LogTail:    chart.LogTail,
+			})
+		}
+		for _, notes := range deployment.ReleaseNotes {
+			report := htmlreport.ReleaseNotes{Chart: notes.Chart, From: notes.From, To: notes.To}
+			for _, entry := range notes.Entries {
+				report.Entries = append(report.Entries, htmlreport.ReleaseNote{Version: entry.Version, Body: entry.Body, Breaking: entry.Breaking})
+			}
+			summary.Notes = append(summary.Notes, report)
+		}
+	}
+
+	var validation validationReportData
+	if m.readStepReport("post-validation-report.json", &validation) {
+		summary.Checks = htmlreport.Counts{Passed: validation.PassedChecks, Failed: validation.FailedChecks, Skipped: validation.SkippedChecks}
+		for _, failure := range validation.Failures {
+			summary.Validation = append(summary.Validation, htmlreport.Failure{Name: failure.Name, Category: failure.Category, Error: failure.Error})
+		}
+	}
+
+	var tests testReportData
+	if m.readStepReport("e2e-results.json", &tests) {
+		summary.Tests = htmlreport.Counts{Passed: tests.PassedTests, Failed: tests.FailedTests, Skipped: tests.SkippedTests}
+		for _, failure := range tests.Failures {
+			summary.TestFails = append(summary.TestFails, htmlreport.Failure{Name: failure.Name, Error: failure.Error})
+		}
+	}
+
+	for _, finding := range collectFindings(m.reportDirs()) {
+		summary.Findings = append(summary.Findings, htmlreport.Finding{
+			Severity: finding.Rule.Severity,
+			Source:   finding.Source,
+			Object:   finding.Object,
+			Detail:   finding.Message,
+		})
+	}
+
+	var images imageSyncReportData
+	m.readStepReport("image-sync-report-latest.json", &images)
+	summary.Artifacts = m.artifactBOM(images.Images)
+	return summary
+}
+
+// artifactBOM lists the images, charts and repositories of the installation with the digests
+// of the last image sync, or of the artifacts lockfile when no sync report is available
+func (m *InstallationManager) artifactBOM(synced []artifacts.ImageSyncResult) []htmlreport.Artifact {
+	lock, err := artifacts.LoadLockfile(filepath.Join(m.workspace, "artifacts", artifacts.LockfileName))
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to read artifacts lockfile for the report")
+		lock = &artifacts.Lockfile{}
+	}
+	byName := make(map[string]artifacts.ImageSyncResult)
+	for _, result := range synced {
+		byName[result.Name] = result
+	}
+
+	var bom []htmlreport.Artifact
+	for _, image := range m.config.Artifacts.Images.Images {
+		entry := htmlreport.Artifact{Kind: "image", Name: image.Name, Version: image.Version}
+		if result, ok := byName[image.Name]; ok {
+			entry.Reference, entry.Digest = result.Destination, result.DestinationDigest
+			if entry.Digest == "" {
+				entry.Digest = result.SourceDigest
+			}
+		} else if locked, ok := lock.Images[image.Name]; ok {
+			entry.Reference, entry.Digest = locked.Destination, locked.Digest
+		}
+		bom = append(bom, entry)
+	}
+
+	for _, chart := range m.config.Artifacts.Helm.Charts {
+		bom = append(bom, htmlreport.Artifact{Kind: "chart", Name: chart.Name, Version: chart.Version, Reference: chart.Path})
+	}
+
+	kinds := make([]string, 0, len(lock.Repos))
+	for kind := range lock.Repos {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		repo := lock.Repos[kind]
+		bom = append(bom, htmlreport.Artifact{Kind: "repository", Name: kind, Version: repo.Ref, Reference: repo.URL, Digest: repo.Commit})
+	}
+	return bom
+}
+
+// reportDirs are where the step reports are looked up: the installation reports directory,
+// then the reports directory standalone commands write to
+func (m *InstallationManager) reportDirs() []string {
+	return []string{filepath.Dir(m.reportPath), reportsDir()}
+}
+
+func (m *InstallationManager) readStepReport(name string, v interface{}) bool {
+	return readLatestReport(m.reportDirs(), name, v)
+}
+
+// validateInstallReportFormat rejects unknown --report-format values before anything runs
+func validateInstallReportFormat() error {
+	switch strings.ToLower(installReportFormat) {
+	case installReportJSON, installReportHTML:
+		return nil
+	}
+	return fmt.Errorf("unsupported report format %q, use %s or %s", installReportFormat, installReportJSON, installReportHTML)
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/ci"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+// configureSteps applies installer.steps to the built-in steps: disabled steps are dropped,
+// replaced steps run their command and, with an order, the steps run in that order. A
+// dependency on a disabled step counts as met, like one on a step --skip-steps excludes.
+func (m *InstallationManager) configureSteps(steps []InstallationStep) ([]InstallationStep, error) {
+	cfg := m.config.Installer.Steps
+	builtin := make(map[string]bool)
+	for _, step := range steps {
+		builtin[step.Name] = true
+	}
+	for _, name := range cfg.Disabled {
+		if !builtin[name] {
+			return nil, fmt.Errorf("installer.steps.disabled names unknown step %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Replace))
+	for name := range cfg.Replace {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	timeouts := make(map[string]time.Duration)
+	for _, name := range names {
+		if !builtin[name] {
+			return nil, fmt.Errorf("installer.steps.replace names unknown step %q", name)
+		}
+		if timeout := cfg.Replace[name].Timeout; timeout != "" {
+			parsed, err := time.ParseDuration(timeout)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("installer.steps.replace.%s has invalid timeout %q, use a duration such as 10m", name, timeout)
+			}
+			timeouts[name] = parsed
+		}
+		for _, dependency := range cfg.Replace[name].DependsOn {
+			if !builtin[dependency] {
+				return nil, fmt.Errorf("installer.steps.replace.%s depends on unknown step %q", name, dependency)
+			}
+		}
+	}
+
+	var enabled []InstallationStep
+	for _, step := range steps {
+		if slices.Contains(cfg.Disabled, step.Name) {
+			m.logger.Info().Str("step", step.Name).Msg("Step disabled by the configuration")
+			continue
+		}
+		if replacement, ok := cfg.Replace[step.Name]; ok {
+			step.Handler = m.replacedStep(step.Name, replacement, timeouts[step.Name])
+			step.Replaced = replacement.Command
+			if replacement.Description != "" {
+				step.Description = replacement.Description
+			}
+			if replacement.DependsOn != nil {
+				step.Dependencies = replacement.DependsOn
+			}
+		}
+		enabled = append(enabled, step)
+	}
+
+	if len(cfg.Order) > 0 {
+		byName := make(map[string]InstallationStep)
+		for _, step := range enabled {
+			byName[step.Name] = step
+		}
+		ordered := make([]InstallationStep, 0, len(enabled))
+		for _, name := range cfg.Order {
+			step, ok := byName[name]
+			switch {
+			case !builtin[name]:
+				return nil, fmt.Errorf("installer.steps.order names unknown step %q", name)
+			case !ok && slices.Contains(cfg.Disabled, name):
+				return nil, fmt.Errorf("installer.steps.order lists %s, which is disabled", name)
+			case !ok:
+				return nil, fmt.Errorf("installer.steps.order lists %s more than once", name)
+			}
+			ordered = append(ordered, step)
+			delete(byName, name)
+		}
+		for _, step := range enabled {
+			if _, missing := byName[step.Name]; missing {
+				return nil, fmt.Errorf("installer.steps.order does not list %s; add it to the order or disable it", step.Name)
+			}
+		}
+		enabled = ordered
+	}
+
+	position := make(map[string]int)
+	for i, step := range enabled {
+		position[step.Name] = i
+	}
+	for i, step := range enabled {
+		for _, dependency := range step.Dependencies {
+			if j, ok := position[dependency]; ok && j > i {
+				return nil, fmt.Errorf("step %s runs before %s, which it depends on", step.Name, dependency)
+			}
+			if dependency == step.Name {
+				return nil, fmt.Errorf("step %s depends on itself", step.Name)
+			}
+		}
+	}
+	return enabled, nil
+}
+
+// replacedStep returns the handler running the command of a replaced step, bounded by timeout
+// unless it is zero
+func (m *InstallationManager) replacedStep(name string, replacement config.StepReplacement, timeout time.Duration) func() error {
+	return func() error {
+		store := params.GetStore()
+		cmd := execx.Command("sh", "-c", replacement.Command)
+		cmd.Env = parameterEnviron("E2E_INSTALLER_STEP="+name, "E2E_INSTALLER_WORKSPACE="+m.workspace)
+		cmd.Secrets = store.SensitiveValues()
+		cmd.Tag = "step " + name
+		cmd.Step = name
+		cmd.Audit, cmd.AuditTarget = "install.step", name
+		cmd.Timeout = timeout
+		_, err := cmd.Run(context.Background())
+		return err
+	}
+}
+
+// commandStep returns the handler of a built-in step: the installer command of the same name,
+// run in its own process on the configuration and workspace of the installation, as it runs
+// on its own. The commands keep their flags and progress in package state, so steps running
+// at the same time with --parallel stay apart this way.
+func (m *InstallationManager) commandStep(name string, extra ...string) func() error {
+	return func() error {
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate the installer binary: %w", err)
+		}
+
+		// Without a configuration file every command runs with its own sample configuration
+		args := append([]string{name, "--config=" + m.configFile}, extra...)
+		if workspaceFlag != "" {
+			args = append(args, "--workspace", workspaceFlag)
+		}
+		if simulateFlag {
+			args = append(args, "--simulate")
+		}
+		if inClusterFlag {
+			args = append(args, "--in-cluster")
+		}
+		if installVerbose || verbose {
+			args = append(args, "--verbose")
+		}
+		// Every step records and replays its own cassette
+		if recordFlag != "" {
+			args = append(args, "--record", stepCassette(recordFlag, name))
+		}
+		if replayFlag != "" {
+			args = append(args, "--replay", stepCassette(replayFlag, name))
+		}
+
+		cmd := execx.Command(executable, args...)
+		// The output goes to the step log, and the installation reports to CI on its own
+		cmd.Env = []string{"E2E_INSTALLER_STEP=" + name, "E2E_INSTALLER_WORKSPACE=" + m.workspace, "NO_COLOR=1", ci.Env + "="}
+		cmd.Secrets = params.GetStore().SensitiveValues()
+		cmd.Tag = "step " + name
+		cmd.Step = name
+		cmd.Local = true
+		run := func() error {
+			output, err := cmd.Run(context.Background())
+			if err != nil {
+				return commandError(output, err)
+			}
+			return nil
+		}
+		// A simulating step continues from the environment of this run, and this run from
+		// the environment the step leaves behind
+		if simulation != nil {
+			return simulation.Handoff(run)
+		}
+		return run()
+	}
+}
+
+// commandError returns the failure of an installer command with the error it printed, and
+// with its exit code unless it did not exit on its own
+func commandError(output []byte, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	lines := strings.Split(string(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if message, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), "Error: "); ok {
+			err = fmt.Errorf("%s (%w)", message, err)
+			break
+		}
+	}
+	return exitcode.Wrap(exitErr.ExitCode(), err)
+}
+
+// stepCassette names the cassette of one step after the cassette of the installation, e.g.
+// run.deploy.json for run.json
+func stepCassette(path, step string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + step + ext
+}
+
+// parameterEnviron returns the installation parameters as environment variables for a
+// configured command, with extra variables of the form KEY=value
+func parameterEnviron(extra ...string) []string {
+	env := append([]string{}, extra...)
+	for key, value := range params.GetStore().Environ() {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
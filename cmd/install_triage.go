@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// Choices offered when a step fails in --interactive mode
+const (
+	triageRetry       = "Retry the step"
+	triageSkip        = "Skip the step and continue"
+	triageDiagnostics = "Show diagnostic summary"
+	triageShell       = "Open a debug shell (KUBECONFIG and workspace preset)"
+	triageAbort       = "Abort the installation"
+)
+
+// errInstallAborted marks a step failure the operator chose not to continue past
+var errInstallAborted = errors.New("installation aborted by operator")
+
+// validateInteractive rejects --interactive when nobody can answer the prompt
+func validateInteractive() error {
+	if installInteractive && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("--interactive requires a terminal, use --continue-on-error in pipelines")
+	}
+	return nil
+}
+
+// runStep runs the handler of step. In interactive mode a failure is triaged with the operator
+// until the step succeeds on retry, is skipped, or the installation is aborted; skipped reports
+// that the operator skipped the failed step.
+func (m *InstallationManager) runStep(step InstallationStep, progressArea *pterm.AreaPrinter) (skipped bool, err error) {
+	err = step.Handler()
+	if err == nil || !installInteractive {
+		return false, err
+	}
+	return m.triageStep(step, err, progressArea)
+}
+
+// triageStep asks the operator how to handle the failure err of step, retrying the step for
+// as long as they choose to
+func (m *InstallationManager) triageStep(step InstallationStep, err error, progressArea *pterm.AreaPrinter) (skipped bool, _ error) {
+	progressArea.Stop()
+	defer progressArea.Start()
+
+	attempts := 1
+	for {
+		pterm.Error.Printf("Step %s failed: %v\n", step.Name, err)
+		if tail := logtail.Of(err); tail != nil {
+			pterm.DefaultBox.WithTitle(fmt.Sprintf("Last %d lines of %s output", len(tail.LogTail), tail.Tool)).
+				Println(strings.Join(tail.LogTail, "\n"))
+		}
+
+		options := []string{triageRetry}
+		if !step.Required {
+			options = append(options, triageSkip)
+		}
+		options = append(options, triageDiagnostics, triageShell, triageAbort)
+
+		choice, promptErr := pterm.DefaultInteractiveSelect.
+			WithOptions(options).
+			WithDefaultOption(triageRetry).
+			Show(fmt.Sprintf("How should the installer handle the failed %s step?", step.Name))
+		if promptErr != nil {
+			return false, fmt.Errorf("failed to read triage choice: %w (step error: %v)", promptErr, err)
+		}
+
+		switch choice {
+		case triageRetry:
+			attempts++
+			m.logger.Info().Str("step", step.Name).Int("attempt", attempts).Msg("Retrying installation step")
+			spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Retrying %s (attempt %d)...", step.Name, attempts))
+			if err = step.Handler(); err == nil {
+				spinner.Success(fmt.Sprintf("%s succeeded on attempt %d", step.Name, attempts))
+				return false, nil
+			}
+			spinner.Fail(fmt.Sprintf("%s failed again", step.Name))
+		case triageSkip:
+			m.logger.Warn().Err(err).Str("step", step.Name).Msg("Operator skipped failed installation step")
+			return true, err
+		case triageDiagnostics:
+			m.showStepDiagnostics(step, attempts, err)
+		case triageShell:
+			if shellErr := m.openDebugShell(step, err); shellErr != nil {
+				pterm.Warning.Printf("Debug shell unavailable: %v\n", shellErr)
+			}
+		case triageAbort:
+			return false, fmt.Errorf("%w: %w", errInstallAborted, err)
+		}
+	}
+}
+
+// showStepDiagnostics prints what an operator needs to investigate a failed step
+func (m *InstallationManager) showStepDiagnostics(step InstallationStep, attempts int, err error) {
+	kubeconfig := k8s.ResolveKubeconfig(&m.config.Kubernetes)
+	if kubeconfig == "" {
+		kubeconfig = ambientKubeconfig() + " (ambient)"
+	}
+	required := "no"
+	if step.Required {
+		required = "yes"
+	}
+
+	pterm.DefaultSection.Println("Step Diagnostics")
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Property", "Value"},
+		{"Step", step.Name},
+		{"Command", step.Command},
+		{"Required", required},
+		{"Attempts", fmt.Sprintf("%d", attempts)},
+		{"Error", err.Error()},
+		{"Workspace", m.workspace},
+		{"Kubeconfig", kubeconfig},
+		{"Kube context", valueOr(m.config.Kubernetes.Context, "current context")},
+		{"Namespace", valueOr(m.config.Kubernetes.Namespace, "default")},
+		{"State file", m.stateFile},
+		{"Reports", filepath.Dir(m.reportPath)},
+		{"Elapsed", time.Since(m.results.StartTime).Round(time.Second).String()},
+	}).Render()
+
+	if step.Replaced != "" {
+		pterm.Info.Printf("Run '%s' in the debug shell to reproduce the step on its own\n", step.Replaced)
+		return
+	}
+	pterm.Info.Printf("Run 'e2e-k8s-installer %s --verbose' in the debug shell to reproduce the step on its own\n", step.Command)
+}
+
+// openDebugShell starts the operator's shell in the workspace with the cluster credentials of
+// the installation preset, and returns when the shell exits
+func (m *InstallationManager) openDebugShell(step InstallationStep, stepErr error) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+		if runtime.GOOS == "windows" {
+			shell = "cmd.exe"
+		}
+	}
+
+	workspace, err := filepath.Abs(m.workspace)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	env := append(os.Environ(),
+		"E2E_INSTALLER_WORKSPACE="+workspace,
+		"E2E_INSTALLER_STEP="+step.Name,
+		"E2E_INSTALLER_STEP_ERROR="+stepErr.Error(),
+	)
+	if kubeconfig := k8s.ResolveKubeconfig(&m.config.Kubernetes); kubeconfig != "" {
+		if abs, err := filepath.Abs(kubeconfig); err == nil {
+			kubeconfig = abs
+		}
+		env = append(env, "KUBECONFIG="+kubeconfig)
+	}
+
+	shellCmd := exec.Command(shell)
+	shellCmd.Env = env
+	shellCmd.Stdin, shellCmd.Stdout, shellCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if info, err := os.Stat(workspace); err == nil && info.IsDir() {
+		shellCmd.Dir = workspace
+	}
+
+	pterm.Info.Printf("Opening %s for step %s, exit the shell to return to the installer\n", shell, step.Name)
+	if err := shellCmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// triagedSkips counts the failed steps the operator chose to skip
+func (m *InstallationManager) triagedSkips() int {
+	count := 0
+	for _, step := range m.completed {
+		if step.Skipped && step.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
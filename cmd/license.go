@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	licenseConfigFile string
+	licenseOutput     string
+)
+
+// licenseCmd represents the license command
+var licenseCmd = &cobra.Command{
+	Use:   "license",
+	Short: "Show the installer license and its entitlements",
+	Long: `Vendor builds of the installer require a signed license key, verified offline. The key
+is read from installer.license.key or installer.license.keyFile in the configuration, the
+` + license.KeyEnv + ` environment variable, or license.key in the workspace.
+
+A license limits the modules that may run (package-pull, provision-infra, db-migrate,
+deploy, post-validate, e2e-test) and the number of cluster nodes. Every run warns during
+the last 30 days before expiry, and reports record the license in use.`,
+}
+
+var licenseStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Verify the license and list its entitlements",
+	Args:  cobra.NoArgs,
+	RunE:  runLicenseStatus,
+}
+
+func init() {
+	licenseCmd.PersistentFlags().StringVarP(&licenseConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	licenseStatusCmd.Flags().StringVarP(&licenseOutput, "output", "o", "table", "Output format (table, json)")
+	licenseCmd.AddCommand(licenseStatusCmd)
+}
+
+func runLicenseStatus(cmd *cobra.Command, args []string) error {
+	if !license.Enforced() {
+		pterm.Info.Println("This build does not enforce licensing")
+		return nil
+	}
+
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, licenseConfigFile))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+	lic, err := license.InitGlobal(cfg.Installer.License, cfg.Installer.Workspace)
+	if err != nil {
+		return err
+	}
+	info := lic.Info()
+
+	if licenseOutput == "json" {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal license: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	modules := "all"
+	if len(info.Modules) > 0 {
+		modules = strings.Join(info.Modules, ", ")
+	}
+	maxNodes := "unlimited"
+	if info.MaxNodes > 0 {
+		maxNodes = fmt.Sprintf("%d", info.MaxNodes)
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Property", "Value"},
+		{"License", info.ID},
+		{"Customer", info.Customer},
+		{"Expires", fmt.Sprintf("%s (%d days)", info.Expires.Format("2006-01-02"), info.DaysLeft)},
+		{"Modules", modules},
+		{"Max Nodes", maxNodes},
+	}).Render()
+	return nil
+}
+
+// requireLicense loads the license and fails when it does not enable module. Commands that
+// do not load the installer configuration pass nil and read the key from the environment
+// or the selected workspace.
+func requireLicense(cfg *config.InstallerConfig, module string) error {
+	licenseCfg, workspaceDir := config.LicenseConfig{}, selectedWorkspace("./workspace")
+	if cfg != nil {
+		licenseCfg, workspaceDir = cfg.Installer.License, cfg.Installer.Workspace
+	}
+	if _, err := license.InitGlobal(licenseCfg, workspaceDir); err != nil {
+		return err
+	}
+	return license.Require(module)
+}
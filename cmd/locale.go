@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/i18n"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// localeChosen is set when E2E_INSTALLER_LOCALE picked the locale, which then wins over
+// the configuration
+var localeChosen bool
+
+// startLocale applies E2E_INSTALLER_LOCALE, or a supported locale of LC_ALL, LC_MESSAGES or
+// LANG, which the configuration may still override
+func startLocale() error {
+	if name := os.Getenv("E2E_INSTALLER_LOCALE"); name != "" {
+		localeChosen = true
+		return i18n.SetLocale(name)
+	}
+	if name := i18n.SystemLocale(); name != "" {
+		return i18n.SetLocale(name)
+	}
+	return nil
+}
+
+// applyConfiguredLocale switches to the locale named in the configuration, unless
+// E2E_INSTALLER_LOCALE chose one
+func applyConfiguredLocale(name string) {
+	if name == "" || localeChosen {
+		return
+	}
+	if err := i18n.SetLocale(name); err != nil {
+		logger.Warn("Ignoring the configured locale").Err(err).Send()
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// logsPollInterval is how often a followed journal is checked for new entries
+const logsPollInterval = 250 * time.Millisecond
+
+var (
+	logsFollow     bool
+	logsSteps      []string
+	logsComponents []string
+	logsOutput     string
+	logsConfigFile string
+)
+
+// logsCmd prints the journal of a run
+var logsCmd = &cobra.Command{
+	Use:   "logs [run-id]",
+	Short: "Show or follow the log journal of a run",
+	Long: `Print the journal of a run: every log entry and every line of helm, terraform and
+make output, as written to logs/<run id>/journal.jsonl in the workspace. The run is
+referenced by its ID, a unique prefix, or "latest", the default.
+
+With --follow the journal of an installation still in progress is tailed until the run
+finishes, so a second terminal or a teammate on the same machine can watch a long
+install. Entries can be narrowed down to steps and components; tool output matches the
+tool's name, e.g. helm.
+
+Examples:
+  e2e-k8s-installer logs --follow
+  e2e-k8s-installer logs --workspace prod --follow --step deploy-charts
+  e2e-k8s-installer logs install-20250301-101500-ab12 --component helm -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new entries until the run finishes")
+	logsCmd.Flags().StringSliceVar(&logsSteps, "step", nil, "Only show entries of these steps")
+	logsCmd.Flags().StringSliceVar(&logsComponents, "component", nil, "Only show entries of these components or tools")
+	logsCmd.Flags().StringVar(&logsConfigFile, "config", "", "installation configuration whose workspace keeps the logs")
+	logsCmd.Flags().StringVarP(&logsOutput, "output", "o", "pretty", "Output format (pretty, json)")
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	// The viewer must not start a journal of its own, logs/latest would point at it
+	logger.ConfigureToolLogs("", false)
+
+	if logsOutput != "pretty" && logsOutput != "json" {
+		return fmt.Errorf("unsupported output format %q, use pretty or json", logsOutput)
+	}
+	run := "latest"
+	if len(args) == 1 {
+		run = args[0]
+	}
+	path, err := journalPath(filepath.Join(runWorkspace(), "logs"), run)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	for os.IsNotExist(err) && logsFollow {
+		// The run has not logged anything yet
+		time.Sleep(logsPollInterval)
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open the journal of run %s: %w", run, err)
+	}
+	defer file.Close()
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	filter := journalFilter{steps: logsSteps, components: logsComponents}
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// An incomplete line is still being written
+			partial += line
+			if !logsFollow {
+				return nil
+			}
+			select {
+			case <-interrupted:
+				return nil
+			case <-time.After(logsPollInterval):
+			}
+			continue
+		}
+		line, partial = partial+line, ""
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			if logsOutput == "json" {
+				fmt.Print(line)
+			} else {
+				fmt.Println(formatJournalEntry(entry))
+			}
+		}
+		if entry["event"] == logger.RunFinished {
+			return nil
+		}
+	}
+}
+
+// journalPath resolves a run ID, unique prefix or "latest" to the run's journal
+func journalPath(logsDir, run string) (string, error) {
+	if run == "latest" {
+		target, err := os.Readlink(filepath.Join(logsDir, "latest"))
+		if err != nil {
+			return "", fmt.Errorf("no run has logged to %s yet", logsDir)
+		}
+		return filepath.Join(logsDir, target, logger.JournalFile), nil
+	}
+
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read log directory: %w", err)
+	}
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == run {
+			return filepath.Join(logsDir, run, logger.JournalFile), nil
+		}
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), run) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no logs of run %s in %s", run, logsDir)
+	case 1:
+		return filepath.Join(logsDir, matches[0], logger.JournalFile), nil
+	}
+	return "", fmt.Errorf("run %s is ambiguous, it matches %s", run, strings.Join(matches, ", "))
+}
+
+// journalFilter selects entries by step and component. Entries without a step belong to
+// the step started last.
+type journalFilter struct {
+	steps      []string
+	components []string
+	current    string
+}
+
+func (f *journalFilter) matches(entry map[string]interface{}) bool {
+	step, _ := entry["step"].(string)
+	if step == "" {
+		step = f.current
+	} else if entry["message"] == "Step started" {
+		f.current = step
+	}
+	if len(f.steps) > 0 && !slices.Contains(f.steps, step) {
+		return false
+	}
+	if len(f.components) == 0 {
+		return true
+	}
+	component, _ := entry["component"].(string)
+	if tool, _ := entry["tool"].(string); tool != "" {
+		component = strings.Fields(tool)[0]
+	}
+	return slices.Contains(f.components, component)
+}
+
+// formatJournalEntry renders an entry as "15:04:05 INFO  [step] message key=value ..."
+func formatJournalEntry(entry map[string]interface{}) string {
+	var b strings.Builder
+	if t, err := time.Parse(time.RFC3339, fmt.Sprint(entry["time"])); err == nil {
+		b.WriteString(pterm.Gray(t.Local().Format("15:04:05")) + " ")
+	}
+
+	level := strings.ToUpper(fmt.Sprint(entry["level"]))
+	switch level {
+	case "ERROR", "FATAL":
+		level = pterm.Red(fmt.Sprintf("%-5s", level))
+	case "WARN":
+		level = pterm.Yellow(fmt.Sprintf("%-5s", level))
+	default:
+		level = pterm.Cyan(fmt.Sprintf("%-5s", level))
+	}
+	b.WriteString(level + " ")
+
+	if tool, ok := entry["tool"].(string); ok {
+		b.WriteString(pterm.Gray(fmt.Sprintf("│ [%s] ", tool)))
+		b.WriteString(fmt.Sprint(entry["message"]))
+		return b.String()
+	}
+	if step, ok := entry["step"].(string); ok {
+		b.WriteString(pterm.Gray("[" + step + "] "))
+	}
+	b.WriteString(fmt.Sprint(entry["message"]))
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		switch key {
+		case "time", "level", "message", "step":
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + pterm.Cyan(key+"=") + fmt.Sprint(entry[key]))
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/maintenance"
+	"github.com/pterm/pterm"
+)
+
+// errOutsideWindow stops a phase that may only run inside a maintenance window
+var errOutsideWindow = errors.New("outside maintenance window")
+
+// awaitMaintenanceWindow holds back a phase the maintenance windows govern until a window is
+// open. Outside a window it waits for the next one when the configuration asks for it, and
+// otherwise fails with the scheduled exit code.
+func awaitMaintenanceWindow(cfg *config.InstallerConfig, phase string) error {
+	policy, err := maintenance.NewPolicy(cfg.Installer.Maintenance)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	if !policy.Governs(phase) {
+		return nil
+	}
+
+	status := policy.StatusAt(time.Now())
+	if status.Open {
+		logger.Info("Inside maintenance window").
+			Str("phase", phase).
+			Str("window", status.Window).
+			Time("closes", status.Closes).
+			Send()
+		return nil
+	}
+	if status.NextOpen.IsZero() {
+		return exitcode.Wrap(exitcode.Scheduled, fmt.Errorf("%w: %s may only run in a maintenance window and none of %s opens within a year", errOutsideWindow, phase, policy.Describe()))
+	}
+
+	wait := time.Until(status.NextOpen).Round(time.Second)
+	if !policy.Wait || (policy.MaxWait > 0 && wait > policy.MaxWait) {
+		return exitcode.Wrap(exitcode.Scheduled, fmt.Errorf("%w: %s may only run in a maintenance window, %s opens at %s (in %s)",
+			errOutsideWindow, phase, status.Window, status.NextOpen.Format(time.RFC3339), wait))
+	}
+
+	pterm.Info.Printf("⏳ %s may only run in a maintenance window, waiting %s for %s to open at %s\n",
+		phase, wait, status.Window, status.NextOpen.Format(time.RFC3339))
+	logger.Info("Waiting for maintenance window").
+		Str("phase", phase).
+		Str("window", status.Window).
+		Time("opens", status.NextOpen).
+		Send()
+	time.Sleep(time.Until(status.NextOpen))
+	return nil
+}
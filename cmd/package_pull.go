@@ -1,19 +1,33 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
 	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/tuning"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 // packagePullCmd represents the package-pull command
 var packagePullCmd = &cobra.Command{
 	Use:   "package-pull",
-	Short: "Synchronize OCI images, Helm charts, and Terraform modules",
+	Short: "Synchronize OCI images, Helm charts, Terraform modules, and database scripts",
 	Long: `The package-pull command synchronizes all required artifacts for installation:
 
 1. OCI Images:
@@ -23,6 +37,7 @@ var packagePullCmd = &cobra.Command{
    
 2. Helm Charts:
    - Clone vendor helm repository
+   - Resolve Chart.yaml dependencies into artifacts/charts.lock.yaml
    - Push to client repository (if configured)
    - Keep local copy for deployment
    
@@ -31,33 +46,95 @@ var packagePullCmd = &cobra.Command{
    - Push to client repository (if configured)
    - Validate terraform modules
 
+4. Database Scripts:
+   - Clone the database migration scripts repository
+
+Use --only to sync a subset of artifact kinds and --images-only to sync just the
+named images. With --incremental, artifacts whose image digest or git commit
+still matches artifacts/artifacts.lock.json in the workspace are skipped, and
+the lockfile is updated after each successful sync.
+
+After each copy the destination manifest digest, and for multi-arch images the
+digest of every platform manifest, is compared with the source; an image that a
+proxy or registry rewrote in transit fails the sync and is not recorded in the
+lockfile.
+
+Every image synchronization writes reports/image-sync-report_<timestamp>.json
+with each image's source and destination digests, size, duration, status and
+verification result, for change records; image-sync-report-latest.json links
+to the newest one.
+
 All operations include progress tracking and detailed logging.
 
 Example:
   k8s-installer package-pull --config installer-config.json
-  k8s-installer package-pull --images-only
+  k8s-installer package-pull --only images,helm
+  k8s-installer package-pull --images-only api-server,web-ui
+  k8s-installer package-pull --incremental
   k8s-installer package-pull --dry-run`,
-	RunE: runPackagePull,
+	RunE: withExitCode(exitcode.Deploy, runPackagePull),
 }
 
 var (
-	packagePullConfig     string
-	packagePullImagesOnly bool
-	packagePullHelmOnly   bool
-	packagePullTfOnly     bool
-	packagePullDryRun     bool
-	packagePullParallel   bool
+	packagePullConfig      string
+	packagePullOnly        []string
+	packagePullImagesOnly  []string
+	packagePullHelmOnly    bool
+	packagePullTfOnly      bool
+	packagePullDryRun      bool
+	packagePullParallel    bool
+	packagePullIncremental bool
+)
+
+// Artifact kinds accepted by package-pull --only
+const (
+	artifactKindImages    = "images"
+	artifactKindHelm      = "helm"
+	artifactKindTerraform = "terraform"
+	artifactKindDBScripts = "db-scripts"
 )
 
 func init() {
 	rootCmd.AddCommand(packagePullCmd)
 
 	packagePullCmd.Flags().StringVarP(&packagePullConfig, "config", "c", "installer-config.json", "Configuration file path")
-	packagePullCmd.Flags().BoolVar(&packagePullImagesOnly, "images-only", false, "Only pull OCI images")
-	packagePullCmd.Flags().BoolVar(&packagePullHelmOnly, "helm-only", false, "Only pull Helm charts")
-	packagePullCmd.Flags().BoolVar(&packagePullTfOnly, "terraform-only", false, "Only pull Terraform modules")
+	packagePullCmd.Flags().StringSliceVar(&packagePullOnly, "only", []string{}, "Only sync these artifact kinds: images, helm, terraform, db-scripts (comma-separated)")
+	packagePullCmd.Flags().StringSliceVar(&packagePullImagesOnly, "images-only", []string{}, "Only sync the named OCI images (comma-separated)")
+	packagePullCmd.Flags().BoolVar(&packagePullHelmOnly, "helm-only", false, "Only pull Helm charts (same as --only helm)")
+	packagePullCmd.Flags().BoolVar(&packagePullTfOnly, "terraform-only", false, "Only pull Terraform modules (same as --only terraform)")
 	packagePullCmd.Flags().BoolVarP(&packagePullDryRun, "dry-run", "n", false, "Show what would be done without actually doing it")
 	packagePullCmd.Flags().BoolVarP(&packagePullParallel, "parallel", "p", true, "Enable parallel processing")
+	packagePullCmd.Flags().BoolVar(&packagePullIncremental, "incremental", false, "Skip artifacts whose digest or commit matches the lockfile")
+}
+
+// packagePullKinds resolves the artifact kinds to sync from --only and its shorthand flags
+func packagePullKinds() (map[string]bool, error) {
+	valid := []string{artifactKindImages, artifactKindHelm, artifactKindTerraform, artifactKindDBScripts}
+
+	kinds := make(map[string]bool)
+	for _, kind := range packagePullOnly {
+		kind = strings.TrimSpace(kind)
+		if !slices.Contains(valid, kind) {
+			return nil, fmt.Errorf("invalid --only value %q, expected one of: %s", kind, strings.Join(valid, ", "))
+		}
+		kinds[kind] = true
+	}
+	if len(packagePullImagesOnly) > 0 {
+		kinds[artifactKindImages] = true
+	}
+	if packagePullHelmOnly {
+		kinds[artifactKindHelm] = true
+	}
+	if packagePullTfOnly {
+		kinds[artifactKindTerraform] = true
+	}
+
+	if len(kinds) == 0 {
+		for _, kind := range valid {
+			kinds[kind] = true
+		}
+	}
+	return kinds, nil
 }
 
 func runPackagePull(cmd *cobra.Command, args []string) error {
@@ -65,10 +142,16 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 	progress.InitGlobalProgressManager()
 	pm := progress.GetProgressManager()
 
-	// Load configuration
-	cfg, err := config.LoadConfig(packagePullConfig)
+	// Load configuration, the sample configuration with --config ""
+	cfg, err := loadInstallConfig(workspaceConfigFile(cmd, packagePullConfig))
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+	if interval, err := time.ParseDuration(cfg.Installer.Progress.RefreshInterval); err == nil {
+		pm.SetRefreshInterval(interval)
 	}
 
 	// Initialize logger based on config
@@ -78,22 +161,60 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 	}
 	logger.InitGlobalLogger(logConfig)
 
-	progress.ShowBanner("1.0.0")
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+
+	if err := requireLicense(cfg, license.ModulePackagePull); err != nil {
+		return err
+	}
+
+	applyBranding(workspaceConfigFile(cmd, packagePullConfig))
+	progress.ShowBanner(version.Version)
+
+	if !packagePullDryRun {
+		lock, err := workspace.Acquire(cfg.Installer.Workspace, "package-pull")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	kinds, err := packagePullKinds()
+	if err != nil {
+		return err
+	}
+
+	// Create artifacts manager
+	artifactsManager := artifacts.NewManager(cfg, packagePullDryRun)
+	if len(packagePullImagesOnly) > 0 {
+		if err := artifactsManager.SelectImages(packagePullImagesOnly); err != nil {
+			return err
+		}
+	}
+	if packagePullIncremental {
+		if err := artifactsManager.EnableIncremental(); err != nil {
+			return fmt.Errorf("failed to load artifacts lockfile: %w", err)
+		}
+	}
 
 	// Start overall progress tracking
 	pm.StartArea("package-pull")
 
 	// Determine steps based on flags
 	steps := []string{}
-	if !packagePullHelmOnly && !packagePullTfOnly {
+	if kinds[artifactKindImages] {
 		steps = append(steps, "Synchronize OCI Images")
 	}
-	if !packagePullImagesOnly && !packagePullTfOnly {
+	if kinds[artifactKindHelm] {
 		steps = append(steps, "Synchronize Helm Charts")
 	}
-	if !packagePullImagesOnly && !packagePullHelmOnly {
+	if kinds[artifactKindTerraform] {
 		steps = append(steps, "Synchronize Terraform Modules")
 	}
+	if kinds[artifactKindDBScripts] {
+		steps = append(steps, "Synchronize Database Scripts")
+	}
 	steps = append(steps, "Package pull complete")
 
 	currentStep := 0
@@ -103,18 +224,33 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 		Str("config", packagePullConfig).
 		Bool("dry_run", packagePullDryRun).
 		Bool("parallel", packagePullParallel).
+		Bool("incremental", packagePullIncremental).
 		Send()
 
-	// Create artifacts manager
-	artifactsManager := artifacts.NewManager(cfg, packagePullDryRun)
+	// Record whatever was synced even if a later step fails, so a rerun resumes from there
+	defer func() {
+		if err := artifactsManager.SaveLockfile(); err != nil {
+			logger.Warn("Failed to save artifacts lockfile").Err(err).Send()
+		}
+	}()
 
 	// Step 1: Synchronize OCI Images
-	if !packagePullHelmOnly && !packagePullTfOnly {
+	if kinds[artifactKindImages] {
 		logger.StepStart("sync-images")
 
 		pm.StartSpinner("images", "Synchronizing OCI images...")
 
-		if err := syncImages(artifactsManager, cfg, pm); err != nil {
+		err := syncImages(artifactsManager, cfg, pm)
+		// The report covers failed runs too, showing which images made it across
+		if results := artifactsManager.ImageSyncResults(); len(results) > 0 {
+			printImageSyncSummary(results)
+			if reportPath, reportErr := writeImageSyncReport(cfg, results); reportErr != nil {
+				logger.Warn("Failed to write image sync report").Err(reportErr).Send()
+			} else {
+				logger.Info("Image sync report generated").Str("path", reportPath).Send()
+			}
+		}
+		if err != nil {
 			pm.FailSpinner("images", "Image synchronization failed")
 			logger.StepFailed("sync-images", err)
 			return fmt.Errorf("image synchronization failed: %w", err)
@@ -127,7 +263,7 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 2: Synchronize Helm Charts
-	if !packagePullImagesOnly && !packagePullTfOnly {
+	if kinds[artifactKindHelm] {
 		logger.StepStart("sync-helm")
 
 		pm.StartSpinner("helm", "Synchronizing Helm charts...")
@@ -145,7 +281,7 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 3: Synchronize Terraform Modules
-	if !packagePullImagesOnly && !packagePullHelmOnly {
+	if kinds[artifactKindTerraform] {
 		logger.StepStart("sync-terraform")
 
 		pm.StartSpinner("terraform", "Synchronizing Terraform modules...")
@@ -162,6 +298,24 @@ func runPackagePull(cmd *cobra.Command, args []string) error {
 		progress.ShowStepProgress(steps, currentStep)
 	}
 
+	// Step 4: Synchronize Database Scripts
+	if kinds[artifactKindDBScripts] {
+		logger.StepStart("sync-db-scripts")
+
+		pm.StartSpinner("db-scripts", "Synchronizing database scripts...")
+
+		if err := artifactsManager.CloneDatabaseScripts(); err != nil {
+			pm.FailSpinner("db-scripts", "Database script synchronization failed")
+			logger.StepFailed("sync-db-scripts", err)
+			return fmt.Errorf("database script synchronization failed: %w", err)
+		}
+
+		pm.SuccessSpinner("db-scripts", "Database scripts synchronized successfully")
+		logger.StepComplete("sync-db-scripts", 0)
+		currentStep++
+		progress.ShowStepProgress(steps, currentStep)
+	}
+
 	// Complete
 	currentStep++
 	progress.ShowStepProgress(steps, currentStep)
@@ -181,19 +335,28 @@ func syncImages(manager *artifacts.Manager, cfg *config.InstallerConfig, pm *pro
 		return manager.ValidateImages()
 	}
 
-	images := cfg.Artifacts.Images.Images
+	images := manager.SelectedImages()
 	completed := make([]bool, len(images))
 
 	// Start image progress area
 	pm.StartArea("images")
 	progress.ShowImagePullProgress(extractImageNames(images), completed)
 
-	// Start progress bar
+	// Start progress bar, its title showing the transfer rate and ETA
 	pm.StartProgressBar("image-progress", "Pulling Images", len(images))
+	tracker := pm.TrackRate("image-progress", "Pulling Images", "images", len(images))
+	defer tracker.Stop()
+	synced := func(image config.ImageReference) {
+		tracker.Add(1)
+		if result, ok := manager.ImageSyncResult(image.Name); ok && result.Status == artifacts.SyncStatusCopied {
+			tracker.AddBytes(result.Size)
+		}
+	}
 
 	// Process images
 	if packagePullParallel {
 		return manager.SyncImagesParallel(func(index int, image config.ImageReference, err error) {
+			synced(image)
 			if err == nil {
 				completed[index] = true
 				logger.Info("Image synchronized").
@@ -218,6 +381,7 @@ func syncImages(manager *artifacts.Manager, cfg *config.InstallerConfig, pm *pro
 			}
 
 			completed[i] = true
+			synced(image)
 			pm.IncrementProgressBar("image-progress")
 			progress.ShowImagePullProgress(extractImageNames(images), completed)
 
@@ -257,6 +421,25 @@ func syncHelmCharts(manager *artifacts.Manager, cfg *config.InstallerConfig, pm
 		return fmt.Errorf("helm chart validation failed: %w", err)
 	}
 
+	// Pin chart dependencies for the whole set, failing on conflicting constraints
+	if _, err := manager.ResolveChartDependencies(); err != nil {
+		return fmt.Errorf("failed to resolve chart dependencies: %w", err)
+	}
+
+	// Build an HTTP chart repository for airgapped clients if configured
+	chartRepo := cfg.Artifacts.Helm.Client.ChartRepo
+	if chartRepo.Enabled {
+		if _, err := manager.BuildHelmRepoIndex(); err != nil {
+			return fmt.Errorf("failed to build Helm repository index: %w", err)
+		}
+
+		if chartRepo.Type != "local" {
+			if err := manager.PushChartsToChartRepo(); err != nil {
+				return fmt.Errorf("failed to push charts to chart repository: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -293,3 +476,95 @@ func extractImageNames(images []config.ImageReference) []string {
 	}
 	return names
 }
+
+// writeImageSyncReport writes the per-image sync results to the workspace reports directory
+func writeImageSyncReport(cfg *config.InstallerConfig, results []artifacts.ImageSyncResult) (string, error) {
+	reportDir := filepath.Join(cfg.Installer.Workspace, "reports")
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	summary := map[string]int{}
+	var totalSize int64
+	for _, result := range results {
+		summary[result.Status]++
+		if result.Verification == artifacts.VerificationMismatch {
+			summary["mismatched"]++
+		}
+		if result.Status == artifacts.SyncStatusCopied {
+			totalSize += result.Size
+		}
+	}
+
+	report := map[string]interface{}{
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"source":      cfg.Artifacts.Images.Vendor.Registry,
+		"destination": cfg.Artifacts.Images.Client.Registry,
+		"dryRun":      packagePullDryRun,
+		"incremental": packagePullIncremental,
+		"summary":     summary,
+		"copiedBytes": totalSize,
+		"images":      results,
+		"resources":   tuning.Current(),
+	}
+	if info := license.ReportInfo(); info != nil {
+		report["license"] = info
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal image sync report: %w", err)
+	}
+
+	reportName := fmt.Sprintf("image-sync-report_%s.json", time.Now().Format("2006-01-02_15-04-05"))
+	reportPath := filepath.Join(reportDir, reportName)
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write image sync report: %w", err)
+	}
+	history.AddReport(reportPath)
+
+	latestPath := filepath.Join(reportDir, "image-sync-report-latest.json")
+	if err := os.Remove(latestPath); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove existing latest report link").Err(err).Send()
+	}
+	if err := os.Symlink(reportName, latestPath); err != nil {
+		logger.Warn("Failed to create latest report link").Err(err).Send()
+	}
+	return reportPath, nil
+}
+
+// printImageSyncSummary shows the status, size and digest verification of each image
+func printImageSyncSummary(results []artifacts.ImageSyncResult) {
+	tableData := pterm.TableData{{"Image", "Status", "Size", "Duration", "Digest", "Verification"}}
+	for _, result := range results {
+		size := "-"
+		if result.Size > 0 {
+			size = formatImageSize(result.Size)
+		}
+		digest := result.DestinationDigest
+		if digest == "" {
+			digest = result.SourceDigest
+		}
+		if len(digest) > 19 {
+			digest = digest[:19]
+		}
+		if digest == "" {
+			digest = "-"
+		}
+		tableData = append(tableData, []string{result.Name + ":" + result.Version, result.Status, size, result.Duration, digest, result.Verification})
+	}
+	fmt.Println()
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+func formatImageSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
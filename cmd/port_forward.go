@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	portForwardNamespace  string
+	portForwardKubeconfig string
+	portForwardContext    string
+	portForwardAddress    string
+)
+
+// portForwardCmd forwards local ports to the services of installed releases
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward [chart|service]...",
+	Short: "Forward local ports to the services of installed charts",
+	Long: `Look up the Services the installed Helm releases created and forward a local port to
+each of their ports, for demos or to run e2e suites from a workstation against the
+cluster. Charts are matched by release, chart or service name; without arguments every
+release service is forwarded.
+
+Local ports follow the service's port, 8000 higher for privileged ports (80 becomes
+8080), and the next free one when that is taken. The forwards run until Ctrl+C.
+
+Examples:
+  e2e-k8s-installer port-forward
+  e2e-k8s-installer port-forward api-gateway frontend -n production
+  e2e-k8s-installer port-forward grafana --address 0.0.0.0`,
+	RunE: runPortForward,
+}
+
+func init() {
+	portForwardCmd.Flags().StringVarP(&portForwardNamespace, "namespace", "n", "", "Namespace of the releases, all namespaces when omitted")
+	portForwardCmd.Flags().StringVar(&portForwardKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	portForwardCmd.Flags().StringVar(&portForwardContext, "context", "", "Kubernetes context to use")
+	portForwardCmd.Flags().StringVar(&portForwardAddress, "address", "127.0.0.1", "Local address to listen on")
+}
+
+// portForward is a local port forwarded to a service port
+type portForward struct {
+	service k8s.ReleaseService
+	local   int
+}
+
+func runPortForward(cmd *cobra.Command, args []string) error {
+	if err := initWorkspaceParameters(selectedWorkspace("./workspace")); err != nil {
+		return err
+	}
+	k8sMgr, err := k8s.NewManager(&config.K8sConfig{ConfigPath: portForwardKubeconfig, Context: portForwardContext})
+	if err != nil {
+		return err
+	}
+	services, err := k8sMgr.ReleaseServices(portForwardNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to list release services: %w", err)
+	}
+	services, err = selectReleaseServices(services, args)
+	if err != nil {
+		return err
+	}
+
+	taken := make(map[int]bool)
+	forwards := make([]portForward, 0, len(services))
+	for _, service := range services {
+		forwards = append(forwards, portForward{service: service, local: localPort(portForwardAddress, service.Port, taken)})
+	}
+	printPortForwards(forwards)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pterm.Info.Println("Forwarding, press Ctrl+C to stop")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for _, forward := range forwards {
+		wg.Add(1)
+		go func(forward portForward) {
+			defer wg.Done()
+			target := fmt.Sprintf("svc/%s:%d", forward.service.Service, forward.service.Port)
+			// kubectl's output already goes to the tool log of the run
+			if err := k8sMgr.PortForward(ctx, io.Discard, forward.service, portForwardAddress, forward.local); err != nil {
+				pterm.Warning.Printf("Forward of %s:%d to %s ended: %v\n", portForwardAddress, forward.local, target, err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(forward)
+	}
+	wg.Wait()
+
+	if failed == len(forwards) && ctx.Err() == nil {
+		return fmt.Errorf("every port forward failed")
+	}
+	return nil
+}
+
+// selectReleaseServices keeps the services matching a release, chart or service name;
+// no names keep them all
+func selectReleaseServices(services []k8s.ReleaseService, names []string) ([]k8s.ReleaseService, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no services of Helm releases found, deploy the charts first")
+	}
+	if len(names) == 0 {
+		return services, nil
+	}
+
+	var selected []k8s.ReleaseService
+	for _, name := range names {
+		found := false
+		for _, service := range services {
+			if service.Release == name || service.Chart == name || service.Service == name {
+				if !slices.Contains(selected, service) {
+					selected = append(selected, service)
+				}
+				found = true
+			}
+		}
+		if !found {
+			var releases []string
+			for _, service := range services {
+				if !slices.Contains(releases, service.Release) {
+					releases = append(releases, service.Release)
+				}
+			}
+			return nil, fmt.Errorf("no release, chart or service named %s, installed releases: %v", name, releases)
+		}
+	}
+	return selected, nil
+}
+
+// localPort picks the local port for a service port: the same port, 8000 higher when it is
+// privileged, or the next one free on address
+func localPort(address string, remote int, taken map[int]bool) int {
+	port := remote
+	if port < 1024 {
+		port += 8000
+	}
+	for ; port < 65535; port++ {
+		if taken[port] {
+			continue
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		listener.Close()
+		break
+	}
+	taken[port] = true
+	return port
+}
+
+func printPortForwards(forwards []portForward) {
+	data := [][]string{{"Release", "Service", "Namespace", "Local", "Remote"}}
+	for _, forward := range forwards {
+		service := forward.service
+		remote := strconv.Itoa(service.Port)
+		if service.PortName != "" {
+			remote += " (" + service.PortName + ")"
+		}
+		data = append(data, []string{
+			service.Release, service.Service, service.Namespace,
+			net.JoinHostPort(portForwardAddress, strconv.Itoa(forward.local)),
+			remote,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/postrender"
+	"github.com/spf13/cobra"
+)
+
+var (
+	postRenderLabels    []string
+	postRenderRenderers string
+)
+
+// postRenderCmd is the Helm post-renderer the installer registers with helm template, which
+// pipes the rendered chart through it; installs and upgrades run the same post-rendering in
+// the process. The chart's own post-renderers run first so objects they add are labelled too.
+var postRenderCmd = &cobra.Command{
+	Use:    "post-render",
+	Short:  "Helm post-renderer applying the installer label policy",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE:   runPostRender,
+}
+
+func init() {
+	postRenderCmd.Flags().StringArrayVar(&postRenderLabels, "label", []string{}, "Label to add to every object (key=value, repeatable)")
+	postRenderCmd.Flags().StringVar(&postRenderRenderers, "renderers", "", "Chart post-renderers to run first, as JSON")
+}
+
+func runPostRender(cmd *cobra.Command, args []string) error {
+	policy, err := labels.Parse(postRenderLabels)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read rendered manifests: %w", err)
+	}
+
+	var renderers []config.ChartPostRenderer
+	if postRenderRenderers != "" {
+		if err := json.Unmarshal([]byte(postRenderRenderers), &renderers); err != nil {
+			return fmt.Errorf("invalid --renderers: %w", err)
+		}
+	}
+
+	labelled, err := postRenderManifest(manifest, renderers, policy)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(labelled)
+	return err
+}
+
+// postRenderManifest runs rendered manifests through a chart's post-renderers, then labels
+// every object with the policy
+func postRenderManifest(manifest []byte, renderers []config.ChartPostRenderer, policy map[string]string) ([]byte, error) {
+	manifest, err := postrender.Run(manifest, renderers)
+	if err != nil {
+		return nil, err
+	}
+	return labels.Apply(manifest, policy)
+}
+
+// releasePostRenderer returns the post-renderer the Helm SDK runs in the process for a
+// chart's releases, or nil when neither the chart nor the label policy has anything to do
+func releasePostRenderer(chart config.DeployChart) func(manifest []byte) ([]byte, error) {
+	policy := labels.Global()
+	if len(policy) == 0 && len(chart.PostRenderers) == 0 {
+		return nil
+	}
+	return func(manifest []byte) ([]byte, error) {
+		return postRenderManifest(manifest, chart.PostRenderers, policy)
+	}
+}
+
+// postRendererArgs returns the helm flags running the chart's rendered manifests through
+// post-render, for the releases helm template renders
+func postRendererArgs(chart config.DeployChart) ([]string, error) {
+	policy := labels.Global()
+	if len(policy) == 0 && len(chart.PostRenderers) == 0 {
+		return nil, nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate installer binary for the helm post-renderer: %w", err)
+	}
+
+	args := []string{"--post-renderer", self, "--post-renderer-args", "post-render"}
+	for _, label := range labels.Args(policy) {
+		args = append(args, "--post-renderer-args", "--label="+label)
+	}
+
+	if len(chart.PostRenderers) > 0 {
+		// Helm may run the post-renderer from another directory, so pass absolute paths
+		renderers := make([]config.ChartPostRenderer, len(chart.PostRenderers))
+		for i, renderer := range chart.PostRenderers {
+			renderers[i] = renderer
+			if renderer.Type == postrender.TypeKustomize || filepath.Base(renderer.Path) != renderer.Path {
+				if abs, err := filepath.Abs(renderer.Path); err == nil {
+					renderers[i].Path = abs
+				}
+			}
+		}
+		data, err := json.Marshal(renderers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode post-renderers for %s: %w", chart.Name, err)
+		}
+		args = append(args, "--post-renderer-args", "--renderers="+string(data))
+	}
+	return args, nil
+}
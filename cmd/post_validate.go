@@ -9,6 +9,16 @@ import (
 	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/gpu"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
+	"github.com/judebantony/e2e-k8s-installer/pkg/loadtest"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/soak"
+	"github.com/judebantony/e2e-k8s-installer/pkg/synthetic"
 	"github.com/pterm/pterm"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -24,6 +34,8 @@ var (
 	postValidateSkipHealth bool
 	postValidateSkipCustom bool
 	postValidateChecksOnly []string
+	postValidateTargetVer  string
+	postValidateSoak       string
 )
 
 // postValidateCmd represents the post-validate command
@@ -38,8 +50,12 @@ This command handles:
 - Custom validation script execution
 - Database connectivity and integrity checks
 - Service-to-service communication validation
+- Synthetic transactions: scripted multi-step API workflows with assertions
 - Performance and load validation
+- Resilience: deleting a pod of each critical Deployment and checking its recovery
 - Security and compliance checks
+- Deprecated Kubernetes API usage, against the next minor version by default
+- An optional soak period gated on SLO burn rates
 
 Examples:
   # Run all post-deployment validations
@@ -58,12 +74,18 @@ Examples:
   e2e-k8s-installer post-validate --skip-health
 
   # Dry run to preview validation plan
-  e2e-k8s-installer post-validate --dry-run`,
-	RunE: runPostValidate,
+  e2e-k8s-installer post-validate --dry-run
+
+  # Report the charts to update before the cluster moves to Kubernetes 1.31
+  e2e-k8s-installer post-validate --checks-only deprecated-apis --target-version 1.31
+
+  # Watch the configured SLOs for 15 minutes and fail if their error budget burns too fast
+  e2e-k8s-installer post-validate --soak 15m`,
+	RunE: withExitCode(exitcode.Validation, runPostValidate),
 }
 
 func init() {
-	postValidateCmd.Flags().StringVar(&postValidateConfigPath, "config", "", "Path to post-validation configuration file")
+	postValidateCmd.Flags().StringVar(&postValidateConfigPath, "config", "", "Path to the installer configuration file")
 	postValidateCmd.Flags().BoolVarP(&postValidateVerbose, "verbose", "v", false, "Enable verbose logging")
 	postValidateCmd.Flags().BoolVar(&postValidateDryRun, "dry-run", false, "Preview validation plan without executing")
 	postValidateCmd.Flags().StringVar(&postValidateNamespace, "namespace", "", "Kubernetes namespace to validate")
@@ -72,11 +94,14 @@ func init() {
 	postValidateCmd.Flags().BoolVar(&postValidateSkipHealth, "skip-health", false, "Skip health check validations")
 	postValidateCmd.Flags().BoolVar(&postValidateSkipCustom, "skip-custom", false, "Skip custom validation scripts")
 	postValidateCmd.Flags().StringSliceVar(&postValidateChecksOnly, "checks-only", []string{}, "Run only specified validation checks (comma-separated)")
+	postValidateCmd.Flags().StringVar(&postValidateTargetVer, "target-version", "", "Kubernetes version to check deployed APIs against (default: one minor above the cluster)")
+	postValidateCmd.Flags().StringVar(&postValidateSoak, "soak", "", "Monitor the SLOs for this long after the other checks (overrides validation.post.soak.duration)")
+	addFailOnFlag(postValidateCmd)
 }
 
 func runPostValidate(cmd *cobra.Command, args []string) error {
 	// Initialize logger
-	logger := zerolog.New(os.Stderr).With().
+	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, logger.Journal())).With().
 		Timestamp().
 		Str("component", "post-validate").
 		Logger()
@@ -85,6 +110,10 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 		logger = logger.Level(zerolog.DebugLevel)
 	}
 
+	if err := validateFailOn(); err != nil {
+		return err
+	}
+
 	// Create spinner for initialization
 	spinner, _ := pterm.DefaultSpinner.Start("Initializing post-deployment validation...")
 
@@ -92,14 +121,21 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 	startTime := time.Now()
 
 	// Load configuration
-	config, err := loadPostValidateConfig(postValidateConfigPath)
+	config, err := loadPostValidateConfig(workspaceConfigFile(cmd, postValidateConfigPath))
 	if err != nil {
 		spinner.Fail("Failed to load configuration")
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
 	}
 
 	spinner.Success("Configuration loaded")
 	logger.Info().Msg("Post-validation configuration loaded successfully")
+	if err := initWorkspaceParameters(config.Workspace); err != nil {
+		return err
+	}
+
+	if err := requireLicense(nil, license.ModulePostValidate); err != nil {
+		return err
+	}
 
 	// Create validation manager
 	manager, err := NewPostValidationManager(config, logger)
@@ -133,6 +169,12 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 			action:      manager.ValidateConnectivity,
 			skip:        false,
 		},
+		{
+			name:        "synthetic-transactions",
+			description: "Running synthetic transactions",
+			action:      manager.RunSyntheticTransactions,
+			skip:        len(config.Validation.Post.Synthetics) == 0,
+		},
 		{
 			name:        "custom-validations",
 			description: "Running custom validation scripts",
@@ -145,12 +187,42 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 			action:      manager.ValidatePerformance,
 			skip:        false,
 		},
+		{
+			name:        "load-gate",
+			description: "Running the load test gate",
+			action:      manager.RunLoadGate,
+			skip:        !config.Validation.Post.Load.Enabled,
+		},
+		{
+			name:        "resilience",
+			description: "Deleting pods and checking recovery",
+			action:      manager.RunResilienceCheck,
+			skip:        !config.Validation.Post.Resilience.Enabled,
+		},
 		{
 			name:        "security-checks",
 			description: "Performing security validation",
 			action:      manager.ValidateSecurity,
 			skip:        false,
 		},
+		{
+			name:        "deprecated-apis",
+			description: "Scanning for deprecated Kubernetes APIs",
+			action:      manager.ValidateAPIDeprecations,
+			skip:        false,
+		},
+		{
+			name:        "gpu-checks",
+			description: "Validating GPU nodes and drivers",
+			action:      manager.ValidateGPUs,
+			skip:        !config.Kubernetes.GPU.Enabled,
+		},
+		{
+			name:        "soak",
+			description: "Soaking and checking SLO burn rates",
+			action:      manager.RunSoak,
+			skip:        manager.SoakDuration() == 0,
+		},
 	}
 
 	// Filter steps based on checks-only flag
@@ -203,6 +275,31 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 		append([][]string{{"Property", "Value"}}, info...),
 	).Render()
 
+	if len(manager.syntheticResults) > 0 {
+		pterm.DefaultSection.Println("Synthetic Transactions")
+		printSyntheticResults(manager.syntheticResults)
+	}
+
+	if manager.loadResult != nil {
+		pterm.DefaultSection.Println("Load Gate")
+		printLoadResult(manager.loadResult)
+	}
+
+	if manager.gpuReport != nil {
+		pterm.DefaultSection.Println("GPU Checks")
+		printGPUReport(manager.gpuReport)
+	}
+
+	if len(manager.resilienceResults) > 0 {
+		pterm.DefaultSection.Println("Resilience")
+		printResilienceResults(manager.resilienceResults)
+	}
+
+	if len(manager.soakResults) > 0 {
+		pterm.DefaultSection.Println("SLO Soak")
+		printSoakResults(manager.soakResults)
+	}
+
 	// Display detailed results if there are failures
 	if results.FailedChecks > 0 {
 		pterm.DefaultSection.Println("Failed Validations")
@@ -232,13 +329,16 @@ func runPostValidate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("post-validation completed with %d failed checks", results.FailedChecks)
 	}
 
-	return nil
+	return checkFailOn(collectFindings(findingsDirs()))
 }
 
 // PostValidationConfig represents post-validation configuration
 type PostValidationConfig struct {
 	Validation config.ValidationConfig `json:"validation"`
 	Kubernetes config.K8sConfig        `json:"kubernetes"`
+	Monitoring config.MonitoringConfig `json:"monitoring"`
+	Images     []config.ImageReference `json:"images,omitempty"`
+	Workspace  string                  `json:"-"` // selected workspace, whose state holds the parameters
 }
 
 // ValidationResults represents the results of validation execution
@@ -273,6 +373,13 @@ type PostValidationManager struct {
 	namespace         string
 	timeout           time.Duration
 	validationResults ValidationResults
+	apiFindings       []deprecations.Finding
+	soakResults       []soak.Result
+	syntheticResults  []synthetic.Result
+	loadResult        *loadtest.Result
+	resilienceResults []k8s.PodKillResult
+	gpuReport         *gpu.Report
+	progress          func(message string)
 }
 
 // NewPostValidationManager creates a new post-validation manager
@@ -398,6 +505,75 @@ func (m *PostValidationManager) ValidateConnectivity() error {
 	return nil
 }
 
+// RunSyntheticTransactions runs every configured transaction, failing when any of them fails
+func (m *PostValidationManager) RunSyntheticTransactions() error {
+	transactions := m.config.Validation.Post.Synthetics
+	m.logger.Info().Int("transactions", len(transactions)).Msg("Running synthetic transactions")
+
+	if postValidateDryRun {
+		m.logger.Info().Msg("DRY RUN: Synthetic transactions skipped")
+		return nil
+	}
+
+	store := params.GetStore()
+	runner := synthetic.NewRunner(store.Values(), store.SensitiveValues())
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var failed []string
+	for i, tx := range transactions {
+		if m.progress != nil {
+			m.progress(fmt.Sprintf("%s (%d/%d)", tx.Name, i+1, len(transactions)))
+		}
+		result := runner.Run(ctx, tx)
+		m.syntheticResults = append(m.syntheticResults, result)
+		if !result.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", tx.Name, result.Error))
+			m.logger.Error().Str("transaction", tx.Name).Str("error", result.Error).Str("duration", result.Duration).Msg("Synthetic transaction failed")
+			continue
+		}
+		m.validationResults.PassedChecks++
+		m.logger.Info().Str("transaction", tx.Name).Int("steps", len(result.Steps)).Str("duration", result.Duration).Msg("Synthetic transaction passed")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d synthetic transactions failed: %s", len(failed), len(transactions), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// printSyntheticResults renders one row per transaction step with its timing
+func printSyntheticResults(results []synthetic.Result) {
+	tableData := pterm.TableData{{"Transaction", "Step", "Request", "Status", "Duration", "Result"}}
+	for _, result := range results {
+		for _, step := range result.Steps {
+			outcome := "✅"
+			switch {
+			case step.Skipped:
+				outcome = "⏭️ skipped"
+			case !step.Passed:
+				outcome = "❌ " + step.Error
+			}
+			status := "-"
+			if step.Status != 0 {
+				status = fmt.Sprintf("%d", step.Status)
+			}
+			tableData = append(tableData, []string{
+				result.Name, step.Name, strings.TrimSpace(step.Method + " " + step.URL), status, valueOr(step.Duration, "-"), outcome,
+			})
+		}
+		tableData = append(tableData, []string{result.Name, "total", "", "", result.Duration, passedLabel(result.Passed)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+func passedLabel(passed bool) string {
+	if passed {
+		return "✅ passed"
+	}
+	return "❌ failed"
+}
+
 // RunCustomValidations runs custom validation scripts
 func (m *PostValidationManager) RunCustomValidations() error {
 	m.logger.Info().Msg("Running custom validation scripts")
@@ -456,6 +632,158 @@ func (m *PostValidationManager) ValidatePerformance() error {
 	return nil
 }
 
+// RunLoadGate puts the configured endpoints under load and fails when the error rate,
+// latency percentiles or throughput miss their thresholds
+func (m *PostValidationManager) RunLoadGate() error {
+	gate := m.config.Validation.Post.Load
+	engine, users, duration, err := loadtest.Settings(gate)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	m.logger.Info().Str("engine", engine).Int("virtual_users", users).Dur("duration", duration).Msg("Starting load gate")
+
+	if postValidateDryRun {
+		m.logger.Info().Msg("DRY RUN: Load gate skipped")
+		return nil
+	}
+	if m.progress != nil {
+		m.progress(fmt.Sprintf("%d virtual users for %s (%s)", users, duration, engine))
+	}
+
+	result, err := loadtest.Run(context.Background(), gate, filepath.Join(reportsDir(), "load-test"))
+	if err != nil {
+		return err
+	}
+	m.loadResult = result
+	m.logger.Info().
+		Int("requests", result.Requests).
+		Float64("error_rate", result.ErrorRate).
+		Float64("rps", result.RPS).
+		Str("p95", result.P95).
+		Str("p99", result.P99).
+		Msg("Load test finished")
+
+	if !result.Passed {
+		return fmt.Errorf("load gate failed: %s", strings.Join(result.Breaches, "; "))
+	}
+	m.validationResults.PassedChecks++
+	return nil
+}
+
+// RunResilienceCheck deletes one pod of every critical Deployment in turn and fails when a
+// service became unavailable or its pod was not replaced within the recovery timeout
+func (m *PostValidationManager) RunResilienceCheck() error {
+	check := m.config.Validation.Post.Resilience
+	timeout, interval := 2*time.Minute, time.Second
+	if check.RecoveryTimeout != "" {
+		timeout, _ = time.ParseDuration(check.RecoveryTimeout)
+	}
+	if check.ProbeInterval != "" {
+		interval, _ = time.ParseDuration(check.ProbeInterval)
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+	targets := check.Deployments
+	if check.Selector != "" {
+		names, err := k8sMgr.DeploymentNames(m.namespace, check.Selector)
+		if err != nil {
+			return fmt.Errorf("failed to list critical deployments: %w", err)
+		}
+		for _, name := range names {
+			if !hasResilienceTarget(targets, m.namespace, name) {
+				targets = append(targets, config.ResilienceTarget{Name: name})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("no deployments selected for the resilience check"))
+	}
+	m.logger.Info().Int("deployments", len(targets)).Dur("recovery_timeout", timeout).Msg("Starting resilience check")
+
+	if postValidateDryRun {
+		for _, target := range targets {
+			m.logger.Info().Str("deployment", target.Name).Msg("DRY RUN: Would delete one pod")
+		}
+		return nil
+	}
+
+	var failed []string
+	for i, target := range targets {
+		namespace := valueOr(target.Namespace, m.namespace)
+		if m.progress != nil {
+			m.progress(fmt.Sprintf("%s (%d/%d)", target.Name, i+1, len(targets)))
+		}
+		result := k8sMgr.PodKill(namespace, target.Name, target.URL, timeout, interval)
+		m.resilienceResults = append(m.resilienceResults, result)
+		if result.Warning != "" {
+			m.logger.Warn().Str("deployment", target.Name).Msg(result.Warning)
+		}
+		if !result.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", target.Name, result.Error))
+			m.logger.Error().Str("deployment", target.Name).Str("error", result.Error).Msg("Resilience check failed")
+			continue
+		}
+		m.logger.Info().
+			Str("deployment", target.Name).
+			Str("pod", result.Pod).
+			Str("replacement", result.Replacement).
+			Str("recovery_time", result.RecoveryTime).
+			Msg("Pod replaced without downtime")
+		m.validationResults.PassedChecks++
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d deployments are not resilient to losing a pod: %s", len(failed), len(targets), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func hasResilienceTarget(targets []config.ResilienceTarget, namespace, name string) bool {
+	for _, target := range targets {
+		if target.Name == name && valueOr(target.Namespace, namespace) == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// printResilienceResults renders one row per disrupted Deployment
+func printResilienceResults(results []k8s.PodKillResult) {
+	tableData := pterm.TableData{{"Deployment", "Replicas", "PDB", "Deleted Pod", "Recovery", "Min Ready", "Probes", "Result"}}
+	for _, result := range results {
+		outcome := passedLabel(result.Passed)
+		if !result.Passed {
+			outcome = "❌ " + result.Error
+		}
+		probes := "-"
+		if result.Probes > 0 {
+			probes = fmt.Sprintf("%d/%d ok", result.Probes-result.FailedProbes, result.Probes)
+		}
+		tableData = append(tableData, []string{
+			result.Namespace + "/" + result.Deployment, fmt.Sprintf("%d", result.Replicas), valueOr(result.PDB, "none"),
+			valueOr(result.Pod, "-"), valueOr(result.RecoveryTime, "-"), fmt.Sprintf("%d", result.MinReady), probes, outcome,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// printLoadResult renders the load test measurements and any breached thresholds
+func printLoadResult(result *loadtest.Result) {
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Engine", "Users", "Duration", "Requests", "Errors", "Req/s", "p50", "p95", "p99", "Result"},
+		{
+			result.Engine, fmt.Sprintf("%d", result.VirtualUsers), result.Duration, fmt.Sprintf("%d", result.Requests),
+			fmt.Sprintf("%.2f%%", result.ErrorRate*100), fmt.Sprintf("%.1f", result.RPS),
+			result.P50, result.P95, result.P99, passedLabel(result.Passed),
+		},
+	}).Render()
+	for _, breach := range result.Breaches {
+		pterm.Error.Println(breach)
+	}
+}
+
 // ValidateSecurity performs security validation
 func (m *PostValidationManager) ValidateSecurity() error {
 	m.logger.Info().Msg("Performing security validation")
@@ -485,6 +813,183 @@ func (m *PostValidationManager) ValidateSecurity() error {
 	return nil
 }
 
+// ValidateAPIDeprecations scans the deployed releases and kubectl-applied objects for APIs
+// deprecated or removed in the target version, failing with the charts that block the upgrade
+func (m *PostValidationManager) ValidateAPIDeprecations() error {
+	m.logger.Info().Msg("Scanning for deprecated Kubernetes APIs")
+
+	if postValidateDryRun {
+		m.logger.Info().Msg("DRY RUN: Deprecated API scan skipped")
+		return nil
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+	target, err := m.apiTargetVersion(k8sMgr)
+	if err != nil {
+		return err
+	}
+
+	deployCfg, err := loadDeployConfig("")
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load deployment configuration: %w", err))
+	}
+	findings, err := scanReleaseAPIs(deployCfg, target)
+	if err != nil {
+		return err
+	}
+	live, err := scanLiveAPIs(k8sMgr, target)
+	if err != nil {
+		return err
+	}
+	m.apiFindings = append(findings, live...)
+
+	for _, finding := range m.apiFindings {
+		m.logger.Warn().
+			Str("chart", finding.Source).
+			Str("object", findingObject(finding)).
+			Str("api_version", finding.APIVersion).
+			Str("replacement", finding.Replacement).
+			Bool("removed", finding.Removed).
+			Msg("Deprecated API in use")
+	}
+	if err := deprecationError(m.apiFindings, target); err != nil {
+		return err
+	}
+
+	m.logger.Info().Str("target", target.String()).Int("deprecated", len(m.apiFindings)).Msg("Deprecated API scan completed")
+	m.validationResults.PassedChecks++
+	return nil
+}
+
+// ValidateGPUs checks the GPU nodes, device plugin and driver versions, and runs the
+// nvidia-smi smoke Job when kubernetes.gpu.smokeTest is enabled
+func (m *PostValidationManager) ValidateGPUs() error {
+	m.logger.Info().Msg("Validating GPU nodes")
+
+	if postValidateDryRun {
+		m.logger.Info().Msg("DRY RUN: GPU validation skipped")
+		return nil
+	}
+
+	k8sMgr, err := k8s.NewManager(&m.config.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+	gpuCfg := m.config.Kubernetes.GPU
+	report, err := gpu.Run(k8sMgr, gpuCfg, m.config.Images, m.namespace, gpuCfg.SmokeTest.Enabled)
+	if err != nil {
+		return err
+	}
+	m.gpuReport = report
+
+	for _, check := range report.Checks {
+		switch {
+		case !check.Passed:
+			m.logger.Error().Str("check", check.Name).Msg(check.Detail)
+		case check.Warning:
+			m.logger.Warn().Str("check", check.Name).Msg(check.Detail)
+		default:
+			m.validationResults.PassedChecks++
+		}
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("GPU validation failed: %s", strings.Join(failed, "; "))
+	}
+	m.logger.Info().Int("nodes", len(report.Nodes)).Msg("GPU validation completed")
+	return nil
+}
+
+// SoakDuration returns --soak, or the configured soak duration, 0 when there is no soak phase
+func (m *PostValidationManager) SoakDuration() time.Duration {
+	value := m.config.Validation.Post.Soak.Duration
+	if postValidateSoak != "" {
+		value = postValidateSoak
+	}
+	duration, _ := time.ParseDuration(value)
+	return duration
+}
+
+// RunSoak samples the configured SLOs for the soak duration and fails when one of them
+// burns its error budget faster than its maxBurnRate, so a progressive rollout can stop here
+func (m *PostValidationManager) RunSoak() error {
+	duration := m.SoakDuration()
+	m.logger.Info().Dur("duration", duration).Int("slos", len(m.config.Validation.Post.Soak.SLOs)).Msg("Starting soak period")
+
+	runner, err := soak.NewRunner(m.config.Validation.Post.Soak, m.config.Monitoring.Prometheus.Endpoint)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	if postValidateDryRun {
+		m.logger.Info().Msg("DRY RUN: Soak period skipped")
+		return nil
+	}
+
+	m.soakResults = runner.Run(context.Background(), duration, func(elapsed time.Duration, results []soak.Result) {
+		worst := results[0]
+		for _, result := range results {
+			if result.BurnRate() > worst.BurnRate() {
+				worst = result
+			}
+		}
+		if m.progress != nil {
+			m.progress(fmt.Sprintf("%s of %s, highest burn rate %.2f (%s)",
+				elapsed.Round(time.Second), duration, worst.BurnRate(), worst.Name))
+		}
+		m.logger.Debug().Dur("elapsed", elapsed).Str("slo", worst.Name).Float64("burn_rate", worst.BurnRate()).Msg("Soak sample")
+	})
+
+	var breached []string
+	for _, result := range m.soakResults {
+		if result.Passed {
+			m.validationResults.PassedChecks++
+			continue
+		}
+		breached = append(breached, fmt.Sprintf("%s: %s", result.Name, result.Reason))
+		m.logger.Error().Str("slo", result.Name).Str("reason", result.Reason).Msg("SLO breached during soak")
+	}
+	if len(breached) > 0 {
+		return fmt.Errorf("%d SLOs breached during the soak: %s", len(breached), strings.Join(breached, "; "))
+	}
+	m.logger.Info().Int("slos", len(m.soakResults)).Msg("Soak period completed within SLO")
+	return nil
+}
+
+// printSoakResults renders one row per SLO evaluated during the soak
+func printSoakResults(results []soak.Result) {
+	tableData := pterm.TableData{{"SLO", "Source", "Objective", "Samples", "Error Ratio", "Burn Rate", "Max Latency", "Status"}}
+	for _, result := range results {
+		status := "✅ within SLO"
+		if !result.Passed {
+			status = "❌ " + result.Reason
+		}
+		tableData = append(tableData, []string{
+			result.Name, result.Source, fmt.Sprintf("%.3f%%", result.Objective*100), fmt.Sprintf("%d", result.Samples),
+			fmt.Sprintf("%.4f", result.ErrorRatio), fmt.Sprintf("%.2f / %.2f", result.BurnRate(), result.MaxBurnRate),
+			valueOr(result.MaxLatency, "-"), status,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// apiTargetVersion returns --target-version, or the minor version after the cluster's
+func (m *PostValidationManager) apiTargetVersion(k8sMgr *k8s.Manager) (deprecations.Version, error) {
+	if postValidateTargetVer != "" {
+		return deprecations.ParseVersion(postValidateTargetVer)
+	}
+	serverVersion, err := k8sMgr.ServerVersion()
+	if err != nil {
+		return deprecations.Version{}, fmt.Errorf("failed to read the cluster version: %w", err)
+	}
+	current, err := deprecations.ParseVersion(serverVersion)
+	if err != nil {
+		return deprecations.Version{}, err
+	}
+	return deprecations.Version{Major: current.Major, Minor: current.Minor + 1}, nil
+}
+
 // ExecuteStepsSequential executes validation steps sequentially
 func (m *PostValidationManager) ExecuteStepsSequential(ctx context.Context, steps []ValidationStep, progressArea *pterm.AreaPrinter) error {
 	for i, step := range steps {
@@ -495,6 +1000,9 @@ func (m *PostValidationManager) ExecuteStepsSequential(ctx context.Context, step
 
 		stepProgress := fmt.Sprintf("[%d/%d] %s", i+1, len(steps), step.description)
 		progressArea.Update(pterm.Sprintf("🔄 %s", stepProgress))
+		m.progress = func(message string) {
+			progressArea.Update(pterm.Sprintf("⏳ %s: %s", stepProgress, message))
+		}
 
 		m.logger.Info().Str("step", step.name).Msg("Starting validation step")
 
@@ -541,7 +1049,7 @@ func (m *PostValidationManager) ExecuteStepsParallel(ctx context.Context, steps
 
 // GenerateReport generates post-validation report
 func (m *PostValidationManager) GenerateReport() error {
-	reportPath := filepath.Join(".", "reports", "post-validation-report.json")
+	reportPath := filepath.Join(reportsDir(), "post-validation-report.json")
 
 	// Create reports directory
 	if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
@@ -549,19 +1057,30 @@ func (m *PostValidationManager) GenerateReport() error {
 	}
 
 	report := map[string]interface{}{
-		"timestamp":      time.Now().UTC().Format(time.RFC3339),
-		"namespace":      m.namespace,
-		"total_checks":   m.validationResults.TotalChecks,
-		"passed_checks":  m.validationResults.PassedChecks,
-		"failed_checks":  m.validationResults.FailedChecks,
-		"skipped_checks": m.validationResults.SkippedChecks,
-		"success_rate":   m.validationResults.SuccessRate,
-		"failures":       m.validationResults.Failures,
-		"dry_run":        postValidateDryRun,
-		"status":         "completed",
-	}
-
-	// TODO: Write actual report to file
+		"timestamp":       time.Now().UTC().Format(time.RFC3339),
+		"namespace":       m.namespace,
+		"total_checks":    m.validationResults.TotalChecks,
+		"passed_checks":   m.validationResults.PassedChecks,
+		"failed_checks":   m.validationResults.FailedChecks,
+		"skipped_checks":  m.validationResults.SkippedChecks,
+		"success_rate":    m.validationResults.SuccessRate,
+		"failures":        m.validationResults.Failures,
+		"deprecated_apis": m.apiFindings,
+		"soak":            m.soakResults,
+		"synthetics":      m.syntheticResults,
+		"load":            m.loadResult,
+		"resilience":      m.resilienceResults,
+		"gpu":             m.gpuReport,
+		"dry_run":         postValidateDryRun,
+		"status":          "completed",
+	}
+	if info := license.ReportInfo(); info != nil {
+		report["license"] = info
+	}
+
+	if err := writeReport(reportPath, report); err != nil {
+		return err
+	}
 	m.logger.Info().Interface("report", report).Str("report_path", reportPath).Msg("Post-validation report generated")
 	return nil
 }
@@ -597,10 +1116,22 @@ func filterValidationSteps(steps []ValidationStep, checksOnly []string) []Valida
 }
 
 func loadPostValidateConfig(configPath string) (*PostValidationConfig, error) {
-	// Load configuration from file or use defaults
-	// For now, return a default configuration
+	if configPath != "" {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		return &PostValidationConfig{
+			Validation: cfg.Validation,
+			Kubernetes: cfg.Kubernetes,
+			Monitoring: cfg.Monitoring,
+			Images:     cfg.Artifacts.Images.Images,
+			Workspace:  selectedWorkspace(valueOr(cfg.Installer.Workspace, "./workspace")),
+		}, nil
+	}
 
-	config := &PostValidationConfig{
+	// Without a configuration file the sample application is validated
+	defaults := &PostValidationConfig{
 		Validation: config.ValidationConfig{
 			Post: config.PostValidation{
 				Scripts: []config.ScriptConfig{
@@ -629,8 +1160,7 @@ func loadPostValidateConfig(configPath string) (*PostValidationConfig, error) {
 			Timeout:     "5m",
 			WaitTimeout: "10m",
 		},
+		Workspace: selectedWorkspace("./workspace"),
 	}
-
-	// TODO: Implement actual configuration loading from file
-	return config, nil
+	return defaults, nil
 }
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	preflightKubeconfig string
+	preflightContext    string
+	preflightNamespace  string
+	preflightSteps      []string
+	preflightRoleName   string
+	preflightRoleOut    string
+	preflightOutput     string
+)
+
+// defaultInstallerRoleName is the name of the generated least-privilege ClusterRole
+const defaultInstallerRoleName = "e2e-k8s-installer"
+
+// preflightCmd represents the preflight command
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Run pre-installation checks against the target cluster",
+	Long: `Run checks against the target cluster before an installation starts so that
+problems surface before any resources have been changed.`,
+}
+
+// preflightRBACCmd represents the preflight rbac command
+var preflightRBACCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Verify the current identity has every permission the installer needs",
+	Long: `Check every verb and resource each installation step needs using
+SelfSubjectAccessReview against the target cluster, print a consolidated report
+of missing permissions, and generate a ClusterRole manifest that grants exactly
+what the selected steps require.
+
+Examples:
+  e2e-k8s-installer preflight rbac --namespace production
+  e2e-k8s-installer preflight rbac --steps deploy,post-validate --role-out installer-role.yaml`,
+	RunE: withExitCode(exitcode.Preflight, runPreflightRBAC),
+}
+
+func init() {
+	preflightCmd.AddCommand(preflightRBACCmd)
+
+	preflightCmd.PersistentFlags().StringVar(&preflightKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	preflightCmd.PersistentFlags().StringVar(&preflightContext, "context", "", "Kubernetes context to use")
+	preflightCmd.PersistentFlags().StringVar(&preflightNamespace, "namespace", "default", "Namespace the installation targets")
+
+	preflightRBACCmd.Flags().StringSliceVar(&preflightSteps, "steps", []string{"deploy", "db-migrate", "post-validate", "e2e-test"}, "Installation steps to check permissions for")
+	preflightRBACCmd.Flags().StringVar(&preflightRoleName, "role-name", defaultInstallerRoleName, "Name of the generated ClusterRole")
+	preflightRBACCmd.Flags().StringVar(&preflightRoleOut, "role-out", "", "Write the generated ClusterRole manifest to this file")
+	preflightRBACCmd.Flags().StringVarP(&preflightOutput, "output", "o", "table", "Output format (table, json)")
+}
+
+func runPreflightRBAC(cmd *cobra.Command, args []string) error {
+	for _, step := range preflightSteps {
+		if _, ok := k8s.PermissionCatalog[step]; !ok {
+			return fmt.Errorf("no permission catalog for step %q (known steps: %s)", step, strings.Join(catalogSteps(), ", "))
+		}
+	}
+
+	if err := initWorkspaceParameters(selectedWorkspace("./workspace")); err != nil {
+		return err
+	}
+
+	k8sMgr, err := k8s.NewManager(&config.K8sConfig{
+		ConfigPath: preflightKubeconfig,
+		Context:    preflightContext,
+	})
+	if err != nil {
+		return err
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Reviewing installer permissions...")
+	checks, err := k8sMgr.CheckPermissions(preflightSteps, preflightNamespace)
+	if err != nil {
+		spinner.Fail("Permission review failed")
+		return err
+	}
+	missing := k8s.MissingPermissions(checks)
+	spinner.Success(fmt.Sprintf("Reviewed %d permissions", len(checks)))
+
+	// Kept for report sarif and the installation summary
+	if err := writeReport(filepath.Join(reportsDir(), rbacPreflightReportName), map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"namespace": preflightNamespace,
+		"steps":     preflightSteps,
+		"checks":    len(checks),
+		"missing":   missing,
+	}); err != nil {
+		pterm.Warning.Printf("Failed to save the permission report: %v\n", err)
+	}
+
+	manifest := k8s.GenerateClusterRole(preflightRoleName, preflightSteps)
+	if preflightRoleOut != "" {
+		if err := os.WriteFile(preflightRoleOut, []byte(manifest), 0644); err != nil {
+			return fmt.Errorf("failed to write ClusterRole manifest: %w", err)
+		}
+	}
+
+	switch preflightOutput {
+	case "json":
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"checks":  len(checks),
+			"missing": missing,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal permission report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		printPermissionReport(missing)
+		if len(missing) > 0 && preflightRoleOut == "" {
+			pterm.DefaultSection.Println("Least-privilege ClusterRole")
+			fmt.Println(manifest)
+		}
+	default:
+		return fmt.Errorf("unsupported output format: %s", preflightOutput)
+	}
+
+	if preflightRoleOut != "" {
+		pterm.Info.Printf("ClusterRole manifest written to %s\n", preflightRoleOut)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d required permissions are missing", len(missing))
+	}
+	return nil
+}
+
+// printPermissionReport renders the consolidated missing-permissions table
+func printPermissionReport(missing []k8s.PermissionCheck) {
+	pterm.DefaultSection.Println("RBAC Preflight")
+
+	if len(missing) == 0 {
+		pterm.Success.Println("All required permissions are granted")
+		return
+	}
+
+	tableData := [][]string{{"Step", "Verb", "Resource", "API Group", "Namespace"}}
+	for _, check := range missing {
+		group := check.APIGroup
+		if group == "" {
+			group = "core"
+		}
+		namespace := check.Namespace
+		if namespace == "" {
+			namespace = "(cluster)"
+		}
+		tableData = append(tableData, []string{check.Step, check.Verb, check.Resource, group, namespace})
+	}
+
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Error.Printf("%d required permissions are missing\n", len(missing))
+}
+
+func catalogSteps() []string {
+	steps := make([]string, 0, len(k8s.PermissionCatalog))
+	for step := range k8s.PermissionCatalog {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	return steps
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/timesync"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var preflightClockConfig string
+
+// clockPreflightReportName is the report preflight clock writes
+const clockPreflightReportName = "clock-preflight-report.json"
+
+// clockQueryTimeout bounds each NTP and metadata query; off the cloud the metadata service
+// does not answer at all
+const clockQueryTimeout = 3 * time.Second
+
+// preflightClockCmd represents the preflight clock command
+var preflightClockCmd = &cobra.Command{
+	Use:   "clock",
+	Short: "Verify the local clock agrees with NTP, the cloud and the API server",
+	Long: `Compare the local clock with the NTP servers of installer.clock.ntpServers, the
+instance metadata service of cloud.provider and the Kubernetes API server. Skewed
+clocks make certificates look not yet valid or expired and tokens get rejected, with
+errors that rarely point at the clock.
+
+A skew beyond installer.clock.warnSkew (2s by default) is a warning and one beyond
+installer.clock.maxSkew (30s) fails the check. References that cannot be queried,
+such as the metadata service off the cloud, are reported but do not fail it. install
+runs the same check before any work starts.
+
+Examples:
+  e2e-k8s-installer preflight clock --config installer-config.json
+  e2e-k8s-installer preflight clock --workspace prod -o json`,
+	RunE: withExitCode(exitcode.Preflight, runPreflightClock),
+}
+
+func init() {
+	preflightCmd.AddCommand(preflightClockCmd)
+
+	preflightClockCmd.Flags().StringVar(&preflightClockConfig, "config", "installer-config.json", "Path to the installer configuration file")
+	preflightClockCmd.Flags().StringVarP(&preflightOutput, "output", "o", "table", "Output format (table, json)")
+}
+
+func runPreflightClock(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, preflightClockConfig))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+	if err := initWorkspaceParameters(cfg.Installer.Workspace); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("namespace") || cfg.Kubernetes.Namespace == "" {
+		cfg.Kubernetes.Namespace = preflightNamespace
+	}
+	if preflightKubeconfig != "" {
+		cfg.Kubernetes.ConfigPath = preflightKubeconfig
+	}
+	if preflightContext != "" {
+		cfg.Kubernetes.Context = preflightContext
+	}
+
+	measurements, err := checkClock(cfg, preflightOutput == "table")
+	if err != nil {
+		return err
+	}
+	switch preflightOutput {
+	case "json":
+		data, err := json.MarshalIndent(measurements, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal clock report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+	default:
+		return fmt.Errorf("unsupported output format: %s", preflightOutput)
+	}
+	return clockError(cfg, measurements)
+}
+
+// checkClock measures the local clock against every reference at once, saves the results as
+// the clock report and prints them when show is set
+func checkClock(cfg *config.InstallerConfig, show bool) ([]timesync.Measurement, error) {
+	limits, err := timesync.ParseLimits(cfg.Installer.Clock)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	// Simulated and replayed runs answer from inside the process, the network is never used
+	if simulateFlag || replayFlag != "" {
+		if show {
+			pterm.Info.Println("Clock skew not checked in a simulated or replayed run")
+		}
+		return nil, nil
+	}
+
+	var spinner *pterm.SpinnerPrinter
+	if show {
+		spinner, _ = pterm.DefaultSpinner.Start("Comparing the local clock with NTP, the cloud and the API server...")
+	}
+
+	servers := cfg.Installer.Clock.NTPServers
+	if len(servers) == 0 {
+		servers = []string{timesync.DefaultNTPServer}
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var measurements []timesync.Measurement
+	measure := func(query func(ctx context.Context) (timesync.Measurement, bool)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), clockQueryTimeout)
+			defer cancel()
+			if m, ok := query(ctx); ok {
+				limits.Evaluate(&m)
+				mutex.Lock()
+				measurements = append(measurements, m)
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, server := range servers {
+		measure(func(ctx context.Context) (timesync.Measurement, bool) { return timesync.NTP(ctx, server), true })
+	}
+	measure(func(ctx context.Context) (timesync.Measurement, bool) {
+		return timesync.Metadata(ctx, cfg.Cloud.Provider)
+	})
+	measure(func(context.Context) (timesync.Measurement, bool) { return apiServerClock(cfg.Kubernetes), true })
+	wg.Wait()
+
+	// References in a stable order: NTP servers as configured, then the cloud and the cluster
+	order := map[string]int{timesync.KindNTP: 0, timesync.KindMetadata: 1, timesync.KindAPIServer: 2}
+	sort.SliceStable(measurements, func(i, j int) bool {
+		a, b := measurements[i], measurements[j]
+		if order[a.Kind] != order[b.Kind] {
+			return order[a.Kind] < order[b.Kind]
+		}
+		return slices.Index(servers, a.Source) < slices.Index(servers, b.Source)
+	})
+
+	failed, warned := timesync.Worst(measurements)
+	if spinner != nil {
+		switch {
+		case len(failed) > 0:
+			spinner.Fail(fmt.Sprintf("Local clock skewed beyond %s", limits.Max))
+		case len(warned) > 0:
+			spinner.Warning(fmt.Sprintf("Local clock skewed beyond %s", limits.Warn))
+		default:
+			spinner.Success("Local clock in sync")
+		}
+	}
+
+	if err := writeReport(filepath.Join(reportsDir(), clockPreflightReportName), map[string]interface{}{
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		"warnSkew":     limits.Warn.String(),
+		"maxSkew":      limits.Max.String(),
+		"measurements": measurements,
+	}); err != nil {
+		pterm.Warning.Printf("Failed to save the clock report: %v\n", err)
+	}
+	if show {
+		printClockMeasurements(measurements)
+	}
+	return measurements, nil
+}
+
+// apiServerClock measures the API server's clock, unavailable when the cluster cannot be reached
+func apiServerClock(k8sConfig config.K8sConfig) timesync.Measurement {
+	namespace := valueOr(k8sConfig.Namespace, "default")
+	k8sMgr, err := k8s.NewManager(&k8sConfig)
+	if err != nil {
+		return timesync.Unavailable("Kubernetes API server", timesync.KindAPIServer, err)
+	}
+	serverTime, local, err := k8sMgr.ServerTime(namespace)
+	if err != nil {
+		return timesync.Unavailable("Kubernetes API server", timesync.KindAPIServer, err)
+	}
+	return timesync.Compare("Kubernetes API server", timesync.KindAPIServer, serverTime, local, time.Second)
+}
+
+func clockError(cfg *config.InstallerConfig, measurements []timesync.Measurement) error {
+	failed, _ := timesync.Worst(measurements)
+	if len(failed) == 0 {
+		return nil
+	}
+	var skews []string
+	for _, m := range failed {
+		skews = append(skews, fmt.Sprintf("%s off by %s", m.Source, time.Duration(m.OffsetMs)*time.Millisecond))
+	}
+	limits, _ := timesync.ParseLimits(cfg.Installer.Clock)
+	return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("the local clock is skewed beyond %s: %s; synchronize it with NTP (e.g. chronyc makestep)",
+		limits.Max, strings.Join(skews, ", ")))
+}
+
+// printClockMeasurements renders one row per time reference
+func printClockMeasurements(measurements []timesync.Measurement) {
+	tableData := pterm.TableData{{"Reference", "Kind", "Offset", "Status", "Detail"}}
+	for _, m := range measurements {
+		offset := "-"
+		if m.Status != timesync.StatusUnavailable {
+			offset = fmt.Sprintf("%+dms", m.OffsetMs)
+			if m.ResolutionMs > 0 {
+				offset += fmt.Sprintf(" (±%dms)", m.ResolutionMs)
+			}
+		}
+		status := m.Status
+		switch status {
+		case timesync.StatusFailed:
+			status = pterm.Red(status)
+		case timesync.StatusWarning:
+			status = pterm.Yellow(status)
+		}
+		tableData = append(tableData, []string{m.Source, m.Kind, offset, status, m.Error})
+	}
+	pterm.DefaultSection.Println("Clock")
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/connectivity"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	preflightConnectivityConfig  string
+	preflightConnectivitySteps   []string
+	preflightConnectivityTimeout time.Duration
+)
+
+// connectivityPreflightReportName is the report preflight connectivity writes
+const connectivityPreflightReportName = "connectivity-preflight-report.json"
+
+// defaultConnectivityTimeout bounds the DNS lookup and TCP connect of each endpoint
+const defaultConnectivityTimeout = 5 * time.Second
+
+// preflightConnectivityCmd represents the preflight connectivity command
+var preflightConnectivityCmd = &cobra.Command{
+	Use:   "connectivity",
+	Short: "Verify DNS resolution and reachability of every external endpoint the run needs",
+	Long: `Collect the external hosts the configuration names: the vendor and client image
+registries, the Helm and Terraform git repositories, the client chart repository,
+the cloud provider APIs and the URLs post-validate probes. Each host is resolved
+and a TCP connection is opened to it, through the proxy HTTPS_PROXY, HTTP_PROXY and
+NO_PROXY select, and the results are printed as one connectivity matrix.
+
+Cluster-internal hosts, such as service names, are skipped. With --steps only the
+endpoints of those installation steps are checked; install runs the same check for
+the steps it is about to run before any work starts.
+
+Examples:
+  e2e-k8s-installer preflight connectivity --config installer-config.json
+  e2e-k8s-installer preflight connectivity --workspace prod --steps deploy,post-validate`,
+	RunE: withExitCode(exitcode.Preflight, runPreflightConnectivity),
+}
+
+func init() {
+	preflightCmd.AddCommand(preflightConnectivityCmd)
+
+	preflightConnectivityCmd.Flags().StringVar(&preflightConnectivityConfig, "config", "installer-config.json", "Path to the installer configuration file")
+	preflightConnectivityCmd.Flags().StringSliceVar(&preflightConnectivitySteps, "steps", []string{}, "Check only the endpoints of these installation steps (default all)")
+	preflightConnectivityCmd.Flags().DurationVar(&preflightConnectivityTimeout, "timeout", defaultConnectivityTimeout, "Timeout for resolving and connecting to each endpoint")
+	preflightConnectivityCmd.Flags().StringVarP(&preflightOutput, "output", "o", "table", "Output format (table, json)")
+}
+
+func runPreflightConnectivity(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, preflightConnectivityConfig))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+
+	results, err := checkConnectivity(cfg, preflightConnectivitySteps, preflightConnectivityTimeout, preflightOutput == "table")
+	if err != nil {
+		return err
+	}
+	switch preflightOutput {
+	case "json":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal connectivity report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+	default:
+		return fmt.Errorf("unsupported output format: %s", preflightOutput)
+	}
+	return connectivityError(results)
+}
+
+// checkConnectivity probes the endpoints of the given steps, all when none are given, saves
+// the results as the connectivity report and prints the matrix when show is set
+func checkConnectivity(cfg *config.InstallerConfig, steps []string, timeout time.Duration, show bool) ([]connectivity.Result, error) {
+	endpoints := connectivity.Endpoints(cfg)
+	if len(steps) > 0 {
+		endpoints = connectivity.ForSteps(endpoints, steps)
+	}
+	if len(endpoints) == 0 {
+		if show {
+			pterm.Info.Println("No external endpoints to check")
+		}
+		return nil, nil
+	}
+	// Simulated and replayed runs answer from inside the process, the network is never used
+	if simulateFlag || replayFlag != "" {
+		if show {
+			pterm.Info.Printf("Connectivity of %d endpoints not checked in a simulated or replayed run\n", len(endpoints))
+		}
+		return nil, nil
+	}
+
+	var spinner *pterm.SpinnerPrinter
+	if show {
+		spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Checking connectivity to %d endpoints...", len(endpoints)))
+	}
+	results := connectivity.Check(context.Background(), endpoints, timeout)
+	failed := connectivity.Failed(results)
+	if spinner != nil {
+		if len(failed) > 0 {
+			spinner.Warning(fmt.Sprintf("%d of %d endpoints unreachable", len(failed), len(results)))
+		} else {
+			spinner.Success(fmt.Sprintf("All %d endpoints reachable", len(results)))
+		}
+	}
+
+	if err := writeReport(filepath.Join(reportsDir(), connectivityPreflightReportName), map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"steps":     steps,
+		"endpoints": results,
+		"failed":    len(failed),
+	}); err != nil {
+		pterm.Warning.Printf("Failed to save the connectivity report: %v\n", err)
+	}
+	if show {
+		printConnectivityMatrix(results)
+	}
+	return results, nil
+}
+
+func connectivityError(results []connectivity.Result) error {
+	if failed := connectivity.Failed(results); len(failed) > 0 {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("%d endpoints are unreachable: %s", len(failed), strings.Join(failed, "; ")))
+	}
+	return nil
+}
+
+// printConnectivityMatrix renders one row per endpoint with its DNS and TCP results
+func printConnectivityMatrix(results []connectivity.Result) {
+	tableData := pterm.TableData{{"Endpoint", "Used By", "DNS", "TCP", "Via", "Latency", "Detail"}}
+	for _, result := range results {
+		latency := "-"
+		if result.Reachable {
+			latency = strconv.FormatInt(result.LatencyMs, 10) + "ms"
+		}
+		tableData = append(tableData, []string{
+			result.Address(),
+			strings.Join(result.Steps, ", "),
+			result.DNS,
+			passedLabel(result.Reachable),
+			valueOr(result.Proxy, "direct"),
+			latency,
+			valueOr(result.Error, strings.Join(result.Addresses, ", ")),
+		})
+	}
+	pterm.DefaultSection.Println("Connectivity")
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/gpu"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	preflightGPUConfig string
+	preflightGPUSmoke  bool
+)
+
+// gpuPreflightReportName is the report preflight gpu writes
+const gpuPreflightReportName = "gpu-preflight-report.json"
+
+// preflightGPUCmd represents the preflight gpu command
+var preflightGPUCmd = &cobra.Command{
+	Use:   "gpu",
+	Short: "Verify the cluster has the GPU capacity AI workloads need",
+	Long: `Check that enough nodes allocate GPUs, that the NVIDIA device plugin is ready on
+them, and that their driver is recent enough for kubernetes.gpu.minDriverVersion and
+for the CUDA version of each image (from its cudaVersion annotation, or the tag of
+images named after CUDA). With --smoke a Job requesting one GPU runs nvidia-smi.
+
+Examples:
+  e2e-k8s-installer preflight gpu --config installer-config.json
+  e2e-k8s-installer preflight gpu --workspace prod --smoke`,
+	RunE: withExitCode(exitcode.Preflight, runPreflightGPU),
+}
+
+func init() {
+	preflightCmd.AddCommand(preflightGPUCmd)
+
+	preflightGPUCmd.Flags().StringVar(&preflightGPUConfig, "config", "", "Path to the installer configuration file")
+	preflightGPUCmd.Flags().BoolVar(&preflightGPUSmoke, "smoke", false, "Run nvidia-smi in a Job requesting one GPU (default from kubernetes.gpu.smokeTest.enabled)")
+	preflightGPUCmd.Flags().StringVarP(&preflightOutput, "output", "o", "table", "Output format (table, json)")
+}
+
+func runPreflightGPU(cmd *cobra.Command, args []string) error {
+	k8sConfig := config.K8sConfig{Namespace: preflightNamespace}
+	var images []config.ImageReference
+	dir := selectedWorkspace("./workspace")
+	if configFile := workspaceConfigFile(cmd, preflightGPUConfig); configFile != "" {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+		}
+		dir = selectedWorkspace(cfg.Installer.Workspace)
+		k8sConfig = cfg.Kubernetes
+		images = cfg.Artifacts.Images.Images
+		if cmd.Flags().Changed("namespace") || k8sConfig.Namespace == "" {
+			k8sConfig.Namespace = preflightNamespace
+		}
+	}
+	if preflightKubeconfig != "" {
+		k8sConfig.ConfigPath = preflightKubeconfig
+	}
+	if preflightContext != "" {
+		k8sConfig.Context = preflightContext
+	}
+	if err := initWorkspaceParameters(dir); err != nil {
+		return err
+	}
+	smoke := preflightGPUSmoke || (!cmd.Flags().Changed("smoke") && k8sConfig.GPU.SmokeTest.Enabled)
+
+	k8sMgr, err := k8s.NewManager(&k8sConfig)
+	if err != nil {
+		return err
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Checking GPU nodes...")
+	report, err := gpu.Run(k8sMgr, k8sConfig.GPU, images, k8sConfig.Namespace, smoke)
+	if err != nil {
+		spinner.Fail("GPU checks failed")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Checked %d GPU nodes", len(report.Nodes)))
+
+	if err := writeReport(filepath.Join(reportsDir(), gpuPreflightReportName), map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"nodes":     report.Nodes,
+		"checks":    report.Checks,
+		"passed":    report.Passed(),
+	}); err != nil {
+		pterm.Warning.Printf("Failed to save the GPU report: %v\n", err)
+	}
+
+	switch preflightOutput {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal GPU report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		pterm.DefaultSection.Println("GPU Preflight")
+		printGPUReport(report)
+	default:
+		return fmt.Errorf("unsupported output format: %s", preflightOutput)
+	}
+
+	if !report.Passed() {
+		return fmt.Errorf("GPU checks failed: %s", strings.Join(report.Failed(), "; "))
+	}
+	return nil
+}
+
+// printGPUReport renders the GPU nodes and the outcome of each check
+func printGPUReport(report *gpu.Report) {
+	if len(report.Nodes) > 0 {
+		nodeData := pterm.TableData{{"Node", "GPUs", "Product", "Driver", "CUDA"}}
+		for _, node := range report.Nodes {
+			nodeData = append(nodeData, []string{
+				node.Name, fmt.Sprintf("%d", node.GPUs), valueOr(node.Product, "-"), valueOr(node.Driver, "-"), valueOr(node.CUDA, "-"),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(nodeData).Render()
+	}
+
+	checkData := pterm.TableData{{"Check", "Result", "Detail"}}
+	for _, check := range report.Checks {
+		result := passedLabel(check.Passed)
+		if check.Warning {
+			result = "⚠️ unverified"
+		}
+		checkData = append(checkData, []string{check.Name, result, check.Detail})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(checkData).Render()
+}
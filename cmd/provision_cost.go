@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/infrastructure"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// costResourcesShown is how many of the most expensive resources the plan summary lists
+const costResourcesShown = 10
+
+// estimateInfraCost prints the projected monthly cost of the plan and checks it against the
+// budget. Exceeding the budget, or being unable to estimate while a budget is set, needs
+// --approve-cost or an interactive confirmation unless only planning.
+func estimateInfraCost(infraManager *infrastructure.Manager) (*terraform.CostEstimate, error) {
+	tfManager := infraManager.GetTerraformManager()
+	if tfManager == nil || !tfManager.CostEstimationEnabled() {
+		return nil, nil
+	}
+	budget := tfManager.Budget()
+
+	estimate, err := tfManager.EstimateCost()
+	if err != nil {
+		if budget <= 0 {
+			logger.Warn("Cost estimation skipped").Err(err).Send()
+			return nil, nil
+		}
+		pterm.Warning.Printf("Cost estimation failed, the %.2f monthly budget cannot be checked: %v\n", budget, err)
+		return nil, approveCost("Apply without a cost estimate?")
+	}
+
+	printCostEstimate(estimate)
+
+	if !tfManager.OverBudget(estimate) {
+		return estimate, nil
+	}
+	pterm.Warning.Printf("Monthly cost increase of %.2f %s exceeds the budget of %.2f\n", estimate.Delta, estimate.Currency, budget)
+	return estimate, approveCost("Apply despite exceeding the budget?")
+}
+
+func printCostEstimate(estimate *terraform.CostEstimate) {
+	pterm.DefaultSection.Println("Projected monthly cost")
+
+	tableData := pterm.TableData{{"Before", "After", "Change"}}
+	tableData = append(tableData, []string{
+		fmt.Sprintf("%.2f %s", estimate.PastMonthly, estimate.Currency),
+		fmt.Sprintf("%.2f %s", estimate.Monthly, estimate.Currency),
+		fmt.Sprintf("%+.2f %s", estimate.Delta, estimate.Currency),
+	})
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if len(estimate.Resources) == 0 {
+		return
+	}
+	resources := pterm.TableData{{"Resource", "Monthly"}}
+	for _, resource := range estimate.Resources[:min(costResourcesShown, len(estimate.Resources))] {
+		resources = append(resources, []string{resource.Name, fmt.Sprintf("%.2f %s", resource.Monthly, estimate.Currency)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(resources).Render()
+}
+
+// approveCost asks the operator to accept the cost, unless --approve-cost was given or only planning
+func approveCost(question string) error {
+	if provisionApproveCost || provisionPlanOnly {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("cost approval required: re-run with --approve-cost to accept")
+	}
+
+	approved, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(false).Show(question)
+	if err != nil {
+		return fmt.Errorf("failed to read cost approval: %w", err)
+	}
+	if !approved {
+		return fmt.Errorf("apply cancelled: cost not approved")
+	}
+	return nil
+}
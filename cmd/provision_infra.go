@@ -5,12 +5,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/ansible"
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/healthcheck"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
 	"github.com/judebantony/e2e-k8s-installer/pkg/infrastructure"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/license"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/maintenance"
+	"github.com/judebantony/e2e-k8s-installer/pkg/makefile"
+	"github.com/judebantony/e2e-k8s-installer/pkg/managed"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
 	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/residency"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,6 +41,11 @@ var provisionInfraCmd = &cobra.Command{
 This includes Kubernetes clusters, managed databases, storage, and networking 
 components based on your configuration.
 
+Databases and caches declared under infrastructure.managedServices are created
+directly with the aws, gcloud or az CLI after Terraform, or on their own with
+provisionMode "managed". Their endpoints are published as parameters for
+db-migrate and deploy.
+
 This command will:
 1. Initialize Terraform backend
 2. Plan infrastructure changes
@@ -30,10 +53,18 @@ This command will:
 4. Run embedded health checks
 5. Generate infrastructure report
 
+Each apply records which Terraform modules succeeded or failed in the workspace
+installation state. --resume re-applies only the modules that failed or were never
+applied, and --target passes resource addresses through to terraform -target.
+The state is snapshotted into the workspace before every apply and destroy; see
+'provision-infra state' for snapshots and importing existing resources.
+
 Example:
   k8s-installer provision-infra --config installer-config.json
-  k8s-installer provision-infra --config config.json --plan-only`,
-	RunE: runProvisionInfra,
+  k8s-installer provision-infra --config config.json --plan-only
+  k8s-installer provision-infra --target module.database
+  k8s-installer provision-infra --resume`,
+	RunE: withExitCode(exitcode.Deploy, runProvisionInfra),
 }
 
 var (
@@ -42,14 +73,20 @@ var (
 	provisionDestroy     bool
 	provisionAutoApprove bool
 	provisionVarsFile    string
+	provisionTargets     []string
+	provisionResume      bool
+	provisionApproveCost bool
 )
 
 func init() {
-	provisionInfraCmd.Flags().StringVarP(&provisionConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	provisionInfraCmd.PersistentFlags().StringVarP(&provisionConfigFile, "config", "c", "installer-config.json", "Configuration file path")
 	provisionInfraCmd.Flags().BoolVar(&provisionPlanOnly, "plan-only", false, "Only show the Terraform plan without applying")
 	provisionInfraCmd.Flags().BoolVar(&provisionDestroy, "destroy", false, "Destroy infrastructure instead of creating")
 	provisionInfraCmd.Flags().BoolVar(&provisionAutoApprove, "auto-approve", false, "Skip interactive approval of plan")
 	provisionInfraCmd.Flags().StringVar(&provisionVarsFile, "vars-file", "", "Additional Terraform variables file")
+	provisionInfraCmd.Flags().StringArrayVar(&provisionTargets, "target", []string{}, "Limit plan and apply to a resource address, e.g. module.database (repeatable)")
+	provisionInfraCmd.Flags().BoolVar(&provisionApproveCost, "approve-cost", false, "Accept a projected cost increase above the configured budget")
+	provisionInfraCmd.Flags().BoolVar(&provisionResume, "resume", false, "Re-apply only the Terraform modules that failed or were never applied")
 }
 
 func runProvisionInfra(cmd *cobra.Command, args []string) error {
@@ -58,7 +95,8 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	pm := progress.GetProgressManager()
 
 	// Show banner
-	progress.ShowBanner("1.0.0")
+	applyBranding(workspaceConfigFile(cmd, provisionConfigFile))
+	progress.ShowBanner(version.Version)
 
 	// Start overall progress area
 	pm.StartArea("provision-infra")
@@ -103,12 +141,12 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	var cfg *config.InstallerConfig
 	var err error
 
-	if provisionConfigFile != "" {
-		cfg, err = config.LoadConfig(provisionConfigFile)
+	if configFile := workspaceConfigFile(cmd, provisionConfigFile); configFile != "" {
+		cfg, err = config.LoadConfig(configFile)
 		if err != nil {
 			pm.FailSpinner("config", "Failed to load configuration file")
 			logger.StepFailed("load-config", err)
-			return fmt.Errorf("failed to load configuration file: %w", err)
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration file: %w", err))
 		}
 	} else {
 		cfg = config.GenerateDefaultConfig()
@@ -120,6 +158,71 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("configuration validation failed: %w", err)
 	}
 
+	if err := applyWorkspace(cfg); err != nil {
+		pm.FailSpinner("config", "Workspace not found")
+		logger.StepFailed("load-config", err)
+		return err
+	}
+	if interval, err := time.ParseDuration(cfg.Installer.Progress.RefreshInterval); err == nil {
+		pm.SetRefreshInterval(interval)
+	}
+
+	residencyPolicy := residency.NewPolicy(cfg.Security)
+	if residencyPolicy != nil {
+		if err := residencyError(residencyPolicy.CheckConfig(cfg), "the configuration"); err != nil {
+			pm.FailSpinner("config", "Data residency policy violated")
+			logger.StepFailed("load-config", err)
+			return err
+		}
+	}
+
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		pm.FailSpinner("config", "Failed to initialize audit log")
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+
+	if err := requireLicense(cfg, license.ModuleProvisionInfra); err != nil {
+		pm.FailSpinner("config", "License check failed")
+		return err
+	}
+
+	lock, err := workspace.Acquire(cfg.Installer.Workspace, "provision-infra")
+	if err != nil {
+		pm.FailSpinner("config", "Workspace is in use")
+		return err
+	}
+	defer lock.Release()
+
+	// Plans change nothing, so only applies and destroys wait for a maintenance window
+	if !provisionPlanOnly && !viper.GetBool("dry-run") {
+		if err := awaitMaintenanceWindow(cfg, maintenance.PhaseProvisionInfra); err != nil {
+			pm.FailSpinner("config", "Outside maintenance window")
+			return err
+		}
+	}
+
+	// Cloud resources are tagged with the label policy through Terraform
+	if err := labels.InitGlobalPolicy(cfg.Labels, history.RunID()); err != nil {
+		pm.FailSpinner("config", "Invalid label policy")
+		return fmt.Errorf("invalid label policy: %w", err)
+	}
+
+	// Share outputs with later steps through the workspace installation state
+	if err := params.InitGlobalStore(filepath.Join(cfg.Installer.Workspace, "install-state.json"), true); err != nil {
+		pm.FailSpinner("config", "Failed to load installation parameters")
+		return fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+
+	// Secret Terraform variables and managed database passwords are read from credentials
+	// published to the parameter store
+	needsCredentials := len(cfg.Infrastructure.Terraform.SecretVars) > 0 || len(cfg.Infrastructure.ManagedServices.Databases) > 0
+	if needsCredentials && len(cfg.Security.Credentials.Items) > 0 && !viper.GetBool("dry-run") {
+		if _, err := publishCredentials(cfg, cfg.Installer.Workspace); err != nil {
+			pm.FailSpinner("config", "Failed to prepare credentials")
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+
 	pm.SuccessSpinner("config", "Configuration loaded and validated")
 	logger.StepComplete("load-config", 0)
 	currentStep++
@@ -140,12 +243,61 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 		Str("mode", infraManager.GetProvisionMode()).
 		Send()
 
+	if tfManager := infraManager.GetTerraformManager(); tfManager != nil {
+		tfManager.SetStateBackupDir(terraformBackupDir(cfg.Installer.Workspace))
+		if residencyPolicy != nil && residencyPolicy.RestrictsRegions() {
+			tfManager.SavePlan()
+		}
+	}
+	if declarativeManager := infraManager.GetDeclarativeManager(); declarativeManager != nil {
+		declarativeManager.SetKubeconfigDir(filepath.Join(cfg.Installer.Workspace, ".kube"))
+	}
+
+	stateFile := filepath.Join(cfg.Installer.Workspace, workspace.StateFileName)
+	targets, err := provisionTargetList(cfg, stateFile)
+	if err != nil {
+		pm.FailSpinner("init", "Invalid targets")
+		return err
+	}
+	if provisionResume && len(targets) == 0 {
+		pm.SuccessSpinner("init", "Every Terraform module is applied")
+		pm.StopArea("provision-infra")
+		progress.ShowSuccess("🎉 Nothing to resume, all modules were applied")
+		return nil
+	}
+	if len(targets) > 0 {
+		tfManager := infraManager.GetTerraformManager()
+		if tfManager == nil {
+			pm.FailSpinner("init", "Targets require Terraform")
+			return fmt.Errorf("--target and --resume require terraform or hybrid provision mode")
+		}
+		if err := tfManager.SetTargets(targets); err != nil {
+			pm.FailSpinner("init", "Invalid targets")
+			return err
+		}
+		pterm.Info.Printf("Targeting %s\n", strings.Join(targets, ", "))
+	}
+
 	if err := infraManager.Init(viper.GetBool("dry-run")); err != nil {
 		pm.FailSpinner("init", "Infrastructure initialization failed")
 		logger.StepFailed("infra-init", err)
 		return fmt.Errorf("infrastructure initialization failed: %w", err)
 	}
 
+	managedServices, err := newManagedServices(cfg)
+	if err != nil {
+		pm.FailSpinner("init", "Invalid managed services")
+		logger.StepFailed("infra-init", err)
+		return err
+	}
+	if managedServices != nil && !viper.GetBool("dry-run") {
+		if err := managedServices.CheckTools(); err != nil {
+			pm.FailSpinner("init", "Cloud CLI not found")
+			logger.StepFailed("infra-init", err)
+			return err
+		}
+	}
+
 	pm.SuccessSpinner("init", "Infrastructure initialized successfully")
 	logger.StepComplete("infra-init", 0)
 	currentStep++
@@ -161,6 +313,14 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("infrastructure planning failed: %w", err)
 	}
 
+	if !provisionDestroy && !viper.GetBool("dry-run") {
+		if err := checkPlanResidency(residencyPolicy, infraManager); err != nil {
+			pm.FailSpinner("plan", "Data residency policy violated")
+			logger.StepFailed("infra-plan", err)
+			return err
+		}
+	}
+
 	pm.SuccessSpinner("plan", "Infrastructure plan completed")
 	logger.StepComplete("infra-plan", 0)
 	currentStep++
@@ -169,6 +329,31 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	// Show plan information
 	fmt.Printf("\n📋 Infrastructure Plan (%s mode):\n", infraManager.GetProvisionMode())
 	fmt.Println("Plan completed successfully - review the output above for details")
+	if makeManager := infraManager.GetMakefileManager(); makeManager != nil {
+		printMakefileDryRuns(makeManager.DryRuns())
+	}
+	if ansibleManager := infraManager.GetAnsibleManager(); ansibleManager != nil {
+		if err := printAnsibleRecaps(ansibleManager.Results()); err != nil {
+			return err
+		}
+	}
+
+	if managedServices != nil && !viper.GetBool("dry-run") {
+		changes, err := managedServices.Plan(provisionDestroy)
+		if err != nil {
+			return fmt.Errorf("managed services planning failed: %w", err)
+		}
+		if err := printManagedPlan(changes); err != nil {
+			return err
+		}
+	}
+
+	var costEstimate *terraform.CostEstimate
+	if !provisionDestroy && !viper.GetBool("dry-run") {
+		if costEstimate, err = estimateInfraCost(infraManager); err != nil {
+			return err
+		}
+	}
 
 	// If plan-only, stop here
 	if provisionPlanOnly {
@@ -198,26 +383,89 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	pm.StartSpinner("apply", action)
 	logger.StepStart("infra-apply")
 
+	var managedEndpoints []managed.Endpoint
+	var appliedPlaybooks []ansible.PlaybookResult
+
 	if viper.GetBool("dry-run") {
+		// Make targets echo their commands, every other mode only logs the skip
+		makeManager := infraManager.GetMakefileManager()
+		planned := 0
+		if makeManager != nil {
+			planned = len(makeManager.DryRuns())
+		}
+		var dryRunErr error
+		if provisionDestroy {
+			dryRunErr = infraManager.Destroy(true)
+		} else {
+			dryRunErr = infraManager.Apply(true)
+		}
+		if dryRunErr != nil {
+			pm.FailSpinner("apply", "Dry run failed")
+			logger.StepFailed("infra-apply", dryRunErr)
+			return fmt.Errorf("infrastructure dry run failed: %w", dryRunErr)
+		}
 		pm.SuccessSpinner("apply", "Dry run: Infrastructure changes would be applied")
+		if makeManager != nil {
+			printMakefileDryRuns(makeManager.DryRuns()[planned:])
+		}
 		logger.StepComplete("infra-apply", 0)
 	} else {
+		stopTracking := trackTerraformApply(pm, infraManager, action, provisionDestroy)
+		defer stopTracking()
+
 		if provisionDestroy {
+			// Managed services may live in networks Terraform created, so they go first
+			if managedServices != nil {
+				if err := managedServices.Destroy(); err != nil {
+					pm.FailSpinner("apply", "Managed services destruction failed")
+					logger.StepFailed("infra-apply", err)
+					return fmt.Errorf("managed services destruction failed: %w", err)
+				}
+			}
 			if err := infraManager.Destroy(false); err != nil {
 				pm.FailSpinner("apply", "Infrastructure destruction failed")
 				logger.StepFailed("infra-apply", err)
 				return fmt.Errorf("infrastructure destruction failed: %w", err)
 			}
 		} else {
-			if err := infraManager.Apply(false); err != nil {
+			ansibleManager := infraManager.GetAnsibleManager()
+			checked := 0
+			if ansibleManager != nil {
+				checked = len(ansibleManager.Results())
+			}
+			err := infraManager.Apply(false)
+			if ansibleManager != nil {
+				appliedPlaybooks = ansibleManager.Results()[checked:]
+			}
+			if tfManager := infraManager.GetTerraformManager(); tfManager != nil && len(tfManager.ModuleStates()) > 0 {
+				if saveErr := terraform.SaveModuleStates(stateFile, tfManager.ModuleStates()); saveErr != nil {
+					logger.Warn("Failed to record Terraform module states").Err(saveErr).Send()
+				}
+			}
+			if err != nil {
 				pm.FailSpinner("apply", "Infrastructure application failed")
 				logger.StepFailed("infra-apply", err)
+				printAnsibleRecaps(appliedPlaybooks)
+				if failed := failedModules(infraManager); len(failed) > 0 {
+					pterm.Info.Printf("Failed modules: %s. Re-run with --resume to apply only these\n", strings.Join(failed, ", "))
+				}
 				return fmt.Errorf("infrastructure application failed: %w", err)
 			}
+
+			if managedServices != nil {
+				if managedEndpoints, err = applyManagedServices(managedServices); err != nil {
+					pm.FailSpinner("apply", "Managed services provisioning failed")
+					logger.StepFailed("infra-apply", err)
+					return fmt.Errorf("managed services provisioning failed: %w", err)
+				}
+			}
 		}
 
 		pm.SuccessSpinner("apply", "Infrastructure operation completed")
 		logger.StepComplete("infra-apply", 0)
+		if err := printAnsibleRecaps(appliedPlaybooks); err != nil {
+			return err
+		}
 	}
 
 	currentStep++
@@ -229,7 +477,7 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 		pm.StartSpinner("report", "Generating destruction report...")
 		logger.StepStart("generate-report")
 
-		reportPath, err := generateInfraReport(cfg, infraManager, true)
+		reportPath, err := generateInfraReport(cfg, infraManager, true, nil, nil)
 		if err != nil {
 			pm.FailSpinner("report", "Report generation failed")
 			logger.StepFailed("generate-report", err)
@@ -268,6 +516,11 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 			pm.SuccessSpinner("health", "Health checks passed")
 			logger.StepComplete("health-checks", 0)
 		}
+		if tfMgr := infraManager.GetTerraformManager(); tfMgr != nil {
+			if err := printHealthResults(tfMgr.HealthResults()); err != nil {
+				return err
+			}
+		}
 	}
 
 	currentStep++
@@ -277,7 +530,7 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	pm.StartSpinner("report", "Generating infrastructure report...")
 	logger.StepStart("generate-report")
 
-	reportPath, err := generateInfraReport(cfg, infraManager, false)
+	reportPath, err := generateInfraReport(cfg, infraManager, false, costEstimate, managedEndpoints)
 	if err != nil {
 		pm.FailSpinner("report", "Report generation failed")
 		logger.StepFailed("generate-report", err)
@@ -298,6 +551,16 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	progress.ShowSuccess("🎉 Infrastructure provisioning completed!")
 	fmt.Printf("📄 Report: %s\n", reportPath)
 
+	if localMgr := infraManager.GetLocalClusterManager(); localMgr != nil {
+		fmt.Printf("\n🧪 Local %s cluster %s is the current kubectl context (%s)\n", localMgr.Tool(), localMgr.Name(), localMgr.Context())
+		if registry := localMgr.Registry(); registry != "" {
+			fmt.Printf("   Push images to %s; the cluster pulls them from the same address\n", registry)
+		}
+	}
+	if declarativeMgr := infraManager.GetDeclarativeManager(); declarativeMgr != nil && !viper.GetBool("dry-run") {
+		fmt.Printf("\n☸️  %s cluster %s is ready, later steps use its kubeconfig %s\n", declarativeMgr.Engine(), declarativeMgr.ClusterName(), declarativeMgr.KubeconfigPath())
+	}
+
 	// Show next steps
 	fmt.Println("\n📝 Next steps:")
 	fmt.Println("   1. Run 'k8s-installer db-migrate' to initialize databases")
@@ -306,7 +569,118 @@ func runProvisionInfra(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manager, isDestroy bool) (string, error) {
+// printMakefileDryRuns lists the commands each make target would run, as 'make -n' reported them
+// trackTerraformApply shows the resources Terraform completed, its rate and ETA on the apply
+// spinner until the returned function is called
+func trackTerraformApply(pm *progress.ProgressManager, infraManager *infrastructure.Manager, action string, destroy bool) func() {
+	tfManager := infraManager.GetTerraformManager()
+	if tfManager == nil {
+		return func() {}
+	}
+	tracker := pm.TrackRate("apply", action, "resources", tfManager.PlannedChanges(destroy))
+	tfManager.OnApplyProgress(func(done, total int) {
+		tracker.Set(done)
+	})
+	return func() {
+		tfManager.OnApplyProgress(nil)
+		tracker.Stop()
+	}
+}
+
+func printMakefileDryRuns(dryRuns []makefile.DryRun) {
+	for _, dryRun := range dryRuns {
+		fmt.Printf("\n  make %s:\n", dryRun.Target)
+		if len(dryRun.Commands) == 0 {
+			fmt.Println("    (nothing to be done)")
+		}
+		for _, command := range dryRun.Commands {
+			fmt.Printf("    %s\n", command)
+		}
+	}
+}
+
+// printAnsibleRecaps shows the play recap of each playbook run, one row per host
+func printAnsibleRecaps(results []ansible.PlaybookResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	fmt.Println("\n📒 Ansible play recap:")
+	tableData := pterm.TableData{{"Playbook", "Mode", "Host", "OK", "Changed", "Unreachable", "Failed", "Skipped"}}
+	for _, result := range results {
+		mode := result.Operation
+		if result.Check {
+			mode += " (check)"
+		}
+		if len(result.Hosts) == 0 {
+			tableData = append(tableData, []string{result.Playbook, mode, "-", "-", "-", "-", "-", "-"})
+		}
+		for _, host := range result.Hosts {
+			tableData = append(tableData, []string{
+				result.Playbook, mode, host.Host,
+				fmt.Sprintf("%d", host.Ok), fmt.Sprintf("%d", host.Changed), fmt.Sprintf("%d", host.Unreachable),
+				fmt.Sprintf("%d", host.Failed), fmt.Sprintf("%d", host.Skipped),
+			})
+		}
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// printHealthResults renders one row per infrastructure health check
+func printHealthResults(results []healthcheck.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	fmt.Println("\n🩺 Infrastructure health checks:")
+	tableData := pterm.TableData{{"Check", "Target", "Status", "Attempts", "Duration", "Detail"}}
+	for _, result := range results {
+		status, detail := "✅ passed", result.Detail
+		if !result.Passed {
+			status, detail = "❌ failed", result.Error
+		}
+		tableData = append(tableData, []string{
+			result.Name, result.Target, status, fmt.Sprintf("%d", result.Attempts), result.Duration, detail,
+		})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// provisionTargetList combines --target with the modules a --resume must re-apply
+func provisionTargetList(cfg *config.InstallerConfig, stateFile string) ([]string, error) {
+	targets := append([]string{}, provisionTargets...)
+	if !provisionResume {
+		return targets, nil
+	}
+	if provisionDestroy {
+		return nil, fmt.Errorf("--resume cannot be combined with --destroy")
+	}
+
+	states, err := terraform.LoadModuleStates(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range terraform.PendingModules(cfg.Infrastructure.Terraform.Modules, states) {
+		targets = append(targets, terraform.ModuleTarget(module))
+	}
+	return targets, nil
+}
+
+// failedModules lists the modules the last apply recorded as failed
+func failedModules(infraManager *infrastructure.Manager) []string {
+	tfManager := infraManager.GetTerraformManager()
+	if tfManager == nil {
+		return nil
+	}
+	var failed []string
+	for module, state := range tfManager.ModuleStates() {
+		if state.Status == terraform.ModuleFailed {
+			failed = append(failed, module)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manager, isDestroy bool, cost *terraform.CostEstimate, services []managed.Endpoint) (string, error) {
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
 	reportDir := filepath.Join(cfg.Installer.Workspace, "reports")
 
@@ -341,11 +715,21 @@ func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manage
 		} else {
 			outputs = make(map[string]interface{})
 		}
+	} else if localMgr := infraManager.GetLocalClusterManager(); localMgr != nil {
+		outputs = localMgr.Outputs()
+	} else if declarativeMgr := infraManager.GetDeclarativeManager(); declarativeMgr != nil && !isDestroy {
+		outputs = declarativeMgr.Outputs()
 	} else {
-		// For makefile mode, we don't have structured outputs
+		// For makefile and ansible modes, we don't have structured outputs
 		outputs = make(map[string]interface{})
 	}
 
+	if !isDestroy {
+		if err := publishInfraOutputs(outputs); err != nil {
+			logger.Warn("Failed to publish infrastructure outputs as parameters").Err(err).Send()
+		}
+	}
+
 	// Create report
 	report := map[string]interface{}{
 		"timestamp": timestamp,
@@ -361,11 +745,37 @@ func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manage
 			"mode":             infraManager.GetProvisionMode(),
 			"terraformEnabled": infraInfo.TerraformEnabled,
 			"makefileEnabled":  infraInfo.MakefileEnabled,
+			"ansibleEnabled":   infraInfo.AnsibleEnabled,
 			"healthCheck":      infraInfo.HealthCheckConfig,
 		},
 		"outputs": outputs,
 		"status":  "completed",
 	}
+	if cost != nil {
+		report["cost"] = cost
+	}
+	if info := license.ReportInfo(); info != nil {
+		report["license"] = info
+	}
+	if len(services) > 0 {
+		report["managedServices"] = services
+	}
+	if len(infraInfo.HybridOrder) > 0 {
+		report["infrastructure"].(map[string]interface{})["hybridOrder"] = infraInfo.HybridOrder
+	}
+	if ansibleMgr := infraManager.GetAnsibleManager(); ansibleMgr != nil && len(ansibleMgr.Results()) > 0 {
+		report["ansible"] = ansibleMgr.Results()
+	}
+	if tfMgr := infraManager.GetTerraformManager(); tfMgr != nil && len(tfMgr.HealthResults()) > 0 {
+		report["healthChecks"] = tfMgr.HealthResults()
+	}
+	if tfMgr := infraManager.GetTerraformManager(); tfMgr != nil {
+		infra := report["infrastructure"].(map[string]interface{})
+		infra["flavor"] = tfMgr.Flavor()
+		if modules := tfMgr.ModuleStates(); len(modules) > 0 {
+			infra["modules"] = modules
+		}
+	}
 
 	// Convert to JSON
 	jsonData, err := json.MarshalIndent(report, "", "  ")
@@ -377,6 +787,7 @@ func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manage
 	if err := os.WriteFile(reportPath, jsonData, 0644); err != nil {
 		return "", fmt.Errorf("failed to write report: %w", err)
 	}
+	history.AddReport(reportPath)
 
 	// Create symlink to latest
 	latestPath := filepath.Join(reportDir, "infra-report-latest.json")
@@ -395,3 +806,47 @@ func generateInfraReport(cfg *config.Config, infraManager *infrastructure.Manage
 
 	return reportPath, nil
 }
+
+// infraOutputParams maps conventional terraform output names to well-known parameters
+var infraOutputParams = map[string]string{
+	"database_endpoint": params.KeyDatabaseHost,
+	"database_host":     params.KeyDatabaseHost,
+	"database_port":     params.KeyDatabasePort,
+	"database_name":     params.KeyDatabaseName,
+	"database_username": params.KeyDatabaseUsername,
+	"database_password": params.KeyDatabasePassword,
+	"database_instance": params.KeyDatabaseInstance,
+	"cache_host":        params.KeyCacheHost,
+	"cache_port":        params.KeyCachePort,
+	"kubeconfig_path":   params.KeyKubeconfig,
+}
+
+// publishInfraOutputs stores terraform outputs in the shared parameter store for later steps
+func publishInfraOutputs(outputs map[string]interface{}) error {
+	store := params.GetStore()
+
+	for name, output := range outputs {
+		value := output
+		sensitive := false
+		if wrapped, ok := output.(map[string]interface{}); ok {
+			if v, ok := wrapped["value"]; ok {
+				value = v
+			}
+			sensitive, _ = wrapped["sensitive"].(bool)
+		}
+
+		keys := []string{"outputs." + name}
+		if key, ok := infraOutputParams[name]; ok {
+			keys = append(keys, key)
+		}
+		for _, key := range keys {
+			if sensitive {
+				store.SetSensitive(key, value, "provision-infra")
+			} else {
+				store.Set(key, value, "provision-infra")
+			}
+		}
+	}
+
+	return store.Persist()
+}
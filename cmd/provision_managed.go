@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/managed"
+	"github.com/pterm/pterm"
+)
+
+// newManagedServices returns the manager for the configured managed services, nil when none are declared
+func newManagedServices(cfg *config.InstallerConfig) (*managed.Manager, error) {
+	if !managed.Configured(cfg.Infrastructure.ManagedServices) {
+		return nil, nil
+	}
+	mgr, err := managed.NewManager(cfg.Infrastructure.ManagedServices, cfg.Cloud)
+	if err != nil {
+		return nil, fmt.Errorf("invalid managed services: %w", err)
+	}
+	return mgr, nil
+}
+
+// printManagedPlan shows what apply or destroy would do to each managed service
+func printManagedPlan(changes []managed.PlannedChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	fmt.Println("\n🗄️  Managed services:")
+	tableData := pterm.TableData{{"Service", "Kind", "Action", "Status"}}
+	for _, change := range changes {
+		status := change.Status
+		if status == "" {
+			status = "-"
+		}
+		tableData = append(tableData, []string{change.Name, change.Kind, change.Action, status})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// applyManagedServices creates the managed services and publishes their endpoints as parameters
+func applyManagedServices(mgr *managed.Manager) ([]managed.Endpoint, error) {
+	endpoints, err := mgr.Apply()
+	if err != nil {
+		return endpoints, err
+	}
+	if err := publishInfraOutputs(mgr.Outputs(endpoints)); err != nil {
+		return endpoints, fmt.Errorf("failed to publish managed service endpoints: %w", err)
+	}
+
+	tableData := pterm.TableData{{"Service", "Kind", "Engine", "Endpoint"}}
+	for _, endpoint := range endpoints {
+		tableData = append(tableData, []string{endpoint.Name, endpoint.Kind, endpoint.Engine, endpoint.Host + ":" + strconv.Itoa(endpoint.Port)})
+	}
+	fmt.Println()
+	return endpoints, pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
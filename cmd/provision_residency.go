@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/infrastructure"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/residency"
+	"github.com/pterm/pterm"
+)
+
+// checkPlanResidency fails when the Terraform plan creates or updates resources outside
+// security.allowedRegions. Modes without a saved Terraform plan rely on the configuration check.
+func checkPlanResidency(policy *residency.Policy, infraManager *infrastructure.Manager) error {
+	tfManager := infraManager.GetTerraformManager()
+	if policy == nil || !policy.RestrictsRegions() || tfManager == nil {
+		return nil
+	}
+
+	planJSON, err := tfManager.PlanJSON()
+	if err != nil {
+		logger.Warn("Residency check of the Terraform plan skipped").Err(err).Send()
+		return nil
+	}
+	violations, err := policy.CheckPlan(planJSON)
+	if err != nil {
+		return err
+	}
+	return residencyError(violations, "the Terraform plan")
+}
+
+// residencyError prints the violations and returns the error that stops planning
+func residencyError(violations []residency.Violation, subject string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	pterm.DefaultSection.Println("Data residency violations")
+	tableData := pterm.TableData{{"Source", "Value", "Reason"}}
+	for _, violation := range violations {
+		tableData = append(tableData, []string{violation.Source, violation.Value, violation.Reason})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("%s references %d disallowed regions or domains (security.allowedRegions, security.allowedDomains)", subject, len(violations)))
+}
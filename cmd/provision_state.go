@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// provisionStateCmd groups Terraform state helpers under provision-infra
+var provisionStateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Back up and adopt resources into the managed Terraform state",
+	Long: `The state is snapshotted into the workspace (state/terraform-backups) before every
+apply, destroy and import, pulled from the configured backend whether local or remote.
+The newest infrastructure.terraform.stateBackups snapshots are kept (10 by default).
+
+Examples:
+  e2e-k8s-installer provision-infra state import module.database.aws_db_instance.main prod-db
+  e2e-k8s-installer provision-infra state backups`,
+}
+
+var provisionStateImportCmd = &cobra.Command{
+	Use:   "import ADDR ID",
+	Short: "Adopt an existing cloud resource into the managed state",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runProvisionStateImport,
+}
+
+var provisionStateBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List state snapshots, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runProvisionStateBackups,
+}
+
+func init() {
+	provisionStateCmd.AddCommand(provisionStateImportCmd)
+	provisionStateCmd.AddCommand(provisionStateBackupsCmd)
+	provisionInfraCmd.AddCommand(provisionStateCmd)
+}
+
+// terraformBackupDir is where state snapshots of a workspace are kept
+func terraformBackupDir(workspaceDir string) string {
+	return filepath.Join(workspaceDir, "state", "terraform-backups")
+}
+
+func runProvisionStateImport(cmd *cobra.Command, args []string) error {
+	cfg, err := loadProvisionStateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+	lock, err := workspace.Acquire(cfg.Installer.Workspace, "provision-infra state import")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := labels.InitGlobalPolicy(cfg.Labels, history.RunID()); err != nil {
+		return fmt.Errorf("invalid label policy: %w", err)
+	}
+	if err := params.InitGlobalStore(filepath.Join(cfg.Installer.Workspace, workspace.StateFileName), true); err != nil {
+		return fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+	if len(cfg.Infrastructure.Terraform.SecretVars) > 0 && len(cfg.Security.Credentials.Items) > 0 {
+		if _, err := publishCredentials(cfg, cfg.Installer.Workspace); err != nil {
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+
+	tfManager, err := terraform.NewManager(&cfg.Infrastructure)
+	if err != nil {
+		return fmt.Errorf("failed to create terraform manager: %w", err)
+	}
+	tfManager.SetStateBackupDir(terraformBackupDir(cfg.Installer.Workspace))
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Importing %s into %s...", args[1], args[0]))
+	if err := tfManager.Init(); err != nil {
+		spinner.Fail("Terraform initialization failed")
+		return err
+	}
+	if err := tfManager.Import(args[0], args[1]); err != nil {
+		spinner.Fail("Import failed")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Imported %s as %s", args[1], args[0]))
+	pterm.Info.Println("Run 'provision-infra --plan-only' to check the configuration matches the imported resource")
+	return nil
+}
+
+func runProvisionStateBackups(cmd *cobra.Command, args []string) error {
+	cfg, err := loadProvisionStateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	tfManager, err := terraform.NewManager(&cfg.Infrastructure)
+	if err != nil {
+		return fmt.Errorf("failed to create terraform manager: %w", err)
+	}
+	tfManager.SetStateBackupDir(terraformBackupDir(cfg.Installer.Workspace))
+
+	backups, err := tfManager.StateBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		pterm.Info.Printf("No state snapshots in %s\n", terraformBackupDir(cfg.Installer.Workspace))
+		return nil
+	}
+
+	tableData := pterm.TableData{{"Snapshot", "Size", "Taken"}}
+	for _, backup := range backups {
+		stat, err := os.Stat(backup)
+		if err != nil {
+			continue
+		}
+		tableData = append(tableData, []string{backup, fmt.Sprintf("%d B", stat.Size()), stat.ModTime().Format(time.RFC3339)})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// loadProvisionStateConfig loads and validates the configuration for the state subcommands
+func loadProvisionStateConfig(cmd *cobra.Command) (*config.InstallerConfig, error) {
+	cfg := config.GenerateDefaultConfig()
+	if configFile := workspaceConfigFile(cmd, provisionConfigFile); configFile != "" {
+		loaded, err := config.LoadConfig(configFile)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration file: %w", err))
+		}
+		cfg = loaded
+	}
+
+	if err := cfg.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if !cfg.Infrastructure.Terraform.Enabled {
+		return nil, fmt.Errorf("terraform is not enabled in configuration")
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
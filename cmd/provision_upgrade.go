@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/clusterupgrade"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/maintenance"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	upgradeTo               string
+	upgradeControlPlaneOnly bool
+	upgradeNodePoolsOnly    bool
+	upgradeSkipAPICheck     bool
+	upgradeSkipRolloutCheck bool
+	upgradeMaxSurge         string
+	upgradeMaxUnavailable   string
+)
+
+// provisionUpgradeCmd upgrades the Kubernetes version of the provisioned cluster
+var provisionUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the cluster control plane and node pools to a Kubernetes version",
+	Long: `Upgrade the provisioned EKS, AKS or GKE cluster by one minor version at a time.
+
+Before upgrading, the manifests of the deployed charts and the objects applied with
+kubectl are scanned for APIs the target version no longer serves; the upgrade stops if
+any are found, since those releases could not be upgraded or rolled back afterwards.
+Node pool upgrades are then simulated within the surge settings: the pods of the most
+loaded nodes must fit elsewhere, no PodDisruptionBudget may be out of disruptions, and
+workloads with required hostname anti-affinity must keep enough nodes, so blockers are
+reported up front instead of stalling the upgrade halfway through a drain.
+
+With the terraform method (the default when Terraform is enabled) the target version is
+set as infrastructure.upgrade.versionVariable and applied, so the modules order the
+control plane and node pool upgrades; surge settings are passed as node_max_surge and
+node_max_unavailable. With the cli method the control plane is upgraded through the
+provider CLI, then each node pool in infrastructure.upgrade.nodePools, which the
+provider cordons and drains within the configured surge settings.
+
+Examples:
+  e2e-k8s-installer provision-infra upgrade --to 1.30
+  e2e-k8s-installer provision-infra upgrade --to 1.30 --control-plane-only
+  e2e-k8s-installer provision-infra upgrade --to 1.30 --node-pools-only --max-surge 2`,
+	Args: cobra.NoArgs,
+	RunE: withExitCode(exitcode.Deploy, runProvisionUpgrade),
+}
+
+func init() {
+	provisionUpgradeCmd.Flags().StringVar(&upgradeTo, "to", "", "Target Kubernetes version, e.g. 1.30")
+	provisionUpgradeCmd.Flags().BoolVar(&upgradeControlPlaneOnly, "control-plane-only", false, "Upgrade only the control plane (cli method)")
+	provisionUpgradeCmd.Flags().BoolVar(&upgradeNodePoolsOnly, "node-pools-only", false, "Upgrade only the node pools to the control plane version (cli method)")
+	provisionUpgradeCmd.Flags().BoolVar(&upgradeSkipAPICheck, "skip-api-check", false, "Upgrade even if deployed charts use APIs removed in the target version")
+	provisionUpgradeCmd.Flags().BoolVar(&upgradeSkipRolloutCheck, "skip-rollout-check", false, "Upgrade even if the node pool rollout simulation finds blockers")
+	provisionUpgradeCmd.Flags().StringVar(&upgradeMaxSurge, "max-surge", "", "Extra nodes created during the node pool upgrade, overrides the configuration")
+	provisionUpgradeCmd.Flags().StringVar(&upgradeMaxUnavailable, "max-unavailable", "", "Nodes that may be unavailable during the node pool upgrade, overrides the configuration")
+	provisionUpgradeCmd.MarkFlagRequired("to")
+	provisionInfraCmd.AddCommand(provisionUpgradeCmd)
+}
+
+func runProvisionUpgrade(cmd *cobra.Command, args []string) error {
+	if upgradeControlPlaneOnly && upgradeNodePoolsOnly {
+		return fmt.Errorf("--control-plane-only and --node-pools-only cannot be combined")
+	}
+
+	cfg := config.GenerateDefaultConfig()
+	if configFile := workspaceConfigFile(cmd, provisionConfigFile); configFile != "" {
+		loaded, err := config.LoadConfig(configFile)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration file: %w", err))
+		}
+		cfg = loaded
+	}
+	if err := cfg.ValidateConfig(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+
+	upgrade := cfg.Infrastructure.Upgrade
+	if upgradeMaxSurge != "" {
+		upgrade.MaxSurge = upgradeMaxSurge
+	}
+	if upgradeMaxUnavailable != "" {
+		upgrade.MaxUnavailable = upgradeMaxUnavailable
+	}
+	method := upgrade.Method
+	if method == "" {
+		method = clusterupgrade.MethodCLI
+		if cfg.Infrastructure.Terraform.Enabled {
+			method = clusterupgrade.MethodTerraform
+		}
+	}
+	if method == clusterupgrade.MethodTerraform && (upgradeControlPlaneOnly || upgradeNodePoolsOnly) {
+		return fmt.Errorf("--control-plane-only and --node-pools-only require the cli upgrade method")
+	}
+
+	target, err := deprecations.ParseVersion(upgradeTo)
+	if err != nil {
+		return err
+	}
+
+	// Step 1: version skew
+	k8sMgr, err := k8s.NewManager(&cfg.Kubernetes)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+	serverVersion, err := k8sMgr.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read the cluster version: %w", err)
+	}
+	current, err := deprecations.ParseVersion(serverVersion)
+	if err != nil {
+		return err
+	}
+	if err := checkUpgradeSkew(current, target); err != nil {
+		return err
+	}
+	pterm.Info.Printf("Cluster runs %s, upgrading to %s with the %s method\n", serverVersion, upgradeTo, method)
+
+	// Step 2: deprecated API usage of the deployed charts
+	if upgradeSkipAPICheck {
+		pterm.Warning.Println("Skipping the deprecated API check")
+	} else if err := checkDeployedAPIs(k8sMgr, target); err != nil {
+		return err
+	}
+
+	// Step 3: node pool rollout simulation
+	switch {
+	case upgradeControlPlaneOnly:
+	case upgradeSkipRolloutCheck:
+		pterm.Warning.Println("Skipping the node pool rollout simulation")
+	default:
+		if err := checkNodeRollout(k8sMgr, upgrade); err != nil {
+			return err
+		}
+	}
+
+	if viper.GetBool("dry-run") {
+		pterm.Info.Printf("DRY RUN: would upgrade %s to %s\n", upgradeTargetDescription(upgrade), upgradeTo)
+		return nil
+	}
+
+	lock, err := workspace.Acquire(cfg.Installer.Workspace, "provision-infra upgrade")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := awaitMaintenanceWindow(cfg, maintenance.PhaseUpgrade); err != nil {
+		return err
+	}
+
+	// Step 4: upgrade
+	start := time.Now()
+	if method == clusterupgrade.MethodTerraform {
+		err = upgradeWithTerraform(cfg, upgrade)
+	} else {
+		err = upgradeWithCLI(cfg, upgrade, current == target)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Step 5: confirm the control plane reports the target
+	if serverVersion, err = k8sMgr.ServerVersion(); err == nil {
+		if upgraded, _ := deprecations.ParseVersion(serverVersion); upgraded != target && !upgradeNodePoolsOnly {
+			pterm.Warning.Printf("The API server still reports %s; the upgrade may not have reached the control plane\n", serverVersion)
+		}
+	}
+
+	pterm.Success.Printf("Cluster upgraded to %s in %s\n", upgradeTo, time.Since(start).Round(time.Second))
+	logger.Info("Cluster upgrade completed").Str("version", upgradeTo).Str("method", method).Send()
+
+	if method == clusterupgrade.MethodTerraform {
+		pterm.Info.Printf("Set infrastructure.terraform.variables.%s to %s in the configuration so later applies keep it\n",
+			versionVariable(upgrade), upgradeTo)
+	}
+	return nil
+}
+
+// checkUpgradeSkew enforces one minor version per upgrade and no downgrades
+func checkUpgradeSkew(current, target deprecations.Version) error {
+	if target.Less(current) {
+		return fmt.Errorf("cannot downgrade the cluster from %s to %s", current, target)
+	}
+	if target.Major != current.Major || target.Minor > current.Minor+1 {
+		return fmt.Errorf("kubernetes upgrades one minor version at a time: upgrade %s to %d.%d first", current, current.Major, current.Minor+1)
+	}
+	if upgradeNodePoolsOnly && target != current {
+		return fmt.Errorf("node pools cannot run a newer version than the control plane (%s); upgrade the control plane first", current)
+	}
+	return nil
+}
+
+// checkDeployedAPIs scans the deployed releases and kubectl-applied objects for APIs removed in target
+func checkDeployedAPIs(k8sMgr *k8s.Manager, target deprecations.Version) error {
+	deployCfg, err := loadDeployConfig("")
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load deployment configuration: %w", err))
+	}
+	findings, err := scanReleaseAPIs(deployCfg, target)
+	if err != nil {
+		return err
+	}
+	live, err := scanLiveAPIs(k8sMgr, target)
+	if err != nil {
+		return err
+	}
+	if err := reportAPIFindings(append(findings, live...), target); err != nil {
+		return fmt.Errorf("%w, or pass --skip-api-check", err)
+	}
+	return nil
+}
+
+// checkNodeRollout simulates draining the node pools and fails on anything that would stall it
+func checkNodeRollout(k8sMgr *k8s.Manager, upgrade config.ClusterUpgrade) error {
+	sim, err := k8sMgr.SimulateNodeRollout(upgrade.MaxSurge, upgrade.MaxUnavailable)
+	if err != nil {
+		return fmt.Errorf("failed to simulate the node pool rollout: %w", err)
+	}
+	if len(sim.Blockers) == 0 {
+		pterm.Success.Printf("Node pools can roll across %d nodes (surge %d, unavailable %d)\n", sim.Nodes, sim.Surge, sim.Unavailable)
+		return nil
+	}
+
+	pterm.DefaultSection.Println("Node Pool Rollout")
+	tableData := pterm.TableData{{"Kind", "Object", "Severity", "Reason"}}
+	for _, blocker := range sim.Blockers {
+		severity := "warning"
+		if blocker.Blocking {
+			severity = "blocker"
+		}
+		tableData = append(tableData, []string{blocker.Kind, blocker.Object, severity, blocker.Reason})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if blocking := sim.Blocking(); len(blocking) > 0 {
+		logger.Warn("Node pool rollout blocked").Int("blockers", len(blocking)).Send()
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf(
+			"%d blockers would stall the node pool rollout with surge %d and unavailable %d; resolve them, raise --max-surge, or pass --skip-rollout-check",
+			len(blocking), sim.Surge, sim.Unavailable))
+	}
+	return nil
+}
+
+// upgradeWithTerraform applies the configuration with the target version variable set
+func upgradeWithTerraform(cfg *config.InstallerConfig, upgrade config.ClusterUpgrade) error {
+	if err := labels.InitGlobalPolicy(cfg.Labels, history.RunID()); err != nil {
+		return fmt.Errorf("invalid label policy: %w", err)
+	}
+	if err := params.InitGlobalStore(filepath.Join(cfg.Installer.Workspace, workspace.StateFileName), true); err != nil {
+		return fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+	if len(cfg.Infrastructure.Terraform.SecretVars) > 0 && len(cfg.Security.Credentials.Items) > 0 {
+		if _, err := publishCredentials(cfg, cfg.Installer.Workspace); err != nil {
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+
+	infraCfg := cfg.Infrastructure
+	variables := make(map[string]string, len(infraCfg.Terraform.Variables)+3)
+	for name, value := range infraCfg.Terraform.Variables {
+		variables[name] = value
+	}
+	variables[versionVariable(upgrade)] = upgradeTo
+	if upgrade.MaxSurge != "" {
+		variables[clusterupgrade.MaxSurgeVariable] = upgrade.MaxSurge
+	}
+	if upgrade.MaxUnavailable != "" {
+		variables[clusterupgrade.MaxUnavailableVariable] = upgrade.MaxUnavailable
+	}
+	infraCfg.Terraform.Variables = variables
+
+	tfMgr, err := terraform.NewManager(&infraCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create terraform manager: %w", err)
+	}
+	tfMgr.SetStateBackupDir(terraformBackupDir(cfg.Installer.Workspace))
+
+	if err := tfMgr.Init(); err != nil {
+		return err
+	}
+	if _, err := tfMgr.Plan(false); err != nil {
+		return err
+	}
+	err = tfMgr.Apply(false)
+	audit.Record("cluster.upgrade", cfg.Infrastructure.Upgrade.ClusterName, map[string]interface{}{
+		"version": upgradeTo,
+		"method":  clusterupgrade.MethodTerraform,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("terraform upgrade failed: %w", err)
+	}
+	return nil
+}
+
+// upgradeWithCLI upgrades the control plane, unless it already runs the target, then each node pool
+func upgradeWithCLI(cfg *config.InstallerConfig, upgrade config.ClusterUpgrade, controlPlaneCurrent bool) error {
+	mgr, err := clusterupgrade.NewManager(upgrade, cfg.Cloud)
+	if err != nil {
+		return err
+	}
+
+	if !upgradeNodePoolsOnly {
+		if controlPlaneCurrent {
+			pterm.Info.Printf("Control plane already runs %s\n", upgradeTo)
+		} else {
+			spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Upgrading control plane of %s to %s...", upgrade.ClusterName, upgradeTo))
+			if err := mgr.UpgradeControlPlane(upgradeTo); err != nil {
+				spinner.Fail("Control plane upgrade failed")
+				return err
+			}
+			spinner.Success(fmt.Sprintf("Control plane upgraded to %s", upgradeTo))
+		}
+	}
+
+	if upgradeControlPlaneOnly {
+		return nil
+	}
+	if len(upgrade.NodePools) == 0 {
+		pterm.Warning.Println("No node pools configured under infrastructure.upgrade.nodePools")
+		return nil
+	}
+	for _, pool := range upgrade.NodePools {
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Upgrading node pool %s to %s...", pool, upgradeTo))
+		if err := mgr.UpgradeNodePool(pool, upgradeTo); err != nil {
+			spinner.Fail(fmt.Sprintf("Node pool %s upgrade failed", pool))
+			return err
+		}
+		spinner.Success(fmt.Sprintf("Node pool %s upgraded to %s", pool, upgradeTo))
+	}
+	return nil
+}
+
+func upgradeTargetDescription(upgrade config.ClusterUpgrade) string {
+	switch {
+	case upgradeControlPlaneOnly:
+		return "the control plane"
+	case upgradeNodePoolsOnly:
+		return fmt.Sprintf("node pools %v", upgrade.NodePools)
+	default:
+		return "the control plane and node pools"
+	}
+}
+
+func versionVariable(upgrade config.ClusterUpgrade) string {
+	if upgrade.VersionVariable != "" {
+		return upgrade.VersionVariable
+	}
+	return clusterupgrade.DefaultVersionVariable
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/artifacts"
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var (
+	registryConfigFile  string
+	registryGCKeep      int
+	registryGCUntracked bool
+	registryGCYes       bool
+)
+
+// registryCmd groups maintenance of the client registry and chart repository
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Maintain the client image registry and chart repository",
+}
+
+// registryGCCmd deletes stale installer artifacts from the client registry
+var registryGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete old images and charts the installer pushed to the client side",
+	Long: `List, then delete, the images and charts that package-pull pushed to the client
+registry and chart repository and that no longer matter.
+
+An image tag is kept when the configuration or artifacts lockfile references it,
+or when it is one of the last --keep versions synced before. Only tags recorded
+in the lockfile history are collected unless --include-untracked is passed, which
+considers every tag of the configured image repositories. Tags are deleted by
+manifest digest, so a stale tag pointing at the same manifest as a kept tag stays.
+
+Chart versions are collected from the client chart repository when they are not in
+the local chart index built by package-pull and are older than the last --keep
+versions. Deleting charts is supported for chartmuseum and artifactory.
+
+The candidates are always listed first; deletion asks for confirmation unless
+--yes is given. Registries only reclaim storage after their own garbage collection.
+
+Examples:
+  e2e-k8s-installer registry gc --dry-run
+  e2e-k8s-installer registry gc --keep 3
+  e2e-k8s-installer registry gc --include-untracked --yes`,
+	Args: cobra.NoArgs,
+	RunE: runRegistryGC,
+}
+
+func init() {
+	registryCmd.PersistentFlags().StringVarP(&registryConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	registryGCCmd.Flags().IntVar(&registryGCKeep, "keep", artifacts.DefaultKeepReleases, "Previous versions of each image and chart to keep")
+	registryGCCmd.Flags().BoolVar(&registryGCUntracked, "include-untracked", false, "Also delete tags the lockfile never recorded")
+	registryGCCmd.Flags().BoolVar(&registryGCYes, "yes", false, "Delete without asking for confirmation")
+	registryCmd.AddCommand(registryGCCmd)
+}
+
+func runRegistryGC(cmd *cobra.Command, args []string) error {
+	if registryGCKeep < 0 {
+		return fmt.Errorf("--keep cannot be negative")
+	}
+	cfg, err := config.LoadConfig(workspaceConfigFile(cmd, registryConfigFile))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return err
+	}
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+
+	dryRun := viper.GetBool("dry-run")
+	if !dryRun {
+		lock, err := workspace.Acquire(cfg.Installer.Workspace, "registry gc")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	manager := artifacts.NewManager(cfg, dryRun)
+	spinner, _ := pterm.DefaultSpinner.Start("Listing client registry and chart repository...")
+	candidates, err := manager.PlanGC(artifacts.GCOptions{KeepReleases: registryGCKeep, IncludeUntracked: registryGCUntracked})
+	if err != nil {
+		spinner.Fail("Failed to list artifacts")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("%d stale artifacts found", len(candidates)))
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	tableData := pterm.TableData{{"Kind", "Name", "Versions", "Reference", "Tracked"}}
+	for _, candidate := range candidates {
+		tracked := "yes"
+		if !candidate.Tracked {
+			tracked = "no"
+		}
+		tableData = append(tableData, []string{candidate.Kind, candidate.Name, strings.Join(candidate.Versions, ", "), candidate.Reference, tracked})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if dryRun {
+		pterm.Info.Println("DRY RUN: nothing was deleted")
+		return nil
+	}
+	if !registryGCYes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("confirmation required: re-run with --yes to delete these artifacts")
+		}
+		confirmed, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(false).Show(fmt.Sprintf("Delete %d artifacts?", len(candidates)))
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if !confirmed {
+			pterm.Info.Println("Nothing deleted")
+			return nil
+		}
+	}
+
+	if err := manager.DeleteGC(candidates); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Deleted %d artifacts\n", len(candidates))
+	return nil
+}
@@ -0,0 +1,484 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+	"github.com/judebantony/e2e-k8s-installer/pkg/sarif"
+	"github.com/judebantony/e2e-k8s-installer/pkg/snapshot"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportSnapshotsDir string
+	reportOutput       string
+	reportAuditPath    string
+	reportAuditKeyFile string
+	reportLimit        int
+	reportOpen         bool
+	reportSARIFFile    string
+	reportConfigFile   string
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Inspect and compare installation reports",
+	Long: `Inspect reports and deployment snapshots produced by previous runs.
+
+Every successful install or upgrade captures a normalized snapshot of the
+deployed resources (images, environment variables, replica counts and chart
+values) that can be compared between runs for change-management reviews.`,
+}
+
+// reportDiffCmd represents the report diff command
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff <runA> <runB>",
+	Short: "Show what changed between two installations",
+	Long: `Compare the deployment snapshots of two runs and list every change in
+images, environment variables, replica counts and chart values.
+
+Runs can be referenced by run ID or by snapshot file path.
+
+Examples:
+  e2e-k8s-installer report diff deploy-20250101-120000 deploy-20250201-090000
+  e2e-k8s-installer report diff ./old.json ./new.json --output json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runReportDiff,
+}
+
+// reportAuditCmd represents the report audit command
+var reportAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show and verify the audit log of mutating operations",
+	Long: `List every recorded helm, kubectl, terraform, make, registry and database
+mutation together with the user and host that performed it, and verify that the
+hash chain of the log has not been tampered with.
+
+When entries were signed, pass the same key with --signing-key-file or the
+E2E_INSTALLER_AUDIT_KEY environment variable to verify the signatures as well.
+
+Examples:
+  e2e-k8s-installer report audit
+  e2e-k8s-installer report audit --audit-log ./workspace/logs/audit.jsonl -o json`,
+	RunE: runReportAudit,
+}
+
+// reportListCmd represents the report list command
+var reportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List previous runs recorded in the workspace",
+	Long: `List the runs recorded in the run registry (reports/runs.jsonl) of the workspace,
+newest first, with their outcome, duration and the reports they produced.
+
+Examples:
+  e2e-k8s-installer report list
+  e2e-k8s-installer report list --workspace prod --limit 50 -o json`,
+	Args: cobra.NoArgs,
+	RunE: runReportList,
+}
+
+// reportShowCmd represents the report show command
+var reportShowCmd = &cobra.Command{
+	Use:   "show <run-id>",
+	Short: "Show a recorded run and its reports",
+	Long: `Show the details of a run from the run registry and pretty-print the JSON reports
+it produced. The run can be referenced by its full ID, a unique prefix, or "latest".
+With --open the HTML report of the run is opened in the default browser instead.
+
+Examples:
+  e2e-k8s-installer report show latest
+  e2e-k8s-installer report show deploy-20250101-120000-3f9a
+  e2e-k8s-installer report show install-2025 --open`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportShow,
+}
+
+// reportSARIFCmd represents the report sarif command
+var reportSARIFCmd = &cobra.Command{
+	Use:   "sarif",
+	Short: "Export security, policy and misconfiguration findings as SARIF",
+	Long: `Collect the findings of the latest post-validate (deprecated and removed Kubernetes APIs),
+package-pull (image digest mismatches and unverified copies) and preflight rbac (missing
+installer permissions) reports in the workspace and write them as a SARIF 2.1.0 log that
+GitHub and GitLab security dashboards and enterprise scanners can ingest.
+
+Examples:
+  e2e-k8s-installer report sarif
+  e2e-k8s-installer report sarif --workspace prod --output-file installer.sarif`,
+	Args: cobra.NoArgs,
+	RunE: runReportSARIF,
+}
+
+func init() {
+	reportCmd.AddCommand(reportSARIFCmd)
+	reportCmd.AddCommand(reportDiffCmd)
+	reportCmd.AddCommand(reportAuditCmd)
+	reportCmd.AddCommand(reportListCmd)
+	reportCmd.AddCommand(reportShowCmd)
+
+	reportCmd.PersistentFlags().StringVar(&reportSnapshotsDir, "snapshots-dir", defaultSnapshotsDir, "Directory containing deployment snapshots")
+	reportDiffCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format (table, json)")
+
+	reportAuditCmd.Flags().StringVar(&reportAuditPath, "audit-log", audit.DefaultPath, "Path to the audit log")
+	reportAuditCmd.Flags().StringVar(&reportAuditKeyFile, "signing-key-file", "", "HMAC key used to verify entry signatures")
+	reportAuditCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format (table, json)")
+
+	// The run registry is kept in the workspace of the installation configuration
+	for _, c := range []*cobra.Command{reportListCmd, reportShowCmd} {
+		c.Flags().StringVar(&reportConfigFile, "config", "", "installation configuration whose workspace keeps the runs")
+	}
+	reportListCmd.Flags().IntVar(&reportLimit, "limit", 20, "Maximum number of runs to list (0 for all)")
+	reportListCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format (table, json)")
+	reportShowCmd.Flags().BoolVar(&reportOpen, "open", false, "Open the HTML report in the default browser")
+	reportShowCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format (table, json)")
+	reportSARIFCmd.Flags().StringVar(&reportSARIFFile, "output-file", "", "SARIF file to write (default reports/installer-findings.sarif)")
+	addFailOnFlag(reportSARIFCmd)
+}
+
+func runReportDiff(cmd *cobra.Command, args []string) error {
+	reportSnapshotsDir = workspaceDefault(cmd, "snapshots-dir", reportSnapshotsDir, "reports/snapshots")
+
+	before, err := snapshot.Load(reportSnapshotsDir, args[0])
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshot.Load(reportSnapshotsDir, args[1])
+	if err != nil {
+		return err
+	}
+
+	changes := snapshot.Diff(before, after)
+
+	switch reportOutput {
+	case "json":
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "table":
+	default:
+		return fmt.Errorf("unsupported output format: %s", reportOutput)
+	}
+
+	pterm.DefaultSection.Printf("Changes from %s to %s\n", before.RunID, after.RunID)
+
+	if len(changes) == 0 {
+		pterm.Success.Println("No changes detected between the two runs")
+		return nil
+	}
+
+	tableData := [][]string{{"Change", "Object", "Field", "Before", "After"}}
+	for _, change := range changes {
+		tableData = append(tableData, []string{
+			string(change.Type),
+			change.Object,
+			change.Field,
+			change.OldValue,
+			change.NewValue,
+		})
+	}
+
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Printf("%d changes detected\n", len(changes))
+
+	return nil
+}
+
+func runReportAudit(cmd *cobra.Command, args []string) error {
+	reportAuditPath = workspaceDefault(cmd, "audit-log", reportAuditPath, "logs/audit.jsonl")
+
+	entries, err := audit.ReadEntries(reportAuditPath)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	key, err := audit.SigningKey(config.AuditConfig{SigningKeyFile: reportAuditKeyFile})
+	if err != nil {
+		return err
+	}
+
+	switch reportOutput {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit entries: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		pterm.DefaultSection.Printf("Audit log %s\n", reportAuditPath)
+
+		tableData := [][]string{{"Seq", "Time", "User@Host", "Action", "Target", "Status"}}
+		for _, entry := range entries {
+			tableData = append(tableData, []string{
+				fmt.Sprintf("%d", entry.Sequence),
+				entry.Timestamp.Format("2006-01-02 15:04:05"),
+				fmt.Sprintf("%s@%s", entry.User, entry.Host),
+				entry.Action,
+				entry.Target,
+				entry.Status,
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	default:
+		return fmt.Errorf("unsupported output format: %s", reportOutput)
+	}
+
+	verified, err := audit.Verify(reportAuditPath, key)
+	if err != nil {
+		pterm.Error.Printf("Audit log verification failed after %d entries: %v\n", verified, err)
+		return err
+	}
+
+	if len(key) > 0 {
+		pterm.Success.Printf("%d entries verified (hash chain and signatures intact)\n", verified)
+	} else {
+		pterm.Success.Printf("%d entries verified (hash chain intact)\n", verified)
+	}
+	return nil
+}
+
+func runReportList(cmd *cobra.Command, args []string) error {
+	runs, err := history.List(reportsDir())
+	if err != nil {
+		return err
+	}
+	if reportLimit > 0 && len(runs) > reportLimit {
+		runs = runs[:reportLimit]
+	}
+
+	switch reportOutput {
+	case "json":
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal runs: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "table":
+	default:
+		return fmt.Errorf("unsupported output format: %s", reportOutput)
+	}
+
+	if len(runs) == 0 {
+		pterm.Info.Printf("No runs recorded in %s yet\n", reportsDir())
+		return nil
+	}
+
+	tableData := [][]string{{"Run ID", "Command", "Status", "Started", "Duration", "Reports"}}
+	for _, run := range runs {
+		status := run.Status
+		if run.DryRun {
+			status += " (dry run)"
+		}
+		tableData = append(tableData, []string{
+			run.ID,
+			run.Command,
+			status,
+			run.Started.Local().Format("2006-01-02 15:04:05"),
+			run.Duration.Round(time.Second).String(),
+			fmt.Sprintf("%d", len(run.Reports)),
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return nil
+}
+
+func runReportShow(cmd *cobra.Command, args []string) error {
+	run, err := history.Find(reportsDir(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if reportOpen {
+		for _, path := range run.Reports {
+			if strings.EqualFold(filepath.Ext(path), ".html") {
+				pterm.Info.Printf("Opening %s\n", path)
+				return openInBrowser(path)
+			}
+		}
+		return fmt.Errorf("run %s has no HTML report", run.ID)
+	}
+
+	switch reportOutput {
+	case "json":
+		data, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal run: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "table":
+	default:
+		return fmt.Errorf("unsupported output format: %s", reportOutput)
+	}
+
+	pterm.DefaultSection.Printf("Run %s\n", run.ID)
+	tableData := pterm.TableData{
+		{"Property", "Value"},
+		{"Command", run.Command},
+		{"Status", run.Status},
+		{"Dry Run", fmt.Sprintf("%t", run.DryRun)},
+		{"Started", run.Started.Local().Format("2006-01-02 15:04:05")},
+		{"Duration", run.Duration.Round(time.Millisecond).String()},
+	}
+	if run.Error != "" {
+		tableData = append(tableData, []string{"Error", run.Error})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if len(run.Reports) == 0 {
+		pterm.Info.Println("This run produced no reports")
+		return nil
+	}
+	for _, path := range run.Reports {
+		pterm.DefaultSection.WithLevel(2).Println(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			pterm.Warning.Printf("Report is no longer available: %v\n", err)
+			continue
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".json") {
+			pterm.Info.Println("Not a JSON report, open it directly")
+			continue
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, data, "", "  "); err != nil {
+			pterm.Warning.Printf("Report is not valid JSON: %v\n", err)
+			continue
+		}
+		fmt.Println(pretty.String())
+	}
+	return nil
+}
+
+func runReportSARIF(cmd *cobra.Command, args []string) error {
+	if err := validateFailOn(); err != nil {
+		return err
+	}
+	findings := collectFindings(findingsDirs())
+
+	log := sarif.New("e2e-k8s-installer", version.Version, "https://github.com/judebantony/e2e-k8s-installer")
+	for _, finding := range findings {
+		rule := finding.Rule
+		log.AddRule(sarif.Rule{
+			ID:               rule.ID,
+			Name:             rule.Name,
+			ShortDescription: sarif.Message{Text: rule.Description},
+			Help:             &sarif.Message{Text: rule.Help},
+			DefaultConfig:    &sarif.RuleLevel{Level: sarifLevel(rule.Severity)},
+			Properties:       &sarif.RuleProps{Tags: rule.Tags, SecuritySeverity: sarifSecuritySeverity(rule.Severity)},
+		})
+		log.AddResult(sarif.Result{
+			RuleID:  rule.ID,
+			Level:   sarifLevel(rule.Severity),
+			Message: sarif.Message{Text: finding.Message},
+			Locations: []sarif.Location{{
+				PhysicalLocation: sarif.PhysicalLocation{ArtifactLocation: sarif.ArtifactLocation{URI: filepath.ToSlash(filepath.Clean(finding.File))}},
+				LogicalLocations: []sarif.LogicalLocation{{FullyQualifiedName: finding.Object, Kind: "resource"}},
+			}},
+		})
+	}
+
+	path := reportSARIFFile
+	if path == "" {
+		path = filepath.Join(reportsDir(), "installer-findings.sarif")
+	}
+	if err := log.Write(path); err != nil {
+		return err
+	}
+	history.AddReport(path)
+
+	if len(findings) == 0 {
+		pterm.Success.Printf("No findings, empty SARIF log written to %s\n", path)
+		return nil
+	}
+	counts := make(map[string]int)
+	var rules []findingRule
+	for _, finding := range findings {
+		if counts[finding.Rule.ID] == 0 {
+			rules = append(rules, finding.Rule)
+		}
+		counts[finding.Rule.ID]++
+	}
+	tableData := [][]string{{"Rule", "Name", "Severity", "Findings"}}
+	for _, rule := range rules {
+		tableData = append(tableData, []string{rule.ID, rule.Name, rule.Severity, fmt.Sprintf("%d", counts[rule.ID])})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Printf("%d findings written to %s\n", len(findings), path)
+	return checkFailOn(findings)
+}
+
+// sarifLevel maps a finding severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case htmlreport.SeverityCritical, htmlreport.SeverityHigh:
+		return sarif.LevelError
+	case htmlreport.SeverityMedium:
+		return sarif.LevelWarning
+	}
+	return sarif.LevelNote
+}
+
+// sarifSecuritySeverity is the CVSS-like score GitHub uses to rank security alerts
+func sarifSecuritySeverity(severity string) string {
+	switch severity {
+	case htmlreport.SeverityCritical:
+		return "9.5"
+	case htmlreport.SeverityHigh:
+		return "8.0"
+	case htmlreport.SeverityMedium:
+		return "5.0"
+	}
+	return "2.0"
+}
+
+// openInBrowser opens a file with the desktop's default application
+func openInBrowser(path string) error {
+	var opener *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		opener = exec.Command("open", path)
+	case "windows":
+		opener = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		opener = exec.Command("xdg-open", path)
+	}
+	if err := opener.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeReport writes a JSON report and attaches it to the current run in the run registry
+func writeReport(path string, report interface{}) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	history.AddReport(path)
+	return nil
+}
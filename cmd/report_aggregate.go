@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/compliance"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportAggregateFrom []string
+	reportAggregateFile string
+)
+
+// fleetSearchDepth is how far below a source workspaces are looked for, e.g.
+// <customer>/<environment>/reports
+const fleetSearchDepth = 3
+
+// reportAggregateCmd merges the reports of many workspaces into a fleet summary
+var reportAggregateCmd = &cobra.Command{
+	Use:   "aggregate",
+	Short: "Summarize the reports of many workspaces as a fleet report",
+	Long: `Merge the reports of many workspaces, one per customer site or environment, into a
+fleet summary: the chart versions each site runs, its drift from its configuration and its
+failing health checks.
+
+Each --from is a workspace, a directory holding workspaces, or an object store prefix the
+workspaces are pushed to with --workspace-store (s3://, gs:// or azblob://); of those only
+the reports directories are downloaded. A site is named after its directory below the
+source. Per site the report shows:
+  last run     the newest install, deploy or other run that was not a dry run or an audit
+  versions     the charts of the last deployment report
+  drift        deviations of the last 'audit' in the releases and drift categories
+  health       failed checks of the last post-validate and failed health checks of the
+               last audit
+
+Examples:
+  e2e-k8s-installer report aggregate --from ./customers
+  e2e-k8s-installer report aggregate --from s3://vendor-fleet/workspaces --output html
+  e2e-k8s-installer report aggregate --from ./eu --from gs://fleet/us --output json`,
+	Args: cobra.NoArgs,
+	RunE: runReportAggregate,
+}
+
+func init() {
+	reportCmd.AddCommand(reportAggregateCmd)
+
+	reportAggregateCmd.Flags().StringSliceVar(&reportAggregateFrom, "from", nil, "Workspace, directory of workspaces or object store prefix to aggregate (repeatable)")
+	reportAggregateCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format (table, json, html)")
+	reportAggregateCmd.Flags().StringVar(&reportAggregateFile, "output-file", "", "File to write the json or html report to (default stdout for json, reports/fleet-report.html for html)")
+	reportAggregateCmd.MarkFlagRequired("from")
+}
+
+func runReportAggregate(cmd *cobra.Command, args []string) error {
+	switch reportOutput {
+	case "table", "json", "html":
+	default:
+		return fmt.Errorf("unsupported output format: %s", reportOutput)
+	}
+
+	fleet := &htmlreport.Fleet{Title: "Fleet Report", Generated: time.Now().UTC(), Sources: reportAggregateFrom}
+	for _, source := range reportAggregateFrom {
+		sites, err := aggregateSource(source)
+		if err != nil {
+			return err
+		}
+		fleet.Sites = append(fleet.Sites, sites...)
+	}
+	if len(fleet.Sites) == 0 {
+		return fmt.Errorf("no workspace reports found in %s", strings.Join(reportAggregateFrom, ", "))
+	}
+	sort.SliceStable(fleet.Sites, func(i, j int) bool {
+		return fleet.Sites[i].Name < fleet.Sites[j].Name
+	})
+	fleet.Versions = fleetVersions(fleet.Sites)
+
+	switch reportOutput {
+	case "json":
+		data, err := json.MarshalIndent(fleet, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal fleet report: %w", err)
+		}
+		if reportAggregateFile == "" {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err := writeReport(reportAggregateFile, fleet); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Fleet report of %d sites written to %s\n", len(fleet.Sites), reportAggregateFile)
+	case "html":
+		path := valueOr(reportAggregateFile, filepath.Join(reportsDir(), "fleet-report.html"))
+		if err := htmlreport.WriteFleet(path, fleet); err != nil {
+			return err
+		}
+		history.AddReport(path)
+		pterm.Success.Printf("Fleet report of %d sites written to %s\n", len(fleet.Sites), path)
+	default:
+		printFleet(fleet)
+	}
+	return nil
+}
+
+// aggregateSource reads the sites of one --from, downloading the reports of an object store
+// prefix first
+func aggregateSource(source string) ([]htmlreport.Site, error) {
+	if !strings.Contains(source, "://") {
+		if info, err := os.Stat(source); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("--from %s is not a directory", source)
+		}
+		return fleetSites(source, filepath.Base(filepath.Clean(source)))
+	}
+
+	store, err := workspace.ParseStore(source)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "fleet-reports-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Downloading reports from %s...", source))
+	if err := store.PullReports(dir); err != nil {
+		spinner.Fail("Download failed")
+		return nil, err
+	}
+	spinner.Success(fmt.Sprintf("Reports downloaded from %s", source))
+	return fleetSites(dir, valueOr(path.Base(strings.TrimRight(source, "/")), source))
+}
+
+// fleetSites finds the workspaces below root, every directory with a reports directory, and
+// sums each up. A workspace at root itself is named rootName.
+func fleetSites(root, rootName string) ([]htmlreport.Site, error) {
+	var sites []htmlreport.Site
+	err := filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, dir)
+		if rel != "." && strings.Count(filepath.ToSlash(rel), "/") >= fleetSearchDepth {
+			return filepath.SkipDir
+		}
+		reports := filepath.Join(dir, "reports")
+		if info, err := os.Stat(reports); err != nil || !info.IsDir() {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		if rel == "." {
+			name = rootName
+		}
+		sites = append(sites, readSite(name, reports))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s for workspaces: %w", root, err)
+	}
+	return sites, nil
+}
+
+// readSite sums up a workspace from the reports of its last runs
+func readSite(name, dir string) htmlreport.Site {
+	site := htmlreport.Site{Name: name}
+	known := false
+
+	runs, err := history.List(dir)
+	if err != nil {
+		logger.Warn("Ignoring the run registry of a site").Str("site", name).Err(err).Send()
+	}
+	for _, run := range runs {
+		if run.DryRun || strings.HasSuffix(run.Command, " audit") {
+			continue
+		}
+		started := run.Started
+		site.LastRun, site.LastStatus, site.LastRunAt = run.ID, run.Status, &started
+		known = true
+		break
+	}
+
+	var deployment deploymentReportData
+	if readLatestReport([]string{dir}, "deployment-report.json", &deployment) {
+		known = true
+		for _, chart := range deployment.DeployedCharts {
+			site.Charts = append(site.Charts, htmlreport.SiteChart{
+				Name:       chart.Name,
+				Namespace:  chart.Namespace,
+				Version:    chart.Version,
+				AppVersion: chart.AppVersion,
+				Status:     chart.Status,
+			})
+		}
+	}
+
+	var validation validationReportData
+	if readLatestReport([]string{dir}, "post-validation-report.json", &validation) {
+		known = true
+		for _, failure := range validation.Failures {
+			site.Health = append(site.Health, htmlreport.Failure{Name: failure.Name, Category: failure.Category, Error: failure.Error})
+		}
+	}
+
+	var audit compliance.Report
+	if readLatestReport([]string{dir}, auditReportName, &audit) {
+		known = true
+		site.Score, site.Audited, site.Environment = &audit.Score, &audit.Timestamp, audit.Environment
+		for _, check := range audit.Deviations() {
+			failure := htmlreport.Failure{Name: check.Name, Category: check.Category, Error: check.Message}
+			switch check.Category {
+			case compliance.CategoryReleases, compliance.CategoryDrift:
+				site.Drift = append(site.Drift, failure)
+			case compliance.CategoryHealth:
+				site.Health = append(site.Health, failure)
+			}
+		}
+	}
+
+	switch {
+	case !known:
+		site.Status = htmlreport.StatusSkipped
+	case len(site.Health) > 0 || site.LastStatus == history.StatusFailed:
+		site.Status = htmlreport.StatusFailed
+	case len(site.Drift) > 0:
+		site.Status = htmlreport.StatusDrifted
+	default:
+		site.Status = htmlreport.StatusSuccess
+	}
+	return site
+}
+
+// fleetVersions lists, per chart, the sites running each of its versions
+func fleetVersions(sites []htmlreport.Site) []htmlreport.ChartVersions {
+	bySite := make(map[string]map[string][]string)
+	for _, site := range sites {
+		for _, chart := range site.Charts {
+			if bySite[chart.Name] == nil {
+				bySite[chart.Name] = make(map[string][]string)
+			}
+			bySite[chart.Name][chart.Version] = append(bySite[chart.Name][chart.Version], site.Name)
+		}
+	}
+
+	var versions []htmlreport.ChartVersions
+	for chart, sitesByVersion := range bySite {
+		entry := htmlreport.ChartVersions{Chart: chart}
+		for version, names := range sitesByVersion {
+			entry.Versions = append(entry.Versions, htmlreport.VersionSites{Version: version, Sites: names})
+		}
+		// The version most sites run first, the outliers after it
+		sort.Slice(entry.Versions, func(i, j int) bool {
+			if len(entry.Versions[i].Sites) != len(entry.Versions[j].Sites) {
+				return len(entry.Versions[i].Sites) > len(entry.Versions[j].Sites)
+			}
+			return entry.Versions[i].Version < entry.Versions[j].Version
+		})
+		versions = append(versions, entry)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Chart < versions[j].Chart
+	})
+	return versions
+}
+
+func printFleet(fleet *htmlreport.Fleet) {
+	tableData := pterm.TableData{{"Site", "Environment", "Status", "Last run", "Charts", "Audit score", "Drift", "Failing checks"}}
+	for _, site := range fleet.Sites {
+		status := site.Status
+		switch status {
+		case htmlreport.StatusFailed:
+			status = pterm.Red(status)
+		case htmlreport.StatusDrifted:
+			status = pterm.Yellow(status)
+		}
+		charts := make([]string, len(site.Charts))
+		for i, chart := range site.Charts {
+			charts[i] = chart.Name + " " + chart.Version
+		}
+		score := "-"
+		if site.Score != nil {
+			score = fmt.Sprintf("%.1f", *site.Score)
+		}
+		lastRun := site.LastRun
+		if site.LastStatus != "" {
+			lastRun += " (" + site.LastStatus + ")"
+		}
+		tableData = append(tableData, []string{site.Name, site.Environment, status, lastRun, strings.Join(charts, ", "),
+			score, fmt.Sprint(len(site.Drift)), fmt.Sprint(len(site.Health))})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	for _, chart := range fleet.Versions {
+		if len(chart.Versions) < 2 {
+			continue
+		}
+		parts := make([]string, len(chart.Versions))
+		for i, version := range chart.Versions {
+			parts[i] = fmt.Sprintf("%s on %s", version.Version, strings.Join(version.Sites, ", "))
+		}
+		pterm.Warning.Printf("Chart %s runs %d versions: %s\n", chart.Chart, len(chart.Versions), strings.Join(parts, "; "))
+	}
+	for _, site := range fleet.Sites {
+		for _, failure := range append(site.Drift, site.Health...) {
+			pterm.Warning.Printf("%s: %s: %s\n", site.Name, failure.Name, failure.Error)
+		}
+	}
+	pterm.Info.Printf("%d sites: %d healthy, %d drifted, %d failing\n", len(fleet.Sites),
+		fleet.Count(htmlreport.StatusSuccess), fleet.Count(htmlreport.StatusDrifted), fleet.Count(htmlreport.StatusFailed))
+}
@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/rollback"
+	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackConfigFile string
+	rollbackStateFile  string
+	rollbackTo         string
+	rollbackList       bool
+	rollbackDryRun     bool
+	rollbackTimeout    time.Duration
+	rollbackOverride   string
+)
+
+// rollbackCmd undoes the journalled changes of installations in reverse order
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Undo the changes of failed or unwanted installations",
+	Long: `Undo what installations changed, newest change first. Every mutating action is
+journalled in the installation state file as it is made:
+
+  helm-release     rolled back to the revision it had before, or uninstalled when it was new
+  namespace        deleted, only namespaces the installer created are journalled
+  terraform-apply  destroyed when it applied to an empty state; an apply changing existing
+                   infrastructure is irreversible, its state snapshot is listed instead
+  db-migration     reverted with the database.migration.rollback command; without one it
+                   is irreversible, restore the database from a backup
+
+Every completed install step is a checkpoint, and 'rollback checkpoint <name>' adds one.
+Without --to everything journalled is undone; --to <checkpoint> undoes only what was done
+after the last checkpoint of that name. Irreversible actions are left in place and the
+rollback continues; an undo that fails stops it, and the next rollback retries from there.
+
+Examples:
+  e2e-k8s-installer rollback --list
+  e2e-k8s-installer rollback --to provision-infra --dry-run
+  e2e-k8s-installer rollback --to provision-infra
+  e2e-k8s-installer rollback checkpoint before-upgrade`,
+	Args: cobra.NoArgs,
+	RunE: runRollback,
+}
+
+// rollbackCheckpointCmd adds a named checkpoint to the journal
+var rollbackCheckpointCmd = &cobra.Command{
+	Use:   "checkpoint <name>",
+	Short: "Mark the current point of the journal to roll back to later",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRollbackCheckpoint,
+}
+
+func init() {
+	rollbackCmd.AddCommand(rollbackCheckpointCmd)
+
+	rollbackCmd.PersistentFlags().StringVarP(&rollbackConfigFile, "config", "c", "installer-config.json", "Configuration file path")
+	rollbackCmd.PersistentFlags().StringVar(&rollbackStateFile, "state-file", "", "Path to installation state file")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Undo only what was done after this checkpoint")
+	rollbackCmd.Flags().BoolVar(&rollbackList, "list", false, "List the journal and its checkpoints without undoing anything")
+	rollbackCmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false, "Show what would be undone without undoing it")
+	rollbackCmd.Flags().DurationVar(&rollbackTimeout, "timeout", 5*time.Minute, "Timeout for each release rollback or uninstall")
+	rollbackCmd.Flags().StringVar(&rollbackOverride, "override-freeze", "", "Roll back in frozen namespaces, giving the reason recorded in the audit log")
+}
+
+// loadRollbackConfig loads the installer configuration and the state file holding the journal
+func loadRollbackConfig(cmd *cobra.Command) (*config.InstallerConfig, string, error) {
+	cfg, err := loadInstallConfig(workspaceConfigFile(cmd, rollbackConfigFile))
+	if err != nil {
+		return nil, "", exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return nil, "", err
+	}
+	stateFile := valueOr(rollbackStateFile, filepath.Join(cfg.Installer.Workspace, workspace.StateFileName))
+	if err := params.InitGlobalStore(stateFile, true); err != nil {
+		return nil, "", fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+	return cfg, stateFile, nil
+}
+
+func runRollbackCheckpoint(cmd *cobra.Command, args []string) error {
+	_, stateFile, err := loadRollbackConfig(cmd)
+	if err != nil {
+		return err
+	}
+	rollback.Checkpoint(args[0])
+	pterm.Success.Printf("Checkpoint %s added to %s\n", args[0], stateFile)
+	return nil
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	cfg, stateFile, err := loadRollbackConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if rollbackList {
+		actions, err := rollback.Load(stateFile)
+		if err != nil {
+			return err
+		}
+		printRollbackJournal(stateFile, actions)
+		return nil
+	}
+
+	engine := rollback.NewEngine(stateFile)
+	plan, err := engine.Plan(rollbackTo)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		pterm.Info.Printf("Nothing to roll back in %s\n", stateFile)
+		return nil
+	}
+	printRollbackPlan(plan)
+	if rollbackDryRun {
+		pterm.Info.Printf("DRY RUN: %d actions would be undone\n", len(plan))
+		return nil
+	}
+
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+	lock, err := workspace.Acquire(cfg.Installer.Workspace, "rollback")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	releaseFreeze, err := checkNamespaceFreeze(&cfg.Kubernetes, rollbackNamespaces(plan), "rollback", rollbackOverride, false)
+	if err != nil {
+		return err
+	}
+	defer releaseFreeze()
+
+	undo := &rollbackUndo{cfg: cfg}
+	engine.Handle(rollback.KindHelmRelease, undo.helmRelease)
+	engine.Handle(rollback.KindNamespace, undo.namespace)
+	engine.Handle(rollback.KindTerraformApply, undo.terraformApply)
+	engine.Handle(rollback.KindMigration, undo.migration)
+
+	var undone []config.RollbackAction
+	runErr := engine.Run(plan, func(action config.RollbackAction) {
+		undone = append(undone, action)
+		if action.Kind == rollback.KindCheckpoint {
+			return
+		}
+		switch action.Status {
+		case rollback.StatusReverted:
+			pterm.Success.Printf("Undone %s %s\n", action.Kind, rollbackTarget(action))
+		case rollback.StatusIrreversible:
+			pterm.Warning.Printf("Left %s %s in place: %s\n", action.Kind, rollbackTarget(action), action.Error)
+		default:
+			pterm.Error.Printf("Failed to undo %s %s: %s\n", action.Kind, rollbackTarget(action), action.Error)
+		}
+	})
+
+	if err := writeReport(filepath.Join(reportsDir(), "environment-rollback-report.json"), map[string]interface{}{
+		"timestamp": time.Now().UTC(),
+		"stateFile": stateFile,
+		"to":        rollbackTo,
+		"actions":   undone,
+	}); err != nil {
+		pterm.Warning.Printf("Failed to write rollback report: %v\n", err)
+	}
+
+	counts := make(map[string]int)
+	for _, action := range undone {
+		if action.Kind != rollback.KindCheckpoint {
+			counts[action.Status]++
+		}
+	}
+	pterm.Info.Printf("%d undone, %d irreversible, %d failed, %d not reached\n", counts[rollback.StatusReverted],
+		counts[rollback.StatusIrreversible], counts[rollback.StatusFailed], len(plan)-len(undone))
+	if runErr != nil {
+		return exitcode.Wrap(exitcode.Deploy, runErr)
+	}
+	return nil
+}
+
+// rollbackUndo undoes each kind of journalled action against the configured environment
+type rollbackUndo struct {
+	cfg     *config.InstallerConfig
+	helmMgr *helm.Manager
+	k8sMgr  *k8s.Manager
+}
+
+func (u *rollbackUndo) helmRelease(action config.RollbackAction) error {
+	if u.helmMgr == nil {
+		helmMgr, err := helm.NewManager(&u.cfg.Kubernetes)
+		if err != nil {
+			return err
+		}
+		u.helmMgr = helmMgr
+	}
+	if action.Revision == 0 {
+		return u.helmMgr.Uninstall(action.Name, action.Namespace, rollbackTimeout)
+	}
+	return u.helmMgr.Rollback(action.Name, action.Namespace, action.Revision, rollbackTimeout, true)
+}
+
+func (u *rollbackUndo) namespace(action config.RollbackAction) error {
+	if u.k8sMgr == nil {
+		k8sMgr, err := k8s.NewManager(&u.cfg.Kubernetes)
+		if err != nil {
+			return err
+		}
+		u.k8sMgr = k8sMgr
+	}
+	return u.k8sMgr.Delete("namespaces", "", action.Name, true)
+}
+
+func (u *rollbackUndo) terraformApply(action config.RollbackAction) error {
+	if action.Details["fresh"] != "true" {
+		return fmt.Errorf("%w: it changed existing infrastructure, the state before it is %s", rollback.ErrIrreversible,
+			valueOr(action.Details["snapshot"], "not snapshotted"))
+	}
+
+	infra := u.cfg.Infrastructure
+	infra.Terraform.Workspace = action.Name
+	if len(infra.Terraform.SecretVars) > 0 && len(u.cfg.Security.Credentials.Items) > 0 {
+		if _, err := publishCredentials(u.cfg, u.cfg.Installer.Workspace); err != nil {
+			return fmt.Errorf("failed to prepare credentials: %w", err)
+		}
+	}
+	tfManager, err := terraform.NewManager(&infra)
+	if err != nil {
+		return fmt.Errorf("failed to create terraform manager: %w", err)
+	}
+	tfManager.SetStateBackupDir(terraformBackupDir(u.cfg.Installer.Workspace))
+	if targets := action.Details["targets"]; targets != "" {
+		if err := tfManager.SetTargets(strings.Split(targets, ",")); err != nil {
+			return err
+		}
+	}
+	if err := tfManager.Init(); err != nil {
+		return err
+	}
+	return tfManager.Apply(true)
+}
+
+func (u *rollbackUndo) migration(action config.RollbackAction) error {
+	migration := u.cfg.Database.Migration
+	if migration.Rollback == "" {
+		return fmt.Errorf("%w: database.migration.rollback is not configured, restore %s from a backup", rollback.ErrIrreversible, action.Name)
+	}
+
+	cmd := execx.Command("sh", "-c", migration.Rollback)
+	cmd.Env = parameterEnviron("MIGRATIONS_APPLIED="+action.Details["applied"], "MIGRATION_TOOL="+action.Details["tool"])
+	cmd.Secrets = params.GetStore().SensitiveValues()
+	cmd.Tag = "migration rollback"
+	cmd.Audit, cmd.AuditTarget = "db.rollback", action.Name
+	cmd.AuditDetails = map[string]interface{}{"migrations": action.Details["applied"], "runId": action.RunID}
+	if timeout, err := time.ParseDuration(migration.Timeout); err == nil {
+		cmd.Timeout = timeout
+	}
+	_, err := cmd.Run(context.Background())
+	return err
+}
+
+// rollbackNamespaces returns the namespaces the plan changes, checked against namespace freezes
+func rollbackNamespaces(plan []config.RollbackAction) []string {
+	var namespaces []string
+	for _, action := range plan {
+		namespace := action.Namespace
+		if action.Kind == rollback.KindNamespace {
+			namespace = action.Name
+		}
+		if namespace != "" && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// rollbackTarget names what an action changed, e.g. app/backend for a release
+func rollbackTarget(action config.RollbackAction) string {
+	if action.Namespace != "" {
+		return action.Namespace + "/" + action.Name
+	}
+	return action.Name
+}
+
+// rollbackDescription says how an action is undone
+func rollbackDescription(action config.RollbackAction) string {
+	switch action.Kind {
+	case rollback.KindHelmRelease:
+		if action.Revision == 0 {
+			return "uninstall"
+		}
+		return fmt.Sprintf("roll back to revision %d", action.Revision)
+	case rollback.KindNamespace:
+		return "delete"
+	case rollback.KindTerraformApply:
+		if action.Details["fresh"] == "true" {
+			if targets := action.Details["targets"]; targets != "" {
+				return "destroy " + targets
+			}
+			return "destroy"
+		}
+		return "irreversible"
+	case rollback.KindMigration:
+		return fmt.Sprintf("revert %s migrations", valueOr(action.Details["applied"], "the"))
+	}
+	return "checkpoint"
+}
+
+func printRollbackPlan(plan []config.RollbackAction) {
+	tableData := pterm.TableData{{"#", "Kind", "Target", "Undo", "Recorded", "Run"}}
+	for _, action := range plan {
+		tableData = append(tableData, []string{fmt.Sprint(action.Sequence), action.Kind, rollbackTarget(action),
+			rollbackDescription(action), action.RecordedAt.Local().Format("2006-01-02 15:04:05"), action.RunID})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+func printRollbackJournal(stateFile string, actions []config.RollbackAction) {
+	if len(actions) == 0 {
+		pterm.Info.Printf("No actions journalled in %s\n", stateFile)
+		return
+	}
+	tableData := pterm.TableData{{"#", "Kind", "Target", "Status", "Recorded", "Run"}}
+	for _, action := range actions {
+		status := action.Status
+		switch status {
+		case rollback.StatusFailed:
+			status = pterm.Red(status)
+		case rollback.StatusIrreversible:
+			status = pterm.Yellow(status)
+		}
+		tableData = append(tableData, []string{fmt.Sprint(action.Sequence), action.Kind, rollbackTarget(action),
+			status, action.RecordedAt.Local().Format("2006-01-02 15:04:05"), action.RunID})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
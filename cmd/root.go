@@ -3,7 +3,17 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/telemetry"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -13,6 +23,11 @@ var (
 	verbose    bool
 	dryRun     bool
 	configPath string
+
+	workspaceFlag string
+
+	// commandWorkspace is the workspace of the running command's configuration
+	commandWorkspace string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -30,11 +45,107 @@ Features:
 - Application deployment with Helm charts
 - Comprehensive monitoring and logging
 - End-to-end testing and validation`,
+	Version: version.Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		configureWorkspace(cmd)
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	return rootCmd.Execute()
+	start := time.Now()
+	target, _, err := rootCmd.Find(os.Args[1:])
+	if err == nil {
+		history.Start(runName(target))
+		logger.SetToolStep(runName(target))
+	} else {
+		target = nil
+	}
+	executed, err := executeRecovering(target)
+	stopSimulation()
+	stopCassette()
+	logger.FinishJournal(err)
+	logger.CloseToolLogs()
+	var run *history.Run
+	if recordsHistory(executed) {
+		// Several commands define their own --dry-run, so read it from the command that ran
+		dryRun, _ := executed.Flags().GetBool("dry-run")
+		var historyErr error
+		if run, historyErr = history.Finish(reportsDir(), executed.CommandPath(), dryRun, err); historyErr != nil {
+			logger.Warn("Failed to record run history").Err(historyErr).Send()
+		}
+	}
+	if executed != nil && executed.Runnable() {
+		finishCI(executed.CommandPath(), time.Since(start), run, err)
+		finishInCluster(executed.CommandPath(), err)
+	}
+	if recordsTelemetry(executed) {
+		telemetry.RecordRun(executed.CommandPath(), time.Since(start), err)
+	}
+	return err
+}
+
+// configureWorkspace points the reports, the run registry, the step logs and the audit log at
+// the workspace of the command's configuration, so every file of a run lands in one workspace,
+// and signs the audit entries of commands that never initialize the log themselves with its
+// audit settings
+func configureWorkspace(cmd *cobra.Command) {
+	configFile := ""
+	// The root --config is the viper file, so only a command's own --config names an installer configuration
+	if flag := cmd.NonInheritedFlags().Lookup("config"); flag != nil {
+		configFile = flag.Value.String()
+	}
+	dir, auditConfig := selectedWorkspace("./workspace"), config.AuditConfig{}
+	if cfg, err := loadInstallConfig(workspaceConfigFile(cmd, configFile)); err == nil {
+		dir, auditConfig = selectedWorkspace(cfg.Installer.Workspace), cfg.Installer.Audit
+	}
+	commandWorkspace = dir
+	logger.ConfigureToolLogs(toolLogsDir(), verbose)
+	audit.Configure(dir, auditConfig)
+}
+
+// withExitCode makes failures of run exit with code, unless a more specific code was
+// attached closer to the failure
+func withExitCode(code int, run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return exitcode.Wrap(code, run(cmd, args))
+	}
+}
+
+// historyCommands are the top-level commands whose runs are kept in the run registry
+var historyCommands = map[string]bool{
+	"setup": true, "package-pull": true, "provision-infra": true, "db-migrate": true, "deploy": true,
+	"post-validate": true, "e2e-test": true, "install": true, "preflight": true, "uninstall": true,
+	"registry": true, "self-update": true, "audit": true, "rollback": true,
+}
+
+func recordsHistory(cmd *cobra.Command) bool {
+	if cmd == nil || !cmd.Runnable() || !cmd.HasParent() {
+		return false
+	}
+	top := cmd
+	for top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	return historyCommands[top.Name()]
+}
+
+// runName names a run after its command path without the binary, e.g. registry-gc
+func runName(cmd *cobra.Command) string {
+	path := strings.Fields(cmd.CommandPath())
+	return strings.Join(path[1:], "-")
+}
+
+// recordsTelemetry skips runs that only print help, completions or manage telemetry itself
+func recordsTelemetry(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "telemetry", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return cmd != nil && cmd.Runnable()
 }
 
 func init() {
@@ -45,6 +156,14 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "perform a dry run without making changes")
 	rootCmd.PersistentFlags().StringVar(&configPath, "config-path", "", "path to configuration directory")
+	rootCmd.PersistentFlags().StringVarP(&workspaceFlag, "workspace", "w", "", "workspace name (see 'workspace list') or directory")
+	rootCmd.PersistentFlags().BoolVar(&simulateFlag, "simulate", false, "run against a simulated cluster, cloud and registry instead of real infrastructure")
+	rootCmd.PersistentFlags().StringVar(&recordFlag, "record", "", "record every command and HTTP call of the run into this cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayFlag, "replay", "", "answer every command and HTTP call from this recorded cassette file")
+	rootCmd.PersistentFlags().StringVar(&themeFlag, "theme", "", "output theme: default, monochrome, high-contrast or corporate")
+	rootCmd.PersistentFlags().BoolVar(&inClusterFlag, "in-cluster", false, "run inside a pod, reaching the cluster through its ServiceAccount and using the workspace volume")
+	rootCmd.PersistentFlags().StringVar(&workspaceStoreFlag, "workspace-store", "", "pull the workspace from this object store before the run and push it back after: s3://, gs:// or azblob://")
+	rootCmd.PersistentFlags().StringVar(&ciFlag, "ci", "", "emit the workflow commands of a CI system: github, gitlab, azure or auto")
 
 	// Bind flags to viper
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
@@ -58,16 +177,24 @@ func init() {
 	rootCmd.AddCommand(dbMigrateCmd)
 	rootCmd.AddCommand(deployCmd)
 	rootCmd.AddCommand(postValidateCmd)
-	// Temporary placeholder for e2e-test command
-	tempE2ECmd := &cobra.Command{
-		Use:   "e2e-test",
-		Short: "Execute end-to-end testing suite (placeholder)",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return fmt.Errorf("e2e-test command not yet fully implemented")
-		},
-	}
-	rootCmd.AddCommand(tempE2ECmd)
+	rootCmd.AddCommand(e2eTestCmd)
 	rootCmd.AddCommand(installCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(preflightCmd)
+	rootCmd.AddCommand(uninstallCmd)
+	rootCmd.AddCommand(freezeCmd)
+	rootCmd.AddCommand(approveCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(portForwardCmd)
+	rootCmd.AddCommand(workspaceCmd)
+	rootCmd.AddCommand(postRenderCmd)
+	rootCmd.AddCommand(registryCmd)
+	rootCmd.AddCommand(checkUpdatesCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(licenseCmd)
+	rootCmd.AddCommand(telemetryCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -87,6 +214,15 @@ func initConfig() {
 
 	viper.AutomaticEnv()
 
+	// Flags are parsed by now, so the workspace and --verbose are known
+	cobra.CheckErr(startTheme())
+	cobra.CheckErr(startCI())
+	cobra.CheckErr(startInCluster())
+	cobra.CheckErr(startLocale())
+	cobra.CheckErr(startSimulation())
+	cobra.CheckErr(startCassette())
+	k8s.UseWorkspace(selectedWorkspace("./workspace"))
+
 	if err := viper.ReadInConfig(); err == nil {
 		if verbose {
 			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/selfupdate"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	selfUpdateChannel  string
+	selfUpdateEndpoint string
+	selfUpdateBundle   string
+	selfUpdateCheck    bool
+	selfUpdateForce    bool
+)
+
+// selfUpdateCmd replaces the installer binary with a newer signed release
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update the installer binary to the latest signed release",
+	Long: `Check the vendor release endpoint for a newer installer and replace this binary with it.
+
+The endpoint serves <channel>/release.json, listing the version and the binary and sha256
+checksum of each platform, and release.json.sig, its base64 ed25519 signature. The manifest
+signature is checked against the vendor key built into the installer (` + selfupdate.PublicKeyEnv + `
+in builds without one), the binary against its checksum, and the new binary must run before
+it atomically replaces the current one.
+
+Airgapped hosts update offline with --bundle, pointing at a directory or .tar.gz holding the
+same release.json, release.json.sig and binaries.
+
+Examples:
+  e2e-k8s-installer self-update --check
+  e2e-k8s-installer self-update --channel beta
+  e2e-k8s-installer self-update --bundle ./e2e-k8s-installer-1.5.0-bundle.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: runSelfUpdate,
+}
+
+func init() {
+	selfUpdateCmd.Flags().StringVar(&selfUpdateChannel, "channel", selfupdate.ChannelStable, "Release channel (stable, beta)")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateEndpoint, "endpoint", "", "Vendor release endpoint (default from the build or "+selfupdate.EndpointEnv+")")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateBundle, "bundle", "", "Update offline from a release bundle directory or .tar.gz")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "Only report whether a newer release is available")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "Install the release even if it is not newer")
+}
+
+func runSelfUpdate(cmd *cobra.Command, args []string) error {
+	updater, err := selfupdate.New(selfUpdateEndpoint, selfUpdateChannel, selfUpdateBundle)
+	if err != nil {
+		return err
+	}
+	defer updater.Close()
+
+	spinner, _ := pterm.DefaultSpinner.Start("Checking " + updater.Source() + "...")
+	release, err := updater.Latest()
+	if err != nil {
+		spinner.Fail("Failed to read the latest release")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Latest %s release: %s (installed: %s)", selfUpdateChannel, release.Version, version.Version))
+
+	newer, err := selfupdate.Newer(version.Version, release)
+	if err != nil && !selfUpdateForce {
+		return err
+	}
+	if !newer && !selfUpdateForce {
+		pterm.Info.Println("The installer is up to date")
+		return nil
+	}
+	if release.Notes != "" {
+		pterm.DefaultBox.WithTitle("Release " + release.Version).Println(release.Notes)
+	}
+	if selfUpdateCheck {
+		return nil
+	}
+
+	asset, err := release.PlatformAsset()
+	if err != nil {
+		return err
+	}
+	target, err := selfupdate.Executable()
+	if err != nil {
+		return err
+	}
+	if viper.GetBool("dry-run") {
+		pterm.Info.Printf("DRY RUN: Would replace %s with %s from %s\n", target, release.Version, asset.URL)
+		return nil
+	}
+
+	spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Installing %s...", release.Version))
+	if err := updater.Apply(asset, target); err != nil {
+		spinner.Fail("Update failed, the current binary was kept")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Updated %s to %s", target, release.Version))
+	logger.Info("Installer updated").
+		Str("from", version.Version).
+		Str("to", release.Version).
+		Str("channel", selfUpdateChannel).
+		Str("sha256", asset.SHA256).
+		Send()
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,59 +9,122 @@ import (
 	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
 	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/judebantony/e2e-k8s-installer/pkg/tools"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
 // setupCmd represents the set-up command
 var setupCmd = &cobra.Command{
-	Use:   "set-up",
-	Short: "Initialize workspace and generate sample configuration",
+	Use:     "set-up",
+	Aliases: []string{"setup"},
+	Short:   "Initialize workspace and generate sample configuration",
 	Long: `The set-up command initializes a new workspace for the K8s installer and generates
 a sample configuration file that can be customized for your specific deployment needs.
 
 This command will:
-1. Create the workspace directory structure
-2. Generate a sample installer-config.json file
-3. Validate prerequisites (kubectl, helm, terraform)
-4. Create necessary subdirectories for artifacts and logs
+1. Create the workspace layout (artifacts/, reports/, state/, bin/, cache/, logs/)
+2. Verify the workspace directories are writable
+3. Generate a sample installer-config.json file, keeping an existing one unless --force
+4. Optionally install pinned kubectl, helm and terraform binaries into bin/
+5. Validate prerequisites (kubectl, helm, terraform)
+6. Initialize the installation state file
+
+The install command runs the same workspace bootstrap as its first step.
 
 Example:
   k8s-installer set-up --workspace ./my-project
-  k8s-installer set-up --config-file custom-config.json`,
-	RunE: runSetup,
+  k8s-installer set-up --workspace prod-eu
+  k8s-installer set-up --config-file custom-config.json
+  k8s-installer set-up --install-tools`,
+	RunE: withExitCode(exitcode.Preflight, runSetup),
 }
 
 var (
-	setupWorkspace  string
-	setupConfigFile string
-	setupForce      bool
+	setupWorkspace    string
+	setupConfigFile   string
+	setupForce        bool
+	setupInstallTools bool
 )
 
+// workspaceDirs is the layout created under the workspace root
+var workspaceDirs = []string{
+	"artifacts/images",
+	"artifacts/helm",
+	"artifacts/terraform",
+	"artifacts/db-scripts",
+	"bin",
+	"cache",
+	"logs",
+	"reports",
+	"state",
+	"scripts",
+	"charts",
+	"terraform",
+	"tests",
+}
+
 func init() {
 	rootCmd.AddCommand(setupCmd)
 
-	setupCmd.Flags().StringVarP(&setupWorkspace, "workspace", "w", "./workspace", "Workspace directory path")
 	setupCmd.Flags().StringVarP(&setupConfigFile, "config-file", "c", "installer-config.json", "Configuration file name")
 	setupCmd.Flags().BoolVarP(&setupForce, "force", "f", false, "Force overwrite existing files")
+	setupCmd.Flags().BoolVar(&setupInstallTools, "install-tools", false, "Download pinned kubectl, helm and terraform into the workspace bin directory")
+}
+
+// bootstrapWorkspace creates the workspace layout, checks it is writable, installs
+// pinned tools when enabled and validates the prerequisites are on PATH
+func bootstrapWorkspace(workspace string, toolsCfg config.ToolsConfig) error {
+	if err := createWorkspaceStructure(workspace); err != nil {
+		return fmt.Errorf("workspace creation failed: %w", err)
+	}
+	if err := verifyWorkspaceWritable(workspace); err != nil {
+		return err
+	}
+
+	binDir := filepath.Join(workspace, "bin")
+	if toolsCfg.Install {
+		if err := installPinnedTools(workspace, toolsCfg); err != nil {
+			return err
+		}
+	}
+	// Tools installed by an earlier setup run take precedence over the system ones
+	if err := tools.PrependPath(binDir); err != nil {
+		return err
+	}
+
+	if err := validatePrerequisites(); err != nil {
+		return fmt.Errorf("prerequisite validation failed: %w", err)
+	}
+	return nil
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
+	setupWorkspace = selectedWorkspace("./workspace")
+
 	// Initialize progress manager
 	progress.InitGlobalProgressManager()
 	pm := progress.GetProgressManager()
 
-	// Show banner
-	progress.ShowBanner("1.0.0")
+	// Show banner, branded when the workspace already has a configuration
+	applyBranding(filepath.Join(setupWorkspace, setupConfigFile))
+	progress.ShowBanner(version.Version)
 
 	// Start overall progress area
 	pm.StartArea("setup")
 
 	steps := []string{
-		"Validate prerequisites",
 		"Create workspace structure",
+		"Verify write permissions",
 		"Generate configuration file",
+		"Install pinned tools",
+		"Validate prerequisites",
+		"Initialize state file",
 		"Initialize logging directories",
 		"Setup complete",
 	}
@@ -68,22 +132,7 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	currentStep := 0
 	progress.ShowStepProgress(steps, currentStep)
 
-	// Step 1: Validate prerequisites
-	pm.StartSpinner("prereq", "Validating prerequisites...")
-	logger.StepStart("validate-prerequisites")
-
-	if err := validatePrerequisites(); err != nil {
-		pm.FailSpinner("prereq", "Prerequisites validation failed")
-		logger.StepFailed("validate-prerequisites", err)
-		return fmt.Errorf("prerequisite validation failed: %w", err)
-	}
-
-	pm.SuccessSpinner("prereq", "Prerequisites validated successfully")
-	logger.StepComplete("validate-prerequisites", 0)
-	currentStep++
-	progress.ShowStepProgress(steps, currentStep)
-
-	// Step 2: Create workspace structure
+	// Step 1: Create workspace structure
 	pm.StartSpinner("workspace", "Creating workspace structure...")
 	logger.StepStart("create-workspace")
 
@@ -98,6 +147,21 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	currentStep++
 	progress.ShowStepProgress(steps, currentStep)
 
+	// Step 2: Verify write permissions
+	pm.StartSpinner("permissions", "Verifying write permissions...")
+	logger.StepStart("verify-permissions")
+
+	if err := verifyWorkspaceWritable(setupWorkspace); err != nil {
+		pm.FailSpinner("permissions", "Workspace is not writable")
+		logger.StepFailed("verify-permissions", err)
+		return err
+	}
+
+	pm.SuccessSpinner("permissions", "Workspace is writable")
+	logger.StepComplete("verify-permissions", 0)
+	currentStep++
+	progress.ShowStepProgress(steps, currentStep)
+
 	// Step 3: Generate configuration file
 	configPath := filepath.Join(setupWorkspace, setupConfigFile)
 	pm.StartSpinner("config", "Generating configuration file...")
@@ -109,12 +173,64 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("configuration generation failed: %w", err)
 	}
 
-	pm.SuccessSpinner("config", "Configuration file generated")
+	pm.SuccessSpinner("config", "Configuration file ready")
 	logger.StepComplete("generate-config", 0)
 	currentStep++
 	progress.ShowStepProgress(steps, currentStep)
 
-	// Step 4: Initialize logging directories
+	// Step 4: Install pinned tools
+	if setupInstallTools {
+		pm.StartSpinner("tools", "Installing pinned tools...")
+		logger.StepStart("install-tools")
+
+		if err := installPinnedTools(setupWorkspace, config.ToolsConfig{Install: true}); err != nil {
+			pm.FailSpinner("tools", "Tool installation failed")
+			logger.StepFailed("install-tools", err)
+			return err
+		}
+
+		pm.SuccessSpinner("tools", "Pinned tools installed")
+		logger.StepComplete("install-tools", 0)
+	} else {
+		logger.Info("Skipping tool installation (use --install-tools to download pinned binaries)").Send()
+	}
+	if err := tools.PrependPath(filepath.Join(setupWorkspace, "bin")); err != nil {
+		return err
+	}
+	currentStep++
+	progress.ShowStepProgress(steps, currentStep)
+
+	// Step 5: Validate prerequisites
+	pm.StartSpinner("prereq", "Validating prerequisites...")
+	logger.StepStart("validate-prerequisites")
+
+	if err := validatePrerequisites(); err != nil {
+		pm.FailSpinner("prereq", "Prerequisites validation failed")
+		logger.StepFailed("validate-prerequisites", err)
+		return fmt.Errorf("prerequisite validation failed: %w", err)
+	}
+
+	pm.SuccessSpinner("prereq", "Prerequisites validated successfully")
+	logger.StepComplete("validate-prerequisites", 0)
+	currentStep++
+	progress.ShowStepProgress(steps, currentStep)
+
+	// Step 6: Initialize state file
+	pm.StartSpinner("state", "Initializing state file...")
+	logger.StepStart("init-state")
+
+	if err := initializeStateFile(setupWorkspace); err != nil {
+		pm.FailSpinner("state", "State file initialization failed")
+		logger.StepFailed("init-state", err)
+		return fmt.Errorf("state file initialization failed: %w", err)
+	}
+
+	pm.SuccessSpinner("state", "State file initialized")
+	logger.StepComplete("init-state", 0)
+	currentStep++
+	progress.ShowStepProgress(steps, currentStep)
+
+	// Step 7: Initialize logging directories
 	pm.StartSpinner("logging", "Initializing logging directories...")
 	logger.StepStart("init-logging")
 
@@ -153,20 +269,26 @@ func validatePrerequisites() error {
 
 	// Check for required tools
 	tools := []struct {
-		name    string
-		command string
-		version string
+		name     string
+		command  string
+		version  string
+		optional bool
 	}{
-		{"kubectl", "kubectl", "version --client=true"},
-		{"helm", "helm", "version"},
-		{"terraform", "terraform", "version"},
+		// The cluster is reached through its API, kubectl only serves port-forwards and log streams
+		{"kubectl", "kubectl", "version --client=true", true},
+		{"helm", "helm", "version", false},
+		{"terraform", "terraform", "version", false},
 	}
 
 	for _, tool := range tools {
 		logger.Debug("Checking tool").Str("tool", tool.name).Send()
 
 		// Check if tool exists in PATH
-		if _, err := exec.LookPath(tool.command); err != nil {
+		if _, err := execx.LookPath(tool.command); err != nil {
+			if tool.optional {
+				logger.Warn("Tool not found in PATH - some features may be limited").Str("tool", tool.name).Send()
+				continue
+			}
 			return fmt.Errorf("%s not found in PATH - please install %s", tool.command, tool.name)
 		}
 
@@ -190,21 +312,7 @@ func createWorkspaceStructure(workspace string) error {
 	}
 
 	// Create subdirectories
-	subdirs := []string{
-		"artifacts/images",
-		"artifacts/helm",
-		"artifacts/terraform",
-		"artifacts/db-scripts",
-		"logs",
-		"reports",
-		"state",
-		"scripts",
-		"charts",
-		"terraform",
-		"tests",
-	}
-
-	for _, subdir := range subdirs {
+	for _, subdir := range workspaceDirs {
 		path := filepath.Join(workspace, subdir)
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", path, err)
@@ -219,6 +327,8 @@ logs/
 state/
 reports/
 artifacts/
+bin/
+cache/
 *.log
 
 # Sensitive files
@@ -251,9 +361,10 @@ Thumbs.db
 func generateConfigFile(configPath string) error {
 	logger.Info("Generating configuration file").Str("path", configPath).Send()
 
-	// Check if file exists and force flag
+	// Keep an existing configuration so setup can be rerun on a workspace
 	if _, err := os.Stat(configPath); err == nil && !setupForce {
-		return fmt.Errorf("configuration file already exists at %s (use --force to overwrite)", configPath)
+		logger.Info("Keeping existing configuration file (use --force to overwrite)").Str("path", configPath).Send()
+		return nil
 	}
 
 	// Generate default configuration
@@ -277,6 +388,52 @@ func generateConfigFile(configPath string) error {
 	return nil
 }
 
+// verifyWorkspaceWritable writes and removes a probe file in each workspace directory
+func verifyWorkspaceWritable(workspace string) error {
+	dirs := append([]string{"."}, workspaceDirs...)
+	for _, dir := range dirs {
+		path := filepath.Join(workspace, dir)
+		probe, err := os.CreateTemp(path, ".write-check-*")
+		if err != nil {
+			return fmt.Errorf("workspace directory %s is not writable: %w", path, err)
+		}
+		probe.Close()
+		if err := os.Remove(probe.Name()); err != nil {
+			return fmt.Errorf("failed to remove write probe in %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// installPinnedTools downloads the configured tool versions into the workspace bin directory
+func installPinnedTools(workspace string, toolsCfg config.ToolsConfig) error {
+	binDir := filepath.Join(workspace, "bin")
+	cacheDir := filepath.Join(workspace, "cache", "tools")
+
+	for _, tool := range tools.Pinned(toolsCfg) {
+		if _, err := tools.Install(tool, binDir, cacheDir); err != nil {
+			return fmt.Errorf("failed to install %s %s: %w", tool.Name, tool.Version, err)
+		}
+	}
+	return nil
+}
+
+// initializeStateFile writes an empty installation state unless one already exists
+func initializeStateFile(dir string) error {
+	stateFile := filepath.Join(dir, workspace.StateFileName)
+	return workspace.UpdateState(stateFile, func(sections map[string]json.RawMessage) error {
+		if len(sections) > 0 {
+			logger.Info("Keeping existing state file").Str("path", stateFile).Send()
+			return nil
+		}
+		return workspace.ReplaceState(sections, config.InstallState{
+			Steps:     []config.StepState{},
+			StartTime: time.Now(),
+			Status:    "pending",
+		})
+	})
+}
+
 func initializeLoggingDirs(workspace string) error {
 	logger.Info("Initializing logging directories").Str("workspace", workspace).Send()
 
@@ -306,6 +463,8 @@ This workspace was created by the K8s installer set-up command.
 ## Directory Structure
 
 - artifacts/ - Downloaded and synchronized artifacts (images, charts, terraform)
+- bin/ - Pinned kubectl, helm and terraform binaries (set-up --install-tools)
+- cache/ - Download cache for tools and artifacts
 - charts/ - Helm charts for deployment
 - logs/ - Installation logs and reports
 - reports/ - Test and validation reports
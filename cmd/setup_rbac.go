@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setupRBACKubeconfig     string
+	setupRBACContext        string
+	setupRBACNamespace      string
+	setupRBACServiceAccount string
+	setupRBACSteps          []string
+	setupRBACTokenDuration  time.Duration
+	setupRBACKubeconfigOut  string
+	setupRBACPrintOnly      bool
+)
+
+// setupRBACCmd represents the set-up rbac command
+var setupRBACCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Create a least-privilege installer identity and kubeconfig",
+	Long: `Create a dedicated ServiceAccount for the installer bound to the generated
+least-privilege ClusterRole, mint a kubeconfig that authenticates as it, and
+switch subsequent steps to that identity so installs don't run as cluster-admin.
+
+This command must be run once with an identity that is allowed to create RBAC
+objects. The generated kubeconfig is written to ` + k8s.InstallerKubeconfigFile + `
+in the workspace and is picked up automatically by every step on that workspace
+that has no explicit kubeconfig or context configured.
+
+Examples:
+  e2e-k8s-installer setup rbac
+  e2e-k8s-installer setup rbac --steps deploy,post-validate --token-duration 8h
+  e2e-k8s-installer setup rbac --print-only > installer-rbac.yaml`,
+	RunE: runSetupRBAC,
+}
+
+func init() {
+	setupCmd.AddCommand(setupRBACCmd)
+
+	setupRBACCmd.Flags().StringVar(&setupRBACKubeconfig, "kubeconfig", "", "Admin kubeconfig used to create the installer identity")
+	setupRBACCmd.Flags().StringVar(&setupRBACContext, "context", "", "Kubernetes context to use")
+	setupRBACCmd.Flags().StringVar(&setupRBACNamespace, "namespace", "e2e-k8s-installer", "Namespace for the installer ServiceAccount")
+	setupRBACCmd.Flags().StringVar(&setupRBACServiceAccount, "service-account", defaultInstallerRoleName, "Name of the installer ServiceAccount and ClusterRole")
+	setupRBACCmd.Flags().StringSliceVar(&setupRBACSteps, "steps", []string{"deploy", "db-migrate", "post-validate", "e2e-test"}, "Installation steps the identity must be able to run")
+	setupRBACCmd.Flags().DurationVar(&setupRBACTokenDuration, "token-duration", 24*time.Hour, "Lifetime of the minted ServiceAccount token")
+	setupRBACCmd.Flags().StringVar(&setupRBACKubeconfigOut, "kubeconfig-out", "", "Where to write the installer kubeconfig (default "+k8s.InstallerKubeconfigFile+" in the workspace)")
+	setupRBACCmd.Flags().BoolVar(&setupRBACPrintOnly, "print-only", false, "Print the RBAC manifests without applying them")
+}
+
+func runSetupRBAC(cmd *cobra.Command, args []string) error {
+	manifests := k8s.InstallerIdentityManifests(setupRBACNamespace, setupRBACServiceAccount, setupRBACSteps)
+
+	if setupRBACPrintOnly {
+		fmt.Print(manifests)
+		return nil
+	}
+
+	progress.InitGlobalProgressManager()
+	pm := progress.GetProgressManager()
+
+	// Always act as the admin identity, never a previously generated installer kubeconfig
+	kubeconfig := setupRBACKubeconfig
+	if kubeconfig == "" {
+		kubeconfig = ambientKubeconfig()
+	}
+
+	k8sMgr, err := k8s.NewManager(&config.K8sConfig{
+		ConfigPath: kubeconfig,
+		Context:    setupRBACContext,
+	})
+	if err != nil {
+		return err
+	}
+
+	pm.StartSpinner("rbac-apply", "Creating installer ServiceAccount and ClusterRole...")
+	logger.StepStart("setup-rbac")
+	if err := k8sMgr.ApplyManifest(manifests); err != nil {
+		pm.FailSpinner("rbac-apply", "Failed to create installer identity")
+		logger.StepFailed("setup-rbac", err)
+		return fmt.Errorf("failed to create installer identity: %w", err)
+	}
+	pm.SuccessSpinner("rbac-apply", "Installer identity created")
+
+	pm.StartSpinner("rbac-token", "Minting installer kubeconfig...")
+	cluster, err := k8sMgr.CurrentCluster()
+	if err != nil {
+		pm.FailSpinner("rbac-token", "Failed to read cluster connection details")
+		logger.StepFailed("setup-rbac", err)
+		return err
+	}
+
+	token, err := k8sMgr.CreateToken(setupRBACNamespace, setupRBACServiceAccount, setupRBACTokenDuration)
+	if err != nil {
+		pm.FailSpinner("rbac-token", "Failed to mint ServiceAccount token")
+		logger.StepFailed("setup-rbac", err)
+		return fmt.Errorf("failed to mint ServiceAccount token: %w", err)
+	}
+
+	identity := installerKubeconfig()
+	out := valueOr(setupRBACKubeconfigOut, identity)
+	if err := k8s.WriteKubeconfig(out, cluster, setupRBACNamespace, setupRBACServiceAccount, token); err != nil {
+		pm.FailSpinner("rbac-token", "Failed to write installer kubeconfig")
+		logger.StepFailed("setup-rbac", err)
+		return err
+	}
+	pm.SuccessSpinner("rbac-token", "Installer kubeconfig written")
+	logger.StepComplete("setup-rbac", 0)
+
+	progress.ShowSuccess("🔐 Installer identity configured")
+	fmt.Printf("\n👤 ServiceAccount: %s/%s\n", setupRBACNamespace, setupRBACServiceAccount)
+	fmt.Printf("🔑 Kubeconfig: %s (expires in %s)\n", out, setupRBACTokenDuration)
+	if out != identity {
+		fmt.Printf("\n📝 Set kubernetes.configPath to %s to use this identity\n", out)
+	} else {
+		fmt.Println("\n📝 Subsequent steps will run as this identity unless a kubeconfig or context is configured")
+	}
+
+	return nil
+}
+
+// installerKubeconfig is where setup rbac writes the installer identity of the selected
+// workspace, and where the other commands on the workspace look for it
+func installerKubeconfig() string {
+	return filepath.Join(selectedWorkspace("./workspace"), k8s.InstallerKubeconfigFile)
+}
+
+// ambientKubeconfig returns the kubeconfig kubectl would use without an installer identity
+func ambientKubeconfig() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 {
+		return paths[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
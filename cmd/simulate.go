@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/simulate"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+)
+
+var (
+	simulateFlag bool
+
+	// simulation is the fake environment of a --simulate run, nil otherwise
+	simulation *simulate.Environment
+)
+
+// simulationDir keeps the state, reports and logs of simulated runs: the simulation
+// directory of the selected workspace, or ./simulation without one
+func simulationDir() string {
+	root := "."
+	if workspaceFlag != "" {
+		root = workspace.Resolve(workspaceFlag)
+	}
+	return filepath.Join(root, "simulation")
+}
+
+// startSimulation routes every tool and HTTP request of the run to the fake environment
+// when --simulate is set
+func startSimulation() error {
+	if !simulateFlag {
+		return nil
+	}
+	env, err := simulate.Start(simulationDir())
+	if err != nil {
+		return err
+	}
+	simulation = env
+	pterm.Info.Printf("Simulating: no real infrastructure is touched (state in %s)\n", simulationDir())
+	return nil
+}
+
+// stopSimulation saves the fake environment for the next simulated run
+func stopSimulation() {
+	if simulation == nil {
+		return
+	}
+	if err := simulation.Stop(); err != nil {
+		logger.Warn("Failed to save the simulated environment").Err(err).Send()
+	}
+	simulation = nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/telemetry"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// telemetryCmd represents the telemetry command
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Show or change the anonymous usage statistics setting",
+	Long: `Telemetry is off until you enable it. When enabled, each run reports an anonymous
+summary to the vendor to help prioritize reliability work:
+
+  - installer version, OS and architecture
+  - the command run, without its arguments or flags
+  - run and step outcomes, durations and failure categories (timeout, network, ...)
+  - a random installation ID created on enable and deleted on disable
+
+Configuration, hostnames, paths, resource names and error messages are never sent. Events
+that cannot be delivered, such as in airgapped environments, are queued and retried on later
+runs; at most 200 are kept. Setting ` + telemetry.DisableEnv + `=0 or DO_NOT_TRACK=1 turns
+telemetry off regardless of this setting.`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether telemetry is enabled",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryStatus,
+}
+
+var telemetryEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Opt in to anonymous usage statistics",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if telemetry.CollectorURL() == "" {
+			return fmt.Errorf("this build has no telemetry endpoint, set %s to enable telemetry", telemetry.EndpointEnv)
+		}
+		if _, err := telemetry.SetEnabled(true); err != nil {
+			return err
+		}
+		pterm.Success.Println("Telemetry enabled, thank you. Run 'e2e-k8s-installer telemetry disable' to opt out.")
+		if telemetry.DisabledByEnvironment() {
+			pterm.Warning.Printf("Telemetry stays off while %s or DO_NOT_TRACK disables it\n", telemetry.DisableEnv)
+		}
+		return nil
+	},
+}
+
+var telemetryDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Opt out and delete queued events",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := telemetry.SetEnabled(false); err != nil {
+			return err
+		}
+		pterm.Success.Println("Telemetry disabled, the installation ID and queued events were deleted")
+		return nil
+	},
+}
+
+func init() {
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+	telemetryCmd.AddCommand(telemetryEnableCmd)
+	telemetryCmd.AddCommand(telemetryDisableCmd)
+}
+
+func runTelemetryStatus(cmd *cobra.Command, args []string) error {
+	settings, err := telemetry.LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	state := "disabled"
+	switch {
+	case settings.Enabled && telemetry.DisabledByEnvironment():
+		state = "disabled by environment"
+	case settings.Enabled && telemetry.CollectorURL() == "":
+		state = "enabled, but this build has no endpoint"
+	case settings.Enabled:
+		state = "enabled"
+	}
+	decided := "never"
+	if !settings.DecidedAt.IsZero() {
+		decided = settings.DecidedAt.Format("2006-01-02 15:04:05")
+	}
+	endpoint := telemetry.CollectorURL()
+	if endpoint == "" {
+		endpoint = "none"
+	}
+	installID := settings.InstallID
+	if installID == "" {
+		installID = "-"
+	}
+
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Property", "Value"},
+		{"Telemetry", state},
+		{"Decided", decided},
+		{"Installation ID", installID},
+		{"Endpoint", endpoint},
+		{"Queued Events", fmt.Sprintf("%d", telemetry.Pending())},
+		{"Settings", telemetry.SettingsPath()},
+	}).Render()
+	return nil
+}
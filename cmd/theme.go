@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/progress"
+)
+
+var (
+	themeFlag string
+
+	// themeChosen is set when --theme or the environment picked the theme, which then wins
+	// over the configuration
+	themeChosen bool
+)
+
+// startTheme applies --theme, or E2E_INSTALLER_THEME; NO_COLOR selects the monochrome theme
+func startTheme() error {
+	name := themeFlag
+	if name == "" {
+		name = os.Getenv("E2E_INSTALLER_THEME")
+	}
+	if name == "" && os.Getenv("NO_COLOR") != "" {
+		name = progress.ThemeMonochrome
+	}
+	if name == "" {
+		return nil
+	}
+	themeChosen = true
+	return progress.SetTheme(name)
+}
+
+// applyConfiguredTheme switches to the theme named in the configuration, unless --theme or
+// the environment chose one
+func applyConfiguredTheme(name string) {
+	if name == "" || themeChosen {
+		return
+	}
+	if err := progress.SetTheme(name); err != nil {
+		logger.Warn("Ignoring the configured theme").Err(err).Send()
+	}
+}
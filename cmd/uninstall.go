@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/helm"
+	"github.com/judebantony/e2e-k8s-installer/pkg/infrastructure"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/localcluster"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallConfigPath string
+	uninstallChartsOnly []string
+	uninstallTimeout    time.Duration
+	uninstallDryRun     bool
+	uninstallPurgeCRDs  bool
+	uninstallInstaller  string
+	uninstallKeepLocal  bool
+	uninstallOverride   string
+)
+
+// uninstallCmd represents the uninstall command
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Uninstall deployed Helm releases",
+	Long: `Uninstall the Helm releases created by deploy, in reverse deployment order.
+
+CRDs shipped with the charts are left in place so that custom resources owned
+by other workloads are not deleted with them. Pass --purge-crds to remove them
+as well, which deletes every resource of those kinds cluster-wide.
+
+When the installer configuration uses the local provision mode, the kind or k3d
+cluster and its registry are deleted instead, taking every release with them.
+Pass --keep-cluster to uninstall only the releases.
+
+Releases in namespaces frozen with 'freeze set' are not removed unless
+--override-freeze gives a reason, which is recorded in the audit log.
+
+Examples:
+  e2e-k8s-installer uninstall
+  e2e-k8s-installer uninstall --charts-only backend
+  e2e-k8s-installer uninstall --purge-crds`,
+	RunE: runUninstall,
+}
+
+func init() {
+	uninstallCmd.Flags().StringVar(&uninstallConfigPath, "config", "", "Path to deployment configuration file")
+	uninstallCmd.Flags().StringSliceVar(&uninstallChartsOnly, "charts-only", []string{}, "Uninstall only specified charts (comma-separated)")
+	uninstallCmd.Flags().DurationVar(&uninstallTimeout, "timeout", 5*time.Minute, "Timeout for each release uninstall")
+	uninstallCmd.Flags().BoolVar(&uninstallDryRun, "dry-run", false, "Show what would be removed without removing it")
+	uninstallCmd.Flags().BoolVar(&uninstallPurgeCRDs, "purge-crds", false, "Also delete CRDs shipped with the charts and all their resources")
+	uninstallCmd.Flags().StringVar(&uninstallInstaller, "installer-config", "installer-config.json", "Installer configuration, read to tear down a local cluster")
+	uninstallCmd.Flags().BoolVar(&uninstallKeepLocal, "keep-cluster", false, "Keep the local cluster and uninstall only the releases")
+	uninstallCmd.Flags().StringVar(&uninstallOverride, "override-freeze", "", "Uninstall from frozen namespaces, giving the reason recorded in the audit log")
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	if !uninstallKeepLocal {
+		localMgr, err := localClusterToDelete(cmd)
+		if err != nil {
+			return err
+		}
+		if localMgr != nil {
+			return deleteLocalCluster(localMgr)
+		}
+	}
+
+	cfg, err := loadDeployConfig(uninstallConfigPath)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := initWorkspaceParameters(selectedWorkspace("./workspace")); err != nil {
+		return err
+	}
+
+	charts := cfg.Helm.Charts
+	if len(uninstallChartsOnly) > 0 {
+		selected := make(map[string]bool)
+		for _, name := range uninstallChartsOnly {
+			selected[strings.TrimSpace(name)] = true
+		}
+		var filtered []config.DeployChart
+		for _, chart := range charts {
+			if selected[chart.Name] {
+				filtered = append(filtered, chart)
+			}
+		}
+		charts = filtered
+	}
+
+	// Remove dependents before the charts they depend on
+	sort.SliceStable(charts, func(i, j int) bool {
+		return charts[i].Order > charts[j].Order
+	})
+
+	var namespaces []string
+	for _, chart := range charts {
+		namespace := chart.Namespace
+		if namespace == "" {
+			namespace = cfg.Kubernetes.Namespace
+		}
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	releaseFreeze, err := checkNamespaceFreeze(&cfg.Kubernetes, namespaces, "uninstall", uninstallOverride, uninstallDryRun)
+	if err != nil {
+		return err
+	}
+	defer releaseFreeze()
+
+	var helmMgr *helm.Manager
+	if !uninstallDryRun {
+		helmMgr, err = helm.NewManager(&cfg.Kubernetes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, chart := range charts {
+		if uninstallDryRun {
+			pterm.Info.Printf("DRY RUN: would uninstall release %s/%s\n", chart.Namespace, chart.Name)
+			continue
+		}
+
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Uninstalling %s/%s...", chart.Namespace, chart.Name))
+		if err := helmMgr.Uninstall(chart.Name, chart.Namespace, uninstallTimeout); err != nil {
+			spinner.Fail(fmt.Sprintf("Failed to uninstall %s", chart.Name))
+			return fmt.Errorf("failed to uninstall release %s: %w", chart.Name, err)
+		}
+		spinner.Success(fmt.Sprintf("Uninstalled %s/%s", chart.Namespace, chart.Name))
+	}
+
+	return handleChartCRDs(&cfg.Kubernetes, charts)
+}
+
+// localClusterToDelete returns the local cluster of the installer configuration, nil when
+// it does not use the local provision mode or the configuration does not exist
+func localClusterToDelete(cmd *cobra.Command) (*localcluster.Manager, error) {
+	path := workspaceDefault(cmd, "installer-config", uninstallInstaller, workspace.ConfigFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to load installer configuration: %w", err))
+	}
+	if cfg.Infrastructure.ProvisionMode != infrastructure.ProvisionModeLocal {
+		return nil, nil
+	}
+	if err := applyWorkspace(cfg); err != nil {
+		return nil, err
+	}
+	if err := audit.InitGlobalAuditLog(cfg.Installer.Workspace, cfg.Installer.Audit); err != nil {
+		return nil, fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+	return localcluster.NewManager(cfg.Infrastructure.Local), nil
+}
+
+func deleteLocalCluster(localMgr *localcluster.Manager) error {
+	if uninstallDryRun {
+		pterm.Info.Printf("DRY RUN: would delete local %s cluster %s and its releases\n", localMgr.Tool(), localMgr.Name())
+		return nil
+	}
+
+	exists, err := localMgr.Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		pterm.Info.Printf("Local %s cluster %s does not exist\n", localMgr.Tool(), localMgr.Name())
+		return nil
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Deleting local %s cluster %s...", localMgr.Tool(), localMgr.Name()))
+	if err := localMgr.Delete(); err != nil {
+		spinner.Fail("Failed to delete local cluster")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Deleted local %s cluster %s", localMgr.Tool(), localMgr.Name()))
+	return nil
+}
+
+// handleChartCRDs deletes chart CRDs only when explicitly asked to and otherwise reports them as kept
+func handleChartCRDs(k8sConfig *config.K8sConfig, charts []config.DeployChart) error {
+	var crds []k8s.CRD
+	for _, chart := range charts {
+		if remoteChart(chart.Path) {
+			continue
+		}
+		chartCRDs, err := k8s.ExtractCRDs(chart.Path)
+		if err != nil {
+			logger.Warn("Failed to read chart CRDs").Str("chart", chart.Name).Err(err).Send()
+			continue
+		}
+		crds = append(crds, chartCRDs...)
+	}
+	if len(crds) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(crds))
+	for i, crd := range crds {
+		names[i] = crd.Name
+	}
+
+	if !uninstallPurgeCRDs {
+		pterm.Info.Printf("Kept %d CRD(s): %s (use --purge-crds to delete them)\n", len(crds), strings.Join(names, ", "))
+		return nil
+	}
+
+	if uninstallDryRun {
+		pterm.Info.Printf("DRY RUN: would delete CRDs %s\n", strings.Join(names, ", "))
+		return nil
+	}
+
+	k8sMgr, err := k8s.NewManager(k8sConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+	if err := k8sMgr.DeleteCRDs(crds); err != nil {
+		return fmt.Errorf("failed to delete CRDs: %w", err)
+	}
+
+	pterm.Warning.Printf("Deleted %d CRD(s) and their resources: %s\n", len(crds), strings.Join(names, ", "))
+	return nil
+}
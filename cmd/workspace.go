@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/tuning"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var workspaceListOutput string
+
+// workspaceCmd represents the workspace command
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage named workspaces",
+	Long: `Named workspaces keep the state, caches, locks and reports of each environment
+apart, so one host can manage several environments. Pass --workspace <name> to any
+command to use the workspace at $E2E_INSTALLER_HOME/workspaces/<name>
+(~/.e2e-k8s-installer/workspaces/<name> by default); its installer-config.json is
+used unless --config is given. Values containing a path separator are used as
+workspace directories directly.
+
+Examples:
+  e2e-k8s-installer setup --workspace prod-eu
+  e2e-k8s-installer install --workspace prod-eu
+  e2e-k8s-installer workspace list`,
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List named workspaces and their last run status",
+	RunE:  runWorkspaceList,
+}
+
+func init() {
+	workspaceListCmd.Flags().StringVarP(&workspaceListOutput, "output", "o", "table", "Output format (table, json)")
+	workspaceCmd.AddCommand(workspaceListCmd)
+}
+
+func runWorkspaceList(cmd *cobra.Command, args []string) error {
+	infos, err := workspace.List()
+	if err != nil {
+		return err
+	}
+
+	if workspaceListOutput == "json" {
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal workspaces: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(infos) == 0 {
+		pterm.Info.Printf("No workspaces in %s (create one with: setup --workspace <name>)\n", filepath.Join(workspace.Home(), "workspaces"))
+		return nil
+	}
+
+	tableData := pterm.TableData{{"Name", "Status", "Last Run", "Config", "Locked By", "Path"}}
+	for _, info := range infos {
+		lastRun := "-"
+		if info.LastRun != nil {
+			lastRun = info.LastRun.Format(time.RFC3339)
+		}
+		hasConfig := "missing"
+		if info.HasConfig {
+			hasConfig = "yes"
+		}
+		lockedBy := "-"
+		if info.LockedBy != nil {
+			lockedBy = fmt.Sprintf("%s (pid %d)", info.LockedBy.Command, info.LockedBy.PID)
+		}
+		tableData = append(tableData, []string{info.Name, info.Status, lastRun, hasConfig, lockedBy, info.Path})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// selectedWorkspace returns the directory chosen with --workspace, or fallback when it is not set.
+// Simulated runs use the simulation directory instead, so they leave the real state alone.
+func selectedWorkspace(fallback string) string {
+	if simulateFlag {
+		return simulationDir()
+	}
+	if workspaceFlag == "" {
+		return fallback
+	}
+	return workspace.Resolve(workspaceFlag)
+}
+
+// workspaceConfigFile returns the configuration file to load: the command's --config when given,
+// otherwise the selected workspace's configuration file if one was selected
+func workspaceConfigFile(cmd *cobra.Command, configFile string) string {
+	if workspaceFlag == "" || cmd.Flags().Changed("config") {
+		return configFile
+	}
+	return filepath.Join(workspace.Resolve(workspaceFlag), workspace.ConfigFileName)
+}
+
+// applyWorkspace points the configuration at the selected workspace so state, caches and
+// reports stay inside it regardless of the workspace recorded in the file. Configuration
+// wide output settings such as the theme take effect here too.
+func applyWorkspace(cfg *config.InstallerConfig) error {
+	applyConfiguredTheme(cfg.Installer.Theme)
+	applyConfiguredLocale(cfg.Installer.Locale)
+	if _, err := tuning.Apply(cfg.Installer.Resources); err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	if workspaceFlag != "" {
+		dir := workspace.Resolve(workspaceFlag)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return fmt.Errorf("workspace %s does not exist at %s (create it with: setup --workspace %s)", workspaceFlag, dir, workspaceFlag)
+		}
+	}
+	if workspaceFlag != "" || simulateFlag {
+		cfg.Installer.Workspace = selectedWorkspace("")
+	}
+	return nil
+}
+
+// initWorkspaceParameters loads the parameters earlier steps published, and the rollback
+// journal, from the state file of workspace dir, for commands run on their own rather than
+// by install
+func initWorkspaceParameters(dir string) error {
+	if err := params.InitGlobalStore(filepath.Join(dir, workspace.StateFileName), true); err != nil {
+		return fmt.Errorf("failed to load installation parameters: %w", err)
+	}
+	return nil
+}
+
+// runWorkspace is the workspace of the running command: the selected workspace, otherwise the
+// workspace of the command's configuration
+func runWorkspace() string {
+	if commandWorkspace != "" {
+		return commandWorkspace
+	}
+	return selectedWorkspace("./workspace")
+}
+
+// reportsDir is where commands write their reports and the run registry is kept: the reports
+// directory of the run's workspace
+func reportsDir() string {
+	return filepath.Join(runWorkspace(), "reports")
+}
+
+// toolLogsDir is where the output of helm, terraform and make is logged for this run, one
+// file per step: logs/<run ID> in the run's workspace
+func toolLogsDir() string {
+	return filepath.Join(runWorkspace(), "logs", history.RunID())
+}
+
+// workspaceDefault returns the path of a file inside the selected workspace when the flag
+// was left at its default, so per-environment inputs are read from the right workspace
+func workspaceDefault(cmd *cobra.Command, flag, value, rel string) string {
+	if (workspaceFlag == "" && !simulateFlag) || cmd.Flags().Changed(flag) {
+		return value
+	}
+	return filepath.Join(selectedWorkspace(""), rel)
+}
@@ -3,13 +3,26 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/judebantony/e2e-k8s-installer/cmd"
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+	"github.com/judebantony/e2e-k8s-installer/pkg/i18n"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+	"github.com/pterm/pterm"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, i18n.Tf("Error: %v", err))
+		if tail := logtail.Of(err); tail != nil {
+			pterm.DefaultBox.WithWriter(os.Stderr).
+				WithTitle(i18n.Tf("Last %d lines of %s output", len(tail.LogTail), tail.Tool)).
+				Println(strings.Join(tail.LogTail, "\n"))
+		}
+		if remediation := exitcode.Remediation(exitcode.Code(err)); remediation != "" {
+			fmt.Fprintln(os.Stderr, i18n.Tf("Hint: %s", i18n.T(remediation)))
+		}
+		os.Exit(exitcode.Code(err))
 	}
 }
@@ -0,0 +1,341 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Manager runs Ansible playbooks for infrastructure operations
+type Manager struct {
+	config       *config.AnsibleExecution
+	workingDir   string
+	playbookPath string
+	env          []string
+	results      []PlaybookResult
+}
+
+// HostRecap is one host's line of a PLAY RECAP
+type HostRecap struct {
+	Host        string `json:"host"`
+	Ok          int    `json:"ok"`
+	Changed     int    `json:"changed"`
+	Unreachable int    `json:"unreachable"`
+	Failed      int    `json:"failed"`
+	Skipped     int    `json:"skipped"`
+	Rescued     int    `json:"rescued"`
+	Ignored     int    `json:"ignored"`
+}
+
+// PlaybookResult is the outcome of one playbook run
+type PlaybookResult struct {
+	Playbook  string      `json:"playbook"`
+	Operation string      `json:"operation"`
+	Check     bool        `json:"check"`
+	Hosts     []HostRecap `json:"hosts"`
+	Duration  string      `json:"duration"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Changed returns the number of changed tasks across hosts
+func (r PlaybookResult) Changed() int {
+	total := 0
+	for _, host := range r.Hosts {
+		total += host.Changed
+	}
+	return total
+}
+
+// Operation names recorded in results
+const (
+	OperationPlan     = "plan"
+	OperationApply    = "apply"
+	OperationDestroy  = "destroy"
+	OperationValidate = "validate"
+)
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	recapLine  = regexp.MustCompile(`^(\S+)\s*:\s*((?:\w+=\d+\s*)+)$`)
+)
+
+// NewManager creates a new Ansible manager
+func NewManager(ansibleConfig *config.AnsibleExecution) (*Manager, error) {
+	if ansibleConfig == nil {
+		return nil, fmt.Errorf("ansible configuration is required")
+	}
+	if !ansibleConfig.Enabled {
+		return nil, fmt.Errorf("ansible execution is not enabled")
+	}
+
+	workingDir := ansibleConfig.WorkingDirectory
+	if workingDir == "" {
+		workingDir = "."
+	}
+
+	playbookPath, err := execx.LookPath("ansible-playbook")
+	if err != nil {
+		return nil, fmt.Errorf("ansible-playbook command not found in PATH: %w", err)
+	}
+
+	// Keep host key prompts and colour codes out of unattended runs
+	env := []string{"ANSIBLE_HOST_KEY_CHECKING=False", "ANSIBLE_NOCOLOR=1"}
+	for key, value := range ansibleConfig.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return &Manager{
+		config:       ansibleConfig,
+		workingDir:   workingDir,
+		playbookPath: playbookPath,
+		env:          env,
+	}, nil
+}
+
+// Init checks the syntax of every configured playbook
+func (m *Manager) Init(dryRun bool) error {
+	args, err := m.playbookArgs()
+	if err != nil {
+		return err
+	}
+	playbooks := m.config.Playbooks
+	all := append(append(append([]string{}, playbooks.Apply...), playbooks.Destroy...), playbooks.Validate...)
+	for _, playbook := range all {
+		if _, err := m.run(playbook, append(args, "--syntax-check")...); err != nil {
+			return fmt.Errorf("playbook %s failed the syntax check: %w", playbook, err)
+		}
+	}
+	return nil
+}
+
+// Plan runs the apply playbooks in check mode, reporting what they would change
+func (m *Manager) Plan(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Ansible check run skipped").Send()
+		return nil
+	}
+	return m.RunPlaybooks(OperationPlan, m.config.Playbooks.Apply, true)
+}
+
+// Apply runs the apply playbooks
+func (m *Manager) Apply(dryRun bool) error {
+	if dryRun {
+		return m.echoPlaybooks(m.config.Playbooks.Apply)
+	}
+	return m.RunPlaybooks(OperationApply, m.config.Playbooks.Apply, false)
+}
+
+// Destroy runs the destroy playbooks
+func (m *Manager) Destroy(dryRun bool) error {
+	if len(m.config.Playbooks.Destroy) == 0 {
+		logger.Warn("No Ansible destroy playbooks configured, nothing to destroy").Send()
+		return nil
+	}
+	if dryRun {
+		return m.echoPlaybooks(m.config.Playbooks.Destroy)
+	}
+	return m.RunPlaybooks(OperationDestroy, m.config.Playbooks.Destroy, false)
+}
+
+// Validate runs the validate playbooks, or checks the playbooks' syntax when there are none
+func (m *Manager) Validate(dryRun bool) error {
+	if len(m.config.Playbooks.Validate) == 0 || dryRun {
+		return m.Init(dryRun)
+	}
+	return m.RunPlaybooks(OperationValidate, m.config.Playbooks.Validate, false)
+}
+
+// RunPlaybooks runs playbooks in order, stopping at the first failure
+func (m *Manager) RunPlaybooks(operation string, playbooks []string, check bool) error {
+	for _, playbook := range playbooks {
+		if err := m.RunPlaybook(operation, playbook, check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunPlaybook runs one playbook with the configured inventory and variables and records its
+// play recap
+func (m *Manager) RunPlaybook(operation, playbook string, check bool) error {
+	args, err := m.playbookArgs()
+	if err != nil {
+		return err
+	}
+	if check {
+		args = append(args, "--check", "--diff")
+	}
+
+	logger.Info("Running Ansible playbook").
+		Str("playbook", playbook).
+		Str("operation", operation).
+		Bool("check", check).
+		Send()
+
+	start := time.Now()
+	output, err := m.run(playbook, args...)
+	result := PlaybookResult{
+		Playbook:  playbook,
+		Operation: operation,
+		Check:     check,
+		Hosts:     ParseRecap(output),
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err == nil {
+		for _, host := range result.Hosts {
+			if host.Failed > 0 || host.Unreachable > 0 {
+				err = fmt.Errorf("host %s had %d failed and %d unreachable tasks", host.Host, host.Failed, host.Unreachable)
+				break
+			}
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	m.results = append(m.results, result)
+
+	if !check {
+		audit.Record("ansible.playbook", playbook, map[string]interface{}{
+			"operation": operation,
+			"inventory": m.config.Inventory,
+			"changed":   result.Changed(),
+		}, err)
+	}
+	if err != nil {
+		return fmt.Errorf("ansible playbook '%s' failed: %w", playbook, err)
+	}
+
+	logger.Info("Ansible playbook completed successfully").
+		Str("playbook", playbook).
+		Int("hosts", len(result.Hosts)).
+		Int("changed", result.Changed()).
+		Send()
+	return nil
+}
+
+// playbookArgs builds the inventory, variable, limit and tag arguments
+func (m *Manager) playbookArgs() ([]string, error) {
+	var args []string
+	if m.config.Inventory != "" {
+		args = append(args, "-i", m.config.Inventory)
+	}
+	if len(m.config.ExtraVars) > 0 {
+		vars, err := json.Marshal(m.config.ExtraVars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ansible extra vars: %w", err)
+		}
+		args = append(args, "--extra-vars", string(vars))
+	}
+	if m.config.Limit != "" {
+		args = append(args, "--limit", m.config.Limit)
+	}
+	if len(m.config.Tags) > 0 {
+		args = append(args, "--tags", strings.Join(m.config.Tags, ","))
+	}
+	return args, nil
+}
+
+// run executes ansible-playbook, streaming its output and returning a copy for parsing
+func (m *Manager) run(playbook string, args ...string) (string, error) {
+	cmd, err := m.command(playbook, args)
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.Run(context.Background())
+	return string(output), err
+}
+
+// command prepares ansible-playbook for playbook, printing to the terminal as it runs
+func (m *Manager) command(playbook string, args []string) (*execx.Cmd, error) {
+	cmd := execx.Command(m.playbookPath, append(args, playbook)...)
+	cmd.Dir = m.workingDir
+	cmd.Env = m.env
+	cmd.Tag = "ansible-playbook " + playbook
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if m.config.Timeout != "" {
+		timeout, err := time.ParseDuration(m.config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout duration: %w", err)
+		}
+		cmd.Timeout = timeout
+	}
+	return cmd, nil
+}
+
+// echoPlaybooks prints the commands running playbooks would take, for dry runs
+func (m *Manager) echoPlaybooks(playbooks []string) error {
+	args, err := m.playbookArgs()
+	if err != nil {
+		return err
+	}
+	for _, playbook := range playbooks {
+		cmd, err := m.command(playbook, args)
+		if err != nil {
+			return err
+		}
+		cmd.DryRun = true
+		if _, err := cmd.Run(context.Background()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseRecap extracts the per-host counters from the PLAY RECAP section of playbook output
+func ParseRecap(output string) []HostRecap {
+	var hosts []HostRecap
+	inRecap := false
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "PLAY RECAP") {
+			inRecap = true
+			continue
+		}
+		if !inRecap || line == "" {
+			continue
+		}
+		match := recapLine.FindStringSubmatch(line)
+		if match == nil {
+			inRecap = false
+			continue
+		}
+		host := HostRecap{Host: match[1]}
+		for _, field := range strings.Fields(match[2]) {
+			key, value, _ := strings.Cut(field, "=")
+			n, _ := strconv.Atoi(value)
+			switch key {
+			case "ok":
+				host.Ok = n
+			case "changed":
+				host.Changed = n
+			case "unreachable":
+				host.Unreachable = n
+			case "failed":
+				host.Failed = n
+			case "skipped":
+				host.Skipped = n
+			case "rescued":
+				host.Rescued = n
+			case "ignored":
+				host.Ignored = n
+			}
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// Results returns the playbook runs so far, in order
+func (m *Manager) Results() []PlaybookResult {
+	return m.results
+}
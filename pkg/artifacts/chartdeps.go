@@ -0,0 +1,334 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// ChartLockName is the file in the artifacts directory pinning the chart dependencies of the
+// whole deployment set, in the spirit of Helm's Chart.lock
+const ChartLockName = "charts.lock.yaml"
+
+// ChartLock pins one version of every chart dependency used by the synced charts
+type ChartLock struct {
+	Generated    time.Time        `yaml:"generated"`
+	Digest       string           `yaml:"digest"`
+	Dependencies []LockedChartDep `yaml:"dependencies"`
+}
+
+// LockedChartDep is a dependency resolved to a single version for every chart requiring it
+type LockedChartDep struct {
+	Name       string            `yaml:"name"`
+	Version    string            `yaml:"version"`
+	Repository string            `yaml:"repository"`
+	RequiredBy map[string]string `yaml:"requiredBy"` // parent chart to its version constraint
+}
+
+// ChartConflict is a dependency whose constraints no single available version satisfies
+type ChartConflict struct {
+	Name        string
+	Pinned      string            // version configured under artifacts.helm.charts, if any
+	Constraints map[string]string // parent chart to its version constraint
+	Available   []string
+}
+
+// ChartConflictError reports every dependency that could not be resolved
+type ChartConflictError struct {
+	Conflicts []ChartConflict
+}
+
+// Error lists each conflicting dependency with the constraints that disagree
+func (e *ChartConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d chart dependencies cannot be resolved:", len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		parents := make([]string, 0, len(conflict.Constraints))
+		for parent := range conflict.Constraints {
+			parents = append(parents, parent)
+		}
+		sort.Strings(parents)
+		requirements := make([]string, len(parents))
+		for i, parent := range parents {
+			requirements[i] = fmt.Sprintf("%s requires %s", parent, conflict.Constraints[parent])
+		}
+
+		fmt.Fprintf(&b, "\n  %s: %s", conflict.Name, strings.Join(requirements, ", "))
+		if conflict.Pinned != "" {
+			fmt.Fprintf(&b, "; configured version %s", conflict.Pinned)
+		}
+		if len(conflict.Available) == 0 {
+			b.WriteString("; no version available in the synced charts or client chart repository")
+		} else {
+			fmt.Fprintf(&b, "; available %s", strings.Join(conflict.Available, ", "))
+		}
+	}
+	return b.String()
+}
+
+// chartMetadata holds the Chart.yaml fields needed for dependency resolution
+type chartMetadata struct {
+	Name         string            `yaml:"name"`
+	Version      string            `yaml:"version"`
+	Dependencies []chartDependency `yaml:"dependencies"`
+}
+
+type chartDependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Repository string `yaml:"repository"`
+}
+
+// dependencyRequest is one parent chart's constraint on a dependency
+type dependencyRequest struct {
+	parent     string
+	constraint string
+	repository string
+}
+
+// ResolveChartDependencies resolves the Chart.yaml dependencies of every synced chart against
+// the synced charts, their vendored charts/ directories and the client chart repository, picking
+// one version per dependency that satisfies every parent and the version configured under
+// artifacts.helm.charts. The result is written to ChartLockName; conflicts are returned as a
+// *ChartConflictError and leave the previous lockfile in place.
+func (m *Manager) ResolveChartDependencies() (*ChartLock, error) {
+	chartsDir := filepath.Join(m.config.Installer.Workspace, "artifacts", "helm")
+	lockPath := filepath.Join(m.config.Installer.Workspace, "artifacts", ChartLockName)
+
+	if m.dryRun {
+		logger.Info("DRY RUN: Would resolve chart dependencies").Str("lockfile", lockPath).Send()
+		return nil, nil
+	}
+
+	chartDirs, err := findChartDirs(chartsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover charts: %w", err)
+	}
+
+	available := make(map[string]map[string]bool)
+	addVersion := func(chart, version string) {
+		if available[chart] == nil {
+			available[chart] = make(map[string]bool)
+		}
+		available[chart][version] = true
+	}
+
+	requests := make(map[string][]dependencyRequest)
+	for _, dir := range chartDirs {
+		chart, err := readChartMetadata(dir)
+		if err != nil {
+			return nil, err
+		}
+		addVersion(chart.Name, chart.Version)
+
+		for _, dep := range chart.Dependencies {
+			if strings.HasPrefix(dep.Repository, "file://") {
+				// Local path dependencies are part of the chart source, Helm uses them as they are
+				continue
+			}
+			requests[dep.Name] = append(requests[dep.Name], dependencyRequest{
+				parent:     chart.Name,
+				constraint: dep.Version,
+				repository: dep.Repository,
+			})
+		}
+		for _, version := range vendoredChartVersions(dir) {
+			addVersion(version[0], version[1])
+		}
+	}
+
+	if len(requests) == 0 {
+		logger.Info("Synced charts declare no repository dependencies").Send()
+		return nil, nil
+	}
+
+	if repo := m.config.Artifacts.Helm.Client.ChartRepo; repo.Enabled && repo.URL != "" && repo.Type != "local" {
+		index, err := fetchHelmRepoIndex(repo)
+		if err != nil {
+			logger.Warn("Could not read client chart repository index, resolving against synced charts only").Err(err).Send()
+		} else {
+			for chart, entries := range index.Entries {
+				for _, entry := range entries {
+					addVersion(chart, fmt.Sprint(entry["version"]))
+				}
+			}
+		}
+	}
+
+	pinned := make(map[string]string)
+	for _, chart := range m.config.Artifacts.Helm.Charts {
+		if chart.Version != "" {
+			pinned[chart.Name] = chart.Version
+		}
+	}
+
+	names := make([]string, 0, len(requests))
+	for dep := range requests {
+		names = append(names, dep)
+	}
+	sort.Strings(names)
+
+	lock := &ChartLock{Generated: time.Now().UTC()}
+	var conflicts []ChartConflict
+	for _, dep := range names {
+		locked, conflict, err := resolveDependency(dep, requests[dep], available[dep], pinned[dep])
+		if err != nil {
+			return nil, err
+		}
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
+			continue
+		}
+		lock.Dependencies = append(lock.Dependencies, *locked)
+		logger.Debug("Chart dependency resolved").Str("chart", dep).Str("version", locked.Version).Send()
+	}
+	if len(conflicts) > 0 {
+		return nil, &ChartConflictError{Conflicts: conflicts}
+	}
+
+	lock.Digest = chartLockDigest(lock.Dependencies)
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chart lockfile: %w", err)
+	}
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write chart lockfile: %w", err)
+	}
+
+	logger.Info("Chart dependencies locked").
+		Int("dependencies", len(lock.Dependencies)).
+		Str("lockfile", lockPath).
+		Send()
+	return lock, nil
+}
+
+// resolveDependency picks the configured version of a dependency, or the highest available
+// version, that satisfies every parent's constraint
+func resolveDependency(name string, requests []dependencyRequest, versions map[string]bool, pinned string) (*LockedChartDep, *ChartConflict, error) {
+	constraints := make(map[string]string, len(requests))
+	var parsed []*semver.Constraints
+	for _, request := range requests {
+		constraint := request.constraint
+		if constraint == "" {
+			constraint = "*"
+		}
+		c, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chart %s has an invalid version constraint %q for %s: %w", request.parent, constraint, name, err)
+		}
+		constraints[request.parent] = constraint
+		parsed = append(parsed, c)
+	}
+
+	var candidates semver.Collection
+	for version := range versions {
+		if v, err := semver.NewVersion(version); err == nil {
+			candidates = append(candidates, v)
+		}
+	}
+	sort.Sort(sort.Reverse(candidates))
+
+	satisfies := func(v *semver.Version) bool {
+		for _, c := range parsed {
+			if !c.Check(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var selected *semver.Version
+	if pinned != "" {
+		if v, err := semver.NewVersion(pinned); err == nil && satisfies(v) {
+			selected = v
+		}
+	} else {
+		for _, v := range candidates {
+			if satisfies(v) {
+				selected = v
+				break
+			}
+		}
+	}
+
+	if selected == nil {
+		conflict := &ChartConflict{Name: name, Pinned: pinned, Constraints: constraints}
+		for _, v := range candidates {
+			conflict.Available = append(conflict.Available, v.Original())
+		}
+		return nil, conflict, nil
+	}
+
+	return &LockedChartDep{
+		Name:       name,
+		Version:    selected.Original(),
+		Repository: requests[0].repository,
+		RequiredBy: constraints,
+	}, nil, nil
+}
+
+func readChartMetadata(chartDir string) (*chartMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+	var chart chartMetadata
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(chartDir, "Chart.yaml"), err)
+	}
+	if chart.Name == "" || chart.Version == "" {
+		return nil, fmt.Errorf("%s must define name and version", filepath.Join(chartDir, "Chart.yaml"))
+	}
+	return &chart, nil
+}
+
+// vendoredChartVersions lists the name and version of the subcharts shipped in a chart's charts/
+// directory, either unpacked or as <name>-<version>.tgz archives
+func vendoredChartVersions(chartDir string) [][2]string {
+	entries, err := os.ReadDir(filepath.Join(chartDir, "charts"))
+	if err != nil {
+		return nil
+	}
+
+	var versions [][2]string
+	for _, entry := range entries {
+		path := filepath.Join(chartDir, "charts", entry.Name())
+		if entry.IsDir() {
+			if chart, err := readChartMetadata(path); err == nil {
+				versions = append(versions, [2]string{chart.Name, chart.Version})
+			}
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".tgz")
+		if base == entry.Name() {
+			continue
+		}
+		// Versions may contain dashes, so split at the first dash followed by a digit
+		for i := 0; i < len(base)-1; i++ {
+			if base[i] == '-' && base[i+1] >= '0' && base[i+1] <= '9' {
+				versions = append(versions, [2]string{base[:i], base[i+1:]})
+				break
+			}
+		}
+	}
+	return versions
+}
+
+// chartLockDigest hashes the resolved dependencies so changes to the lock are easy to spot
+func chartLockDigest(dependencies []LockedChartDep) string {
+	hash := sha256.New()
+	for _, dep := range dependencies {
+		fmt.Fprintf(hash, "%s@%s\n", dep.Name, dep.Version)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
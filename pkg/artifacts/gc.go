@@ -0,0 +1,350 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"gopkg.in/yaml.v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Kinds of artifacts removed by registry garbage collection
+const (
+	GCKindImage = "image"
+	GCKindChart = "chart"
+)
+
+// DefaultKeepReleases is how many previous versions of each artifact garbage collection keeps
+const DefaultKeepReleases = 2
+
+// GCOptions selects what registry garbage collection keeps
+type GCOptions struct {
+	// KeepReleases is the number of previous versions kept besides the current one
+	KeepReleases int
+	// IncludeUntracked also collects tags of the configured repositories that the lockfile
+	// never recorded, such as tags pushed before incremental pulls were used
+	IncludeUntracked bool
+}
+
+// GCCandidate is a stale artifact in the client registry or chart repository
+type GCCandidate struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Versions  []string `json:"versions"`
+	Reference string   `json:"reference"`
+	Tracked   bool     `json:"tracked"`
+}
+
+// PlanGC lists the images and charts pushed to the client side that neither the configuration,
+// the lockfile nor the last KeepReleases syncs reference
+func (m *Manager) PlanGC(opts GCOptions) ([]GCCandidate, error) {
+	images, err := m.planImageGC(opts)
+	if err != nil {
+		return nil, err
+	}
+	charts, err := m.planChartGC(opts)
+	if err != nil {
+		return nil, err
+	}
+	return append(images, charts...), nil
+}
+
+// DeleteGC removes the candidates through the registry and chart repository APIs, continuing
+// past failures and returning them together
+func (m *Manager) DeleteGC(candidates []GCCandidate) error {
+	var failures []string
+	client := &http.Client{Timeout: 2 * time.Minute}
+	for _, candidate := range candidates {
+		if m.dryRun {
+			logger.Info("DRY RUN: Would delete artifact").Str("kind", candidate.Kind).Str("reference", candidate.Reference).Send()
+			continue
+		}
+
+		var err error
+		if candidate.Kind == GCKindImage {
+			err = crane.Delete(candidate.Reference, craneAuth(m.config.Artifacts.Images.Client.Auth)...)
+			err = explainDeleteError(err)
+			audit.Record("registry.delete", candidate.Reference, map[string]interface{}{"tags": candidate.Versions}, err)
+		} else {
+			err = deleteChart(client, m.config.Artifacts.Helm.Client.ChartRepo, candidate.Name, candidate.Versions[0])
+			audit.Record("helm.delete", m.config.Artifacts.Helm.Client.ChartRepo.URL, map[string]interface{}{
+				"chart":   candidate.Name,
+				"version": candidate.Versions[0],
+			}, err)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", candidate.Reference, err))
+			continue
+		}
+		logger.Info("Artifact deleted").Str("kind", candidate.Kind).Str("reference", candidate.Reference).Send()
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to delete %d of %d artifacts:\n%s", len(failures), len(candidates), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// planImageGC collects the stale tags of every configured image repository in the client registry.
+// Tags are deleted by manifest digest, so a tag sharing its digest with a kept one is never collected.
+func (m *Manager) planImageGC(opts GCOptions) ([]GCCandidate, error) {
+	images := m.config.Artifacts.Images
+	if images.Client.Registry == "" {
+		return nil, nil
+	}
+	lock := m.lock
+	if lock == nil {
+		var err error
+		if lock, err = LoadLockfile(filepath.Join(m.config.Installer.Workspace, "artifacts", LockfileName)); err != nil {
+			return nil, err
+		}
+	}
+	auth := craneAuth(images.Client.Auth)
+
+	var candidates []GCCandidate
+	for _, image := range images.Images {
+		repo := images.Client.Registry + "/" + image.Name
+		tags, err := crane.ListTags(repo, auth...)
+		if err != nil {
+			if imageFailureReason(err) == ReasonNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list tags of %s: %w", repo, err)
+		}
+
+		kept := map[string]bool{image.Version: true}
+		if current, ok := lock.Images[image.Name]; ok {
+			kept[referenceTag(current.Destination)] = true
+		}
+		tracked := make(map[string]bool)
+		previous := 0
+		for _, entry := range lock.History[image.Name] {
+			tag := referenceTag(entry.Destination)
+			tracked[tag] = true
+			if !kept[tag] && previous < opts.KeepReleases {
+				kept[tag] = true
+				previous++
+			}
+		}
+
+		keptDigests := make(map[string]bool)
+		stale := make(map[string][]string)
+		for _, tag := range tags {
+			if !kept[tag] && !tracked[tag] && !opts.IncludeUntracked {
+				continue
+			}
+			digest, err := crane.Digest(repo+":"+tag, auth...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s:%s: %w", repo, tag, err)
+			}
+			if kept[tag] {
+				keptDigests[digest] = true
+			} else {
+				stale[digest] = append(stale[digest], tag)
+			}
+		}
+
+		for digest, digestTags := range stale {
+			if keptDigests[digest] {
+				logger.Debug("Stale tag shares its manifest with a kept tag, keeping it").
+					Str("repository", repo).
+					Str("tags", strings.Join(digestTags, ",")).
+					Send()
+				continue
+			}
+			sort.Strings(digestTags)
+			isTracked := false
+			for _, tag := range digestTags {
+				isTracked = isTracked || tracked[tag]
+			}
+			candidates = append(candidates, GCCandidate{
+				Kind:      GCKindImage,
+				Name:      image.Name,
+				Versions:  digestTags,
+				Reference: repo + "@" + digest,
+				Tracked:   isTracked,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Name != candidates[j].Name {
+			return candidates[i].Name < candidates[j].Name
+		}
+		return candidates[i].Versions[0] < candidates[j].Versions[0]
+	})
+	return candidates, nil
+}
+
+// planChartGC collects chart versions the installer packaged before, found in the client chart
+// repository index but neither in the local index nor among the newest KeepReleases others
+func (m *Manager) planChartGC(opts GCOptions) ([]GCCandidate, error) {
+	repo := m.config.Artifacts.Helm.Client.ChartRepo
+	if !repo.Enabled || repo.URL == "" || repo.Type == "local" {
+		return nil, nil
+	}
+
+	local, err := readHelmRepoIndex(filepath.Join(m.HelmRepoDir(), "index.yaml"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Info("No local chart index, skipping chart garbage collection").Send()
+			return nil, nil
+		}
+		return nil, err
+	}
+	remoteIndex, err := fetchHelmRepoIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(repo.URL, "/")
+	var candidates []GCCandidate
+	names := make([]string, 0, len(local.Entries))
+	for chart := range local.Entries {
+		names = append(names, chart)
+	}
+	sort.Strings(names)
+
+	for _, chart := range names {
+		kept := make(map[string]bool)
+		for _, entry := range local.Entries[chart] {
+			kept[fmt.Sprint(entry["version"])] = true
+		}
+
+		versions := remoteIndex.Entries[chart]
+		sort.SliceStable(versions, func(i, j int) bool {
+			return fmt.Sprint(versions[i]["created"]) > fmt.Sprint(versions[j]["created"])
+		})
+		previous := 0
+		for _, entry := range versions {
+			version := fmt.Sprint(entry["version"])
+			if kept[version] {
+				continue
+			}
+			if previous < opts.KeepReleases {
+				kept[version] = true
+				previous++
+				continue
+			}
+			candidates = append(candidates, GCCandidate{
+				Kind:      GCKindChart,
+				Name:      chart,
+				Versions:  []string{version},
+				Reference: fmt.Sprintf("%s/%s-%s.tgz", base, chart, version),
+				Tracked:   true,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+func readHelmRepoIndex(path string) (*HelmRepoIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart index: %w", err)
+	}
+	var index HelmRepoIndex
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse chart index %s: %w", path, err)
+	}
+	return &index, nil
+}
+
+// fetchHelmRepoIndex downloads the index.yaml of the client chart repository
+func fetchHelmRepoIndex(repo config.ChartRepoConfig) (*HelmRepoIndex, error) {
+	url := strings.TrimSuffix(repo.URL, "/") + "/index.yaml"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index request: %w", err)
+	}
+	setChartRepoAuth(req, repo.Auth)
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	var index HelmRepoIndex
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return &index, nil
+}
+
+// deleteChart removes a chart version using the API of the given repository type
+func deleteChart(client *http.Client, repo config.ChartRepoConfig, chart, version string) error {
+	base := strings.TrimSuffix(repo.URL, "/")
+	var url string
+	switch repo.Type {
+	case "chartmuseum":
+		url = fmt.Sprintf("%s/api/charts/%s/%s", base, chart, version)
+	case "artifactory":
+		url = fmt.Sprintf("%s/%s-%s.tgz", base, chart, version)
+	default:
+		return fmt.Errorf("deleting charts is not supported for %s repositories, use its cleanup policies", repo.Type)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	setChartRepoAuth(req, repo.Auth)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s-%s: %w", chart, version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to delete %s-%s: %s: %s", chart, version, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func setChartRepoAuth(req *http.Request, auth config.AuthConfig) {
+	if auth.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	} else if auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// explainDeleteError points at the registry setting that most often rejects manifest deletes
+func explainDeleteError(err error) error {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Errorf("%w (the registry does not allow deletes; for the distribution registry set REGISTRY_STORAGE_DELETE_ENABLED=true)", err)
+	}
+	return err
+}
+
+// referenceTag returns the tag of an image reference, empty for digest references
+func referenceTag(ref string) string {
+	tag, err := name.NewTag(ref)
+	if err != nil {
+		return ""
+	}
+	return tag.TagStr()
+}
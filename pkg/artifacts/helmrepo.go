@@ -0,0 +1,392 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// HelmRepoIndex mirrors the index.yaml file of an HTTP Helm repository
+type HelmRepoIndex struct {
+	APIVersion string                              `yaml:"apiVersion"`
+	Entries    map[string][]map[string]interface{} `yaml:"entries"`
+	Generated  time.Time                           `yaml:"generated"`
+}
+
+// packagedChart is a chart archive produced from a synced chart directory
+type packagedChart struct {
+	Name     string
+	Version  string
+	Archive  string
+	Metadata map[string]interface{}
+}
+
+// HelmRepoDir returns the directory holding the packaged charts and index.yaml
+func (m *Manager) HelmRepoDir() string {
+	return filepath.Join(m.config.Installer.Workspace, "artifacts", "helm-repo")
+}
+
+// BuildHelmRepoIndex packages every synced chart and writes a Helm repository index
+func (m *Manager) BuildHelmRepoIndex() (string, error) {
+	chartsDir := filepath.Join(m.config.Installer.Workspace, "artifacts", "helm")
+	repoDir := m.HelmRepoDir()
+
+	logger.Info("Building Helm repository index").
+		Str("charts_dir", chartsDir).
+		Str("repo_dir", repoDir).
+		Send()
+
+	if m.dryRun {
+		logger.Info("DRY RUN: Would build Helm repository index").Send()
+		return repoDir, nil
+	}
+
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create helm repository directory: %w", err)
+	}
+
+	chartDirs, err := findChartDirs(chartsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover charts: %w", err)
+	}
+	if len(chartDirs) == 0 {
+		return "", fmt.Errorf("no charts found in %s", chartsDir)
+	}
+
+	index := HelmRepoIndex{
+		APIVersion: "v1",
+		Entries:    make(map[string][]map[string]interface{}),
+		Generated:  time.Now().UTC(),
+	}
+
+	for _, dir := range chartDirs {
+		chart, err := packageChart(dir, repoDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to package chart %s: %w", dir, err)
+		}
+
+		digest, err := fileDigest(chart.Archive)
+		if err != nil {
+			return "", err
+		}
+
+		entry := chart.Metadata
+		entry["created"] = index.Generated.Format(time.RFC3339)
+		entry["digest"] = digest
+		entry["urls"] = []string{filepath.Base(chart.Archive)}
+		index.Entries[chart.Name] = append(index.Entries[chart.Name], entry)
+
+		logger.Info("Chart packaged").
+			Str("chart", chart.Name).
+			Str("version", chart.Version).
+			Str("archive", chart.Archive).
+			Send()
+	}
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal helm repository index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "index.yaml"), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write helm repository index: %w", err)
+	}
+
+	logger.Info("Helm repository index built").
+		Int("charts", len(chartDirs)).
+		Str("repo_dir", repoDir).
+		Send()
+
+	return repoDir, nil
+}
+
+// PushChartsToChartRepo uploads packaged charts to the configured client chart repository
+func (m *Manager) PushChartsToChartRepo() error {
+	repo := m.config.Artifacts.Helm.Client.ChartRepo
+	if repo.URL == "" {
+		return fmt.Errorf("client chart repository URL not configured")
+	}
+
+	logger.Info("Pushing charts to chart repository").
+		Str("type", repo.Type).
+		Str("url", repo.URL).
+		Send()
+
+	if m.dryRun {
+		logger.Info("DRY RUN: Would push charts to chart repository").Str("url", repo.URL).Send()
+		return nil
+	}
+
+	archives, err := filepath.Glob(filepath.Join(m.HelmRepoDir(), "*.tgz"))
+	if err != nil {
+		return fmt.Errorf("failed to list packaged charts: %w", err)
+	}
+	sort.Strings(archives)
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	for _, archive := range archives {
+		err := uploadChart(client, repo, archive)
+		audit.Record("helm.push", repo.URL, map[string]interface{}{
+			"type":  repo.Type,
+			"chart": filepath.Base(archive),
+		}, err)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Chart pushed").Str("chart", filepath.Base(archive)).Send()
+	}
+
+	logger.Info("Charts pushed to chart repository successfully").Int("charts", len(archives)).Send()
+	return nil
+}
+
+// ServeHelmRepo serves the local Helm repository over HTTP until the returned stop function is called
+func (m *Manager) ServeHelmRepo() (string, func(), error) {
+	addr := m.config.Artifacts.Helm.Client.ChartRepo.ServeAddr
+	repoDir := m.HelmRepoDir()
+
+	if _, err := os.Stat(filepath.Join(repoDir, "index.yaml")); err != nil {
+		return "", nil, fmt.Errorf("helm repository index not found in %s, run package-pull first: %w", repoDir, err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           http.FileServer(http.Dir(repoDir)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Helm repository server stopped").Err(err).Send()
+		}
+	}()
+
+	url := fmt.Sprintf("http://%s", listener.Addr().String())
+	logger.Info("Serving local Helm repository").Str("url", url).Str("repo_dir", repoDir).Send()
+
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+	return url, stop, nil
+}
+
+// uploadChart sends a chart archive using the API of the given repository type
+func uploadChart(client *http.Client, repo config.ChartRepoConfig, archive string) error {
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read chart archive: %w", err)
+	}
+
+	base := strings.TrimSuffix(repo.URL, "/")
+	var req *http.Request
+	switch repo.Type {
+	case "chartmuseum":
+		req, err = http.NewRequest(http.MethodPost, base+"/api/charts", bytes.NewReader(data))
+	case "nexus", "artifactory":
+		req, err = http.NewRequest(http.MethodPut, base+"/"+filepath.Base(archive), bytes.NewReader(data))
+	default:
+		return fmt.Errorf("unsupported chart repository type for push: %s", repo.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/gzip")
+	if repo.Auth.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+repo.Auth.Token)
+	} else if repo.Auth.Username != "" {
+		req.SetBasicAuth(repo.Auth.Username, repo.Auth.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", filepath.Base(archive), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to upload %s: %s: %s", filepath.Base(archive), resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// findChartDirs returns every directory containing a Chart.yaml, excluding subcharts
+func findChartDirs(root string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	return dirs, err
+}
+
+// packageChart writes <name>-<version>.tgz for a chart directory the same way `helm package` does
+func packageChart(chartDir, destDir string) (*packagedChart, error) {
+	metaData, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+
+	metadata := make(map[string]interface{})
+	if err := yaml.Unmarshal(metaData, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse Chart.yaml: %w", err)
+	}
+
+	name, _ := metadata["name"].(string)
+	version := fmt.Sprintf("%v", metadata["version"])
+	if name == "" || metadata["version"] == nil {
+		return nil, fmt.Errorf("Chart.yaml must define name and version")
+	}
+
+	ignore := loadHelmIgnore(chartDir)
+	archive := filepath.Join(destDir, fmt.Sprintf("%s-%s.tgz", name, version))
+
+	file, err := os.Create(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chart archive: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(chartDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(chartDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.Name() == ".git" || ignore(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(name, rel))
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive chart: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize chart archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize chart archive: %w", err)
+	}
+
+	return &packagedChart{
+		Name:     name,
+		Version:  version,
+		Archive:  archive,
+		Metadata: metadata,
+	}, nil
+}
+
+// loadHelmIgnore returns a matcher for the simple glob patterns in a chart's .helmignore
+func loadHelmIgnore(chartDir string) func(rel string, isDir bool) bool {
+	data, err := os.ReadFile(filepath.Join(chartDir, ".helmignore"))
+	if err != nil {
+		return func(string, bool) bool { return false }
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	return func(rel string, isDir bool) bool {
+		for _, pattern := range patterns {
+			dirOnly := strings.HasSuffix(pattern, "/")
+			pattern = strings.TrimSuffix(pattern, "/")
+			if dirOnly && !isDir {
+				continue
+			}
+			if matched, _ := filepath.Match(pattern, filepath.Base(rel)); matched {
+				return true
+			}
+			if matched, _ := filepath.Match(pattern, rel); matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func fileDigest(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,299 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-containerregistry/pkg/crane"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// LockfileName is the file in the artifacts directory recording what was last synced
+const LockfileName = "artifacts.lock.json"
+
+// historyLimit bounds the previous destinations kept per image
+const historyLimit = 50
+
+// Lockfile records the digests and commits of synced artifacts for incremental pulls
+type Lockfile struct {
+	Version int                    `json:"version"`
+	Images  map[string]LockedImage `json:"images"`
+	Repos   map[string]LockedRepo  `json:"repos"`
+
+	// History holds the destinations each image was previously synced to, newest first,
+	// so registry garbage collection knows what the installer pushed
+	History map[string][]LockedImage `json:"history,omitempty"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// LockedImage is an image copied to the client registry
+type LockedImage struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Digest      string    `json:"digest"`
+	SyncedAt    time.Time `json:"syncedAt"`
+}
+
+// LockedRepo is a git repository cloned into the workspace
+type LockedRepo struct {
+	URL        string    `json:"url"`
+	Ref        string    `json:"ref"`
+	RemoteHash string    `json:"remoteHash"`
+	Commit     string    `json:"commit"`
+	SyncedAt   time.Time `json:"syncedAt"`
+}
+
+// LoadLockfile reads the lockfile, returning an empty one if it does not exist
+func LoadLockfile(path string) (*Lockfile, error) {
+	lock := &Lockfile{
+		Version: 1,
+		Images:  make(map[string]LockedImage),
+		Repos:   make(map[string]LockedRepo),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.Images == nil {
+		lock.Images = make(map[string]LockedImage)
+	}
+	if lock.Repos == nil {
+		lock.Repos = make(map[string]LockedRepo)
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile
+func (l *Lockfile) Save() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create lockfile directory: %w", err)
+	}
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+func (l *Lockfile) image(name string) (LockedImage, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	entry, ok := l.Images[name]
+	return entry, ok
+}
+
+func (l *Lockfile) setImage(name string, entry LockedImage) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if previous, ok := l.Images[name]; ok && previous.Destination != entry.Destination {
+		if l.History == nil {
+			l.History = make(map[string][]LockedImage)
+		}
+		history := append([]LockedImage{previous}, l.History[name]...)
+		l.History[name] = history[:min(len(history), historyLimit)]
+	}
+	l.Images[name] = entry
+}
+
+func (l *Lockfile) repo(kind string) (LockedRepo, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	entry, ok := l.Repos[kind]
+	return entry, ok
+}
+
+func (l *Lockfile) setRepo(kind string, entry LockedRepo) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Repos[kind] = entry
+}
+
+// EnableIncremental loads the lockfile so unchanged artifacts are skipped
+func (m *Manager) EnableIncremental() error {
+	lock, err := LoadLockfile(filepath.Join(m.config.Installer.Workspace, "artifacts", LockfileName))
+	if err != nil {
+		return err
+	}
+	m.lock = lock
+	return nil
+}
+
+// SaveLockfile persists the lockfile after a sync; a no-op outside incremental mode
+func (m *Manager) SaveLockfile() error {
+	if m.lock == nil || m.dryRun {
+		return nil
+	}
+	return m.lock.Save()
+}
+
+// imageUpToDate reports whether the destination already holds the source digest recorded in the lockfile
+func (m *Manager) imageUpToDate(key, sourceRef, destRef string) (string, bool) {
+	if m.lock == nil {
+		return "", false
+	}
+
+	digest, err := crane.Digest(sourceRef)
+	if err != nil {
+		logger.Debug("Could not resolve source digest, syncing image").Str("image", sourceRef).Err(err).Send()
+		return "", false
+	}
+
+	entry, ok := m.lock.image(key)
+	if !ok || entry.Source != sourceRef || entry.Destination != destRef || entry.Digest != digest {
+		return digest, false
+	}
+
+	// Guard against the destination having been cleaned up since the last sync
+	destDigest, err := crane.Digest(destRef)
+	if err != nil || destDigest != digest {
+		return digest, false
+	}
+	return digest, true
+}
+
+func (m *Manager) recordImage(key, sourceRef, destRef, digest string) {
+	if m.lock == nil || digest == "" {
+		return
+	}
+	m.lock.setImage(key, LockedImage{
+		Source:      sourceRef,
+		Destination: destRef,
+		Digest:      digest,
+		SyncedAt:    time.Now().UTC(),
+	})
+}
+
+// repoUpToDate reports whether localPath is a clone of the commit the remote ref still points to
+func (m *Manager) repoUpToDate(kind string, repo config.GitRepoConfig, localPath string) (string, bool) {
+	if m.lock == nil {
+		return "", false
+	}
+
+	remoteHash, err := remoteRefHash(repo)
+	if err != nil {
+		logger.Debug("Could not resolve remote ref, cloning repository").Str("repo", repo.Repo).Err(err).Send()
+		return "", false
+	}
+
+	entry, ok := m.lock.repo(kind)
+	if !ok || entry.URL != repo.Repo || entry.Ref != gitRefName(repo).String() || entry.RemoteHash != remoteHash {
+		return remoteHash, false
+	}
+
+	commit, err := localHead(localPath)
+	if err != nil || commit != entry.Commit {
+		return remoteHash, false
+	}
+	return remoteHash, true
+}
+
+func (m *Manager) recordRepo(kind string, repo config.GitRepoConfig, localPath, remoteHash string) {
+	if m.lock == nil || remoteHash == "" {
+		return
+	}
+	commit, err := localHead(localPath)
+	if err != nil {
+		logger.Warn("Failed to read cloned commit for lockfile").Str("path", localPath).Err(err).Send()
+		return
+	}
+	m.lock.setRepo(kind, LockedRepo{
+		URL:        repo.Repo,
+		Ref:        gitRefName(repo).String(),
+		RemoteHash: remoteHash,
+		Commit:     commit,
+		SyncedAt:   time.Now().UTC(),
+	})
+}
+
+// gitRefName returns the ref a repository is cloned at
+func gitRefName(repo config.GitRepoConfig) plumbing.ReferenceName {
+	switch {
+	case repo.Branch != "":
+		return plumbing.NewBranchReferenceName(repo.Branch)
+	case repo.Tag != "":
+		return plumbing.NewTagReferenceName(repo.Tag)
+	default:
+		return plumbing.HEAD
+	}
+}
+
+// gitCloneOptions builds clone options with authentication and ref selection
+func gitCloneOptions(repo config.GitRepoConfig) *git.CloneOptions {
+	options := &git.CloneOptions{
+		URL:  repo.Repo,
+		Auth: gitAuth(repo.Auth),
+	}
+	if ref := gitRefName(repo); ref != plumbing.HEAD {
+		options.ReferenceName = ref
+	}
+	return options
+}
+
+func remoteRefHash(repo config.GitRepoConfig) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repo.Repo},
+	})
+
+	refs, err := remote.List(&git.ListOptions{Auth: gitAuth(repo.Auth)})
+	if err != nil {
+		return "", fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	want := gitRefName(repo)
+	for _, ref := range refs {
+		if ref.Name() == want {
+			if ref.Type() == plumbing.SymbolicReference {
+				want = ref.Target()
+				break
+			}
+			return ref.Hash().String(), nil
+		}
+	}
+	// HEAD is usually symbolic; resolve its target
+	for _, ref := range refs {
+		if ref.Name() == want && ref.Type() == plumbing.HashReference {
+			return ref.Hash().String(), nil
+		}
+	}
+	return "", fmt.Errorf("ref %s not found on %s", gitRefName(repo), repo.Repo)
+}
+
+func localHead(path string) (string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
@@ -6,23 +6,35 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/tuning"
 )
 
 // Manager handles artifact synchronization operations
 type Manager struct {
 	config *config.InstallerConfig
 	dryRun bool
+
+	// Incremental mode and image selection, see EnableIncremental and SelectImages
+	lock        *Lockfile
+	imageFilter map[string]bool
+
+	// Per-image outcome of SyncImage, see ImageSyncResults
+	resultsMu sync.Mutex
+	results   map[string]*ImageSyncResult
 }
 
 // NewManager creates a new artifacts manager
@@ -33,54 +45,84 @@ func NewManager(cfg *config.InstallerConfig, dryRun bool) *Manager {
 	}
 }
 
-// ImageSyncCallback is called during parallel image synchronization
-type ImageSyncCallback func(index int, image config.ImageReference, err error)
+// SelectImages restricts synchronization to the named images
+func (m *Manager) SelectImages(names []string) error {
+	known := make(map[string]bool)
+	for _, image := range m.config.Artifacts.Images.Images {
+		known[image.Name] = true
+	}
+
+	m.imageFilter = make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return fmt.Errorf("image %s is not in the configuration", name)
+		}
+		m.imageFilter[name] = true
+	}
+	return nil
+}
 
-// ValidateImages checks if all required images are accessible
-func (m *Manager) ValidateImages() error {
-	logger.Info("Validating image accessibility").Send()
+// SelectedImages returns the configured images to synchronize
+func (m *Manager) SelectedImages() []config.ImageReference {
+	if len(m.imageFilter) == 0 {
+		return m.config.Artifacts.Images.Images
+	}
 
+	var images []config.ImageReference
 	for _, image := range m.config.Artifacts.Images.Images {
-		if err := m.validateSingleImage(image); err != nil {
-			if image.Required {
-				return fmt.Errorf("required image %s:%s not accessible: %w", image.Name, image.Version, err)
-			}
-			logger.Warn("Optional image not accessible").
-				Str("image", image.Name).
-				Str("version", image.Version).
-				Err(err).
-				Send()
+		if m.imageFilter[image.Name] {
+			images = append(images, image)
 		}
 	}
-
-	return nil
+	return images
 }
 
-// SyncImage synchronizes a single OCI image
+// ImageSyncCallback is called during parallel image synchronization
+type ImageSyncCallback func(index int, image config.ImageReference, err error)
+
+// SyncImage synchronizes a single OCI image and records its result
 func (m *Manager) SyncImage(image config.ImageReference) error {
+	started := time.Now()
+	result := &ImageSyncResult{Name: image.Name, Version: image.Version, Verification: VerificationUnverified}
+
+	err := m.syncImage(image, result)
+	result.Duration = time.Since(started).Round(time.Millisecond).String()
+	if err != nil {
+		result.Status = SyncStatusFailed
+		result.Error = err.Error()
+	}
+	m.recordSyncResult(result)
+	return err
+}
+
+func (m *Manager) syncImage(image config.ImageReference, result *ImageSyncResult) error {
 	logger.Info("Synchronizing image").
 		Str("image", image.Name).
 		Str("version", image.Version).
 		Bool("dry_run", m.dryRun).
 		Send()
 
+	// Build source and destination image references
+	sourceRef := fmt.Sprintf("%s/%s:%s",
+		m.config.Artifacts.Images.Vendor.Registry,
+		image.Name,
+		image.Version)
+	result.Source = sourceRef
+
 	if m.dryRun {
 		logger.Info("DRY RUN: Would sync image").
 			Str("image", image.Name).
 			Str("version", image.Version).
 			Send()
+		result.Status = SyncStatusDryRun
 		return nil
 	}
 
-	// Build source and destination image references
-	sourceRef := fmt.Sprintf("%s/%s:%s",
-		m.config.Artifacts.Images.Vendor.Registry,
-		image.Name,
-		image.Version)
-
 	// Check if client registry is configured
 	if m.config.Artifacts.Images.Client.Registry == "" {
 		// No client registry - just validate vendor image exists
+		result.Status = SyncStatusValidated
 		return m.validateImageExists(sourceRef, m.config.Artifacts.Images.Vendor.Auth)
 	}
 
@@ -89,18 +131,45 @@ func (m *Manager) SyncImage(image config.ImageReference) error {
 		m.config.Artifacts.Images.Client.Registry,
 		image.Name,
 		image.Version)
+	result.Destination = destRef
+
+	digest, upToDate := m.imageUpToDate(image.Name, sourceRef, destRef)
+	result.SourceDigest = digest
+	if upToDate {
+		logger.Info("Image unchanged since last sync, skipping").
+			Str("image", image.Name).
+			Str("digest", digest).
+			Send()
+		result.Status = SyncStatusSkipped
+		result.DestinationDigest = digest
+		// imageUpToDate already compared the destination digest with the source
+		result.Verification = VerificationMatch
+		m.completeSyncResult(result)
+		return nil
+	}
 
-	return m.copyImage(sourceRef, destRef)
+	if err := m.copyImage(sourceRef, destRef); err != nil {
+		return err
+	}
+	result.Status = SyncStatusCopied
+	m.completeSyncResult(result)
+	if err := m.verifyImageCopy(result); err != nil {
+		return err
+	}
+	m.recordImage(image.Name, sourceRef, destRef, digest)
+	return nil
 }
 
 // SyncImagesParallel synchronizes multiple images in parallel
 func (m *Manager) SyncImagesParallel(callback ImageSyncCallback) error {
-	images := m.config.Artifacts.Images.Images
+	images := m.SelectedImages()
 	var wg sync.WaitGroup
 	errorChan := make(chan error, len(images))
 
-	// Create semaphore to limit concurrent operations
-	semaphore := make(chan struct{}, 5) // Limit to 5 concurrent operations
+	// Limit concurrent copies to what the host's CPUs and memory support
+	workers := tuning.Current().ImageSyncWorkers
+	logger.Info("Syncing images in parallel").Int("images", len(images)).Int("workers", workers).Send()
+	semaphore := make(chan struct{}, workers)
 
 	for i, image := range images {
 		wg.Add(1)
@@ -178,6 +247,12 @@ func (m *Manager) CloneHelmCharts() error {
 
 	// Clone to local path
 	localPath := filepath.Join(m.config.Installer.Workspace, "artifacts", "helm")
+	remoteHash, upToDate := m.repoUpToDate("helm", m.config.Artifacts.Helm.Vendor, localPath)
+	if upToDate {
+		logger.Info("Helm charts unchanged since last sync, skipping clone").Str("commit", remoteHash).Send()
+		return nil
+	}
+
 	if err := os.RemoveAll(localPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to clean existing helm directory: %w", err)
 	}
@@ -186,6 +261,7 @@ func (m *Manager) CloneHelmCharts() error {
 	if err != nil {
 		return fmt.Errorf("failed to clone helm repository: %w", err)
 	}
+	m.recordRepo("helm", m.config.Artifacts.Helm.Vendor, localPath, remoteHash)
 
 	logger.Info("Helm charts cloned successfully").
 		Str("local_path", localPath).
@@ -293,6 +369,12 @@ func (m *Manager) CloneTerraformModules() error {
 
 	// Clone to local path
 	localPath := filepath.Join(m.config.Installer.Workspace, "artifacts", "terraform")
+	remoteHash, upToDate := m.repoUpToDate("terraform", m.config.Artifacts.Terraform.Vendor, localPath)
+	if upToDate {
+		logger.Info("Terraform modules unchanged since last sync, skipping clone").Str("commit", remoteHash).Send()
+		return nil
+	}
+
 	if err := os.RemoveAll(localPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to clean existing terraform directory: %w", err)
 	}
@@ -301,6 +383,7 @@ func (m *Manager) CloneTerraformModules() error {
 	if err != nil {
 		return fmt.Errorf("failed to clone terraform repository: %w", err)
 	}
+	m.recordRepo("terraform", m.config.Artifacts.Terraform.Vendor, localPath, remoteHash)
 
 	logger.Info("Terraform modules cloned successfully").
 		Str("local_path", localPath).
@@ -309,6 +392,51 @@ func (m *Manager) CloneTerraformModules() error {
 	return nil
 }
 
+// CloneDatabaseScripts clones the database migration scripts repository
+func (m *Manager) CloneDatabaseScripts() error {
+	repo := m.config.Database.Scripts
+	if repo.Repo == "" {
+		logger.Info("No database scripts repository configured").Send()
+		return nil
+	}
+
+	logger.Info("Cloning database scripts").Str("repo", repo.Repo).Send()
+
+	if m.dryRun {
+		logger.Info("DRY RUN: Would clone database scripts").Str("repo", repo.Repo).Send()
+		return nil
+	}
+
+	localPath := filepath.Join(m.config.Installer.Workspace, "artifacts", "db-scripts")
+	remoteHash, upToDate := m.repoUpToDate("db-scripts", repo, localPath)
+	if upToDate {
+		logger.Info("Database scripts unchanged since last sync, skipping clone").Str("commit", remoteHash).Send()
+		return nil
+	}
+
+	if err := os.RemoveAll(localPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clean existing db-scripts directory: %w", err)
+	}
+	if _, err := git.PlainClone(localPath, false, gitCloneOptions(repo)); err != nil {
+		return fmt.Errorf("failed to clone database scripts repository: %w", err)
+	}
+	m.recordRepo("db-scripts", repo, localPath, remoteHash)
+
+	logger.Info("Database scripts cloned successfully").Str("local_path", localPath).Send()
+	return nil
+}
+
+// gitAuth returns basic auth for a token or username/password, or nil for anonymous access
+func gitAuth(auth config.AuthConfig) transport.AuthMethod {
+	if auth.Token != "" {
+		return &http.BasicAuth{Username: "token", Password: auth.Token}
+	}
+	if auth.Username != "" {
+		return &http.BasicAuth{Username: auth.Username, Password: auth.Password}
+	}
+	return nil
+}
+
 // PushTerraformModulesToClient pushes Terraform modules to client repository
 func (m *Manager) PushTerraformModulesToClient() error {
 	if m.config.Artifacts.Terraform.Client.Repo == "" {
@@ -368,31 +496,6 @@ func (m *Manager) ValidateTerraformModules() error {
 	return nil
 }
 
-// validateSingleImage validates if an image is accessible
-func (m *Manager) validateSingleImage(image config.ImageReference) error {
-	// Try vendor registry first
-	vendorRef := fmt.Sprintf("%s/%s:%s",
-		m.config.Artifacts.Images.Vendor.Registry,
-		image.Name,
-		image.Version)
-
-	if err := m.validateImageExists(vendorRef, m.config.Artifacts.Images.Vendor.Auth); err == nil {
-		return nil
-	}
-
-	// If vendor fails and client registry is configured, try client
-	if m.config.Artifacts.Images.Client.Registry != "" {
-		clientRef := fmt.Sprintf("%s/%s:%s",
-			m.config.Artifacts.Images.Client.Registry,
-			image.Name,
-			image.Version)
-
-		return m.validateImageExists(clientRef, m.config.Artifacts.Images.Client.Auth)
-	}
-
-	return fmt.Errorf("image not accessible in any configured registry")
-}
-
 // validateImageExists checks if an image exists in a registry
 func (m *Manager) validateImageExists(imageRef string, auth config.AuthConfig) error {
 	logger.Debug("Validating image exists").Str("image", imageRef).Send()
@@ -445,7 +548,9 @@ func (m *Manager) copyImage(sourceRef, destRef string) error {
 		// Configure auth for client registry
 	}
 
-	if err := crane.Copy(sourceRef, destRef, options...); err != nil {
+	err := crane.Copy(sourceRef, destRef, options...)
+	audit.Record("image.copy", destRef, map[string]interface{}{"source": sourceRef}, err)
+	if err != nil {
 		return fmt.Errorf("failed to copy image from %s to %s: %w", sourceRef, destRef, err)
 	}
 
@@ -0,0 +1,178 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// Image sync statuses
+const (
+	SyncStatusCopied    = "copied"
+	SyncStatusSkipped   = "skipped"
+	SyncStatusValidated = "validated"
+	SyncStatusDryRun    = "dry-run"
+	SyncStatusFailed    = "failed"
+)
+
+// Verification results comparing the destination digests with the source, see verifyImageCopy
+const (
+	VerificationMatch      = "match"
+	VerificationMismatch   = "mismatch"
+	VerificationUnverified = "unverified"
+)
+
+// ImageSyncResult records how one image was synchronized
+type ImageSyncResult struct {
+	Name              string                 `json:"name"`
+	Version           string                 `json:"version"`
+	Source            string                 `json:"source"`
+	Destination       string                 `json:"destination,omitempty"`
+	SourceDigest      string                 `json:"sourceDigest,omitempty"`
+	DestinationDigest string                 `json:"destinationDigest,omitempty"`
+	Size              int64                  `json:"size,omitempty"`
+	Duration          string                 `json:"duration"`
+	Status            string                 `json:"status"`
+	Verification      string                 `json:"verification"`
+	Platforms         []PlatformVerification `json:"platforms,omitempty"`
+	Error             string                 `json:"error,omitempty"`
+}
+
+// ImageSyncResults returns the result of every image synchronized so far, in configuration order
+func (m *Manager) ImageSyncResults() []ImageSyncResult {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+
+	var results []ImageSyncResult
+	for _, image := range m.SelectedImages() {
+		if result, ok := m.results[image.Name]; ok {
+			results = append(results, *result)
+		}
+	}
+	return results
+}
+
+// ImageSyncResult returns the result of synchronizing the named image, if it was synchronized
+func (m *Manager) ImageSyncResult(name string) (ImageSyncResult, bool) {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+
+	result, ok := m.results[name]
+	if !ok {
+		return ImageSyncResult{}, false
+	}
+	return *result, true
+}
+
+func (m *Manager) recordSyncResult(result *ImageSyncResult) {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+	if m.results == nil {
+		m.results = make(map[string]*ImageSyncResult)
+	}
+	m.results[result.Name] = result
+}
+
+// completeSyncResult fills in the digests and size of a copied or skipped image. Lookups that
+// fail leave the fields empty rather than failing the sync.
+func (m *Manager) completeSyncResult(result *ImageSyncResult) {
+	images := m.config.Artifacts.Images
+	if result.DestinationDigest == "" {
+		if digest, err := crane.Digest(result.Destination, craneAuth(images.Client.Auth)...); err == nil {
+			result.DestinationDigest = digest
+		}
+	}
+	if result.SourceDigest == "" {
+		if digest, err := crane.Digest(result.Source, craneAuth(images.Vendor.Auth)...); err == nil {
+			result.SourceDigest = digest
+		}
+	}
+	if size, err := imageSize(result.Destination, craneAuth(images.Client.Auth)...); err == nil {
+		result.Size = size
+	}
+}
+
+// imageSize sums the config and distinct layer sizes of an image, over every platform of a
+// multi-arch index
+func imageSize(ref string, options ...crane.Option) (int64, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return 0, err
+	}
+	manifest, err := fetchManifest(ref, options...)
+	if err != nil {
+		return 0, err
+	}
+
+	manifests := []registryManifest{manifest}
+	if len(manifest.Manifests) > 0 {
+		manifests = manifests[:0]
+		for _, child := range manifest.Manifests {
+			platform, err := fetchManifest(parsed.Context().Digest(child.Digest).String(), options...)
+			if err != nil {
+				return 0, err
+			}
+			manifests = append(manifests, platform)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var size int64
+	for _, platform := range manifests {
+		for _, blob := range append([]registryDescriptor{platform.Config}, platform.Layers...) {
+			if blob.Digest == "" || seen[blob.Digest] {
+				continue
+			}
+			seen[blob.Digest] = true
+			size += blob.Size
+		}
+	}
+	return size, nil
+}
+
+// registryManifest holds the fields shared by image manifests and indexes, OCI or Docker
+type registryManifest struct {
+	MediaType string               `json:"mediaType"`
+	Config    registryDescriptor   `json:"config"`
+	Layers    []registryDescriptor `json:"layers"`
+	Manifests []registryDescriptor `json:"manifests"`
+}
+
+type registryDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant,omitempty"`
+	} `json:"platform,omitempty"`
+}
+
+func fetchManifest(ref string, options ...crane.Option) (registryManifest, error) {
+	var manifest registryManifest
+	data, err := crane.Manifest(ref, options...)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse manifest of %s: %w", ref, err)
+	}
+	return manifest, nil
+}
+
+// craneAuth returns the crane option authenticating with a configured registry, if any
+func craneAuth(auth config.AuthConfig) []crane.Option {
+	switch {
+	case auth.Token != "":
+		return []crane.Option{crane.WithAuth(authn.FromConfig(authn.AuthConfig{Auth: auth.Token}))}
+	case auth.Username != "":
+		return []crane.Option{crane.WithAuth(authn.FromConfig(authn.AuthConfig{Username: auth.Username, Password: auth.Password}))}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"gopkg.in/yaml.v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Update severities, named after the first version component that changes
+const (
+	SeverityMajor = "major"
+	SeverityMinor = "minor"
+	SeverityPatch = "patch"
+)
+
+// severityRank orders severities for --fail-on, patch lowest
+var severityRank = map[string]int{SeverityPatch: 1, SeverityMinor: 2, SeverityMajor: 3}
+
+// Update kinds
+const (
+	UpdateKindImage     = "image"
+	UpdateKindChart     = "chart"
+	UpdateKindChartRepo = "chart-repo"
+	UpdateKindTerraform = "terraform"
+)
+
+// Update is a newer version of a configured artifact available from its vendor source
+type Update struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest"`
+	Severity string `json:"severity"`
+	Source   string `json:"source"`
+}
+
+// UpdateCheck is the outcome of CheckUpdates. Sources that could not be queried are listed
+// in Errors rather than failing the whole check.
+type UpdateCheck struct {
+	Checked int      `json:"checked"`
+	Updates []Update `json:"updates"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// AtLeast reports whether an update of the given severity or above is available
+func (c *UpdateCheck) AtLeast(severity string) bool {
+	for _, update := range c.Updates {
+		if severityRank[update.Severity] >= severityRank[severity] {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidSeverity reports whether severity is patch, minor or major
+func ValidSeverity(severity string) bool {
+	return severityRank[severity] > 0
+}
+
+// CheckUpdates compares the configured image tags, chart and Terraform repository tags and
+// chart versions with the newest semantic versions their vendor sources offer
+func (m *Manager) CheckUpdates() *UpdateCheck {
+	check := &UpdateCheck{}
+	m.checkImageUpdates(check)
+
+	helmVendor := m.config.Artifacts.Helm.Vendor
+	latestChartsRef := m.checkRepoUpdate(check, UpdateKindChartRepo, "helm charts", helmVendor)
+	if len(m.config.Artifacts.Helm.Charts) > 0 {
+		m.checkChartUpdates(check, helmVendor, latestChartsRef)
+	}
+	m.checkRepoUpdate(check, UpdateKindTerraform, "terraform modules", m.config.Artifacts.Terraform.Vendor)
+
+	sort.SliceStable(check.Updates, func(i, j int) bool {
+		return severityRank[check.Updates[i].Severity] > severityRank[check.Updates[j].Severity]
+	})
+	return check
+}
+
+func (m *Manager) checkImageUpdates(check *UpdateCheck) {
+	vendor := m.config.Artifacts.Images.Vendor
+	auth := craneAuth(vendor.Auth)
+	for _, image := range m.config.Artifacts.Images.Images {
+		repo := vendor.Registry + "/" + image.Name
+		check.Checked++
+		tags, err := crane.ListTags(repo, auth...)
+		if err != nil {
+			check.Errors = append(check.Errors, fmt.Sprintf("image %s: failed to list tags: %v", image.Name, err))
+			continue
+		}
+		if latest, severity, ok := newestVersion(image.Version, tags); ok {
+			check.Updates = append(check.Updates, Update{Kind: UpdateKindImage, Name: image.Name,
+				Current: image.Version, Latest: latest, Severity: severity, Source: repo})
+		}
+	}
+}
+
+// checkRepoUpdate compares the tag a vendor repository is synced at with its newest tag. It
+// returns the ref holding the newest content: that tag, or the configured branch.
+func (m *Manager) checkRepoUpdate(check *UpdateCheck, kind, name string, repo config.GitRepoConfig) plumbing.ReferenceName {
+	if repo.Repo == "" {
+		return ""
+	}
+	if repo.Tag == "" {
+		logger.Debug("Repository follows a branch, no tag to compare").Str("repo", repo.Repo).Send()
+		return gitRefName(repo)
+	}
+	check.Checked++
+	tags, err := remoteTags(repo)
+	if err != nil {
+		check.Errors = append(check.Errors, fmt.Sprintf("%s: %v", name, err))
+		return ""
+	}
+	latest, severity, ok := newestVersion(repo.Tag, tags)
+	if !ok {
+		return gitRefName(repo)
+	}
+	check.Updates = append(check.Updates, Update{Kind: kind, Name: name,
+		Current: repo.Tag, Latest: latest, Severity: severity, Source: repo.Repo})
+	return plumbing.NewTagReferenceName(latest)
+}
+
+// checkChartUpdates reads the Chart.yaml of every configured chart at ref of the vendor
+// repository, cloned shallowly in memory, and compares its version with the configured one
+func (m *Manager) checkChartUpdates(check *UpdateCheck, repo config.GitRepoConfig, ref plumbing.ReferenceName) {
+	if ref == "" {
+		return
+	}
+	options := &git.CloneOptions{URL: repo.Repo, Auth: gitAuth(repo.Auth), Depth: 1, SingleBranch: true}
+	if ref != plumbing.HEAD {
+		options.ReferenceName = ref
+	}
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), options)
+	if err != nil {
+		check.Errors = append(check.Errors, fmt.Sprintf("helm charts: failed to fetch %s: %v", ref.Short(), err))
+		return
+	}
+	worktree, err := clone.Worktree()
+	if err != nil {
+		check.Errors = append(check.Errors, fmt.Sprintf("helm charts: %v", err))
+		return
+	}
+
+	for _, chart := range m.config.Artifacts.Helm.Charts {
+		if chart.Version == "" {
+			continue
+		}
+		check.Checked++
+		file, err := worktree.Filesystem.Open(path.Join(path.Clean(chart.Path), "Chart.yaml"))
+		if err != nil {
+			check.Errors = append(check.Errors, fmt.Sprintf("chart %s: no Chart.yaml at %s in %s", chart.Name, chart.Path, ref.Short()))
+			continue
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		var metadata chartMetadata
+		if err == nil {
+			err = yaml.Unmarshal(data, &metadata)
+		}
+		if err != nil {
+			check.Errors = append(check.Errors, fmt.Sprintf("chart %s: failed to read Chart.yaml: %v", chart.Name, err))
+			continue
+		}
+		if latest, severity, ok := newestVersion(chart.Version, []string{metadata.Version}); ok {
+			check.Updates = append(check.Updates, Update{Kind: UpdateKindChart, Name: chart.Name,
+				Current: chart.Version, Latest: latest, Severity: severity, Source: repo.Repo + "@" + ref.Short()})
+		}
+	}
+}
+
+// remoteTags lists the tags of a remote git repository
+func remoteTags(repo config.GitRepoConfig) ([]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repo.Repo},
+	})
+	refs, err := remote.List(&git.ListOptions{Auth: gitAuth(repo.Auth)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote refs: %w", err)
+	}
+	var tags []string
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	return tags, nil
+}
+
+// newestVersion returns the highest candidate newer than current and the severity of the
+// update. Candidates that are not semantic versions are ignored, as are pre-releases unless
+// current is one.
+func newestVersion(current string, candidates []string) (string, string, bool) {
+	from, err := semver.NewVersion(current)
+	if err != nil {
+		return "", "", false
+	}
+	var newest *semver.Version
+	latest := ""
+	for _, candidate := range candidates {
+		version, err := semver.NewVersion(candidate)
+		if err != nil || (version.Prerelease() != "" && from.Prerelease() == "") {
+			continue
+		}
+		if version.GreaterThan(from) && (newest == nil || version.GreaterThan(newest)) {
+			newest, latest = version, candidate
+		}
+	}
+	if newest == nil {
+		return "", "", false
+	}
+	switch {
+	case newest.Major() != from.Major():
+		return latest, SeverityMajor, true
+	case newest.Minor() != from.Minor():
+		return latest, SeverityMinor, true
+	}
+	return latest, SeverityPatch, true
+}
+
+// Severities lists the update severities from lowest to highest
+func Severities() []string {
+	return []string{SeverityPatch, SeverityMinor, SeverityMajor}
+}
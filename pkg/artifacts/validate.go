@@ -0,0 +1,200 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// validateConcurrency bounds the registry requests made at once by ValidateImages
+const validateConcurrency = 10
+
+// Reasons an image is not accessible, used to group validation failures
+const (
+	ReasonNotFound     = "not found"
+	ReasonUnauthorized = "unauthorized"
+	ReasonUnreachable  = "unreachable"
+	ReasonInvalid      = "invalid reference"
+	ReasonOther        = "error"
+)
+
+// ImageFailure is an image that could not be found in any configured registry
+type ImageFailure struct {
+	Image    config.ImageReference
+	Registry string
+	Reason   string
+	Err      error
+}
+
+// ImageValidationError reports every required image that is not accessible
+type ImageValidationError struct {
+	Total    int
+	Failures []ImageFailure
+}
+
+// Error lists the failed images grouped by registry and reason
+func (e *ImageValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d required images not accessible:", len(e.Failures), e.Total)
+	for _, group := range groupImageFailures(e.Failures) {
+		fmt.Fprintf(&b, "\n  %s (%s): %s", group.registry, group.reason, strings.Join(group.images, ", "))
+	}
+	return b.String()
+}
+
+// ValidateImages checks that every selected image is accessible, querying the registries
+// in parallel. Optional images that fail are logged; all required images that fail are
+// returned together as an *ImageValidationError.
+func (m *Manager) ValidateImages() error {
+	images := m.SelectedImages()
+	logger.Info("Validating image accessibility").Int("images", len(images)).Send()
+
+	failures := make([]*ImageFailure, len(images))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, validateConcurrency)
+
+	for i, image := range images {
+		wg.Add(1)
+		go func(index int, img config.ImageReference) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			registry, err := m.validateSingleImage(img)
+			if err != nil {
+				failures[index] = &ImageFailure{Image: img, Registry: registry, Reason: imageFailureReason(err), Err: err}
+			}
+		}(i, image)
+	}
+	wg.Wait()
+
+	var required, optional []ImageFailure
+	for _, failure := range failures {
+		if failure == nil {
+			continue
+		}
+		logger.Debug("Image not accessible").
+			Str("image", failure.Image.Name).
+			Str("version", failure.Image.Version).
+			Err(failure.Err).
+			Send()
+		if failure.Image.Required {
+			required = append(required, *failure)
+		} else {
+			optional = append(optional, *failure)
+		}
+	}
+
+	for _, group := range groupImageFailures(optional) {
+		logger.Warn("Optional images not accessible").
+			Str("registry", group.registry).
+			Str("reason", group.reason).
+			Str("images", strings.Join(group.images, ", ")).
+			Send()
+	}
+
+	if len(required) > 0 {
+		requiredTotal := 0
+		for _, image := range images {
+			if image.Required {
+				requiredTotal++
+			}
+		}
+		return &ImageValidationError{Total: requiredTotal, Failures: required}
+	}
+
+	logger.Info("Images validated").
+		Int("accessible", len(images)-len(optional)).
+		Int("optional_missing", len(optional)).
+		Send()
+	return nil
+}
+
+// validateSingleImage checks the vendor registry, then the client registry if configured,
+// returning the last registry tried when the image is in neither
+func (m *Manager) validateSingleImage(image config.ImageReference) (string, error) {
+	images := m.config.Artifacts.Images
+	vendorRef := fmt.Sprintf("%s/%s:%s", images.Vendor.Registry, image.Name, image.Version)
+
+	err := m.validateImageExists(vendorRef, images.Vendor.Auth)
+	if err == nil {
+		return "", nil
+	}
+	if images.Client.Registry == "" {
+		return images.Vendor.Registry, err
+	}
+
+	clientRef := fmt.Sprintf("%s/%s:%s", images.Client.Registry, image.Name, image.Version)
+	if clientErr := m.validateImageExists(clientRef, images.Client.Auth); clientErr != nil {
+		return images.Client.Registry, fmt.Errorf("%w (vendor registry: %v)", clientErr, err)
+	}
+	return "", nil
+}
+
+// imageFailureReason classifies a registry error for grouping
+func imageFailureReason(err error) string {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		switch transportErr.StatusCode {
+		case http.StatusNotFound:
+			return ReasonNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ReasonUnauthorized
+		}
+		for _, diagnostic := range transportErr.Errors {
+			switch diagnostic.Code {
+			case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode:
+				return ReasonNotFound
+			case transport.UnauthorizedErrorCode, transport.DeniedErrorCode:
+				return ReasonUnauthorized
+			}
+		}
+		return ReasonOther
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ReasonUnreachable
+	}
+	if strings.Contains(err.Error(), "invalid image reference") {
+		return ReasonInvalid
+	}
+	return ReasonOther
+}
+
+type imageFailureGroup struct {
+	registry string
+	reason   string
+	images   []string
+}
+
+// groupImageFailures groups failures by registry and reason, largest groups first
+func groupImageFailures(failures []ImageFailure) []imageFailureGroup {
+	index := make(map[string]int)
+	var groups []imageFailureGroup
+	for _, failure := range failures {
+		key := failure.Registry + "\x00" + failure.Reason
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, imageFailureGroup{registry: failure.Registry, reason: failure.Reason})
+		}
+		groups[i].images = append(groups[i].images, failure.Image.Name+":"+failure.Image.Version)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].images) > len(groups[j].images)
+	})
+	return groups
+}
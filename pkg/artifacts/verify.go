@@ -0,0 +1,99 @@
+package artifacts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// PlatformVerification compares one platform manifest of a multi-arch image
+type PlatformVerification struct {
+	Platform          string `json:"platform"`
+	SourceDigest      string `json:"sourceDigest"`
+	DestinationDigest string `json:"destinationDigest,omitempty"`
+	Match             bool   `json:"match"`
+}
+
+// verifyImageCopy checks that the destination serves the same manifest as the source, and for a
+// multi-arch index the same manifest for each platform. crane copies manifests byte for byte,
+// so any difference means a proxy or registry in between rewrote the image.
+func (m *Manager) verifyImageCopy(result *ImageSyncResult) error {
+	if result.SourceDigest == "" || result.DestinationDigest == "" {
+		result.Verification = VerificationUnverified
+		logger.Warn("Could not resolve image digests, copy not verified").
+			Str("source", result.Source).
+			Str("destination", result.Destination).
+			Send()
+		return nil
+	}
+
+	var mismatches []string
+	if result.SourceDigest != result.DestinationDigest {
+		mismatches = append(mismatches, fmt.Sprintf("manifest %s became %s", result.SourceDigest, result.DestinationDigest))
+	}
+
+	platforms, err := m.verifyPlatforms(result)
+	if err != nil {
+		logger.Warn("Failed to compare platform manifests").Str("image", result.Destination).Err(err).Send()
+	}
+	result.Platforms = platforms
+	for _, platform := range platforms {
+		if !platform.Match {
+			mismatches = append(mismatches, fmt.Sprintf("%s manifest %s became %s", platform.Platform, platform.SourceDigest, platform.DestinationDigest))
+		}
+	}
+
+	if len(mismatches) == 0 {
+		result.Verification = VerificationMatch
+		return nil
+	}
+
+	result.Verification = VerificationMismatch
+	verifyErr := fmt.Errorf("image %s differs from its source after copy (%s); a proxy or registry may have modified it",
+		result.Destination, strings.Join(mismatches, "; "))
+	audit.Record("image.verify", result.Destination, map[string]interface{}{
+		"source":     result.Source,
+		"mismatches": mismatches,
+	}, verifyErr)
+	return verifyErr
+}
+
+// verifyPlatforms pairs the platform manifests of a source index with the destination's
+func (m *Manager) verifyPlatforms(result *ImageSyncResult) ([]PlatformVerification, error) {
+	images := m.config.Artifacts.Images
+	source, err := fetchManifest(result.Source, craneAuth(images.Vendor.Auth)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(source.Manifests) == 0 {
+		return nil, nil
+	}
+	destination, err := fetchManifest(result.Destination, craneAuth(images.Client.Auth)...)
+	if err != nil {
+		return nil, err
+	}
+
+	platforms := make([]PlatformVerification, len(source.Manifests))
+	for i, child := range source.Manifests {
+		platforms[i] = PlatformVerification{Platform: platformName(child, i), SourceDigest: child.Digest}
+		if i < len(destination.Manifests) {
+			platforms[i].DestinationDigest = destination.Manifests[i].Digest
+		}
+		platforms[i].Match = platforms[i].DestinationDigest == child.Digest
+	}
+	return platforms, nil
+}
+
+// platformName formats os/architecture[/variant], or the manifest position when it has no platform
+func platformName(descriptor registryDescriptor, index int) string {
+	if descriptor.Platform == nil {
+		return fmt.Sprintf("manifest %d", index)
+	}
+	name := descriptor.Platform.OS + "/" + descriptor.Platform.Architecture
+	if descriptor.Platform.Variant != "" {
+		name += "/" + descriptor.Platform.Variant
+	}
+	return name
+}
@@ -0,0 +1,309 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// SigningKeyEnv is the environment variable holding the HMAC key used to sign entries
+const SigningKeyEnv = "E2E_INSTALLER_AUDIT_KEY"
+
+// DefaultPath is the audit log of the default workspace
+const DefaultPath = "./workspace/logs/audit.jsonl"
+
+// genesisHash is the previous-hash value of the first entry in a chain
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Entry is a single audit record of a mutating action
+type Entry struct {
+	Sequence  int64                  `json:"seq"`
+	Timestamp time.Time              `json:"timestamp"`
+	Action    string                 `json:"action"`
+	Target    string                 `json:"target"`
+	Status    string                 `json:"status"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	User      string                 `json:"user"`
+	Host      string                 `json:"host"`
+	PrevHash  string                 `json:"prevHash"`
+	Hash      string                 `json:"hash"`
+	Signature string                 `json:"signature,omitempty"`
+}
+
+// Logger appends hash-chained entries to a JSONL audit file
+type Logger struct {
+	path     string
+	key      []byte
+	user     string
+	host     string
+	mutex    sync.Mutex
+	sequence int64
+	lastHash string
+}
+
+// NewLogger opens (or creates) the audit log at path and restores the hash chain
+func NewLogger(path string, signingKey []byte) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	l := &Logger{
+		path:     path,
+		key:      signingKey,
+		lastHash: genesisHash,
+	}
+
+	if u, err := user.Current(); err == nil {
+		l.user = u.Username
+	}
+	if h, err := os.Hostname(); err == nil {
+		l.host = h
+	}
+
+	entries, err := ReadEntries(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read existing audit log: %w", err)
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		l.sequence = last.Sequence
+		l.lastHash = last.Hash
+	}
+
+	return l, nil
+}
+
+// Record appends an entry for the given action; a non-nil opErr marks it as failed
+func (l *Logger) Record(action, target string, details map[string]interface{}, opErr error) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry := Entry{
+		Sequence:  l.sequence + 1,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Target:    target,
+		Status:    "succeeded",
+		Details:   details,
+		User:      l.user,
+		Host:      l.host,
+		PrevHash:  l.lastHash,
+	}
+	if opErr != nil {
+		entry.Status = "failed"
+		entry.Error = opErr.Error()
+	}
+
+	hash, err := entryHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	if len(l.key) > 0 {
+		entry.Signature = sign(l.key, hash)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	l.sequence = entry.Sequence
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// Path returns the location of the audit log file
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// ReadEntries reads all entries from an audit log file
+func ReadEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid audit entry after seq %d: %w", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Verify checks the hash chain (and signatures when a key is given) of an audit log
+func Verify(path string, signingKey []byte) (int, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	prev := genesisHash
+	for i, entry := range entries {
+		if entry.PrevHash != prev {
+			return i, fmt.Errorf("audit chain broken at seq %d: previous hash mismatch", entry.Sequence)
+		}
+
+		expected, err := entryHash(entry)
+		if err != nil {
+			return i, err
+		}
+		if entry.Hash != expected {
+			return i, fmt.Errorf("audit entry seq %d has been modified: hash mismatch", entry.Sequence)
+		}
+
+		if len(signingKey) > 0 && !hmac.Equal([]byte(entry.Signature), []byte(sign(signingKey, entry.Hash))) {
+			return i, fmt.Errorf("audit entry seq %d has an invalid signature", entry.Sequence)
+		}
+
+		prev = entry.Hash
+	}
+
+	return len(entries), nil
+}
+
+// entryHash computes the chain hash of an entry excluding its hash and signature fields
+func entryHash(entry Entry) (string, error) {
+	entry.Hash = ""
+	entry.Signature = ""
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit entry for hashing: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func sign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SigningKey resolves the signing key from the audit configuration or environment
+func SigningKey(auditConfig config.AuditConfig) ([]byte, error) {
+	if auditConfig.SigningKeyFile != "" {
+		key, err := os.ReadFile(auditConfig.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit signing key: %w", err)
+		}
+		return key, nil
+	}
+	if key := os.Getenv(SigningKeyEnv); key != "" {
+		return []byte(key), nil
+	}
+	return nil, nil
+}
+
+// Global audit logger instance
+var (
+	globalLogger *Logger
+	globalMutex  sync.Mutex
+
+	// The workspace and settings of the log opened on first use when no command initialized one
+	defaultWorkspace   = filepath.Dir(filepath.Dir(DefaultPath))
+	defaultAuditConfig config.AuditConfig
+)
+
+// Configure sets the workspace and audit settings of the global audit log without opening it,
+// so commands that never record an entry leave no log behind
+func Configure(workspace string, auditConfig config.AuditConfig) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	defaultWorkspace, defaultAuditConfig = workspace, auditConfig
+}
+
+// InitGlobalAuditLog initializes the global audit log for a workspace
+func InitGlobalAuditLog(workspace string, auditConfig config.AuditConfig) error {
+	l, err := openLogger(workspace, auditConfig)
+	if err != nil {
+		return err
+	}
+
+	globalMutex.Lock()
+	globalLogger = l
+	globalMutex.Unlock()
+	return nil
+}
+
+// openLogger opens the audit log of a workspace, or the configured path, signed with the
+// configured key
+func openLogger(workspace string, auditConfig config.AuditConfig) (*Logger, error) {
+	path := auditConfig.Path
+	if path == "" {
+		path = filepath.Join(workspace, "logs", "audit.jsonl")
+	}
+
+	key, err := SigningKey(auditConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(path, key)
+}
+
+// GetAuditLogger returns the global audit logger, opening the configured one if needed
+func GetAuditLogger() *Logger {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	if globalLogger == nil {
+		l, err := openLogger(defaultWorkspace, defaultAuditConfig)
+		if err != nil {
+			logger.Warn("Failed to open audit log").Err(err).Send()
+			return nil
+		}
+		globalLogger = l
+	}
+	return globalLogger
+}
+
+// Record appends an entry to the global audit log; failures are logged, never fatal
+func Record(action, target string, details map[string]interface{}, opErr error) {
+	l := GetAuditLogger()
+	if l == nil {
+		return
+	}
+	if err := l.Record(action, target, details, opErr); err != nil {
+		logger.Warn("Failed to write audit entry").
+			Str("action", action).
+			Str("target", target).
+			Err(err).
+			Send()
+	}
+}
@@ -0,0 +1,280 @@
+// Package cassette records the external interactions of a run, the commands it ran with
+// their output, the requests to the Kubernetes API and the HTTP exchanges with registries,
+// git servers and other services, into a cassette file. Replaying the cassette answers the same calls with the recorded results,
+// so support can reproduce the failure path of a customer's run without their environment.
+package cassette
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+)
+
+// FormatVersion is the version of the cassette file format. Version 2 records the
+// Kubernetes API requests kubectl commands stood in for in version 1.
+const FormatVersion = 2
+
+// Interaction kinds
+const (
+	KindCommand = "command"
+	KindHTTP    = "http"
+	KindAPI     = "api" // Kubernetes API requests, by path so a replay needs no cluster address
+)
+
+// Cassette is the recording of one run
+type Cassette struct {
+	Version      int           `json:"version"`
+	Installer    string        `json:"installer"` // version of the installer that recorded it
+	Command      string        `json:"command"`
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one command or HTTP exchange, in the order the run made them
+type Interaction struct {
+	Kind     string `json:"kind"`
+	Duration string `json:"duration"`
+
+	// Commands, with the command line masked
+	Command  string `json:"command,omitempty"`
+	Dir      string `json:"dir,omitempty"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+
+	// HTTP and Kubernetes API requests
+	Method string      `json:"method,omitempty"`
+	URL    string      `json:"url,omitempty"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+
+	// Error is a failure with no exit code or response, e.g. a timeout or a refused connection
+	Error string `json:"error,omitempty"`
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if cassette.Version != FormatVersion {
+		return nil, fmt.Errorf("cassette %s has format version %d, this installer reads version %d", path, cassette.Version, FormatVersion)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path. It holds command output, so only the owner may read it.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Mask replaces every secret in the recorded command lines, output and bodies with ***
+func (c *Cassette) Mask(secrets []string) {
+	for i := range c.Interactions {
+		interaction := &c.Interactions[i]
+		interaction.Command = execx.Mask(interaction.Command, secrets)
+		interaction.Stdout = execx.Mask(interaction.Stdout, secrets)
+		interaction.Stderr = execx.Mask(interaction.Stderr, secrets)
+		interaction.URL = execx.Mask(interaction.URL, secrets)
+		interaction.Error = execx.Mask(interaction.Error, secrets)
+		interaction.Body = maskBody(interaction.Body, secrets)
+		if interaction.Kind == KindAPI {
+			interaction.Body = maskSecretData(interaction.Body, secrets)
+		}
+	}
+}
+
+func maskBody(body []byte, secrets []string) []byte {
+	for _, secret := range secrets {
+		if secret != "" {
+			body = bytes.ReplaceAll(body, []byte(secret), []byte("***"))
+		}
+	}
+	return body
+}
+
+// releaseSecretType is the type of the Secrets Helm stores releases in, whose release is
+// gzipped JSON in base64 on top of the Secret's own base64
+const releaseSecretType = "helm.sh/release.v1"
+
+// maskSecretData masks the secrets in the data of a Kubernetes Secret or list of Secrets,
+// which are base64 and so out of reach of the masking of the body, e.g. the values of a
+// Helm release. A body with nothing to mask is kept as it is.
+func maskSecretData(body []byte, secrets []string) []byte {
+	var document map[string]interface{}
+	if json.Unmarshal(body, &document) != nil {
+		return body
+	}
+	objects := []interface{}{document}
+	if document["kind"] == "SecretList" {
+		objects, _ = document["items"].([]interface{})
+	}
+	masked := false
+	for _, object := range objects {
+		secret, _ := object.(map[string]interface{})
+		data, _ := secret["data"].(map[string]interface{})
+		for key, value := range data {
+			encoded, _ := value.(string)
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				continue
+			}
+			var replaced []byte
+			if secret["type"] == releaseSecretType && key == "release" {
+				replaced, err = maskRelease(decoded, secrets)
+				if err != nil {
+					continue
+				}
+			} else {
+				replaced = maskBody(decoded, secrets)
+			}
+			if !bytes.Equal(replaced, decoded) {
+				data[key] = base64.StdEncoding.EncodeToString(replaced)
+				masked = true
+			}
+		}
+	}
+	if !masked {
+		return body
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// maskRelease masks the secrets in a Helm release as its storage Secret holds it
+func maskRelease(encoded []byte, secrets []string) ([]byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	release, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	masked := maskBody(release, secrets)
+	if bytes.Equal(masked, release) {
+		return encoded, nil
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(masked); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// sensitiveHeaders are dropped from recorded responses
+var sensitiveHeaders = []string{"Set-Cookie"}
+
+// tokenFields hold the bearer tokens registries and git servers hand out
+var tokenFields = []string{"token", "access_token", "refresh_token", "id_token"}
+
+// redactTokens masks the tokens of a token endpoint response, or of a Kubernetes
+// TokenRequest. Replays accept any token, so they do not need the real one.
+func redactTokens(body []byte) []byte {
+	var document map[string]interface{}
+	if json.Unmarshal(body, &document) != nil {
+		return body
+	}
+	redacted := false
+	for _, field := range tokenFields {
+		if _, ok := document[field]; ok {
+			document[field] = "***"
+			redacted = true
+		}
+	}
+	if status, ok := document["status"].(map[string]interface{}); ok && document["kind"] == "TokenRequest" {
+		status["token"] = "***"
+		redacted = true
+	}
+	if !redacted {
+		return body
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// hooks swaps the transports the installer's HTTP requests go through: net/http's default,
+// go-containerregistry's default for registry calls, and go-git's for git remotes
+type hooks struct {
+	previousTransport http.RoundTripper
+	previousRemote    http.RoundTripper
+}
+
+func install(transport http.RoundTripper) *hooks {
+	h := &hooks{previousTransport: http.DefaultTransport, previousRemote: remote.DefaultTransport}
+	http.DefaultTransport, remote.DefaultTransport = transport, transport
+	git := githttp.NewClient(&http.Client{Transport: transport})
+	client.InstallProtocol("https", git)
+	client.InstallProtocol("http", git)
+	return h
+}
+
+func (h *hooks) restore() {
+	http.DefaultTransport, remote.DefaultTransport = h.previousTransport, h.previousRemote
+	client.InstallProtocol("https", githttp.DefaultClient)
+	client.InstallProtocol("http", githttp.DefaultClient)
+}
+
+// roundTripper adapts a function to http.RoundTripper
+type roundTripper func(req *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// key identifies an interaction when replaying: the command line, or the method and URL
+func key(kind, command, method, url string) string {
+	if kind == KindCommand {
+		return kind + " " + command
+	}
+	return kind + " " + method + " " + url
+}
+
+// tool is the binary of a command line
+func tool(command string) string {
+	name, _, _ := strings.Cut(command, " ")
+	return filepath.Base(name)
+}
@@ -0,0 +1,186 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+)
+
+// Recorder writes the interactions of the run into a cassette
+type Recorder struct {
+	path     string
+	cassette Cassette
+	secrets  []string
+	hooks    *hooks
+	next     http.RoundTripper
+	mu       sync.Mutex
+}
+
+// Record starts recording every command, Kubernetes API and HTTP request of the process.
+// command names the run.
+func Record(path, command string) *Recorder {
+	r := &Recorder{
+		path: path,
+		cassette: Cassette{
+			Version:    FormatVersion,
+			Installer:  version.Version,
+			Command:    command,
+			RecordedAt: time.Now().UTC(),
+		},
+		next: http.DefaultTransport,
+	}
+	r.hooks = install(r)
+	execx.Observe(r.observe)
+	k8s.Observe(r.API)
+	logger.Info("Recording external calls").Str("cassette", path).Send()
+	return r
+}
+
+// Stop ends the recording and saves the cassette with the secrets of the run masked
+func (r *Recorder) Stop() error {
+	execx.Observe(nil)
+	k8s.Observe(nil)
+	r.hooks.restore()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secrets := r.secrets
+	if store := params.GetStore(); store != nil {
+		secrets = append(secrets, store.SensitiveValues()...)
+	}
+	r.cassette.Mask(secrets)
+	if err := r.cassette.Save(r.path); err != nil {
+		return err
+	}
+	logger.Info("Cassette saved").Str("path", r.path).Int("interactions", len(r.cassette.Interactions)).Send()
+	return nil
+}
+
+// add records an interaction, returning its index
+func (r *Recorder) add(interaction Interaction, secrets ...string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.secrets = append(r.secrets, secrets...)
+	return len(r.cassette.Interactions) - 1
+}
+
+func (r *Recorder) observe(c *execx.Cmd, stdout, stderr []byte, err error, duration time.Duration) {
+	interaction := Interaction{
+		Kind:     KindCommand,
+		Duration: duration.Round(time.Millisecond).String(),
+		Command:  c.String(),
+		Dir:      c.Dir,
+		Stdout:   string(stdout),
+		Stderr:   string(stderr),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		interaction.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		interaction.Error = err.Error()
+	}
+	r.add(interaction, c.Secrets...)
+}
+
+// RoundTrip sends the request on and records the response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.exchange(Interaction{Kind: KindHTTP, Method: req.Method, URL: req.URL.String()}, req, r.next)
+}
+
+// API wraps the transport of a Kubernetes API client, recording its requests by path
+func (r *Recorder) API(next http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		return r.exchange(Interaction{Kind: KindAPI, Method: req.Method, URL: req.URL.RequestURI()}, req, next)
+	})
+}
+
+// exchange sends the request of an interaction on to next and records the response
+func (r *Recorder) exchange(interaction Interaction, req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	interaction.Duration = time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		interaction.Error = err.Error()
+		r.add(interaction)
+		return nil, err
+	}
+
+	interaction.Status = resp.StatusCode
+	interaction.Header = resp.Header.Clone()
+	for _, header := range sensitiveHeaders {
+		interaction.Header.Del(header)
+	}
+	secrets := []string{}
+	if _, password, ok := req.BasicAuth(); ok {
+		secrets = append(secrets, password)
+	}
+
+	if watching(req) {
+		// A watch streams its events until the client is done with it, so its body is
+		// recorded as the client reads it rather than read up front
+		i := r.add(interaction, secrets...)
+		resp.Body = &stream{ReadCloser: resp.Body, closed: func(body []byte) { r.setBody(i, body) }}
+		return resp, nil
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil {
+		interaction.Error = readErr.Error()
+	}
+	interaction.Body = redactTokens(body)
+	r.add(interaction, secrets...)
+	return resp, readErr
+}
+
+func (r *Recorder) setBody(i int, body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions[i].Body = body
+}
+
+// watching reports whether a Kubernetes API request watches its resources
+func watching(req *http.Request) bool {
+	watch := req.URL.Query().Get("watch")
+	return watch == "true" || watch == "1"
+}
+
+// stream is a response body recorded as it is read, handed to closed when it is closed
+type stream struct {
+	io.ReadCloser
+	mu     sync.Mutex
+	body   bytes.Buffer
+	once   sync.Once
+	closed func(body []byte)
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.mu.Lock()
+	s.body.Write(p[:n])
+	s.mu.Unlock()
+	return n, err
+}
+
+func (s *stream) Close() error {
+	err := s.ReadCloser.Close()
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed(bytes.Clone(s.body.Bytes()))
+	})
+	return err
+}
@@ -0,0 +1,171 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+// Player answers the commands, Kubernetes API and HTTP requests of the process from a cassette. Calls are
+// matched on the command line, or on method and URL, in recorded order; a call made more
+// often than recorded, such as a status poll, gets the last recorded answer again. A command
+// the recording does not have gets the next unused recording of the same tool, so a run that
+// drifts slightly from the recorded one keeps going.
+type Player struct {
+	cassette *Cassette
+	queues   map[string][]int
+	used     []bool
+	hooks    *hooks
+	mu       sync.Mutex
+
+	replayed, substituted, missing int
+}
+
+// Replay loads the cassette at path and routes every command, Kubernetes API and HTTP request
+// to it until Stop
+func Replay(path string) (*Player, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Player{cassette: cassette, queues: make(map[string][]int), used: make([]bool, len(cassette.Interactions))}
+	for i, interaction := range cassette.Interactions {
+		k := key(interaction.Kind, interaction.Command, interaction.Method, interaction.URL)
+		p.queues[k] = append(p.queues[k], i)
+	}
+	p.hooks = install(p)
+	execx.Simulate(p.run)
+	k8s.Simulate(p.API())
+	logger.Info("Replaying external calls").
+		Str("cassette", path).
+		Str("command", cassette.Command).
+		Str("recordedBy", cassette.Installer).
+		Time("recordedAt", cassette.RecordedAt).
+		Int("interactions", len(cassette.Interactions)).
+		Send()
+	return p, nil
+}
+
+// Stop routes commands and requests to the real tools and services again and logs how
+// closely the run followed the recording
+func (p *Player) Stop() {
+	execx.Simulate(nil)
+	k8s.Simulate(nil)
+	p.hooks.restore()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	unused := 0
+	for _, used := range p.used {
+		if !used {
+			unused++
+		}
+	}
+	event := logger.Info("Replay finished")
+	if p.substituted > 0 || p.missing > 0 {
+		event = logger.Warn("Replay diverged from the recording")
+	}
+	event.Int("replayed", p.replayed).Int("substituted", p.substituted).Int("missing", p.missing).Int("unused", unused).Send()
+}
+
+// next returns the recording answering a call
+func (p *Player) next(kind, command, method, url string) *Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k := key(kind, command, method, url)
+	if queue := p.queues[k]; len(queue) > 0 {
+		i := queue[0]
+		if len(queue) > 1 {
+			p.queues[k] = queue[1:]
+		}
+		p.used[i] = true
+		p.replayed++
+		return &p.cassette.Interactions[i]
+	}
+
+	if kind == KindCommand {
+		for i, interaction := range p.cassette.Interactions {
+			if !p.used[i] && interaction.Kind == KindCommand && tool(interaction.Command) == tool(command) {
+				p.used[i] = true
+				p.substituted++
+				logger.Warn("Command not in the recording, replaying the next one of the tool").
+					Str("command", command).
+					Str("recorded", interaction.Command).
+					Send()
+				return &p.cassette.Interactions[i]
+			}
+		}
+	}
+	p.missing++
+	logger.Warn("Call not in the recording").Str("call", k).Send()
+	return nil
+}
+
+func (p *Player) run(c *execx.Cmd, stdout, stderr io.Writer) error {
+	command := c.String()
+	if store := params.GetStore(); store != nil {
+		command = execx.Mask(command, store.SensitiveValues())
+	}
+	interaction := p.next(KindCommand, command, "", "")
+	if interaction == nil {
+		return fmt.Errorf("no recorded call for %s", command)
+	}
+	io.WriteString(stdout, interaction.Stdout)
+	io.WriteString(stderr, interaction.Stderr)
+	switch {
+	case interaction.ExitCode != 0:
+		return fmt.Errorf("exit status %d", interaction.ExitCode)
+	case interaction.Error != "":
+		return errors.New(interaction.Error)
+	}
+	return nil
+}
+
+// RoundTrip answers a request with its recorded response
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	return p.answer(KindHTTP, req.URL.String(), req)
+}
+
+// API answers the requests of the Kubernetes API clients with their recorded responses
+func (p *Player) API() http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		return p.answer(KindAPI, req.URL.RequestURI(), req)
+	})
+}
+
+// answer replays the response recorded for a request of a kind at url
+func (p *Player) answer(kind, url string, req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	interaction := p.next(kind, "", req.Method, url)
+	if interaction == nil {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	if interaction.Status == 0 {
+		return nil, errors.New(interaction.Error)
+	}
+	header := interaction.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}, nil
+}
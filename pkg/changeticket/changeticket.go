@@ -0,0 +1,242 @@
+package changeticket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// TokenEnv is the environment variable holding the password or API token of the ticket
+// service, over installer.changeTicket.tokenFile
+const TokenEnv = "E2E_INSTALLER_CHANGE_TOKEN"
+
+// Phases a change moves through, the keys of installer.changeTicket.states
+const (
+	PhaseImplement = "implement" // the run started
+	PhaseSucceeded = "succeeded"
+	PhaseFailed    = "failed"
+)
+
+// requestTimeout bounds each call to the ticket service, so an unresponsive service holds up
+// the run for seconds rather than indefinitely
+const requestTimeout = 15 * time.Second
+
+// Change describes the run a ticket is opened for
+type Change struct {
+	Summary     string
+	Description string
+	PlanName    string // file name the plan is attached as
+	Plan        []byte
+}
+
+// provider is the API of one ticket service. id identifies a ticket to the API, number to
+// people; they are the same for Jira.
+type provider interface {
+	create(ctx context.Context, change Change) (id, number string, err error)
+	find(ctx context.Context, number string) (id string, err error)
+	comment(ctx context.Context, id, text string) error
+	attach(ctx context.Context, id, name string, data []byte) error
+	transition(ctx context.Context, id, phase, notes string) error
+	link(id, number string) string
+}
+
+// Ticket is the change ticket of a run. Calls on a nil ticket do nothing, so runs without one
+// need no checks.
+type Ticket struct {
+	Number string // CHG0030001 or OPS-123, as operators refer to it
+	URL    string
+
+	id       string
+	provider provider
+}
+
+// Applies reports whether tickets are configured for the environment
+func Applies(cfg *config.ChangeTicketConfig, environment string) bool {
+	return cfg != nil && (len(cfg.Environments) == 0 || slices.Contains(cfg.Environments, environment))
+}
+
+// Open creates the change ticket of a run, or reports to the existing ticket number when
+// one is given, then attaches the plan and moves the ticket to implement. Only a ticket that
+// can be neither created nor found fails; later failures are logged.
+func Open(cfg config.ChangeTicketConfig, existing string, change Change) (*Ticket, error) {
+	p, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t := &Ticket{provider: p}
+	if existing != "" {
+		err = call(func(ctx context.Context) error {
+			t.id, err = p.find(ctx, existing)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("change ticket %s: %w", existing, err)
+		}
+		t.Number = existing
+		t.Note(change.Description)
+	} else {
+		err = call(func(ctx context.Context) error {
+			t.id, t.Number, err = p.create(ctx, change)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the change ticket: %w", err)
+		}
+	}
+	t.URL = p.link(t.id, t.Number)
+
+	if len(change.Plan) > 0 {
+		t.warn("attach the plan", call(func(ctx context.Context) error { return p.attach(ctx, t.id, change.PlanName, change.Plan) }))
+	}
+	t.warn("move the ticket to implement", call(func(ctx context.Context) error {
+		return p.transition(ctx, t.id, PhaseImplement, "Installation started")
+	}))
+	return t, nil
+}
+
+// Note adds a work note or comment to the ticket
+func (t *Ticket) Note(text string) {
+	if t == nil {
+		return
+	}
+	t.warn("add a note", call(func(ctx context.Context) error { return t.provider.comment(ctx, t.id, text) }))
+}
+
+// Close attaches the reports that exist and closes the ticket as succeeded or failed
+func (t *Ticket) Close(succeeded bool, notes string, reports ...string) {
+	if t == nil {
+		return
+	}
+	for _, report := range reports {
+		data, err := os.ReadFile(report)
+		if err != nil {
+			continue
+		}
+		t.warn("attach "+filepath.Base(report), call(func(ctx context.Context) error {
+			return t.provider.attach(ctx, t.id, filepath.Base(report), data)
+		}))
+	}
+	phase := PhaseSucceeded
+	if !succeeded {
+		phase = PhaseFailed
+	}
+	t.warn("close the ticket", call(func(ctx context.Context) error { return t.provider.transition(ctx, t.id, phase, notes) }))
+}
+
+func (t *Ticket) warn(action string, err error) {
+	if err != nil {
+		logger.Warn("Failed to update the change ticket").Str("ticket", t.Number).Str("action", action).Err(err).Send()
+	}
+}
+
+func call(request func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return request(ctx)
+}
+
+func newProvider(cfg config.ChangeTicketConfig) (provider, error) {
+	token := os.Getenv(TokenEnv)
+	if token == "" && cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read change ticket token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	a := api{base: strings.TrimSuffix(cfg.URL, "/"), username: cfg.Username, token: token, client: &http.Client{}}
+
+	switch cfg.Provider {
+	case "servicenow":
+		return &serviceNow{api: a, fields: cfg.Fields, states: withDefaults(cfg.States, serviceNowStates)}, nil
+	case "jira":
+		return &jira{
+			api:       a,
+			project:   cfg.Project,
+			issueType: cfg.IssueType,
+			fields:    cfg.Fields,
+			states:    withDefaults(cfg.States, jiraTransitions),
+		}, nil
+	}
+	return nil, fmt.Errorf("installer.changeTicket.provider: unsupported provider %q", cfg.Provider)
+}
+
+func withDefaults(states, defaults map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults))
+	for phase, state := range defaults {
+		merged[phase] = state
+	}
+	for phase, state := range states {
+		merged[phase] = state
+	}
+	return merged
+}
+
+// api sends authenticated JSON requests to a ticket service
+type api struct {
+	base     string
+	username string
+	token    string
+	client   *http.Client
+}
+
+// request sends body, JSON encoded unless it already is a reader, and decodes the response
+// into out when given. Any status but 2xx fails.
+func (a api) request(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, a.base+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if a.username != "" {
+		request.SetBasicAuth(a.username, a.token)
+	} else if a.token != "" {
+		request.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s %s answered %s: %s", method, request.URL.Path, response.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("unexpected response to %s %s: %w", method, request.URL.Path, err)
+	}
+	return nil
+}
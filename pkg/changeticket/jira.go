@@ -0,0 +1,151 @@
+package changeticket
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// jiraTransitions are the transitions of the phases in Jira's default workflow
+var jiraTransitions = map[string]string{
+	PhaseImplement: "In Progress",
+	PhaseSucceeded: "Done",
+	PhaseFailed:    "Done",
+}
+
+// jira records the change on an issue through the REST API v2
+type jira struct {
+	api
+	project   string
+	issueType string
+	fields    map[string]interface{}
+	states    map[string]string
+}
+
+func (j *jira) create(ctx context.Context, change Change) (string, string, error) {
+	fields := map[string]interface{}{}
+	for key, value := range j.fields {
+		fields[key] = value
+	}
+	issueType := j.issueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	fields["project"] = map[string]string{"key": j.project}
+	fields["issuetype"] = map[string]string{"name": issueType}
+	fields["summary"] = change.Summary
+	fields["description"] = change.Description
+
+	var issue struct {
+		Key string `json:"key"`
+	}
+	if err := j.request(ctx, http.MethodPost, "/rest/api/2/issue", nil, map[string]interface{}{"fields": fields}, &issue); err != nil {
+		return "", "", err
+	}
+	if issue.Key == "" {
+		return "", "", fmt.Errorf("jira returned no issue")
+	}
+	return issue.Key, issue.Key, nil
+}
+
+func (j *jira) find(ctx context.Context, number string) (string, error) {
+	var issue struct {
+		Key string `json:"key"`
+	}
+	if err := j.request(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(number)+"?fields=summary", nil, nil, &issue); err != nil {
+		return "", err
+	}
+	return issue.Key, nil
+}
+
+func (j *jira) comment(ctx context.Context, id, text string) error {
+	return j.request(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(id)+"/comment", nil, map[string]string{"body": text}, nil)
+}
+
+func (j *jira) attach(ctx context.Context, id, name string, data []byte) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, name)},
+		"Content-Type":        {contentType(name)},
+	})
+	if err != nil {
+		return err
+	}
+	part.Write(data)
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	// Jira rejects attachments without the token as cross-site requests
+	header := http.Header{"Content-Type": {writer.FormDataContentType()}, "X-Atlassian-Token": {"no-check"}}
+	return j.request(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(id)+"/attachments", header, &body, nil)
+}
+
+// transition moves the issue through the transition named for the phase and comments the
+// notes. An issue already in the transition's target status is left where it is.
+func (j *jira) transition(ctx context.Context, id, phase, notes string) error {
+	path := "/rest/api/2/issue/" + url.PathEscape(id)
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := j.request(ctx, http.MethodGet, path+"?fields=status", nil, nil, &issue); err != nil {
+		return err
+	}
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.request(ctx, http.MethodGet, path+"/transitions", nil, nil, &available); err != nil {
+		return err
+	}
+
+	name := j.states[phase]
+	transitionID := ""
+	var names []string
+	for _, t := range available.Transitions {
+		if strings.EqualFold(t.Name, name) || strings.EqualFold(t.To.Name, name) {
+			transitionID = t.ID
+			break
+		}
+		names = append(names, t.Name)
+	}
+	switch {
+	case transitionID != "":
+		if err := j.request(ctx, http.MethodPost, path+"/transitions", nil,
+			map[string]interface{}{"transition": map[string]string{"id": transitionID}}, nil); err != nil {
+			return err
+		}
+	case !strings.EqualFold(issue.Fields.Status.Name, name):
+		return fmt.Errorf("%s cannot transition to %q from %s, available: %s", id, name, issue.Fields.Status.Name, strings.Join(names, ", "))
+	}
+	return j.comment(ctx, id, notes)
+}
+
+func (j *jira) link(id, number string) string {
+	return j.base + "/browse/" + url.PathEscape(number)
+}
+
+func contentType(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "application/json"
+	case ".html":
+		return "text/html"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,97 @@
+package changeticket
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// serviceNowStates are the change_request states of the phases in a default instance.
+// Implement needs a change the instance lets skip assessment, such as a standard change.
+var serviceNowStates = map[string]string{
+	PhaseImplement: "-1",
+	PhaseSucceeded: "3",
+	PhaseFailed:    "3",
+}
+
+// serviceNow records the change on a change_request through the Table API
+type serviceNow struct {
+	api
+	fields map[string]interface{}
+	states map[string]string
+}
+
+type serviceNowRecord struct {
+	SysID  string `json:"sys_id"`
+	Number string `json:"number"`
+}
+
+func (s *serviceNow) create(ctx context.Context, change Change) (string, string, error) {
+	record := map[string]interface{}{}
+	for key, value := range s.fields {
+		record[key] = value
+	}
+	record["short_description"] = change.Summary
+	record["description"] = change.Description
+
+	var response struct {
+		Result serviceNowRecord `json:"result"`
+	}
+	if err := s.request(ctx, http.MethodPost, "/api/now/table/change_request?sysparm_fields=sys_id,number", nil, record, &response); err != nil {
+		return "", "", err
+	}
+	if response.Result.SysID == "" {
+		return "", "", fmt.Errorf("ServiceNow returned no change request")
+	}
+	return response.Result.SysID, response.Result.Number, nil
+}
+
+func (s *serviceNow) find(ctx context.Context, number string) (string, error) {
+	query := url.Values{
+		"sysparm_query":  {"number=" + number},
+		"sysparm_fields": {"sys_id,number"},
+		"sysparm_limit":  {"1"},
+	}
+	var response struct {
+		Result []serviceNowRecord `json:"result"`
+	}
+	if err := s.request(ctx, http.MethodGet, "/api/now/table/change_request?"+query.Encode(), nil, nil, &response); err != nil {
+		return "", err
+	}
+	if len(response.Result) == 0 {
+		return "", fmt.Errorf("no change request %s in ServiceNow", number)
+	}
+	return response.Result[0].SysID, nil
+}
+
+func (s *serviceNow) comment(ctx context.Context, id, text string) error {
+	return s.update(ctx, id, map[string]interface{}{"work_notes": text})
+}
+
+func (s *serviceNow) attach(ctx context.Context, id, name string, data []byte) error {
+	query := url.Values{"table_name": {"change_request"}, "table_sys_id": {id}, "file_name": {name}}
+	header := http.Header{"Content-Type": {contentType(name)}}
+	return s.request(ctx, http.MethodPost, "/api/now/attachment/file?"+query.Encode(), header, bytes.NewReader(data), nil)
+}
+
+// transition sets the state of the phase, with the close code and notes when it closes the change
+func (s *serviceNow) transition(ctx context.Context, id, phase, notes string) error {
+	update := map[string]interface{}{"state": s.states[phase], "work_notes": notes}
+	switch phase {
+	case PhaseSucceeded:
+		update["close_code"], update["close_notes"] = "successful", notes
+	case PhaseFailed:
+		update["close_code"], update["close_notes"] = "unsuccessful", notes
+	}
+	return s.update(ctx, id, update)
+}
+
+func (s *serviceNow) update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return s.request(ctx, http.MethodPatch, "/api/now/table/change_request/"+url.PathEscape(id)+"?sysparm_fields=sys_id", nil, fields, nil)
+}
+
+func (s *serviceNow) link(id, number string) string {
+	return s.base + "/nav_to.do?uri=" + url.QueryEscape("change_request.do?sys_id="+id)
+}
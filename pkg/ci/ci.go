@@ -0,0 +1,240 @@
+package ci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CI systems whose workflow commands the installer speaks
+const (
+	GitHub = "github" // GitHub Actions
+	GitLab = "gitlab" // GitLab CI/CD
+	Azure  = "azure"  // Azure Pipelines
+	Auto   = "auto"   // whichever the environment shows the run is inside
+)
+
+// Providers lists the supported CI systems
+var Providers = []string{GitHub, GitLab, Azure}
+
+// Env selects the CI system when --ci is not given
+const Env = "E2E_INSTALLER_CI"
+
+// GitHub Actions appends job summaries to the file this variable names
+const githubSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+var (
+	mu       sync.Mutex
+	provider string
+	out      io.Writer = os.Stdout
+	group    string    // section of the open group, GitLab closes sections by name
+	groups   int
+	sections []string
+)
+
+// Detect returns the CI system the process runs in, "" outside CI
+func Detect() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return GitLab
+	case strings.EqualFold(os.Getenv("TF_BUILD"), "true"):
+		return Azure
+	}
+	return ""
+}
+
+// Enable switches the output to the workflow commands of a CI system, detecting it for auto
+func Enable(name string) error {
+	if name == Auto {
+		if name = Detect(); name == "" {
+			return fmt.Errorf("--ci auto: no GitHub Actions, GitLab CI or Azure Pipelines environment detected")
+		}
+	}
+	if !slices.Contains(Providers, name) {
+		return fmt.Errorf("unsupported CI system %q, use one of %s or %s", name, strings.Join(Providers, ", "), Auto)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	provider = name
+	return nil
+}
+
+// Enabled reports whether the output goes to a CI system
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return provider != ""
+}
+
+// StartGroup opens a collapsible group of log lines titled title. Groups do not nest, an
+// open group is ended first.
+func StartGroup(title string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == "" {
+		return
+	}
+	endGroupLocked()
+	switch provider {
+	case GitHub:
+		command("::group::%s\n", escapeData(title))
+	case GitLab:
+		groups++
+		group = fmt.Sprintf("installer_%d_%s", groups, strings.Trim(sectionName.ReplaceAllString(strings.ToLower(title), "_"), "_"))
+		command("\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", time.Now().Unix(), group, title)
+	case Azure:
+		command("##[group]%s\n", oneLine(title))
+	}
+	if provider != GitLab {
+		group = title
+	}
+}
+
+// EndGroup closes the open group
+func EndGroup() {
+	mu.Lock()
+	defer mu.Unlock()
+	endGroupLocked()
+}
+
+func endGroupLocked() {
+	if group == "" {
+		return
+	}
+	switch provider {
+	case GitHub:
+		command("::endgroup::\n")
+	case GitLab:
+		command("\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), group)
+	case Azure:
+		command("##[endgroup]\n")
+	}
+	group = ""
+}
+
+// Error annotates the job with an error. GitLab has no annotations, the error is logged in red.
+func Error(title, message string) {
+	annotate("error", title, message)
+}
+
+// Warning annotates the job with a warning
+func Warning(title, message string) {
+	annotate("warning", title, message)
+}
+
+func annotate(level, title, message string) {
+	mu.Lock()
+	defer mu.Unlock()
+	switch provider {
+	case GitHub:
+		command("::%s title=%s::%s\n", level, escapeProperty(title), escapeData(message))
+	case GitLab:
+		color := "31"
+		if level == "warning" {
+			color = "33"
+		}
+		command("\x1b[%s;1m%s: %s: %s\x1b[0m\n", color, strings.ToUpper(level), title, message)
+	case Azure:
+		command("##vso[task.logissue type=%s]%s: %s\n", level, escapeAzure(title), escapeAzure(message))
+	}
+}
+
+// AddSummary adds a Markdown section to the job summary of the run
+func AddSummary(markdown string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider != "" {
+		sections = append(sections, markdown)
+	}
+}
+
+// HasSummary reports whether a section was added to the job summary
+func HasSummary() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(sections) > 0
+}
+
+// WriteSummary ends the open group and publishes the job summary: the sections are written
+// to path and appended to GitHub's job summary, uploaded as an Azure Pipelines summary or,
+// GitLab having no job summaries, logged in a collapsed section.
+func WriteSummary(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == "" {
+		return nil
+	}
+	endGroupLocked()
+	if len(sections) == 0 {
+		return nil
+	}
+	markdown := strings.Join(sections, "\n")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(markdown), 0644); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+
+	switch provider {
+	case GitHub:
+		summaryFile := os.Getenv(githubSummaryEnv)
+		if summaryFile == "" {
+			return nil
+		}
+		file, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", githubSummaryEnv, err)
+		}
+		defer file.Close()
+		if _, err := io.WriteString(file, markdown+"\n"); err != nil {
+			return fmt.Errorf("failed to append the job summary: %w", err)
+		}
+	case GitLab:
+		command("\x1b[0Ksection_start:%d:installer_summary[collapsed=true]\r\x1b[0KJob summary (%s)\n%s\n", time.Now().Unix(), path, markdown)
+		command("\x1b[0Ksection_end:%d:installer_summary\r\x1b[0K\n", time.Now().Unix())
+	case Azure:
+		// The summary is uploaded from the file, which must be given by absolute path
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		command("##vso[task.uploadsummary]%s\n", path)
+	}
+	return nil
+}
+
+// command writes a workflow command on a line of its own: progress areas and spinners leave
+// the cursor at the end of their line
+func command(format string, args ...interface{}) {
+	fmt.Fprintf(out, "\n"+format, args...)
+}
+
+// sectionName matches what GitLab does not accept in section names
+var sectionName = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// escapeData escapes a GitHub workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a GitHub workflow command property such as title
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// escapeAzure escapes an Azure Pipelines logging command message
+func escapeAzure(s string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,337 @@
+package clusterupgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Upgrade methods
+const (
+	MethodTerraform = "terraform"
+	MethodCLI       = "cli"
+)
+
+// DefaultVersionVariable is the Terraform variable holding the cluster version
+const DefaultVersionVariable = "kubernetes_version"
+
+// Terraform variables receiving the surge settings with the terraform method
+const (
+	MaxSurgeVariable       = "node_max_surge"
+	MaxUnavailableVariable = "node_max_unavailable"
+)
+
+// DefaultTimeout bounds one control plane or node pool upgrade
+const DefaultTimeout = 90 * time.Minute
+
+// pollInterval is how often a pending EKS update is described again
+var pollInterval = 30 * time.Second
+
+// Manager upgrades and scales a managed cluster through its cloud provider CLI
+type Manager struct {
+	config   config.ClusterUpgrade
+	provider string
+	region   string
+	project  string
+	timeout  time.Duration
+}
+
+// NewManager creates a cluster upgrade manager for the cli method
+func NewManager(upgrade config.ClusterUpgrade, cloud config.CloudConfig) (*Manager, error) {
+	if upgrade.ClusterName == "" {
+		return nil, fmt.Errorf("infrastructure.upgrade.clusterName is required for the cli upgrade method")
+	}
+	if cloud.Provider == "azure" && upgrade.ResourceGroup == "" {
+		return nil, fmt.Errorf("infrastructure.upgrade.resourceGroup is required to upgrade AKS clusters")
+	}
+	if cloud.Provider == "gcp" && (strings.HasSuffix(upgrade.MaxSurge, "%") || strings.HasSuffix(upgrade.MaxUnavailable, "%")) {
+		return nil, fmt.Errorf("GKE surge settings are node counts, not percentages")
+	}
+
+	mgr := &Manager{
+		config:   upgrade,
+		provider: cloud.Provider,
+		region:   cloud.Region,
+		project:  cloud.GCP.ProjectID,
+		timeout:  DefaultTimeout,
+	}
+	if upgrade.Timeout != "" {
+		timeout, err := time.ParseDuration(upgrade.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upgrade timeout %q: %w", upgrade.Timeout, err)
+		}
+		mgr.timeout = timeout
+	}
+	if _, err := execx.LookPath(mgr.binary()); err != nil {
+		return nil, fmt.Errorf("%s CLI not found in PATH: %w", mgr.binary(), err)
+	}
+	return mgr, nil
+}
+
+// UpgradeControlPlane upgrades the control plane to version and waits for it
+func (m *Manager) UpgradeControlPlane(version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	logger.Info("Upgrading control plane").
+		Str("cluster", m.config.ClusterName).
+		Str("provider", m.provider).
+		Str("version", version).
+		Send()
+
+	var err error
+	switch m.provider {
+	case "aws":
+		var out []byte
+		out, err = m.run(ctx, "eks", "update-cluster-version", "--name", m.config.ClusterName, "--kubernetes-version", version)
+		if err == nil {
+			err = m.waitEKSUpdate(ctx, out)
+		}
+	case "azure":
+		_, err = m.run(ctx, "aks", "upgrade", "--resource-group", m.config.ResourceGroup, "--name", m.config.ClusterName,
+			"--kubernetes-version", version, "--control-plane-only", "--yes")
+	case "gcp":
+		_, err = m.run(ctx, "container", "clusters", "upgrade", m.config.ClusterName, "--master", "--cluster-version", version)
+	default:
+		err = fmt.Errorf("cluster upgrades are not supported on cloud provider %q", m.provider)
+	}
+
+	audit.Record("cluster.upgrade", m.config.ClusterName, map[string]interface{}{"version": version, "controlPlane": true}, err)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade control plane of %s: %w", m.config.ClusterName, err)
+	}
+	return nil
+}
+
+// UpgradeNodePool applies the surge settings to a node pool, then upgrades its nodes to version.
+// Nodes are cordoned and drained by the provider, honouring PodDisruptionBudgets.
+func (m *Manager) UpgradeNodePool(pool, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	logger.Info("Upgrading node pool").
+		Str("cluster", m.config.ClusterName).
+		Str("pool", pool).
+		Str("version", version).
+		Str("maxSurge", m.config.MaxSurge).
+		Str("maxUnavailable", m.config.MaxUnavailable).
+		Send()
+
+	var err error
+	switch m.provider {
+	case "aws":
+		err = m.upgradeEKSNodeGroup(ctx, pool, version)
+	case "azure":
+		args := []string{"aks", "nodepool", "upgrade", "--resource-group", m.config.ResourceGroup,
+			"--cluster-name", m.config.ClusterName, "--name", pool, "--kubernetes-version", version, "--yes"}
+		if m.config.MaxSurge != "" {
+			args = append(args, "--max-surge", m.config.MaxSurge)
+		}
+		if m.config.DrainTimeout != "" {
+			drain, _ := time.ParseDuration(m.config.DrainTimeout)
+			args = append(args, "--drain-timeout", strconv.Itoa(int(drain.Minutes())))
+		}
+		_, err = m.run(ctx, args...)
+	case "gcp":
+		if m.config.MaxSurge != "" || m.config.MaxUnavailable != "" {
+			args := []string{"container", "node-pools", "update", pool, "--cluster", m.config.ClusterName}
+			if m.config.MaxSurge != "" {
+				args = append(args, "--max-surge-upgrade", m.config.MaxSurge)
+			}
+			if m.config.MaxUnavailable != "" {
+				args = append(args, "--max-unavailable-upgrade", m.config.MaxUnavailable)
+			}
+			if _, err = m.run(ctx, args...); err != nil {
+				break
+			}
+		}
+		_, err = m.run(ctx, "container", "clusters", "upgrade", m.config.ClusterName, "--node-pool", pool, "--cluster-version", version)
+	default:
+		err = fmt.Errorf("cluster upgrades are not supported on cloud provider %q", m.provider)
+	}
+
+	audit.Record("cluster.upgrade", m.config.ClusterName+"/"+pool, map[string]interface{}{"version": version, "nodePool": pool}, err)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade node pool %s: %w", pool, err)
+	}
+	return nil
+}
+
+// ScaleNodePool sets the node count of a node pool and waits for the provider to apply it
+func (m *Manager) ScaleNodePool(pool string, count int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	logger.Info("Scaling node pool").
+		Str("cluster", m.config.ClusterName).
+		Str("pool", pool).
+		Int("nodes", count).
+		Send()
+
+	var err error
+	switch m.provider {
+	case "aws":
+		err = m.scaleEKSNodeGroup(ctx, pool, count)
+	case "azure":
+		_, err = m.run(ctx, "aks", "nodepool", "scale", "--resource-group", m.config.ResourceGroup,
+			"--cluster-name", m.config.ClusterName, "--name", pool, "--node-count", strconv.Itoa(count))
+	case "gcp":
+		_, err = m.run(ctx, "container", "clusters", "resize", m.config.ClusterName, "--node-pool", pool, "--num-nodes", strconv.Itoa(count))
+	default:
+		err = fmt.Errorf("node pool scaling is not supported on cloud provider %q", m.provider)
+	}
+
+	audit.Record("cluster.scale", m.config.ClusterName+"/"+pool, map[string]interface{}{"nodes": count}, err)
+	if err != nil {
+		return fmt.Errorf("failed to scale node pool %s: %w", pool, err)
+	}
+	return nil
+}
+
+// scaleEKSNodeGroup sets the desired size of a managed node group, raising its maximum
+// size when the desired size exceeds it
+func (m *Manager) scaleEKSNodeGroup(ctx context.Context, pool string, count int) error {
+	out, err := m.run(ctx, "eks", "describe-nodegroup", "--cluster-name", m.config.ClusterName, "--nodegroup-name", pool)
+	if err != nil {
+		return err
+	}
+	var described struct {
+		Nodegroup struct {
+			ScalingConfig struct {
+				MinSize int `json:"minSize"`
+				MaxSize int `json:"maxSize"`
+			} `json:"scalingConfig"`
+		} `json:"nodegroup"`
+	}
+	if err := json.Unmarshal(out, &described); err != nil {
+		return fmt.Errorf("failed to parse EKS node group %s: %w", pool, err)
+	}
+	scaling := described.Nodegroup.ScalingConfig
+	scaling.MaxSize = max(scaling.MaxSize, count)
+	scaling.MinSize = min(scaling.MinSize, count)
+
+	out, err = m.run(ctx, "eks", "update-nodegroup-config", "--cluster-name", m.config.ClusterName, "--nodegroup-name", pool,
+		"--scaling-config", fmt.Sprintf("minSize=%d,maxSize=%d,desiredSize=%d", scaling.MinSize, scaling.MaxSize, count))
+	if err != nil {
+		return err
+	}
+	return m.waitEKSUpdate(ctx, out, "--nodegroup-name", pool)
+}
+
+// upgradeEKSNodeGroup sets maxUnavailable on a managed node group and rolls it to version.
+// EKS surges one node per unavailable slot, so MaxSurge does not apply.
+func (m *Manager) upgradeEKSNodeGroup(ctx context.Context, pool, version string) error {
+	if unavailable := m.config.MaxUnavailable; unavailable != "" {
+		setting := "maxUnavailable=" + unavailable
+		if strings.HasSuffix(unavailable, "%") {
+			setting = "maxUnavailablePercentage=" + strings.TrimSuffix(unavailable, "%")
+		}
+		out, err := m.run(ctx, "eks", "update-nodegroup-config", "--cluster-name", m.config.ClusterName,
+			"--nodegroup-name", pool, "--update-config", setting)
+		if err != nil {
+			return err
+		}
+		if err := m.waitEKSUpdate(ctx, out, "--nodegroup-name", pool); err != nil {
+			return err
+		}
+	}
+
+	out, err := m.run(ctx, "eks", "update-nodegroup-version", "--cluster-name", m.config.ClusterName,
+		"--nodegroup-name", pool, "--kubernetes-version", version)
+	if err != nil {
+		return err
+	}
+	return m.waitEKSUpdate(ctx, out, "--nodegroup-name", pool)
+}
+
+// waitEKSUpdate polls the update returned by an EKS update call until it finishes
+func (m *Manager) waitEKSUpdate(ctx context.Context, response []byte, extraArgs ...string) error {
+	var started struct {
+		Update struct {
+			ID string `json:"id"`
+		} `json:"update"`
+	}
+	if err := json.Unmarshal(response, &started); err != nil || started.Update.ID == "" {
+		return fmt.Errorf("failed to read EKS update id: %s", strings.TrimSpace(string(response)))
+	}
+
+	for {
+		args := append([]string{"eks", "describe-update", "--name", m.config.ClusterName, "--update-id", started.Update.ID}, extraArgs...)
+		out, err := m.run(ctx, args...)
+		if err != nil {
+			return err
+		}
+		var described struct {
+			Update struct {
+				Status string `json:"status"`
+				Errors []struct {
+					ErrorMessage string `json:"errorMessage"`
+				} `json:"errors"`
+			} `json:"update"`
+		}
+		if err := json.Unmarshal(out, &described); err != nil {
+			return fmt.Errorf("failed to parse EKS update: %w", err)
+		}
+
+		switch described.Update.Status {
+		case "Successful":
+			return nil
+		case "Failed", "Cancelled":
+			var messages []string
+			for _, updateErr := range described.Update.Errors {
+				messages = append(messages, updateErr.ErrorMessage)
+			}
+			return fmt.Errorf("EKS update %s %s: %s", started.Update.ID, strings.ToLower(described.Update.Status), strings.Join(messages, "; "))
+		}
+
+		logger.Debug("Waiting for EKS update").Str("id", started.Update.ID).Str("status", described.Update.Status).Send()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("EKS update %s still %s after %s", started.Update.ID, described.Update.Status, m.timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (m *Manager) binary() string {
+	switch m.provider {
+	case "azure":
+		return "az"
+	case "gcp":
+		return "gcloud"
+	default:
+		return "aws"
+	}
+}
+
+// run executes the provider CLI with its region and output flags
+func (m *Manager) run(ctx context.Context, args ...string) ([]byte, error) {
+	switch m.provider {
+	case "aws":
+		args = append(args, "--region", m.region, "--output", "json")
+	case "azure":
+		args = append(args, "--output", "json")
+	case "gcp":
+		args = append(args, "--location", m.region, "--quiet", "--format", "json")
+		if m.project != "" {
+			args = append(args, "--project", m.project)
+		}
+	}
+
+	cmd := &execx.Cmd{Name: m.binary(), Args: args, Timeout: m.timeout, Tag: m.binary() + " " + strings.Join(args[:2], " ")}
+	logger.Debug("Running cluster upgrade command").Str("command", cmd.String()).Send()
+
+	stdout, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", m.binary(), strings.Join(args[:2], " "), err)
+	}
+	return stdout, nil
+}
@@ -0,0 +1,161 @@
+package compliance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/htmlreport"
+)
+
+// Categories an environment is audited in
+const (
+	CategoryReleases   = "releases"
+	CategoryImages     = "images"
+	CategoryMigrations = "migrations"
+	CategoryDrift      = "drift"
+	CategorySecurity   = "security"
+	CategoryHealth     = "health"
+)
+
+// Categories returns the audit categories in report order
+func Categories() []string {
+	return []string{CategoryReleases, CategoryImages, CategoryMigrations, CategoryDrift, CategorySecurity, CategoryHealth}
+}
+
+// Outcomes of a check
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip" // nothing to verify, or it could not be verified; not scored
+)
+
+// weights is how much a check of each severity counts towards the score
+var weights = map[string]int{
+	htmlreport.SeverityCritical: 10,
+	htmlreport.SeverityHigh:     5,
+	htmlreport.SeverityMedium:   3,
+	htmlreport.SeverityLow:      1,
+}
+
+// Check is one expectation of the configuration verified against the environment
+type Check struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Severity string `json:"severity"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// CategoryScore sums up the checks of one category
+type CategoryScore struct {
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Score   float64 `json:"score"`
+}
+
+// Report is the outcome of auditing an environment. The score is the severity-weighted share
+// of passed checks, from 0 to 100; skipped checks are not scored.
+type Report struct {
+	Timestamp   time.Time                `json:"timestamp"`
+	Environment string                   `json:"environment,omitempty"`
+	Cluster     string                   `json:"cluster,omitempty"` // kubeconfig context, "" for the current one
+	Workspace   string                   `json:"workspace"`
+	Score       float64                  `json:"score"`
+	Categories  map[string]CategoryScore `json:"categories"`
+	Checks      []Check                  `json:"checks"`
+
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// NewReport starts the audit report of an environment
+func NewReport(environment, cluster, workspace string) *Report {
+	return &Report{
+		Timestamp:   time.Now().UTC(),
+		Environment: environment,
+		Cluster:     cluster,
+		Workspace:   workspace,
+		Categories:  make(map[string]CategoryScore),
+	}
+}
+
+// Pass records a check the environment meets
+func (r *Report) Pass(category, severity, name, expected, actual string) {
+	r.Checks = append(r.Checks, Check{Category: category, Name: name, Status: StatusPass, Severity: severity, Expected: expected, Actual: actual})
+}
+
+// Fail records a deviation of the environment from its configuration
+func (r *Report) Fail(category, severity, name, expected, actual, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{
+		Category: category,
+		Name:     name,
+		Status:   StatusFail,
+		Severity: severity,
+		Expected: expected,
+		Actual:   actual,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Skip records a check that could not be made, with the reason
+func (r *Report) Skip(category, name, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Category: category, Name: name, Status: StatusSkip, Message: fmt.Sprintf(format, args...)})
+}
+
+// Finish scores the report, overall and by category. An audit without a scored check scores
+// 100, there was nothing to deviate from.
+func (r *Report) Finish() {
+	totals := make(map[string][2]int) // passed and total weight by category
+	var passed, total int
+	for _, check := range r.Checks {
+		score := r.Categories[check.Category]
+		weight := weights[check.Severity]
+		sums := totals[check.Category]
+		switch check.Status {
+		case StatusPass:
+			score.Passed++
+			sums[0] += weight
+			passed += weight
+		case StatusFail:
+			score.Failed++
+		default:
+			score.Skipped++
+			weight = 0
+		}
+		sums[1] += weight
+		total += weight
+		totals[check.Category] = sums
+		r.Categories[check.Category] = score
+	}
+	for category, score := range r.Categories {
+		score.Score = percentage(totals[category][0], totals[category][1])
+		r.Categories[category] = score
+	}
+	r.Score = percentage(passed, total)
+}
+
+// Deviations returns the failed checks, most severe first
+func (r *Report) Deviations() []Check {
+	var deviations []Check
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			deviations = append(deviations, check)
+		}
+	}
+	sort.SliceStable(deviations, func(i, j int) bool {
+		return weights[deviations[i].Severity] > weights[deviations[j].Severity]
+	})
+	return deviations
+}
+
+// percentage rounds passed/total down to one decimal, so a single failed check never shows as 100
+func percentage(passed, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Floor(float64(passed)*1000/float64(total)) / 10
+}
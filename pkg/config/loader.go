@@ -1,13 +1,17 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
 )
 
 var validate *validator.Validate
@@ -29,36 +33,197 @@ func validateDuration(fl validator.FieldLevel) bool {
 	return err == nil
 }
 
-// LoadConfig loads and validates configuration from a JSON file
+// LoadConfig loads a JSON or YAML configuration file, expands the environment references in
+// it, applies the defaults of optional fields and validates the result
 func LoadConfig(path string) (*InstallerConfig, error) {
 	// Check if file exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, fmt.Errorf("configuration file not found: %s", path)
 	}
 
-	// Read file content
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read configuration file: %w", err)
-	}
-
-	// Parse JSON
 	var config InstallerConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON configuration: %w", err)
+	if err := decodeFile(path, &config); err != nil {
+		return nil, err
 	}
 
+	// Defaults first, so optional fields left out of the file validate as their defaults
+	config.setDefaults()
+
 	// Validate configuration
 	if err := config.ValidateConfig(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
-	// Set default values
-	config.setDefaults()
-
 	return &config, nil
 }
 
+// decodeFile reads a configuration file into v. Files ending in .yaml or .yml are YAML, .json
+// files JSON and others are told apart by their first character. ${VAR} references in the
+// string values are expanded once the file is parsed, so no variable can change its structure.
+func decodeFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	var expander envExpander
+
+	if !isYAML(path, data) {
+		var document interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return fmt.Errorf("failed to parse JSON configuration: %w", err)
+		}
+		document = expander.value(document)
+		if err := expander.err(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		converted, err := json.Marshal(document)
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON configuration: %w", err)
+		}
+		if err := json.Unmarshal(converted, v); err != nil {
+			return fmt.Errorf("failed to parse JSON configuration: %w", err)
+		}
+		return nil
+	}
+
+	// YAML goes through JSON so the json tags of the configuration types apply to both
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to parse YAML configuration: %w", err)
+	}
+	expander.node(&node)
+	if err := expander.err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var document interface{}
+	if err := node.Decode(&document); err != nil {
+		return fmt.Errorf("failed to parse YAML configuration: %w", err)
+	}
+	converted, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to parse YAML configuration: %w", err)
+	}
+	if err := json.Unmarshal(converted, v); err != nil {
+		return fmt.Errorf("failed to parse YAML configuration: %w", err)
+	}
+	return nil
+}
+
+func isYAML(path string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[')
+}
+
+// envReference matches $${...} escapes and ${VAR}, ${VAR:-default} and ${VAR:?message}
+// references
+var envReference = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|:\?)([^}]*))?\}`)
+
+// envExpander replaces the environment references of configuration values. ${VAR} is
+// replaced by the variable, empty when unset; ${VAR:-default} falls back to default when it
+// is unset or empty and ${VAR:?message} fails with message then. $${ is a literal ${. Bare
+// $VAR is kept, so passwords and shell snippets containing $ are left alone.
+type envExpander struct {
+	missing []string
+}
+
+// expand returns value with its references replaced
+func (e *envExpander) expand(value string) string {
+	return envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+		match := envReference.FindStringSubmatch(reference)
+		name, operator, operand := match[1], match[2], match[3]
+		value := os.Getenv(name)
+		if value != "" {
+			return value
+		}
+		switch operator {
+		case ":-":
+			return operand
+		case ":?":
+			message := operand
+			if message == "" {
+				message = "not set"
+			}
+			e.missing = append(e.missing, name+": "+message)
+		}
+		return value
+	})
+}
+
+// value expands the strings of a decoded JSON document; keys are left as they are
+func (e *envExpander) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return e.expand(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = e.value(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = e.value(item)
+		}
+	}
+	return value
+}
+
+// node expands the scalar values of a YAML document; keys are left as they are. Unquoted
+// values are typed again, so port: ${DB_PORT} is still a number.
+func (e *envExpander) node(node *yaml.Node) {
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		e.node(child)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	if expanded := e.expand(node.Value); expanded != node.Value {
+		node.Value = expanded
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+}
+
+// err reports the required variables that were not set
+func (e *envExpander) err() error {
+	if len(e.missing) > 0 {
+		return fmt.Errorf("required environment variables: %s", strings.Join(e.missing, "; "))
+	}
+	return nil
+}
+
+// LoadBranding reads only the branding of a configuration file, so banners printed before
+// the configuration is loaded and validated are branded too. A relative logo file is
+// resolved against the configuration file's directory.
+func LoadBranding(path string) (BrandingConfig, error) {
+	var config struct {
+		Installer struct {
+			Branding BrandingConfig `json:"branding"`
+		} `json:"installer"`
+	}
+	if err := decodeFile(path, &config); err != nil {
+		return BrandingConfig{}, err
+	}
+	branding := config.Installer.Branding
+	if branding.LogoFile != "" && !filepath.IsAbs(branding.LogoFile) {
+		branding.LogoFile = filepath.Join(filepath.Dir(path), branding.LogoFile)
+	}
+	return branding, nil
+}
+
 // ValidateConfig validates the configuration structure
 func (c *InstallerConfig) ValidateConfig() error {
 	if err := validate.Struct(c); err != nil {
@@ -105,6 +270,16 @@ func (c *InstallerConfig) setDefaults() {
 		}
 	}
 
+	// Set default chart repository settings
+	if c.Artifacts.Helm.Client.ChartRepo.Enabled {
+		if c.Artifacts.Helm.Client.ChartRepo.Type == "" {
+			c.Artifacts.Helm.Client.ChartRepo.Type = "local"
+		}
+		if c.Artifacts.Helm.Client.ChartRepo.ServeAddr == "" {
+			c.Artifacts.Helm.Client.ChartRepo.ServeAddr = "127.0.0.1:8879"
+		}
+	}
+
 	// Set default Terraform settings
 	if c.Infrastructure.Terraform.Enabled {
 		if c.Infrastructure.Terraform.Parallelism == 0 {
@@ -172,6 +347,18 @@ func (c *InstallerConfig) setDefaults() {
 	if c.Validation.E2E.Timeout == "" {
 		c.Validation.E2E.Timeout = "30m"
 	}
+	if c.Validation.E2E.Framework == "" {
+		c.Validation.E2E.Framework = "go-test"
+	}
+	if c.Validation.E2E.Config.Workers == 0 {
+		c.Validation.E2E.Config.Workers = 1
+	}
+	if c.Validation.E2E.Reporting.Format == "" {
+		c.Validation.E2E.Reporting.Format = "junit"
+	}
+	if c.Validation.E2E.Reporting.Output == "" {
+		c.Validation.E2E.Reporting.Output = "./reports/e2e-results.xml"
+	}
 
 	// Set default script settings
 	for i := range c.Validation.Post.Scripts {
@@ -211,6 +398,24 @@ func (c *InstallerConfig) validateCustomRules() error {
 		}
 	}
 
+	// Validate Ansible playbooks if Ansible provisioning is enabled
+	if c.Infrastructure.Ansible.Enabled {
+		if len(c.Infrastructure.Ansible.Playbooks.Apply) == 0 {
+			return fmt.Errorf("ansible apply playbooks must be specified when ansible is enabled")
+		}
+	}
+
+	// Validate the declarative cluster if declarative mode is selected
+	if c.Infrastructure.ProvisionMode == "declarative" {
+		declarative := c.Infrastructure.Declarative
+		if len(declarative.Manifests) == 0 || declarative.ClusterName == "" {
+			return fmt.Errorf("declarative manifests and clusterName must be specified in declarative mode")
+		}
+		if declarative.Engine == "crossplane" && declarative.ClaimResource == "" {
+			return fmt.Errorf("declarative claimResource must be specified for the crossplane engine")
+		}
+	}
+
 	// Validate database connection if database is enabled
 	if c.Database.Enabled {
 		if c.Database.Connection.Host == "" {
@@ -236,6 +441,23 @@ func (c *InstallerConfig) validateCustomRules() error {
 		}
 	}
 
+	// Validate approval gates
+	gates := make(map[string]bool)
+	for _, gate := range c.Installer.Gates {
+		if gates[gate.Name] {
+			return fmt.Errorf("duplicate approval gate %s", gate.Name)
+		}
+		gates[gate.Name] = true
+		if (gate.Before == "") == (gate.After == "") {
+			return fmt.Errorf("approval gate %s must set exactly one of before or after", gate.Name)
+		}
+	}
+
+	// Validate the change ticket integration
+	if ticket := c.Installer.ChangeTicket; ticket != nil && ticket.Provider == "jira" && ticket.Project == "" {
+		return fmt.Errorf("installer.changeTicket.project must be specified for Jira")
+	}
+
 	// Validate E2E test configuration
 	if c.Validation.E2E.Enabled {
 		if c.Validation.E2E.TestSuite == "" {
@@ -285,6 +507,8 @@ func (c *InstallerConfig) GetWorkspaceConfig() WorkspaceConfig {
 		ChartsDir:    filepath.Join(c.Installer.Workspace, "charts"),
 		TerraformDir: filepath.Join(c.Installer.Workspace, "terraform"),
 		TestsDir:     filepath.Join(c.Installer.Workspace, "tests"),
+		BinDir:       filepath.Join(c.Installer.Workspace, "bin"),
+		CacheDir:     filepath.Join(c.Installer.Workspace, "cache"),
 	}
 }
 
@@ -299,6 +523,8 @@ type WorkspaceConfig struct {
 	ChartsDir    string
 	TerraformDir string
 	TestsDir     string
+	BinDir       string
+	CacheDir     string
 }
 
 // EnsureDirectories creates all workspace directories
@@ -313,6 +539,8 @@ func (w *WorkspaceConfig) EnsureDirectories() error {
 		w.ChartsDir,
 		w.TerraformDir,
 		w.TestsDir,
+		w.BinDir,
+		w.CacheDir,
 	}
 
 	for _, dir := range dirs {
@@ -17,16 +17,213 @@ type InstallerConfig struct {
 	Security       SecurityConfig       `json:"security,omitempty"`
 	Kubernetes     K8sConfig            `json:"kubernetes,omitempty"`
 	Cloud          CloudConfig          `json:"cloud,omitempty"`
+	Labels         LabelPolicy          `json:"labels,omitempty"`
+}
+
+// LabelPolicy labels and tags everything the installer creates so it can be attributed.
+// Owner and Environment become the owner and environment labels, and every resource
+// also gets installer-run-id.
+type LabelPolicy struct {
+	Owner       string            `json:"owner,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	// TerraformVariable is the map(string) variable the labels are passed in, default_tags by default
+	TerraformVariable string `json:"terraformVariable,omitempty"`
 }
 
 // InstallerSettings contains general installer configuration
 type InstallerSettings struct {
-	Version   string `json:"version" validate:"required,semver"`
-	Workspace string `json:"workspace" validate:"required,dir"`
-	Verbose   bool   `json:"verbose"`
-	DryRun    bool   `json:"dryRun"`
-	LogLevel  string `json:"logLevel" validate:"oneof=debug info warn error"`
-	LogFormat string `json:"logFormat" validate:"oneof=json text"`
+	Version     string            `json:"version" validate:"required,semver"`
+	Workspace   string            `json:"workspace" validate:"required,dir"`
+	Verbose     bool              `json:"verbose"`
+	DryRun      bool              `json:"dryRun"`
+	LogLevel    string            `json:"logLevel" validate:"oneof=debug info warn error"`
+	LogFormat   string            `json:"logFormat" validate:"oneof=json text"`
+	Audit       AuditConfig       `json:"audit"`
+	Tools       ToolsConfig       `json:"tools,omitempty"`
+	License     LicenseConfig     `json:"license,omitempty"`
+	Gates       []ApprovalGate    `json:"gates,omitempty" validate:"dive"`
+	Steps       StepsConfig       `json:"steps,omitempty"`
+	Maintenance MaintenanceConfig `json:"maintenance,omitempty"`
+	Progress    ProgressConfig    `json:"progress,omitempty"`
+	Theme       string            `json:"theme,omitempty" validate:"omitempty,oneof=default monochrome high-contrast corporate"`
+	Branding    BrandingConfig    `json:"branding,omitempty"`
+	Locale      string            `json:"locale,omitempty"` // language of the output, e.g. "de"; see i18n.Locales
+	Resources   ResourcesConfig   `json:"resources,omitempty"`
+	Clock       ClockConfig       `json:"clock,omitempty"`
+	Events      EventsConfig      `json:"events,omitempty"`
+
+	ChangeTicket *ChangeTicketConfig `json:"changeTicket,omitempty"`
+}
+
+// StepsConfig customizes the built-in install steps: Disabled ones never run, Replace runs a
+// command in place of a step, and Order, when set, lists every enabled step in the order
+// they run. A step may not run before a step it depends on.
+type StepsConfig struct {
+	Order    []string                   `json:"order,omitempty"`
+	Disabled []string                   `json:"disabled,omitempty"`
+	Replace  map[string]StepReplacement `json:"replace,omitempty" validate:"dive"`
+}
+
+// StepReplacement is a shell command run in place of a built-in step, with the installation
+// parameters as environment variables
+type StepReplacement struct {
+	Command     string   `json:"command" validate:"required"`
+	Description string   `json:"description,omitempty"`
+	DependsOn   []string `json:"dependsOn,omitempty"` // replaces the dependencies of the built-in step when set
+	Timeout     string   `json:"timeout,omitempty" validate:"omitempty,duration"`
+}
+
+// ChangeTicketConfig records each install on a change ticket in ServiceNow or Jira: the
+// ticket is opened with the plan attached when the run starts, noted as steps finish and
+// closed with the final report. Ticket service failures are warnings unless Required is set.
+type ChangeTicketConfig struct {
+	Provider     string   `json:"provider" validate:"required,oneof=servicenow jira"`
+	URL          string   `json:"url" validate:"required,url"` // instance, e.g. https://acme.service-now.com
+	Username     string   `json:"username,omitempty"`          // basic auth user; a bearer token is sent without one
+	TokenFile    string   `json:"tokenFile,omitempty" validate:"omitempty,file"`
+	Environments []string `json:"environments,omitempty"` // labels.environment values tickets are opened for, all when empty
+	Required     bool     `json:"required,omitempty"`     // refuse to install when the ticket cannot be opened
+
+	Project   string `json:"project,omitempty"`   // Jira project key
+	IssueType string `json:"issueType,omitempty"` // Jira issue type, default Task
+	// Fields are set on the created ticket as they are, e.g. assignment_group or labels
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	// States maps implement, succeeded and failed to ServiceNow state values or Jira
+	// transition names, overriding the defaults
+	States map[string]string `json:"states,omitempty"`
+	// ReportURL is where reviewers find the final report, with {runId} replaced; the
+	// report is attached to the ticket either way
+	ReportURL string `json:"reportUrl,omitempty"`
+}
+
+// EventsConfig streams install lifecycle events, the run and each step starting and ending,
+// to orchestration platforms as they happen. Delivery is best effort and never fails a run.
+type EventsConfig struct {
+	Webhook *EventWebhook `json:"webhook,omitempty"`
+	NATS    *EventNATS    `json:"nats,omitempty"`
+	Kafka   *EventKafka   `json:"kafka,omitempty"`
+	Types   []string      `json:"types,omitempty"` // event types to send, e.g. step.failed; all when empty
+}
+
+// EventWebhook posts each event as JSON. Header values expand ${VAR} environment references.
+type EventWebhook struct {
+	URL        string            `json:"url" validate:"required,url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	SecretFile string            `json:"secretFile,omitempty" validate:"omitempty,file"` // HMAC key signing each body
+}
+
+// EventNATS publishes each event to a subject of a NATS server
+type EventNATS struct {
+	URL       string `json:"url" validate:"required"`     // nats://host:4222, credentials in the URL
+	Subject   string `json:"subject" validate:"required"` // e.g. installer.events
+	TokenFile string `json:"tokenFile,omitempty" validate:"omitempty,file"`
+	CredsFile string `json:"credsFile,omitempty" validate:"omitempty,file"` // user JWT and nkey seed, as nsc writes them
+	NKeyFile  string `json:"nkeyFile,omitempty" validate:"omitempty,file"`  // nkey seed of a user without a JWT
+}
+
+// EventKafka produces each event to a topic of a Kafka cluster, keyed by run ID
+type EventKafka struct {
+	Brokers []string        `json:"brokers" validate:"required,min=1"` // host:port of bootstrap brokers
+	Topic   string          `json:"topic" validate:"required"`
+	TLS     bool            `json:"tls,omitempty"`
+	SASL    *EventKafkaSASL `json:"sasl,omitempty"`
+}
+
+// EventKafkaSASL authenticates to the Kafka brokers
+type EventKafkaSASL struct {
+	Mechanism    string `json:"mechanism" validate:"required,oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"`
+	Username     string `json:"username" validate:"required"`
+	PasswordFile string `json:"passwordFile,omitempty" validate:"omitempty,file"` // over E2E_INSTALLER_KAFKA_PASSWORD
+}
+
+// ClockConfig bounds the skew of the local clock against NTP, the cloud metadata service and
+// the API server, checked by preflight clock and before install starts. Skews are durations.
+type ClockConfig struct {
+	NTPServers []string `json:"ntpServers,omitempty"` // default pool.ntp.org
+	WarnSkew   string   `json:"warnSkew,omitempty"`   // default 2s
+	MaxSkew    string   `json:"maxSkew,omitempty"`    // default 30s, beyond which the check fails
+	Disabled   bool     `json:"disabled,omitempty"`   // skip the check before install
+}
+
+// ResourcesConfig overrides the concurrency and runtime limits the installer derives from the
+// CPUs and memory of its host
+type ResourcesConfig struct {
+	MaxProcs         int    `json:"maxProcs,omitempty" validate:"omitempty,min=1"`         // GOMAXPROCS
+	MemoryLimit      string `json:"memoryLimit,omitempty"`                                 // soft memory limit of the Go runtime, e.g. 2Gi
+	ImageSyncWorkers int    `json:"imageSyncWorkers,omitempty" validate:"omitempty,min=1"` // images copied at once
+	HelmWorkers      int    `json:"helmWorkers,omitempty" validate:"omitempty,min=1"`      // helm operations at once within a tier
+}
+
+// BrandingConfig white-labels the banner, summaries and reports of the installer for a
+// customer engagement; empty fields keep the installer's own branding
+type BrandingConfig struct {
+	ProductName string `json:"productName,omitempty"`
+	Edition     string `json:"edition,omitempty"` // e.g. "Enterprise Edition", shown under the product name
+	Tagline     string `json:"tagline,omitempty"`
+	// Logo is ASCII art printed instead of the framed product name; LogoFile reads it from
+	// a file, relative to the configuration file
+	Logo       string `json:"logo,omitempty"`
+	LogoFile   string `json:"logoFile,omitempty"`
+	SupportURL string `json:"supportUrl,omitempty" validate:"omitempty,url"`
+	Version    string `json:"version,omitempty"` // shown instead of the installer's version
+}
+
+// ProgressConfig tunes the progress display of long-running steps
+type ProgressConfig struct {
+	// RefreshInterval is how often the rate and ETA of image sync and Terraform apply are
+	// redrawn, 1s by default
+	RefreshInterval string `json:"refreshInterval,omitempty" validate:"omitempty,duration"`
+}
+
+// MaintenanceConfig restricts phases that change infrastructure or data to maintenance windows
+type MaintenanceConfig struct {
+	Windows []MaintenanceWindow `json:"windows,omitempty" validate:"dive"`
+	// Phases default to provision-infra, db-migrate and provision-upgrade
+	Phases []string `json:"phases,omitempty" validate:"dive,oneof=provision-infra db-migrate deploy provision-upgrade"`
+	// Wait for the next window instead of exiting, for at most MaxWait when set
+	Wait    bool   `json:"wait,omitempty"`
+	MaxWait string `json:"maxWait,omitempty" validate:"omitempty,duration"`
+}
+
+// MaintenanceWindow is a recurring period changes are allowed in
+type MaintenanceWindow struct {
+	Name     string `json:"name" validate:"required"`
+	Schedule string `json:"schedule" validate:"required"`          // cron start: minute hour day-of-month month day-of-week
+	Duration string `json:"duration" validate:"required,duration"` // how long the window stays open, e.g. 4h
+	Timezone string `json:"timezone,omitempty"`                    // IANA name such as Europe/Berlin, local time when empty
+}
+
+// ApprovalGate pauses install before or after a step until an operator approves it, either
+// at the terminal or by re-running install with --approve <name>
+type ApprovalGate struct {
+	Name         string   `json:"name" validate:"required"`
+	Before       string   `json:"before,omitempty"`       // step the gate holds back, e.g. db-migrate
+	After        string   `json:"after,omitempty"`        // step whose result must be reviewed, e.g. provision-infra
+	Environments []string `json:"environments,omitempty"` // labels.environment values the gate applies to, all when empty
+	Message      string   `json:"message,omitempty"`      // shown to the approver
+	Webhook      string   `json:"webhook,omitempty" validate:"omitempty,url"`
+	Approvers    []string `json:"approvers,omitempty"` // users, or group:<name>, who may approve; anyone RBAC allows when empty
+}
+
+// LicenseConfig locates the signed license key of vendor builds that enforce licensing
+type LicenseConfig struct {
+	Key     string `json:"key,omitempty"`
+	KeyFile string `json:"keyFile,omitempty" validate:"omitempty,file"`
+}
+
+// ToolsConfig pins the CLI tool versions setup installs into the workspace bin directory
+type ToolsConfig struct {
+	Install   bool   `json:"install"`
+	Kubectl   string `json:"kubectl,omitempty"`   // e.g. v1.31.1
+	Helm      string `json:"helm,omitempty"`      // e.g. v3.16.1
+	Terraform string `json:"terraform,omitempty"` // e.g. 1.9.5
+}
+
+// AuditConfig controls the append-only audit log of mutating operations
+type AuditConfig struct {
+	Path           string `json:"path"`           // defaults to <workspace>/logs/audit.jsonl
+	SigningKeyFile string `json:"signingKeyFile"` // HMAC key used to sign entries
 }
 
 // ArtifactsConfig handles OCI images, Helm charts, and Terraform modules
@@ -73,9 +270,24 @@ type ImageReference struct {
 
 // HelmConfig manages Helm chart repositories and synchronization
 type HelmConfig struct {
-	Vendor GitRepoConfig `json:"vendor" validate:"required"`
-	Client GitRepoConfig `json:"client"`
-	Charts []HelmChart   `json:"charts,omitempty"`
+	Vendor GitRepoConfig    `json:"vendor" validate:"required"`
+	Client HelmClientConfig `json:"client"`
+	Charts []HelmChart      `json:"charts,omitempty"`
+}
+
+// HelmClientConfig describes where synced charts are published on the client side
+type HelmClientConfig struct {
+	GitRepoConfig
+	ChartRepo ChartRepoConfig `json:"chartRepo"`
+}
+
+// ChartRepoConfig configures an HTTP Helm chart repository for airgapped clients
+type ChartRepoConfig struct {
+	Enabled   bool       `json:"enabled"`
+	Type      string     `json:"type" validate:"omitempty,oneof=chartmuseum nexus artifactory local"`
+	URL       string     `json:"url" validate:"omitempty,url"`
+	Auth      AuthConfig `json:"auth"`
+	ServeAddr string     `json:"serveAddr"` // listen address when Type is local
 }
 
 // TerraformConfig manages Terraform module repositories
@@ -115,23 +327,133 @@ type TerraformModule struct {
 // InfrastructureConfig manages infrastructure provisioning
 // InfrastructureConfig manages infrastructure provisioning
 type InfrastructureConfig struct {
-	ProvisionMode string             `json:"provisionMode" validate:"oneof=terraform makefile hybrid"`
-	Terraform     TerraformExecution `json:"terraform"`
-	Makefile      MakefileExecution  `json:"makefile"`
-	HealthCheck   HealthCheckConfig  `json:"healthCheck"`
+	ProvisionMode   string             `json:"provisionMode" validate:"oneof=terraform makefile ansible hybrid managed local declarative"`
+	Terraform       TerraformExecution `json:"terraform"`
+	Makefile        MakefileExecution  `json:"makefile"`
+	Ansible         AnsibleExecution   `json:"ansible"`
+	HybridOrder     []string           `json:"hybridOrder,omitempty" validate:"dive,oneof=terraform makefile ansible"` // destroy reverses it
+	ManagedServices ManagedServices    `json:"managedServices"`
+	Local           LocalCluster       `json:"local"`
+	Declarative     DeclarativeCluster `json:"declarative"`
+	Upgrade         ClusterUpgrade     `json:"upgrade"`
+	NodeScaling     NodePoolScaling    `json:"nodeScaling"`
+	HealthCheck     HealthCheckConfig  `json:"healthCheck"`
+}
+
+// ClusterUpgrade configures 'provision-infra upgrade'. The terraform method sets
+// VersionVariable and applies; the cli method calls the EKS, AKS or GKE API through the
+// provider CLI, control plane first and then each node pool.
+type ClusterUpgrade struct {
+	Method          string   `json:"method,omitempty" validate:"omitempty,oneof=terraform cli"`
+	VersionVariable string   `json:"versionVariable,omitempty"` // default kubernetes_version
+	ClusterName     string   `json:"clusterName,omitempty"`
+	ResourceGroup   string   `json:"resourceGroup,omitempty"` // AKS only
+	NodePools       []string `json:"nodePools,omitempty"`
+	MaxSurge        string   `json:"maxSurge,omitempty"`       // nodes or percentage
+	MaxUnavailable  string   `json:"maxUnavailable,omitempty"` // nodes or percentage
+	DrainTimeout    string   `json:"drainTimeout,omitempty" validate:"omitempty,duration"`
+	Timeout         string   `json:"timeout,omitempty" validate:"omitempty,duration"` // per operation, default 90m
+}
+
+// NodePoolScaling adds nodes to a node pool when deploy predicts the charts will not fit,
+// and removes them after the deployment once they are no longer needed. The terraform
+// method applies CountVariable; the cli method resizes the pool through the provider CLI
+// with the clusterName and resourceGroup of Upgrade.
+type NodePoolScaling struct {
+	Enabled       bool              `json:"enabled"`
+	Method        string            `json:"method,omitempty" validate:"omitempty,oneof=terraform cli"`
+	NodePool      string            `json:"nodePool,omitempty"`
+	NodeSelector  map[string]string `json:"nodeSelector,omitempty"`  // nodes of the pool, default the provider's node pool label
+	CountVariable string            `json:"countVariable,omitempty"` // default node_count
+	MaxNodes      int               `json:"maxNodes,omitempty" validate:"min=0"`
+	KeepScaled    bool              `json:"keepScaled,omitempty"`                            // leave the added nodes in place
+	Timeout       string            `json:"timeout,omitempty" validate:"omitempty,duration"` // for new nodes to be ready, default 15m
+}
+
+// LocalCluster is the kind or k3d cluster provisioned in local mode for development and demos
+type LocalCluster struct {
+	Tool              string        `json:"tool,omitempty" validate:"omitempty,oneof=kind k3d"` // default kind
+	Name              string        `json:"name,omitempty"`                                     // default e2e-installer
+	KubernetesVersion string        `json:"kubernetesVersion,omitempty"`                        // node image tag, e.g. v1.30.0
+	Workers           int           `json:"workers,omitempty" validate:"min=0,max=10"`
+	Registry          LocalRegistry `json:"registry"`
+}
+
+// DeclarativeCluster provisions the workload cluster in declarative mode by applying Cluster
+// API resources or a Crossplane claim to an existing management cluster, without Terraform
+type DeclarativeCluster struct {
+	Engine               string   `json:"engine,omitempty" validate:"omitempty,oneof=cluster-api crossplane"` // default cluster-api
+	Manifests            []string `json:"manifests"`                                                          // files or directories for kubectl apply -f
+	ClusterName          string   `json:"clusterName"`                                                        // Cluster or claim name
+	Namespace            string   `json:"namespace,omitempty"`                                                // default default
+	ClaimResource        string   `json:"claimResource,omitempty"`                                            // crossplane claim resource, e.g. clusters.platform.example.org
+	ConnectionSecretKey  string   `json:"connectionSecretKey,omitempty"`                                      // crossplane, default kubeconfig
+	ManagementKubeconfig string   `json:"managementKubeconfig,omitempty" validate:"omitempty,file"`
+	ManagementContext    string   `json:"managementContext,omitempty"`
+	ReadyTimeout         string   `json:"readyTimeout,omitempty" validate:"omitempty,duration"` // default 30m
+	Kubeconfig           string   `json:"kubeconfig,omitempty"`                                 // default <workspace>/.kube/<clusterName>.kubeconfig
+}
+
+// LocalRegistry is a registry container the local cluster pulls from, served on localhost:<port>
+type LocalRegistry struct {
+	Enabled bool `json:"enabled"`
+	Port    int  `json:"port,omitempty" validate:"omitempty,min=1,max=65535"` // default 5001
+}
+
+// ManagedServices are databases and caches the installer creates directly through the
+// cloud provider's CLI, for environments that do not provision them with Terraform.
+// They use the cloud provider and region of the installer configuration.
+type ManagedServices struct {
+	Timeout   string            `json:"timeout,omitempty" validate:"omitempty,duration"` // per service, default 45m
+	Databases []ManagedDatabase `json:"databases,omitempty" validate:"dive"`
+	Caches    []ManagedCache    `json:"caches,omitempty" validate:"dive"`
+}
+
+// ManagedDatabase is an RDS, Cloud SQL or Azure Database instance
+type ManagedDatabase struct {
+	Name          string `json:"name" validate:"required"`
+	Engine        string `json:"engine" validate:"required,oneof=postgres mysql"`
+	Version       string `json:"version,omitempty"`
+	Size          string `json:"size,omitempty"` // instance class, tier or SKU
+	StorageGB     int    `json:"storageGB,omitempty" validate:"min=0"`
+	Database      string `json:"database,omitempty"`
+	Username      string `json:"username" validate:"required"`
+	PasswordParam string `json:"passwordParam" validate:"required"` // parameter key holding the admin password
+	ResourceGroup string `json:"resourceGroup,omitempty"`           // Azure only
+	Primary       bool   `json:"primary,omitempty"`                 // published as the database.* parameters
+}
+
+// ManagedCache is an ElastiCache, Memorystore or Azure Cache for Redis instance
+type ManagedCache struct {
+	Name          string `json:"name" validate:"required"`
+	Engine        string `json:"engine,omitempty" validate:"omitempty,oneof=redis"`
+	Version       string `json:"version,omitempty"`
+	Size          string `json:"size,omitempty"`          // node type, memory size in GB or SKU
+	ResourceGroup string `json:"resourceGroup,omitempty"` // Azure only
 }
 
 // TerraformExecution contains Terraform execution settings
 type TerraformExecution struct {
 	Enabled        bool              `json:"enabled"`
+	Flavor         string            `json:"flavor,omitempty" validate:"omitempty,oneof=terraform terragrunt"` // terragrunt runs run-all across module directories
 	Modules        []string          `json:"modules" validate:"required_if=Enabled true,min=1"`
 	Workspace      string            `json:"workspace"`
 	VarFiles       []string          `json:"varFiles,omitempty" validate:"dive,file"`
 	Variables      map[string]string `json:"variables,omitempty"`
+	SecretVars     map[string]string `json:"secretVariables,omitempty"` // variable name -> parameter key, passed as TF_VAR_<name>
 	ValidateHealth bool              `json:"validateHealth"`
 	AutoApprove    bool              `json:"autoApprove"`
 	Parallelism    int               `json:"parallelism" validate:"min=1,max=100"`
 	Timeout        string            `json:"timeout" validate:"duration"`
+	StateBackups   int               `json:"stateBackups,omitempty" validate:"min=0"` // state snapshots kept, default 10
+	CostEstimation CostEstimation    `json:"costEstimation"`
+}
+
+// CostEstimation runs Infracost on each plan. An increase in projected monthly cost above
+// MonthlyBudget needs explicit approval before apply; 0 disables the check.
+type CostEstimation struct {
+	Enabled       bool    `json:"enabled"`
+	MonthlyBudget float64 `json:"monthlyBudget,omitempty" validate:"min=0"`
 }
 
 // MakefileExecution contains Makefile-based provisioning settings
@@ -148,6 +470,27 @@ type MakefileExecution struct {
 	DryRun           bool              `json:"dryRun"`
 }
 
+// AnsibleExecution contains Ansible playbook provisioning settings
+type AnsibleExecution struct {
+	Enabled          bool                   `json:"enabled"`
+	Playbooks        AnsiblePlaybooks       `json:"playbooks"`
+	Inventory        string                 `json:"inventory,omitempty"` // file, directory or comma-separated hosts
+	ExtraVars        map[string]interface{} `json:"extraVars,omitempty"`
+	Limit            string                 `json:"limit,omitempty"`
+	Tags             []string               `json:"tags,omitempty"`
+	Environment      map[string]string      `json:"environment,omitempty"`
+	WorkingDirectory string                 `json:"workingDirectory,omitempty"`
+	Timeout          string                 `json:"timeout,omitempty" validate:"omitempty,duration"`
+}
+
+// AnsiblePlaybooks lists the playbooks run for each operation, in order. Plan runs the
+// apply playbooks in check mode.
+type AnsiblePlaybooks struct {
+	Apply    []string `json:"apply"`
+	Destroy  []string `json:"destroy,omitempty"`
+	Validate []string `json:"validate,omitempty"`
+}
+
 // MakefileTargets defines the targets for different operations
 type MakefileTargets struct {
 	Init        string            `json:"init"`
@@ -169,6 +512,18 @@ type DatabaseConfig struct {
 	Connection         DatabaseConnection `json:"connection"`
 	Validation         DatabaseValidation `json:"validation"`
 	Migration          MigrationConfig    `json:"migration"`
+	Readiness          DatabaseReadiness  `json:"readiness"`
+}
+
+// DatabaseReadiness makes db-migrate wait for a database that is still being created: for
+// the instance status reported by the cloud API, then for a successful login. It runs when
+// enabled or when provision-infra published the instance as the database.instance parameter.
+type DatabaseReadiness struct {
+	Enabled       bool   `json:"enabled"`
+	Instance      string `json:"instance,omitempty"`                               // RDS identifier, Cloud SQL instance or flexible server
+	ResourceGroup string `json:"resourceGroup,omitempty"`                          // Azure only
+	Timeout       string `json:"timeout,omitempty" validate:"omitempty,duration"`  // default 20m
+	Interval      string `json:"interval,omitempty" validate:"omitempty,duration"` // default 15s
 }
 
 // DatabaseConnection contains database connection details
@@ -197,6 +552,7 @@ type MigrationConfig struct {
 	Baseline bool   `json:"baseline"`
 	DryRun   bool   `json:"dryRun"`
 	Timeout  string `json:"timeout" validate:"duration"`
+	Rollback string `json:"rollback,omitempty"` // shell command reverting the migrations of a run, e.g. "flyway undo"
 }
 
 // DeploymentConfig manages application deployment
@@ -204,6 +560,7 @@ type DeploymentConfig struct {
 	Helm       HelmDeployment   `json:"helm"`
 	Kubernetes K8sConfig        `json:"kubernetes"`
 	Validation DeployValidation `json:"validation"`
+	Labels     LabelPolicy      `json:"labels,omitempty"`
 }
 
 // HelmDeployment contains Helm deployment configuration
@@ -214,6 +571,43 @@ type HelmDeployment struct {
 	Timeout         string        `json:"timeout" validate:"duration"`
 	Atomic          bool          `json:"atomic"`
 	CleanupOnFail   bool          `json:"cleanupOnFail"`
+	MaxParallel     int           `json:"maxParallel" validate:"omitempty,min=1"` // concurrent releases within a dependency tier
+	RunTests        bool          `json:"runTests"`                               // run helm test on each release after deployment
+	TestTimeout     string        `json:"testTimeout,omitempty" validate:"omitempty,duration"`
+	PrePull         ImagePrePull  `json:"prePull,omitempty"`
+
+	// RequireBreakingAck stops upgrades whose release notes list breaking changes until
+	// they are acknowledged, interactively or with deploy --ack-breaking
+	RequireBreakingAck bool `json:"requireBreakingAck,omitempty"`
+
+	// Profiles are sizing presets, e.g. small, medium and large, selected with deploy --profile
+	// or by Profile
+	Profiles map[string]SizingProfile `json:"profiles,omitempty" validate:"dive"`
+	Profile  string                   `json:"profile,omitempty"`
+}
+
+// SizingProfile overlays the values of charts for one deployment size: replicas, resource
+// requests and limits, storage sizes
+type SizingProfile struct {
+	Description string                    `json:"description,omitempty"`
+	Charts      map[string]ProfileOverlay `json:"charts" validate:"dive"` // by chart name
+}
+
+// ProfileOverlay is merged over a chart's valuesFile and under its inline values
+type ProfileOverlay struct {
+	Values     map[string]interface{} `json:"values,omitempty"`
+	ValuesFile string                 `json:"valuesFile,omitempty" validate:"omitempty,file"`
+}
+
+// ImagePrePull pulls the images of every chart onto the nodes with a temporary DaemonSet
+// before any release is installed, so large images do not slow down the rollouts
+type ImagePrePull struct {
+	Enabled      bool              `json:"enabled"`
+	Images       []string          `json:"images,omitempty"`                                // pulled besides those in the rendered charts
+	Namespace    string            `json:"namespace,omitempty"`                             // default the deployment namespace
+	Timeout      string            `json:"timeout,omitempty" validate:"omitempty,duration"` // default 15m
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	PullSecrets  []string          `json:"pullSecrets,omitempty"` // image pull secrets in the namespace
 }
 
 // DeployChart defines a chart to be deployed
@@ -226,6 +620,42 @@ type DeployChart struct {
 	ValuesFile  string                 `json:"valuesFile,omitempty" validate:"omitempty,file"`
 	HealthCheck HealthCheckConfig      `json:"healthCheck"`
 	DependsOn   []string               `json:"dependsOn,omitempty"`
+	Hooks       []ChartHook            `json:"hooks,omitempty" validate:"dive"`
+	Optional    bool                   `json:"optional,omitempty"` // test failures are reported as warnings
+
+	// Override the helm timeout, wait and atomic settings of the deployment for this chart
+	Timeout string `json:"timeout,omitempty" validate:"omitempty,duration"`
+	Wait    *bool  `json:"wait,omitempty"`
+	Atomic  *bool  `json:"atomic,omitempty"`
+
+	// ReleaseNotes is a changelog file for the chart, e.g. from a synced application repository;
+	// by default the CHANGELOG.md or RELEASE-NOTES.md of the chart directory is read
+	ReleaseNotes string `json:"releaseNotes,omitempty"`
+
+	PostRenderers []ChartPostRenderer `json:"postRenderers,omitempty" validate:"dive"`
+}
+
+// ChartPostRenderer patches a chart's rendered manifests before Helm applies them,
+// either with a kustomize overlay or an external binary reading stdin and writing stdout
+type ChartPostRenderer struct {
+	Type string   `json:"type" validate:"required,oneof=kustomize exec"`
+	Path string   `json:"path" validate:"required"` // overlay directory or executable
+	Args []string `json:"args,omitempty"`           // exec only
+}
+
+// ChartHook is a script or in-cluster Job run before or after a chart is deployed
+type ChartHook struct {
+	Name           string            `json:"name" validate:"required"`
+	Phase          string            `json:"phase" validate:"required,oneof=pre post"`
+	Type           string            `json:"type" validate:"required,oneof=script job"`
+	Command        []string          `json:"command" validate:"required,min=1"`
+	WorkingDir     string            `json:"workingDir,omitempty"` // script hooks
+	Env            map[string]string `json:"env,omitempty"`
+	Image          string            `json:"image,omitempty" validate:"required_if=Type job"`
+	Namespace      string            `json:"namespace,omitempty"` // job hooks, defaults to the chart namespace
+	ServiceAccount string            `json:"serviceAccount,omitempty"`
+	Timeout        string            `json:"timeout,omitempty" validate:"omitempty,duration"`
+	FailurePolicy  string            `json:"failurePolicy,omitempty" validate:"omitempty,oneof=fail warn"`
 }
 
 // K8sConfig contains Kubernetes-specific settings
@@ -236,6 +666,9 @@ type K8sConfig struct {
 	Timeout     string `json:"timeout" validate:"duration"`
 	WaitTimeout string `json:"waitTimeout" validate:"duration"`
 
+	// GPU requirements of AI workloads
+	GPU GPUConfig `json:"gpu,omitempty"`
+
 	// RBAC configuration
 	RBAC struct {
 		Enabled  bool     `json:"enabled"`
@@ -286,6 +719,7 @@ type HealthCheckConfig struct {
 	Timeout         string            `json:"timeout" validate:"duration"`
 	Retries         int               `json:"retries" validate:"min=0,max=10"`
 	Interval        string            `json:"interval" validate:"duration"`
+	URLs            []string          `json:"urls,omitempty" validate:"omitempty,dive,url"` // infrastructure checks, probed like URL
 }
 
 // CustomValidation defines custom validation scripts
@@ -303,13 +737,140 @@ type ValidationConfig struct {
 	E2E  E2EConfig      `json:"e2e"`
 }
 
+// GPUConfig describes the GPU capacity AI workloads need. preflight gpu and post-validate
+// check the nodes, the device plugin and the driver against it.
+type GPUConfig struct {
+	Enabled          bool              `json:"enabled"`
+	Resource         string            `json:"resource,omitempty"`                  // extended resource, default nvidia.com/gpu
+	MinNodes         int               `json:"minNodes,omitempty" validate:"min=0"` // default 1
+	MinGPUs          int               `json:"minGpus,omitempty" validate:"min=0"`  // allocatable across the GPU nodes
+	NodeSelector     map[string]string `json:"nodeSelector,omitempty"`
+	MinDriverVersion string            `json:"minDriverVersion,omitempty"` // e.g. 535.104
+	DevicePlugin     string            `json:"devicePlugin,omitempty"`     // namespace/name of the device plugin DaemonSet, found by name when empty
+	SmokeTest        GPUSmokeTest      `json:"smokeTest"`
+}
+
+// GPUSmokeTest runs nvidia-smi in a Job that requests one GPU
+type GPUSmokeTest struct {
+	Enabled   bool   `json:"enabled"`
+	Image     string `json:"image,omitempty"`                                 // default nvcr.io/nvidia/cuda:12.4.1-base-ubuntu22.04
+	Namespace string `json:"namespace,omitempty"`                             // default the kubernetes namespace
+	Timeout   string `json:"timeout,omitempty" validate:"omitempty,duration"` // default 5m
+}
+
 // PostValidation contains post-deployment validation settings
 type PostValidation struct {
-	Scripts      []ScriptConfig      `json:"scripts,omitempty"`
-	HealthChecks []HealthCheckConfig `json:"healthChecks,omitempty"`
-	CustomChecks []CustomValidation  `json:"customChecks,omitempty"`
-	Parallel     bool                `json:"parallel"`
-	Timeout      string              `json:"timeout" validate:"duration"`
+	Scripts      []ScriptConfig         `json:"scripts,omitempty"`
+	HealthChecks []HealthCheckConfig    `json:"healthChecks,omitempty"`
+	CustomChecks []CustomValidation     `json:"customChecks,omitempty"`
+	Parallel     bool                   `json:"parallel"`
+	Timeout      string                 `json:"timeout" validate:"duration"`
+	Soak         SoakConfig             `json:"soak"`
+	Synthetics   []SyntheticTransaction `json:"synthetics,omitempty" validate:"dive"`
+	Load         LoadGate               `json:"load"`
+	Resilience   ResilienceCheck        `json:"resilience"`
+}
+
+// ResilienceCheck deletes one pod of each critical Deployment and checks that the service
+// stays available while the pod is replaced within RecoveryTimeout
+type ResilienceCheck struct {
+	Enabled         bool               `json:"enabled"`
+	Selector        string             `json:"selector,omitempty"` // label selector for critical Deployments in the namespace
+	Deployments     []ResilienceTarget `json:"deployments,omitempty" validate:"dive"`
+	RecoveryTimeout string             `json:"recoveryTimeout,omitempty" validate:"omitempty,duration"` // default 2m
+	ProbeInterval   string             `json:"probeInterval,omitempty" validate:"omitempty,duration"`   // default 1s
+}
+
+// ResilienceTarget is a Deployment to disrupt, with the URL its service answers on
+type ResilienceTarget struct {
+	Name      string `json:"name" validate:"required"`
+	Namespace string `json:"namespace,omitempty"`
+	URL       string `json:"url,omitempty" validate:"omitempty,url"`
+}
+
+// LoadGate is a short load test run by post-validate: VirtualUsers loop over the endpoints
+// for Duration, and the run fails when a threshold is exceeded
+type LoadGate struct {
+	Enabled      bool           `json:"enabled"`
+	Engine       string         `json:"engine,omitempty" validate:"omitempty,oneof=builtin k6"` // default builtin
+	Script       string         `json:"script,omitempty" validate:"omitempty,file"`             // k6 script, generated from the endpoints when empty
+	VirtualUsers int            `json:"virtualUsers,omitempty" validate:"min=0,max=1000"`       // default 10
+	Duration     string         `json:"duration,omitempty" validate:"omitempty,duration"`       // default 30s
+	Endpoints    []LoadEndpoint `json:"endpoints,omitempty" validate:"dive"`
+	Thresholds   LoadThresholds `json:"thresholds"`
+}
+
+// LoadEndpoint is a request the virtual users send
+type LoadEndpoint struct {
+	Name    string            `json:"name" validate:"required"`
+	URL     string            `json:"url" validate:"required,url"`
+	Method  string            `json:"method,omitempty" validate:"omitempty,oneof=GET POST PUT PATCH DELETE HEAD"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// LoadThresholds fail the load gate; unset thresholds are not checked
+type LoadThresholds struct {
+	MaxErrorRate float64 `json:"maxErrorRate,omitempty" validate:"min=0,max=1"` // failed requests, e.g. 0.01
+	P95Latency   string  `json:"p95Latency,omitempty" validate:"omitempty,duration"`
+	P99Latency   string  `json:"p99Latency,omitempty" validate:"omitempty,duration"`
+	MinRPS       float64 `json:"minRps,omitempty" validate:"min=0"`
+}
+
+// SyntheticTransaction is a scripted sequence of HTTP calls checked as one user workflow,
+// such as login, create, read and delete. Values extracted from a response are available
+// to later steps as {{.name}}, next to {{env "NAME"}} and {{param "key"}}.
+type SyntheticTransaction struct {
+	Name    string            `json:"name" validate:"required"`
+	BaseURL string            `json:"baseUrl,omitempty" validate:"omitempty,url"`
+	Headers map[string]string `json:"headers,omitempty"`                               // sent with every step
+	Timeout string            `json:"timeout,omitempty" validate:"omitempty,duration"` // per step, default 30s
+	Steps   []SyntheticStep   `json:"steps" validate:"required,min=1,dive"`
+}
+
+// SyntheticStep is one HTTP call of a synthetic transaction. The transaction stops at the
+// first failing step, except that steps marked Always still run, for cleanup.
+type SyntheticStep struct {
+	Name           string               `json:"name" validate:"required"`
+	Method         string               `json:"method,omitempty" validate:"omitempty,oneof=GET POST PUT PATCH DELETE HEAD"`
+	Path           string               `json:"path" validate:"required"` // joined to baseUrl unless absolute
+	Headers        map[string]string    `json:"headers,omitempty"`
+	Body           string               `json:"body,omitempty"`
+	ExpectedStatus int                  `json:"expectedStatus,omitempty" validate:"omitempty,min=100,max=599"` // default any 2xx
+	MaxLatency     string               `json:"maxLatency,omitempty" validate:"omitempty,duration"`
+	Assertions     []SyntheticAssertion `json:"assertions,omitempty" validate:"dive"`
+	Extract        map[string]string    `json:"extract,omitempty"` // variable -> JSON path such as data.items.0.id, or header:Name
+	Always         bool                 `json:"always,omitempty"`
+}
+
+// SyntheticAssertion checks a value of a step's response: a JSON path, header:Name or body.
+// Without Equals or Contains the value only has to exist.
+type SyntheticAssertion struct {
+	Path     string `json:"path" validate:"required"`
+	Equals   string `json:"equals,omitempty"`
+	Contains string `json:"contains,omitempty"`
+}
+
+// SoakConfig is the post-validate soak phase: the SLOs are sampled for Duration after the
+// deployment and the run fails when one burns its error budget faster than allowed
+type SoakConfig struct {
+	Duration   string `json:"duration,omitempty" validate:"omitempty,duration"` // enables the phase, --soak overrides it
+	Interval   string `json:"interval,omitempty" validate:"omitempty,duration"` // default 30s
+	Prometheus string `json:"prometheus,omitempty" validate:"omitempty,url"`    // default monitoring.prometheus.endpoint
+	SLOs       []SLO  `json:"slos,omitempty" validate:"dive"`
+}
+
+// SLO is an objective checked during the soak, either with PromQL queries or by probing a URL.
+// Errors and samples slower than MaxLatency are bad events; the burn rate is their ratio
+// divided by the error budget, 1 - Objective.
+type SLO struct {
+	Name         string  `json:"name" validate:"required"`
+	Objective    float64 `json:"objective" validate:"gt=0,lt=1"`         // e.g. 0.999
+	MaxBurnRate  float64 `json:"maxBurnRate,omitempty" validate:"min=0"` // default 1
+	ErrorQuery   string  `json:"errorQuery,omitempty"`                   // PromQL returning the error ratio
+	LatencyQuery string  `json:"latencyQuery,omitempty"`                 // PromQL returning seconds
+	MaxLatency   string  `json:"maxLatency,omitempty" validate:"omitempty,duration"`
+	URL          string  `json:"url,omitempty" validate:"omitempty,url"` // probed instead of querying Prometheus
 }
 
 // E2EConfig contains end-to-end testing configuration
@@ -347,6 +908,11 @@ type SecurityConfig struct {
 	AllowedRegistries []string          `json:"allowedRegistries"`
 	RequiredLabels    map[string]string `json:"requiredLabels"`
 
+	// Data residency: regions (globs such as eu-*) cloud resources may be created in, and
+	// domains the registries, repositories and endpoints must belong to
+	AllowedRegions []string `json:"allowedRegions,omitempty"`
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+
 	// Scanning configuration
 	Scanning struct {
 		Enabled    bool     `json:"enabled"`
@@ -361,6 +927,9 @@ type SecurityConfig struct {
 		Config  map[string]interface{} `json:"config"`
 	} `json:"authentication"`
 
+	// Generated credentials
+	Credentials CredentialsConfig `json:"credentials"`
+
 	// Encryption configuration
 	Encryption struct {
 		Enabled bool   `json:"enabled"`
@@ -452,14 +1021,85 @@ type ScriptConfig struct {
 	Shell   string            `json:"shell" validate:"oneof=bash sh zsh fish"`
 }
 
+// CredentialsConfig controls generation and storage of component credentials
+type CredentialsConfig struct {
+	Backend   string           `json:"backend" validate:"omitempty,oneof=file kubernetes"`
+	VaultPath string           `json:"vaultPath"` // file backend, defaults to <workspace>/credentials.vault
+	KeyFile   string           `json:"keyFile"`   // file backend, defaults to <workspace>/.vault.key
+	Namespace string           `json:"namespace"` // kubernetes backend
+	Secret    string           `json:"secret"`    // kubernetes backend Secret name
+	Items     []CredentialSpec `json:"items,omitempty" validate:"dive"`
+}
+
+// CredentialSpec describes a credential generated on first install
+type CredentialSpec struct {
+	Name   string `json:"name" validate:"required"`
+	Type   string `json:"type" validate:"omitempty,oneof=password token hex base64"`
+	Length int    `json:"length" validate:"omitempty,min=8,max=256"`
+	Param  string `json:"param,omitempty"` // parameter key, defaults to credentials.<name>
+}
+
 // InstallState tracks the state of installation steps
 type InstallState struct {
 	Steps     []StepState `json:"steps"`
 	StartTime time.Time   `json:"startTime"`
 	EndTime   *time.Time  `json:"endTime,omitempty"`
-	Status    string      `json:"status" validate:"oneof=pending running completed failed paused"`
+	Status    string      `json:"status" validate:"oneof=pending running completed failed paused scheduled"`
 	LastError string      `json:"lastError,omitempty"`
 	Resume    bool        `json:"resume"`
+	PausedAt  string      `json:"pausedAt,omitempty"` // approval gate a paused installation waits at
+	RunID     string      `json:"runId,omitempty"`    // run that last saved the state
+
+	ChangeTicket string `json:"changeTicket,omitempty"` // open change ticket a resumed run reports to
+
+	Approvals []GateApproval `json:"approvals,omitempty"`
+
+	Parameters map[string]Parameter   `json:"parameters,omitempty"`
+	Modules    map[string]ModuleState `json:"modules,omitempty"` // Terraform modules by name, for module-level resume
+
+	Actions []RollbackAction `json:"actions,omitempty"` // changes rollback can undo, oldest first
+}
+
+// RollbackAction is a change made to an environment, journalled so rollback can undo it
+type RollbackAction struct {
+	Sequence   int               `json:"seq"`
+	Kind       string            `json:"kind" validate:"oneof=helm-release namespace terraform-apply db-migration checkpoint"`
+	Name       string            `json:"name"` // release, namespace, Terraform working directory, database or checkpoint
+	Namespace  string            `json:"namespace,omitempty"`
+	Revision   int               `json:"revision,omitempty"` // release revision before the change, 0 for a new release
+	Details    map[string]string `json:"details,omitempty"`
+	RunID      string            `json:"runId,omitempty"`
+	Status     string            `json:"status" validate:"oneof=applied reverted irreversible failed"`
+	Error      string            `json:"error,omitempty"`
+	RecordedAt time.Time         `json:"recordedAt"`
+	RevertedAt *time.Time        `json:"revertedAt,omitempty"`
+}
+
+// ModuleState is the outcome of the last apply that touched a Terraform module
+type ModuleState struct {
+	Status    string    `json:"status" validate:"oneof=applied failed"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Parameter is a typed value produced by one step and consumed by later steps
+type Parameter struct {
+	Type      string      `json:"type" validate:"oneof=string number bool list object"`
+	Value     interface{} `json:"value"`
+	Source    string      `json:"source"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+	Transient bool        `json:"-"` // held in memory only, never written to the state file
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// GateApproval records who let an installation past an approval gate
+type GateApproval struct {
+	Gate       string    `json:"gate"`
+	ApprovedBy string    `json:"approvedBy"`
+	Method     string    `json:"method" validate:"oneof=flag interactive command"`
+	ApprovedAt time.Time `json:"approvedAt"`
+	Comment    string    `json:"comment,omitempty"`
+	RunID      string    `json:"runId,omitempty"` // run the approval was given for
 }
 
 // StepState tracks individual step execution state
@@ -533,10 +1173,12 @@ func GenerateDefaultConfig() *InstallerConfig {
 					Branch: "main",
 					Auth:   AuthConfig{Token: "vendor_github_token"},
 				},
-				Client: GitRepoConfig{
-					Repo:       "https://github.com/client/helm-charts",
-					PushToRepo: true,
-					Auth:       AuthConfig{Token: "client_github_token"},
+				Client: HelmClientConfig{
+					GitRepoConfig: GitRepoConfig{
+						Repo:       "https://github.com/client/helm-charts",
+						PushToRepo: true,
+						Auth:       AuthConfig{Token: "client_github_token"},
+					},
 				},
 			},
 			Terraform: TerraformConfig{
@@ -0,0 +1,338 @@
+package connectivity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// checkConcurrency bounds the endpoints probed at once
+const checkConcurrency = 8
+
+// DNS results
+const (
+	DNSResolved = "resolved"
+	DNSFailed   = "failed"
+	DNSViaProxy = "via proxy" // not resolvable here, but the proxy resolves it
+)
+
+// Endpoint is an external host and port a run needs, with the settings that name it and
+// the steps that connect to it
+type Endpoint struct {
+	Host    string   `json:"host"`
+	Port    int      `json:"port"`
+	Scheme  string   `json:"scheme"` // https, http or ssh
+	Sources []string `json:"sources"`
+	Steps   []string `json:"steps"`
+}
+
+// Address is the host:port of the endpoint
+func (e Endpoint) Address() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// Result is the outcome of resolving and connecting to one endpoint
+type Result struct {
+	Endpoint
+	Addresses []string `json:"addresses,omitempty"`
+	DNS       string   `json:"dns"`
+	Proxy     string   `json:"proxy,omitempty"` // proxy the connection went through
+	Reachable bool     `json:"reachable"`
+	LatencyMs int64    `json:"latencyMs,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Endpoints extracts the external endpoints of the configuration: the vendor and client
+// registries and repositories, the cloud provider APIs and the URLs post-validate probes.
+// Cluster-internal hosts, such as service names, are left out.
+func Endpoints(cfg *config.InstallerConfig) []Endpoint {
+	byAddress := make(map[string]*Endpoint)
+	var order []string
+	add := func(source, value, defaultScheme string, steps ...string) {
+		endpoint, ok := parseEndpoint(value, defaultScheme)
+		if !ok {
+			return
+		}
+		existing, found := byAddress[endpoint.Address()]
+		if !found {
+			existing = &endpoint
+			byAddress[endpoint.Address()] = existing
+			order = append(order, endpoint.Address())
+		}
+		existing.Sources = appendUnique(existing.Sources, source)
+		for _, step := range steps {
+			existing.Steps = appendUnique(existing.Steps, step)
+		}
+	}
+
+	images := cfg.Artifacts.Images
+	vendorScheme, clientScheme := registryScheme(images.Vendor), registryScheme(images.Client)
+	add("artifacts.images.vendor.registry", valueOr(images.Vendor.Registry, images.Vendor.URL), vendorScheme, "package-pull")
+	add("artifacts.images.client.registry", valueOr(images.Client.Registry, images.Client.URL), clientScheme, "package-pull", "deploy")
+	add("artifacts.helm.vendor.repo", cfg.Artifacts.Helm.Vendor.Repo, "https", "package-pull")
+	add("artifacts.helm.client.repo", cfg.Artifacts.Helm.Client.Repo, "https", "package-pull")
+	if chartRepo := cfg.Artifacts.Helm.Client.ChartRepo; chartRepo.Enabled && chartRepo.Type != "local" {
+		add("artifacts.helm.client.chartRepo.url", chartRepo.URL, "https", "package-pull", "deploy")
+	}
+	add("artifacts.terraform.vendor.repo", cfg.Artifacts.Terraform.Vendor.Repo, "https", "package-pull")
+	add("artifacts.terraform.client.repo", cfg.Artifacts.Terraform.Client.Repo, "https", "package-pull", "provision-infra")
+
+	for _, api := range cloudAPIs(cfg.Cloud) {
+		add("cloud.provider", api, "https", "provision-infra")
+	}
+
+	post := cfg.Validation.Post
+	add("monitoring.prometheus.endpoint", cfg.Monitoring.Prometheus.Endpoint, "http", "post-validate")
+	for i, check := range post.HealthChecks {
+		add(fmt.Sprintf("validation.post.healthChecks[%d].url", i), check.URL, "https", "post-validate")
+		for _, checkURL := range check.URLs {
+			add(fmt.Sprintf("validation.post.healthChecks[%d].urls", i), checkURL, "https", "post-validate")
+		}
+	}
+	for _, tx := range post.Synthetics {
+		add(fmt.Sprintf("validation.post.synthetics[%s].baseUrl", tx.Name), tx.BaseURL, "https", "post-validate")
+	}
+	for _, target := range post.Load.Endpoints {
+		add(fmt.Sprintf("validation.post.load.endpoints[%s].url", target.Name), target.URL, "https", "post-validate")
+	}
+
+	endpoints := make([]Endpoint, 0, len(order))
+	for _, address := range order {
+		endpoints = append(endpoints, *byAddress[address])
+	}
+	return endpoints
+}
+
+// ForSteps returns the endpoints at least one of the steps connects to
+func ForSteps(endpoints []Endpoint, steps []string) []Endpoint {
+	var selected []Endpoint
+	for _, endpoint := range endpoints {
+		for _, step := range steps {
+			if slices.Contains(endpoint.Steps, step) {
+				selected = append(selected, endpoint)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// cloudAPIs are the API hosts the cloud CLIs and Terraform providers call for a provider
+func cloudAPIs(cloud config.CloudConfig) []string {
+	switch cloud.Provider {
+	case "aws":
+		if cloud.Region == "" {
+			return []string{"sts.amazonaws.com"}
+		}
+		return []string{"sts." + cloud.Region + ".amazonaws.com", "ec2." + cloud.Region + ".amazonaws.com"}
+	case "azure":
+		return []string{"login.microsoftonline.com", "management.azure.com"}
+	case "gcp":
+		return []string{"oauth2.googleapis.com", "compute.googleapis.com"}
+	}
+	return nil
+}
+
+// parseEndpoint reads a URL, a registry reference such as registry.example.com/team or an
+// scp-like git address such as git@github.com:org/repo.git
+func parseEndpoint(value, defaultScheme string) (Endpoint, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Endpoint{}, false
+	}
+	if !strings.Contains(value, "://") {
+		if user, rest, found := strings.Cut(value, "@"); found && !strings.Contains(user, "/") && strings.Contains(rest, ":") {
+			value = "ssh://" + user + "@" + strings.Replace(rest, ":", "/", 1)
+		} else {
+			value = defaultScheme + "://" + value
+		}
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Hostname() == "" || clusterInternal(parsed.Hostname()) {
+		return Endpoint{}, false
+	}
+	scheme := parsed.Scheme
+	if scheme == "oci" {
+		scheme = "https"
+	}
+	port, _ := strconv.Atoi(parsed.Port())
+	if port == 0 {
+		port = map[string]int{"http": 80, "https": 443, "ssh": 22}[scheme]
+	}
+	if port == 0 {
+		return Endpoint{}, false
+	}
+	return Endpoint{Host: parsed.Hostname(), Port: port, Scheme: scheme}, true
+}
+
+// clusterInternal reports whether a host only resolves inside the cluster or is local
+func clusterInternal(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	if host == "localhost" {
+		return true
+	}
+	return !strings.Contains(host, ".") || strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".cluster.local")
+}
+
+func registryScheme(registry config.RegistryConfig) string {
+	if registry.Insecure {
+		return "http"
+	}
+	return "https"
+}
+
+// Check resolves every endpoint and opens a TCP connection to it, through the proxy the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables select for its URL
+func Check(ctx context.Context, endpoints []Endpoint, timeout time.Duration) []Result {
+	results := make([]Result, len(endpoints))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, checkConcurrency)
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = check(ctx, endpoint, timeout)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	sort.SliceStable(results, func(i, j int) bool { return !results[i].Reachable && results[j].Reachable })
+	return results
+}
+
+func check(ctx context.Context, endpoint Endpoint, timeout time.Duration) Result {
+	result := Result{Endpoint: endpoint}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addresses, dnsErr := net.DefaultResolver.LookupHost(ctx, endpoint.Host)
+	result.Addresses = addresses
+	result.DNS = DNSResolved
+
+	proxy := proxyFor(endpoint)
+	if dnsErr != nil {
+		if proxy == nil {
+			result.DNS = DNSFailed
+			result.Error = fmt.Sprintf("DNS lookup failed: %v", dnsErr)
+			return result
+		}
+		result.DNS = DNSViaProxy
+	}
+
+	started := time.Now()
+	var err error
+	if proxy != nil {
+		result.Proxy = proxy.Host
+		err = connectThroughProxy(ctx, proxy, endpoint.Address())
+	} else {
+		var conn net.Conn
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", endpoint.Address())
+		if err == nil {
+			conn.Close()
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Reachable = true
+	result.LatencyMs = time.Since(started).Milliseconds()
+	return result
+}
+
+// proxyFor returns the proxy for an HTTP or HTTPS endpoint, nil for a direct connection
+func proxyFor(endpoint Endpoint) *url.URL {
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil
+	}
+	request := &http.Request{URL: &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Address()}}
+	proxy, err := http.ProxyFromEnvironment(request)
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// connectThroughProxy opens a tunnel to address with an HTTP CONNECT request to the proxy
+func connectThroughProxy(ctx context.Context, proxy *url.URL, address string) error {
+	proxyAddress := proxy.Host
+	if proxy.Port() == "" {
+		port := "80"
+		if proxy.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxy.Hostname(), port)
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return fmt.Errorf("proxy %s unreachable: %w", proxy.Host, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		request.SetBasicAuth(user.Username(), password)
+		request.Header.Set("Proxy-Authorization", request.Header.Get("Authorization"))
+		request.Header.Del("Authorization")
+	}
+	if err := request.Write(conn); err != nil {
+		return fmt.Errorf("proxy %s: %w", proxy.Host, err)
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return fmt.Errorf("proxy %s: %w", proxy.Host, err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy %s refused the tunnel: %s", proxy.Host, response.Status)
+	}
+	return nil
+}
+
+// Failed returns the endpoints that could not be reached
+func Failed(results []Result) []string {
+	var failed []string
+	for _, result := range results {
+		if !result.Reachable {
+			failed = append(failed, fmt.Sprintf("%s (%s)", result.Address(), result.Error))
+		}
+	}
+	return failed
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
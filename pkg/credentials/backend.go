@@ -0,0 +1,291 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"golang.org/x/crypto/scrypt"
+)
+
+// VaultKeyEnv is the environment variable holding the passphrase for the local vault
+const VaultKeyEnv = "E2E_INSTALLER_VAULT_KEY"
+
+// Backend stores generated credentials
+type Backend interface {
+	Load() (map[string]string, error)
+	Save(credentials map[string]string) error
+	Name() string
+}
+
+// FileVault is a local vault file sealed with AES-256-GCM
+type FileVault struct {
+	path    string
+	keyFile string
+}
+
+// sealedVault is the on-disk format of the vault file. Version 2 derives the key from the
+// passphrase with scrypt and the salt; version 1 vaults, sealed with its SHA-256, still open
+// and are sealed again as version 2 when next saved.
+type sealedVault struct {
+	Version    int    `json:"version"`
+	Salt       string `json:"salt,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// vaultVersion is the version vaults are sealed with
+const vaultVersion = 2
+
+// scrypt cost parameters, the recommendation for interactive use; a key takes ~100ms
+const (
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+// NewFileVault creates a vault backend; the key comes from VaultKeyEnv or keyFile
+func NewFileVault(path, keyFile string) *FileVault {
+	return &FileVault{path: path, keyFile: keyFile}
+}
+
+// Name returns the backend description
+func (v *FileVault) Name() string {
+	return "file:" + v.path
+}
+
+// Load decrypts the vault, returning an empty set when it does not exist yet
+func (v *FileVault) Load() (map[string]string, error) {
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), nil
+		}
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var sealed sealedVault
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse vault %s: %w", v.path, err)
+	}
+
+	var salt []byte
+	if sealed.Version >= vaultVersion {
+		if salt, err = base64.StdEncoding.DecodeString(sealed.Salt); err != nil || len(salt) == 0 {
+			return nil, fmt.Errorf("invalid vault salt in %s", v.path)
+		}
+	}
+	gcm, err := v.cipher(false, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(sealed.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault ciphertext: %w", err)
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal vault, wrong key?: %w", err)
+	}
+
+	credentials := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse unsealed vault: %w", err)
+	}
+	return credentials, nil
+}
+
+// Save encrypts the credentials into the vault file with a new salt. The file is replaced in
+// one rename, so a crash leaves the previous vault intact.
+func (v *FileVault) Save(credentials map[string]string) error {
+	salt, err := randomBytes(16)
+	if err != nil {
+		return err
+	}
+	gcm, err := v.cipher(true, salt)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	nonce, err := randomBytes(gcm.NonceSize())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sealedVault{
+		Version:    vaultVersion,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	// The vault holds the only copy of the credentials, so it is on disk before it replaces the old one
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(temp.Name(), v.path); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+// cipher derives the AES-GCM cipher from the secret and salt, creating a key file on first use
+// if allowed. Without a salt the key is the SHA-256 of the secret, as version 1 vaults have it.
+func (v *FileVault) cipher(create bool, salt []byte) (cipher.AEAD, error) {
+	secret, err := v.secret(create)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if len(salt) > 0 {
+		if key, err = scrypt.Key(secret, salt, scryptN, scryptR, scryptP, 32); err != nil {
+			return nil, fmt.Errorf("failed to derive vault key: %w", err)
+		}
+	} else {
+		sum := sha256.Sum256(secret)
+		key = sum[:]
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize vault cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// secret returns the passphrase of VaultKeyEnv or the contents of the key file
+func (v *FileVault) secret(create bool) ([]byte, error) {
+	var secret []byte
+
+	if passphrase := os.Getenv(VaultKeyEnv); passphrase != "" {
+		secret = []byte(passphrase)
+	} else {
+		data, err := os.ReadFile(v.keyFile)
+		switch {
+		case err == nil:
+			secret = []byte(strings.TrimSpace(string(data)))
+		case os.IsNotExist(err) && create:
+			generated, err := randomBytes(32)
+			if err != nil {
+				return nil, err
+			}
+			secret = []byte(base64.StdEncoding.EncodeToString(generated))
+			if err := os.MkdirAll(filepath.Dir(v.keyFile), 0700); err != nil {
+				return nil, fmt.Errorf("failed to create vault key directory: %w", err)
+			}
+			if err := os.WriteFile(v.keyFile, secret, 0600); err != nil {
+				return nil, fmt.Errorf("failed to write vault key: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("vault key not available, set %s or provide %s: %w", VaultKeyEnv, v.keyFile, err)
+		}
+	}
+
+	return secret, nil
+}
+
+// KubernetesBackend stores credentials in a Kubernetes Secret
+type KubernetesBackend struct {
+	k8sMgr    *k8s.Manager
+	namespace string
+	secret    string
+}
+
+// NewKubernetesBackend creates a backend storing credentials in namespace/secret
+func NewKubernetesBackend(k8sMgr *k8s.Manager, namespace, secret string) *KubernetesBackend {
+	return &KubernetesBackend{k8sMgr: k8sMgr, namespace: namespace, secret: secret}
+}
+
+// Name returns the backend description
+func (b *KubernetesBackend) Name() string {
+	return fmt.Sprintf("kubernetes:%s/%s", b.namespace, b.secret)
+}
+
+// Load reads the Secret, returning an empty set when it does not exist yet
+func (b *KubernetesBackend) Load() (map[string]string, error) {
+	credentials := make(map[string]string)
+	output, err := b.k8sMgr.Get("secrets", b.namespace, b.secret)
+	if k8s.IsNotFound(err) {
+		return credentials, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(output, &secret); err != nil {
+		return nil, fmt.Errorf("failed to parse secret %s: %w", b.secret, err)
+	}
+
+	for key, encoded := range secret.Data {
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data for key %s in secret %s: %w", key, b.secret, err)
+		}
+		credentials[key] = string(value)
+	}
+	return credentials, nil
+}
+
+// Save writes the credentials into the Secret
+func (b *KubernetesBackend) Save(credentials map[string]string) error {
+	manifest := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "Opaque",
+		"metadata": map[string]interface{}{
+			"name":      b.secret,
+			"namespace": b.namespace,
+			"labels": map[string]string{
+				"app.kubernetes.io/managed-by": "e2e-k8s-installer",
+			},
+		},
+		"stringData": credentials,
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret: %w", err)
+	}
+
+	return b.k8sMgr.ApplyManifest(string(data))
+}
@@ -0,0 +1,108 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+const (
+	lowerChars  = "abcdefghijkmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	digitChars  = "23456789"
+	symbolChars = "!#%+-.:=@_~"
+)
+
+// defaultLength is used when a spec does not set a length
+const defaultLength = 32
+
+// Generate creates a random credential according to the spec
+func Generate(spec config.CredentialSpec) (string, error) {
+	length := spec.Length
+	if length == 0 {
+		length = defaultLength
+	}
+
+	switch spec.Type {
+	case "", "password":
+		return generatePassword(length)
+	case "token":
+		return randomString(length, lowerChars+upperChars+digitChars)
+	case "hex":
+		buf, err := randomBytes((length + 1) / 2)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf)[:length], nil
+	case "base64":
+		buf, err := randomBytes(length)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(buf), nil
+	default:
+		return "", fmt.Errorf("unsupported credential type: %s", spec.Type)
+	}
+}
+
+// generatePassword returns a password containing every character class
+func generatePassword(length int) (string, error) {
+	classes := []string{lowerChars, upperChars, digitChars, symbolChars}
+	all := lowerChars + upperChars + digitChars + symbolChars
+
+	password := make([]byte, length)
+	for i := range password {
+		charset := all
+		if i < len(classes) {
+			charset = classes[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	// Shuffle so the guaranteed classes are not always at the start
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random data: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomString(length int, charset string) (string, error) {
+	out := make([]byte, length)
+	for i := range out {
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		out[i] = c
+	}
+	return string(out), nil
+}
+
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random data: %w", err)
+	}
+	return charset[n.Int64()], nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate random data: %w", err)
+	}
+	return buf, nil
+}
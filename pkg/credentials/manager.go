@@ -0,0 +1,144 @@
+package credentials
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+const (
+	// DefaultSecretName is the Secret used by the kubernetes backend
+	DefaultSecretName = "e2e-k8s-installer-credentials"
+	// DefaultNamespace is the namespace used by the kubernetes backend
+	DefaultNamespace = "e2e-k8s-installer"
+)
+
+// Manager generates component credentials on first install and reuses them afterwards
+type Manager struct {
+	backend Backend
+	specs   []config.CredentialSpec
+}
+
+// EnsureResult describes the outcome of ensuring credentials
+type EnsureResult struct {
+	Generated []string
+	Reused    []string
+}
+
+// NewManager creates a credentials manager for the configured backend
+func NewManager(cfg config.CredentialsConfig, workspace string, k8sCfg *config.KubernetesConfig) (*Manager, error) {
+	var backend Backend
+
+	switch cfg.Backend {
+	case "", "file":
+		vaultPath := cfg.VaultPath
+		if vaultPath == "" {
+			vaultPath = filepath.Join(workspace, "credentials.vault")
+		}
+		keyFile := cfg.KeyFile
+		if keyFile == "" {
+			keyFile = filepath.Join(workspace, ".vault.key")
+		}
+		backend = NewFileVault(vaultPath, keyFile)
+	case "kubernetes":
+		k8sMgr, err := k8s.NewManager(k8sCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kubernetes backend: %w", err)
+		}
+		namespace := cfg.Namespace
+		if namespace == "" {
+			namespace = DefaultNamespace
+		}
+		secret := cfg.Secret
+		if secret == "" {
+			secret = DefaultSecretName
+		}
+		backend = NewKubernetesBackend(k8sMgr, namespace, secret)
+	default:
+		return nil, fmt.Errorf("unsupported credentials backend: %s", cfg.Backend)
+	}
+
+	return &Manager{
+		backend: backend,
+		specs:   cfg.Items,
+	}, nil
+}
+
+// Ensure loads stored credentials, generates any that are missing and saves them back
+func (m *Manager) Ensure() (map[string]string, *EnsureResult, error) {
+	stored, err := m.backend.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load credentials from %s: %w", m.backend.Name(), err)
+	}
+
+	result := &EnsureResult{}
+	credentials := make(map[string]string, len(m.specs))
+
+	for _, spec := range m.specs {
+		if value, ok := stored[spec.Name]; ok && value != "" {
+			credentials[spec.Name] = value
+			result.Reused = append(result.Reused, spec.Name)
+			continue
+		}
+
+		value, err := Generate(spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate credential %s: %w", spec.Name, err)
+		}
+		stored[spec.Name] = value
+		credentials[spec.Name] = value
+		result.Generated = append(result.Generated, spec.Name)
+	}
+
+	if len(result.Generated) > 0 {
+		err := m.backend.Save(stored)
+		audit.Record("credentials.generate", m.backend.Name(), map[string]interface{}{
+			"names": result.Generated,
+		}, err)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to store credentials in %s: %w", m.backend.Name(), err)
+		}
+	}
+
+	sort.Strings(result.Generated)
+	sort.Strings(result.Reused)
+
+	logger.Info("Credentials ready").
+		Str("backend", m.backend.Name()).
+		Int("generated", len(result.Generated)).
+		Int("reused", len(result.Reused)).
+		Send()
+
+	return credentials, result, nil
+}
+
+// Publish makes credentials available to values templates and tests through the parameter store.
+// They are held in memory only; the backend remains the source of truth.
+func (m *Manager) Publish(store *params.Store, credentials map[string]string) {
+	for _, spec := range m.specs {
+		value, ok := credentials[spec.Name]
+		if !ok {
+			continue
+		}
+		store.SetSecret(ParamKey(spec), value, "credentials")
+	}
+}
+
+// Location describes where the credentials are stored, e.g. file:<path>
+func (m *Manager) Location() string {
+	return m.backend.Name()
+}
+
+// ParamKey returns the parameter key a credential is published under
+func ParamKey(spec config.CredentialSpec) string {
+	if spec.Param != "" {
+		return spec.Param
+	}
+	return "credentials." + spec.Name
+}
@@ -0,0 +1,335 @@
+package declarative
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Engines that reconcile the applied resources into a cluster
+const (
+	EngineClusterAPI = "cluster-api"
+	EngineCrossplane = "crossplane"
+)
+
+// Defaults for the declarative cluster
+const (
+	DefaultNamespace           = "default"
+	DefaultReadyTimeout        = 30 * time.Minute
+	DefaultConnectionSecretKey = "kubeconfig"
+)
+
+// clusterAPIResource is the Cluster API resource the workload cluster is tracked by
+const clusterAPIResource = "clusters.cluster.x-k8s.io"
+
+// commandTimeout bounds kubectl calls other than waiting for the cluster
+const commandTimeout = 5 * time.Minute
+
+// Manager applies Cluster API or Crossplane resources to a management cluster and
+// retrieves the workload cluster's kubeconfig once it is Ready
+type Manager struct {
+	config        config.DeclarativeCluster
+	engine        string
+	namespace     string
+	readyTimeout  time.Duration
+	kubeconfigDir string
+}
+
+// NewManager creates a declarative cluster manager with defaults applied
+func NewManager(cfg config.DeclarativeCluster) (*Manager, error) {
+	mgr := &Manager{
+		config:        cfg,
+		engine:        cfg.Engine,
+		namespace:     cfg.Namespace,
+		readyTimeout:  DefaultReadyTimeout,
+		kubeconfigDir: filepath.Join(".", "workspace", ".kube"),
+	}
+	if mgr.engine == "" {
+		mgr.engine = EngineClusterAPI
+	}
+	if mgr.namespace == "" {
+		mgr.namespace = DefaultNamespace
+	}
+	if cfg.ReadyTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.ReadyTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid declarative readyTimeout %q: %w", cfg.ReadyTimeout, err)
+		}
+		mgr.readyTimeout = timeout
+	}
+	if cfg.ClusterName == "" || len(cfg.Manifests) == 0 {
+		return nil, fmt.Errorf("declarative mode requires clusterName and manifests")
+	}
+	if mgr.engine == EngineCrossplane && cfg.ClaimResource == "" {
+		return nil, fmt.Errorf("the crossplane engine requires claimResource")
+	}
+	return mgr, nil
+}
+
+// SetKubeconfigDir sets where the workload kubeconfig is written when none is configured
+func (m *Manager) SetKubeconfigDir(dir string) {
+	m.kubeconfigDir = dir
+}
+
+// Engine returns cluster-api or crossplane
+func (m *Manager) Engine() string {
+	return m.engine
+}
+
+// ClusterName returns the Cluster or claim name
+func (m *Manager) ClusterName() string {
+	return m.config.ClusterName
+}
+
+// KubeconfigPath returns where the workload cluster's kubeconfig is written
+func (m *Manager) KubeconfigPath() string {
+	if m.config.Kubeconfig != "" {
+		return m.config.Kubeconfig
+	}
+	return filepath.Join(m.kubeconfigDir, m.config.ClusterName+".kubeconfig")
+}
+
+// resource is the resource/name the engine reports readiness on
+func (m *Manager) resource() string {
+	if m.engine == EngineCrossplane {
+		return m.config.ClaimResource + "/" + m.config.ClusterName
+	}
+	return clusterAPIResource + "/" + m.config.ClusterName
+}
+
+// CheckManagementCluster verifies kubectl, the manifests and that the management cluster
+// serves the engine's resource
+func (m *Manager) CheckManagementCluster(dryRun bool) error {
+	for _, manifest := range m.config.Manifests {
+		if _, err := os.Stat(manifest); err != nil {
+			return fmt.Errorf("declarative manifest %s: %w", manifest, err)
+		}
+	}
+	if dryRun {
+		logger.Info("DRY RUN: Management cluster check skipped").Send()
+		return nil
+	}
+	if _, err := execx.LookPath("kubectl"); err != nil {
+		return fmt.Errorf("kubectl not found in PATH, it is required for declarative mode: %w", err)
+	}
+
+	crd := clusterAPIResource
+	if m.engine == EngineCrossplane {
+		crd = m.config.ClaimResource
+	}
+	if _, err := m.kubectl(commandTimeout, "get", "crd", crd); err != nil {
+		return fmt.Errorf("management cluster does not serve %s, is %s installed: %w", crd, m.engine, err)
+	}
+	return nil
+}
+
+// Diff shows what applying the manifests would change on the management cluster
+func (m *Manager) Diff(dryRun bool) (string, error) {
+	if dryRun {
+		logger.Info("DRY RUN: Declarative diff skipped").Send()
+		return "", nil
+	}
+	out, err := m.kubectl(commandTimeout, m.manifestArgs("diff")...)
+	// kubectl diff exits 1 when there are differences
+	if exitErr, ok := err.(*kubectlError); ok && exitErr.code == 1 {
+		err = nil
+	}
+	return string(out), err
+}
+
+// Validate checks the manifests against the management cluster with a server-side dry run
+func (m *Manager) Validate(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Declarative validation skipped").Send()
+		return nil
+	}
+	_, err := m.kubectl(commandTimeout, append(m.manifestArgs("apply"), "--dry-run=server")...)
+	return err
+}
+
+// Apply applies the manifests, waits for the workload cluster to become Ready and writes
+// its kubeconfig
+func (m *Manager) Apply(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Declarative cluster apply skipped").Str("resource", m.resource()).Send()
+		return nil
+	}
+
+	logger.Info("Applying declarative cluster resources").
+		Str("engine", m.engine).
+		Str("cluster", m.config.ClusterName).
+		Str("namespace", m.namespace).
+		Send()
+
+	_, err := m.kubectl(commandTimeout, m.manifestArgs("apply")...)
+	if err == nil {
+		err = m.waitReady()
+	}
+	if err == nil {
+		err = m.writeKubeconfig()
+	}
+	audit.Record("declarative.apply", m.resource(), map[string]interface{}{
+		"engine":    m.engine,
+		"namespace": m.namespace,
+		"manifests": m.config.Manifests,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("failed to provision %s cluster %s: %w", m.engine, m.config.ClusterName, err)
+	}
+
+	logger.Info("Workload cluster is ready").
+		Str("cluster", m.config.ClusterName).
+		Str("kubeconfig", m.KubeconfigPath()).
+		Send()
+	return nil
+}
+
+// Destroy deletes the applied resources and waits for the engine to tear the cluster down
+func (m *Manager) Destroy(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Declarative cluster deletion skipped").Str("resource", m.resource()).Send()
+		return nil
+	}
+
+	logger.Info("Deleting declarative cluster resources").Str("resource", m.resource()).Send()
+	args := append(m.manifestArgs("delete"), "--ignore-not-found", "--wait",
+		fmt.Sprintf("--timeout=%s", m.readyTimeout))
+	_, err := m.kubectl(m.readyTimeout+time.Minute, args...)
+	audit.Record("declarative.delete", m.resource(), map[string]interface{}{"engine": m.engine}, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s cluster %s: %w", m.engine, m.config.ClusterName, err)
+	}
+	if err := os.Remove(m.KubeconfigPath()); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove workload kubeconfig").Str("path", m.KubeconfigPath()).Err(err).Send()
+	}
+	return nil
+}
+
+// waitReady waits for the Ready condition of the Cluster or claim
+func (m *Manager) waitReady() error {
+	logger.Info("Waiting for workload cluster to become Ready").
+		Str("resource", m.resource()).
+		Dur("timeout", m.readyTimeout).
+		Send()
+	_, err := m.kubectl(m.readyTimeout+time.Minute, "wait", "--for=condition=Ready", m.resource(),
+		"-n", m.namespace, fmt.Sprintf("--timeout=%s", m.readyTimeout))
+	if err != nil {
+		return fmt.Errorf("workload cluster did not become Ready within %s: %w", m.readyTimeout, err)
+	}
+	return nil
+}
+
+// writeKubeconfig extracts the workload kubeconfig from the secret the engine wrote: Cluster
+// API's <cluster>-kubeconfig secret, or the claim's connection secret for Crossplane
+func (m *Manager) writeKubeconfig() error {
+	secret, key := m.config.ClusterName+"-kubeconfig", "value"
+	if m.engine == EngineCrossplane {
+		out, err := m.kubectl(commandTimeout, "get", m.resource(), "-n", m.namespace,
+			"-o", "jsonpath={.spec.writeConnectionSecretToRef.name}")
+		if err != nil {
+			return err
+		}
+		if secret = strings.TrimSpace(string(out)); secret == "" {
+			return fmt.Errorf("claim %s has no spec.writeConnectionSecretToRef", m.resource())
+		}
+		key = m.config.ConnectionSecretKey
+		if key == "" {
+			key = DefaultConnectionSecretKey
+		}
+	}
+
+	out, err := m.kubectl(commandTimeout, "get", "secret", secret, "-n", m.namespace,
+		"-o", fmt.Sprintf("jsonpath={.data.%s}", key))
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig secret %s: %w", secret, err)
+	}
+	kubeconfig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil || len(kubeconfig) == 0 {
+		return fmt.Errorf("kubeconfig secret %s has no %s key", secret, key)
+	}
+
+	path := m.KubeconfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+	if err := os.WriteFile(path, kubeconfig, 0600); err != nil {
+		return fmt.Errorf("failed to write workload kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// CheckWorkloadCluster verifies the workload cluster's API server is ready
+func (m *Manager) CheckWorkloadCluster() error {
+	cmd := execx.Command("kubectl", "--kubeconfig", m.KubeconfigPath(), "get", "--raw", "/readyz")
+	cmd.Timeout = time.Minute
+	if _, err := cmd.Output(context.Background()); err != nil {
+		return fmt.Errorf("workload cluster %s is not ready: %w", m.config.ClusterName, err)
+	}
+	return nil
+}
+
+// Outputs are the cluster details published like infrastructure outputs
+func (m *Manager) Outputs() map[string]interface{} {
+	kubeconfig, err := filepath.Abs(m.KubeconfigPath())
+	if err != nil {
+		kubeconfig = m.KubeconfigPath()
+	}
+	return map[string]interface{}{
+		"cluster_name":    m.config.ClusterName,
+		"kubeconfig_path": kubeconfig,
+		"engine":          m.engine,
+	}
+}
+
+func (m *Manager) manifestArgs(verb string) []string {
+	args := []string{verb, "-n", m.namespace}
+	for _, manifest := range m.config.Manifests {
+		args = append(args, "-f", manifest)
+	}
+	return args
+}
+
+// kubectlError keeps the exit code of a failed kubectl call
+type kubectlError struct {
+	code int
+	msg  string
+}
+
+func (e *kubectlError) Error() string {
+	return e.msg
+}
+
+// kubectl runs kubectl against the management cluster and returns its stdout
+func (m *Manager) kubectl(timeout time.Duration, args ...string) ([]byte, error) {
+	var global []string
+	if m.config.ManagementKubeconfig != "" {
+		global = append(global, "--kubeconfig", m.config.ManagementKubeconfig)
+	}
+	if m.config.ManagementContext != "" {
+		global = append(global, "--context", m.config.ManagementContext)
+	}
+	cmd := execx.Command("kubectl", append(global, args...)...)
+	cmd.Timeout = timeout
+
+	output, err := cmd.Output(context.Background())
+	if err != nil {
+		code := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		return output, &kubectlError{code: code, msg: fmt.Sprintf("kubectl %s failed: %v", args[0], err)}
+	}
+	return output, nil
+}
@@ -0,0 +1,288 @@
+package deprecations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// API is a Kubernetes group version and kind that was deprecated and later removed
+type API struct {
+	APIVersion   string `json:"apiVersion"`
+	Kind         string `json:"kind"`
+	DeprecatedIn string `json:"deprecatedIn"`
+	RemovedIn    string `json:"removedIn"`
+	Replacement  string `json:"replacement,omitempty"`
+}
+
+// Finding is an object that uses an API deprecated or removed in the target version
+type Finding struct {
+	Source       string `json:"source"`
+	Kind         string `json:"kind"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name"`
+	APIVersion   string `json:"apiVersion"`
+	DeprecatedIn string `json:"deprecatedIn"`
+	RemovedIn    string `json:"removedIn"`
+	Replacement  string `json:"replacement,omitempty"`
+	Removed      bool   `json:"removed"`
+}
+
+// Version is a Kubernetes minor version
+type Version struct {
+	Major int
+	Minor int
+}
+
+var versionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// removedAPIs lists the APIs removed from Kubernetes since 1.16, from the deprecated API migration guide
+var removedAPIs = []API{
+	{"extensions/v1beta1", "Deployment", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta1", "Deployment", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "Deployment", "1.9", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "DaemonSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "DaemonSet", "1.9", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "ReplicaSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta1", "StatefulSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "StatefulSet", "1.9", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", "1.9", "1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "PodSecurityPolicy", "1.11", "1.16", "policy/v1beta1"},
+	{"extensions/v1beta1", "Ingress", "1.14", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "Ingress", "1.19", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "IngressClass", "1.19", "1.22", "networking.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "1.16", "1.22", "apiextensions.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRole", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "Role", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "PriorityClass", "1.14", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", "1.17", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "CertificateSigningRequest", "1.19", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "Lease", "1.19", "1.22", "coordination.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "APIService", "1.19", "1.22", "apiregistration.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", "1.21", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", "1.21", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "Event", "1.19", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.22", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", "PodDisruptionBudget", "1.21", "1.25", "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", "1.21", "1.25", ""},
+	{"node.k8s.io/v1beta1", "RuntimeClass", "1.20", "1.25", "node.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "FlowSchema", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "PriorityLevelConfiguration", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.23", "1.26", "autoscaling/v2"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.24", "1.27", "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "FlowSchema", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "PriorityLevelConfiguration", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// ParseVersion reads the minor version of v1.30, 1.30.2 or v1.30.2-eks-1234
+func ParseVersion(version string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return Version{}, fmt.Errorf("invalid Kubernetes version %q, expected <major>.<minor>", version)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return Version{Major: major, Minor: minor}, nil
+}
+
+// String formats the version as <major>.<minor>
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Less reports whether v is older than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+// Lookup returns the deprecation of an API, if it is one
+func Lookup(apiVersion, kind string) (API, bool) {
+	for _, api := range removedAPIs {
+		if api.APIVersion == apiVersion && api.Kind == kind {
+			return api, true
+		}
+	}
+	return API{}, false
+}
+
+// Check reports whether an object of apiVersion and kind is deprecated or removed in target
+func Check(apiVersion, kind string, target Version) (API, bool, bool) {
+	api, found := Lookup(apiVersion, kind)
+	if !found {
+		return API{}, false, false
+	}
+	deprecatedIn, _ := ParseVersion(api.DeprecatedIn)
+	removedIn, _ := ParseVersion(api.RemovedIn)
+	if target.Less(deprecatedIn) {
+		return api, false, false
+	}
+	return api, true, !target.Less(removedIn)
+}
+
+// ScanManifest checks every object of a multi-document manifest against target
+func ScanManifest(manifest []byte, source string, target Version) ([]Finding, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	var findings []Finding
+	for {
+		var object manifestObject
+		if err := decoder.Decode(&object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest of %s: %w", source, err)
+		}
+		findings = append(findings, object.findings(source, target)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Removed && !findings[j].Removed
+	})
+	return findings, nil
+}
+
+// Annotations identifying how a live object was applied
+const (
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+	releaseNameAnnotation = "meta.helm.sh/release-name"
+)
+
+// LiveSource is the Source of live objects that no Helm release owns
+const LiveSource = "kubectl"
+
+// ScanLiveObjects checks the objects of a kubectl get -o json list. The API server returns
+// every object in its preferred version, so each object is judged by the apiVersion in its
+// last-applied configuration; objects without one are skipped. Findings are attributed to
+// the Helm release owning the object, or LiveSource.
+func ScanLiveObjects(list []byte, target Version) ([]Finding, error) {
+	var objects struct {
+		Items []struct {
+			Metadata struct {
+				Name        string            `json:"name"`
+				Namespace   string            `json:"namespace"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(list, &objects); err != nil {
+		return nil, fmt.Errorf("failed to parse live objects: %w", err)
+	}
+
+	var findings []Finding
+	for _, item := range objects.Items {
+		applied := item.Metadata.Annotations[lastAppliedAnnotation]
+		if applied == "" {
+			continue
+		}
+		var object manifestObject
+		if err := json.Unmarshal([]byte(applied), &object); err != nil {
+			continue
+		}
+		source := item.Metadata.Annotations[releaseNameAnnotation]
+		if source == "" {
+			source = LiveSource
+		}
+		object.Metadata.Name = item.Metadata.Name
+		object.Metadata.Namespace = item.Metadata.Namespace
+		findings = append(findings, object.findings(source, target)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Removed && !findings[j].Removed
+	})
+	return findings, nil
+}
+
+// Kinds returns every kind with a removed API version, to list live objects by
+func Kinds() []string {
+	seen := make(map[string]bool)
+	var kinds []string
+	for _, api := range removedAPIs {
+		if !seen[api.Kind] {
+			seen[api.Kind] = true
+			kinds = append(kinds, api.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// BlockingSources returns the charts, or LiveSource, whose objects use APIs removed in the target
+func BlockingSources(findings []Finding) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, finding := range Removed(findings) {
+		if !seen[finding.Source] {
+			seen[finding.Source] = true
+			sources = append(sources, finding.Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Removed returns the findings whose API is no longer served in the target version
+func Removed(findings []Finding) []Finding {
+	var removed []Finding
+	for _, finding := range findings {
+		if finding.Removed {
+			removed = append(removed, finding)
+		}
+	}
+	return removed
+}
+
+type manifestObject struct {
+	APIVersion string `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string `yaml:"kind" json:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name" json:"name"`
+		Namespace string `yaml:"namespace" json:"namespace"`
+	} `yaml:"metadata" json:"metadata"`
+	Items []manifestObject `yaml:"items" json:"items"`
+}
+
+func (o manifestObject) findings(source string, target Version) []Finding {
+	if strings.HasSuffix(o.Kind, "List") {
+		var findings []Finding
+		for _, item := range o.Items {
+			findings = append(findings, item.findings(source, target)...)
+		}
+		return findings
+	}
+
+	api, deprecated, removed := Check(o.APIVersion, o.Kind, target)
+	if !deprecated {
+		return nil
+	}
+	return []Finding{{
+		Source:       source,
+		Kind:         o.Kind,
+		Namespace:    o.Metadata.Namespace,
+		Name:         o.Metadata.Name,
+		APIVersion:   o.APIVersion,
+		DeprecatedIn: api.DeprecatedIn,
+		RemovedIn:    api.RemovedIn,
+		Replacement:  api.Replacement,
+		Removed:      removed,
+	}}
+}
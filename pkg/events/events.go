@@ -0,0 +1,231 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Event types
+const (
+	RunStarted    = "run.started"
+	RunSucceeded  = "run.succeeded"
+	RunFailed     = "run.failed"
+	RunPaused     = "run.paused" // at an approval gate or outside a maintenance window
+	StepStarted   = "step.started"
+	StepSucceeded = "step.succeeded"
+	StepFailed    = "step.failed"
+	StepSkipped   = "step.skipped"
+)
+
+// Types lists every event type
+var Types = []string{RunStarted, RunSucceeded, RunFailed, RunPaused, StepStarted, StepSucceeded, StepFailed, StepSkipped}
+
+// Delivery limits: events wait in a bounded queue so a slow endpoint cannot hold up the run,
+// and each sink gets a few attempts per event
+const (
+	queueSize       = 256
+	deliveryTimeout = 10 * time.Second
+	maxAttempts     = 3
+)
+
+// Event is one lifecycle transition of an installer run
+type Event struct {
+	ID          string                 `json:"id"` // <runId>-<sequence>, for deduplication
+	Type        string                 `json:"type"`
+	Time        time.Time              `json:"time"`
+	Sequence    int                    `json:"sequence"`
+	RunID       string                 `json:"runId"`
+	Workspace   string                 `json:"workspace,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Version     string                 `json:"installerVersion,omitempty"`
+	Step        string                 `json:"step,omitempty"`
+	Attempt     int                    `json:"attempt,omitempty"`
+	DurationMs  int64                  `json:"durationMs,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	ExitCode    int                    `json:"exitCode,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// sink delivers an encoded event to one destination
+type sink interface {
+	name() string
+	send(ctx context.Context, event Event, body []byte) error
+	close()
+}
+
+// Emitter delivers events to the configured sinks in order, in the background
+type Emitter struct {
+	base     Event
+	types    []string
+	sinks    []sink
+	queue    chan Event
+	done     chan struct{}
+	mutex    sync.Mutex
+	sequence int
+	closed   bool
+}
+
+// NewEmitter creates an emitter for the configured sinks, nil when none is configured. The
+// run, workspace, environment and version of base are set on every event.
+func NewEmitter(cfg config.EventsConfig, base Event) (*Emitter, error) {
+	for _, eventType := range cfg.Types {
+		if !slices.Contains(Types, eventType) {
+			return nil, fmt.Errorf("installer.events.types: unknown event type %q", eventType)
+		}
+	}
+	var sinks []sink
+	if cfg.Webhook != nil {
+		webhook, err := newWebhookSink(*cfg.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, webhook)
+	}
+	if cfg.NATS != nil {
+		nats, err := newNATSSink(*cfg.NATS)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, nats)
+	}
+	if cfg.Kafka != nil {
+		kafka, err := newKafkaSink(*cfg.Kafka)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, kafka)
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	e := &Emitter{
+		base:  base,
+		types: cfg.Types,
+		sinks: sinks,
+		queue: make(chan Event, queueSize),
+		done:  make(chan struct{}),
+	}
+	go e.deliver()
+	return e, nil
+}
+
+// Emit queues an event. When the queue is full the event is dropped rather than wait.
+func (e *Emitter) Emit(event Event) {
+	if e == nil || (len(e.types) > 0 && !slices.Contains(e.types, event.Type)) {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.closed {
+		return
+	}
+	e.sequence++
+	event.Sequence = e.sequence
+	event.ID = fmt.Sprintf("%s-%d", e.base.RunID, e.sequence)
+	event.Time = time.Now().UTC()
+	event.RunID, event.Workspace, event.Environment, event.Version = e.base.RunID, e.base.Workspace, e.base.Environment, e.base.Version
+
+	select {
+	case e.queue <- event:
+	default:
+		logger.Warn("Event queue full, dropping event").Str("type", event.Type).Str("step", event.Step).Send()
+	}
+}
+
+// Close delivers the queued events, waiting at most timeout, and closes the sinks
+func (e *Emitter) Close(timeout time.Duration) {
+	if e == nil {
+		return
+	}
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return
+	}
+	e.closed = true
+	close(e.queue)
+	e.mutex.Unlock()
+
+	select {
+	case <-e.done:
+	case <-time.After(timeout):
+		logger.Warn("Undelivered events dropped").Int("queued", len(e.queue)).Send()
+	}
+	for _, s := range e.sinks {
+		s.close()
+	}
+}
+
+func (e *Emitter) deliver() {
+	defer close(e.done)
+	for event := range e.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			logger.Warn("Failed to marshal event").Str("type", event.Type).Err(err).Send()
+			continue
+		}
+		for _, s := range e.sinks {
+			e.send(s, event, body)
+		}
+	}
+}
+
+// send delivers an event to one sink, retrying with a growing backoff
+func (e *Emitter) send(s sink, event Event, body []byte) {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err = s.send(ctx, event, body)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	logger.Warn("Failed to deliver event").Str("sink", s.name()).Str("type", event.Type).Str("step", event.Step).Err(err).Send()
+}
+
+// Global emitter instance
+var (
+	globalEmitter *Emitter
+	globalMutex   sync.Mutex
+)
+
+// InitGlobalEmitter sets up the emitter install reports its transitions to
+func InitGlobalEmitter(cfg config.EventsConfig, base Event) error {
+	emitter, err := NewEmitter(cfg, base)
+	if err != nil {
+		return err
+	}
+	globalMutex.Lock()
+	globalEmitter = emitter
+	globalMutex.Unlock()
+	return nil
+}
+
+// Emit queues an event on the global emitter; without one it does nothing
+func Emit(event Event) {
+	globalMutex.Lock()
+	emitter := globalEmitter
+	globalMutex.Unlock()
+	emitter.Emit(event)
+}
+
+// CloseGlobalEmitter flushes and closes the global emitter
+func CloseGlobalEmitter(timeout time.Duration) {
+	globalMutex.Lock()
+	emitter := globalEmitter
+	globalEmitter = nil
+	globalMutex.Unlock()
+	emitter.Close(timeout)
+}
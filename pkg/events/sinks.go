@@ -0,0 +1,233 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/nats-io/nats.go"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// SecretEnv is the environment variable holding the HMAC key signing webhook bodies, over
+// installer.events.webhook.secretFile
+const SecretEnv = "E2E_INSTALLER_EVENTS_SECRET"
+
+// KafkaPasswordEnv is the environment variable holding the SASL password of the Kafka sink,
+// over installer.events.kafka.sasl.passwordFile
+const KafkaPasswordEnv = "E2E_INSTALLER_KAFKA_PASSWORD"
+
+// Headers set on webhook requests
+const (
+	SignatureHeader = "X-Installer-Signature" // sha256=<hex HMAC-SHA256 of the body>
+	EventHeader     = "X-Installer-Event"
+)
+
+// webhookSink posts each event to an HTTP endpoint
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	secret  []byte
+	client  *http.Client
+}
+
+func newWebhookSink(cfg config.EventWebhook) (*webhookSink, error) {
+	secret := []byte(os.Getenv(SecretEnv))
+	if len(secret) == 0 && cfg.SecretFile != "" {
+		data, err := os.ReadFile(cfg.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events webhook secret: %w", err)
+		}
+		secret = bytes.TrimSpace(data)
+	}
+	return &webhookSink{url: cfg.URL, headers: expandHeaders(cfg.Headers), secret: secret, client: &http.Client{}}, nil
+}
+
+func (s *webhookSink) name() string { return "webhook" }
+
+func (s *webhookSink) send(ctx context.Context, event Event, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, event.Type)
+	for key, value := range s.headers {
+		request.Header.Set(key, value)
+	}
+	if len(s.secret) > 0 {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		request.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return doRequest(s.client, request)
+}
+
+func (s *webhookSink) close() {}
+
+// doRequest sends a request, failing on any status but 2xx
+func doRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s answered %s: %s", request.URL.Host, response.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func expandHeaders(headers map[string]string) map[string]string {
+	expanded := make(map[string]string, len(headers))
+	for key, value := range headers {
+		expanded[key] = os.ExpandEnv(value)
+	}
+	return expanded
+}
+
+// natsSink publishes each event to a subject of a NATS server, over a connection opened
+// with the first event and kept, reconnecting, for the run
+type natsSink struct {
+	url     string
+	subject string
+	options []nats.Option
+	conn    *nats.Conn
+}
+
+func newNATSSink(cfg config.EventNATS) (*natsSink, error) {
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("installer.events.nats.url: invalid NATS URL %q", cfg.URL)
+	}
+	options := []nats.Option{nats.Name("e2e-k8s-installer/" + version.Version)}
+	if cfg.TokenFile != "" {
+		token, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NATS token: %w", err)
+		}
+		options = append(options, nats.Token(strings.TrimSpace(string(token))))
+	}
+	if cfg.CredsFile != "" {
+		options = append(options, nats.UserCredentials(cfg.CredsFile))
+	}
+	if cfg.NKeyFile != "" {
+		option, err := nats.NkeyOptionFromSeed(cfg.NKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NATS nkey seed: %w", err)
+		}
+		options = append(options, option)
+	}
+	return &natsSink{url: cfg.URL, subject: cfg.Subject, options: options}, nil
+}
+
+func (s *natsSink) name() string { return "nats" }
+
+func (s *natsSink) send(ctx context.Context, event Event, body []byte) error {
+	if s.conn == nil {
+		options := s.options
+		if deadline, ok := ctx.Deadline(); ok {
+			options = append(options[:len(options):len(options)], nats.Timeout(time.Until(deadline)))
+		}
+		conn, err := nats.Connect(s.url, options...)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.Publish(s.subject, body); err != nil {
+		return err
+	}
+	// The flush returns once the server has processed the message
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *natsSink) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// kafkaSink produces each event to a topic, with the run ID as the record key so the events
+// of a run stay ordered in one partition
+type kafkaSink struct {
+	client *kgo.Client
+}
+
+func newKafkaSink(cfg config.EventKafka) (*kafkaSink, error) {
+	options := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.ClientID("e2e-k8s-installer"),
+		kgo.RecordDeliveryTimeout(deliveryTimeout),
+	}
+	if cfg.TLS {
+		options = append(options, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if cfg.SASL != nil {
+		mechanism, err := kafkaSASL(*cfg.SASL)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, kgo.SASL(mechanism))
+	}
+	// The client connects with the first event
+	client, err := kgo.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("installer.events.kafka: %w", err)
+	}
+	return &kafkaSink{client: client}, nil
+}
+
+// kafkaSASL returns the SASL mechanism authenticating to the brokers, with the password of
+// KafkaPasswordEnv or the password file
+func kafkaSASL(cfg config.EventKafkaSASL) (sasl.Mechanism, error) {
+	password := os.Getenv(KafkaPasswordEnv)
+	if password == "" && cfg.PasswordFile != "" {
+		data, err := os.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka password: %w", err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	switch cfg.Mechanism {
+	case "PLAIN":
+		return plain.Auth{User: cfg.Username, Pass: password}.AsMechanism(), nil
+	case "SCRAM-SHA-256":
+		return scram.Auth{User: cfg.Username, Pass: password}.AsSha256Mechanism(), nil
+	case "SCRAM-SHA-512":
+		return scram.Auth{User: cfg.Username, Pass: password}.AsSha512Mechanism(), nil
+	}
+	return nil, fmt.Errorf("installer.events.kafka.sasl: unsupported mechanism %q", cfg.Mechanism)
+}
+
+func (s *kafkaSink) name() string { return "kafka" }
+
+func (s *kafkaSink) send(ctx context.Context, event Event, body []byte) error {
+	record := &kgo.Record{
+		Key:     []byte(event.RunID),
+		Value:   body,
+		Headers: []kgo.RecordHeader{{Key: EventHeader, Value: []byte(event.Type)}},
+	}
+	return s.client.ProduceSync(ctx, record).FirstErr()
+}
+
+func (s *kafkaSink) close() {
+	s.client.Close()
+}
@@ -0,0 +1,302 @@
+// Package execx runs the external tools the installer drives: terraform, make, kubectl,
+// helm, the cloud CLIs and database clients. Every command is bounded by its timeout, runs
+// with the installer's environment plus its own variables, streams its output into the step
+// logs, is recorded with its secrets masked, is echoed rather than run in dry-run mode, and
+// leaves an audit entry when it changes something.
+package execx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+)
+
+// Cmd is one external command. Only Name is required.
+type Cmd struct {
+	Name    string
+	Args    []string
+	Dir     string
+	Env     []string // added to the installer's environment
+	Stdin   io.Reader
+	Timeout time.Duration // zero leaves the command bounded by its context alone
+
+	// Grace is how long a command that timed out or was cancelled has to stop after it is
+	// sent an interrupt, like Ctrl+C, before it is killed; DefaultGrace when zero. Tools
+	// such as terraform use it to release state locks and record what they changed.
+	Grace time.Duration
+
+	// Stdout and Stderr receive the output as well, e.g. the terminal or a live log viewer
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Tag names the command in the step logs, the binary and its first argument by default.
+	// Console echoes its output in verbose mode.
+	Tag     string
+	Console bool
+
+	// Step is the step whose log the output goes to, the current tool step when empty
+	Step string
+
+	// Secrets are masked wherever the command line is recorded and in errors
+	Secrets []string
+
+	// DryRun prints the command line instead of running it
+	DryRun bool
+
+	// Local runs the command even while commands are simulated or kept in a cassette, for
+	// installer processes that simulate, record or replay their own commands
+	Local bool
+
+	// Audit is the action recorded in the audit log once the command ran, e.g. ansible.playbook,
+	// with AuditTarget and AuditDetails; commands that change nothing leave it empty
+	Audit        string
+	AuditTarget  string
+	AuditDetails map[string]interface{}
+}
+
+// DefaultGrace is the Grace of commands that set none
+const DefaultGrace = 10 * time.Second
+
+// Simulator answers a command in place of the tool, writing what the tool would print; see
+// pkg/simulate. It reports a failure the way the tool would, with a non-nil error.
+type Simulator func(c *Cmd, stdout, stderr io.Writer) error
+
+var simulator Simulator
+
+// Simulate routes every command to s rather than running it; nil runs commands again
+func Simulate(s Simulator) {
+	simulator = s
+}
+
+// Simulating reports whether commands are answered by a simulator
+func Simulating() bool {
+	return simulator != nil
+}
+
+// Observer sees every command that ran, with what it printed and how it ended; see pkg/cassette
+type Observer func(c *Cmd, stdout, stderr []byte, err error, duration time.Duration)
+
+var observer Observer
+
+// Observe passes every command run from now on to o; nil stops observing
+func Observe(o Observer) {
+	observer = o
+}
+
+// LookPath finds a tool like exec.LookPath. Every tool is present when simulating.
+func LookPath(file string) (string, error) {
+	if simulator != nil {
+		return file, nil
+	}
+	return exec.LookPath(file)
+}
+
+// Command returns the Cmd running name with args
+func Command(name string, args ...string) *Cmd {
+	return &Cmd{Name: name, Args: args}
+}
+
+// Run runs the command and returns its combined stdout and stderr, all of which is logged.
+// A failure carries the last lines of the output.
+func (c *Cmd) Run(ctx context.Context) ([]byte, error) {
+	var combined bytes.Buffer
+	stream := logger.StepToolOutput(c.Step, c.tag(), c.Console)
+	defer stream.Close()
+	// stdout and stderr are written from two goroutines once they are different writers
+	shared := &lockedWriter{w: io.MultiWriter(&combined, stream)}
+	var stdout, stderr io.Writer = shared, shared
+	if c.Stdout != nil {
+		stdout = io.MultiWriter(shared, c.Stdout)
+	}
+	if c.Stderr != nil && sameWriter(c.Stderr, c.Stdout) {
+		stderr = stdout
+	} else if c.Stderr != nil {
+		stderr = io.MultiWriter(shared, c.Stderr)
+	}
+
+	err := c.run(ctx, stdout, stderr)
+	if err != nil {
+		return combined.Bytes(), logtail.Attach(c.tool(), combined.Bytes(), c.error(err, nil))
+	}
+	return combined.Bytes(), nil
+}
+
+// Output runs the command and returns its stdout alone, for commands whose output is data.
+// Only stderr is logged, and it is quoted in the error of a failure.
+func (c *Cmd) Output(ctx context.Context) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	stream := logger.StepToolOutput(c.Step, c.tag(), c.Console)
+	defer stream.Close()
+
+	err := c.run(ctx, c.writers(&stdout, nil, c.Stdout), c.writers(&stderr, stream, c.Stderr))
+	if err != nil {
+		return stdout.Bytes(), logtail.Attach(c.tool(), stderr.Bytes(), c.error(err, stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// String returns the command line with the secrets masked
+func (c *Cmd) String() string {
+	return Mask(strings.Join(append([]string{c.Name}, c.Args...), " "), c.Secrets)
+}
+
+// Mask replaces every secret in text with ***
+func Mask(text string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+func (c *Cmd) run(ctx context.Context, stdout, stderr io.Writer) error {
+	if c.DryRun {
+		line := c.String()
+		if c.Dir != "" {
+			line += fmt.Sprintf(" (in %s)", c.Dir)
+		}
+		fmt.Printf("would run: %s\n", line)
+		logger.Info("DRY RUN: command not run").Str("command", c.String()).Send()
+		return nil
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	observer, simulator := observer, simulator
+	if c.Local {
+		observer, simulator = nil, nil
+	}
+	logger.Debug("Running command").Str("command", c.String()).Str("dir", c.Dir).Bool("simulated", simulator != nil).Send()
+
+	var captured, capturedErr bytes.Buffer
+	if observer != nil {
+		// One writer taking both streams stays one, so its writes are not concurrent
+		if sameWriter(stdout, stderr) {
+			stdout = io.MultiWriter(stdout, &captured)
+			stderr = stdout
+		} else {
+			stdout, stderr = io.MultiWriter(stdout, &captured), io.MultiWriter(stderr, &capturedErr)
+		}
+	}
+
+	start := time.Now()
+	var err error
+	if simulator != nil {
+		err = simulator(c, stdout, stderr)
+	} else {
+		cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+		cmd.Cancel = func() error {
+			// Interrupts are not delivered on Windows; the process is killed there at once
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				return cmd.Process.Kill()
+			}
+			return nil
+		}
+		cmd.WaitDelay = c.Grace
+		if cmd.WaitDelay == 0 {
+			cmd.WaitDelay = DefaultGrace
+		}
+		cmd.Dir = c.Dir
+		cmd.Env = append(os.Environ(), c.Env...)
+		cmd.Stdin = c.Stdin
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		err = cmd.Run()
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out")
+		if c.Timeout > 0 {
+			err = fmt.Errorf("timed out after %s", c.Timeout)
+		}
+	}
+	if observer != nil {
+		observer(c, captured.Bytes(), capturedErr.Bytes(), err, time.Since(start))
+	}
+	if c.Audit != "" {
+		details := map[string]interface{}{
+			"command":  c.String(),
+			"duration": time.Since(start).Round(time.Millisecond).String(),
+		}
+		if c.Dir != "" {
+			details["dir"] = c.Dir
+		}
+		if simulator != nil {
+			details["simulated"] = true
+		}
+		for key, value := range c.AuditDetails {
+			details[key] = value
+		}
+		audit.Record(c.Audit, c.AuditTarget, details, err)
+	}
+	return err
+}
+
+// error quotes what the command printed on stderr, secrets masked
+func (c *Cmd) error(err error, stderr []byte) error {
+	if message := Mask(strings.TrimSpace(string(stderr)), c.Secrets); message != "" {
+		return fmt.Errorf("%w: %s", err, message)
+	}
+	return err
+}
+
+func (c *Cmd) writers(buffer *bytes.Buffer, stream, extra io.Writer) io.Writer {
+	writers := []io.Writer{buffer}
+	if stream != nil {
+		writers = append(writers, stream)
+	}
+	if extra != nil {
+		writers = append(writers, extra)
+	}
+	return io.MultiWriter(writers...)
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// sameWriter is os/exec's test for one writer taking both streams, which then sees a single
+// goroutine write at a time
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+func (c *Cmd) tool() string {
+	return filepath.Base(c.Name)
+}
+
+func (c *Cmd) tag() string {
+	if c.Tag != "" {
+		return c.Tag
+	}
+	if len(c.Args) > 0 && !strings.HasPrefix(c.Args[0], "-") {
+		return c.tool() + " " + c.Args[0]
+	}
+	return c.tool()
+}
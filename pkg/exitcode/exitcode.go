@@ -0,0 +1,80 @@
+package exitcode
+
+import (
+	"errors"
+)
+
+// Process exit codes. They are part of the CLI contract for CI pipelines, so existing
+// values must never change meaning.
+const (
+	OK           = 0
+	Failure      = 1  // any failure without a more specific code
+	Config       = 3  // configuration could not be loaded or is invalid
+	Preflight    = 4  // prerequisite or preflight checks failed
+	Deploy       = 5  // artifacts, infrastructure or applications failed to deploy
+	Validation   = 6  // post-deployment validation failed or findings exceeded --fail-on
+	Test         = 7  // end-to-end tests failed
+	Partial      = 8  // the run completed but optional steps failed
+	Paused       = 9  // the run stopped at an approval gate and waits for --approve
+	Scheduled    = 10 // a phase was held back until its next maintenance window
+	Frozen       = 11 // the target namespaces are frozen and the freeze was not overridden
+	Updates      = 12 // check-updates found updates at or above --fail-on
+	Noncompliant = 13 // audit scored the environment below --min-score
+)
+
+// Error carries the exit code the process should end with
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap attaches code to err. Errors that already carry a code keep it, so the most specific
+// code set closest to the failure wins. A nil err stays nil.
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var coded *Error
+	if errors.As(err, &coded) {
+		return err
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Code returns the exit code for err: OK for nil, the attached code, or Failure
+func Code(err error) int {
+	if err == nil {
+		return OK
+	}
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return Failure
+}
+
+// remediations are the first thing to check for a failure with a given code
+var remediations = map[int]string{
+	Config:       "Check the configuration file against the error above, or regenerate a sample with 'setup --force'",
+	Preflight:    "Install the missing tools or fix the reported prerequisites, then run the command again",
+	Deploy:       "Inspect the tool logs of this run in the workspace logs directory and re-run the failed phase",
+	Paused:       "Approve the gate with 'approve --run <run ID> --gate <gate>', then resume with 'install --resume'",
+	Scheduled:    "Run the phase again inside a maintenance window, or let it wait with installer.maintenance.wait",
+	Frozen:       "Lift the freeze with 'freeze lift <namespace>', or pass --override-freeze \"<reason>\"",
+	Updates:      "Review the listed updates, raise the pinned versions in the configuration and run 'package-pull'",
+	Noncompliant: "Review the deviations in reports/audit-report.json, then re-run the phases they belong to, e.g. 'deploy' or 'db-migrate'",
+}
+
+// Remediation returns what to check first for a failure with code, "" when there is no
+// general advice
+func Remediation(code int) string {
+	return remediations[code]
+}
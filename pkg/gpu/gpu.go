@@ -0,0 +1,385 @@
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Defaults for an unset GPU configuration
+const (
+	DefaultResource   = "nvidia.com/gpu"
+	DefaultSmokeImage = "nvcr.io/nvidia/cuda:12.4.1-base-ubuntu22.04"
+
+	// CUDAAnnotation on an artifacts image declares the CUDA version it was built against
+	CUDAAnnotation = "cudaVersion"
+)
+
+// Check is the outcome of one GPU check
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Warning bool   `json:"warning,omitempty"` // passed, but could not be fully verified
+	Detail  string `json:"detail"`
+}
+
+// Node is a node advertising the GPU resource, with the versions NVIDIA GPU feature
+// discovery labels it with
+type Node struct {
+	Name    string `json:"name"`
+	GPUs    int    `json:"gpus"`
+	Product string `json:"product,omitempty"`
+	Driver  string `json:"driver,omitempty"`
+	CUDA    string `json:"cuda,omitempty"` // highest CUDA version the driver supports
+}
+
+// Report is the result of the GPU checks
+type Report struct {
+	Nodes  []Node  `json:"nodes"`
+	Checks []Check `json:"checks"`
+}
+
+// Passed reports whether no check failed
+func (r *Report) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the details of the failed checks
+func (r *Report) Failed() []string {
+	var failed []string
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	return failed
+}
+
+func (r *Report) add(name string, passed bool, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) warn(name, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Passed: true, Warning: true, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Run checks GPU node capacity, the device plugin and the driver and CUDA versions the
+// images need. With smoke it also runs nvidia-smi in a Job requesting one GPU in namespace.
+func Run(k8sMgr *k8s.Manager, cfg config.GPUConfig, images []config.ImageReference, namespace string, smoke bool) (*Report, error) {
+	resource := cfg.Resource
+	if resource == "" {
+		resource = DefaultResource
+	}
+	report := &Report{}
+
+	nodes, err := Nodes(k8sMgr, resource, cfg.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	report.Nodes = nodes
+	report.capacity(cfg, resource)
+
+	if err := report.devicePlugin(k8sMgr, cfg.DevicePlugin); err != nil {
+		return nil, err
+	}
+	report.compatibility(cfg.MinDriverVersion, images)
+
+	if smoke {
+		report.smokeTest(k8sMgr, cfg, resource, namespace)
+	}
+	return report, nil
+}
+
+// Nodes returns the nodes matching selector with the GPU resource allocatable
+func Nodes(k8sMgr *k8s.Manager, resource string, selector map[string]string) ([]Node, error) {
+	pairs := make([]string, 0, len(selector))
+	for key, value := range selector {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	output, err := k8sMgr.List("nodes", "", strings.Join(pairs, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	var nodes []Node
+	for _, item := range list.Items {
+		count, _ := strconv.Atoi(item.Status.Allocatable[resource])
+		if count == 0 {
+			continue
+		}
+		labels := item.Metadata.Labels
+		nodes = append(nodes, Node{
+			Name:    item.Metadata.Name,
+			GPUs:    count,
+			Product: labels["nvidia.com/gpu.product"],
+			Driver:  labelVersion(labels, "nvidia.com/cuda.driver-version.full", "nvidia.com/cuda.driver"),
+			CUDA:    labelVersion(labels, "nvidia.com/cuda.runtime-version.full", "nvidia.com/cuda.runtime"),
+		})
+	}
+	return nodes, nil
+}
+
+// labelVersion reads a version from its full label, or from the major, minor and rev labels
+// older GPU feature discovery releases set
+func labelVersion(labels map[string]string, full, prefix string) string {
+	if version := labels[full]; version != "" {
+		return version
+	}
+	var parts []string
+	for _, part := range []string{"major", "minor", "rev"} {
+		value := labels[prefix+"."+part]
+		if value == "" {
+			break
+		}
+		parts = append(parts, value)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (r *Report) capacity(cfg config.GPUConfig, resource string) {
+	minNodes := cfg.MinNodes
+	if minNodes == 0 {
+		minNodes = 1
+	}
+	total := 0
+	for _, node := range r.Nodes {
+		total += node.GPUs
+	}
+	r.add("gpu-nodes", len(r.Nodes) >= minNodes, "%d nodes allocate %s, %d required", len(r.Nodes), resource, minNodes)
+	if cfg.MinGPUs > 0 {
+		r.add("gpu-capacity", total >= cfg.MinGPUs, "%d GPUs allocatable, %d required", total, cfg.MinGPUs)
+	}
+}
+
+// devicePlugin checks that every pod of the device plugin DaemonSet is ready
+func (r *Report) devicePlugin(k8sMgr *k8s.Manager, name string) error {
+	namespace, dsName, named := strings.Cut(name, "/")
+	var output []byte
+	var err error
+	if named {
+		output, err = k8sMgr.Get("daemonsets", namespace, dsName)
+	} else {
+		output, err = k8sMgr.List("daemonsets", "", "")
+	}
+	if err != nil {
+		if named {
+			r.add("device-plugin", false, "daemonset %s not found", name)
+			return nil
+		}
+		return fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+
+	type daemonSet struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Status struct {
+			Desired int `json:"desiredNumberScheduled"`
+			Ready   int `json:"numberReady"`
+		} `json:"status"`
+	}
+	var candidates []daemonSet
+	if named {
+		var ds daemonSet
+		if err := json.Unmarshal(output, &ds); err != nil {
+			return fmt.Errorf("failed to parse daemonset %s: %w", name, err)
+		}
+		candidates = append(candidates, ds)
+	} else {
+		var list struct {
+			Items []daemonSet `json:"items"`
+		}
+		if err := json.Unmarshal(output, &list); err != nil {
+			return fmt.Errorf("failed to parse daemonsets: %w", err)
+		}
+		for _, ds := range list.Items {
+			if strings.Contains(ds.Metadata.Name, "nvidia-device-plugin") {
+				candidates = append(candidates, ds)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		if len(r.Nodes) > 0 {
+			r.warn("device-plugin", "no nvidia-device-plugin daemonset found, but %d nodes advertise GPUs", len(r.Nodes))
+		} else {
+			r.add("device-plugin", false, "no nvidia-device-plugin daemonset found")
+		}
+		return nil
+	}
+	for _, ds := range candidates {
+		id := ds.Metadata.Namespace + "/" + ds.Metadata.Name
+		switch {
+		case ds.Status.Desired == 0:
+			r.add("device-plugin", false, "%s is scheduled on no node", id)
+		default:
+			r.add("device-plugin", ds.Status.Ready == ds.Status.Desired, "%s has %d of %d pods ready", id, ds.Status.Ready, ds.Status.Desired)
+		}
+	}
+	return nil
+}
+
+// cudaTag matches the CUDA version at the start of tags such as 12.2.0-runtime-ubuntu22.04
+var cudaTag = regexp.MustCompile(`^v?(\d+\.\d+)`)
+
+// imageCUDA returns the CUDA version an image declares in its cudaVersion annotation or,
+// for images named after CUDA, in its tag
+func imageCUDA(image config.ImageReference) string {
+	if version := image.Annotations[CUDAAnnotation]; version != "" {
+		return version
+	}
+	if strings.Contains(strings.ToLower(image.Name), "cuda") {
+		if match := cudaTag.FindStringSubmatch(image.Version); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// compatibility checks every GPU node's driver against the minimum driver version and the
+// CUDA version each image was built with
+func (r *Report) compatibility(minDriver string, images []config.ImageReference) {
+	if len(r.Nodes) == 0 {
+		return
+	}
+	type requirement struct{ name, image, version string }
+	var requirements []requirement
+	if minDriver != "" {
+		requirements = append(requirements, requirement{"driver-version", "", minDriver})
+	}
+	for _, image := range images {
+		if version := imageCUDA(image); version != "" {
+			requirements = append(requirements, requirement{"cuda-compatibility", image.Name + ":" + image.Version, version})
+		}
+	}
+
+	for _, req := range requirements {
+		var incompatible, unknown []string
+		for _, node := range r.Nodes {
+			have := node.Driver
+			if req.image != "" {
+				have = node.CUDA
+			}
+			switch {
+			case have == "":
+				unknown = append(unknown, node.Name)
+			case compareVersions(have, req.version) < 0:
+				incompatible = append(incompatible, fmt.Sprintf("%s (%s)", node.Name, have))
+			}
+		}
+
+		subject := "driver " + req.version
+		if req.image != "" {
+			subject = fmt.Sprintf("%s needs CUDA %s", req.image, req.version)
+		}
+		switch {
+		case len(incompatible) > 0:
+			r.add(req.name, false, "%s; too old on %s", subject, strings.Join(incompatible, ", "))
+		case len(unknown) > 0:
+			r.warn(req.name, "%s; no GPU feature discovery version labels on %s", subject, strings.Join(unknown, ", "))
+		default:
+			r.add(req.name, true, "%s; satisfied on %d nodes", subject, len(r.Nodes))
+		}
+	}
+}
+
+// compareVersions compares dotted numeric versions such as 535.104.05 and 12.2
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// smokeTest runs nvidia-smi in a Job requesting one GPU, tolerating the usual GPU node taint
+func (r *Report) smokeTest(k8sMgr *k8s.Manager, cfg config.GPUConfig, resource, namespace string) {
+	if len(r.Nodes) == 0 {
+		r.add("smoke-test", false, "skipped, no GPU nodes")
+		return
+	}
+	image := cfg.SmokeTest.Image
+	if image == "" {
+		image = DefaultSmokeImage
+	}
+	if cfg.SmokeTest.Namespace != "" {
+		namespace = cfg.SmokeTest.Namespace
+	}
+	timeout := 5 * time.Minute
+	if cfg.SmokeTest.Timeout != "" {
+		timeout, _ = time.ParseDuration(cfg.SmokeTest.Timeout)
+	}
+
+	logger.Info("Running GPU smoke test").Str("image", image).Str("namespace", namespace).Send()
+	job, err := k8sMgr.RunJob(k8s.JobSpec{
+		Name:         "e2e-installer-gpu-smoke",
+		Namespace:    namespace,
+		Image:        image,
+		Command:      []string{"nvidia-smi"},
+		Labels:       map[string]string{"app.kubernetes.io/component": "gpu-smoke-test"},
+		Limits:       map[string]string{resource: "1"},
+		NodeSelector: cfg.NodeSelector,
+		Tolerations:  []string{resource},
+	}, timeout, 20)
+	if err != nil {
+		detail := err.Error()
+		if job != nil && len(job.LogTail) > 0 {
+			detail += ": " + job.LogTail[len(job.LogTail)-1]
+		}
+		r.add("smoke-test", false, "%s", detail)
+		return
+	}
+
+	output := strings.Join(job.LogTail, "\n")
+	if !strings.Contains(output, "NVIDIA-SMI") {
+		r.add("smoke-test", false, "nvidia-smi printed no GPU table")
+		return
+	}
+	detail := "nvidia-smi succeeded"
+	if match := regexp.MustCompile(`Driver Version:\s*(\S+)\s+CUDA Version:\s*(\S+)`).FindStringSubmatch(output); match != nil {
+		detail = fmt.Sprintf("nvidia-smi succeeded, driver %s, CUDA %s", match[1], match[2])
+	}
+	r.add("smoke-test", true, "%s", detail)
+}
@@ -0,0 +1,319 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Check kinds recorded in results
+const (
+	KindHTTP       = "http"
+	KindKubernetes = "kubernetes"
+	KindTCP        = "tcp"
+	KindDatabase   = "database"
+)
+
+// Result is the outcome of one probe after its retries
+type Result struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Target   string `json:"target"`
+	Passed   bool   `json:"passed"`
+	Attempts int    `json:"attempts"`
+	Duration string `json:"duration"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Database is the connection a database probe dials and, when a client is installed, logs in to
+type Database struct {
+	Engine   string // postgres or mysql, guessed from the port when empty
+	Host     string
+	Port     int
+	Name     string
+	Username string
+	Password string
+	SSLMode  string
+}
+
+// Prober runs probes with the timeout, retries and interval of a health check configuration
+type Prober struct {
+	timeout  time.Duration
+	interval time.Duration
+	retries  int
+}
+
+// NewProber creates a prober, defaulting to a 30s timeout and 3 retries 10s apart
+func NewProber(cfg config.HealthCheckConfig) (*Prober, error) {
+	prober := &Prober{timeout: 30 * time.Second, interval: 10 * time.Second, retries: 3}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health check timeout: %w", err)
+		}
+		prober.timeout = timeout
+	}
+	if cfg.Interval != "" {
+		interval, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health check interval: %w", err)
+		}
+		prober.interval = interval
+	}
+	if cfg.Retries > 0 {
+		prober.retries = cfg.Retries
+	}
+	return prober, nil
+}
+
+// run calls probe until it succeeds or the retries are used up, each attempt bounded by the timeout
+func (p *Prober) run(name, kind, target string, probe func(ctx context.Context) (string, error)) Result {
+	result := Result{Name: name, Kind: kind, Target: target}
+	start := time.Now()
+
+	var err error
+	for attempt := 1; attempt <= p.retries+1; attempt++ {
+		result.Attempts = attempt
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		result.Detail, err = probe(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		logger.Debug("Health check attempt failed").
+			Str("check", name).
+			Int("attempt", attempt).
+			Err(err).
+			Send()
+		if attempt <= p.retries {
+			time.Sleep(p.interval)
+		}
+	}
+
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	result.Passed = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// HTTP requests rawURL with the method, headers and expectations of cfg
+func (p *Prober) HTTP(name, rawURL string, cfg config.HealthCheckConfig) Result {
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	expected := cfg.ExpectedStatus
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+
+	return p.run(name, KindHTTP, rawURL, func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to build request: %w", err)
+		}
+		for key, value := range cfg.Headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != expected {
+			return "", fmt.Errorf("expected status %d, got %d", expected, resp.StatusCode)
+		}
+		if cfg.ExpectedContent != "" {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			if err != nil {
+				return "", fmt.Errorf("failed to read response: %w", err)
+			}
+			if !strings.Contains(string(body), cfg.ExpectedContent) {
+				return "", fmt.Errorf("response does not contain %q", cfg.ExpectedContent)
+			}
+		}
+		return fmt.Sprintf("status %d", resp.StatusCode), nil
+	})
+}
+
+// KubernetesAPI completes a TLS handshake with the API server and requests /healthz. The
+// certificate is verified against caData, PEM or base64-encoded PEM, when it is given. A 401
+// or 403 still passes: the server is up but serves no anonymous health endpoints.
+func (p *Prober) KubernetesAPI(endpoint, caData string) Result {
+	base := endpoint
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	parsed, err := url.Parse(base)
+	if err != nil {
+		return Result{Name: "kubernetes-api", Kind: KindKubernetes, Target: endpoint, Error: fmt.Sprintf("invalid endpoint: %v", err)}
+	}
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), "443")
+	}
+
+	tlsConfig := &tls.Config{ServerName: parsed.Hostname(), MinVersion: tls.VersionTLS12}
+	verified := false
+	if caData != "" {
+		pool, err := certPool(caData)
+		if err != nil {
+			return Result{Name: "kubernetes-api", Kind: KindKubernetes, Target: endpoint, Error: err.Error()}
+		}
+		tlsConfig.RootCAs = pool
+		verified = true
+	} else {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return p.run("kubernetes-api", KindKubernetes, endpoint, func(ctx context.Context) (string, error) {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return "", fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		conn.Close()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+address+"/healthz", nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("healthz request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+		detail := fmt.Sprintf("healthz %d", resp.StatusCode)
+		if !verified {
+			detail += ", certificate not verified"
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return detail, nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return detail + ", anonymous access denied", nil
+		default:
+			return "", fmt.Errorf("healthz returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+	})
+}
+
+// TCP opens a connection to address
+func (p *Prober) TCP(name, address string) Result {
+	return p.run(name, KindTCP, address, func(ctx context.Context) (string, error) {
+		return "", dialTCP(ctx, address)
+	})
+}
+
+// Database dials the database and, when credentials are known and psql or mysql is on the
+// PATH, logs in and runs SELECT 1
+func (p *Prober) Database(db Database) Result {
+	address := net.JoinHostPort(db.Host, strconv.Itoa(db.Port))
+	engine := db.Engine
+	if engine == "" {
+		engine = guessEngine(db.Port)
+	}
+
+	return p.run("database", KindDatabase, address, func(ctx context.Context) (string, error) {
+		if err := dialTCP(ctx, address); err != nil {
+			return "", err
+		}
+		if db.Username == "" {
+			return "reachable, no credentials to test a login", nil
+		}
+
+		var cmd *execx.Cmd
+		switch engine {
+		case "postgres":
+			cmd = execx.Command("psql", "-w", "-X", "-q", "-t", "-c", "SELECT 1")
+			cmd.Env = []string{
+				"PGHOST=" + db.Host,
+				"PGPORT=" + strconv.Itoa(db.Port),
+				"PGUSER=" + db.Username,
+				"PGPASSWORD=" + db.Password,
+				"PGDATABASE=" + db.Name,
+				"PGCONNECT_TIMEOUT=" + strconv.Itoa(int(p.timeout.Seconds())),
+			}
+			if db.SSLMode != "" {
+				cmd.Env = append(cmd.Env, "PGSSLMODE="+db.SSLMode)
+			}
+		case "mysql":
+			args := []string{"-h", db.Host, "-P", strconv.Itoa(db.Port), "-u", db.Username, "-e", "SELECT 1"}
+			if db.Name != "" {
+				args = append(args, db.Name)
+			}
+			cmd = execx.Command("mysql", args...)
+			cmd.Env = []string{"MYSQL_PWD=" + db.Password}
+		default:
+			return "reachable, unknown engine so the login was not tested", nil
+		}
+		cmd.Secrets = []string{db.Password}
+
+		if _, err := execx.LookPath(cmd.Name); err != nil {
+			return fmt.Sprintf("reachable, %s not found so the login was not tested", cmd.Name), nil
+		}
+		if output, err := cmd.Run(ctx); err != nil {
+			return "", fmt.Errorf("login as %s failed: %s", db.Username, execx.Mask(strings.TrimSpace(string(output)), cmd.Secrets))
+		}
+		return fmt.Sprintf("login as %s succeeded", db.Username), nil
+	})
+}
+
+// dialTCP opens and closes a TCP connection
+func dialTCP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// guessEngine maps well-known ports to a database engine
+func guessEngine(port int) string {
+	switch port {
+	case 5432:
+		return "postgres"
+	case 3306:
+		return "mysql"
+	default:
+		return ""
+	}
+}
+
+// certPool parses a PEM bundle, decoding it from base64 first as cloud providers output it
+func certPool(data string) (*x509.CertPool, error) {
+	pemData := []byte(data)
+	if !strings.Contains(data, "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode cluster CA certificate: %w", err)
+		}
+		pemData = decoded
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("cluster CA certificate contains no PEM certificates")
+	}
+	return pool, nil
+}
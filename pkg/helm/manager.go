@@ -0,0 +1,397 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"gopkg.in/yaml.v3"
+)
+
+// ManagedLabel is the release label marking releases installed by the installer,
+// set on every release the installer deploys and used to find releases to prune
+const ManagedLabel = "managed-by=e2e-k8s-installer"
+
+// Manager runs Helm release operations through the helm CLI; releases are installed and
+// upgraded with the Helm SDK
+type Manager struct {
+	helmPath    string
+	kubeconfig  string
+	kubeContext string
+}
+
+// Release is a Helm release as listed by helm list
+type Release struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Updated    string `json:"updated"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+}
+
+// Revision is one entry of a release's history as listed by helm history
+type Revision struct {
+	Revision    int    `json:"revision"`
+	Updated     string `json:"updated"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+}
+
+// ReleaseStatus is the current revision of an installed release, with the chart it was
+// installed from and the container images its manifest runs
+type ReleaseStatus struct {
+	Revision     int
+	Status       string
+	Chart        string
+	ChartVersion string
+	AppVersion   string
+	Images       []string
+}
+
+// ChartInfo is the name and versions of a chart as declared in its Chart.yaml
+type ChartInfo struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	AppVersion string `yaml:"appVersion"`
+}
+
+// TestSuite is the result of one test hook of a release
+type TestSuite struct {
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+}
+
+// TestResult is the outcome of helm test for a release
+type TestResult struct {
+	Release   string      `json:"release"`
+	Namespace string      `json:"namespace"`
+	Passed    bool        `json:"passed"`
+	Suites    []TestSuite `json:"suites,omitempty"`
+	Duration  string      `json:"duration"`
+	Error     string      `json:"error,omitempty"`
+	LogTail   []string    `json:"logTail,omitempty"`
+}
+
+// NewManager creates a Helm manager targeting the configured cluster
+func NewManager(k8sConfig *config.K8sConfig) (*Manager, error) {
+	helmPath, err := execx.LookPath("helm")
+	if err != nil {
+		return nil, fmt.Errorf("helm not found in PATH: %w", err)
+	}
+
+	return &Manager{
+		helmPath:    helmPath,
+		kubeconfig:  k8s.ResolveKubeconfig(k8sConfig),
+		kubeContext: k8sConfig.Context,
+	}, nil
+}
+
+// Run executes a helm command, returning its combined output
+func (m *Manager) Run(ctx context.Context, args ...string) ([]byte, error) {
+	return m.RunWithInput(ctx, nil, args...)
+}
+
+// RunWithInput executes a helm command with input on stdin, such as values passed with --values -
+func (m *Manager) RunWithInput(ctx context.Context, input []byte, args ...string) ([]byte, error) {
+	cmd := m.command(args)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	output, err := cmd.Run(ctx)
+	if err != nil {
+		return output, fmt.Errorf("helm %s failed: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// command prepares a helm command against the configured cluster, named in the step logs by
+// its verb and release, e.g. helm upgrade api
+func (m *Manager) command(args []string) *execx.Cmd {
+	cmd := execx.Command(m.helmPath, m.clusterArgs(args)...)
+	cmd.Tag = "helm"
+	for i, arg := range args {
+		if i > 1 || strings.HasPrefix(arg, "-") {
+			break
+		}
+		cmd.Tag += " " + arg
+	}
+	cmd.Console = true
+	return cmd
+}
+
+// Template renders a chart locally the way an install would, including any post-renderer
+// flags in extraArgs. Values are read from stdin and the cluster is queried for lookups and
+// capabilities, so the output matches what the release would apply.
+func (m *Manager) Template(release, chartPath, namespace string, values []byte, extraArgs ...string) ([]byte, error) {
+	args := append([]string{"template", release, chartPath, "-n", namespace, "--values", "-", "--validate"}, extraArgs...)
+	cmd := m.command(args)
+	cmd.Stdin = bytes.NewReader(values)
+
+	// Keep stdout alone so warnings on stderr do not end up in the manifest
+	output, err := cmd.Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s failed: %w", release, err)
+	}
+	return output, nil
+}
+
+// GetManifest returns the manifest of a release's current revision, or nil if it is not installed
+func (m *Manager) GetManifest(release, namespace string) ([]byte, error) {
+	args := []string{"list", "-n", namespace, "--all", "-q", "--filter", "^" + regexp.QuoteMeta(release) + "$"}
+	releases, err := m.command(args).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("helm %s failed: %w", strings.Join(args, " "), err)
+	}
+	if strings.TrimSpace(string(releases)) == "" {
+		return nil, nil
+	}
+
+	output, err := m.command([]string{"get", "manifest", release, "-n", namespace}).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest of release %s/%s: %w", namespace, release, err)
+	}
+	return output, nil
+}
+
+// Status returns the current revision of a release as reported by helm status
+func (m *Manager) Status(release, namespace string) (*ReleaseStatus, error) {
+	output, err := m.command([]string{"status", release, "-n", namespace, "-o", "json"}).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of release %s/%s: %w", namespace, release, err)
+	}
+
+	var status struct {
+		Version int `json:"version"`
+		Info    struct {
+			Status string `json:"status"`
+		} `json:"info"`
+		Chart struct {
+			Metadata struct {
+				Name       string `json:"name"`
+				Version    string `json:"version"`
+				AppVersion string `json:"appVersion"`
+			} `json:"metadata"`
+		} `json:"chart"`
+		Manifest string `json:"manifest"`
+	}
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status of release %s/%s: %w", namespace, release, err)
+	}
+
+	images, err := k8s.ManifestImages([]byte(status.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("release %s/%s: %w", namespace, release, err)
+	}
+	return &ReleaseStatus{
+		Revision:     status.Version,
+		Status:       status.Info.Status,
+		Chart:        status.Chart.Metadata.Name,
+		ChartVersion: status.Chart.Metadata.Version,
+		AppVersion:   status.Chart.Metadata.AppVersion,
+		Images:       images,
+	}, nil
+}
+
+// Revision returns the current revision of a release, in any state, or 0 if it is not installed
+func (m *Manager) Revision(release, namespace string) (int, error) {
+	args := []string{"list", "-n", namespace, "--all", "-o", "json", "--filter", "^" + regexp.QuoteMeta(release) + "$"}
+	output, err := m.command(args).Output(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("helm %s failed: %w", strings.Join(args, " "), err)
+	}
+	var releases []Release
+	if err := json.Unmarshal(output, &releases); err != nil {
+		return 0, fmt.Errorf("failed to parse releases in %s: %w", namespace, err)
+	}
+	if len(releases) == 0 {
+		return 0, nil
+	}
+	revision, err := strconv.Atoi(releases[0].Revision)
+	if err != nil {
+		return 0, fmt.Errorf("invalid revision %q of release %s/%s", releases[0].Revision, namespace, release)
+	}
+	return revision, nil
+}
+
+// History returns the revisions of a release, oldest first
+func (m *Manager) History(release, namespace string) ([]Revision, error) {
+	output, err := m.command([]string{"history", release, "-n", namespace, "-o", "json"}).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of release %s/%s: %w", namespace, release, err)
+	}
+	var revisions []Revision
+	if err := json.Unmarshal(output, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to parse history of release %s/%s: %w", namespace, release, err)
+	}
+	return revisions, nil
+}
+
+// Values returns the user-supplied values of a release revision
+func (m *Manager) Values(release, namespace string, revision int) (map[string]interface{}, error) {
+	output, err := m.command([]string{"get", "values", release, "-n", namespace,
+		"--revision", strconv.Itoa(revision), "-o", "yaml"}).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values of release %s/%s revision %d: %w", namespace, release, revision, err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(output, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values of release %s/%s revision %d: %w", namespace, release, revision, err)
+	}
+	return values, nil
+}
+
+// Rollback rolls a release back to a revision, waiting for its resources when wait is set
+func (m *Manager) Rollback(release, namespace string, revision int, timeout time.Duration, wait bool) error {
+	args := []string{"rollback", release, strconv.Itoa(revision), "-n", namespace, "--timeout", timeout.String()}
+	if wait {
+		args = append(args, "--wait")
+	}
+	_, err := m.Run(context.Background(), args...)
+	audit.Record("helm.rollback", namespace+"/"+release, map[string]interface{}{"revision": revision}, err)
+	return err
+}
+
+// LocalChart reads the Chart.yaml of a chart directory
+func LocalChart(chartPath string) (*ChartInfo, error) {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+	var info ChartInfo
+	if err := yaml.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(chartPath, "Chart.yaml"), err)
+	}
+	return &info, nil
+}
+
+// clusterArgs appends the kubeconfig and context flags to a helm command
+func (m *Manager) clusterArgs(args []string) []string {
+	fullArgs := append([]string{}, args...)
+	if m.kubeconfig != "" {
+		fullArgs = append(fullArgs, "--kubeconfig", m.kubeconfig)
+	}
+	if m.kubeContext != "" {
+		fullArgs = append(fullArgs, "--kube-context", m.kubeContext)
+	}
+	return fullArgs
+}
+
+// Uninstall removes a release and waits for its resources to be deleted
+func (m *Manager) Uninstall(release, namespace string, timeout time.Duration) error {
+	_, err := m.Run(context.Background(), "uninstall", release, "-n", namespace,
+		"--wait", "--timeout", timeout.String(), "--ignore-not-found")
+	audit.Record("helm.uninstall", namespace+"/"+release, nil, err)
+	return err
+}
+
+// ListManaged returns the installer-managed releases in a namespace, in any state
+func (m *Manager) ListManaged(namespace string) ([]Release, error) {
+	// Keep stdout alone so warnings on stderr, e.g. about kubeconfig permissions, do not break the JSON
+	args := []string{"list", "-n", namespace, "--all", "-o", "json", "--selector", ManagedLabel}
+	output, err := m.command(args).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("helm %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(output, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases in %s: %w", namespace, err)
+	}
+	return releases, nil
+}
+
+// Test runs the release's test hooks and captures their pod logs
+func (m *Manager) Test(release, namespace string, timeout time.Duration, tail int) (*TestResult, error) {
+	start := time.Now()
+
+	// Give the helm process slightly longer than its own timeout so it can report which suite timed out
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+30*time.Second)
+	defer cancel()
+
+	output, err := m.Run(ctx, "test", release, "-n", namespace, "--timeout", timeout.String(), "--logs")
+	suites, logs := parseTestOutput(string(output))
+
+	result := &TestResult{
+		Release:   release,
+		Namespace: namespace,
+		Passed:    err == nil,
+		Suites:    suites,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+		LogTail:   tailLines(logs, tail),
+	}
+	if err != nil {
+		// The full helm output is already captured in the suites and log tail
+		var failedSuites []string
+		for _, suite := range suites {
+			if suite.Phase != "Succeeded" {
+				failedSuites = append(failedSuites, suite.Name)
+			}
+		}
+		result.Error = strings.SplitN(err.Error(), "\n", 2)[0]
+		if len(failedSuites) > 0 {
+			result.Error = "failed test suites: " + strings.Join(failedSuites, ", ")
+		}
+	}
+
+	audit.Record("helm.test", namespace+"/"+release, map[string]interface{}{
+		"suites": len(suites),
+	}, err)
+	return result, err
+}
+
+// parseTestOutput extracts test suite phases and pod logs from helm test output
+func parseTestOutput(output string) ([]TestSuite, []string) {
+	var suites []TestSuite
+	var logs []string
+	inLogs := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "TEST SUITE:"):
+			inLogs = false
+			name := strings.TrimSpace(strings.TrimPrefix(trimmed, "TEST SUITE:"))
+			if name != "None" {
+				suites = append(suites, TestSuite{Name: name})
+			}
+		case strings.HasPrefix(trimmed, "Phase:") && !inLogs && len(suites) > 0:
+			suites[len(suites)-1].Phase = strings.TrimSpace(strings.TrimPrefix(trimmed, "Phase:"))
+		case strings.HasPrefix(trimmed, "POD LOGS:"):
+			inLogs = true
+			logs = append(logs, line)
+		case inLogs:
+			logs = append(logs, line)
+		}
+	}
+	return suites, logs
+}
+
+func tailLines(lines []string, n int) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
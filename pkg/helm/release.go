@@ -0,0 +1,196 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logtail"
+)
+
+// maxHistory is the number of revisions an upgrade keeps of a release, helm's default
+const maxHistory = 10
+
+// DeployOptions are the settings of a release install or upgrade
+type DeployOptions struct {
+	Timeout       time.Duration
+	Wait          bool
+	Atomic        bool // roll back, or uninstall a new release, when the release fails
+	CleanupOnFail bool
+	Labels        map[string]string // release labels, e.g. ManagedLabels
+
+	// PostRender rewrites the rendered manifests before they are applied; nil applies them as rendered
+	PostRender func(manifest []byte) ([]byte, error)
+}
+
+// ManagedLabels returns ManagedLabel as release labels
+func ManagedLabels() map[string]string {
+	key, value, _ := strings.Cut(ManagedLabel, "=")
+	return map[string]string{key: value}
+}
+
+// Deploy installs a chart as a release, or upgrades the release when it exists, the way
+// helm upgrade --install does but with the Helm SDK actions. Local charts are loaded as
+// they are; charts of repositories and OCI registries are fetched with helm pull, using
+// the CLI's repositories and registry logins. The SDK's log goes to the tool logs and,
+// when the release fails, with the error.
+func (m *Manager) Deploy(ctx context.Context, name, chartRef, namespace string, values map[string]interface{}, options DeployOptions) (*ReleaseStatus, error) {
+	tag := "helm upgrade " + name
+	output := logger.ToolOutput(tag, true)
+	defer output.Close()
+	var buffer logtail.Buffer
+	log := io.MultiWriter(output, &buffer)
+
+	rel, err := m.deploy(ctx, name, chartRef, namespace, values, options, func(format string, v ...interface{}) {
+		fmt.Fprintf(log, format+"\n", v...)
+	})
+	if err != nil {
+		return nil, logtail.Attach("helm", buffer.Bytes(), fmt.Errorf("%s failed: %w", tag, err))
+	}
+	return releaseStatus(rel)
+}
+
+func (m *Manager) deploy(ctx context.Context, name, chartRef, namespace string, values map[string]interface{}, options DeployOptions, log action.DebugLog) (*release.Release, error) {
+	chrt, err := m.loadChart(ctx, chartRef)
+	if err != nil {
+		return nil, err
+	}
+	if dependencies := chrt.Metadata.Dependencies; dependencies != nil {
+		if err := action.CheckDependencies(chrt, dependencies); err != nil {
+			return nil, fmt.Errorf("chart %s: %w", chartRef, err)
+		}
+	}
+
+	cfg := new(action.Configuration)
+	if err := cfg.Init(k8s.NewRESTClientGetter(m.kubeconfig, m.kubeContext, namespace), namespace, os.Getenv("HELM_DRIVER"), log); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm: %w", err)
+	}
+
+	// Simulated and replayed clusters serve no OpenAPI schema to validate the manifests with
+	validate := !k8s.Simulated()
+
+	history := action.NewHistory(cfg)
+	history.Max = 1
+	if _, err := history.Run(name); errors.Is(err, driver.ErrReleaseNotFound) {
+		log("Release %q does not exist. Installing it now.", name)
+		install := action.NewInstall(cfg)
+		install.ReleaseName = name
+		install.Namespace = namespace
+		install.Timeout = options.Timeout
+		install.Wait = options.Wait
+		install.Atomic = options.Atomic
+		install.Labels = options.Labels
+		install.DisableOpenAPIValidation = !validate
+		if options.PostRender != nil {
+			install.PostRenderer = postRenderFunc(options.PostRender)
+		}
+		return install.RunWithContext(ctx, chrt, values)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the history of release %s/%s: %w", namespace, name, err)
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = namespace
+	upgrade.Timeout = options.Timeout
+	upgrade.Wait = options.Wait
+	upgrade.Atomic = options.Atomic
+	upgrade.CleanupOnFail = options.CleanupOnFail
+	upgrade.Labels = options.Labels
+	upgrade.MaxHistory = maxHistory
+	upgrade.DisableOpenAPIValidation = !validate
+	if options.PostRender != nil {
+		upgrade.PostRenderer = postRenderFunc(options.PostRender)
+	}
+	return upgrade.RunWithContext(ctx, name, chrt, values)
+}
+
+// loadChart loads a chart directory or archive, pulling a chart that is not on disk into a
+// temporary directory first
+func (m *Manager) loadChart(ctx context.Context, chartRef string) (*chart.Chart, error) {
+	if _, err := os.Stat(chartRef); err == nil {
+		chrt, err := loader.Load(chartRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chart %s: %w", chartRef, err)
+		}
+		return chrt, nil
+	}
+
+	dir, err := os.MkdirTemp("", "helm-chart-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chart directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := m.Run(ctx, "pull", chartRef, "--destination", dir); err != nil {
+		return nil, err
+	}
+	archives, _ := filepath.Glob(filepath.Join(dir, "*.tgz"))
+	if len(archives) != 1 {
+		return nil, fmt.Errorf("helm pull %s saved no chart archive", chartRef)
+	}
+	chrt, err := loader.Load(archives[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", chartRef, err)
+	}
+	return chrt, nil
+}
+
+// MissingDependencies reports whether a chart directory declares dependencies that are not
+// in its charts/ directory
+func MissingDependencies(chartDir string) (bool, error) {
+	chrt, err := loader.LoadDir(chartDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to load chart %s: %w", chartDir, err)
+	}
+	dependencies := chrt.Metadata.Dependencies
+	return len(dependencies) > 0 && action.CheckDependencies(chrt, dependencies) != nil, nil
+}
+
+// BuildDependencies downloads the dependencies of a chart directory into its charts/
+// directory with helm dependency build, at the versions of its Chart.lock when it has one
+func (m *Manager) BuildDependencies(ctx context.Context, chartDir string) error {
+	_, err := m.Run(ctx, "dependency", "build", chartDir)
+	return err
+}
+
+// releaseStatus describes a release the SDK returned like Status does
+func releaseStatus(rel *release.Release) (*ReleaseStatus, error) {
+	images, err := k8s.ManifestImages([]byte(rel.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("release %s/%s: %w", rel.Namespace, rel.Name, err)
+	}
+	status := &ReleaseStatus{Revision: rel.Version, Images: images}
+	if rel.Info != nil {
+		status.Status = rel.Info.Status.String()
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		status.Chart = rel.Chart.Metadata.Name
+		status.ChartVersion = rel.Chart.Metadata.Version
+		status.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	return status, nil
+}
+
+// postRenderFunc is the Helm SDK post-renderer of a DeployOptions.PostRender
+type postRenderFunc func(manifest []byte) ([]byte, error)
+
+func (f postRenderFunc) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	manifest, err := f(rendered.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(manifest), nil
+}
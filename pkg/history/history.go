@@ -0,0 +1,209 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/exitcode"
+)
+
+// FileName is the run registry kept in the reports directory of a workspace
+const FileName = "runs.jsonl"
+
+// Run statuses
+const (
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusPaused    = "paused"
+	StatusScheduled = "scheduled"
+	StatusFrozen    = "frozen"
+)
+
+// Run is one recorded invocation of an installer command
+type Run struct {
+	ID       string        `json:"id"`
+	Command  string        `json:"command"`
+	Status   string        `json:"status"`
+	DryRun   bool          `json:"dryRun,omitempty"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"` // nanoseconds
+	Error    string        `json:"error,omitempty"`
+	Reports  []string      `json:"reports,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	started = time.Now()
+	runID   string
+	reports []string
+)
+
+// Start begins a new run named after the command about to execute
+func Start(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	started, reports = time.Now(), nil
+	runID = newID(name)
+}
+
+// RunID returns the ID of the current run, so snapshots, labels and the registry all refer
+// to the run by the same ID
+func RunID() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if runID == "" {
+		runID = newID("run")
+	}
+	return runID
+}
+
+// newID names a run after its command and start time, with a random suffix telling apart
+// runs started within the same second, e.g. install-20250301-101500-ab12
+func newID(name string) string {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		// Without randomness the nanoseconds still tell the runs apart
+		return fmt.Sprintf("%s-%s-%09d", name, started.Format("20060102-150405"), started.Nanosecond())
+	}
+	return fmt.Sprintf("%s-%s-%x", name, started.Format("20060102-150405"), suffix)
+}
+
+// AddReport attaches a report written by the current run
+func AddReport(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range reports {
+		if existing == path {
+			return
+		}
+	}
+	reports = append(reports, path)
+}
+
+// Finish appends the outcome of the current run to the registry in dir. Reports that were
+// attached but never written are left out.
+func Finish(dir, command string, dryRun bool, runErr error) (*Run, error) {
+	id := RunID()
+
+	mu.Lock()
+	run := &Run{
+		ID:       id,
+		Command:  command,
+		Status:   StatusSuccess,
+		DryRun:   dryRun,
+		Started:  started.UTC(),
+		Duration: time.Since(started),
+	}
+	for _, path := range reports {
+		if _, err := os.Stat(path); err == nil {
+			run.Reports = append(run.Reports, path)
+		}
+	}
+	mu.Unlock()
+	if runErr != nil {
+		run.Status, run.Error = StatusFailed, runErr.Error()
+		switch exitcode.Code(runErr) {
+		case exitcode.Paused:
+			run.Status = StatusPaused
+		case exitcode.Scheduled:
+			run.Status = StatusScheduled
+		case exitcode.Frozen:
+			run.Status = StatusFrozen
+		}
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal run: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, FileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run registry: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to record run: %w", err)
+	}
+	return run, nil
+}
+
+// List returns the runs recorded in dir, newest first
+func List(dir string) ([]Run, error) {
+	file, err := os.Open(filepath.Join(dir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run registry: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("run registry %s is corrupt at line %d: %w", file.Name(), line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run registry: %w", err)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Started.After(runs[j].Started)
+	})
+	return runs, nil
+}
+
+// Find returns the run with the given ID, a unique prefix of one, or the most recent run for
+// "latest". When an ID was recorded more than once the most recent run wins.
+func Find(dir, id string) (*Run, error) {
+	runs, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no runs recorded in %s", dir)
+	}
+	if id == "latest" {
+		return &runs[0], nil
+	}
+
+	var matches []Run
+	for _, run := range runs {
+		if run.ID == id {
+			return &run, nil
+		}
+		if strings.HasPrefix(run.ID, id) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %s not found in %s", id, dir)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("run %s is ambiguous, it matches %d runs", id, len(matches))
+}
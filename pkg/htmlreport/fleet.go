@@ -0,0 +1,147 @@
+package htmlreport
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StatusDrifted is a site whose releases differ from its configuration but are healthy
+const StatusDrifted = "drifted"
+
+// Fleet is everything a fleet report shows: the environments of many workspaces side by side
+type Fleet struct {
+	Title     string          `json:"title"`
+	Generated time.Time       `json:"generated"`
+	Sources   []string        `json:"sources"`
+	Sites     []Site          `json:"sites"`
+	Versions  []ChartVersions `json:"versions"` // installed chart versions across the sites
+}
+
+// Site is one workspace of the fleet, summed up from the reports of its last runs
+type Site struct {
+	Name        string      `json:"name"`
+	Environment string      `json:"environment,omitempty"`
+	Status      string      `json:"status"` // success, drifted, failed, or skipped when nothing is known
+	LastRun     string      `json:"lastRun,omitempty"`
+	LastStatus  string      `json:"lastStatus,omitempty"`
+	LastRunAt   *time.Time  `json:"lastRunAt,omitempty"`
+	Charts      []SiteChart `json:"charts,omitempty"`
+	Audited     *time.Time  `json:"audited,omitempty"`
+	Score       *float64    `json:"score,omitempty"` // compliance score of the last audit, nil without one
+	Drift       []Failure   `json:"drift,omitempty"`
+	Health      []Failure   `json:"health,omitempty"` // failing health checks
+}
+
+// SiteChart is a release of a site as its last deployment report lists it
+type SiteChart struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+	Status     string `json:"status"`
+}
+
+// ChartVersions lists the sites running each version of a chart
+type ChartVersions struct {
+	Chart    string         `json:"chart"`
+	Versions []VersionSites `json:"versions"`
+}
+
+// VersionSites are the sites running a version of a chart
+type VersionSites struct {
+	Version string   `json:"version"`
+	Sites   []string `json:"sites"`
+}
+
+// Count returns how many sites have status
+func (f *Fleet) Count(status string) int {
+	count := 0
+	for _, site := range f.Sites {
+		if site.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteFleet renders the fleet report as a single self-contained HTML file, styled like the
+// installation report
+func WriteFleet(path string, fleet *Fleet) error {
+	var buf bytes.Buffer
+	if err := fleetPage.Execute(&buf, fleet); err != nil {
+		return fmt.Errorf("failed to render HTML fleet report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML fleet report: %w", err)
+	}
+	return nil
+}
+
+var fleetPage = template.Must(template.Must(page.Clone()).New("fleet").Funcs(template.FuncMap{
+	"score": func(score *float64) string {
+		if score == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f", *score)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "styles"}}
+</head>
+<body>
+<header>
+  <h1>{{.Title}}</h1>
+  <div class="meta">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}} from {{range $i, $source := .Sources}}{{if $i}}, {{end}}{{$source}}{{end}}</div>
+</header>
+<main>
+<section>
+  <h2>Summary</h2>
+  <div class="cards">
+    <div class="card"><div class="label">Sites</div><div class="value">{{len .Sites}}</div></div>
+    <div class="card"><div class="label">Healthy</div><div class="value">{{.Count "success"}}</div></div>
+    <div class="card"><div class="label">Drifted</div><div class="value">{{.Count "drifted"}}</div></div>
+    <div class="card"><div class="label">Failing</div><div class="value">{{.Count "failed"}}</div></div>
+    <div class="card"><div class="label">Charts</div><div class="value">{{len .Versions}}</div></div>
+  </div>
+</section>
+
+<section>
+  <h2>Sites</h2>
+  <table>
+    <tr><th>Site</th><th>Environment</th><th>Status</th><th>Last run</th><th>Charts</th><th>Audit score</th><th>Drift</th><th>Failing health checks</th></tr>
+    {{range .Sites}}
+    <tr><td>{{.Name}}</td><td>{{.Environment}}</td><td><span class="badge {{.Status}}">{{.Status}}</span></td>
+    <td class="mono">{{.LastRun}}{{if .LastStatus}}<div>{{.LastStatus}}{{with .LastRunAt}}, {{.Format "2006-01-02 15:04"}}{{end}}</div>{{end}}</td>
+    <td class="mono">{{range .Charts}}<div>{{.Name}} {{.Version}}</div>{{end}}</td>
+    <td>{{score .Score}}</td>
+    <td>{{range .Drift}}<div class="error">{{.Name}}: {{.Error}}</div>{{end}}</td>
+    <td>{{range .Health}}<div class="error">{{.Name}}: {{.Error}}</div>{{end}}</td></tr>
+    {{end}}
+  </table>
+</section>
+
+{{if .Versions}}
+<section>
+  <h2>Installed Versions</h2>
+  <table>
+    <tr><th>Chart</th><th>Version</th><th>Sites</th></tr>
+    {{range .Versions}}{{$chart := .Chart}}{{range $i, $version := .Versions}}
+    <tr><td>{{if not $i}}{{$chart}}{{end}}</td><td>{{$version.Version}}</td><td>{{range $j, $site := $version.Sites}}{{if $j}}, {{end}}{{$site}}{{end}}</td></tr>
+    {{end}}{{end}}
+  </table>
+</section>
+{{end}}
+</main>
+</body>
+</html>`))
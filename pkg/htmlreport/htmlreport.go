@@ -0,0 +1,407 @@
+package htmlreport
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Step, check and finding statuses as shown in the report
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Finding severities, highest first
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Summary is everything an executive summary report shows. Sections that are empty are left
+// out of the report.
+type Summary struct {
+	Title     string
+	Generated time.Time
+	Status    string
+	DryRun    bool
+	Duration  time.Duration
+	Details   [][2]string // property, value pairs shown under the title
+
+	Steps      []Step
+	Charts     []Chart
+	Checks     Counts
+	Validation []Failure
+	Tests      Counts
+	TestFails  []Failure
+	Findings   []Finding
+	Artifacts  []Artifact
+	Notes      []ReleaseNotes
+}
+
+// Step is one step of the installation timeline
+type Step struct {
+	Name        string
+	Description string
+	Status      string
+	Duration    time.Duration
+	Error       string
+	LogTail     []string // last output lines of the tool that failed, shown collapsed
+}
+
+// Chart is one deployed Helm release
+type Chart struct {
+	Name       string
+	Namespace  string
+	Version    string
+	AppVersion string
+	Images     []string
+	Status     string
+	Error      string
+	LogTail    []string
+}
+
+// Counts summarizes passed, failed and skipped checks or tests
+type Counts struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Total is the number of checks or tests counted
+func (c Counts) Total() int {
+	return c.Passed + c.Failed + c.Skipped
+}
+
+// Failure is a failed health check or test
+type Failure struct {
+	Name     string
+	Category string
+	Error    string
+}
+
+// Finding is a security or policy finding
+type Finding struct {
+	Severity string
+	Source   string
+	Object   string
+	Detail   string
+}
+
+// ReleaseNotes are the changelog entries of a chart between the installed and the new version
+type ReleaseNotes struct {
+	Chart   string
+	From    string // empty for a new install
+	To      string
+	Entries []ReleaseNote
+}
+
+// ReleaseNote is the changelog entry of one version
+type ReleaseNote struct {
+	Version  string
+	Body     string
+	Breaking []string
+}
+
+// Artifact is one entry of the artifact bill of materials
+type Artifact struct {
+	Kind      string // image, chart, repository
+	Name      string
+	Version   string
+	Reference string
+	Digest    string
+}
+
+// Write renders the summary as a single self-contained HTML file: styles and charts are
+// inlined so the file can be attached to a change ticket and opened anywhere
+func Write(path string, summary *Summary) error {
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, newView(summary)); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+// timelineWidth is the width in pixels of the bar area of the step timeline
+const timelineWidth = 560
+
+type view struct {
+	*Summary
+	Timeline       []timelineBar
+	TimelineWidth  int
+	TimelineHeight int
+	CheckDonut     []donutSlice
+	TestDonut      []donutSlice
+}
+
+type timelineBar struct {
+	Step
+	Y, X, Width int
+	Label       string
+}
+
+type donutSlice struct {
+	Status string
+	Label  string
+	Count  int
+	Dash   string
+	Offset string
+}
+
+func newView(s *Summary) *view {
+	// Bars start after a 140px step name column and leave room for the duration label
+	v := &view{Summary: s, TimelineWidth: 140 + timelineWidth + 90, TimelineHeight: len(s.Steps)*28 + 4}
+
+	var total time.Duration
+	for _, step := range s.Steps {
+		total += step.Duration
+	}
+	var offset time.Duration
+	for i, step := range s.Steps {
+		bar := timelineBar{Step: step, Y: i * 28, Label: step.Duration.Round(time.Millisecond).String()}
+		if total > 0 {
+			bar.X = int(float64(offset) / float64(total) * timelineWidth)
+			bar.Width = int(math.Max(2, float64(step.Duration)/float64(total)*timelineWidth))
+		} else {
+			bar.Width = 2
+		}
+		offset += step.Duration
+		v.Timeline = append(v.Timeline, bar)
+	}
+
+	v.CheckDonut = donut(s.Checks)
+	v.TestDonut = donut(s.Tests)
+	return v
+}
+
+// donutCircumference is that of the r=15.915 circle the donuts are drawn on, so dash lengths
+// read as percentages
+const donutCircumference = 100.0
+
+func donut(c Counts) []donutSlice {
+	total := c.Total()
+	if total == 0 {
+		return nil
+	}
+	var slices []donutSlice
+	start := 0.0
+	for _, part := range []struct {
+		status, label string
+		count         int
+	}{
+		{StatusSuccess, "Passed", c.Passed},
+		{StatusFailed, "Failed", c.Failed},
+		{StatusSkipped, "Skipped", c.Skipped},
+	} {
+		share := float64(part.count) / float64(total) * donutCircumference
+		slices = append(slices, donutSlice{
+			Status: part.status,
+			Label:  part.label,
+			Count:  part.count,
+			Dash:   fmt.Sprintf("%.2f %.2f", share, donutCircumference-share),
+			Offset: fmt.Sprintf("%.2f", 25-start), // start at 12 o'clock
+		})
+		start += share
+	}
+	return slices
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(c Counts) string {
+		if c.Total() == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", float64(c.Passed)/float64(c.Total())*100)
+	},
+	"round":  func(d time.Duration) string { return d.Round(time.Second).String() },
+	"millis": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"add":    func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "styles"}}
+</head>
+<body>
+<header>
+  <h1>{{.Title}}</h1>
+  <div class="meta">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}{{if .DryRun}} &middot; DRY RUN, no changes were applied{{end}}</div>
+</header>
+<main>
+<section>
+  <h2>Summary</h2>
+  <div class="cards">
+    <div class="card"><div class="label">Status</div><div class="value"><span class="badge {{.Status}}">{{.Status}}</span></div></div>
+    <div class="card"><div class="label">Duration</div><div class="value">{{round .Duration}}</div></div>
+    {{if .Steps}}<div class="card"><div class="label">Steps</div><div class="value">{{len .Steps}}</div></div>{{end}}
+    {{if .Charts}}<div class="card"><div class="label">Charts</div><div class="value">{{len .Charts}}</div></div>{{end}}
+    {{if .Checks.Total}}<div class="card"><div class="label">Health checks passed</div><div class="value">{{percent .Checks}}</div></div>{{end}}
+    {{if .Tests.Total}}<div class="card"><div class="label">Tests passed</div><div class="value">{{percent .Tests}}</div></div>{{end}}
+    {{if .Findings}}<div class="card"><div class="label">Findings</div><div class="value">{{len .Findings}}</div></div>{{end}}
+  </div>
+  {{if .Details}}
+  <table style="margin-top:16px">
+    {{range .Details}}<tr><th style="width:200px">{{index . 0}}</th><td>{{index . 1}}</td></tr>{{end}}
+  </table>
+  {{end}}
+</section>
+
+{{if .Timeline}}
+<section>
+  <h2>Step Timeline</h2>
+  <svg width="{{.TimelineWidth}}" height="{{.TimelineHeight}}" role="img" aria-label="Step timeline">
+    {{range .Timeline}}
+    <g transform="translate(0,{{.Y}})">
+      <text x="0" y="16">{{.Name}}</text>
+      <rect class="{{.Status}}" x="{{add .X 140}}" y="4" width="{{.Width}}" height="16" rx="3"><title>{{.Name}}: {{.Label}}</title></rect>
+      <text x="{{add (add .X .Width) 146}}" y="16">{{.Label}}</text>
+    </g>
+    {{end}}
+  </svg>
+  <table style="margin-top:12px">
+    <tr><th>Step</th><th>Status</th><th>Duration</th><th>Description</th></tr>
+    {{range .Steps}}
+    <tr><td>{{.Name}}</td><td><span class="badge {{.Status}}">{{.Status}}</span></td><td>{{millis .Duration}}</td>
+    <td>{{.Description}}{{if .Error}}<div class="error">{{.Error}}</div>{{end}}{{template "logtail" .LogTail}}</td></tr>
+    {{end}}
+  </table>
+</section>
+{{end}}
+
+{{if or .Charts .CheckDonut}}
+<section>
+  <h2>Deployment and Health</h2>
+  {{if .CheckDonut}}{{template "donut" .CheckDonut}}{{end}}
+  {{if .Charts}}
+  <table style="margin-top:12px">
+    <tr><th>Chart</th><th>Namespace</th><th>Version</th><th>App version</th><th>Images</th><th>Status</th></tr>
+    {{range .Charts}}
+    <tr><td>{{.Name}}</td><td>{{.Namespace}}</td><td>{{.Version}}</td><td>{{.AppVersion}}</td>
+    <td class="mono">{{range .Images}}<div>{{.}}</div>{{end}}</td>
+    <td><span class="badge {{.Status}}">{{.Status}}</span>{{if .Error}}<div class="error">{{.Error}}</div>{{end}}{{template "logtail" .LogTail}}</td></tr>
+    {{end}}
+  </table>
+  {{end}}
+  {{if .Validation}}
+  <table style="margin-top:12px">
+    <tr><th>Failed check</th><th>Category</th><th>Error</th></tr>
+    {{range .Validation}}<tr><td>{{.Name}}</td><td>{{.Category}}</td><td class="error">{{.Error}}</td></tr>{{end}}
+  </table>
+  {{end}}
+</section>
+{{end}}
+
+{{if .TestDonut}}
+<section>
+  <h2>Test Results</h2>
+  {{template "donut" .TestDonut}}
+  {{if .TestFails}}
+  <table style="margin-top:12px">
+    <tr><th>Failed test</th><th>Error</th></tr>
+    {{range .TestFails}}<tr><td>{{.Name}}</td><td class="error">{{.Error}}</td></tr>{{end}}
+  </table>
+  {{end}}
+</section>
+{{end}}
+
+{{if .Findings}}
+<section>
+  <h2>Security and Policy Findings</h2>
+  <table>
+    <tr><th>Severity</th><th>Source</th><th>Object</th><th>Finding</th></tr>
+    {{range .Findings}}
+    <tr><td><span class="badge {{.Severity}}">{{.Severity}}</span></td><td>{{.Source}}</td><td class="mono">{{.Object}}</td><td>{{.Detail}}</td></tr>
+    {{end}}
+  </table>
+</section>
+{{end}}
+
+{{if .Notes}}
+<section>
+  <h2>Release Notes</h2>
+  {{range .Notes}}
+  <h3 style="font-size:14px;margin:12px 0 6px">{{.Chart}} {{if .From}}{{.From}} &rarr; {{end}}{{.To}}</h3>
+  {{range .Entries}}
+  <details class="logs"{{if .Breaking}} open{{end}}><summary>{{.Version}}{{if .Breaking}} <span class="badge failed">breaking</span>{{end}}</summary>
+  {{if .Breaking}}<ul class="error">{{range .Breaking}}<li>{{.}}</li>{{end}}</ul>{{end}}
+  <pre>{{.Body}}</pre></details>
+  {{end}}
+  {{end}}
+</section>
+{{end}}
+
+{{if .Artifacts}}
+<section>
+  <h2>Artifact Bill of Materials</h2>
+  <table>
+    <tr><th>Kind</th><th>Name</th><th>Version</th><th>Reference</th><th>Digest / Commit</th></tr>
+    {{range .Artifacts}}
+    <tr><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Version}}</td><td class="mono">{{.Reference}}</td><td class="mono">{{.Digest}}</td></tr>
+    {{end}}
+  </table>
+</section>
+{{end}}
+</main>
+</body>
+</html>
+{{define "styles"}}
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; margin: 0; color: #1f2933; background: #f5f7fa; }
+  header { background: #1f2933; color: #fff; padding: 24px 40px; }
+  header h1 { margin: 0 0 4px; font-size: 24px; }
+  header .meta { color: #cbd2d9; font-size: 13px; }
+  main { padding: 24px 40px; max-width: 1100px; }
+  section { background: #fff; border-radius: 6px; padding: 20px 24px; margin-bottom: 20px; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+  h2 { font-size: 17px; margin: 0 0 14px; }
+  .cards { display: flex; flex-wrap: wrap; gap: 14px; }
+  .card { flex: 1 1 140px; border: 1px solid #e4e7eb; border-radius: 6px; padding: 12px 16px; }
+  .card .value { font-size: 22px; font-weight: 600; }
+  .card .label { font-size: 12px; color: #616e7c; text-transform: uppercase; letter-spacing: .04em; }
+  table { border-collapse: collapse; width: 100%; font-size: 13px; }
+  th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e4e7eb; vertical-align: top; }
+  th { background: #f5f7fa; font-weight: 600; }
+  td.mono { font-family: SFMono-Regular, Consolas, monospace; font-size: 12px; word-break: break-all; }
+  .badge { display: inline-block; padding: 1px 8px; border-radius: 10px; font-size: 12px; font-weight: 600; color: #fff; }
+  .success, .deployed { background: #2f9e44; fill: #2f9e44; stroke: #2f9e44; }
+  .critical { background: #862e9c; }
+  .failed, .high { background: #e03131; fill: #e03131; stroke: #e03131; }
+  .skipped, .low { background: #868e96; fill: #868e96; stroke: #868e96; }
+  .medium, .drifted { background: #f08c00; }
+  .donut { display: flex; align-items: center; gap: 16px; }
+  .donut circle.slice { fill: none; stroke-width: 4; }
+  .legend { font-size: 13px; line-height: 1.7; }
+  .legend span.swatch { display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin-right: 6px; }
+  svg text { font-size: 12px; fill: #1f2933; }
+  .error { color: #c92a2a; font-size: 12px; }
+  details.logs summary { cursor: pointer; font-size: 12px; color: #52606d; margin-top: 4px; }
+  details.logs pre { background: #1f2933; color: #e4e7eb; font-size: 11px; padding: 8px; border-radius: 4px; overflow-x: auto; max-width: 640px; }
+</style>
+{{end}}
+{{define "donut"}}
+<div class="donut">
+  <svg width="120" height="120" viewBox="0 0 42 42" role="img">
+    <circle cx="21" cy="21" r="15.915" fill="none" stroke="#e4e7eb" stroke-width="4"></circle>
+    {{range .}}{{if .Count}}<circle class="slice {{.Status}}" cx="21" cy="21" r="15.915" stroke-dasharray="{{.Dash}}" stroke-dashoffset="{{.Offset}}"></circle>{{end}}{{end}}
+  </svg>
+  <div class="legend">
+    {{range .}}<div><span class="swatch {{.Status}}"></span>{{.Label}}: {{.Count}}</div>{{end}}
+  </div>
+</div>
+{{end}}
+{{define "logtail"}}{{if .}}<details class="logs"><summary>Last {{len .}} output lines</summary><pre>{{range .}}{{.}}
+{{end}}</pre></details>{{end}}{{end}}`))
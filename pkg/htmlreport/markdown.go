@@ -0,0 +1,138 @@
+package htmlreport
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statusIcons prefix statuses in the Markdown report, which has no colors
+var statusIcons = map[string]string{
+	StatusSuccess: "✅",
+	StatusFailed:  "❌",
+	StatusSkipped: "⏭️",
+}
+
+// Markdown renders the summary as GitHub-flavored Markdown, for CI job summaries and pull
+// request comments. Log tails are collapsed in details blocks, as in the HTML report.
+func Markdown(s *Summary) string {
+	var b strings.Builder
+	title := s.Title
+	if s.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&b, "## %s %s\n\n", icon(s.Status), title)
+	fmt.Fprintf(&b, "**%s** in %s, generated %s\n\n", s.Status, s.Duration.Round(time.Second), s.Generated.Format("2006-01-02 15:04:05 MST"))
+
+	if len(s.Details) > 0 {
+		b.WriteString("| Property | Value |\n| --- | --- |\n")
+		for _, detail := range s.Details {
+			fmt.Fprintf(&b, "| %s | %s |\n", cell(detail[0]), cell(detail[1]))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(s.Steps) > 0 {
+		b.WriteString("### Steps\n\n| Step | Status | Duration | Description |\n| --- | --- | --- | --- |\n")
+		for _, step := range s.Steps {
+			fmt.Fprintf(&b, "| %s | %s %s | %s | %s |\n", cell(step.Name), icon(step.Status), step.Status,
+				step.Duration.Round(time.Millisecond), cell(step.Description))
+		}
+		b.WriteString("\n")
+		for _, step := range s.Steps {
+			if step.Error != "" {
+				fmt.Fprintf(&b, "**%s failed:** %s\n\n", step.Name, cell(step.Error))
+				logTail(&b, step.LogTail)
+			}
+		}
+	}
+
+	if len(s.Charts) > 0 {
+		b.WriteString("### Releases\n\n| Chart | Namespace | Version | App version | Status |\n| --- | --- | --- | --- | --- |\n")
+		for _, chart := range s.Charts {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", cell(chart.Name), cell(chart.Namespace), cell(chart.Version), cell(chart.AppVersion), cell(chart.Status))
+		}
+		b.WriteString("\n")
+		for _, chart := range s.Charts {
+			if chart.Error != "" {
+				fmt.Fprintf(&b, "**%s failed:** %s\n\n", chart.Name, cell(chart.Error))
+				logTail(&b, chart.LogTail)
+			}
+		}
+	}
+
+	counts(&b, "Validation", s.Checks, s.Validation)
+	counts(&b, "Tests", s.Tests, s.TestFails)
+
+	if len(s.Findings) > 0 {
+		b.WriteString("### Findings\n\n| Severity | Source | Object | Detail |\n| --- | --- | --- | --- |\n")
+		for _, finding := range s.Findings {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", finding.Severity, cell(finding.Source), cell(finding.Object), cell(finding.Detail))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(s.Notes) > 0 {
+		b.WriteString("### Release notes\n\n")
+		for _, notes := range s.Notes {
+			from := notes.From
+			if from == "" {
+				from = "new install"
+			}
+			fmt.Fprintf(&b, "<details><summary>%s %s → %s</summary>\n\n", notes.Chart, from, notes.To)
+			for _, entry := range notes.Entries {
+				fmt.Fprintf(&b, "**%s**\n\n%s\n\n", entry.Version, entry.Body)
+				for _, breaking := range entry.Breaking {
+					fmt.Fprintf(&b, "- ⚠️ Breaking: %s\n", breaking)
+				}
+			}
+			b.WriteString("\n</details>\n\n")
+		}
+	}
+
+	if len(s.Artifacts) > 0 {
+		fmt.Fprintf(&b, "<details><summary>Artifacts (%d)</summary>\n\n| Kind | Name | Version | Reference | Digest |\n| --- | --- | --- | --- | --- |\n", len(s.Artifacts))
+		for _, artifact := range s.Artifacts {
+			fmt.Fprintf(&b, "| %s | %s | %s | `%s` | `%s` |\n", artifact.Kind, cell(artifact.Name), cell(artifact.Version), artifact.Reference, artifact.Digest)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	return b.String()
+}
+
+func counts(b *strings.Builder, title string, c Counts, failures []Failure) {
+	if c.Total() == 0 {
+		return
+	}
+	fmt.Fprintf(b, "### %s\n\n%d passed, %d failed, %d skipped\n\n", title, c.Passed, c.Failed, c.Skipped)
+	for _, failure := range failures {
+		name := failure.Name
+		if failure.Category != "" {
+			name += " (" + failure.Category + ")"
+		}
+		fmt.Fprintf(b, "- ❌ **%s**: %s\n", cell(name), cell(failure.Error))
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n")
+	}
+}
+
+func logTail(b *strings.Builder, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "<details><summary>Last %d lines of output</summary>\n\n```\n%s\n```\n\n</details>\n\n", len(lines), strings.Join(lines, "\n"))
+}
+
+func icon(status string) string {
+	if i, ok := statusIcons[status]; ok {
+		return i
+	}
+	return "•"
+}
+
+// cell keeps a value on one line of a table row
+func cell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
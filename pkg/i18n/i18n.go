@@ -0,0 +1,119 @@
+// Package i18n translates user-facing messages. Messages are written in English in the
+// code and double as the keys of the bundled catalogs, so a message without a translation
+// is shown in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// English is the locale messages are written in
+const English = "en"
+
+//go:embed locales/*.json
+var bundled embed.FS
+
+var (
+	catalogs = loadCatalogs()
+	locale   = English
+	catalog  map[string]string
+)
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := bundled.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := make(map[string]map[string]string)
+	for _, entry := range entries {
+		data, err := bundled.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("invalid message catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return catalogs
+}
+
+// Locales lists the supported locales
+func Locales() []string {
+	locales := []string{English}
+	for name := range catalogs {
+		locales = append(locales, name)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// SetLocale switches all further messages to a locale, given as a language such as "de" or
+// a POSIX locale such as "de_DE.UTF-8"
+func SetLocale(name string) error {
+	language := normalize(name)
+	if language == English {
+		locale, catalog = English, nil
+		return nil
+	}
+	messages, ok := catalogs[language]
+	if !ok {
+		return fmt.Errorf("unsupported locale %q, supported locales: %s", name, strings.Join(Locales(), ", "))
+	}
+	locale, catalog = language, messages
+	return nil
+}
+
+// Locale returns the locale messages are translated to
+func Locale() string {
+	return locale
+}
+
+// SystemLocale returns the supported locale named by LC_ALL, LC_MESSAGES or LANG, or ""
+// when they name none
+func SystemLocale() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		language := normalize(value)
+		if _, ok := catalogs[language]; ok || language == English {
+			return language
+		}
+		return ""
+	}
+	return ""
+}
+
+// T translates a message
+func T(message string) string {
+	if translated, ok := catalog[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Tf translates a format string and formats it with args
+func Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// normalize reduces "de_DE.UTF-8" or "de-DE" to the language "de"
+func normalize(name string) string {
+	language := strings.ToLower(name)
+	if i := strings.IndexAny(language, "_-.@"); i >= 0 {
+		language = language[:i]
+	}
+	if language == "" || language == "c" || language == "posix" {
+		return English
+	}
+	return language
+}
@@ -3,7 +3,10 @@ package infrastructure
 import (
 	"fmt"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/ansible"
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/declarative"
+	"github.com/judebantony/e2e-k8s-installer/pkg/localcluster"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
 	"github.com/judebantony/e2e-k8s-installer/pkg/makefile"
 	"github.com/judebantony/e2e-k8s-installer/pkg/terraform"
@@ -14,14 +17,21 @@ type Manager struct {
 	config        *config.InfrastructureConfig
 	terraformMgr  *terraform.Manager
 	makefileMgr   *makefile.Manager
+	ansibleMgr    *ansible.Manager
+	localMgr      *localcluster.Manager
+	declarative   *declarative.Manager
 	provisionMode string
 }
 
 // ProvisionMode constants
 const (
-	ProvisionModeTerraform = "terraform"
-	ProvisionModeMakefile  = "makefile"
-	ProvisionModeHybrid    = "hybrid"
+	ProvisionModeTerraform   = "terraform"
+	ProvisionModeMakefile    = "makefile"
+	ProvisionModeAnsible     = "ansible"
+	ProvisionModeHybrid      = "hybrid"
+	ProvisionModeManaged     = "managed"
+	ProvisionModeLocal       = "local"
+	ProvisionModeDeclarative = "declarative"
 )
 
 // NewManager creates a new infrastructure manager
@@ -62,8 +72,18 @@ func NewManager(infraConfig *config.InfrastructureConfig) (*Manager, error) {
 		}
 		mgr.makefileMgr = makeMgr
 
+	case ProvisionModeAnsible:
+		if !infraConfig.Ansible.Enabled {
+			return nil, fmt.Errorf("ansible mode selected but ansible is not enabled in configuration")
+		}
+		ansibleMgr, err := ansible.NewManager(&infraConfig.Ansible)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ansible manager: %w", err)
+		}
+		mgr.ansibleMgr = ansibleMgr
+
 	case ProvisionModeHybrid:
-		// Initialize both managers for hybrid mode
+		// Initialize every enabled manager for hybrid mode
 		if infraConfig.Terraform.Enabled {
 			tfMgr, err := terraform.NewManager(infraConfig)
 			if err != nil {
@@ -80,9 +100,33 @@ func NewManager(infraConfig *config.InfrastructureConfig) (*Manager, error) {
 			mgr.makefileMgr = makeMgr
 		}
 
-		if mgr.terraformMgr == nil && mgr.makefileMgr == nil {
-			return nil, fmt.Errorf("hybrid mode requires at least one of terraform or makefile to be enabled")
+		if infraConfig.Ansible.Enabled {
+			ansibleMgr, err := ansible.NewManager(&infraConfig.Ansible)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create ansible manager: %w", err)
+			}
+			mgr.ansibleMgr = ansibleMgr
+		}
+
+		if mgr.terraformMgr == nil && mgr.makefileMgr == nil && mgr.ansibleMgr == nil {
+			return nil, fmt.Errorf("hybrid mode requires at least one of terraform, makefile or ansible to be enabled")
+		}
+
+	case ProvisionModeManaged:
+		// Only the managed services are provisioned, by provision-infra itself
+		if len(infraConfig.ManagedServices.Databases) == 0 && len(infraConfig.ManagedServices.Caches) == 0 {
+			return nil, fmt.Errorf("managed mode selected but no managed services are configured")
+		}
+
+	case ProvisionModeLocal:
+		mgr.localMgr = localcluster.NewManager(infraConfig.Local)
+
+	case ProvisionModeDeclarative:
+		declarativeMgr, err := declarative.NewManager(infraConfig.Declarative)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create declarative cluster manager: %w", err)
 		}
+		mgr.declarative = declarativeMgr
 
 	default:
 		return nil, fmt.Errorf("unsupported provision mode: %s", mgr.provisionMode)
@@ -103,8 +147,16 @@ func (m *Manager) Init(dryRun bool) error {
 		return m.initTerraform(dryRun)
 	case ProvisionModeMakefile:
 		return m.initMakefile(dryRun)
+	case ProvisionModeAnsible:
+		return m.initAnsible(dryRun)
 	case ProvisionModeHybrid:
 		return m.initHybrid(dryRun)
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		return m.initLocal(dryRun)
+	case ProvisionModeDeclarative:
+		return m.declarative.CheckManagementCluster(dryRun)
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -122,8 +174,16 @@ func (m *Manager) Plan(dryRun bool) error {
 		return m.planTerraform(dryRun)
 	case ProvisionModeMakefile:
 		return m.planMakefile(dryRun)
+	case ProvisionModeAnsible:
+		return m.planAnsible(dryRun)
 	case ProvisionModeHybrid:
 		return m.planHybrid(dryRun)
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		return m.planLocal(dryRun)
+	case ProvisionModeDeclarative:
+		return m.planDeclarative(dryRun)
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -141,8 +201,16 @@ func (m *Manager) Apply(dryRun bool) error {
 		return m.applyTerraform(dryRun)
 	case ProvisionModeMakefile:
 		return m.applyMakefile(dryRun)
+	case ProvisionModeAnsible:
+		return m.applyAnsible(dryRun)
 	case ProvisionModeHybrid:
 		return m.applyHybrid(dryRun)
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		return m.applyLocal(dryRun)
+	case ProvisionModeDeclarative:
+		return m.declarative.Apply(dryRun)
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -160,8 +228,16 @@ func (m *Manager) Destroy(dryRun bool) error {
 		return m.destroyTerraform(dryRun)
 	case ProvisionModeMakefile:
 		return m.destroyMakefile(dryRun)
+	case ProvisionModeAnsible:
+		return m.destroyAnsible(dryRun)
 	case ProvisionModeHybrid:
 		return m.destroyHybrid(dryRun)
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		return m.destroyLocal(dryRun)
+	case ProvisionModeDeclarative:
+		return m.declarative.Destroy(dryRun)
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -179,8 +255,16 @@ func (m *Manager) Validate(dryRun bool) error {
 		return m.validateTerraform(dryRun)
 	case ProvisionModeMakefile:
 		return m.validateMakefile(dryRun)
+	case ProvisionModeAnsible:
+		return m.validateAnsible(dryRun)
 	case ProvisionModeHybrid:
 		return m.validateHybrid(dryRun)
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		return m.validateLocal(dryRun)
+	case ProvisionModeDeclarative:
+		return m.declarative.Validate(dryRun)
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -281,71 +365,112 @@ func (m *Manager) validateMakefile(dryRun bool) error {
 	return m.makefileMgr.Validate(dryRun)
 }
 
-// Hybrid mode methods (execute both terraform and makefile)
-func (m *Manager) initHybrid(dryRun bool) error {
-	// Execute makefile init first, then terraform
-	if m.makefileMgr != nil {
-		if err := m.initMakefile(dryRun); err != nil {
-			return fmt.Errorf("makefile init failed: %w", err)
-		}
+// Ansible-specific methods
+func (m *Manager) initAnsible(dryRun bool) error {
+	if m.ansibleMgr == nil {
+		return fmt.Errorf("ansible manager not initialized")
 	}
+	return m.ansibleMgr.Init(dryRun)
+}
 
-	if m.terraformMgr != nil {
-		if err := m.initTerraform(dryRun); err != nil {
-			return fmt.Errorf("terraform init failed: %w", err)
-		}
+func (m *Manager) planAnsible(dryRun bool) error {
+	if m.ansibleMgr == nil {
+		return fmt.Errorf("ansible manager not initialized")
 	}
-
-	return nil
+	return m.ansibleMgr.Plan(dryRun)
 }
 
-func (m *Manager) planHybrid(dryRun bool) error {
-	// Execute makefile plan first, then terraform
-	if m.makefileMgr != nil {
-		if err := m.planMakefile(dryRun); err != nil {
-			return fmt.Errorf("makefile plan failed: %w", err)
-		}
+func (m *Manager) applyAnsible(dryRun bool) error {
+	if m.ansibleMgr == nil {
+		return fmt.Errorf("ansible manager not initialized")
 	}
+	return m.ansibleMgr.Apply(dryRun)
+}
 
-	if m.terraformMgr != nil {
-		if err := m.planTerraform(dryRun); err != nil {
-			return fmt.Errorf("terraform plan failed: %w", err)
-		}
+func (m *Manager) destroyAnsible(dryRun bool) error {
+	if m.ansibleMgr == nil {
+		return fmt.Errorf("ansible manager not initialized")
 	}
+	return m.ansibleMgr.Destroy(dryRun)
+}
 
-	return nil
+func (m *Manager) validateAnsible(dryRun bool) error {
+	if m.ansibleMgr == nil {
+		return fmt.Errorf("ansible manager not initialized")
+	}
+	return m.ansibleMgr.Validate(dryRun)
 }
 
-func (m *Manager) applyHybrid(dryRun bool) error {
-	// Execute makefile apply first, then terraform
-	if m.makefileMgr != nil {
-		if err := m.applyMakefile(dryRun); err != nil {
-			return fmt.Errorf("makefile apply failed: %w", err)
+// Hybrid mode methods (execute every enabled tool in hybrid order)
+
+// defaultHybridOrder runs Makefiles first so they can prepare what Terraform needs, and
+// Ansible last to configure the machines Terraform created
+var defaultHybridOrder = []string{ProvisionModeMakefile, ProvisionModeTerraform, ProvisionModeAnsible}
+
+// hybridTools returns the enabled tools in the configured order
+func (m *Manager) hybridTools() []string {
+	order := m.config.HybridOrder
+	if len(order) == 0 {
+		order = defaultHybridOrder
+	}
+	var tools []string
+	for _, tool := range order {
+		enabled := (tool == ProvisionModeMakefile && m.makefileMgr != nil) ||
+			(tool == ProvisionModeTerraform && m.terraformMgr != nil) ||
+			(tool == ProvisionModeAnsible && m.ansibleMgr != nil)
+		if enabled {
+			tools = append(tools, tool)
 		}
 	}
+	return tools
+}
 
-	if m.terraformMgr != nil {
-		if err := m.applyTerraform(dryRun); err != nil {
-			return fmt.Errorf("terraform apply failed: %w", err)
+// runHybrid runs an operation with each tool, where steps maps a tool to its method
+func (m *Manager) runHybrid(operation string, tools []string, steps map[string]func(bool) error, dryRun bool) error {
+	for _, tool := range tools {
+		if err := steps[tool](dryRun); err != nil {
+			return fmt.Errorf("%s %s failed: %w", tool, operation, err)
 		}
 	}
-
 	return nil
 }
 
-func (m *Manager) destroyHybrid(dryRun bool) error {
-	// Execute terraform destroy first, then makefile
-	var errs []error
+func (m *Manager) initHybrid(dryRun bool) error {
+	return m.runHybrid("init", m.hybridTools(), map[string]func(bool) error{
+		ProvisionModeMakefile:  m.initMakefile,
+		ProvisionModeTerraform: m.initTerraform,
+		ProvisionModeAnsible:   m.initAnsible,
+	}, dryRun)
+}
 
-	if m.terraformMgr != nil {
-		if err := m.destroyTerraform(dryRun); err != nil {
-			errs = append(errs, fmt.Errorf("terraform destroy failed: %w", err))
-		}
-	}
+func (m *Manager) planHybrid(dryRun bool) error {
+	return m.runHybrid("plan", m.hybridTools(), map[string]func(bool) error{
+		ProvisionModeMakefile:  m.planMakefile,
+		ProvisionModeTerraform: m.planTerraform,
+		ProvisionModeAnsible:   m.planAnsible,
+	}, dryRun)
+}
 
-	if m.makefileMgr != nil {
-		if err := m.destroyMakefile(dryRun); err != nil {
-			errs = append(errs, fmt.Errorf("makefile destroy failed: %w", err))
+func (m *Manager) applyHybrid(dryRun bool) error {
+	return m.runHybrid("apply", m.hybridTools(), map[string]func(bool) error{
+		ProvisionModeMakefile:  m.applyMakefile,
+		ProvisionModeTerraform: m.applyTerraform,
+		ProvisionModeAnsible:   m.applyAnsible,
+	}, dryRun)
+}
+
+func (m *Manager) destroyHybrid(dryRun bool) error {
+	// Destroy in reverse order, attempting every tool even after a failure
+	steps := map[string]func(bool) error{
+		ProvisionModeMakefile:  m.destroyMakefile,
+		ProvisionModeTerraform: m.destroyTerraform,
+		ProvisionModeAnsible:   m.destroyAnsible,
+	}
+	tools := m.hybridTools()
+	var errs []error
+	for i := len(tools) - 1; i >= 0; i-- {
+		if err := steps[tools[i]](dryRun); err != nil {
+			errs = append(errs, fmt.Errorf("%s destroy failed: %w", tools[i], err))
 		}
 	}
 
@@ -357,19 +482,74 @@ func (m *Manager) destroyHybrid(dryRun bool) error {
 }
 
 func (m *Manager) validateHybrid(dryRun bool) error {
-	// Validate both makefile and terraform
-	if m.makefileMgr != nil {
-		if err := m.validateMakefile(dryRun); err != nil {
-			return fmt.Errorf("makefile validation failed: %w", err)
-		}
+	return m.runHybrid("validation", m.hybridTools(), map[string]func(bool) error{
+		ProvisionModeMakefile:  m.validateMakefile,
+		ProvisionModeTerraform: m.validateTerraform,
+		ProvisionModeAnsible:   m.validateAnsible,
+	}, dryRun)
+}
+
+// Local cluster methods
+func (m *Manager) initLocal(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Local cluster tool check skipped").Send()
+		return nil
+	}
+	return m.localMgr.CheckTools()
+}
+
+func (m *Manager) planLocal(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Local cluster plan skipped").Send()
+		return nil
+	}
+	exists, err := m.localMgr.Exists()
+	if err != nil {
+		return err
+	}
+	action := "create"
+	if exists {
+		action = "keep existing"
 	}
+	logger.Info("Local cluster plan").
+		Str("tool", m.localMgr.Tool()).
+		Str("name", m.localMgr.Name()).
+		Str("action", action).
+		Send()
+	return nil
+}
 
-	if m.terraformMgr != nil {
-		if err := m.validateTerraform(dryRun); err != nil {
-			return fmt.Errorf("terraform validation failed: %w", err)
-		}
+func (m *Manager) applyLocal(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Local cluster creation skipped").Send()
+		return nil
 	}
+	return m.localMgr.Create()
+}
+
+func (m *Manager) destroyLocal(dryRun bool) error {
+	if dryRun {
+		logger.Info("DRY RUN: Local cluster deletion skipped").Send()
+		return nil
+	}
+	return m.localMgr.Delete()
+}
 
+func (m *Manager) validateLocal(dryRun bool) error {
+	return m.initLocal(dryRun)
+}
+
+// planDeclarative shows the changes applying the manifests would make to the management cluster
+func (m *Manager) planDeclarative(dryRun bool) error {
+	diff, err := m.declarative.Diff(dryRun)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		logger.Info("No changes to the declarative cluster resources").Send()
+		return nil
+	}
+	fmt.Println(diff)
 	return nil
 }
 
@@ -383,23 +563,42 @@ func (m *Manager) GetMakefileManager() *makefile.Manager {
 	return m.makefileMgr
 }
 
+// GetAnsibleManager returns the ansible manager (if available)
+func (m *Manager) GetAnsibleManager() *ansible.Manager {
+	return m.ansibleMgr
+}
+
 // GetTerraformManager returns the terraform manager (if available)
 func (m *Manager) GetTerraformManager() *terraform.Manager {
 	return m.terraformMgr
 }
 
+// GetLocalClusterManager returns the local cluster manager (if available)
+func (m *Manager) GetLocalClusterManager() *localcluster.Manager {
+	return m.localMgr
+}
+
+// GetDeclarativeManager returns the declarative cluster manager (if available)
+func (m *Manager) GetDeclarativeManager() *declarative.Manager {
+	return m.declarative
+}
+
 // GetInfo returns information about the infrastructure manager
 func (m *Manager) GetInfo() *ManagerInfo {
 	info := &ManagerInfo{
 		ProvisionMode:     m.provisionMode,
 		TerraformEnabled:  m.terraformMgr != nil,
 		MakefileEnabled:   m.makefileMgr != nil,
+		AnsibleEnabled:    m.ansibleMgr != nil,
 		HealthCheckConfig: m.config.HealthCheck,
 	}
 
 	if m.makefileMgr != nil {
 		info.MakefileInfo = m.makefileMgr.GetMakefileInfo()
 	}
+	if m.provisionMode == ProvisionModeHybrid {
+		info.HybridOrder = m.hybridTools()
+	}
 
 	return info
 }
@@ -415,6 +614,8 @@ func (m *Manager) RunHealthChecks() error {
 			return m.makefileMgr.ExecuteTarget(m.config.Makefile.Targets.HealthCheck, false)
 		}
 		return nil
+	case ProvisionModeAnsible:
+		return nil
 	case ProvisionModeHybrid:
 		// For hybrid mode, run both terraform and makefile health checks
 		if m.terraformMgr != nil {
@@ -428,6 +629,19 @@ func (m *Manager) RunHealthChecks() error {
 			}
 		}
 		return nil
+	case ProvisionModeManaged:
+		return nil
+	case ProvisionModeLocal:
+		exists, err := m.localMgr.Exists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("local cluster %s is not running", m.localMgr.Name())
+		}
+		return nil
+	case ProvisionModeDeclarative:
+		return m.declarative.CheckWorkloadCluster()
 	default:
 		return fmt.Errorf("unsupported provision mode: %s", m.provisionMode)
 	}
@@ -438,6 +652,8 @@ type ManagerInfo struct {
 	ProvisionMode     string                   `json:"provisionMode"`
 	TerraformEnabled  bool                     `json:"terraformEnabled"`
 	MakefileEnabled   bool                     `json:"makefileEnabled"`
+	AnsibleEnabled    bool                     `json:"ansibleEnabled"`
+	HybridOrder       []string                 `json:"hybridOrder,omitempty"`
 	HealthCheckConfig config.HealthCheckConfig `json:"healthCheckConfig"`
 	MakefileInfo      *makefile.MakefileInfo   `json:"makefileInfo,omitempty"`
 }
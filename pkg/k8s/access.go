@@ -0,0 +1,311 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Access point categories
+const (
+	AccessApplication = "application"
+	AccessAdmin       = "admin"
+	AccessMonitoring  = "monitoring"
+)
+
+// AccessPoint is an address an installed application is reached at
+type AccessPoint struct {
+	Category  string `json:"category"`
+	Release   string `json:"release"`
+	Namespace string `json:"namespace"`
+	URL       string `json:"url"`
+	Source    string `json:"source"` // the Ingress or Service exposing it, e.g. ingress/grafana
+}
+
+// CredentialSecret is a Secret of a release holding initial credentials. Only the key names
+// are kept, never the values.
+type CredentialSecret struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Release   string   `json:"release"`
+	Keys      []string `json:"keys"`
+}
+
+// AccessInfo is how operators get started with the installed releases
+type AccessInfo struct {
+	AccessPoints []AccessPoint      `json:"accessPoints"`
+	Secrets      []CredentialSecret `json:"secrets"`
+}
+
+// releaseObjectList mirrors the subset of ingress, service and secret lists that is needed
+type releaseObjectList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+		Spec struct {
+			Type  string `json:"type"`
+			Ports []struct {
+				Name string `json:"name"`
+				Port int    `json:"port"`
+			} `json:"ports"`
+			TLS []struct {
+				Hosts []string `json:"hosts"`
+			} `json:"tls"`
+			Rules []struct {
+				Host string `json:"host"`
+				HTTP struct {
+					Paths []ingressPath `json:"paths"`
+				} `json:"http"`
+			} `json:"rules"`
+		} `json:"spec"`
+		Status struct {
+			LoadBalancer struct {
+				Ingress []loadBalancerIngress `json:"ingress"`
+			} `json:"loadBalancer"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type ingressPath struct {
+	Path string `json:"path"`
+}
+
+type loadBalancerIngress struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+// AccessInfo collects the ingress and load balancer URLs of the Helm releases in namespaces,
+// or in every namespace when none are given, the in-cluster addresses of monitoring services
+// that are not exposed, and the Secrets holding their initial credentials
+func (m *Manager) AccessInfo(namespaces []string) (*AccessInfo, error) {
+	info := &AccessInfo{}
+	exposed := make(map[string]bool)
+
+	ingresses, err := m.releaseObjects("ingresses", namespaces)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range ingresses.Items {
+		release := releaseName(item.Metadata.Labels, item.Metadata.Annotations)
+		tls := make(map[string]bool)
+		for _, entry := range item.Spec.TLS {
+			for _, host := range entry.Hosts {
+				tls[host] = true
+			}
+		}
+		for _, rule := range item.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = loadBalancerAddress(item.Status.LoadBalancer.Ingress)
+			}
+			if host == "" {
+				continue
+			}
+			scheme := "http"
+			if tls[rule.Host] {
+				scheme = "https"
+			}
+			for _, path := range ingressPaths(rule.HTTP.Paths) {
+				info.AccessPoints = append(info.AccessPoints, AccessPoint{
+					Category:  accessCategory(item.Metadata.Name, item.Metadata.Labels),
+					Release:   release,
+					Namespace: item.Metadata.Namespace,
+					URL:       scheme + "://" + host + path,
+					Source:    "ingress/" + item.Metadata.Name,
+				})
+			}
+			exposed[item.Metadata.Namespace+"/"+release] = true
+		}
+	}
+
+	services, err := m.releaseObjects("services", namespaces)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range services.Items {
+		release := releaseName(item.Metadata.Labels, item.Metadata.Annotations)
+		category := accessCategory(item.Metadata.Name, item.Metadata.Labels)
+		host := ""
+		switch {
+		case item.Spec.Type == "LoadBalancer":
+			host = loadBalancerAddress(item.Status.LoadBalancer.Ingress)
+		case category == AccessMonitoring && !exposed[item.Metadata.Namespace+"/"+release]:
+			// Not reachable from outside, operators port-forward to the cluster address
+			host = item.Metadata.Name + "." + item.Metadata.Namespace + ".svc"
+		}
+		if host == "" {
+			continue
+		}
+		for _, port := range item.Spec.Ports {
+			scheme := "http"
+			if port.Port == 443 || port.Name == "https" {
+				scheme = "https"
+			}
+			info.AccessPoints = append(info.AccessPoints, AccessPoint{
+				Category:  category,
+				Release:   release,
+				Namespace: item.Metadata.Namespace,
+				URL:       scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port.Port)),
+				Source:    "service/" + item.Metadata.Name,
+			})
+		}
+	}
+
+	secrets, err := m.releaseObjects("secrets", namespaces)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range secrets.Items {
+		if item.Type == "kubernetes.io/tls" || item.Type == "kubernetes.io/service-account-token" {
+			continue
+		}
+		var keys []string
+		for key := range item.Data {
+			keys = append(keys, key)
+		}
+		if !credentialSecret(item.Metadata.Name, keys) {
+			continue
+		}
+		sort.Strings(keys)
+		info.Secrets = append(info.Secrets, CredentialSecret{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Release:   releaseName(item.Metadata.Labels, item.Metadata.Annotations),
+			Keys:      keys,
+		})
+	}
+
+	sort.SliceStable(info.AccessPoints, func(i, j int) bool {
+		a, b := info.AccessPoints[i], info.AccessPoints[j]
+		if a.Category != b.Category {
+			return accessOrder(a.Category) < accessOrder(b.Category)
+		}
+		if a.Release != b.Release {
+			return a.Release < b.Release
+		}
+		return a.URL < b.URL
+	})
+	sort.SliceStable(info.Secrets, func(i, j int) bool {
+		a, b := info.Secrets[i], info.Secrets[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return info, nil
+}
+
+// releaseObjects lists the objects of a resource Helm installed in namespaces
+func (m *Manager) releaseObjects(resource string, namespaces []string) (*releaseObjectList, error) {
+	scopes := namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	list := &releaseObjectList{}
+	for _, namespace := range scopes {
+		output, err := m.List(resource, namespace, "app.kubernetes.io/managed-by=Helm")
+		if err != nil {
+			return nil, err
+		}
+		var page releaseObjectList
+		if err := json.Unmarshal(output, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse %s list: %w", resource, err)
+		}
+		list.Items = append(list.Items, page.Items...)
+	}
+	return list, nil
+}
+
+// releaseName is the Helm release an object belongs to
+func releaseName(labels, annotations map[string]string) string {
+	if release := annotations["meta.helm.sh/release-name"]; release != "" {
+		return release
+	}
+	return labels["app.kubernetes.io/instance"]
+}
+
+// loadBalancerAddress is the first address a load balancer was given
+func loadBalancerAddress(ingress []loadBalancerIngress) string {
+	for _, entry := range ingress {
+		if entry.Hostname != "" {
+			return entry.Hostname
+		}
+		if entry.IP != "" {
+			return entry.IP
+		}
+	}
+	return ""
+}
+
+// ingressPaths are the distinct paths of a rule, "/" for a default backend
+func ingressPaths(paths []ingressPath) []string {
+	var result []string
+	for _, path := range paths {
+		p := path.Path
+		if p == "/" {
+			p = ""
+		}
+		if !slices.Contains(result, p) {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		result = []string{""}
+	}
+	return result
+}
+
+// accessCategory sorts monitoring stacks and admin consoles out of the applications
+func accessCategory(name string, labels map[string]string) string {
+	lookup := strings.ToLower(name + " " + labels["app.kubernetes.io/name"] + " " + labels["app.kubernetes.io/component"])
+	for _, word := range []string{"grafana", "prometheus", "alertmanager", "kibana"} {
+		if strings.Contains(lookup, word) {
+			return AccessMonitoring
+		}
+	}
+	for _, word := range []string{"admin", "console", "dashboard"} {
+		if strings.Contains(lookup, word) {
+			return AccessAdmin
+		}
+	}
+	return AccessApplication
+}
+
+func accessOrder(category string) int {
+	switch category {
+	case AccessApplication:
+		return 0
+	case AccessAdmin:
+		return 1
+	}
+	return 2
+}
+
+// credentialSecret tells Secrets with initial credentials apart from certificates and
+// configuration by their name or keys
+func credentialSecret(name string, keys []string) bool {
+	words := []string{"password", "credential", "admin", "token", "secret-key"}
+	lookup := []string{strings.ToLower(name)}
+	for _, key := range keys {
+		lookup = append(lookup, strings.ToLower(key))
+	}
+	for _, value := range lookup {
+		for _, word := range words {
+			if strings.Contains(value, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/deprecations"
+)
+
+// defaultIngressClassAnnotation marks the IngressClass used by Ingresses that name none
+const defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+
+// Capabilities describes what a cluster offers charts, detected once per deployment and
+// exposed to values templates as .Capabilities
+type Capabilities struct {
+	KubeVersion         string   `json:"kubeVersion"`
+	APIVersions         []string `json:"apiVersions"`
+	IngressClasses      []string `json:"ingressClasses,omitempty"`
+	DefaultIngressClass string   `json:"defaultIngressClass,omitempty"`
+	MetricsServer       bool     `json:"metricsServer"`
+
+	// PodSecurityPolicy is served up to Kubernetes 1.24; Pod Security Admission is on from 1.23
+	PodSecurityPolicy    bool `json:"podSecurityPolicy"`
+	PodSecurityAdmission bool `json:"podSecurityAdmission"`
+}
+
+// HasAPI reports whether the cluster serves an API version, e.g. "monitoring.coreos.com/v1".
+// A version followed by a kind, e.g. "networking.k8s.io/v1/Ingress", checks the group version.
+func (c Capabilities) HasAPI(apiVersion string) bool {
+	if parts := strings.Split(apiVersion, "/"); len(parts) == 3 {
+		apiVersion = parts[0] + "/" + parts[1]
+	}
+	return slices.Contains(c.APIVersions, apiVersion)
+}
+
+// HasIngressClass reports whether an IngressClass of the name exists
+func (c Capabilities) HasIngressClass(name string) bool {
+	return slices.Contains(c.IngressClasses, name)
+}
+
+// PodSecurity returns how pod security is enforced: "psa", "psp" or "none"
+func (c Capabilities) PodSecurity() string {
+	switch {
+	case c.PodSecurityAdmission:
+		return "psa"
+	case c.PodSecurityPolicy:
+		return "psp"
+	}
+	return "none"
+}
+
+// DetectCapabilities queries the API server for the served API versions, the ingress classes
+// and the version-dependent pod security mechanisms
+func (m *Manager) DetectCapabilities() (*Capabilities, error) {
+	serverVersion, err := m.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	apiVersions, err := m.APIVersions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API versions: %w", err)
+	}
+	caps := &Capabilities{
+		KubeVersion: serverVersion,
+		APIVersions: apiVersions,
+	}
+	caps.MetricsServer = caps.HasAPI("metrics.k8s.io/v1beta1")
+
+	if caps.HasAPI("networking.k8s.io/v1") {
+		if err := m.detectIngressClasses(caps); err != nil {
+			return nil, err
+		}
+	}
+
+	version, err := deprecations.ParseVersion(serverVersion)
+	if err != nil {
+		return nil, err
+	}
+	caps.PodSecurityAdmission = !version.Less(deprecations.Version{Major: 1, Minor: 23})
+	if caps.HasAPI("policy/v1beta1") && version.Less(deprecations.Version{Major: 1, Minor: 25}) {
+		served, err := m.ServesResource("policy", "podsecuritypolicies")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list policy resources: %w", err)
+		}
+		caps.PodSecurityPolicy = served
+	}
+	return caps, nil
+}
+
+func (m *Manager) detectIngressClasses(caps *Capabilities) error {
+	output, err := m.List("ingressclasses", "", "")
+	if err != nil {
+		return fmt.Errorf("failed to list ingress classes: %w", err)
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name        string            `json:"name"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return fmt.Errorf("failed to parse ingress classes: %w", err)
+	}
+	for _, item := range list.Items {
+		caps.IngressClasses = append(caps.IngressClasses, item.Metadata.Name)
+		if item.Metadata.Annotations[defaultIngressClassAnnotation] == "true" {
+			caps.DefaultIngressClass = item.Metadata.Name
+		}
+	}
+	return nil
+}
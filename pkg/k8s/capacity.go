@@ -0,0 +1,250 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// Workload is the resource demand of one workload controller in a manifest
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Replicas  int
+	PerPod    Resources
+	PerNode   bool // a DaemonSet, running one pod on every node
+}
+
+func (w Workload) key() string {
+	return w.Kind + "/" + w.Namespace + "/" + w.Name
+}
+
+// workloadDocument is the subset of a controller manifest the forecast reads
+type workloadDocument struct {
+	Kind     string `yaml:"kind" json:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name" json:"name"`
+		Namespace string `yaml:"namespace" json:"namespace"`
+	} `yaml:"metadata" json:"metadata"`
+	Spec struct {
+		Replicas    *int `yaml:"replicas" json:"replicas"`
+		Parallelism *int `yaml:"parallelism" json:"parallelism"`
+		Template    struct {
+			Spec struct {
+				Containers []struct {
+					Resources struct {
+						Requests map[string]string `yaml:"requests" json:"requests"`
+					} `yaml:"resources" json:"resources"`
+				} `yaml:"containers" json:"containers"`
+			} `yaml:"spec" json:"spec"`
+		} `yaml:"template" json:"template"`
+	} `yaml:"spec" json:"spec"`
+}
+
+func (d workloadDocument) workload(defaultNamespace string) (Workload, bool) {
+	w := Workload{Kind: d.Kind, Namespace: d.Metadata.Namespace, Name: d.Metadata.Name, Replicas: 1}
+	if w.Namespace == "" {
+		w.Namespace = defaultNamespace
+	}
+	switch d.Kind {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		if d.Spec.Replicas != nil {
+			w.Replicas = *d.Spec.Replicas
+		}
+	case "Job":
+		if d.Spec.Parallelism != nil {
+			w.Replicas = *d.Spec.Parallelism
+		}
+	case "DaemonSet":
+		w.PerNode = true
+	default:
+		return Workload{}, false
+	}
+	for _, container := range d.Spec.Template.Spec.Containers {
+		w.PerPod = w.PerPod.Add(parseResources(container.Resources.Requests))
+	}
+	return w, true
+}
+
+// ManifestWorkloads returns the Deployments, StatefulSets, ReplicaSets, Jobs and DaemonSets
+// of a multi-document manifest with their resource requests
+func ManifestWorkloads(manifest []byte, defaultNamespace string) ([]Workload, error) {
+	var workloads []Workload
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var doc workloadDocument
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if w, ok := doc.workload(defaultNamespace); ok {
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads, nil
+}
+
+// CapacityForecast compares what a deployment adds to the requests on the cluster with
+// the capacity its schedulable nodes have free
+type CapacityForecast struct {
+	Nodes     int       `json:"nodes"`
+	Free      Resources `json:"free"`
+	Required  Resources `json:"required"`
+	Shortfall Resources `json:"shortfall"`
+}
+
+// Short reports whether the deployment is predicted not to fit
+func (f *CapacityForecast) Short() bool {
+	return f.Shortfall.CPU > 0 || f.Shortfall.Memory > 0
+}
+
+// NodesToAdd returns the nodes of size perNode that cover the shortfall
+func (f *CapacityForecast) NodesToAdd(perNode Resources) int {
+	if !f.Short() || perNode.CPU <= 0 || perNode.Memory <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Max(f.Shortfall.CPU/perNode.CPU, f.Shortfall.Memory/perNode.Memory)))
+}
+
+// ForecastCapacity predicts whether workloads fit the cluster. Workloads that already exist
+// count only for the requests they add over their live spec, so upgrading a release with
+// unchanged replicas needs no room; rolling update surge is left to the rollout simulation.
+func (m *Manager) ForecastCapacity(workloads []Workload) (*CapacityForecast, error) {
+	nodes, err := m.simNodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.placePods(nodes); err != nil {
+		return nil, err
+	}
+	live, err := m.liveWorkloads()
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &CapacityForecast{Nodes: len(nodes)}
+	for _, node := range nodes {
+		forecast.Free = forecast.Free.Add(node.free())
+	}
+	for _, w := range workloads {
+		added := w.demand(len(nodes))
+		if current, ok := live[w.key()]; ok {
+			added = added.Sub(current.demand(len(nodes)))
+		}
+		forecast.Required = forecast.Required.Add(Resources{CPU: math.Max(added.CPU, 0), Memory: math.Max(added.Memory, 0)})
+	}
+	short := forecast.Required.Sub(forecast.Free)
+	forecast.Shortfall = Resources{CPU: math.Max(short.CPU, 0), Memory: math.Max(short.Memory, 0)}
+	return forecast, nil
+}
+
+func (w Workload) demand(nodes int) Resources {
+	if w.PerNode {
+		return w.PerPod.Scale(float64(nodes))
+	}
+	return w.PerPod.Scale(float64(w.Replicas))
+}
+
+// liveWorkloads returns the workload controllers on the cluster by kind, namespace and name
+func (m *Manager) liveWorkloads() (map[string]Workload, error) {
+	output, err := m.List("deployments,statefulsets,replicasets,daemonsets,jobs", "", "")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []workloadDocument `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse workloads: %w", err)
+	}
+
+	live := make(map[string]Workload, len(list.Items))
+	for _, item := range list.Items {
+		if w, ok := item.workload(""); ok {
+			live[w.key()] = w
+		}
+	}
+	return live, nil
+}
+
+// PoolNodes returns the Ready nodes matching selector and the allocatable resources of
+// the largest of them
+func (m *Manager) PoolNodes(selector map[string]string) (int, Resources, error) {
+	output, err := m.List("nodes", "", labelSelector(selector))
+	if err != nil {
+		return 0, Resources{}, err
+	}
+	var list struct {
+		Items []struct {
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+				Conditions  []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return 0, Resources{}, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	ready := 0
+	var largest Resources
+	for _, item := range list.Items {
+		for _, condition := range item.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				ready++
+			}
+		}
+		if allocatable := parseResources(item.Status.Allocatable); allocatable.CPU > largest.CPU {
+			largest = allocatable
+		}
+	}
+	return ready, largest, nil
+}
+
+// WaitForPoolNodes waits until at least count nodes matching selector are Ready
+func (m *Manager) WaitForPoolNodes(selector map[string]string, count int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ready, _, err := m.PoolNodes(selector)
+		if err != nil {
+			return err
+		}
+		if ready >= count {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d of %d nodes ready after %s", ready, count, timeout)
+		}
+		logger.Debug("Waiting for node pool nodes").Int("ready", ready).Int("wanted", count).Send()
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// nodePoolLabels are the node labels naming the node pool on EKS, AKS and GKE
+var nodePoolLabels = map[string]string{
+	"aws":   "eks.amazonaws.com/nodegroup",
+	"azure": "kubernetes.azure.com/agentpool",
+	"gcp":   "cloud.google.com/gke-nodepool",
+}
+
+// NodePoolSelector returns the node selector of a managed node pool
+func NodePoolSelector(provider, pool string) map[string]string {
+	if label, ok := nodePoolLabels[strings.ToLower(provider)]; ok && pool != "" {
+		return map[string]string{label: pool}
+	}
+	return nil
+}
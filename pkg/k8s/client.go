@@ -0,0 +1,551 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// fieldManager is the server-side apply field manager owning what the installer applies,
+// installer-managed CRDs included
+const fieldManager = "e2e-k8s-installer"
+
+// pollInterval is how often the API is asked whether a deletion or CRD has completed
+const pollInterval = time.Second
+
+// APIError is a failed request to the Kubernetes API with the reason the API server gave,
+// so callers can tell a missing object or permission from an unreachable cluster
+type APIError struct {
+	Verb      string // get, list, apply, delete or logs
+	Resource  string
+	Namespace string
+	Name      string
+	Reason    string // status reason, e.g. NotFound, Forbidden or Unauthorized; empty when the server gave none
+	Code      int    // HTTP status code, 0 when unknown
+	Message   string
+}
+
+func (e *APIError) Error() string {
+	object := e.Resource
+	if e.Name != "" {
+		object += "/" + e.Name
+	}
+	if e.Namespace != "" {
+		object = e.Namespace + "/" + object
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("%s %s: %s (%s)", e.Verb, object, e.Message, e.Reason)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Verb, object, e.Message)
+}
+
+// IsNotFound reports whether err is an API error for an object that does not exist
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Reason == string(metav1.StatusReasonNotFound)
+}
+
+// IsForbidden reports whether err is an API error for a request the identity may not make
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Reason == string(metav1.StatusReasonForbidden)
+}
+
+// apiError converts a client-go failure to an APIError
+func apiError(verb, resource, namespace, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	apiErr := &APIError{Verb: verb, Resource: resource, Namespace: namespace, Name: name, Message: err.Error()}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		apiErr.Reason = string(status.Status().Reason)
+		apiErr.Code = int(status.Status().Code)
+		apiErr.Message = status.Status().Message
+	} else if meta.IsNoMatchError(err) {
+		apiErr.Reason = string(metav1.StatusReasonNotFound)
+	}
+	return apiErr
+}
+
+// apiClient talks to the API server directly, without kubectl
+type apiClient struct {
+	config    clientcmd.ClientConfig
+	rest      *rest.Config
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string // the namespace of the context, for namespaced objects given without one
+}
+
+// api returns the client of the Manager's kubeconfig and context, built on first use
+func (m *Manager) api() (*apiClient, error) {
+	m.clientOnce.Do(func() {
+		m.client, m.clientErr = newAPIClient(m.kubeconfig, m.config.Context, m.timeout)
+	})
+	return m.client, m.clientErr
+}
+
+// newAPIClient builds the client of a kubeconfig and context
+func newAPIClient(kubeconfig, context string, timeout time.Duration) (*apiClient, error) {
+	wrap, simulated := intercepted()
+	clientConfig := loadClientConfig(kubeconfig, context, "", simulated != nil)
+	restConfig, err := newRESTConfig(clientConfig, wrap, simulated)
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = timeout
+
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil || namespace == "" {
+		namespace = "default"
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	discovery := memory.NewMemCacheClient(clientset.Discovery())
+	return &apiClient{
+		config:    clientConfig,
+		rest:      restConfig,
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		mapper:    restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(discovery), discovery, nil),
+		namespace: namespace,
+	}, nil
+}
+
+// loadClientConfig loads the kubeconfig the way kubectl does, falling back to the pod's
+// ServiceAccount inside a cluster. A non-empty namespace overrides the context's. A
+// simulated cluster needs no kubeconfig.
+func loadClientConfig(kubeconfig, context, namespace string, simulated bool) clientcmd.ClientConfig {
+	if simulated {
+		return simulatedConfig(namespace)
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	overrides.Context.Namespace = namespace
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// newRESTConfig is the REST configuration of a client config, its requests intercepted
+// the way the next client's are
+func newRESTConfig(clientConfig clientcmd.ClientConfig, wrap func(next http.RoundTripper) http.RoundTripper, simulated http.RoundTripper) (*rest.Config, error) {
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	interceptConfig(restConfig, wrap, simulated)
+	restConfig.UserAgent = "e2e-k8s-installer/" + version.Version
+	return restConfig, nil
+}
+
+// resource resolves a resource the way kubectl names it, e.g. pods, deployment.apps, crd or
+// crontabs.v1.stable.example.com. Discovery is refreshed once when the name is unknown, it
+// may be a CRD applied since.
+func (c *apiClient) resource(name string) (schema.GroupVersionResource, bool, error) {
+	lookup := func() (schema.GroupVersionResource, error) {
+		fullySpecified, gr := schema.ParseResourceArg(name)
+		if fullySpecified != nil {
+			if gvr, err := c.mapper.ResourceFor(*fullySpecified); err == nil {
+				return gvr, nil
+			}
+		}
+		return c.mapper.ResourceFor(gr.WithVersion(""))
+	}
+	gvr, err := lookup()
+	if meta.IsNoMatchError(err) {
+		meta.MaybeResetRESTMapper(c.mapper)
+		gvr, err = lookup()
+	}
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	gvk, err := c.mapper.KindFor(gvr)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	return gvr, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// objects returns the client of a resource in namespace, or across namespaces when empty
+func (c *apiClient) objects(name, namespace string) (dynamic.ResourceInterface, error) {
+	gvr, namespaced, err := c.resource(name)
+	if err != nil {
+		return nil, err
+	}
+	if namespaced && namespace != "" {
+		return c.dynamic.Resource(gvr).Namespace(namespace), nil
+	}
+	return c.dynamic.Resource(gvr), nil
+}
+
+// List returns the objects of a resource as the JSON list `kubectl get -o json` prints,
+// in namespace or across all namespaces when it is empty, matching the label selector
+func (m *Manager) List(resource, namespace, selector string) ([]byte, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	// Several resources are listed together as a List, as kubectl does
+	merged := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+	resources := strings.Split(resource, ",")
+	for _, name := range resources {
+		objects, err := client.objects(name, namespace)
+		if err != nil {
+			return nil, apiError("list", name, namespace, "", err)
+		}
+		list, err := objects.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, apiError("list", name, namespace, "", err)
+		}
+		// Listed items carry no kind, kubectl fills it in
+		kind := strings.TrimSuffix(list.GetKind(), "List")
+		for i := range list.Items {
+			if list.Items[i].GetKind() == "" {
+				list.Items[i].SetKind(kind)
+				list.Items[i].SetAPIVersion(list.GetAPIVersion())
+			}
+		}
+		if len(resources) == 1 {
+			return list.MarshalJSON()
+		}
+		merged.Items = append(merged.Items, list.Items...)
+	}
+	return merged.MarshalJSON()
+}
+
+// Get returns an object as the JSON `kubectl get -o json` prints. An object that does not
+// exist is an APIError IsNotFound reports.
+func (m *Manager) Get(resource, namespace, name string) ([]byte, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := client.objects(resource, valueOrDefault(namespace, client.namespace))
+	if err != nil {
+		return nil, apiError("get", resource, namespace, name, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	object, err := objects.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, apiError("get", resource, namespace, name, err)
+	}
+	return object.MarshalJSON()
+}
+
+// Create creates the object of a JSON manifest and returns it as the API server answered,
+// e.g. the status of a review
+func (m *Manager) Create(manifest []byte) ([]byte, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	gvk := object.GroupVersionKind()
+	mapping, err := client.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, apiError("create", gvk.Kind, object.GetNamespace(), object.GetName(), err)
+	}
+	var objects dynamic.ResourceInterface = client.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		objects = client.dynamic.Resource(mapping.Resource).Namespace(valueOrDefault(object.GetNamespace(), client.namespace))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	created, err := objects.Create(ctx, object, metav1.CreateOptions{})
+	if err != nil {
+		return nil, apiError("create", mapping.Resource.Resource, object.GetNamespace(), object.GetName(), err)
+	}
+	return created.MarshalJSON()
+}
+
+// Delete deletes an object if it exists, waiting until it is gone when wait is set
+func (m *Manager) Delete(resource, namespace, name string, wait bool) error {
+	client, err := m.api()
+	if err != nil {
+		return err
+	}
+	objects, err := client.objects(resource, valueOrDefault(namespace, client.namespace))
+	if err != nil {
+		return apiError("delete", resource, namespace, name, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	// Background propagation removes the pods of Jobs and DaemonSets along with them
+	propagation := metav1.DeletePropagationBackground
+	if err := objects.Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return apiError("delete", resource, namespace, name, err)
+	}
+	for wait {
+		if _, err := objects.Get(ctx, name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return apiError("delete", resource, namespace, name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return apiError("delete", resource, namespace, name, fmt.Errorf("still present after %s", m.timeout))
+		case <-time.After(pollInterval):
+		}
+	}
+	return nil
+}
+
+// labelNamespace sets the labels of a namespace, removing those set to nil
+func (m *Manager) labelNamespace(namespace string, labels map[string]*string) error {
+	return m.patchMetadata(namespace, "labels", labels)
+}
+
+// annotateNamespace sets the annotations of a namespace, removing those set to nil
+func (m *Manager) annotateNamespace(namespace string, annotations map[string]*string) error {
+	return m.patchMetadata(namespace, "annotations", annotations)
+}
+
+// patchMetadata merges values into the labels or annotations of a namespace with a merge
+// patch, as kubectl label and annotate do
+func (m *Manager) patchMetadata(namespace, field string, values map[string]*string) error {
+	client, err := m.api()
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{field: values}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s patch: %w", field, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err = client.clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	return apiError("patch", "namespaces", "", namespace, err)
+}
+
+// apply applies every object of a YAML or JSON manifest with server-side apply, taking over
+// fields other managers own, and returns a line per object the way kubectl reports them
+func (c *apiClient) apply(manifest []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	var results []string
+	for {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return []byte(strings.Join(results, "\n")), fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(document) == 0 {
+			continue
+		}
+		object := &unstructured.Unstructured{Object: document}
+		gvk := object.GroupVersionKind()
+		mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			// The kind may come from a CRD applied earlier in the manifest
+			meta.MaybeResetRESTMapper(c.mapper)
+			mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+		if err != nil {
+			return []byte(strings.Join(results, "\n")), apiError("apply", gvk.Kind, object.GetNamespace(), object.GetName(), err)
+		}
+
+		var objects dynamic.ResourceInterface = c.dynamic.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			if object.GetNamespace() == "" {
+				object.SetNamespace(c.namespace)
+			}
+			objects = c.dynamic.Resource(mapping.Resource).Namespace(object.GetNamespace())
+		}
+		if _, err := objects.Apply(ctx, object.GetName(), object, metav1.ApplyOptions{FieldManager: fieldManager, Force: true}); err != nil {
+			return []byte(strings.Join(results, "\n")), apiError("apply", mapping.Resource.Resource, object.GetNamespace(), object.GetName(), err)
+		}
+		results = append(results, fmt.Sprintf("%s/%s serverside-applied", strings.ToLower(gvk.Kind), object.GetName()))
+	}
+	return []byte(strings.Join(results, "\n")), nil
+}
+
+// podLogs returns the last lines of every container of the newest pod matching selector,
+// as `kubectl logs --all-containers` prints them for a Job
+func (c *apiClient) podLogs(namespace, selector string, tail int, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, apiError("list", "pods", namespace, "", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, &APIError{Verb: "logs", Resource: "pods", Namespace: namespace, Reason: string(metav1.StatusReasonNotFound),
+			Message: fmt.Sprintf("no pods match %s", selector)}
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	pod := pods.Items[0]
+
+	lines := int64(tail)
+	var output bytes.Buffer
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		data, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container.Name, TailLines: &lines}).DoRaw(ctx)
+		if err != nil {
+			// Containers that never started have no logs
+			if apierrors.IsBadRequest(err) {
+				continue
+			}
+			return output.Bytes(), apiError("logs", "pods", namespace, pod.Name, err)
+		}
+		output.Write(data)
+	}
+	return output.Bytes(), nil
+}
+
+// APIVersions returns the group versions the API server serves, as `kubectl api-versions` prints them
+func (m *Manager) APIVersions() ([]string, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := client.clientset.Discovery().ServerGroups()
+	if err != nil {
+		return nil, apiError("list", "apiversions", "", "", err)
+	}
+	versions := metav1.ExtractGroupVersions(groups)
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// ServesResource reports whether the API server serves a resource of an API group in any version
+func (m *Manager) ServesResource(group, resource string) (bool, error) {
+	client, err := m.api()
+	if err != nil {
+		return false, err
+	}
+	_, err = client.mapper.ResourceFor(schema.GroupVersionResource{Group: group, Resource: resource})
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return err == nil, apiError("list", "apiresources", "", "", err)
+}
+
+// createToken mints a bound token for a ServiceAccount, as `kubectl create token` prints it
+func (c *apiClient) createToken(namespace, serviceAccount string, duration, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	seconds := int64(duration.Seconds())
+	request := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}}
+	token, err := c.clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, request, metav1.CreateOptions{})
+	if err != nil {
+		return nil, apiError("create", "serviceaccounts/token", namespace, serviceAccount, err)
+	}
+	return []byte(token.Status.Token), nil
+}
+
+// currentUser asks the API server who the client's credentials authenticate as
+func (c *apiClient) currentUser(timeout time.Duration) (*UserInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	review, err := c.clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, apiError("create", "selfsubjectreviews", "", "", err)
+	}
+	return &UserInfo{Username: review.Status.UserInfo.Username, Groups: review.Status.UserInfo.Groups}, nil
+}
+
+// currentCluster returns the connection details of the cluster the client talks to: the
+// cluster of the current context, or the cluster the pod runs in without a kubeconfig
+func (c *apiClient) currentCluster() (*ClusterInfo, error) {
+	name := InClusterContext
+	if raw, err := c.config.RawConfig(); err == nil {
+		if context, ok := raw.Contexts[raw.CurrentContext]; ok {
+			name = context.Cluster
+		}
+	}
+	caData := c.rest.CAData
+	if len(caData) == 0 && c.rest.CAFile != "" {
+		data, err := os.ReadFile(c.rest.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster certificate authority: %w", err)
+		}
+		caData = data
+	}
+	return &ClusterInfo{
+		Name:                     name,
+		Server:                   c.rest.Host,
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString(caData),
+		InsecureSkipTLSVerify:    c.rest.Insecure,
+	}, nil
+}
+
+// serverTime reads the API server's clock from a ConfigMap created in a server-side dry run
+func (c *apiClient) serverTime(namespace string) (time.Time, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	probe := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "e2e-k8s-installer-clock-probe", Namespace: namespace}}
+	sent := time.Now()
+	created, err := c.clientset.CoreV1().ConfigMaps(namespace).Create(ctx, probe, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	received := time.Now()
+	if err != nil {
+		return time.Time{}, time.Time{}, apiError("create", "configmaps", namespace, probe.Name, err)
+	}
+	return created.CreationTimestamp.Time, sent.Add(received.Sub(sent) / 2), nil
+}
+
+// serverVersion returns the version the API server reports
+func (c *apiClient) serverVersion() (string, error) {
+	info, err := c.clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", apiError("get", "version", "", "", err)
+	}
+	return info.GitVersion, nil
+}
+
+func valueOrDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,324 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// CRD is a CustomResourceDefinition shipped in a chart's crds/ directory
+type CRD struct {
+	Name     string       `json:"name"`
+	Group    string       `json:"group"`
+	Plural   string       `json:"plural"`
+	Versions []CRDVersion `json:"versions"`
+	Source   string       `json:"source"`
+	Manifest []byte       `json:"-"`
+}
+
+// CRDVersion is a version of a CRD
+type CRDVersion struct {
+	Name    string `json:"name"`
+	Served  bool   `json:"served"`
+	Storage bool   `json:"storage"`
+}
+
+// CRDBreakingChange is a version removed or no longer served while objects still use it
+type CRDBreakingChange struct {
+	CRD           string `json:"crd"`
+	Version       string `json:"version"`
+	Change        string `json:"change"`
+	StoredVersion bool   `json:"storedVersion"`
+	Objects       int    `json:"objects"`
+}
+
+// crdDocument is the subset of a CRD manifest used for extraction
+type crdDocument struct {
+	Kind     string `yaml:"kind" json:"kind"`
+	Metadata struct {
+		Name string `yaml:"name" json:"name"`
+	} `yaml:"metadata" json:"metadata"`
+	Spec struct {
+		Group string `yaml:"group" json:"group"`
+		Names struct {
+			Plural string `yaml:"plural" json:"plural"`
+		} `yaml:"names" json:"names"`
+		Versions []CRDVersion `yaml:"versions" json:"versions"`
+	} `yaml:"spec" json:"spec"`
+	Status struct {
+		StoredVersions []string `yaml:"storedVersions" json:"storedVersions"`
+	} `yaml:"status" json:"status"`
+}
+
+// ExtractCRDs reads the CRDs of a chart and its subcharts from their crds/ directories
+func ExtractCRDs(chartPath string) ([]CRD, error) {
+	var crds []CRD
+
+	err := filepath.WalkDir(chartPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Base(filepath.Dir(path)) != "crds" {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		found, err := parseCRDFile(path)
+		if err != nil {
+			return err
+		}
+		crds = append(crds, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract CRDs from %s: %w", chartPath, err)
+	}
+
+	sort.Slice(crds, func(i, j int) bool {
+		return crds[i].Name < crds[j].Name
+	})
+	return crds, nil
+}
+
+func parseCRDFile(path string) ([]CRD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var crds []CRD
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		var doc crdDocument
+		if err := node.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if doc.Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		manifest, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CRD %s: %w", doc.Metadata.Name, err)
+		}
+
+		crds = append(crds, CRD{
+			Name:     doc.Metadata.Name,
+			Group:    doc.Spec.Group,
+			Plural:   doc.Spec.Names.Plural,
+			Versions: doc.Spec.Versions,
+			Source:   path,
+			Manifest: manifest,
+		})
+	}
+	return crds, nil
+}
+
+// CheckCRDCompatibility compares a CRD with the cluster's copy and reports versions
+// that would be removed or stop being served while objects are stored in them
+func (m *Manager) CheckCRDCompatibility(crd CRD) ([]CRDBreakingChange, error) {
+	output, err := m.Get("customresourcedefinitions", "", crd.Name)
+	if IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var existing crdDocument
+	if err := json.Unmarshal(output, &existing); err != nil {
+		return nil, fmt.Errorf("failed to parse CRD %s: %w", crd.Name, err)
+	}
+
+	desired := make(map[string]CRDVersion, len(crd.Versions))
+	for _, version := range crd.Versions {
+		desired[version.Name] = version
+	}
+	stored := make(map[string]bool, len(existing.Status.StoredVersions))
+	for _, version := range existing.Status.StoredVersions {
+		stored[version] = true
+	}
+
+	var changes []CRDBreakingChange
+	for _, current := range existing.Spec.Versions {
+		if !current.Served {
+			continue
+		}
+
+		change := ""
+		if next, ok := desired[current.Name]; !ok {
+			change = "removed"
+		} else if !next.Served {
+			change = "no longer served"
+		} else {
+			continue
+		}
+
+		objects, err := m.countCustomResources(existing.Spec.Names.Plural, current.Name, existing.Spec.Group)
+		if err != nil {
+			return nil, err
+		}
+		if objects == 0 && !stored[current.Name] {
+			continue
+		}
+
+		changes = append(changes, CRDBreakingChange{
+			CRD:           crd.Name,
+			Version:       current.Name,
+			Change:        change,
+			StoredVersion: stored[current.Name],
+			Objects:       objects,
+		})
+	}
+	return changes, nil
+}
+
+func (m *Manager) countCustomResources(plural, version, group string) (int, error) {
+	output, err := m.List(fmt.Sprintf("%s.%s.%s", plural, version, group), "", "")
+	if err != nil {
+		return 0, err
+	}
+
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return 0, fmt.Errorf("failed to parse %s.%s: %w", plural, group, err)
+	}
+	return len(list.Items), nil
+}
+
+// ApplyCRDs installs or upgrades CRDs with server-side apply
+func (m *Manager) ApplyCRDs(crds []CRD) error {
+	if len(crds) == 0 {
+		return nil
+	}
+
+	var manifest bytes.Buffer
+	names := make([]string, 0, len(crds))
+	for _, crd := range crds {
+		manifest.WriteString("---\n")
+		manifest.Write(crd.Manifest)
+		names = append(names, crd.Name)
+	}
+
+	labelled, err := labels.Apply(manifest.Bytes(), labels.Global())
+	if err != nil {
+		return err
+	}
+
+	client, err := m.api()
+	if err != nil {
+		return err
+	}
+	output, err := client.apply(labelled, m.timeout)
+	audit.Record("crd.apply", m.config.Context, map[string]interface{}{
+		"crds": names,
+	}, err)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("CRDs applied").Int("count", len(crds)).Str("result", strings.TrimSpace(string(output))).Send()
+	return nil
+}
+
+// WaitForCRDs blocks until every CRD reports the Established condition
+func (m *Manager) WaitForCRDs(crds []CRD, timeout time.Duration) error {
+	if len(crds) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, crd := range crds {
+		for {
+			established, err := m.crdEstablished(crd.Name)
+			if err != nil {
+				return fmt.Errorf("CRDs not established: %w", err)
+			}
+			if established {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("CRDs not established: %s not established within %s", crd.Name, timeout)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// crdEstablished reports whether the API server serves the kinds of a CRD yet
+func (m *Manager) crdEstablished(name string) (bool, error) {
+	output, err := m.Get("customresourcedefinitions", "", name)
+	if err != nil {
+		return false, err
+	}
+	var crd struct {
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(output, &crd); err != nil {
+		return false, fmt.Errorf("failed to parse CRD %s: %w", name, err)
+	}
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == "Established" {
+			return condition.Status == "True", nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteCRDs removes CRDs, and with them every custom resource of their kinds
+func (m *Manager) DeleteCRDs(crds []CRD) error {
+	if len(crds) == 0 {
+		return nil
+	}
+
+	var errs []error
+	names := make([]string, 0, len(crds))
+	for _, crd := range crds {
+		errs = append(errs, m.Delete("customresourcedefinitions", "", crd.Name, true))
+		names = append(names, crd.Name)
+	}
+
+	err := errors.Join(errs...)
+	audit.Record("crd.delete", m.config.Context, map[string]interface{}{
+		"crds": names,
+	}, err)
+	return err
+}
@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Namespace freeze metadata. The label selects frozen namespaces for listing and for the
+// admission policy, the annotations say why and who froze it.
+const (
+	FrozenLabel              = "e2e-k8s-installer.io/frozen"
+	FreezeReasonAnnotation   = "e2e-k8s-installer.io/freeze-reason"
+	FrozenByAnnotation       = "e2e-k8s-installer.io/frozen-by"
+	FrozenAtAnnotation       = "e2e-k8s-installer.io/frozen-at"
+	FreezeOverrideAnnotation = "e2e-k8s-installer.io/freeze-override"
+)
+
+// FreezePolicyName names the ValidatingAdmissionPolicy and binding enforcing freezes
+const FreezePolicyName = "e2e-k8s-installer-namespace-freeze"
+
+// NamespaceFreeze is the freeze set on a namespace
+type NamespaceFreeze struct {
+	Namespace string    `json:"namespace"`
+	Reason    string    `json:"reason"`
+	By        string    `json:"by,omitempty"`
+	At        time.Time `json:"at,omitempty"`
+	Override  string    `json:"override,omitempty"` // reason of an override in progress
+}
+
+// namespaceList mirrors the subset of `kubectl get namespaces -o json` that is needed
+type namespaceList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// FreezeNamespace marks a namespace as frozen so the installer refuses to change it
+func (m *Manager) FreezeNamespace(freeze NamespaceFreeze) error {
+	if freeze.At.IsZero() {
+		freeze.At = time.Now().UTC()
+	}
+	at := freeze.At.Format(time.RFC3339)
+	err := m.annotateNamespace(freeze.Namespace, map[string]*string{
+		FreezeReasonAnnotation: &freeze.Reason,
+		FrozenByAnnotation:     &freeze.By,
+		FrozenAtAnnotation:     &at,
+	})
+	if err == nil {
+		frozen := "true"
+		err = m.labelNamespace(freeze.Namespace, map[string]*string{FrozenLabel: &frozen})
+	}
+	audit.Record("namespace.freeze", freeze.Namespace, map[string]interface{}{
+		"reason": freeze.Reason,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("failed to freeze namespace %s: %w", freeze.Namespace, err)
+	}
+
+	logger.Info("Namespace frozen").Str("namespace", freeze.Namespace).Str("reason", freeze.Reason).Send()
+	return nil
+}
+
+// UnfreezeNamespace lifts the freeze of a namespace
+func (m *Manager) UnfreezeNamespace(namespace string) error {
+	err := m.labelNamespace(namespace, map[string]*string{FrozenLabel: nil})
+	if err == nil {
+		err = m.annotateNamespace(namespace, map[string]*string{
+			FreezeReasonAnnotation: nil, FrozenByAnnotation: nil, FrozenAtAnnotation: nil, FreezeOverrideAnnotation: nil,
+		})
+	}
+	audit.Record("namespace.unfreeze", namespace, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to lift the freeze of namespace %s: %w", namespace, err)
+	}
+
+	logger.Info("Namespace freeze lifted").Str("namespace", namespace).Send()
+	return nil
+}
+
+// NamespaceFreezes returns the frozen namespaces among the given ones, or every frozen
+// namespace when none are given
+func (m *Manager) NamespaceFreezes(namespaces []string) ([]NamespaceFreeze, error) {
+	output, err := m.List("namespaces", "", FrozenLabel+"=true")
+	if err != nil {
+		return nil, err
+	}
+	var list namespaceList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse namespace list: %w", err)
+	}
+
+	wanted := make(map[string]bool)
+	for _, namespace := range namespaces {
+		wanted[namespace] = true
+	}
+	freezes := []NamespaceFreeze{}
+	for _, item := range list.Items {
+		if len(wanted) > 0 && !wanted[item.Metadata.Name] {
+			continue
+		}
+		annotations := item.Metadata.Annotations
+		freeze := NamespaceFreeze{
+			Namespace: item.Metadata.Name,
+			Reason:    annotations[FreezeReasonAnnotation],
+			By:        annotations[FrozenByAnnotation],
+			Override:  annotations[FreezeOverrideAnnotation],
+		}
+		freeze.At, _ = time.Parse(time.RFC3339, annotations[FrozenAtAnnotation])
+		freezes = append(freezes, freeze)
+	}
+	sort.Slice(freezes, func(i, j int) bool {
+		return freezes[i].Namespace < freezes[j].Namespace
+	})
+	return freezes, nil
+}
+
+// SetFreezeOverride lets changes through the admission policy of a frozen namespace until
+// ClearFreezeOverride is called
+func (m *Manager) SetFreezeOverride(namespace, reason string) error {
+	if err := m.annotateNamespace(namespace, map[string]*string{FreezeOverrideAnnotation: &reason}); err != nil {
+		return fmt.Errorf("failed to override the freeze of namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// ClearFreezeOverride ends an override set by SetFreezeOverride
+func (m *Manager) ClearFreezeOverride(namespace string) error {
+	if err := m.annotateNamespace(namespace, map[string]*string{FreezeOverrideAnnotation: nil}); err != nil {
+		return fmt.Errorf("failed to end the freeze override of namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// FreezePolicyInstalled reports whether the freeze admission policy is on the cluster
+func (m *Manager) FreezePolicyInstalled() bool {
+	_, err := m.Get("validatingadmissionpolicies", "", FreezePolicyName)
+	return err == nil
+}
+
+// FreezePolicyManifest renders a ValidatingAdmissionPolicy and binding that deny changes in
+// frozen namespaces to everyone, not just the installer, unless an override annotation is
+// set. Control plane components and nodes are exempt so running workloads keep healing,
+// and the namespace object itself stays writable so the freeze can be lifted.
+func FreezePolicyManifest() string {
+	var b strings.Builder
+
+	b.WriteString("apiVersion: admissionregistration.k8s.io/v1\n")
+	b.WriteString("kind: ValidatingAdmissionPolicy\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", FreezePolicyName)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: e2e-k8s-installer\n")
+	b.WriteString("spec:\n")
+	b.WriteString("  failurePolicy: Fail\n")
+	b.WriteString("  matchConstraints:\n")
+	b.WriteString("    resourceRules:\n")
+	b.WriteString("      - apiGroups: [\"*\"]\n")
+	b.WriteString("        apiVersions: [\"*\"]\n")
+	b.WriteString("        operations: [\"CREATE\", \"UPDATE\", \"DELETE\"]\n")
+	b.WriteString("        resources: [\"*\"]\n")
+	b.WriteString("    excludeResourceRules:\n")
+	b.WriteString("      - apiGroups: [\"\"]\n")
+	b.WriteString("        apiVersions: [\"*\"]\n")
+	b.WriteString("        operations: [\"*\"]\n")
+	b.WriteString("        resources: [\"namespaces\", \"events\"]\n")
+	b.WriteString("      - apiGroups: [\"events.k8s.io\", \"coordination.k8s.io\"]\n")
+	b.WriteString("        apiVersions: [\"*\"]\n")
+	b.WriteString("        operations: [\"*\"]\n")
+	b.WriteString("        resources: [\"*\"]\n")
+	b.WriteString("  matchConditions:\n")
+	b.WriteString("    - name: not-control-plane\n")
+	b.WriteString("      expression: >-\n")
+	b.WriteString("        !request.userInfo.username.startsWith('system:node:') &&\n")
+	b.WriteString("        !request.userInfo.username.startsWith('system:kube-') &&\n")
+	b.WriteString("        !request.userInfo.username.startsWith('system:serviceaccount:kube-system:')\n")
+	b.WriteString("  validations:\n")
+	b.WriteString("    - expression: >-\n")
+	fmt.Fprintf(&b, "        has(namespaceObject.metadata.annotations) && '%s' in namespaceObject.metadata.annotations\n", FreezeOverrideAnnotation)
+	b.WriteString("      messageExpression: >-\n")
+	fmt.Fprintf(&b, "        'namespace ' + namespaceObject.metadata.name + ' is frozen: ' + (has(namespaceObject.metadata.annotations) && '%s' in namespaceObject.metadata.annotations ? namespaceObject.metadata.annotations['%s'] : 'no reason given')\n",
+		FreezeReasonAnnotation, FreezeReasonAnnotation)
+	b.WriteString("      reason: Forbidden\n")
+	b.WriteString("---\n")
+
+	b.WriteString("apiVersion: admissionregistration.k8s.io/v1\n")
+	b.WriteString("kind: ValidatingAdmissionPolicyBinding\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", FreezePolicyName)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: e2e-k8s-installer\n")
+	b.WriteString("spec:\n")
+	fmt.Fprintf(&b, "  policyName: %s\n", FreezePolicyName)
+	b.WriteString("  validationActions: [\"Deny\"]\n")
+	b.WriteString("  matchResources:\n")
+	b.WriteString("    namespaceSelector:\n")
+	b.WriteString("      matchLabels:\n")
+	fmt.Fprintf(&b, "        %s: \"true\"\n", FrozenLabel)
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// restClientGetter hands the cluster of a kubeconfig and context to libraries building their
+// own clients, such as the Helm SDK
+type restClientGetter struct {
+	config clientcmd.ClientConfig
+
+	once      sync.Once
+	rest      *rest.Config
+	discovery discovery.CachedDiscoveryInterface
+	err       error
+}
+
+// NewRESTClientGetter returns the cluster of a kubeconfig and context, with namespace as the
+// default namespace, for the Helm SDK. Its clients go through the transport the installer's
+// own clients do, so simulated and recorded runs cover their requests too.
+func NewRESTClientGetter(kubeconfig, context, namespace string) genericclioptions.RESTClientGetter {
+	wrap, simulated := intercepted()
+	getter := &restClientGetter{config: loadClientConfig(kubeconfig, context, namespace, simulated != nil)}
+	getter.rest, getter.err = newRESTConfig(getter.config, wrap, simulated)
+	return getter
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return rest.CopyConfig(g.rest), nil
+}
+
+// ToDiscoveryClient returns a discovery client caching in memory, shared by the getter's
+// clients so the API is discovered once
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	g.once.Do(func() {
+		if g.err != nil {
+			return
+		}
+		var client *discovery.DiscoveryClient
+		client, g.err = discovery.NewDiscoveryClientForConfig(rest.CopyConfig(g.rest))
+		if g.err == nil {
+			g.discovery = memory.NewMemCacheClient(client)
+		}
+	})
+	return g.discovery, g.err
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	return restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(client), client, nil), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.config
+}
@@ -0,0 +1,277 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// HookAnnotation marks resources Helm runs as release hooks
+const HookAnnotation = "helm.sh/hook"
+
+// Hook job phases
+const (
+	HookPhaseRunning   = "Running"
+	HookPhaseSucceeded = "Succeeded"
+	HookPhaseFailed    = "Failed"
+)
+
+// HookJob is the observed state of a Helm hook Job
+type HookJob struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Hook      string    `json:"hook"`
+	Phase     string    `json:"phase"`
+	Active    int       `json:"active"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Created   time.Time `json:"created"`
+	LogTail   []string  `json:"logTail,omitempty"`
+}
+
+// jobItem is the subset of a kubectl Job used for hook detection
+type jobItem struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace"`
+		Labels            map[string]string `json:"labels"`
+		Annotations       map[string]string `json:"annotations"`
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+	} `json:"metadata"`
+	Status struct {
+		Active     int `json:"active"`
+		Succeeded  int `json:"succeeded"`
+		Failed     int `json:"failed"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+type jobList struct {
+	Items []jobItem `json:"items"`
+}
+
+// hookJob converts a Job to its observed hook state
+func (item jobItem) hookJob() HookJob {
+	job := HookJob{
+		Name:      item.Metadata.Name,
+		Namespace: item.Metadata.Namespace,
+		Hook:      item.Metadata.Annotations[HookAnnotation],
+		Phase:     HookPhaseRunning,
+		Active:    item.Status.Active,
+		Succeeded: item.Status.Succeeded,
+		Failed:    item.Status.Failed,
+		Created:   item.Metadata.CreationTimestamp,
+	}
+	for _, cond := range item.Status.Conditions {
+		if cond.Status != "True" {
+			continue
+		}
+		switch cond.Type {
+		case "Complete":
+			job.Phase = HookPhaseSucceeded
+		case "Failed":
+			job.Phase = HookPhaseFailed
+			job.Reason = cond.Reason
+			job.Message = cond.Message
+		}
+	}
+	return job
+}
+
+// HookJobs returns hook Jobs in the namespace belonging to the release.
+// Helm does not label hooks itself, so Jobs are matched on the chart's instance
+// labels and, failing that, on having been created since the given time.
+func (m *Manager) HookJobs(namespace, release string, since time.Time) ([]HookJob, error) {
+	output, err := m.List("jobs", namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var list jobList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs in %s: %w", namespace, err)
+	}
+
+	var jobs []HookJob
+	for _, item := range list.Items {
+		if _, ok := item.Metadata.Annotations[HookAnnotation]; !ok {
+			continue
+		}
+
+		labels := item.Metadata.Labels
+		owned := labels["app.kubernetes.io/instance"] == release ||
+			labels["release"] == release ||
+			item.Metadata.Annotations["meta.helm.sh/release-name"] == release
+		if !owned && item.Metadata.CreationTimestamp.Before(since) {
+			continue
+		}
+
+		jobs = append(jobs, item.hookJob())
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Created.Before(jobs[j].Created)
+	})
+	return jobs, nil
+}
+
+// JobLogs returns the last lines logged by the pods of a Job
+func (m *Manager) JobLogs(namespace, job string, tail int) ([]string, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.podLogs(namespace, "job-name="+job, tail, m.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// FailedHooks returns the failed hook Jobs of a release with their last log lines attached
+func (m *Manager) FailedHooks(namespace, release string, since time.Time, tail int) ([]HookJob, error) {
+	jobs, err := m.HookJobs(namespace, release, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []HookJob
+	for _, job := range jobs {
+		if job.Phase != HookPhaseFailed && job.Failed == 0 {
+			continue
+		}
+		logs, err := m.JobLogs(namespace, job.Name, tail)
+		if err != nil {
+			logger.Warn("Failed to read hook job logs").Str("job", job.Name).Err(err).Send()
+		}
+		job.LogTail = logs
+		failed = append(failed, job)
+	}
+	return failed, nil
+}
+
+// HookWatcher streams logs of hook Jobs into the deploy output while a release is installed
+type HookWatcher struct {
+	k8sMgr    *Manager
+	namespace string
+	release   string
+	since     time.Time
+	out       io.Writer
+	interval  time.Duration
+
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	seen    map[string]bool
+	started bool
+}
+
+// NewHookWatcher creates a watcher for hook Jobs of the release created after since
+func (m *Manager) NewHookWatcher(namespace, release string, since time.Time, out io.Writer) *HookWatcher {
+	return &HookWatcher{
+		k8sMgr:    m,
+		namespace: namespace,
+		release:   release,
+		since:     since,
+		out:       out,
+		interval:  2 * time.Second,
+		seen:      make(map[string]bool),
+	}
+}
+
+// Start begins polling for hook Jobs, following the logs of each new one
+func (w *HookWatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.started = true
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends polling and log streaming
+func (w *HookWatcher) Stop() {
+	if !w.started {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *HookWatcher) poll(ctx context.Context) {
+	jobs, err := w.k8sMgr.HookJobs(w.namespace, w.release, w.since)
+	if err != nil {
+		logger.Debug("Hook job poll failed").Str("release", w.release).Err(err).Send()
+		return
+	}
+
+	for _, job := range jobs {
+		w.mutex.Lock()
+		seen := w.seen[job.Name]
+		w.seen[job.Name] = true
+		w.mutex.Unlock()
+		if seen {
+			continue
+		}
+
+		logger.Info("Helm hook started").Str("release", w.release).Str("job", job.Name).Str("hook", job.Hook).Send()
+
+		w.wg.Add(1)
+		go func(job HookJob) {
+			defer w.wg.Done()
+			w.follow(ctx, job)
+		}(job)
+	}
+}
+
+func (w *HookWatcher) follow(ctx context.Context, job HookJob) {
+	reader, writer := io.Pipe()
+	go func() {
+		prefix := fmt.Sprintf("[hook %s/%s] ", job.Hook, job.Name)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			w.mutex.Lock()
+			fmt.Fprintln(w.out, prefix+scanner.Text())
+			w.mutex.Unlock()
+		}
+	}()
+
+	err := w.k8sMgr.Stream(ctx, writer, "logs", "-f", "job/"+job.Name, "-n", job.Namespace, "--all-containers", "--pod-running-timeout=2m")
+	writer.Close()
+	if err != nil {
+		logger.Debug("Hook log stream ended").Str("job", job.Name).Err(err).Send()
+	}
+}
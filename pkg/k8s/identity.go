@@ -0,0 +1,180 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// InstallerKubeconfigFile is where `setup rbac` writes the installer identity kubeconfig in
+// a workspace. When present it is used by every step on that workspace that has no explicit
+// kubeconfig configured.
+const InstallerKubeconfigFile = ".kube/installer.kubeconfig"
+
+// installerKubeconfig is the installer identity kubeconfig of the workspace of the run
+var installerKubeconfig = filepath.Join("workspace", InstallerKubeconfigFile)
+
+// UseWorkspace makes ResolveKubeconfig pick up the installer identity of workspace dir, and
+// of no other workspace
+func UseWorkspace(dir string) {
+	installerKubeconfig = filepath.Join(dir, InstallerKubeconfigFile)
+}
+
+// ClusterInfo holds the connection details of the current kubeconfig context
+type ClusterInfo struct {
+	Name                     string
+	Server                   string
+	CertificateAuthorityData string
+	InsecureSkipTLSVerify    bool
+}
+
+// UserInfo is who the API server authenticates the kubeconfig's credentials as
+type UserInfo struct {
+	Username string
+	Groups   []string
+}
+
+// InstallerIdentityManifests renders the namespace, ServiceAccount, ClusterRole and
+// ClusterRoleBinding for a dedicated installer identity scoped to the given steps
+func InstallerIdentityManifests(namespace, name string, steps []string) string {
+	var b strings.Builder
+
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: Namespace\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", namespace)
+	b.WriteString("---\n")
+
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: ServiceAccount\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	fmt.Fprintf(&b, "  namespace: %s\n", namespace)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: e2e-k8s-installer\n")
+	b.WriteString("---\n")
+
+	b.WriteString(GenerateClusterRole(name, steps))
+	b.WriteString("---\n")
+
+	b.WriteString("apiVersion: rbac.authorization.k8s.io/v1\n")
+	b.WriteString("kind: ClusterRoleBinding\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: e2e-k8s-installer\n")
+	b.WriteString("roleRef:\n")
+	b.WriteString("  apiGroup: rbac.authorization.k8s.io\n")
+	b.WriteString("  kind: ClusterRole\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	b.WriteString("subjects:\n")
+	b.WriteString("  - kind: ServiceAccount\n")
+	fmt.Fprintf(&b, "    name: %s\n", name)
+	fmt.Fprintf(&b, "    namespace: %s\n", namespace)
+
+	return b.String()
+}
+
+// ApplyManifest applies a YAML manifest to the cluster, labelled with the run's label policy
+func (m *Manager) ApplyManifest(manifest string) error {
+	labelled, err := labels.Apply([]byte(manifest), labels.Global())
+	if err != nil {
+		return err
+	}
+
+	client, err := m.api()
+	if err != nil {
+		return err
+	}
+	output, err := client.apply(labelled, m.timeout)
+	audit.Record("kubectl.apply", m.config.Context, map[string]interface{}{
+		"result": strings.TrimSpace(string(output)),
+	}, err)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Manifest applied").Str("result", strings.TrimSpace(string(output))).Send()
+	return nil
+}
+
+// CreateToken mints a bound token for a ServiceAccount
+func (m *Manager) CreateToken(namespace, serviceAccount string, duration time.Duration) (string, error) {
+	client, err := m.api()
+	if err != nil {
+		return "", err
+	}
+	output, err := client.createToken(namespace, serviceAccount, duration, m.timeout)
+	audit.Record("kubectl.create-token", namespace+"/"+serviceAccount, map[string]interface{}{
+		"duration": duration.String(),
+	}, err)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CurrentUser returns who the cluster knows the kubeconfig's user as, from a SelfSubjectReview
+func (m *Manager) CurrentUser() (*UserInfo, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	return client.currentUser(m.timeout)
+}
+
+// CurrentCluster returns the cluster connection details of the active context
+func (m *Manager) CurrentCluster() (*ClusterInfo, error) {
+	client, err := m.api()
+	if err != nil {
+		return nil, err
+	}
+	return client.currentCluster()
+}
+
+// WriteKubeconfig writes a kubeconfig that authenticates as the given ServiceAccount token
+func WriteKubeconfig(path string, cluster *ClusterInfo, namespace, user, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+
+	contextName := fmt.Sprintf("%s@%s", user, cluster.Name)
+
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: Config\n")
+	b.WriteString("clusters:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", cluster.Name)
+	b.WriteString("    cluster:\n")
+	fmt.Fprintf(&b, "      server: %s\n", cluster.Server)
+	if cluster.CertificateAuthorityData != "" {
+		fmt.Fprintf(&b, "      certificate-authority-data: %s\n", cluster.CertificateAuthorityData)
+	}
+	if cluster.InsecureSkipTLSVerify {
+		b.WriteString("      insecure-skip-tls-verify: true\n")
+	}
+	b.WriteString("users:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", user)
+	b.WriteString("    user:\n")
+	fmt.Fprintf(&b, "      token: %s\n", token)
+	b.WriteString("contexts:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", contextName)
+	b.WriteString("    context:\n")
+	fmt.Fprintf(&b, "      cluster: %s\n", cluster.Name)
+	fmt.Fprintf(&b, "      user: %s\n", user)
+	fmt.Fprintf(&b, "      namespace: %s\n", namespace)
+	fmt.Fprintf(&b, "current-context: %s\n", contextName)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+
+	return nil
+}
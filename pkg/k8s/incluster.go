@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceAccountDir is where Kubernetes mounts the ServiceAccount credentials of a pod
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// InClusterContext names the cluster, user and context of the in-cluster kubeconfig
+const InClusterContext = "in-cluster"
+
+// InCluster reports whether the process runs in a pod with ServiceAccount credentials mounted
+func InCluster() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" || os.Getenv("KUBERNETES_SERVICE_PORT") == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(serviceAccountDir, "token"))
+	return err == nil
+}
+
+// PodNamespace returns the namespace the pod runs in, default outside a pod
+func PodNamespace() string {
+	data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "default"
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteInClusterKubeconfig writes a kubeconfig for the cluster the pod runs in that authenticates
+// as the pod's ServiceAccount. The token is referenced by file rather than copied, so kubectl,
+// helm and terraform all pick up the kubelet's rotation of it during long installs.
+func WriteInClusterKubeconfig(path string) error {
+	if !InCluster() {
+		return fmt.Errorf("not running in a pod: no ServiceAccount token in %s", serviceAccountDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+
+	server := "https://" + net.JoinHostPort(os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"))
+
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: Config\n")
+	b.WriteString("clusters:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", InClusterContext)
+	b.WriteString("    cluster:\n")
+	fmt.Fprintf(&b, "      server: %s\n", server)
+	fmt.Fprintf(&b, "      certificate-authority: %s\n", filepath.Join(serviceAccountDir, "ca.crt"))
+	b.WriteString("users:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", InClusterContext)
+	b.WriteString("    user:\n")
+	fmt.Fprintf(&b, "      tokenFile: %s\n", filepath.Join(serviceAccountDir, "token"))
+	b.WriteString("contexts:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", InClusterContext)
+	b.WriteString("    context:\n")
+	fmt.Fprintf(&b, "      cluster: %s\n", InClusterContext)
+	fmt.Fprintf(&b, "      user: %s\n", InClusterContext)
+	fmt.Fprintf(&b, "      namespace: %s\n", PodNamespace())
+	fmt.Fprintf(&b, "current-context: %s\n", InClusterContext)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// PodExists reports whether a pod named name exists in namespace
+func (m *Manager) PodExists(namespace, name string) (bool, error) {
+	if _, err := m.Get("pods", namespace, name); IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to look up pod %s/%s: %w", namespace, name, err)
+	}
+	return true, nil
+}
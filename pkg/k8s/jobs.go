@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// JobSpec describes a one-off Job run by the installer
+type JobSpec struct {
+	Name           string
+	Namespace      string
+	Image          string
+	Command        []string
+	Env            map[string]string
+	ServiceAccount string
+	Labels         map[string]string
+	Limits         map[string]string // container resource limits, e.g. nvidia.com/gpu: "1"
+	NodeSelector   map[string]string
+	Tolerations    []string // taint keys tolerated with any value and effect
+}
+
+// RunJob creates a Job, waits for it to complete or fail, and returns its final state with the last log lines
+func (m *Manager) RunJob(spec JobSpec, timeout time.Duration, tail int) (*HookJob, error) {
+	manifest, err := jobManifest(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jobs are immutable, so replace any left over from a previous run
+	if err := m.Delete("jobs", spec.Namespace, spec.Name, true); err != nil {
+		return nil, err
+	}
+	if err := m.ApplyManifest(manifest); err != nil {
+		return nil, fmt.Errorf("failed to create job %s: %w", spec.Name, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := m.jobState(spec.Namespace, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if job.Phase != HookPhaseRunning || time.Now().After(deadline) {
+			logs, err := m.JobLogs(spec.Namespace, spec.Name, tail)
+			if err != nil {
+				logger.Warn("Failed to read job logs").Str("job", spec.Name).Err(err).Send()
+			}
+			job.LogTail = logs
+
+			switch {
+			case job.Phase == HookPhaseFailed:
+				return job, fmt.Errorf("job %s failed: %s", spec.Name, job.Reason)
+			case job.Phase == HookPhaseRunning:
+				return job, fmt.Errorf("job %s did not finish within %s", spec.Name, timeout)
+			}
+			return job, nil
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func (m *Manager) jobState(namespace, name string) (*HookJob, error) {
+	output, err := m.Get("jobs", namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var item jobItem
+	if err := json.Unmarshal(output, &item); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", name, err)
+	}
+
+	job := item.hookJob()
+	return &job, nil
+}
+
+func jobManifest(spec JobSpec) (string, error) {
+	keys := make([]string, 0, len(spec.Env))
+	for key := range spec.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, map[string]string{"name": key, "value": spec.Env[key]})
+	}
+
+	labels := map[string]string{"app.kubernetes.io/managed-by": "e2e-k8s-installer"}
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+
+	container := map[string]interface{}{
+		"name":    "hook",
+		"image":   spec.Image,
+		"command": spec.Command,
+		"env":     env,
+	}
+	if len(spec.Limits) > 0 {
+		container["resources"] = map[string]interface{}{"limits": spec.Limits}
+	}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+	}
+	if spec.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = spec.ServiceAccount
+	}
+	if len(spec.NodeSelector) > 0 {
+		podSpec["nodeSelector"] = spec.NodeSelector
+	}
+	if len(spec.Tolerations) > 0 {
+		tolerations := make([]map[string]string, 0, len(spec.Tolerations))
+		for _, key := range spec.Tolerations {
+			tolerations = append(tolerations, map[string]string{"key": key, "operator": "Exists"})
+		}
+		podSpec["tolerations"] = tolerations
+	}
+
+	job := map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]interface{}{
+			"name":      spec.Name,
+			"namespace": spec.Namespace,
+			"labels":    labels,
+		},
+		"spec": map[string]interface{}{
+			"backoffLimit": 0,
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job %s: %w", spec.Name, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,181 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+// Manager handles Kubernetes cluster operations. Objects are read, applied and deleted
+// through the API with client-go; kubectl is only needed for the commands that have no
+// API counterpart here, such as port-forward and following logs.
+type Manager struct {
+	config      *config.K8sConfig
+	kubectlPath string // empty when kubectl is not installed
+	kubeconfig  string
+	timeout     time.Duration
+
+	clientOnce sync.Once
+	client     *apiClient
+	clientErr  error
+}
+
+// NewManager creates a new Kubernetes manager
+func NewManager(k8sConfig *config.K8sConfig) (*Manager, error) {
+	if k8sConfig == nil {
+		return nil, fmt.Errorf("kubernetes configuration is required")
+	}
+
+	// kubectl is optional, the commands that need it say so
+	kubectlPath, _ := execx.LookPath("kubectl")
+
+	timeout := 5 * time.Minute
+	if k8sConfig.Timeout != "" {
+		if parsed, err := time.ParseDuration(k8sConfig.Timeout); err == nil {
+			timeout = parsed
+		}
+	}
+
+	return &Manager{
+		config:      k8sConfig,
+		kubectlPath: kubectlPath,
+		kubeconfig:  ResolveKubeconfig(k8sConfig),
+		timeout:     timeout,
+	}, nil
+}
+
+// ResolveKubeconfig returns the kubeconfig for cluster tools. When none is configured it
+// prefers the workload cluster provision-infra created, then the dedicated installer
+// identity, over the ambient kubeconfig.
+func ResolveKubeconfig(k8sConfig *config.K8sConfig) string {
+	kubeconfig := k8sConfig.ConfigPath
+	if kubeconfig == "" && k8sConfig.Context == "" {
+		if provisioned, ok := params.GetStore().GetString(params.KeyKubeconfig); ok {
+			if _, err := os.Stat(provisioned); err == nil {
+				logger.Debug("Using provisioned cluster kubeconfig").Str("path", provisioned).Send()
+				return provisioned
+			}
+		}
+		if _, err := os.Stat(installerKubeconfig); err == nil {
+			kubeconfig = installerKubeconfig
+			logger.Debug("Using installer identity kubeconfig").Str("path", kubeconfig).Send()
+		}
+	}
+	return kubeconfig
+}
+
+// Run executes a kubectl command and returns its standard output
+func (m *Manager) Run(args ...string) ([]byte, error) {
+	return m.RunWithInput(nil, args...)
+}
+
+// Kubeconfig returns the kubeconfig path used for commands, empty for the ambient default
+func (m *Manager) Kubeconfig() string {
+	return m.kubeconfig
+}
+
+// RunWithInput executes a kubectl command with the given data on standard input
+func (m *Manager) RunWithInput(input []byte, args ...string) ([]byte, error) {
+	if m.kubectlPath == "" {
+		return nil, errKubectlNotFound(args[0])
+	}
+	cmd := execx.Command(m.kubectlPath, append(m.globalArgs(), args...)...)
+	cmd.Tag = "kubectl " + args[0]
+	cmd.Timeout = m.timeout
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	output, err := cmd.Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// Stream runs a long-lived kubectl command, writing its output to w until it exits or ctx is cancelled
+func (m *Manager) Stream(ctx context.Context, w io.Writer, args ...string) error {
+	if m.kubectlPath == "" {
+		return errKubectlNotFound(args[0])
+	}
+	cmd := execx.Command(m.kubectlPath, append(m.globalArgs(), args...)...)
+	cmd.Tag = "kubectl " + args[0]
+	cmd.Stdout, cmd.Stderr = w, w
+
+	if _, err := cmd.Run(ctx); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("kubectl %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// GetResourcesJSON returns the JSON list of the given resource kinds in a namespace
+func (m *Manager) GetResourcesJSON(namespace string, kinds []string) ([]byte, error) {
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("at least one resource kind is required")
+	}
+
+	return m.List(strings.Join(kinds, ","), namespace, "")
+}
+
+// ServerVersion returns the API server version, e.g. v1.29.3-eks-adc7111
+func (m *Manager) ServerVersion() (string, error) {
+	client, err := m.api()
+	if err != nil {
+		return "", err
+	}
+	return client.serverVersion()
+}
+
+// ServerTime returns the API server's clock, read from the creationTimestamp it gives a
+// ConfigMap in a server-side dry run, and the local time half way through the request. The
+// server's time has a resolution of one second; nothing is created.
+func (m *Manager) ServerTime(namespace string) (time.Time, time.Time, error) {
+	client, err := m.api()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return client.serverTime(namespace)
+}
+
+// NodeCount returns the number of nodes registered with the cluster
+func (m *Manager) NodeCount() (int, error) {
+	output, err := m.List("nodes", "", "")
+	if err != nil {
+		return 0, err
+	}
+	var nodes struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(output, &nodes); err != nil {
+		return 0, fmt.Errorf("failed to parse node list: %w", err)
+	}
+	return len(nodes.Items), nil
+}
+
+// errKubectlNotFound is the failure of a command that needs kubectl when it is not installed
+func errKubectlNotFound(command string) error {
+	return fmt.Errorf("kubectl %s: kubectl not found in PATH, it is needed for this command", command)
+}
+
+// globalArgs returns kubeconfig and context flags applied to every command
+func (m *Manager) globalArgs() []string {
+	var args []string
+	if m.kubeconfig != "" {
+		args = append(args, "--kubeconfig", m.kubeconfig)
+	}
+	if m.config.Context != "" {
+		args = append(args, "--context", m.config.Context)
+	}
+	return args
+}
@@ -0,0 +1,88 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReleaseService is a port of a Service installed by a Helm release
+type ReleaseService struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Release   string `json:"release"`
+	Chart     string `json:"chart"`
+	PortName  string `json:"portName,omitempty"`
+	Port      int    `json:"port"`
+}
+
+// serviceList mirrors the subset of `kubectl get services -o json` that is needed
+type serviceList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec struct {
+			Type  string `json:"type"`
+			Ports []struct {
+				Name string `json:"name"`
+				Port int    `json:"port"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// ReleaseServices returns the ports of the Services Helm releases installed in a namespace,
+// or in every namespace when it is empty, sorted by namespace, release and service
+func (m *Manager) ReleaseServices(namespace string) ([]ReleaseService, error) {
+	output, err := m.List("services", namespace, "app.kubernetes.io/managed-by=Helm")
+	if err != nil {
+		return nil, err
+	}
+	var list serviceList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse service list: %w", err)
+	}
+
+	var services []ReleaseService
+	for _, item := range list.Items {
+		// ExternalName services point outside the cluster and cannot be forwarded
+		if item.Spec.Type == "ExternalName" {
+			continue
+		}
+		release := releaseName(item.Metadata.Labels, item.Metadata.Annotations)
+		for _, port := range item.Spec.Ports {
+			services = append(services, ReleaseService{
+				Namespace: item.Metadata.Namespace,
+				Service:   item.Metadata.Name,
+				Release:   release,
+				Chart:     item.Metadata.Labels["app.kubernetes.io/name"],
+				PortName:  port.Name,
+				Port:      port.Port,
+			})
+		}
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Release != b.Release {
+			return a.Release < b.Release
+		}
+		return a.Service < b.Service
+	})
+	return services, nil
+}
+
+// PortForward forwards address:local to a port of a Service until ctx is cancelled or the
+// forward breaks, writing kubectl's output to w
+func (m *Manager) PortForward(ctx context.Context, w io.Writer, service ReleaseService, address string, local int) error {
+	return m.Stream(ctx, w, "port-forward", "-n", service.Namespace, "svc/"+service.Service,
+		fmt.Sprintf("%d:%d", local, service.Port), "--address", address)
+}
@@ -0,0 +1,282 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// PrePullSpec describes the temporary DaemonSet that pulls images onto every node
+type PrePullSpec struct {
+	Name         string
+	Namespace    string
+	Images       []string
+	NodeSelector map[string]string
+	PullSecrets  []string
+}
+
+// NodePull is the pre-pull progress of one node
+type NodePull struct {
+	Node   string            `json:"node"`
+	Pulled int               `json:"pulled"`
+	Total  int               `json:"total"`
+	Failed map[string]string `json:"failed,omitempty"` // image to the kubelet's pull error
+}
+
+// Done reports whether every image on the node is pulled or failed to pull
+func (n NodePull) Done() bool {
+	return n.Pulled+len(n.Failed) >= n.Total
+}
+
+// pullFailures are the waiting reasons of containers whose image could not be pulled
+var pullFailures = map[string]bool{
+	"ErrImagePull":        true,
+	"ImagePullBackOff":    true,
+	"InvalidImageName":    true,
+	"ErrImageNeverPull":   true,
+	"RegistryUnavailable": true,
+}
+
+// PrePullImages runs a DaemonSet with one idle container per image, so the kubelet of
+// every selected node pulls them all, and reports each node's progress to onProgress
+// until every image is pulled or failed on every node, or the timeout passes. Containers
+// sleep when the image has a shell; images without one fail to start, which still means
+// they were pulled. The DaemonSet is deleted before returning.
+func (m *Manager) PrePullImages(spec PrePullSpec, timeout time.Duration, onProgress func([]NodePull)) ([]NodePull, error) {
+	manifest, err := prePullManifest(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ApplyManifest(manifest); err != nil {
+		return nil, fmt.Errorf("failed to create pre-pull daemonset %s: %w", spec.Name, err)
+	}
+	defer func() {
+		if err := m.Delete("daemonsets", spec.Namespace, spec.Name, false); err != nil {
+			logger.Warn("Failed to delete the pre-pull daemonset").Str("daemonset", spec.Name).Err(err).Send()
+		}
+	}()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		nodes, desired, err := m.prePullState(spec)
+		if err != nil {
+			return nil, err
+		}
+		onProgress(nodes)
+
+		done := desired > 0 && len(nodes) >= desired
+		for _, node := range nodes {
+			done = done && node.Done()
+		}
+		if done {
+			return nodes, nil
+		}
+		if time.Now().After(deadline) {
+			return nodes, fmt.Errorf("images were not pulled on every node within %s", timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// prePullState returns the progress of each scheduled pre-pull pod and the number of
+// nodes the DaemonSet should run on
+func (m *Manager) prePullState(spec PrePullSpec) ([]NodePull, int, error) {
+	output, err := m.Get("daemonsets", spec.Namespace, spec.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	var daemonSet struct {
+		Status struct {
+			DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(output, &daemonSet); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse pre-pull daemonset: %w", err)
+	}
+	desired := daemonSet.Status.DesiredNumberScheduled
+
+	output, err = m.List("pods", spec.Namespace, prePullLabel+"="+spec.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	var list struct {
+		Items []struct {
+			Spec struct {
+				NodeName string `json:"nodeName"`
+			} `json:"spec"`
+			Status struct {
+				ContainerStatuses []struct {
+					Name  string `json:"name"`
+					State struct {
+						Waiting *struct {
+							Reason  string `json:"reason"`
+							Message string `json:"message"`
+						} `json:"waiting"`
+					} `json:"state"`
+					LastState struct {
+						Terminated *struct{} `json:"terminated"`
+					} `json:"lastState"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse pre-pull pods: %w", err)
+	}
+
+	var nodes []NodePull
+	for _, pod := range list.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		node := NodePull{Node: pod.Spec.NodeName, Total: len(spec.Images)}
+		for _, status := range pod.Status.ContainerStatuses {
+			var index int
+			if _, err := fmt.Sscanf(status.Name, "pull-%d", &index); err != nil || index >= len(spec.Images) {
+				continue
+			}
+			waiting := status.State.Waiting
+			switch {
+			case waiting == nil || status.LastState.Terminated != nil:
+				node.Pulled++
+			case pullFailures[waiting.Reason]:
+				if node.Failed == nil {
+					node.Failed = make(map[string]string)
+				}
+				node.Failed[spec.Images[index]] = valueOrReason(waiting.Message, waiting.Reason)
+			case waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing":
+				// CrashLoopBackOff, CreateContainerError and the like come after the pull
+				node.Pulled++
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+	return nodes, desired, nil
+}
+
+func valueOrReason(message, reason string) string {
+	if message != "" {
+		return message
+	}
+	return reason
+}
+
+// prePullLabel selects the pods of a pre-pull DaemonSet
+const prePullLabel = "e2e-k8s-installer/pre-pull"
+
+func prePullManifest(spec PrePullSpec) (string, error) {
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "e2e-k8s-installer",
+		prePullLabel:                   spec.Name,
+	}
+
+	containers := make([]interface{}, 0, len(spec.Images))
+	for i, image := range spec.Images {
+		containers = append(containers, map[string]interface{}{
+			"name":            fmt.Sprintf("pull-%d", i),
+			"image":           image,
+			"imagePullPolicy": "IfNotPresent",
+			"command":         []string{"sh", "-c", "sleep 86400"},
+			"resources": map[string]interface{}{
+				"requests": map[string]string{"cpu": "1m", "memory": "8Mi"},
+				"limits":   map[string]string{"cpu": "10m", "memory": "32Mi"},
+			},
+		})
+	}
+
+	podSpec := map[string]interface{}{
+		"containers":                    containers,
+		"terminationGracePeriodSeconds": 0,
+		// reach every node workloads may land on, tainted ones included
+		"tolerations": []map[string]string{{"operator": "Exists"}},
+	}
+	if len(spec.NodeSelector) > 0 {
+		podSpec["nodeSelector"] = spec.NodeSelector
+	}
+	if len(spec.PullSecrets) > 0 {
+		secrets := make([]map[string]string, 0, len(spec.PullSecrets))
+		for _, name := range spec.PullSecrets {
+			secrets = append(secrets, map[string]string{"name": name})
+		}
+		podSpec["imagePullSecrets"] = secrets
+	}
+
+	daemonSet := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "DaemonSet",
+		"metadata": map[string]interface{}{
+			"name":      spec.Name,
+			"namespace": spec.Namespace,
+			"labels":    labels,
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]string{prePullLabel: spec.Name}},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+
+	data, err := json.Marshal(daemonSet)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal daemonset %s: %w", spec.Name, err)
+	}
+	return string(data), nil
+}
+
+// ManifestImages returns the distinct container images of a multi-document manifest,
+// from every containers, initContainers and ephemeralContainers list it holds
+func ManifestImages(manifest []byte) ([]string, error) {
+	seen := make(map[string]bool)
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		collectImages(doc, seen)
+	}
+
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func collectImages(node interface{}, seen map[string]bool) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if key == "containers" || key == "initContainers" || key == "ephemeralContainers" {
+				if list, ok := child.([]interface{}); ok {
+					for _, item := range list {
+						if container, ok := item.(map[string]interface{}); ok {
+							if image, ok := container["image"].(string); ok && image != "" {
+								seen[image] = true
+							}
+						}
+					}
+				}
+			}
+			collectImages(child, seen)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectImages(child, seen)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// PermissionRule describes verbs the installer needs on a set of resources
+type PermissionRule struct {
+	APIGroup      string
+	Resources     []string
+	Verbs         []string
+	ClusterScoped bool
+}
+
+// PermissionCatalog lists the permissions each installation step needs on the target cluster
+var PermissionCatalog = map[string][]PermissionRule{
+	"deploy": {
+		{APIGroup: "", Resources: []string{"namespaces"}, Verbs: []string{"get", "list", "create"}, ClusterScoped: true},
+		{APIGroup: "", Resources: []string{"configmaps", "secrets", "services", "serviceaccounts", "persistentvolumeclaims"}, Verbs: []string{"get", "list", "create", "update", "patch", "delete"}},
+		{APIGroup: "", Resources: []string{"pods", "events"}, Verbs: []string{"get", "list", "watch"}},
+		{APIGroup: "apps", Resources: []string{"deployments", "statefulsets", "daemonsets", "replicasets"}, Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"}},
+		{APIGroup: "batch", Resources: []string{"jobs", "cronjobs"}, Verbs: []string{"get", "list", "create", "update", "patch", "delete"}},
+		{APIGroup: "networking.k8s.io", Resources: []string{"ingresses", "networkpolicies"}, Verbs: []string{"get", "list", "create", "update", "patch", "delete"}},
+		{APIGroup: "policy", Resources: []string{"poddisruptionbudgets"}, Verbs: []string{"get", "list", "create", "update", "patch", "delete"}},
+		{APIGroup: "rbac.authorization.k8s.io", Resources: []string{"roles", "rolebindings"}, Verbs: []string{"get", "list", "create", "update", "patch", "delete"}},
+	},
+	"db-migrate": {
+		{APIGroup: "", Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list"}},
+		{APIGroup: "", Resources: []string{"secrets"}, Verbs: []string{"get"}},
+		{APIGroup: "batch", Resources: []string{"jobs"}, Verbs: []string{"get", "create", "delete"}},
+	},
+	"post-validate": {
+		{APIGroup: "", Resources: []string{"pods", "services", "endpoints", "events"}, Verbs: []string{"get", "list"}},
+		{APIGroup: "", Resources: []string{"pods/portforward"}, Verbs: []string{"create"}},
+		{APIGroup: "apps", Resources: []string{"deployments", "statefulsets", "daemonsets"}, Verbs: []string{"get", "list"}},
+	},
+	"e2e-test": {
+		{APIGroup: "", Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list", "create", "delete"}},
+		{APIGroup: "batch", Resources: []string{"jobs"}, Verbs: []string{"get", "create", "delete"}},
+	},
+}
+
+// ApprovalPermission is the permission approving an approval gate takes: the approve verb on
+// the approvalgates of the installer's API group, named after the gate. No API server serves
+// the resource, but Roles and ClusterRoles grant it like any other.
+var ApprovalPermission = PermissionRule{APIGroup: "e2e-k8s-installer.io", Resources: []string{"approvalgates"}, Verbs: []string{"approve"}}
+
+// PermissionCheck is the result of a single access review
+type PermissionCheck struct {
+	Step      string `json:"step"`
+	APIGroup  string `json:"apiGroup"`
+	Resource  string `json:"resource"`
+	Name      string `json:"name,omitempty"`
+	Verb      string `json:"verb"`
+	Namespace string `json:"namespace,omitempty"`
+	Allowed   bool   `json:"allowed"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// selfSubjectAccessReview mirrors the authorization.k8s.io/v1 object used for checks
+type selfSubjectAccessReview struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		ResourceAttributes struct {
+			Namespace   string `json:"namespace,omitempty"`
+			Verb        string `json:"verb"`
+			Group       string `json:"group"`
+			Resource    string `json:"resource"`
+			Subresource string `json:"subresource,omitempty"`
+			Name        string `json:"name,omitempty"`
+		} `json:"resourceAttributes"`
+	} `json:"spec"`
+	Status struct {
+		Allowed bool   `json:"allowed"`
+		Denied  bool   `json:"denied"`
+		Reason  string `json:"reason"`
+	} `json:"status"`
+}
+
+// RequiredRules returns the merged permission rules for the given steps
+func RequiredRules(steps []string) []PermissionRule {
+	type ruleKey struct {
+		group    string
+		resource string
+	}
+
+	verbs := make(map[ruleKey]map[string]bool)
+	clusterScoped := make(map[ruleKey]bool)
+	for _, step := range steps {
+		for _, rule := range PermissionCatalog[step] {
+			for _, resource := range rule.Resources {
+				key := ruleKey{rule.APIGroup, resource}
+				if verbs[key] == nil {
+					verbs[key] = make(map[string]bool)
+				}
+				for _, verb := range rule.Verbs {
+					verbs[key][verb] = true
+				}
+				clusterScoped[key] = clusterScoped[key] || rule.ClusterScoped
+			}
+		}
+	}
+
+	rules := make([]PermissionRule, 0, len(verbs))
+	for key, verbSet := range verbs {
+		rule := PermissionRule{
+			APIGroup:      key.group,
+			Resources:     []string{key.resource},
+			ClusterScoped: clusterScoped[key],
+		}
+		for verb := range verbSet {
+			rule.Verbs = append(rule.Verbs, verb)
+		}
+		sort.Strings(rule.Verbs)
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].APIGroup != rules[j].APIGroup {
+			return rules[i].APIGroup < rules[j].APIGroup
+		}
+		return rules[i].Resources[0] < rules[j].Resources[0]
+	})
+
+	return rules
+}
+
+// CheckPermissions runs a SelfSubjectAccessReview for every permission the given steps need
+func (m *Manager) CheckPermissions(steps []string, namespace string) ([]PermissionCheck, error) {
+	var checks []PermissionCheck
+
+	for _, step := range steps {
+		rules, ok := PermissionCatalog[step]
+		if !ok {
+			continue
+		}
+		for _, rule := range rules {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					check := PermissionCheck{
+						Step:     step,
+						APIGroup: rule.APIGroup,
+						Resource: resource,
+						Verb:     verb,
+					}
+					if !rule.ClusterScoped {
+						check.Namespace = namespace
+					}
+
+					allowed, reason, err := m.accessReview(check)
+					if err != nil {
+						return nil, fmt.Errorf("access review for %s %s failed: %w", verb, resource, err)
+					}
+					check.Allowed = allowed
+					check.Reason = reason
+					checks = append(checks, check)
+				}
+			}
+		}
+	}
+
+	logger.Info("RBAC preflight completed").
+		Int("checks", len(checks)).
+		Int("missing", len(MissingPermissions(checks))).
+		Send()
+
+	return checks, nil
+}
+
+// CanApprove runs a SelfSubjectAccessReview of ApprovalPermission on a gate in namespace,
+// returning whether the kubeconfig's user may approve it and the authorizer's reason
+func (m *Manager) CanApprove(gate, namespace string) (bool, string, error) {
+	return m.accessReview(PermissionCheck{
+		APIGroup:  ApprovalPermission.APIGroup,
+		Resource:  ApprovalPermission.Resources[0],
+		Name:      gate,
+		Verb:      ApprovalPermission.Verbs[0],
+		Namespace: namespace,
+	})
+}
+
+// MissingPermissions filters checks down to the denied ones
+func MissingPermissions(checks []PermissionCheck) []PermissionCheck {
+	var missing []PermissionCheck
+	for _, check := range checks {
+		if !check.Allowed {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+// GenerateClusterRole renders a ClusterRole manifest granting exactly the permissions the steps need
+func GenerateClusterRole(name string, steps []string) string {
+	var b strings.Builder
+
+	b.WriteString("apiVersion: rbac.authorization.k8s.io/v1\n")
+	b.WriteString("kind: ClusterRole\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: e2e-k8s-installer\n")
+	fmt.Fprintf(&b, "  annotations:\n    e2e-k8s-installer/steps: %q\n", strings.Join(steps, ","))
+	b.WriteString("rules:\n")
+
+	for _, rule := range RequiredRules(steps) {
+		fmt.Fprintf(&b, "  - apiGroups: [%q]\n", rule.APIGroup)
+		fmt.Fprintf(&b, "    resources: [%s]\n", quoteList(rule.Resources))
+		fmt.Fprintf(&b, "    verbs: [%s]\n", quoteList(rule.Verbs))
+	}
+
+	return b.String()
+}
+
+// accessReview submits a SelfSubjectAccessReview for a single permission
+func (m *Manager) accessReview(check PermissionCheck) (bool, string, error) {
+	review := selfSubjectAccessReview{
+		APIVersion: "authorization.k8s.io/v1",
+		Kind:       "SelfSubjectAccessReview",
+	}
+
+	attrs := &review.Spec.ResourceAttributes
+	attrs.Namespace = check.Namespace
+	attrs.Verb = check.Verb
+	attrs.Group = check.APIGroup
+	attrs.Resource = check.Resource
+	attrs.Name = check.Name
+	if resource, subresource, found := strings.Cut(check.Resource, "/"); found {
+		attrs.Resource = resource
+		attrs.Subresource = subresource
+	}
+
+	input, err := json.Marshal(review)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to marshal access review: %w", err)
+	}
+
+	output, err := m.Create(input)
+	if err != nil {
+		return false, "", err
+	}
+
+	var result selfSubjectAccessReview
+	if err := json.Unmarshal(output, &result); err != nil {
+		return false, "", fmt.Errorf("failed to parse access review response: %w", err)
+	}
+
+	return result.Status.Allowed, result.Status.Reason, nil
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return strings.Join(quoted, ", ")
+}
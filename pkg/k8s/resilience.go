@@ -0,0 +1,315 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// PodKillResult is the outcome of deleting one pod of a Deployment and waiting for it to be replaced
+type PodKillResult struct {
+	Deployment   string `json:"deployment"`
+	Namespace    string `json:"namespace"`
+	Replicas     int    `json:"replicas"`
+	PDB          string `json:"pdb,omitempty"`
+	Pod          string `json:"pod,omitempty"`
+	Replacement  string `json:"replacement,omitempty"`
+	RecoveryTime string `json:"recoveryTime,omitempty"`
+	MinReady     int    `json:"minReady"` // fewest ready pods seen while the pod was replaced
+	Probes       int    `json:"probes,omitempty"`
+	FailedProbes int    `json:"failedProbes,omitempty"`
+	Passed       bool   `json:"passed"`
+	Warning      string `json:"warning,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type deploymentItem struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+	Status struct {
+		ReadyReplicas int `json:"readyReplicas"`
+	} `json:"status"`
+}
+
+type podItem struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Labels            map[string]string `json:"labels"`
+		DeletionTimestamp *time.Time        `json:"deletionTimestamp"`
+	} `json:"metadata"`
+	Status struct {
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// ready reports whether the pod is Ready and not being deleted
+func (p podItem) ready() bool {
+	if p.Metadata.DeletionTimestamp != nil {
+		return false
+	}
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
+
+// DeploymentNames returns the Deployments in the namespace matching a label selector
+func (m *Manager) DeploymentNames(namespace, selector string) ([]string, error) {
+	output, err := m.List("deployments", namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment list: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	return names, nil
+}
+
+// PodKill deletes one ready pod of a Deployment and waits up to timeout for a replacement to
+// become ready, polling every interval. When url is set it is probed meanwhile, and any
+// failed probe fails the check; otherwise the service counts as unavailable when no ready
+// pod is left. Deployments with a single replica fail without a pod being deleted.
+func (m *Manager) PodKill(namespace, name, url string, timeout, interval time.Duration) PodKillResult {
+	result := PodKillResult{Deployment: name, Namespace: namespace}
+	fail := func(format string, args ...interface{}) PodKillResult {
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	output, err := m.Get("deployments", namespace, name)
+	if err != nil {
+		return fail("%v", err)
+	}
+	var deployment deploymentItem
+	if err := json.Unmarshal(output, &deployment); err != nil {
+		return fail("failed to parse deployment: %v", err)
+	}
+	result.Replicas = 1
+	if deployment.Spec.Replicas != nil {
+		result.Replicas = *deployment.Spec.Replicas
+	}
+	labels := deployment.Spec.Selector.MatchLabels
+	switch {
+	case len(labels) == 0:
+		return fail("the deployment selector has no matchLabels")
+	case result.Replicas < 2:
+		return fail("%d replica: losing its pod takes the service down", result.Replicas)
+	case deployment.Status.ReadyReplicas < result.Replicas:
+		return fail("only %d of %d replicas are ready before the test", deployment.Status.ReadyReplicas, result.Replicas)
+	}
+	selector := labelSelector(labels)
+
+	pods, err := m.pods(namespace, selector)
+	if err != nil {
+		return fail("%v", err)
+	}
+	existing := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		existing[pod.Metadata.Name] = true
+		if result.Pod == "" && pod.ready() {
+			result.Pod = pod.Metadata.Name
+		}
+	}
+	if result.Pod == "" {
+		return fail("no ready pod to delete")
+	}
+
+	result.PDB, err = m.coveringPDB(namespace, labels)
+	if err != nil {
+		logger.Warn("Failed to list PodDisruptionBudgets").Str("namespace", namespace).Err(err).Send()
+	} else if result.PDB == "" {
+		result.Warning = "no PodDisruptionBudget covers the pods, so node drains may evict them all"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var probes sync.WaitGroup
+	if url != "" {
+		probes.Add(1)
+		go func() {
+			defer probes.Done()
+			result.Probes, result.FailedProbes = probe(ctx, url, interval)
+		}()
+	}
+
+	logger.Info("Deleting pod to test resilience").
+		Str("deployment", name).
+		Str("namespace", namespace).
+		Str("pod", result.Pod).
+		Send()
+	start := time.Now()
+	if err := m.Delete("pods", namespace, result.Pod, false); err != nil {
+		cancel()
+		probes.Wait()
+		return fail("%v", err)
+	}
+
+	recovered, observed := false, false
+	for deadline := start.Add(timeout); time.Now().Before(deadline); time.Sleep(interval) {
+		pods, err := m.pods(namespace, selector)
+		if err != nil {
+			logger.Debug("Failed to list pods during recovery").Str("deployment", name).Err(err).Send()
+			continue
+		}
+		ready := 0
+		for _, pod := range pods {
+			if pod.ready() && pod.Metadata.Name != result.Pod {
+				ready++
+				if !existing[pod.Metadata.Name] {
+					result.Replacement = pod.Metadata.Name
+				}
+			}
+		}
+		if !observed || ready < result.MinReady {
+			result.MinReady, observed = ready, true
+		}
+		if ready >= result.Replicas {
+			recovered = true
+			break
+		}
+	}
+	elapsed := time.Since(start)
+	cancel()
+	probes.Wait()
+
+	if !recovered {
+		result.Replacement = ""
+		return fail("%d replicas were not ready again within %s", result.Replicas, timeout)
+	}
+	result.RecoveryTime = elapsed.Round(time.Millisecond).String()
+	switch {
+	case result.FailedProbes > 0:
+		return fail("%d of %d probes of %s failed while the pod was replaced", result.FailedProbes, result.Probes, url)
+	case url == "" && result.MinReady == 0:
+		return fail("no ready pod was left while the pod was replaced")
+	}
+	result.Passed = true
+	return result
+}
+
+// pods returns the pods matching a selector, sorted by name
+func (m *Manager) pods(namespace, selector string) ([]podItem, error) {
+	output, err := m.List("pods", namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []podItem `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods in %s: %w", namespace, err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name
+	})
+	return list.Items, nil
+}
+
+// coveringPDB returns the PodDisruptionBudget whose selector matches pods with the given labels
+func (m *Manager) coveringPDB(namespace string, labels map[string]string) (string, error) {
+	output, err := m.List("poddisruptionbudgets", namespace, "")
+	if err != nil {
+		return "", err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Selector struct {
+					MatchLabels map[string]string `json:"matchLabels"`
+				} `json:"selector"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return "", fmt.Errorf("failed to parse PodDisruptionBudgets in %s: %w", namespace, err)
+	}
+	for _, pdb := range list.Items {
+		match := len(pdb.Spec.Selector.MatchLabels) > 0
+		for key, value := range pdb.Spec.Selector.MatchLabels {
+			if labels[key] != value {
+				match = false
+			}
+		}
+		if match {
+			return pdb.Metadata.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// probe requests url every interval until ctx ends, counting transport errors and 5xx responses
+func probe(ctx context.Context, url string, interval time.Duration) (int, int) {
+	client := &http.Client{Timeout: interval + time.Second}
+	total, failed := 0, 0
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return 1, 1
+		}
+		resp, err := client.Do(req)
+		if ctx.Err() != nil {
+			return total, failed
+		}
+		total++
+		if err != nil {
+			failed++
+			logger.Debug("Resilience probe failed").Str("url", url).Err(err).Send()
+		} else {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				failed++
+				logger.Debug("Resilience probe failed").Str("url", url).Int("status", resp.StatusCode).Send()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, failed
+		case <-time.After(interval):
+		}
+	}
+}
+
+// labelSelector renders matchLabels as a kubectl -l selector
+func labelSelector(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
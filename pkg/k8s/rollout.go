@@ -0,0 +1,532 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RolloutBlocker is something that would stall a node pool rollout, or a workload it risks
+type RolloutBlocker struct {
+	Kind     string `json:"kind"` // pdb, capacity, anti-affinity, surge or unmanaged
+	Object   string `json:"object"`
+	Reason   string `json:"reason"`
+	Blocking bool   `json:"blocking"`
+}
+
+// RolloutSimulation is the outcome of simulating node drains before an upgrade
+type RolloutSimulation struct {
+	Nodes       int              `json:"nodes"` // schedulable nodes
+	Surge       int              `json:"surge"`
+	Unavailable int              `json:"unavailable"`
+	Blockers    []RolloutBlocker `json:"blockers,omitempty"`
+}
+
+// Blocking returns the blockers that would stall the rollout
+func (s *RolloutSimulation) Blocking() []RolloutBlocker {
+	var blocking []RolloutBlocker
+	for _, blocker := range s.Blockers {
+		if blocker.Blocking {
+			blocking = append(blocking, blocker)
+		}
+	}
+	return blocking
+}
+
+func (s *RolloutSimulation) add(kind, object string, blocking bool, format string, args ...interface{}) {
+	s.Blockers = append(s.Blockers, RolloutBlocker{Kind: kind, Object: object, Reason: fmt.Sprintf(format, args...), Blocking: blocking})
+}
+
+// Resources are CPU in millicores and memory in bytes
+type Resources struct {
+	CPU    float64 `json:"cpuMillis"`
+	Memory float64 `json:"memoryBytes"`
+}
+
+// Add returns the sum of r and o
+func (r Resources) Add(o Resources) Resources { return Resources{r.CPU + o.CPU, r.Memory + o.Memory} }
+
+// Sub returns r less o
+func (r Resources) Sub(o Resources) Resources { return Resources{r.CPU - o.CPU, r.Memory - o.Memory} }
+
+// Scale returns r multiplied by n
+func (r Resources) Scale(n float64) Resources { return Resources{r.CPU * n, r.Memory * n} }
+
+// Fits reports whether r fits within in
+func (r Resources) Fits(in Resources) bool { return r.CPU <= in.CPU && r.Memory <= in.Memory }
+
+func (r Resources) String() string {
+	return fmt.Sprintf("%.0fm CPU, %.0fMi memory", r.CPU, r.Memory/(1<<20))
+}
+
+type simNode struct {
+	name        string
+	labels      map[string]string
+	allocatable Resources
+	used        Resources
+	pods        []*simPod
+}
+
+func (n *simNode) free() Resources { return n.allocatable.Sub(n.used) }
+
+type simPod struct {
+	name      string
+	namespace string
+	labels    map[string]string
+	owner     string // Kind/name of the controller, empty for bare pods
+	requests  Resources
+	// spreadByHostname is set by required hostname anti-affinity against its own workload
+	spreadByHostname bool
+}
+
+type rolloutPod struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace"`
+		Labels          map[string]string `json:"labels"`
+		Annotations     map[string]string `json:"annotations"`
+		OwnerReferences []struct {
+			Kind       string `json:"kind"`
+			Name       string `json:"name"`
+			Controller bool   `json:"controller"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Resources struct {
+				Requests map[string]string `json:"requests"`
+			} `json:"resources"`
+		} `json:"containers"`
+		Affinity struct {
+			PodAntiAffinity struct {
+				Required []struct {
+					TopologyKey   string `json:"topologyKey"`
+					LabelSelector struct {
+						MatchLabels map[string]string `json:"matchLabels"`
+					} `json:"labelSelector"`
+				} `json:"requiredDuringSchedulingIgnoredDuringExecution"`
+			} `json:"podAntiAffinity"`
+		} `json:"affinity"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// SimulateNodeRollout checks whether node pools can roll with maxSurge new nodes and
+// maxUnavailable nodes drained at a time (node counts or percentages of the schedulable
+// nodes; one node unavailable when both are empty). The worst batch, the most loaded
+// nodes, is drained on paper: its pods must fit the free capacity of the remaining and
+// surge nodes, no PodDisruptionBudget may be out of allowed disruptions, and workloads with
+// required hostname anti-affinity need enough distinct nodes. Deployments must also have
+// room for their surge pods during rolling updates. Capacity is compared in aggregate with
+// the largest pod checked against the largest free node, not bin-packed.
+func (m *Manager) SimulateNodeRollout(maxSurge, maxUnavailable string) (*RolloutSimulation, error) {
+	nodes, err := m.simNodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.placePods(nodes); err != nil {
+		return nil, err
+	}
+
+	sim := &RolloutSimulation{Nodes: len(nodes)}
+	if sim.Surge, err = nodeCount(maxSurge, len(nodes)); err != nil {
+		return nil, fmt.Errorf("invalid max surge: %w", err)
+	}
+	if sim.Unavailable, err = nodeCount(maxUnavailable, len(nodes)); err != nil {
+		return nil, fmt.Errorf("invalid max unavailable: %w", err)
+	}
+	if maxSurge == "" && maxUnavailable == "" {
+		sim.Unavailable = 1
+	}
+	if len(nodes) == 0 {
+		sim.add("capacity", "cluster", true, "no schedulable nodes")
+		return sim, nil
+	}
+
+	// every node is drained eventually, and pods without a controller are not recreated
+	for _, node := range nodes {
+		for _, pod := range node.pods {
+			if pod.owner == "" {
+				sim.add("unmanaged", pod.namespace+"/"+pod.name, false, "has no controller, so draining %s deletes it for good", node.name)
+			}
+		}
+	}
+	if err := m.checkPDBs(sim); err != nil {
+		return nil, err
+	}
+	sim.checkDrainCapacity(nodes)
+	sim.checkAntiAffinity(nodes)
+	if err := m.checkSurgeHeadroom(sim, nodes); err != nil {
+		return nil, err
+	}
+	return sim, nil
+}
+
+// simNodes returns the schedulable nodes with their allocatable resources
+func (m *Manager) simNodes() ([]*simNode, error) {
+	output, err := m.List("nodes", "", "")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Unschedulable bool `json:"unschedulable"`
+			} `json:"spec"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	var nodes []*simNode
+	for _, item := range list.Items {
+		if item.Spec.Unschedulable {
+			continue
+		}
+		nodes = append(nodes, &simNode{
+			name:        item.Metadata.Name,
+			labels:      item.Metadata.Labels,
+			allocatable: parseResources(item.Status.Allocatable),
+		})
+	}
+	return nodes, nil
+}
+
+// placePods assigns running pods to their nodes, leaving out DaemonSet and static pods,
+// which are not evicted by a drain
+func (m *Manager) placePods(nodes []*simNode) error {
+	output, err := m.List("pods", "", "")
+	if err != nil {
+		return err
+	}
+	var list struct {
+		Items []rolloutPod `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return fmt.Errorf("failed to parse pods: %w", err)
+	}
+
+	byName := make(map[string]*simNode, len(nodes))
+	for _, node := range nodes {
+		byName[node.name] = node
+	}
+	for _, item := range list.Items {
+		node := byName[item.Spec.NodeName]
+		if node == nil || item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		requests := Resources{}
+		for _, container := range item.Spec.Containers {
+			requests = requests.Add(parseResources(container.Resources.Requests))
+		}
+		node.used = node.used.Add(requests)
+
+		pod := &simPod{
+			name:      item.Metadata.Name,
+			namespace: item.Metadata.Namespace,
+			labels:    item.Metadata.Labels,
+			requests:  requests,
+		}
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Controller {
+				pod.owner = owner.Kind + "/" + owner.Name
+			}
+		}
+		if strings.HasPrefix(pod.owner, "DaemonSet/") || item.Metadata.Annotations["kubernetes.io/config.mirror"] != "" {
+			continue
+		}
+		for _, term := range item.Spec.Affinity.PodAntiAffinity.Required {
+			if term.TopologyKey == "kubernetes.io/hostname" && selects(term.LabelSelector.MatchLabels, pod.labels) {
+				pod.spreadByHostname = true
+			}
+		}
+		node.pods = append(node.pods, pod)
+	}
+	return nil
+}
+
+// checkPDBs reports PodDisruptionBudgets that allow no disruption, which block every drain
+// of a node running one of their pods
+func (m *Manager) checkPDBs(sim *RolloutSimulation) error {
+	output, err := m.List("poddisruptionbudgets", "", "")
+	if err != nil {
+		return err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Status struct {
+				DisruptionsAllowed int `json:"disruptionsAllowed"`
+				CurrentHealthy     int `json:"currentHealthy"`
+				DesiredHealthy     int `json:"desiredHealthy"`
+				ExpectedPods       int `json:"expectedPods"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return fmt.Errorf("failed to parse PodDisruptionBudgets: %w", err)
+	}
+	for _, pdb := range list.Items {
+		status := pdb.Status
+		if status.ExpectedPods == 0 || status.DisruptionsAllowed > 0 {
+			continue
+		}
+		sim.add("pdb", pdb.Metadata.Namespace+"/"+pdb.Metadata.Name, true,
+			"allows no disruption (%d healthy, %d required): draining a node with one of its %d pods waits forever",
+			status.CurrentHealthy, status.DesiredHealthy, status.ExpectedPods)
+	}
+	return nil
+}
+
+// checkDrainCapacity drains the most loaded batch of nodes and checks that its pods fit
+// the remaining nodes plus the surge nodes, assumed the size of the largest drained node
+func (sim *RolloutSimulation) checkDrainCapacity(nodes []*simNode) {
+	batch := sim.Surge + sim.Unavailable
+	if batch < 1 {
+		batch = 1
+	}
+	if batch > len(nodes) {
+		batch = len(nodes)
+	}
+	sorted := append([]*simNode(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].used.CPU != sorted[j].used.CPU {
+			return sorted[i].used.CPU > sorted[j].used.CPU
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	drained, remaining := sorted[:batch], sorted[batch:]
+
+	var evicted Resources
+	var largestPod *simPod
+	var largestNode Resources
+	names := make([]string, 0, len(drained))
+	for _, node := range drained {
+		names = append(names, node.name)
+		if largestNode.CPU < node.allocatable.CPU {
+			largestNode = node.allocatable
+		}
+		for _, pod := range node.pods {
+			evicted = evicted.Add(pod.requests)
+			if largestPod == nil || pod.requests.CPU > largestPod.requests.CPU {
+				largestPod = pod
+			}
+		}
+	}
+
+	var free, largestFree Resources
+	for _, node := range remaining {
+		free = free.Add(node.free())
+		if node.free().CPU > largestFree.CPU {
+			largestFree = node.free()
+		}
+	}
+	for i := 0; i < sim.Surge; i++ {
+		free = free.Add(largestNode)
+		if largestNode.CPU > largestFree.CPU {
+			largestFree = largestNode
+		}
+	}
+
+	object := strings.Join(names, ", ")
+	switch {
+	case !evicted.Fits(free):
+		sim.add("capacity", object, true, "evicted pods request %s, but only %s is free on the other and surge nodes", evicted, free)
+	case largestPod != nil && !largestPod.requests.Fits(largestFree):
+		sim.add("capacity", largestPod.namespace+"/"+largestPod.name, true, "requests %s, more than any remaining node has free (%s)", largestPod.requests, largestFree)
+	}
+}
+
+// checkAntiAffinity reports workloads whose pods must each run on a different node but
+// have more replicas than nodes stay schedulable during the rollout
+func (sim *RolloutSimulation) checkAntiAffinity(nodes []*simNode) {
+	replicas := make(map[string]int)
+	for _, node := range nodes {
+		for _, pod := range node.pods {
+			if pod.spreadByHostname && pod.owner != "" {
+				kind, name, _ := strings.Cut(pod.owner, "/")
+				replicas[kind+" "+pod.namespace+"/"+name]++
+			}
+		}
+	}
+	available := len(nodes) - sim.Unavailable
+	if sim.Surge > 0 {
+		available = len(nodes) + sim.Surge - max(sim.Unavailable, 1)
+	}
+	for _, workload := range sortedKeys(replicas) {
+		if replicas[workload] > available {
+			sim.add("anti-affinity", workload, true,
+				"%d replicas need a node each, but only %d nodes are schedulable while nodes are drained", replicas[workload], available)
+		}
+	}
+}
+
+// checkSurgeHeadroom checks that the cluster has room for the surge pods of the largest
+// Deployment rolling update
+func (m *Manager) checkSurgeHeadroom(sim *RolloutSimulation, nodes []*simNode) error {
+	output, err := m.List("deployments", "", "")
+	if err != nil {
+		return err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Replicas *int `json:"replicas"`
+				Strategy struct {
+					Type          string `json:"type"`
+					RollingUpdate struct {
+						MaxSurge interface{} `json:"maxSurge"`
+					} `json:"rollingUpdate"`
+				} `json:"strategy"`
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Resources struct {
+								Requests map[string]string `json:"requests"`
+							} `json:"resources"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return fmt.Errorf("failed to parse deployments: %w", err)
+	}
+
+	var free Resources
+	for _, node := range nodes {
+		free = free.Add(node.free())
+	}
+	for _, deployment := range list.Items {
+		spec := deployment.Spec
+		if spec.Strategy.Type == "Recreate" {
+			continue
+		}
+		replicas := 1
+		if spec.Replicas != nil {
+			replicas = *spec.Replicas
+		}
+		surge := "25%"
+		switch value := spec.Strategy.RollingUpdate.MaxSurge.(type) {
+		case string:
+			surge = value
+		case float64:
+			surge = strconv.Itoa(int(value))
+		}
+		// Kubernetes rounds maxSurge percentages up
+		pods, err := podCount(surge, replicas, true)
+		if err != nil || pods == 0 {
+			continue
+		}
+		var perPod Resources
+		for _, container := range spec.Template.Spec.Containers {
+			perPod = perPod.Add(parseResources(container.Resources.Requests))
+		}
+		needed := perPod.Scale(float64(pods))
+		if !needed.Fits(free) {
+			sim.add("surge", deployment.Metadata.Namespace+"/"+deployment.Metadata.Name, true,
+				"a rolling update surges %d pods requesting %s, but only %s is free", pods, needed, free)
+		}
+	}
+	return nil
+}
+
+// nodeCount resolves a node count or a percentage of total, rounding percentages down
+func nodeCount(value string, total int) (int, error) {
+	return podCount(value, total, false)
+}
+
+func podCount(value string, total int, roundUp bool) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			return 0, err
+		}
+		count := float64(total) * p / 100
+		if roundUp {
+			return int(math.Ceil(count)), nil
+		}
+		return int(count), nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseResources reads the cpu and memory quantities of a resource list
+func parseResources(list map[string]string) Resources {
+	cpu, _ := parseQuantity(list["cpu"])
+	memory, _ := parseQuantity(list["memory"])
+	return Resources{CPU: cpu * 1000, Memory: memory}
+}
+
+// quantitySuffixes are the Kubernetes quantity suffixes
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"n", 1e-9}, {"u", 1e-6}, {"m", 1e-3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+// parseQuantity parses a Kubernetes quantity such as 500m, 2, 128Mi or 1G
+func parseQuantity(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for _, s := range quantitySuffixes {
+		if number, ok := strings.CutSuffix(value, s.suffix); ok {
+			parsed, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid quantity %q", value)
+			}
+			return parsed * s.multiplier, nil
+		}
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// selects reports whether matchLabels selects pods with labels
+func selects(matchLabels, labels map[string]string) bool {
+	if len(matchLabels) == 0 {
+		return false
+	}
+	for key, value := range matchLabels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"net/http"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// SimulatedContext names the cluster and context of the clients Simulate answers
+const SimulatedContext = "simulated"
+
+// SimulatedServer is the API server address of the clients Simulate answers; nothing is dialled
+const SimulatedServer = "https://kubernetes.simulated"
+
+var (
+	transportMu sync.Mutex
+	observer    func(next http.RoundTripper) http.RoundTripper
+	simulator   http.RoundTripper
+)
+
+// Observe wraps the transport of the API clients built from now on, under authentication,
+// e.g. to record the requests of a run; nil stops observing
+func Observe(wrap func(next http.RoundTripper) http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	observer = wrap
+}
+
+// Simulate answers the requests of the API clients built from now on with rt instead of the
+// cluster of the kubeconfig, which is not read then, for simulated and replayed runs; nil
+// talks to the cluster again
+func Simulate(rt http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	simulator = rt
+}
+
+// Simulated reports whether the API clients built from now on are answered by a simulator
+func Simulated() bool {
+	_, rt := intercepted()
+	return rt != nil
+}
+
+// intercepted returns the observer and simulator the next client is built with
+func intercepted() (func(next http.RoundTripper) http.RoundTripper, http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	return observer, simulator
+}
+
+// simulatedConfig is the client configuration of a simulated cluster: one context at
+// SimulatedServer, in namespace or else the default one
+func simulatedConfig(namespace string) clientcmd.ClientConfig {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[SimulatedContext] = &clientcmdapi.Cluster{Server: SimulatedServer}
+	config.AuthInfos[SimulatedContext] = &clientcmdapi.AuthInfo{}
+	config.Contexts[SimulatedContext] = &clientcmdapi.Context{Cluster: SimulatedContext, AuthInfo: SimulatedContext, Namespace: "default"}
+	config.CurrentContext = SimulatedContext
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.Context.Namespace = namespace
+	return clientcmd.NewDefaultClientConfig(*config, overrides)
+}
+
+// interceptConfig routes the requests of restConfig to the simulator, when one is given, and
+// through the observer. Their bodies are JSON rather than protobuf then, which the simulator
+// reads and a cassette shows.
+func interceptConfig(restConfig *rest.Config, wrap func(next http.RoundTripper) http.RoundTripper, rt http.RoundTripper) {
+	if rt == nil && wrap == nil {
+		return
+	}
+	restConfig.ContentType = runtime.ContentTypeJSON
+	restConfig.AcceptContentTypes = runtime.ContentTypeJSON
+	if rt != nil {
+		restConfig.Transport = rt
+		// Nothing to protect from load, a negative QPS turns client-side throttling off
+		restConfig.QPS = -1
+	}
+	if wrap != nil {
+		restConfig.Wrap(wrap)
+	}
+}
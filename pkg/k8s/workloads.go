@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// workloadKinds are the resources whose pods a Helm release runs, with their kinds. Items of
+// lists from the API server carry no kind of their own.
+var workloadKinds = []struct{ resource, kind string }{
+	{"deployments", "Deployment"}, {"statefulsets", "StatefulSet"}, {"daemonsets", "DaemonSet"},
+}
+
+// ReleaseWorkload is a Deployment, StatefulSet or DaemonSet of a Helm release with the containers
+// its pods run
+type ReleaseWorkload struct {
+	Kind       string           `json:"kind"`
+	Namespace  string           `json:"namespace"`
+	Name       string           `json:"name"`
+	Desired    int              `json:"desired"`
+	Ready      int              `json:"ready"`
+	Containers []ContainerState `json:"containers"`
+}
+
+// ContainerState is a container of a running pod
+type ContainerState struct {
+	Pod        string   `json:"pod"`
+	Name       string   `json:"name"`
+	Image      string   `json:"image"`
+	Digest     string   `json:"digest,omitempty"` // of the image the kubelet pulled, empty before it started
+	Privileged bool     `json:"privileged,omitempty"`
+	HostAccess []string `json:"hostAccess,omitempty"` // host namespaces the pod shares: network, pid, ipc
+}
+
+type workloadItem struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+	Status struct {
+		ReadyReplicas          int `json:"readyReplicas"`
+		DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		NumberReady            int `json:"numberReady"`
+	} `json:"status"`
+}
+
+type containerSpec struct {
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	SecurityContext struct {
+		Privileged *bool `json:"privileged"`
+	} `json:"securityContext"`
+}
+
+type containerStatus struct {
+	Name    string `json:"name"`
+	ImageID string `json:"imageID"`
+}
+
+type podStateItem struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		HostNetwork    bool            `json:"hostNetwork"`
+		HostPID        bool            `json:"hostPID"`
+		HostIPC        bool            `json:"hostIPC"`
+		InitContainers []containerSpec `json:"initContainers"`
+		Containers     []containerSpec `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+		ContainerStatuses     []containerStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// ReleaseWorkloads returns the workloads Helm installed for release in namespace, sorted by
+// kind and name
+func (m *Manager) ReleaseWorkloads(namespace, release string) ([]ReleaseWorkload, error) {
+	var workloads []ReleaseWorkload
+	for _, kind := range workloadKinds {
+		output, err := m.List(kind.resource, namespace, "app.kubernetes.io/managed-by=Helm")
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Items []workloadItem `json:"items"`
+		}
+		if err := json.Unmarshal(output, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s: %w", kind.resource, namespace, err)
+		}
+
+		for _, item := range list.Items {
+			if releaseName(item.Metadata.Labels, item.Metadata.Annotations) != release {
+				continue
+			}
+			workload := ReleaseWorkload{Kind: kind.kind, Namespace: namespace, Name: item.Metadata.Name}
+			switch kind.resource {
+			case "daemonsets":
+				workload.Desired, workload.Ready = item.Status.DesiredNumberScheduled, item.Status.NumberReady
+			default:
+				workload.Desired, workload.Ready = 1, item.Status.ReadyReplicas
+				if item.Spec.Replicas != nil {
+					workload.Desired = *item.Spec.Replicas
+				}
+			}
+			if len(item.Spec.Selector.MatchLabels) > 0 {
+				if workload.Containers, err = m.containerStates(namespace, labelSelector(item.Spec.Selector.MatchLabels)); err != nil {
+					return nil, err
+				}
+			}
+			workloads = append(workloads, workload)
+		}
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+	return workloads, nil
+}
+
+// containerStates returns the containers, init containers included, of the pods matching
+// selector
+func (m *Manager) containerStates(namespace, selector string) ([]ContainerState, error) {
+	output, err := m.List("pods", namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []podStateItem `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods in %s: %w", namespace, err)
+	}
+
+	var states []ContainerState
+	for _, pod := range list.Items {
+		var hostAccess []string
+		for name, shared := range map[string]bool{"ipc": pod.Spec.HostIPC, "network": pod.Spec.HostNetwork, "pid": pod.Spec.HostPID} {
+			if shared {
+				hostAccess = append(hostAccess, name)
+			}
+		}
+		sort.Strings(hostAccess)
+
+		digests := make(map[string]string)
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			digests[status.Name] = imageDigest(status.ImageID)
+		}
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			states = append(states, ContainerState{
+				Pod:        pod.Metadata.Name,
+				Name:       container.Name,
+				Image:      container.Image,
+				Digest:     digests[container.Name],
+				Privileged: container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged,
+				HostAccess: hostAccess,
+			})
+		}
+	}
+	return states, nil
+}
+
+// imageDigest extracts the digest from the image ID a container runtime reports, e.g.
+// docker-pullable://registry/app@sha256:...
+func imageDigest(imageID string) string {
+	if _, digest, ok := strings.Cut(imageID, "@"); ok {
+		return digest
+	}
+	if strings.HasPrefix(imageID, "sha256:") {
+		return imageID
+	}
+	return ""
+}
@@ -0,0 +1,308 @@
+package labels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// Label keys set from the policy's dedicated fields and the installer run
+const (
+	OwnerKey       = "owner"
+	EnvironmentKey = "environment"
+	RunIDKey       = "installer-run-id"
+)
+
+// DefaultTerraformVariable receives the labels as a map(string) Terraform variable
+const DefaultTerraformVariable = "default_tags"
+
+var (
+	labelNamePattern  = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	dnsSubdomain      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// podTemplatePaths locate the pod templates of workload kinds, whose pods are labelled too.
+// Templates only get the stable labels: a label changing every run would restart every
+// workload on each deploy, and the pod template of a Job cannot be changed at all.
+var podTemplatePaths = map[string][][]string{
+	"Deployment":  {{"spec", "template"}},
+	"StatefulSet": {{"spec", "template"}},
+	"DaemonSet":   {{"spec", "template"}},
+	"ReplicaSet":  {{"spec", "template"}},
+	"Job":         {{"spec", "template"}},
+	"CronJob":     {{"spec", "jobTemplate"}, {"spec", "jobTemplate", "spec", "template"}},
+}
+
+var (
+	globalLabels map[string]string
+	globalTFVar  = DefaultTerraformVariable
+	globalMutex  sync.RWMutex
+)
+
+// Resolve merges the policy's labels with owner, environment and the run ID, and validates them
+func Resolve(policy config.LabelPolicy, runID string) (map[string]string, error) {
+	resolved := make(map[string]string, len(policy.Labels)+3)
+	for key, value := range policy.Labels {
+		resolved[key] = value
+	}
+	if policy.Owner != "" {
+		resolved[OwnerKey] = policy.Owner
+	}
+	if policy.Environment != "" {
+		resolved[EnvironmentKey] = policy.Environment
+	}
+	if runID != "" {
+		resolved[RunIDKey] = runID
+	}
+
+	if err := Validate(resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// Validate checks labels against Kubernetes label syntax, which is also accepted by cloud tags
+func Validate(labels map[string]string) error {
+	for key, value := range labels {
+		name := key
+		if prefix, rest, ok := strings.Cut(key, "/"); ok {
+			if len(prefix) > 253 || !dnsSubdomain.MatchString(prefix) {
+				return fmt.Errorf("invalid label key %q: prefix must be a DNS subdomain", key)
+			}
+			name = rest
+		}
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid label key %q: name must be 63 characters or less, alphanumeric, '-', '_' or '.'", key)
+		}
+		if !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for label %s: must be 63 characters or less, alphanumeric, '-', '_' or '.'", value, key)
+		}
+	}
+	return nil
+}
+
+// InitGlobalPolicy resolves the policy for this run and makes it available to everything that creates resources
+func InitGlobalPolicy(policy config.LabelPolicy, runID string) error {
+	resolved, err := Resolve(policy, runID)
+	if err != nil {
+		return err
+	}
+
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	globalLabels = resolved
+	globalTFVar = DefaultTerraformVariable
+	if policy.TerraformVariable != "" {
+		globalTFVar = policy.TerraformVariable
+	}
+	return nil
+}
+
+// Global returns a copy of the labels for this run, empty if no policy was initialized
+func Global() map[string]string {
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+
+	labels := make(map[string]string, len(globalLabels))
+	for key, value := range globalLabels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// TerraformEnv passes the labels to Terraform as TF_VAR_<variable>. Terraform ignores
+// undeclared variables set through the environment, so modules opt in by declaring it.
+func TerraformEnv() []string {
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+
+	if len(globalLabels) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(globalLabels)
+	if err != nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("TF_VAR_%s=%s", globalTFVar, data)}
+}
+
+// Args formats labels as sorted key=value pairs
+func Args(labels map[string]string) []string {
+	args := make([]string, 0, len(labels))
+	for key, value := range labels {
+		args = append(args, key+"="+value)
+	}
+	sort.Strings(args)
+	return args
+}
+
+// Parse reads key=value pairs as produced by Args
+func Parse(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = value
+	}
+	return labels, Validate(labels)
+}
+
+// Apply adds the labels to every object in a multi-document manifest and, except the run ID,
+// to the pod templates of workloads. Labels an object already sets are left alone, since a
+// chart may rely on them in selectors that must keep matching.
+func Apply(manifest []byte, labels map[string]string) ([]byte, error) {
+	if len(labels) == 0 {
+		return manifest, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+
+		labelObject(doc.Content[0], labels)
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func labelObject(object *yaml.Node, labels map[string]string) {
+	kind := scalar(object, "kind")
+
+	// kubectl style lists carry their objects in items
+	if strings.HasSuffix(kind, "List") {
+		if items := child(object, "items"); items != nil && items.Kind == yaml.SequenceNode {
+			for _, item := range items.Content {
+				if item.Kind == yaml.MappingNode {
+					labelObject(item, labels)
+				}
+			}
+		}
+		return
+	}
+
+	addLabels(ensureMapping(object, "metadata"), labels)
+	paths := podTemplatePaths[kind]
+	if len(paths) == 0 {
+		return
+	}
+	stable := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if key != RunIDKey {
+			stable[key] = value
+		}
+	}
+	for _, path := range paths {
+		node := object
+		for _, key := range path {
+			if node = child(node, key); node == nil || node.Kind != yaml.MappingNode {
+				break
+			}
+		}
+		if node != nil && node.Kind == yaml.MappingNode {
+			addLabels(ensureMapping(node, "metadata"), stable)
+		}
+	}
+}
+
+func addLabels(metadata *yaml.Node, labels map[string]string) {
+	existing := ensureMapping(metadata, "labels")
+	for _, key := range sortedKeys(labels) {
+		if child(existing, key) != nil {
+			continue
+		}
+		existing.Content = append(existing.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: labels[key], Style: quoteStyle(labels[key])},
+		)
+	}
+}
+
+// quoteStyle quotes values YAML would otherwise read as numbers, booleans or null
+func quoteStyle(value string) yaml.Style {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(value), &decoded); err != nil {
+		return yaml.DoubleQuotedStyle
+	}
+	if _, ok := decoded.(string); ok {
+		return 0
+	}
+	return yaml.DoubleQuotedStyle
+}
+
+func child(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(mapping *yaml.Node, key string) string {
+	if node := child(mapping, key); node != nil && node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	return ""
+}
+
+// ensureMapping returns the mapping under key, creating it or replacing a null value.
+// Any other value is left untouched and a detached mapping is returned.
+func ensureMapping(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		switch {
+		case value.Kind == yaml.MappingNode:
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			*value = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		default:
+			return &yaml.Node{Kind: yaml.MappingNode}
+		}
+		return value
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
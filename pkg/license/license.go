@@ -0,0 +1,237 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Modules that a license can enable, named after the installer steps they gate
+const (
+	ModulePackagePull    = "package-pull"
+	ModuleProvisionInfra = "provision-infra"
+	ModuleDBMigrate      = "db-migrate"
+	ModuleDeploy         = "deploy"
+	ModulePostValidate   = "post-validate"
+	ModuleE2ETest        = "e2e-test"
+)
+
+// KeyEnv holds a license key when neither the configuration nor the workspace provides one
+const KeyEnv = "E2E_INSTALLER_LICENSE"
+
+// FileName is the license key file looked up in the workspace
+const FileName = "license.key"
+
+// ExpiryWarning is how long before expiry every run starts warning
+const ExpiryWarning = 30 * 24 * time.Hour
+
+// PublicKey is the base64 ed25519 key that signs licenses, set by vendor builds with -ldflags "-X".
+// Builds without a key do not enforce licensing. Unlike the release key it has no environment
+// override, which would let anyone sign their own license.
+var PublicKey string
+
+// License is the signed payload of a license key
+type License struct {
+	ID           string       `json:"id"`
+	Customer     string       `json:"customer"`
+	Issued       time.Time    `json:"issued"`
+	Expires      time.Time    `json:"expires"`
+	Entitlements Entitlements `json:"entitlements"`
+}
+
+// Entitlements limit what a license allows; zero values mean unlimited
+type Entitlements struct {
+	MaxNodes int      `json:"maxNodes,omitempty"`
+	Modules  []string `json:"modules,omitempty"` // all modules when empty
+}
+
+// Info is the license summary embedded in reports, without the key itself
+type Info struct {
+	ID       string    `json:"id"`
+	Customer string    `json:"customer"`
+	Expires  time.Time `json:"expires"`
+	DaysLeft int       `json:"daysLeft"`
+	MaxNodes int       `json:"maxNodes,omitempty"`
+	Modules  []string  `json:"modules,omitempty"`
+}
+
+var (
+	current     *License
+	currentOnce sync.Once
+	currentErr  error
+)
+
+// Enforced reports whether this build checks licenses
+func Enforced() bool {
+	return PublicKey != ""
+}
+
+// InitGlobal loads and verifies the license once per process so every command and report sees
+// the same one. It returns nil without error when the build does not enforce licensing.
+func InitGlobal(cfg config.LicenseConfig, workspace string) (*License, error) {
+	currentOnce.Do(func() {
+		if !Enforced() {
+			return
+		}
+		current, currentErr = Load(cfg, workspace)
+		if currentErr == nil {
+			current.WarnIfExpiring()
+		}
+	})
+	return current, currentErr
+}
+
+// Current returns the license loaded by InitGlobal, nil when none was loaded
+func Current() *License {
+	return current
+}
+
+// Require fails when the loaded license does not enable module
+func Require(module string) error {
+	if currentErr != nil {
+		return currentErr
+	}
+	return current.Allows(module)
+}
+
+// ReportInfo returns the summary of the loaded license for reports, nil when none was loaded
+func ReportInfo() *Info {
+	if current == nil {
+		return nil
+	}
+	return current.Info()
+}
+
+// Load reads the license key from the configuration, the KeyEnv variable or the workspace
+// license file, in that order, and verifies its signature and expiry
+func Load(cfg config.LicenseConfig, workspace string) (*License, error) {
+	key, source, err := readKey(cfg, workspace)
+	if err != nil {
+		return nil, err
+	}
+	license, err := Parse(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid license from %s: %w", source, err)
+	}
+	if time.Now().After(license.Expires) {
+		return nil, fmt.Errorf("license %s for %s expired on %s; contact the vendor to renew it",
+			license.ID, license.Customer, license.Expires.Format("2006-01-02"))
+	}
+	logger.Debug("License verified").Str("id", license.ID).Str("source", source).Send()
+	return license, nil
+}
+
+// Parse verifies a <payload>.<signature> license key, both parts base64url encoded, and returns
+// its payload. Verification is offline, against the vendor key built into the installer.
+func Parse(key string) (*License, error) {
+	publicKey, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("license signing key in this build is not a base64 ed25519 public key")
+	}
+
+	payload, signature, ok := strings.Cut(strings.TrimSpace(key), ".")
+	if !ok {
+		return nil, fmt.Errorf("license key is malformed")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("license key is malformed: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("license signature is malformed: %w", err)
+	}
+	if !ed25519.Verify(publicKey, data, sig) {
+		return nil, fmt.Errorf("license signature does not match the vendor key")
+	}
+
+	var license License
+	if err := json.Unmarshal(data, &license); err != nil {
+		return nil, fmt.Errorf("failed to parse license: %w", err)
+	}
+	if license.Expires.IsZero() {
+		return nil, fmt.Errorf("license has no expiry date")
+	}
+	return &license, nil
+}
+
+func readKey(cfg config.LicenseConfig, workspace string) (string, string, error) {
+	if cfg.Key != "" {
+		return cfg.Key, "installer.license.key", nil
+	}
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read license file: %w", err)
+		}
+		return string(data), cfg.KeyFile, nil
+	}
+	if key := os.Getenv(KeyEnv); key != "" {
+		return key, KeyEnv, nil
+	}
+	path := filepath.Join(workspace, FileName)
+	if data, err := os.ReadFile(path); err == nil {
+		return string(data), path, nil
+	}
+	return "", "", fmt.Errorf("a license is required: set installer.license.keyFile, %s, or place the key in %s", KeyEnv, path)
+}
+
+// Allows fails when the license does not enable module. A nil license allows everything.
+func (l *License) Allows(module string) error {
+	if l == nil || len(l.Entitlements.Modules) == 0 {
+		return nil
+	}
+	for _, enabled := range l.Entitlements.Modules {
+		if enabled == module {
+			return nil
+		}
+	}
+	return fmt.Errorf("license %s does not include the %s module (licensed: %s)",
+		l.ID, module, strings.Join(l.Entitlements.Modules, ", "))
+}
+
+// AllowsNodes fails when a cluster of nodes nodes exceeds the licensed maximum
+func (l *License) AllowsNodes(nodes int) error {
+	if l == nil || l.Entitlements.MaxNodes == 0 || nodes <= l.Entitlements.MaxNodes {
+		return nil
+	}
+	return fmt.Errorf("cluster has %d nodes but license %s allows %d", nodes, l.ID, l.Entitlements.MaxNodes)
+}
+
+// DaysLeft is the number of whole days until the license expires
+func (l *License) DaysLeft() int {
+	return int(time.Until(l.Expires).Hours() / 24)
+}
+
+// WarnIfExpiring logs a warning once the license is within ExpiryWarning of expiring
+func (l *License) WarnIfExpiring() {
+	if time.Until(l.Expires) > ExpiryWarning {
+		return
+	}
+	logger.Warn("License expires soon, contact the vendor to renew it").
+		Str("id", l.ID).
+		Str("expires", l.Expires.Format("2006-01-02")).
+		Int("days_left", l.DaysLeft()).
+		Send()
+}
+
+// Info summarizes the license for reports
+func (l *License) Info() *Info {
+	return &Info{
+		ID:       l.ID,
+		Customer: l.Customer,
+		Expires:  l.Expires,
+		DaysLeft: l.DaysLeft(),
+		MaxNodes: l.Entitlements.MaxNodes,
+		Modules:  l.Entitlements.Modules,
+	}
+}
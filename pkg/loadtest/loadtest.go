@@ -0,0 +1,353 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Engines
+const (
+	EngineBuiltin = "builtin"
+	EngineK6      = "k6"
+)
+
+// Result summarises a load test run and its threshold checks
+type Result struct {
+	Engine       string   `json:"engine"`
+	VirtualUsers int      `json:"virtualUsers"`
+	Duration     string   `json:"duration"`
+	Requests     int      `json:"requests"`
+	Failed       int      `json:"failed"`
+	ErrorRate    float64  `json:"errorRate"`
+	RPS          float64  `json:"rps"`
+	P50          string   `json:"p50"`
+	P95          string   `json:"p95"`
+	P99          string   `json:"p99"`
+	Breaches     []string `json:"breaches,omitempty"`
+	Passed       bool     `json:"passed"`
+
+	p95, p99 time.Duration
+}
+
+// Settings resolves the defaults of a load gate configuration
+func Settings(gate config.LoadGate) (string, int, time.Duration, error) {
+	engine := gate.Engine
+	if engine == "" {
+		engine = EngineBuiltin
+	}
+	users := gate.VirtualUsers
+	if users == 0 {
+		users = 10
+	}
+	duration := 30 * time.Second
+	if gate.Duration != "" {
+		parsed, err := time.ParseDuration(gate.Duration)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("invalid load test duration: %w", err)
+		}
+		duration = parsed
+	}
+	if len(gate.Endpoints) == 0 && (engine == EngineBuiltin || gate.Script == "") {
+		return "", 0, 0, fmt.Errorf("the load gate needs endpoints or, with k6, a script")
+	}
+	return engine, users, duration, nil
+}
+
+// Run executes the load test with the configured engine and checks the thresholds
+func Run(ctx context.Context, gate config.LoadGate, workDir string) (*Result, error) {
+	engine, users, duration, err := Settings(gate)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Starting load test").
+		Str("engine", engine).
+		Int("virtual_users", users).
+		Dur("duration", duration).
+		Int("endpoints", len(gate.Endpoints)).
+		Send()
+
+	var result *Result
+	switch engine {
+	case EngineK6:
+		result, err = runK6(ctx, gate, users, duration, workDir)
+	default:
+		result = runBuiltin(ctx, gate.Endpoints, users, duration)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Engine = engine
+	result.VirtualUsers = users
+
+	if err := result.check(gate.Thresholds); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// check records every exceeded threshold
+func (r *Result) check(thresholds config.LoadThresholds) error {
+	if thresholds.MaxErrorRate > 0 && r.ErrorRate > thresholds.MaxErrorRate {
+		r.Breaches = append(r.Breaches, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", r.ErrorRate*100, thresholds.MaxErrorRate*100))
+	}
+	for _, limit := range []struct {
+		name     string
+		value    string
+		observed time.Duration
+	}{
+		{"p95", thresholds.P95Latency, r.p95},
+		{"p99", thresholds.P99Latency, r.p99},
+	} {
+		if limit.value == "" {
+			continue
+		}
+		maximum, err := time.ParseDuration(limit.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s latency threshold: %w", limit.name, err)
+		}
+		if limit.observed > maximum {
+			r.Breaches = append(r.Breaches, fmt.Sprintf("%s latency %s exceeds %s", limit.name, round(limit.observed), maximum))
+		}
+	}
+	if thresholds.MinRPS > 0 && r.RPS < thresholds.MinRPS {
+		r.Breaches = append(r.Breaches, fmt.Sprintf("throughput %.1f req/s is below %.1f", r.RPS, thresholds.MinRPS))
+	}
+	r.Passed = len(r.Breaches) == 0
+	return nil
+}
+
+// runBuiltin has each virtual user send the endpoints' requests in turn until duration ends.
+// Transport errors and 4xx or 5xx responses count as failed requests, as k6 counts them.
+func runBuiltin(ctx context.Context, endpoints []config.LoadEndpoint, users int, duration time.Duration) *Result {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: users},
+	}
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failed    int
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+
+	for user := 0; user < users; user++ {
+		wg.Add(1)
+		go func(user int) {
+			defer wg.Done()
+			for i := user; ctx.Err() == nil; i++ {
+				endpoint := endpoints[i%len(endpoints)]
+				latency, ok := send(ctx, client, endpoint)
+				if ctx.Err() != nil {
+					// The request was cut off by the end of the test, not by the server
+					return
+				}
+				mu.Lock()
+				latencies = append(latencies, latency)
+				if !ok {
+					failed++
+				}
+				mu.Unlock()
+			}
+		}(user)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result := &Result{
+		Duration: round(elapsed),
+		Requests: len(latencies),
+		Failed:   failed,
+		p95:      percentile(latencies, 0.95),
+		p99:      percentile(latencies, 0.99),
+	}
+	if result.Requests > 0 {
+		result.ErrorRate = float64(failed) / float64(result.Requests)
+		result.RPS = float64(result.Requests) / elapsed.Seconds()
+	}
+	result.P50 = round(percentile(latencies, 0.50))
+	result.P95 = round(result.p95)
+	result.P99 = round(result.p99)
+	return result
+}
+
+func send(ctx context.Context, client *http.Client, endpoint config.LoadEndpoint) (time.Duration, bool) {
+	method := endpoint.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.URL, strings.NewReader(endpoint.Body))
+	if err != nil {
+		return 0, false
+	}
+	for key, value := range endpoint.Headers {
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Since(start), false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode < 400
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted))*p+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// k6Summary is the part of k6's --summary-export file the gate reads
+type k6Summary struct {
+	Metrics struct {
+		HTTPReqDuration map[string]float64 `json:"http_req_duration"` // milliseconds
+		HTTPReqFailed   struct {
+			Value float64 `json:"value"`
+		} `json:"http_req_failed"`
+		HTTPReqs struct {
+			Count float64 `json:"count"`
+			Rate  float64 `json:"rate"`
+		} `json:"http_reqs"`
+	} `json:"metrics"`
+}
+
+// runK6 runs k6 with the configured script, or one generated from the endpoints, and reads
+// its summary export. k6 thresholds defined in a custom script still apply to its exit code.
+func runK6(ctx context.Context, gate config.LoadGate, users int, duration time.Duration, workDir string) (*Result, error) {
+	binary, err := execx.LookPath("k6")
+	if err != nil {
+		return nil, fmt.Errorf("k6 command not found in PATH: %w", err)
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create load test directory: %w", err)
+	}
+
+	script := gate.Script
+	if script == "" {
+		script = filepath.Join(workDir, "load-test.js")
+		if err := os.WriteFile(script, []byte(k6Script(gate.Endpoints)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write k6 script: %w", err)
+		}
+	}
+	summaryPath := filepath.Join(workDir, "k6-summary.json")
+	os.Remove(summaryPath)
+
+	cmd := execx.Command(binary, "run",
+		"--vus", fmt.Sprintf("%d", users),
+		"--duration", duration.String(),
+		"--summary-trend-stats", "avg,min,med,max,p(95),p(99)",
+		"--summary-export", summaryPath,
+		"--quiet",
+		script,
+	)
+	cmd.Tag = "k6 run"
+	_, runErr := cmd.Output(ctx)
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("k6 run failed: %w", runErr)
+		}
+		return nil, fmt.Errorf("failed to read k6 summary: %w", err)
+	}
+	var summary k6Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse k6 summary: %w", err)
+	}
+
+	metrics := summary.Metrics
+	millis := func(key string) time.Duration {
+		return time.Duration(metrics.HTTPReqDuration[key] * float64(time.Millisecond))
+	}
+	result := &Result{
+		Duration:  round(duration),
+		Requests:  int(metrics.HTTPReqs.Count),
+		ErrorRate: metrics.HTTPReqFailed.Value,
+		RPS:       metrics.HTTPReqs.Rate,
+		P50:       round(millis("med")),
+		p95:       millis("p(95)"),
+		p99:       millis("p(99)"),
+	}
+	result.Failed = int(metrics.HTTPReqFailed.Value*metrics.HTTPReqs.Count + 0.5)
+	result.P95 = round(result.p95)
+	result.P99 = round(result.p99)
+
+	// k6 exits 99 when a threshold of the script failed
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) && exitErr.ExitCode() == 99 {
+		result.Breaches = append(result.Breaches, "k6 script thresholds failed")
+	} else if runErr != nil {
+		return nil, fmt.Errorf("k6 run failed: %w", runErr)
+	}
+	return result, nil
+}
+
+// k6Script generates a script whose iterations send the endpoints' requests in turn
+func k6Script(endpoints []config.LoadEndpoint) string {
+	requests := make([]map[string]interface{}, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		method := endpoint.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		requests = append(requests, map[string]interface{}{
+			"name":    endpoint.Name,
+			"method":  method,
+			"url":     endpoint.URL,
+			"body":    endpoint.Body,
+			"headers": endpoint.Headers,
+		})
+	}
+	encoded, _ := json.MarshalIndent(requests, "", "  ")
+
+	return `import http from 'k6/http';
+
+// Generated by e2e-k8s-installer from validation.post.load.endpoints
+const requests = ` + string(encoded) + `;
+
+export default function () {
+  for (const r of requests) {
+    http.request(r.method, r.url, r.body || null, { headers: r.headers || {}, tags: { name: r.name } });
+  }
+}
+`
+}
+
+func round(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(100 * time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
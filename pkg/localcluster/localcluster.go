@@ -0,0 +1,333 @@
+package localcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Cluster tools
+const (
+	ToolKind = "kind"
+	ToolK3d  = "k3d"
+)
+
+// Defaults for the local cluster
+const (
+	DefaultName         = "e2e-installer"
+	DefaultRegistryPort = 5001
+)
+
+// operationTimeout bounds creating or deleting the cluster
+const operationTimeout = 15 * time.Minute
+
+// registryImage is the registry run next to the cluster
+const registryImage = "registry:2"
+
+// Manager creates and deletes the local development cluster
+type Manager struct {
+	config config.LocalCluster
+	tool   string
+	name   string
+	port   int
+}
+
+// NewManager creates a local cluster manager with defaults applied
+func NewManager(cfg config.LocalCluster) *Manager {
+	mgr := &Manager{config: cfg, tool: cfg.Tool, name: cfg.Name, port: cfg.Registry.Port}
+	if mgr.tool == "" {
+		mgr.tool = ToolKind
+	}
+	if mgr.name == "" {
+		mgr.name = DefaultName
+	}
+	if mgr.port == 0 {
+		mgr.port = DefaultRegistryPort
+	}
+	return mgr
+}
+
+// CheckTools verifies the cluster tool, and docker and kubectl for a kind registry, are installed
+func (m *Manager) CheckTools() error {
+	tools := []string{m.tool}
+	if m.tool == ToolKind && m.config.Registry.Enabled {
+		tools = append(tools, "docker", "kubectl")
+	}
+	for _, tool := range tools {
+		if _, err := execx.LookPath(tool); err != nil {
+			return fmt.Errorf("%s not found in PATH, it is required for the local cluster: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+// Tool returns kind or k3d
+func (m *Manager) Tool() string {
+	return m.tool
+}
+
+// Name returns the cluster name
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// Context returns the kubeconfig context of the cluster, which creation makes current
+func (m *Manager) Context() string {
+	return m.tool + "-" + m.name
+}
+
+// Registry returns the host address of the local registry, empty when it is disabled
+func (m *Manager) Registry() string {
+	if !m.config.Registry.Enabled {
+		return ""
+	}
+	return "localhost:" + strconv.Itoa(m.port)
+}
+
+// Outputs are the cluster details published like infrastructure outputs
+func (m *Manager) Outputs() map[string]interface{} {
+	outputs := map[string]interface{}{
+		"cluster_name": m.name,
+		"kube_context": m.Context(),
+	}
+	if registry := m.Registry(); registry != "" {
+		outputs["local_registry"] = registry
+	}
+	return outputs
+}
+
+// Exists reports whether the cluster is already running
+func (m *Manager) Exists() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if m.tool == ToolK3d {
+		out, err := m.run(ctx, nil, "k3d", "cluster", "list", "-o", "json")
+		if err != nil {
+			return false, err
+		}
+		var clusters []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(out, &clusters); err != nil {
+			return false, fmt.Errorf("failed to parse k3d clusters: %w", err)
+		}
+		for _, cluster := range clusters {
+			if cluster.Name == m.name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	out, err := m.run(ctx, nil, "kind", "get", "clusters")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == m.name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Create starts the cluster and its registry unless the cluster already exists
+func (m *Manager) Create() error {
+	exists, err := m.Exists()
+	if err != nil {
+		return err
+	}
+	if exists {
+		logger.Info("Local cluster already exists").Str("tool", m.tool).Str("name", m.name).Send()
+		return nil
+	}
+
+	logger.Info("Creating local cluster").
+		Str("tool", m.tool).
+		Str("name", m.name).
+		Int("workers", m.config.Workers).
+		Str("registry", m.Registry()).
+		Send()
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	if m.tool == ToolK3d {
+		err = m.createK3d(ctx)
+	} else {
+		err = m.createKind(ctx)
+	}
+	audit.Record("local.create", m.Context(), map[string]interface{}{
+		"tool":     m.tool,
+		"workers":  m.config.Workers,
+		"registry": m.Registry(),
+	}, err)
+	if err != nil {
+		return fmt.Errorf("failed to create %s cluster %s: %w", m.tool, m.name, err)
+	}
+	return nil
+}
+
+// Delete removes the cluster and its registry
+func (m *Manager) Delete() error {
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	logger.Info("Deleting local cluster").Str("tool", m.tool).Str("name", m.name).Send()
+
+	var err error
+	if m.tool == ToolK3d {
+		// The registry created with the cluster is deleted with it
+		_, err = m.run(ctx, nil, "k3d", "cluster", "delete", m.name)
+	} else {
+		_, err = m.run(ctx, nil, "kind", "delete", "cluster", "--name", m.name)
+		if err == nil && m.config.Registry.Enabled {
+			if _, rmErr := m.run(ctx, nil, "docker", "rm", "-f", m.registryName()); rmErr != nil {
+				logger.Warn("Failed to remove local registry").Str("registry", m.registryName()).Err(rmErr).Send()
+			}
+		}
+	}
+	audit.Record("local.delete", m.Context(), map[string]interface{}{"tool": m.tool}, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s cluster %s: %w", m.tool, m.name, err)
+	}
+	return nil
+}
+
+// createKind follows the kind local registry setup: the registry container joins the kind
+// network and every node's containerd resolves localhost:<port> to it
+func (m *Manager) createKind(ctx context.Context) error {
+	if m.config.Registry.Enabled {
+		if err := m.startKindRegistry(ctx); err != nil {
+			return err
+		}
+	}
+
+	var cluster strings.Builder
+	cluster.WriteString("kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\n")
+	if m.config.Registry.Enabled {
+		cluster.WriteString("containerdConfigPatches:\n- |-\n  [plugins.\"io.containerd.grpc.v1.cri\".registry]\n    config_path = \"/etc/containerd/certs.d\"\n")
+	}
+	cluster.WriteString("nodes:\n")
+	for i := 0; i <= m.config.Workers; i++ {
+		role := "worker"
+		if i == 0 {
+			role = "control-plane"
+		}
+		cluster.WriteString("- role: " + role + "\n")
+		if m.config.KubernetesVersion != "" {
+			cluster.WriteString("  image: kindest/node:" + m.config.KubernetesVersion + "\n")
+		}
+	}
+
+	if _, err := m.run(ctx, []byte(cluster.String()), "kind", "create", "cluster", "--name", m.name, "--config", "-", "--wait", "5m"); err != nil {
+		return err
+	}
+	if !m.config.Registry.Enabled {
+		return nil
+	}
+
+	nodes, err := m.run(ctx, nil, "kind", "get", "nodes", "--name", m.name)
+	if err != nil {
+		return err
+	}
+	registryDir := "/etc/containerd/certs.d/" + m.Registry()
+	hosts := fmt.Sprintf("[host.\"http://%s:5000\"]\n", m.registryName())
+	for _, node := range strings.Fields(string(nodes)) {
+		if _, err := m.run(ctx, []byte(hosts), "docker", "exec", "-i", node, "sh", "-c",
+			fmt.Sprintf("mkdir -p %s && cat > %s/hosts.toml", registryDir, registryDir)); err != nil {
+			return fmt.Errorf("failed to configure registry on node %s: %w", node, err)
+		}
+	}
+
+	if _, err := m.run(ctx, nil, "docker", "network", "connect", "kind", m.registryName()); err != nil && !strings.Contains(err.Error(), "already exists") {
+		return fmt.Errorf("failed to connect registry to the kind network: %w", err)
+	}
+
+	// Documents the registry for tools following KEP-1755
+	hosting := fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: local-registry-hosting
+  namespace: kube-public
+data:
+  localRegistryHosting.v1: |
+    host: "%s"
+    help: "https://kind.sigs.k8s.io/docs/user/local-registry/"
+`, m.Registry())
+	_, err = m.run(ctx, []byte(hosting), "kubectl", "--context", m.Context(), "apply", "-f", "-")
+	return err
+}
+
+// startKindRegistry runs the registry container unless it is running already
+func (m *Manager) startKindRegistry(ctx context.Context) error {
+	out, err := m.run(ctx, nil, "docker", "inspect", "-f", "{{.State.Running}}", m.registryName())
+	if err == nil && strings.TrimSpace(string(out)) == "true" {
+		return nil
+	}
+	_, err = m.run(ctx, nil, "docker", "run", "-d", "--restart=always",
+		"-p", fmt.Sprintf("127.0.0.1:%d:5000", m.port),
+		"--network", "bridge", "--name", m.registryName(), registryImage)
+	if err != nil {
+		return fmt.Errorf("failed to start local registry: %w", err)
+	}
+	return nil
+}
+
+// createK3d creates the cluster with a k3d-managed registry, mirrored as localhost:<port>
+func (m *Manager) createK3d(ctx context.Context) error {
+	args := []string{"cluster", "create", m.name, "--agents", strconv.Itoa(m.config.Workers), "--wait"}
+	if m.config.KubernetesVersion != "" {
+		args = append(args, "--image", "rancher/k3s:"+m.config.KubernetesVersion+"-k3s1")
+	}
+
+	if m.config.Registry.Enabled {
+		registries := fmt.Sprintf("mirrors:\n  \"%s\":\n    endpoint:\n      - http://k3d-%s:5000\n", m.Registry(), m.registryName())
+		file, err := os.CreateTemp("", "k3d-registries-*.yaml")
+		if err != nil {
+			return fmt.Errorf("failed to create registry configuration: %w", err)
+		}
+		defer os.Remove(file.Name())
+		if _, err := file.WriteString(registries); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write registry configuration: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write registry configuration: %w", err)
+		}
+		args = append(args,
+			"--registry-create", fmt.Sprintf("%s:0.0.0.0:%d", m.registryName(), m.port),
+			"--registry-config", file.Name())
+	}
+
+	_, err := m.run(ctx, nil, "k3d", args...)
+	return err
+}
+
+func (m *Manager) registryName() string {
+	return m.name + "-registry"
+}
+
+// run executes a tool and returns its stdout, with stderr in the error
+func (m *Manager) run(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := execx.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", name, args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JournalFile is the file in a run's log directory every log entry and tool output line
+// of the run is appended to as JSON, so another terminal can follow the run with 'logs'
+const JournalFile = "journal.jsonl"
+
+// RunFinished is the event of the last journal entry of a run, whose status is succeeded
+// or failed
+const RunFinished = "run.finished"
+
+// The journal has its own lock: tool streams write to it while holding toolMu, and the
+// loggers that write to it may be called with toolMu held
+var (
+	journalMu   sync.Mutex
+	journalDir  string
+	journalFile *os.File
+)
+
+// Journal returns a writer appending zerolog JSON entries to the run's journal, for loggers
+// that do not go through this package
+func Journal() io.Writer {
+	return journalWriter{}
+}
+
+type journalWriter struct{}
+
+// Write never fails, a journal that cannot be written must not break logging
+func (journalWriter) Write(p []byte) (int, error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if file := openJournal(); file != nil {
+		file.Write(p)
+	}
+	return len(p), nil
+}
+
+// FinishJournal records the end of the run, which ends 'logs --follow'
+func FinishJournal(err error) {
+	entry := map[string]interface{}{
+		"level": "info", "message": "Run finished", "event": RunFinished, "status": "succeeded",
+	}
+	if err != nil {
+		entry["level"], entry["status"], entry["error"] = "error", "failed", err.Error()
+	}
+	writeJournalEntry(entry, false)
+}
+
+func journalToolLine(step, tag, line string) {
+	writeJournalEntry(map[string]interface{}{
+		"level": "info", "message": line, "step": step, "tool": tag,
+	}, true)
+}
+
+// writeJournalEntry appends an entry; open starts the journal if nothing was logged yet
+func writeJournalEntry(entry map[string]interface{}, open bool) {
+	entry["time"] = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if journalFile == nil && !open {
+		return
+	}
+	if file := openJournal(); file != nil {
+		file.Write(append(data, '\n'))
+	}
+}
+
+func setJournalDir(dir string) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	journalDir = dir
+}
+
+// openJournal opens the journal on first use; callers hold journalMu
+func openJournal() *os.File {
+	if journalFile != nil || journalDir == "" {
+		return journalFile
+	}
+	if err := os.MkdirAll(journalDir, 0755); err != nil {
+		journalDir = ""
+		return nil
+	}
+	file, err := os.OpenFile(filepath.Join(journalDir, JournalFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		journalDir = ""
+		return nil
+	}
+	journalFile = file
+	pointLatest(journalDir)
+	return file
+}
+
+func closeJournal() {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if journalFile != nil {
+		journalFile.Close()
+		journalFile = nil
+	}
+	journalDir = ""
+}
@@ -62,35 +62,24 @@ func NewLogger(config Config) *Logger {
 		consoleWriter := zerolog.ConsoleWriter{
 			Out:        writer,
 			TimeFormat: time.RFC3339,
+			NoColor:    !consoleStyle.Color,
 			FormatLevel: func(i interface{}) string {
-				level := strings.ToUpper(fmt.Sprintf("%s", i))
-				switch level {
-				case "DEBUG":
-					return colorize("🔍 DEBUG", "37") // White
-				case "INFO":
-					return colorize("ℹ️  INFO ", "36") // Cyan
-				case "WARN":
-					return colorize("⚠️  WARN ", "33") // Yellow
-				case "ERROR":
-					return colorize("❌ ERROR", "31") // Red
-				default:
-					return level
-				}
+				return formatLevel(strings.ToLower(fmt.Sprintf("%s", i)))
 			},
 			FormatMessage: func(i interface{}) string {
 				return colorize(fmt.Sprintf("%s", i), "0") // Default color
 			},
 			FormatFieldName: func(i interface{}) string {
-				return colorize(fmt.Sprintf("%s=", i), "36") // Cyan
+				return colorize(fmt.Sprintf("%s=", i), consoleStyle.Colors["field"])
 			},
 			FormatFieldValue: func(i interface{}) string {
 				return fmt.Sprintf("%s", i)
 			},
 		}
-		logger = zerolog.New(consoleWriter).With().Timestamp().Logger()
+		logger = zerolog.New(zerolog.MultiLevelWriter(consoleWriter, Journal())).With().Timestamp().Logger()
 	} else {
 		// JSON output
-		logger = zerolog.New(writer).With().Timestamp().Logger()
+		logger = zerolog.New(zerolog.MultiLevelWriter(writer, Journal())).With().Timestamp().Logger()
 	}
 
 	return &Logger{
@@ -98,8 +87,50 @@ func NewLogger(config Config) *Logger {
 	}
 }
 
+// ConsoleStyle is how text format log lines show their level, set by the output theme
+type ConsoleStyle struct {
+	Emoji  bool              // prefix the level with an icon
+	Color  bool              // false prints no ANSI codes at all
+	Colors map[string]string // ANSI color code per level: debug, info, warn and error; field names
+}
+
+// DefaultConsoleStyle labels levels with an icon in white, cyan, yellow and red
+var DefaultConsoleStyle = ConsoleStyle{
+	Emoji:  true,
+	Color:  true,
+	Colors: map[string]string{"debug": "37", "info": "36", "warn": "33", "error": "31", "field": "36"},
+}
+
+var consoleStyle = DefaultConsoleStyle
+
+var (
+	emojiLevelLabels = map[string]string{"debug": "🔍 DEBUG", "info": "ℹ️  INFO ", "warn": "⚠️  WARN ", "error": "❌ ERROR"}
+	plainLevelLabels = map[string]string{"debug": "DEBUG", "info": "INFO ", "warn": "WARN ", "error": "ERROR"}
+)
+
+// SetConsoleStyle changes the level labels of text format log lines, including those of
+// loggers created before
+func SetConsoleStyle(style ConsoleStyle) {
+	consoleStyle = style
+}
+
+func formatLevel(level string) string {
+	labels := plainLevelLabels
+	if consoleStyle.Emoji {
+		labels = emojiLevelLabels
+	}
+	label, known := labels[level]
+	if !known {
+		return strings.ToUpper(level)
+	}
+	return colorize(label, consoleStyle.Colors[level])
+}
+
 // colorize adds ANSI color codes to text
 func colorize(text, colorCode string) string {
+	if !consoleStyle.Color || colorCode == "" {
+		return text
+	}
 	return fmt.Sprintf("\033[%sm%s\033[0m", colorCode, text)
 }
 
@@ -256,6 +287,7 @@ func Fatal(msg string) *LogEvent {
 
 // Step-specific logging helpers
 func StepStart(step string) {
+	SetToolStep(step)
 	Info("Step started").Step(step).Send()
 }
 
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tool output streams: helm, terraform and make write their output line by line into one
+// log file per step as it arrives, rather than only once the command has exited
+var (
+	toolMu    sync.Mutex
+	toolDir   string
+	toolEcho  bool
+	toolStep  = "run"
+	toolFiles = make(map[string]*os.File)
+)
+
+// ConfigureToolLogs makes tool output stream into <dir>/<step>.log, and onto the console
+// as well when echo is set. The directory is created with the first line written, and the
+// latest symlink next to it then points at it. The run's journal is kept there too.
+func ConfigureToolLogs(dir string, echo bool) {
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	toolDir, toolEcho = dir, echo
+	setJournalDir(dir)
+}
+
+// SetToolStep names the step the output of tools started from now on is logged under
+func SetToolStep(step string) {
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	toolStep = step
+}
+
+// ToolLogFiles returns the step log files written so far
+func ToolLogFiles() []string {
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	files := make([]string, 0, len(toolFiles))
+	for _, file := range toolFiles {
+		files = append(files, file.Name())
+	}
+	return files
+}
+
+// CloseToolLogs closes the step log files
+func CloseToolLogs() {
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	for step, file := range toolFiles {
+		file.Close()
+		delete(toolFiles, step)
+	}
+	closeJournal()
+}
+
+// ToolOutput returns a writer streaming the output of one command into the log file of
+// the current step, each line timestamped and tagged, e.g. "helm upgrade api". With
+// console set the lines are echoed in verbose mode too; tools that already print to the
+// terminal pass false. Close the writer when the command exits to flush its last line.
+func ToolOutput(tag string, console bool) io.WriteCloser {
+	return StepToolOutput("", tag, console)
+}
+
+// StepToolOutput is ToolOutput into the log file of step, the current step when it is
+// empty, for commands of steps running at the same time
+func StepToolOutput(step, tag string, console bool) io.WriteCloser {
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	if step == "" {
+		step = toolStep
+	}
+	return &toolStream{tag: tag, step: step, console: console && toolEcho}
+}
+
+type toolStream struct {
+	mu      sync.Mutex
+	tag     string
+	step    string
+	console bool
+	partial []byte
+}
+
+func (s *toolStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partial = append(s.partial, p...)
+	for {
+		end := bytes.IndexByte(s.partial, '\n')
+		if end < 0 {
+			break
+		}
+		s.writeLine(string(s.partial[:end]))
+		s.partial = s.partial[end+1:]
+	}
+	return len(p), nil
+}
+
+func (s *toolStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.partial) > 0 {
+		s.writeLine(string(s.partial))
+		s.partial = nil
+	}
+	return nil
+}
+
+func (s *toolStream) writeLine(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	// Progress bars redraw the line with carriage returns; keep what was drawn last
+	if redraw := strings.LastIndex(line, "\r"); redraw >= 0 {
+		line = line[redraw+1:]
+	}
+
+	if s.console {
+		fmt.Fprintln(os.Stderr, colorize(fmt.Sprintf("  │ [%s] %s", s.tag, line), "90"))
+	}
+
+	toolMu.Lock()
+	defer toolMu.Unlock()
+	file, err := toolFile(s.step)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(file, "%s [%s] %s\n", time.Now().Format("15:04:05"), s.tag, line)
+	journalToolLine(s.step, s.tag, line)
+}
+
+// toolFile opens the log file of step on first use; callers hold toolMu
+func toolFile(step string) (*os.File, error) {
+	if file, ok := toolFiles[step]; ok {
+		return file, nil
+	}
+	if toolDir == "" {
+		return nil, fmt.Errorf("tool logs not configured")
+	}
+	if err := os.MkdirAll(toolDir, 0755); err != nil {
+		toolDir = "" // warn once, not on every line
+		Warn("Failed to create tool log directory").Err(err).Send()
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(toolDir, step+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	toolFiles[step] = file
+	pointLatest(toolDir)
+	return file, nil
+}
+
+// pointLatest makes the latest symlink next to a run's log directory point at it
+func pointLatest(dir string) {
+	latest := filepath.Join(filepath.Dir(dir), "latest")
+	if target, err := os.Readlink(latest); err != nil || target != filepath.Base(dir) {
+		os.Remove(latest)
+		os.Symlink(filepath.Base(dir), latest)
+	}
+}
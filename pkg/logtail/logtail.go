@@ -0,0 +1,95 @@
+// Package logtail keeps the last lines a tool printed during an operation with the error
+// of that operation, so a failure shows its cause without a trip to the log file.
+package logtail
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Lines is the number of output lines kept with a failed operation
+const Lines = 30
+
+// Error is a failed operation with the last output lines of the tool that ran it
+type Error struct {
+	Tool    string
+	LogTail []string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Attach wraps err with the last Lines lines of output. An err that already carries a tail
+// keeps it, since the innermost operation is the one that failed. A nil err stays nil, and
+// so does the tail of an operation that printed nothing.
+func Attach(tool string, output []byte, err error) error {
+	if err == nil || Of(err) != nil {
+		return err
+	}
+	lines := tail(output, Lines)
+	if len(lines) == 0 {
+		return err
+	}
+	return &Error{Tool: tool, LogTail: lines, Err: err}
+}
+
+// Of returns the output tail attached to err, or nil
+func Of(err error) *Error {
+	var tailed *Error
+	if errors.As(err, &tailed) {
+		return tailed
+	}
+	return nil
+}
+
+// Buffer is a writer keeping the last Lines lines written to it, for tools whose output
+// is streamed to the terminal rather than captured
+type Buffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	// Trim now and then rather than on every write; a long build prints a lot
+	if bytes.Count(b.data, []byte("\n")) > 4*Lines {
+		b.data = []byte(strings.Join(tail(b.data, Lines), "\n") + "\n")
+	}
+	return len(p), nil
+}
+
+// Bytes returns the lines the buffer holds
+func (b *Buffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.data...)
+}
+
+// tail returns the last n lines of output without trailing blank lines; a line redrawn
+// with carriage returns, like a progress bar, keeps only its final state
+func tail(output []byte, n int) []string {
+	text := strings.TrimRight(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n\t ")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, line := range lines {
+		if redraw := strings.LastIndex(line, "\r"); redraw >= 0 {
+			lines[i] = line[redraw+1:]
+		}
+	}
+	return lines
+}
@@ -0,0 +1,112 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronFields are the fields of a cron expression in order, with their bounds
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month, month and day of
+// week. Fields accept *, numbers, ranges (1-5), lists (1,3) and steps (*/15, 0-30/10); day of
+// week 7 means Sunday like 0.
+type Cron struct {
+	fields [5][]bool
+
+	// As in cron, when both day fields are restricted a day matching either one is used
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// ParseCron parses a five-field cron expression
+func ParseCron(expr string) (*Cron, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	c := &Cron{}
+	for i, part := range parts {
+		bounds := cronFields[i]
+		max := bounds.max
+		if i == 4 {
+			max = 7 // Sunday may be written as 7
+		}
+		values := make([]bool, max+1)
+		for _, item := range strings.Split(part, ",") {
+			if err := setCronItem(values, item, bounds.min, max); err != nil {
+				return nil, fmt.Errorf("invalid %s %q in %q: %w", bounds.name, item, expr, err)
+			}
+		}
+		if i == 4 && values[7] {
+			values[0] = true
+		}
+		c.fields[i] = values
+	}
+	c.anyDayOfMonth = parts[2] == "*"
+	c.anyDayOfWeek = parts[4] == "*"
+	return c, nil
+}
+
+func setCronItem(values []bool, item string, min, max int) error {
+	rangePart, step := item, 1
+	if before, after, found := strings.Cut(item, "/"); found {
+		n, err := strconv.Atoi(after)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("step must be a positive number")
+		}
+		rangePart, step = before, n
+	}
+
+	start, end := min, max
+	if rangePart != "*" {
+		from, to, isRange := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = strconv.Atoi(from); err != nil {
+			return fmt.Errorf("not a number")
+		}
+		end = start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil {
+				return fmt.Errorf("not a number")
+			}
+		} else if step > 1 {
+			end = max
+		}
+	}
+	if start < min || end > max || start > end {
+		return fmt.Errorf("out of range %d-%d", min, max)
+	}
+	for v := start; v <= end; v += step {
+		values[v] = true
+	}
+	return nil
+}
+
+// Matches reports whether the minute of t is one the expression fires at
+func (c *Cron) Matches(t time.Time) bool {
+	if !c.fields[0][t.Minute()] || !c.fields[1][t.Hour()] || !c.fields[3][int(t.Month())] {
+		return false
+	}
+	dom, dow := c.fields[2][t.Day()], c.fields[4][int(t.Weekday())]
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dow
+	case c.anyDayOfWeek:
+		return dom
+	}
+	return dom || dow
+}
@@ -0,0 +1,151 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// Phases the windows govern when none are configured: the ones that change infrastructure or data
+var DefaultPhases = []string{PhaseProvisionInfra, PhaseDBMigrate, PhaseUpgrade}
+
+// Phase names
+const (
+	PhaseProvisionInfra = "provision-infra"
+	PhaseDBMigrate      = "db-migrate"
+	PhaseDeploy         = "deploy"
+	PhaseUpgrade        = "provision-upgrade"
+)
+
+// searchHorizon bounds the search for the next window; schedules that never fire within a year
+// are treated as closed
+const searchHorizon = 366 * 24 * time.Hour
+
+// Window is a recurring period changes are allowed in
+type Window struct {
+	Name     string
+	cron     *Cron
+	duration time.Duration
+	location *time.Location
+}
+
+// Policy decides when governed phases may run
+type Policy struct {
+	windows []*Window
+	phases  map[string]bool
+	Wait    bool
+	MaxWait time.Duration // zero waits as long as it takes
+}
+
+// NewPolicy parses the maintenance configuration; a policy without windows allows everything
+func NewPolicy(cfg config.MaintenanceConfig) (*Policy, error) {
+	policy := &Policy{phases: make(map[string]bool), Wait: cfg.Wait}
+	for _, windowCfg := range cfg.Windows {
+		window, err := newWindow(windowCfg)
+		if err != nil {
+			return nil, err
+		}
+		policy.windows = append(policy.windows, window)
+	}
+
+	phases := cfg.Phases
+	if len(phases) == 0 {
+		phases = DefaultPhases
+	}
+	for _, phase := range phases {
+		policy.phases[phase] = true
+	}
+
+	if cfg.MaxWait != "" {
+		maxWait, err := time.ParseDuration(cfg.MaxWait)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance maxWait %q: %w", cfg.MaxWait, err)
+		}
+		policy.MaxWait = maxWait
+	}
+	return policy, nil
+}
+
+func newWindow(cfg config.MaintenanceWindow) (*Window, error) {
+	cron, err := ParseCron(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %s: %w", cfg.Name, err)
+	}
+	duration, err := time.ParseDuration(cfg.Duration)
+	if err != nil || duration < time.Minute {
+		return nil, fmt.Errorf("maintenance window %s: duration %q must be at least 1m", cfg.Name, cfg.Duration)
+	}
+	location := time.Local
+	if cfg.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("maintenance window %s: unknown timezone %q: %w", cfg.Name, cfg.Timezone, err)
+		}
+	}
+	return &Window{Name: cfg.Name, cron: cron, duration: duration, location: location}, nil
+}
+
+// Governs reports whether phase may only run inside a window
+func (p *Policy) Governs(phase string) bool {
+	return len(p.windows) > 0 && p.phases[phase]
+}
+
+// Status is where a moment falls relative to the windows
+type Status struct {
+	Open     bool
+	Window   string    // open window, or the one that opens next
+	Closes   time.Time // end of the open window
+	NextOpen time.Time // start of the next window when closed; zero when none opens within a year
+}
+
+// StatusAt returns whether a window is open at t, and otherwise when the next one opens
+func (p *Policy) StatusAt(t time.Time) Status {
+	var status Status
+	for _, window := range p.windows {
+		if closes, open := window.openAt(t); open {
+			if !status.Open || closes.After(status.Closes) {
+				status = Status{Open: true, Window: window.Name, Closes: closes}
+			}
+			continue
+		}
+		if status.Open {
+			continue
+		}
+		if next, ok := window.nextOpen(t); ok && (status.NextOpen.IsZero() || next.Before(status.NextOpen)) {
+			status.Window, status.NextOpen = window.Name, next
+		}
+	}
+	return status
+}
+
+// openAt reports whether a window that started at most one duration before t is still open
+func (w *Window) openAt(t time.Time) (time.Time, bool) {
+	local := t.In(w.location).Truncate(time.Minute)
+	for start := local; local.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.cron.Matches(start) {
+			return start.Add(w.duration), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextOpen returns the first start of the window after t
+func (w *Window) nextOpen(t time.Time) (time.Time, bool) {
+	start := t.In(w.location).Truncate(time.Minute).Add(time.Minute)
+	for end := start.Add(searchHorizon); start.Before(end); start = start.Add(time.Minute) {
+		if w.cron.Matches(start) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Describe lists the windows for messages
+func (p *Policy) Describe() string {
+	var names []string
+	for _, window := range p.windows {
+		names = append(names, fmt.Sprintf("%s (%s for %s)", window.Name, window.location, window.duration))
+	}
+	return strings.Join(names, ", ")
+}
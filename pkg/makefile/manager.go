@@ -1,15 +1,22 @@
 package makefile
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
 )
 
@@ -17,8 +24,19 @@ import (
 type Manager struct {
 	config     *config.MakefileExecution
 	workingDir string
+	makefile   string
 	makePath   string
 	env        []string
+	variables  []string
+
+	targets map[string]bool // discovered on first use
+	dryRuns []DryRun
+}
+
+// DryRun is the command list 'make -n' printed for a target
+type DryRun struct {
+	Target   string   `json:"target"`
+	Commands []string `json:"commands"`
 }
 
 // NewManager creates a new Makefile manager
@@ -47,29 +65,41 @@ func NewManager(makefileConfig *config.MakefileExecution) (*Manager, error) {
 	if _, err := os.Stat(makefilePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("makefile not found at path: %s", makefilePath)
 	}
+	// make runs in the working directory, so the path checked above is passed absolute
+	makefilePath, err := filepath.Abs(makefilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve makefile path: %w", err)
+	}
 
 	// Check if make command is available
-	makePath, err := exec.LookPath("make")
+	makePath, err := execx.LookPath("make")
 	if err != nil {
 		return nil, fmt.Errorf("make command not found in PATH: %w", err)
 	}
 
-	// Prepare environment variables
-	env := os.Environ()
+	// Environment variables added to the installer's own
+	var env []string
 	for key, value := range makefileConfig.Environment {
 		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Add makefile variables as environment variables
+	// Variables are passed as VAR=value arguments, which override assignments in the Makefile
+	var variables []string
 	for key, value := range makefileConfig.Variables {
-		env = append(env, fmt.Sprintf("%s=%s", key, value))
+		variables = append(variables, fmt.Sprintf("%s=%s", key, value))
 	}
+	sort.Strings(variables)
+
+	// Terraform run from make targets picks up the label policy the same way
+	env = append(env, labels.TerraformEnv()...)
 
 	return &Manager{
 		config:     makefileConfig,
 		workingDir: workingDir,
+		makefile:   makefilePath,
 		makePath:   makePath,
 		env:        env,
+		variables:  variables,
 	}, nil
 }
 
@@ -85,14 +115,13 @@ func (m *Manager) ExecuteTarget(target string, dryRun bool) error {
 		Bool("dryRun", dryRun).
 		Send()
 
-	// Prepare command arguments
-	args := []string{}
-
-	// Add makefile path if not default
-	if m.config.MakefilePath != "" && !strings.HasSuffix(m.config.MakefilePath, "Makefile") {
-		args = append(args, "-f", m.config.MakefilePath)
+	if err := m.requireTargets(target); err != nil {
+		return err
 	}
 
+	// Prepare command arguments
+	args := []string{"-f", m.makefile}
+
 	// Add parallel flag if enabled
 	if m.config.Parallel {
 		args = append(args, "-j")
@@ -104,40 +133,53 @@ func (m *Manager) ExecuteTarget(target string, dryRun bool) error {
 	}
 
 	// Add dry run flag if enabled or requested
-	if dryRun || m.config.DryRun {
+	dryRun = dryRun || m.config.DryRun
+	if dryRun {
 		args = append(args, "-n")
 	}
 
-	// Add target
+	// Add variables and target
+	args = append(args, m.variables...)
 	args = append(args, target)
 
-	// Create command context with timeout
-	ctx := context.Background()
+	cmd := execx.Command(m.makePath, args...)
+	cmd.Dir = m.workingDir
+	cmd.Env = m.env
 	if m.config.Timeout != "" {
 		timeout, err := time.ParseDuration(m.config.Timeout)
 		if err != nil {
 			return fmt.Errorf("invalid timeout duration: %w", err)
 		}
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-		defer cancel()
+		cmd.Timeout = timeout
+	}
+	// make prints to the terminal itself, so its output is only logged for the step
+	cmd.Tag = "make " + target
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if !dryRun {
+		cmd.Audit, cmd.AuditTarget = "make.target", target
+		cmd.AuditDetails = map[string]interface{}{
+			"makefile":   m.config.MakefilePath,
+			"workingDir": m.workingDir,
+		}
 	}
 
-	// Execute command
-	cmd := exec.CommandContext(ctx, m.makePath, args...)
-	cmd.Dir = m.workingDir
-	cmd.Env = m.env
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// A dry run prints the commands make would run; keep them for the plan output
+	var commands bytes.Buffer
+	if dryRun {
+		cmd.Stdout = io.MultiWriter(os.Stdout, &commands)
+	}
 
 	logger.Info("Running make command").
-		Str("command", fmt.Sprintf("%s %s", m.makePath, strings.Join(args, " "))).
+		Str("command", cmd.String()).
 		Str("workingDir", m.workingDir).
 		Send()
 
-	if err := cmd.Run(); err != nil {
+	if _, err := cmd.Run(context.Background()); err != nil {
 		return fmt.Errorf("make target '%s' failed: %w", target, err)
 	}
+	if dryRun {
+		m.dryRuns = append(m.dryRuns, DryRun{Target: target, Commands: commandLines(commands.String())})
+	}
 
 	logger.Info("Makefile target completed successfully").
 		Str("target", target).
@@ -146,8 +188,11 @@ func (m *Manager) ExecuteTarget(target string, dryRun bool) error {
 	return nil
 }
 
-// Init executes the init target
+// Init checks the configured targets exist, then executes the init target
 func (m *Manager) Init(dryRun bool) error {
+	if err := m.ValidateTargets(); err != nil {
+		return err
+	}
 	target := m.config.Targets.Init
 	if target == "" {
 		target = "init"
@@ -218,37 +263,112 @@ func (m *Manager) ExecuteCustomTarget(targetName string, dryRun bool) error {
 	return m.ExecuteTarget(target, dryRun)
 }
 
-// ListTargets lists all available targets in the Makefile
+// ListTargets lists the targets the Makefile defines, from the database 'make -qp' prints.
+// Special targets, pattern rules and files make only knows about are left out.
 func (m *Manager) ListTargets() ([]string, error) {
-	cmd := exec.Command(m.makePath, "-f", m.config.MakefilePath, "-p")
+	args := append([]string{"-f", m.makefile, "-qp"}, m.variables...)
+	cmd := execx.Command(m.makePath, args...)
 	cmd.Dir = m.workingDir
 	cmd.Env = m.env
 
-	output, err := cmd.Output()
-	if err != nil {
+	// -q exits 1 when the default goal is out of date; the database is printed regardless
+	output, err := cmd.Output(context.Background())
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
 		return nil, fmt.Errorf("failed to list makefile targets: %w", err)
 	}
 
 	var targets []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, ":") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, ".") {
-			target := strings.Split(line, ":")[0]
-			target = strings.TrimSpace(target)
-			if target != "" && !strings.Contains(target, " ") {
-				targets = append(targets, target)
-			}
+	notATarget := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# Not a target:") {
+			notATarget = true
+			continue
 		}
+		if line == "" || line[0] == '#' || line[0] == '\t' || line[0] == ' ' {
+			continue
+		}
+		name, rest, found := strings.Cut(line, ":")
+		skip := notATarget
+		notATarget = false
+		if !found || skip || strings.HasPrefix(rest, "=") || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "% =") {
+			continue
+		}
+		targets = append(targets, name)
 	}
-
+	sort.Strings(targets)
 	return targets, nil
 }
 
+// ValidateTargets checks that every target the configuration names exists in the Makefile
+func (m *Manager) ValidateTargets() error {
+	t := m.config.Targets
+	configured := []string{t.Init, t.Plan, t.Apply, t.Destroy, t.Validate, t.Clean, t.Format, t.HealthCheck}
+	for _, target := range t.Custom {
+		configured = append(configured, target)
+	}
+	var targets []string
+	for _, target := range configured {
+		if target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return m.requireTargets(targets...)
+}
+
+// requireTargets fails when any of targets is missing from the Makefile
+func (m *Manager) requireTargets(targets ...string) error {
+	if m.targets == nil {
+		available, err := m.ListTargets()
+		if err != nil {
+			return err
+		}
+		m.targets = make(map[string]bool, len(available))
+		for _, target := range available {
+			m.targets[target] = true
+		}
+	}
+
+	var missing []string
+	for _, target := range targets {
+		if !m.targets[target] {
+			missing = append(missing, target)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	available := make([]string, 0, len(m.targets))
+	for target := range m.targets {
+		available = append(available, target)
+	}
+	sort.Strings(available)
+	return fmt.Errorf("makefile %s has no target %s (available: %s)", m.makefile, strings.Join(missing, ", "), strings.Join(available, ", "))
+}
+
+// DryRuns returns the command lists of the targets executed as dry runs, in order
+func (m *Manager) DryRuns() []DryRun {
+	return m.dryRuns
+}
+
+// commandLines splits 'make -n' output into commands, dropping make's own messages
+func commandLines(output string) []string {
+	var commands []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "make: ") || strings.HasPrefix(line, "make[") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+	return commands
+}
+
 // GetMakefileInfo returns information about the Makefile configuration
 func (m *Manager) GetMakefileInfo() *MakefileInfo {
 	return &MakefileInfo{
-		MakefilePath:     m.config.MakefilePath,
+		MakefilePath:     m.makefile,
 		WorkingDirectory: m.workingDir,
 		Targets:          m.config.Targets,
 		Environment:      m.config.Environment,
@@ -0,0 +1,191 @@
+package managed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+)
+
+// awsProvider provisions RDS instances and ElastiCache clusters with the aws CLI
+type awsProvider struct {
+	region  string
+	profile string
+}
+
+type rdsInstances struct {
+	DBInstances []struct {
+		DBInstanceStatus string `json:"DBInstanceStatus"`
+		Endpoint         *struct {
+			Address string `json:"Address"`
+			Port    int    `json:"Port"`
+		} `json:"Endpoint"`
+	} `json:"DBInstances"`
+}
+
+type elastiCacheClusters struct {
+	CacheClusters []struct {
+		CacheClusterStatus string `json:"CacheClusterStatus"`
+		CacheNodes         []struct {
+			Endpoint *struct {
+				Address string `json:"Address"`
+				Port    int    `json:"Port"`
+			} `json:"Endpoint"`
+		} `json:"CacheNodes"`
+	} `json:"CacheClusters"`
+}
+
+type awsTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+func (p *awsProvider) name() string   { return "aws" }
+func (p *awsProvider) binary() string { return "aws" }
+
+func (p *awsProvider) run(ctx context.Context, args []string, secrets ...string) ([]byte, error) {
+	args = append(args, "--region", p.region, "--output", "json")
+	if p.profile != "" {
+		args = append(args, "--profile", p.profile)
+	}
+	return runCLI(ctx, p.binary(), args, secrets...)
+}
+
+func (p *awsProvider) describeDatabase(ctx context.Context, db config.ManagedDatabase) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{"rds", "describe-db-instances", "--db-instance-identifier", db.Name})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var described rdsInstances
+	if err := json.Unmarshal(out, &described); err != nil {
+		return nil, fmt.Errorf("failed to parse RDS instance: %w", err)
+	}
+	if len(described.DBInstances) == 0 {
+		return nil, nil
+	}
+	instance := described.DBInstances[0]
+	endpoint := &Endpoint{Status: instance.DBInstanceStatus}
+	if instance.Endpoint != nil {
+		endpoint.Host = instance.Endpoint.Address
+		endpoint.Port = instance.Endpoint.Port
+	}
+	endpoint.Ready = instance.DBInstanceStatus == "available" && endpoint.Host != ""
+	return endpoint, nil
+}
+
+func (p *awsProvider) createDatabase(ctx context.Context, db config.ManagedDatabase, password string) error {
+	storage := db.StorageGB
+	if storage == 0 {
+		storage = 20
+	}
+	input := map[string]interface{}{
+		"DBInstanceIdentifier": db.Name,
+		"DBInstanceClass":      valueOr(db.Size, "db.t3.medium"),
+		"Engine":               db.Engine,
+		"AllocatedStorage":     storage,
+		"MasterUsername":       db.Username,
+		"MasterUserPassword":   password,
+		"StorageEncrypted":     true,
+	}
+	if db.Version != "" {
+		input["EngineVersion"] = db.Version
+	}
+	if db.Database != "" {
+		input["DBName"] = db.Database
+	}
+	if tags := awsTags(); len(tags) > 0 {
+		input["Tags"] = tags
+	}
+
+	// The password goes through a private input file so it never appears in the process list
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode RDS instance: %w", err)
+	}
+	inputFile, err := secretFile("rds-input-*.json", data)
+	if err != nil {
+		return fmt.Errorf("failed to write RDS input file: %w", err)
+	}
+	defer os.Remove(inputFile)
+
+	_, err = p.run(ctx, []string{"rds", "create-db-instance", "--cli-input-json", "file://" + inputFile}, password)
+	return err
+}
+
+func (p *awsProvider) deleteDatabase(ctx context.Context, db config.ManagedDatabase) error {
+	snapshot := fmt.Sprintf("%s-final-%s", db.Name, time.Now().Format("20060102-150405"))
+	_, err := p.run(ctx, []string{"rds", "delete-db-instance", "--db-instance-identifier", db.Name,
+		"--final-db-snapshot-identifier", snapshot})
+	return err
+}
+
+func (p *awsProvider) describeCache(ctx context.Context, cache config.ManagedCache) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{"elasticache", "describe-cache-clusters", "--cache-cluster-id", cache.Name, "--show-cache-node-info"})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var described elastiCacheClusters
+	if err := json.Unmarshal(out, &described); err != nil {
+		return nil, fmt.Errorf("failed to parse ElastiCache cluster: %w", err)
+	}
+	if len(described.CacheClusters) == 0 {
+		return nil, nil
+	}
+	cluster := described.CacheClusters[0]
+	endpoint := &Endpoint{Status: cluster.CacheClusterStatus}
+	if len(cluster.CacheNodes) > 0 && cluster.CacheNodes[0].Endpoint != nil {
+		endpoint.Host = cluster.CacheNodes[0].Endpoint.Address
+		endpoint.Port = cluster.CacheNodes[0].Endpoint.Port
+	}
+	endpoint.Ready = cluster.CacheClusterStatus == "available" && endpoint.Host != ""
+	return endpoint, nil
+}
+
+func (p *awsProvider) createCache(ctx context.Context, cache config.ManagedCache) error {
+	args := []string{"elasticache", "create-cache-cluster",
+		"--cache-cluster-id", cache.Name,
+		"--engine", cacheEngine(cache),
+		"--cache-node-type", valueOr(cache.Size, "cache.t3.micro"),
+		"--num-cache-nodes", "1",
+	}
+	if cache.Version != "" {
+		args = append(args, "--engine-version", cache.Version)
+	}
+	if tags := awsTags(); len(tags) > 0 {
+		args = append(args, "--tags")
+		for _, tag := range tags {
+			args = append(args, fmt.Sprintf("Key=%s,Value=%s", tag.Key, tag.Value))
+		}
+	}
+	_, err := p.run(ctx, args)
+	return err
+}
+
+func (p *awsProvider) deleteCache(ctx context.Context, cache config.ManagedCache) error {
+	_, err := p.run(ctx, []string{"elasticache", "delete-cache-cluster", "--cache-cluster-id", cache.Name})
+	return err
+}
+
+// awsTags tags services with the run's label policy, like Terraform-managed resources
+func awsTags() []awsTag {
+	var tags []awsTag
+	for _, pair := range labels.Args(labels.Global()) {
+		key, value, _ := strings.Cut(pair, "=")
+		tags = append(tags, awsTag{Key: key, Value: value})
+	}
+	return tags
+}
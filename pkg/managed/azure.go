@@ -0,0 +1,173 @@
+package managed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+)
+
+// azureProvider provisions Azure Database flexible servers and Azure Cache for Redis with the az CLI
+type azureProvider struct {
+	region       string
+	subscription string
+}
+
+type flexibleServer struct {
+	State                    string `json:"state"`
+	FullyQualifiedDomainName string `json:"fullyQualifiedDomainName"`
+}
+
+type redisCache struct {
+	ProvisioningState string `json:"provisioningState"`
+	HostName          string `json:"hostName"`
+	SSLPort           int    `json:"sslPort"`
+}
+
+func (p *azureProvider) name() string   { return "azure" }
+func (p *azureProvider) binary() string { return "az" }
+
+func (p *azureProvider) run(ctx context.Context, args []string, secrets ...string) ([]byte, error) {
+	args = append(args, "--output", "json")
+	if p.subscription != "" {
+		args = append(args, "--subscription", p.subscription)
+	}
+	return runCLI(ctx, p.binary(), args, secrets...)
+}
+
+func (p *azureProvider) describeDatabase(ctx context.Context, db config.ManagedDatabase) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{db.Engine, "flexible-server", "show", "--name", db.Name, "--resource-group", db.ResourceGroup})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var server flexibleServer
+	if err := json.Unmarshal(out, &server); err != nil {
+		return nil, fmt.Errorf("failed to parse flexible server: %w", err)
+	}
+	return &Endpoint{
+		Status: server.State,
+		Host:   server.FullyQualifiedDomainName,
+		Port:   defaultDatabasePorts[db.Engine],
+		Ready:  server.State == "Ready" && server.FullyQualifiedDomainName != "",
+	}, nil
+}
+
+func (p *azureProvider) createDatabase(ctx context.Context, db config.ManagedDatabase, password string) error {
+	// az reads the password from a private file, named with its @<file> syntax, so it never
+	// appears in the process list
+	passwordFile, err := secretFile("az-password-*", []byte(password))
+	if err != nil {
+		return fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	defer os.Remove(passwordFile)
+
+	sku := valueOr(db.Size, "Standard_B2s")
+	args := []string{db.Engine, "flexible-server", "create",
+		"--name", db.Name,
+		"--resource-group", db.ResourceGroup,
+		"--location", p.region,
+		"--admin-user", db.Username,
+		"--admin-password", "@" + passwordFile,
+		"--sku-name", sku,
+		"--tier", flexibleServerTier(sku),
+		"--yes",
+	}
+	if db.Version != "" {
+		args = append(args, "--version", db.Version)
+	}
+	if db.StorageGB > 0 {
+		args = append(args, "--storage-size", strconv.Itoa(db.StorageGB))
+	}
+	if db.Database != "" {
+		args = append(args, "--database-name", db.Database)
+	}
+	args = append(args, azureTags()...)
+
+	_, err = p.run(ctx, args, password)
+	return err
+}
+
+func (p *azureProvider) deleteDatabase(ctx context.Context, db config.ManagedDatabase) error {
+	_, err := p.run(ctx, []string{db.Engine, "flexible-server", "delete", "--name", db.Name, "--resource-group", db.ResourceGroup, "--yes"})
+	return err
+}
+
+func (p *azureProvider) describeCache(ctx context.Context, cache config.ManagedCache) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{"redis", "show", "--name", cache.Name, "--resource-group", cache.ResourceGroup})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var redis redisCache
+	if err := json.Unmarshal(out, &redis); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure Cache for Redis: %w", err)
+	}
+	// Azure Cache for Redis only enables the TLS port by default
+	return &Endpoint{
+		Status: redis.ProvisioningState,
+		Host:   redis.HostName,
+		Port:   redis.SSLPort,
+		Ready:  redis.ProvisioningState == "Succeeded" && redis.HostName != "",
+	}, nil
+}
+
+func (p *azureProvider) createCache(ctx context.Context, cache config.ManagedCache) error {
+	vmSize := strings.ToLower(valueOr(cache.Size, "c1"))
+	sku := "Standard"
+	if strings.HasPrefix(vmSize, "p") {
+		sku = "Premium"
+	}
+	args := []string{"redis", "create",
+		"--name", cache.Name,
+		"--resource-group", cache.ResourceGroup,
+		"--location", p.region,
+		"--sku", sku,
+		"--vm-size", vmSize,
+	}
+	if cache.Version != "" {
+		args = append(args, "--redis-version", cache.Version)
+	}
+	args = append(args, azureTags()...)
+
+	_, err := p.run(ctx, args)
+	return err
+}
+
+func (p *azureProvider) deleteCache(ctx context.Context, cache config.ManagedCache) error {
+	_, err := p.run(ctx, []string{"redis", "delete", "--name", cache.Name, "--resource-group", cache.ResourceGroup, "--yes"})
+	return err
+}
+
+// flexibleServerTier derives the pricing tier from a flexible server SKU name
+func flexibleServerTier(sku string) string {
+	switch {
+	case strings.HasPrefix(sku, "Standard_B"):
+		return "Burstable"
+	case strings.HasPrefix(sku, "Standard_E"):
+		return "MemoryOptimized"
+	default:
+		return "GeneralPurpose"
+	}
+}
+
+// azureTags returns the --tags arguments for the run's label policy
+func azureTags() []string {
+	pairs := labels.Args(labels.Global())
+	if len(pairs) == 0 {
+		return nil
+	}
+	return append([]string{"--tags"}, pairs...)
+}
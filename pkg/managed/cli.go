@@ -0,0 +1,72 @@
+package managed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// errNotFound marks a describe call for a service that does not exist
+var errNotFound = errors.New("not found")
+
+// notFoundPatterns match the errors each CLI returns when describing a missing service
+var notFoundPatterns = regexp.MustCompile(`(?i)(NotFound|not found|was not found|does not exist|ResourceNotFound)`)
+
+var outputNamePattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func checkBinary(binary string) error {
+	if _, err := execx.LookPath(binary); err != nil {
+		return fmt.Errorf("%s CLI not found in PATH, it is required for managed services: %w", binary, err)
+	}
+	return nil
+}
+
+// runCLI runs a cloud CLI and returns its stdout. Secrets are masked in the logged
+// command and in errors. A missing resource is reported as errNotFound.
+func runCLI(ctx context.Context, binary string, args []string, secrets ...string) ([]byte, error) {
+	cmd := &execx.Cmd{Name: binary, Args: args, Secrets: secrets}
+	logger.Debug("Running cloud CLI").Str("command", cmd.String()).Send()
+
+	stdout, err := cmd.Output(ctx)
+	if err != nil {
+		if notFoundPatterns.MatchString(err.Error()) {
+			return nil, fmt.Errorf("%w: %s", errNotFound, err)
+		}
+		return nil, fmt.Errorf("%s %s failed: %w", binary, strings.Join(args[:min(len(args), 3)], " "), err)
+	}
+	return stdout, nil
+}
+
+// secretFile writes a private temporary file for a CLI to read a secret from, so the secret
+// never appears in the process list. The caller removes the file.
+func secretFile(pattern string, content []byte) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	_, writeErr := file.Write(content)
+	if err := errors.Join(writeErr, file.Close()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// outputName makes a service name usable in an output name
+func outputName(name string) string {
+	return strings.ToLower(outputNamePattern.ReplaceAllString(name, "_"))
+}
+
+// valueOr returns value, or fallback when it is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,200 @@
+package managed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
+	"gopkg.in/yaml.v3"
+)
+
+// gcpProvider provisions Cloud SQL and Memorystore instances with the gcloud CLI
+type gcpProvider struct {
+	region  string
+	project string
+}
+
+type cloudSQLInstance struct {
+	State       string `json:"state"`
+	IPAddresses []struct {
+		IPAddress string `json:"ipAddress"`
+		Type      string `json:"type"`
+	} `json:"ipAddresses"`
+}
+
+type memorystoreInstance struct {
+	State string `json:"state"`
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+}
+
+// gcpLabelInvalid matches characters GCP labels do not allow
+var gcpLabelInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+var defaultDatabasePorts = map[string]int{"postgres": 5432, "mysql": 3306}
+
+func (p *gcpProvider) name() string   { return "gcp" }
+func (p *gcpProvider) binary() string { return "gcloud" }
+
+func (p *gcpProvider) run(ctx context.Context, args []string, secrets ...string) ([]byte, error) {
+	args = append(args, "--format", "json", "--quiet")
+	if p.project != "" {
+		args = append(args, "--project", p.project)
+	}
+	return runCLI(ctx, p.binary(), args, secrets...)
+}
+
+func (p *gcpProvider) describeDatabase(ctx context.Context, db config.ManagedDatabase) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{"sql", "instances", "describe", db.Name})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var instance cloudSQLInstance
+	if err := json.Unmarshal(out, &instance); err != nil {
+		return nil, fmt.Errorf("failed to parse Cloud SQL instance: %w", err)
+	}
+	endpoint := &Endpoint{Status: instance.State, Port: defaultDatabasePorts[db.Engine]}
+	// Prefer the private address when the instance has one
+	for _, address := range instance.IPAddresses {
+		if address.Type == "PRIVATE" || endpoint.Host == "" {
+			endpoint.Host = address.IPAddress
+		}
+	}
+	endpoint.Ready = instance.State == "RUNNABLE" && endpoint.Host != ""
+	return endpoint, nil
+}
+
+func (p *gcpProvider) createDatabase(ctx context.Context, db config.ManagedDatabase, password string) error {
+	rootFlags, err := passwordFlags("--root-password", password)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(rootFlags)
+
+	args := []string{"sql", "instances", "create", db.Name,
+		"--database-version", cloudSQLVersion(db),
+		"--tier", valueOr(db.Size, "db-custom-2-7680"),
+		"--region", p.region,
+		"--flags-file", rootFlags,
+	}
+	if db.StorageGB > 0 {
+		args = append(args, "--storage-size", fmt.Sprintf("%dGB", db.StorageGB))
+	}
+	if labelArg := gcpLabels(); labelArg != "" {
+		args = append(args, "--labels", labelArg)
+	}
+	if _, err := p.run(ctx, args, password); err != nil {
+		return err
+	}
+
+	// Cloud SQL creates only the root user, postgres or root, with the instance
+	if db.Username != "postgres" && db.Username != "root" {
+		userFlags, err := passwordFlags("--password", password)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(userFlags)
+		if _, err := p.run(ctx, []string{"sql", "users", "create", db.Username, "--instance", db.Name, "--flags-file", userFlags}, password); err != nil {
+			return fmt.Errorf("failed to create user %s: %w", db.Username, err)
+		}
+	}
+	if db.Database != "" {
+		if _, err := p.run(ctx, []string{"sql", "databases", "create", db.Database, "--instance", db.Name}); err != nil {
+			return fmt.Errorf("failed to create database %s: %w", db.Database, err)
+		}
+	}
+	return nil
+}
+
+// passwordFlags writes a password flag into a private gcloud --flags-file, so the password
+// never appears in the process list
+func passwordFlags(flag, password string) (string, error) {
+	data, err := yaml.Marshal(map[string]string{flag: password})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %w", flag, err)
+	}
+	path, err := secretFile("gcloud-flags-*.yaml", data)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s flags file: %w", flag, err)
+	}
+	return path, nil
+}
+
+func (p *gcpProvider) deleteDatabase(ctx context.Context, db config.ManagedDatabase) error {
+	_, err := p.run(ctx, []string{"sql", "instances", "delete", db.Name})
+	return err
+}
+
+func (p *gcpProvider) describeCache(ctx context.Context, cache config.ManagedCache) (*Endpoint, error) {
+	out, err := p.run(ctx, []string{"redis", "instances", "describe", cache.Name, "--region", p.region})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var instance memorystoreInstance
+	if err := json.Unmarshal(out, &instance); err != nil {
+		return nil, fmt.Errorf("failed to parse Memorystore instance: %w", err)
+	}
+	return &Endpoint{
+		Status: instance.State,
+		Host:   instance.Host,
+		Port:   instance.Port,
+		Ready:  instance.State == "READY" && instance.Host != "",
+	}, nil
+}
+
+func (p *gcpProvider) createCache(ctx context.Context, cache config.ManagedCache) error {
+	args := []string{"redis", "instances", "create", cache.Name,
+		"--region", p.region,
+		"--size", valueOr(cache.Size, "1"),
+	}
+	if cache.Version != "" {
+		args = append(args, "--redis-version", "redis_"+strings.ReplaceAll(cache.Version, ".", "_"))
+	}
+	if labelArg := gcpLabels(); labelArg != "" {
+		args = append(args, "--labels", labelArg)
+	}
+	_, err := p.run(ctx, args)
+	return err
+}
+
+func (p *gcpProvider) deleteCache(ctx context.Context, cache config.ManagedCache) error {
+	_, err := p.run(ctx, []string{"redis", "instances", "delete", cache.Name, "--region", p.region})
+	return err
+}
+
+// cloudSQLVersion maps engine and version to a Cloud SQL database version, e.g. POSTGRES_15
+func cloudSQLVersion(db config.ManagedDatabase) string {
+	if db.Engine == "mysql" {
+		return "MYSQL_" + strings.ReplaceAll(valueOr(db.Version, "8.0"), ".", "_")
+	}
+	major, _, _ := strings.Cut(valueOr(db.Version, "15"), ".")
+	return "POSTGRES_" + major
+}
+
+// gcpLabels formats the label policy as GCP labels, which must be lowercase
+func gcpLabels() string {
+	policy := labels.Global()
+	pairs := make([]string, 0, len(policy))
+	for key, value := range policy {
+		key = gcpLabelInvalid.ReplaceAllString(strings.ToLower(key), "_")
+		value = gcpLabelInvalid.ReplaceAllString(strings.ToLower(value), "_")
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
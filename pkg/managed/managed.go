@@ -0,0 +1,425 @@
+package managed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+)
+
+// Service kinds
+const (
+	KindDatabase = "database"
+	KindCache    = "cache"
+)
+
+// Plan actions
+const (
+	ActionCreate = "create"
+	ActionExists = "exists"
+	ActionDelete = "delete"
+	ActionNone   = "none"
+)
+
+// DefaultTimeout bounds creating one service and waiting for it to become available
+const DefaultTimeout = 45 * time.Minute
+
+// pollInterval is how often a service being created is described again
+var pollInterval = 30 * time.Second
+
+// Endpoint is where a provisioned service is reached
+type Endpoint struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Provider string `json:"provider"`
+	Engine   string `json:"engine"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Ready    bool   `json:"ready"`
+}
+
+// PlannedChange is what Apply or Destroy would do to a service
+type PlannedChange struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	Status string `json:"status,omitempty"`
+}
+
+// provider creates and describes services through one cloud's CLI. Describe calls return
+// nil without an error when the service does not exist.
+type provider interface {
+	name() string
+	binary() string
+	describeDatabase(ctx context.Context, db config.ManagedDatabase) (*Endpoint, error)
+	createDatabase(ctx context.Context, db config.ManagedDatabase, password string) error
+	deleteDatabase(ctx context.Context, db config.ManagedDatabase) error
+	describeCache(ctx context.Context, cache config.ManagedCache) (*Endpoint, error)
+	createCache(ctx context.Context, cache config.ManagedCache) error
+	deleteCache(ctx context.Context, cache config.ManagedCache) error
+}
+
+// Manager provisions the managed databases and caches of the configuration
+type Manager struct {
+	config   config.ManagedServices
+	provider provider
+	timeout  time.Duration
+}
+
+// Configured reports whether any managed service is declared
+func Configured(services config.ManagedServices) bool {
+	return len(services.Databases) > 0 || len(services.Caches) > 0
+}
+
+// NewManager creates a managed services manager for the configured cloud
+func NewManager(services config.ManagedServices, cloud config.CloudConfig) (*Manager, error) {
+	mgr := &Manager{config: services, timeout: DefaultTimeout}
+
+	if services.Timeout != "" {
+		timeout, err := time.ParseDuration(services.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid managed services timeout %q: %w", services.Timeout, err)
+		}
+		mgr.timeout = timeout
+	}
+
+	switch cloud.Provider {
+	case "aws":
+		mgr.provider = &awsProvider{region: cloud.Region, profile: cloud.AWS.Profile}
+	case "gcp":
+		mgr.provider = &gcpProvider{region: cloud.Region, project: cloud.GCP.ProjectID}
+	case "azure":
+		for _, db := range services.Databases {
+			if db.ResourceGroup == "" {
+				return nil, fmt.Errorf("managed database %s requires a resourceGroup on azure", db.Name)
+			}
+		}
+		for _, cache := range services.Caches {
+			if cache.ResourceGroup == "" {
+				return nil, fmt.Errorf("managed cache %s requires a resourceGroup on azure", cache.Name)
+			}
+		}
+		mgr.provider = &azureProvider{region: cloud.Region, subscription: cloud.Azure.SubscriptionID}
+	default:
+		return nil, fmt.Errorf("managed services are not supported on cloud provider %q", cloud.Provider)
+	}
+
+	primaries := 0
+	for _, db := range services.Databases {
+		if db.Primary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return nil, fmt.Errorf("only one managed database can be primary, %d are", primaries)
+	}
+
+	return mgr, nil
+}
+
+// CheckTools verifies the provider CLI is installed
+func (m *Manager) CheckTools() error {
+	return checkBinary(m.provider.binary())
+}
+
+// Plan describes every service and reports whether Apply would create it, or whether
+// Destroy would delete it when destroy is set
+func (m *Manager) Plan(destroy bool) ([]PlannedChange, error) {
+	var changes []PlannedChange
+	err := m.each(func(ctx context.Context, kind, name string, describe func(context.Context) (*Endpoint, error)) error {
+		endpoint, err := describe(ctx)
+		if err != nil {
+			return err
+		}
+		change := PlannedChange{Name: name, Kind: kind}
+		switch {
+		case endpoint == nil && destroy:
+			change.Action = ActionNone
+		case endpoint == nil:
+			change.Action = ActionCreate
+		case destroy:
+			change.Action = ActionDelete
+			change.Status = endpoint.Status
+		default:
+			change.Action = ActionExists
+			change.Status = endpoint.Status
+		}
+		changes = append(changes, change)
+		return nil
+	})
+	return changes, err
+}
+
+// Apply creates the services that do not exist yet and waits until every service is
+// available. Existing services are left as they are, so Apply can be re-run safely.
+func (m *Manager) Apply() ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	for _, db := range m.config.Databases {
+		db := db
+		endpoint, err := m.ensure(KindDatabase, db.Name,
+			func(ctx context.Context) (*Endpoint, error) { return m.provider.describeDatabase(ctx, db) },
+			func(ctx context.Context) error {
+				password, ok := params.GetStore().GetString(db.PasswordParam)
+				if !ok || password == "" {
+					return fmt.Errorf("admin password parameter %s is not set", db.PasswordParam)
+				}
+				return m.provider.createDatabase(ctx, db, password)
+			},
+			map[string]interface{}{"engine": db.Engine, "version": db.Version, "size": db.Size})
+		if err != nil {
+			return endpoints, err
+		}
+		endpoint.Engine = db.Engine
+		endpoints = append(endpoints, *endpoint)
+	}
+
+	for _, cache := range m.config.Caches {
+		cache := cache
+		endpoint, err := m.ensure(KindCache, cache.Name,
+			func(ctx context.Context) (*Endpoint, error) { return m.provider.describeCache(ctx, cache) },
+			func(ctx context.Context) error { return m.provider.createCache(ctx, cache) },
+			map[string]interface{}{"engine": cacheEngine(cache), "version": cache.Version, "size": cache.Size})
+		if err != nil {
+			return endpoints, err
+		}
+		endpoint.Engine = cacheEngine(cache)
+		endpoints = append(endpoints, *endpoint)
+	}
+
+	return endpoints, nil
+}
+
+// Destroy deletes every service that exists, continuing past failures
+func (m *Manager) Destroy() error {
+	var errs []error
+
+	for _, db := range m.config.Databases {
+		db := db
+		if err := m.remove(KindDatabase, db.Name,
+			func(ctx context.Context) (*Endpoint, error) { return m.provider.describeDatabase(ctx, db) },
+			func(ctx context.Context) error { return m.provider.deleteDatabase(ctx, db) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, cache := range m.config.Caches {
+		cache := cache
+		if err := m.remove(KindCache, cache.Name,
+			func(ctx context.Context) (*Endpoint, error) { return m.provider.describeCache(ctx, cache) },
+			func(ctx context.Context) error { return m.provider.deleteCache(ctx, cache) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForDatabase polls an existing database every interval until the cloud reports it
+// available, calling progress with each status seen. It does not create the database.
+func (m *Manager) WaitForDatabase(db config.ManagedDatabase, interval time.Duration, progress func(status string, elapsed time.Duration)) (*Endpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	start := time.Now()
+
+	for {
+		endpoint, err := m.provider.describeDatabase(ctx, db)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s %s not available after %s", KindDatabase, db.Name, m.timeout)
+			}
+			return nil, fmt.Errorf("failed to describe %s %s: %w", KindDatabase, db.Name, err)
+		}
+
+		status := "not found"
+		if endpoint != nil {
+			status = endpoint.Status
+			if endpoint.Ready {
+				endpoint.Name = db.Name
+				endpoint.Kind = KindDatabase
+				endpoint.Provider = m.provider.name()
+				endpoint.Engine = db.Engine
+				return endpoint, nil
+			}
+		}
+		if progress != nil {
+			progress(status, time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s %s not available after %s (status %s)", KindDatabase, db.Name, m.timeout, status)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Outputs turns endpoints into infrastructure outputs named like Terraform outputs, so
+// they are published to the parameter store the same way. The primary database, or the
+// only one, also gets the database_* outputs db-migrate reads; the first cache gets cache_*.
+func (m *Manager) Outputs(endpoints []Endpoint) map[string]interface{} {
+	outputs := make(map[string]interface{})
+
+	primary := ""
+	for _, db := range m.config.Databases {
+		if db.Primary || len(m.config.Databases) == 1 {
+			primary = db.Name
+		}
+	}
+	cachePublished := false
+
+	for _, endpoint := range endpoints {
+		prefix := "managed_" + outputName(endpoint.Name)
+		outputs[prefix+"_host"] = endpoint.Host
+		outputs[prefix+"_port"] = endpoint.Port
+
+		switch {
+		case endpoint.Kind == KindDatabase && endpoint.Name == primary:
+			db := m.database(endpoint.Name)
+			outputs["database_host"] = endpoint.Host
+			outputs["database_port"] = endpoint.Port
+			outputs["database_username"] = db.Username
+			outputs["database_instance"] = endpoint.Name
+			if db.Database != "" {
+				outputs["database_name"] = db.Database
+			}
+		case endpoint.Kind == KindCache && !cachePublished:
+			outputs["cache_host"] = endpoint.Host
+			outputs["cache_port"] = endpoint.Port
+			cachePublished = true
+		}
+	}
+	return outputs
+}
+
+// ensure creates a service unless it exists and waits for it to become available
+func (m *Manager) ensure(kind, name string, describe func(context.Context) (*Endpoint, error), create func(context.Context) error, details map[string]interface{}) (*Endpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	endpoint, err := describe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe %s %s: %w", kind, name, err)
+	}
+
+	if endpoint == nil {
+		logger.Info("Creating managed service").
+			Str("kind", kind).
+			Str("name", name).
+			Str("provider", m.provider.name()).
+			Send()
+
+		err := create(ctx)
+		details["provider"] = m.provider.name()
+		audit.Record("managed.create", kind+"/"+name, details, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s %s: %w", kind, name, err)
+		}
+	} else {
+		logger.Info("Managed service already exists").
+			Str("kind", kind).
+			Str("name", name).
+			Str("status", endpoint.Status).
+			Send()
+	}
+
+	for endpoint == nil || !endpoint.Ready {
+		if endpoint != nil {
+			logger.Debug("Waiting for managed service").Str("name", name).Str("status", endpoint.Status).Send()
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%s %s not available after %s (status %s)", kind, name, m.timeout, endpoint.Status)
+			case <-time.After(pollInterval):
+			}
+		}
+		if endpoint, err = describe(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s %s not available after %s", kind, name, m.timeout)
+			}
+			return nil, fmt.Errorf("failed to describe %s %s: %w", kind, name, err)
+		}
+		if endpoint == nil {
+			return nil, fmt.Errorf("%s %s was not found after creation", kind, name)
+		}
+	}
+
+	endpoint.Name = name
+	endpoint.Kind = kind
+	endpoint.Provider = m.provider.name()
+
+	logger.Info("Managed service available").
+		Str("kind", kind).
+		Str("name", name).
+		Str("host", endpoint.Host).
+		Int("port", endpoint.Port).
+		Send()
+	return endpoint, nil
+}
+
+// remove deletes a service if it exists
+func (m *Manager) remove(kind, name string, describe func(context.Context) (*Endpoint, error), remove func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	endpoint, err := describe(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe %s %s: %w", kind, name, err)
+	}
+	if endpoint == nil {
+		logger.Info("Managed service does not exist, nothing to delete").Str("kind", kind).Str("name", name).Send()
+		return nil
+	}
+
+	logger.Info("Deleting managed service").Str("kind", kind).Str("name", name).Send()
+	err = remove(ctx)
+	audit.Record("managed.delete", kind+"/"+name, map[string]interface{}{"provider": m.provider.name()}, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// each runs fn for every configured service with a describe function bound to it
+func (m *Manager) each(fn func(ctx context.Context, kind, name string, describe func(context.Context) (*Endpoint, error)) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	for _, db := range m.config.Databases {
+		db := db
+		if err := fn(ctx, KindDatabase, db.Name, func(ctx context.Context) (*Endpoint, error) {
+			return m.provider.describeDatabase(ctx, db)
+		}); err != nil {
+			return fmt.Errorf("failed to describe %s %s: %w", KindDatabase, db.Name, err)
+		}
+	}
+	for _, cache := range m.config.Caches {
+		cache := cache
+		if err := fn(ctx, KindCache, cache.Name, func(ctx context.Context) (*Endpoint, error) {
+			return m.provider.describeCache(ctx, cache)
+		}); err != nil {
+			return fmt.Errorf("failed to describe %s %s: %w", KindCache, cache.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) database(name string) config.ManagedDatabase {
+	for _, db := range m.config.Databases {
+		if db.Name == name {
+			return db
+		}
+	}
+	return config.ManagedDatabase{}
+}
+
+func cacheEngine(cache config.ManagedCache) string {
+	if cache.Engine == "" {
+		return "redis"
+	}
+	return cache.Engine
+}
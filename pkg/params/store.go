@@ -0,0 +1,344 @@
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+)
+
+// Well-known parameter keys shared between steps
+const (
+	KeyDatabaseHost     = "database.host"
+	KeyDatabasePort     = "database.port"
+	KeyDatabaseName     = "database.name"
+	KeyDatabaseUsername = "database.username"
+	KeyDatabasePassword = "database.password"
+	KeyDatabaseInstance = "database.instance"
+	KeyCacheHost        = "cache.host"
+	KeyCachePort        = "cache.port"
+	KeyKubeconfig       = "kubernetes.kubeconfig"
+)
+
+// Store is a typed key-value store shared between installation steps
+type Store struct {
+	mutex     sync.RWMutex
+	stateFile string
+	params    map[string]config.Parameter
+}
+
+// NewStore creates an empty store persisted to the given state file, or kept in memory only
+// when stateFile is empty
+func NewStore(stateFile string) *Store {
+	return &Store{
+		stateFile: stateFile,
+		params:    make(map[string]config.Parameter),
+	}
+}
+
+// Load creates a store populated from the parameters of an existing state file
+func Load(stateFile string) (*Store, error) {
+	store := NewStore(stateFile)
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state config.InstallState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", stateFile, err)
+	}
+	store.Restore(state.Parameters)
+
+	return store, nil
+}
+
+// Set stores a value produced by the given step
+func (s *Store) Set(key string, value interface{}, source string) {
+	s.set(key, value, source, false)
+}
+
+// SetSensitive stores a value that must be redacted from logs and reports
+func (s *Store) SetSensitive(key string, value interface{}, source string) {
+	s.set(key, value, source, true)
+}
+
+// SetSecret stores a sensitive value that is kept out of the persisted state,
+// for credentials whose source of truth is a secret backend
+func (s *Store) SetSecret(key string, value interface{}, source string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.params[key] = config.Parameter{
+		Type:      typeOf(value),
+		Value:     value,
+		Source:    source,
+		Sensitive: true,
+		Transient: true,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	logger.Debug("Parameter set").Str("key", key).Str("source", source).Send()
+}
+
+func (s *Store) set(key string, value interface{}, source string, sensitive bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.params[key] = config.Parameter{
+		Type:      typeOf(value),
+		Value:     value,
+		Source:    source,
+		Sensitive: sensitive,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	event := logger.Debug("Parameter set").Str("key", key).Str("source", source)
+	if !sensitive {
+		event = event.Interface("value", value)
+	}
+	event.Send()
+}
+
+// Get returns the parameter stored under key
+func (s *Store) Get(key string) (config.Parameter, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	param, ok := s.params[key]
+	return param, ok
+}
+
+// GetString returns a parameter rendered as a string
+func (s *Store) GetString(key string) (string, bool) {
+	param, ok := s.Get(key)
+	if !ok {
+		return "", false
+	}
+	return formatValue(param.Value), true
+}
+
+// GetInt returns a numeric parameter as an int
+func (s *Store) GetInt(key string) (int, bool) {
+	param, ok := s.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch v := param.Value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// GetBool returns a boolean parameter
+func (s *Store) GetBool(key string) (bool, bool) {
+	param, ok := s.Get(key)
+	if !ok {
+		return false, false
+	}
+	v, ok := param.Value.(bool)
+	return v, ok
+}
+
+// Values returns the raw values keyed by name, for use in templates
+func (s *Store) Values() map[string]interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	values := make(map[string]interface{}, len(s.params))
+	for key, param := range s.params {
+		values[key] = param.Value
+	}
+	return values
+}
+
+// Environ returns parameters as environment variables, upper-casing keys and replacing dots and dashes
+func (s *Store) Environ() map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	env := make(map[string]string, len(s.params))
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	for key, param := range s.params {
+		env[strings.ToUpper(replacer.Replace(key))] = formatValue(param.Value)
+	}
+	return env
+}
+
+// SensitiveValues returns the string form of every sensitive parameter, for redaction
+func (s *Store) SensitiveValues() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var secrets []string
+	for _, param := range s.params {
+		if value := formatValue(param.Value); param.Sensitive && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// Export returns a copy of all non-transient parameters for persisting in the state
+func (s *Store) Export() map[string]config.Parameter {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	params := make(map[string]config.Parameter, len(s.params))
+	for key, param := range s.params {
+		if param.Transient {
+			continue
+		}
+		params[key] = param
+	}
+	return params
+}
+
+// Restore replaces the store contents with previously persisted parameters
+func (s *Store) Restore(params map[string]config.Parameter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.params = make(map[string]config.Parameter, len(params))
+	for key, param := range params {
+		s.params[key] = param
+	}
+}
+
+// Merge adds persisted parameters to the store, e.g. those published by the installer
+// commands install runs, replacing values of the same key
+func (s *Store) Merge(params map[string]config.Parameter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, param := range params {
+		s.params[key] = param
+	}
+}
+
+// Keys returns the sorted parameter names
+func (s *Store) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := make([]string, 0, len(s.params))
+	for key := range s.params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StateFile returns the installation state file the store is persisted in
+func (s *Store) StateFile() string {
+	return s.stateFile
+}
+
+// Persist writes the parameters into the state file, leaving other state fields and the
+// parameters of other keys untouched
+func (s *Store) Persist() error {
+	if s.stateFile == "" {
+		return fmt.Errorf("parameters are not backed by a state file, no workspace was selected")
+	}
+	return workspace.UpdateState(s.stateFile, func(sections map[string]json.RawMessage) error {
+		// Parameters other processes published meanwhile, e.g. parallel install steps, are kept
+		var published map[string]config.Parameter
+		if raw, ok := sections["parameters"]; ok {
+			if err := json.Unmarshal(raw, &published); err != nil {
+				return fmt.Errorf("failed to parse parameters: %w", err)
+			}
+		}
+		params := s.Export()
+		for key, param := range published {
+			if _, ok := params[key]; !ok {
+				params[key] = param
+			}
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal parameters: %w", err)
+		}
+		sections["parameters"] = data
+		return nil
+	})
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "bool"
+	case int, int32, int64, float32, float64:
+		return "number"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool, int, int32, int64, float32, float64:
+		return fmt.Sprintf("%v", v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// Global store instance
+var (
+	globalStore *Store
+	globalMutex sync.Mutex
+)
+
+// InitGlobalStore sets the global store, loading existing parameters from the state file when resume is true
+func InitGlobalStore(stateFile string, resume bool) error {
+	store := NewStore(stateFile)
+	if resume {
+		loaded, err := Load(stateFile)
+		if err != nil {
+			return err
+		}
+		store = loaded
+	}
+
+	globalMutex.Lock()
+	globalStore = store
+	globalMutex.Unlock()
+	return nil
+}
+
+// GetStore returns the global store. Before InitGlobalStore it is an empty store kept in
+// memory, never the parameters of some other workspace.
+func GetStore() *Store {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	if globalStore == nil {
+		globalStore = NewStore("")
+	}
+	return globalStore
+}
@@ -0,0 +1,168 @@
+package postrender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+)
+
+// Post-renderer types
+const (
+	TypeKustomize = "kustomize"
+	TypeExec      = "exec"
+)
+
+// RenderedFile is the file the chart's rendered manifests are written to inside a kustomize overlay
+const RenderedFile = "helm-rendered.yaml"
+
+// execTimeout bounds an external post-renderer so a hung binary cannot stall the release
+const execTimeout = 5 * time.Minute
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Run passes the manifests through each post-renderer in order
+func Run(manifest []byte, renderers []config.ChartPostRenderer) ([]byte, error) {
+	for i, renderer := range renderers {
+		var err error
+		switch renderer.Type {
+		case TypeKustomize:
+			manifest, err = Kustomize(manifest, renderer.Path)
+		case TypeExec:
+			manifest, err = Exec(manifest, renderer.Path, renderer.Args)
+		default:
+			err = fmt.Errorf("unknown post-renderer type %q", renderer.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("post-renderer %d (%s %s) failed: %w", i+1, renderer.Type, renderer.Path, err)
+		}
+	}
+	return manifest, nil
+}
+
+// Validate checks that every post-renderer's overlay or binary exists before a release uses it
+func Validate(renderers []config.ChartPostRenderer) error {
+	for i, renderer := range renderers {
+		switch renderer.Type {
+		case TypeKustomize:
+			found := false
+			for _, name := range kustomizationFiles {
+				if _, err := os.Stat(filepath.Join(renderer.Path, name)); err == nil {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("post-renderer %d: no kustomization.yaml in overlay %s", i+1, renderer.Path)
+			}
+		case TypeExec:
+			if _, err := execx.LookPath(renderer.Path); err != nil {
+				return fmt.Errorf("post-renderer %d: %w", i+1, err)
+			}
+		default:
+			return fmt.Errorf("post-renderer %d: unknown type %q (expected %s or %s)", i+1, renderer.Type, TypeKustomize, TypeExec)
+		}
+	}
+	return nil
+}
+
+// Kustomize builds the overlay with the rendered chart as a resource, using the kustomize
+// library rather than a kustomize binary. The overlay is copied into memory, so it must be
+// self-contained; it is never modified on disk. RenderedFile is added to its resources
+// when the kustomization does not list it already.
+func Kustomize(manifest []byte, overlayDir string) ([]byte, error) {
+	overlay, err := filepath.Abs(overlayDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve overlay %s: %w", overlayDir, err)
+	}
+
+	memFS := filesys.MakeFsInMemory()
+	err = filepath.WalkDir(overlay, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return memFS.MkdirAll(path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return memFS.WriteFile(path, data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay %s: %w", overlayDir, err)
+	}
+
+	if err := memFS.WriteFile(filepath.Join(overlay, RenderedFile), manifest); err != nil {
+		return nil, err
+	}
+	if err := includeRendered(memFS, overlay); err != nil {
+		return nil, err
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(memFS, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	}
+	return resources.AsYaml()
+}
+
+// includeRendered adds RenderedFile to the overlay's resources
+func includeRendered(memFS filesys.FileSystem, overlay string) error {
+	var path string
+	for _, name := range kustomizationFiles {
+		if candidate := filepath.Join(overlay, name); memFS.Exists(candidate) {
+			path = candidate
+			break
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("no kustomization.yaml in %s", overlay)
+	}
+
+	data, err := memFS.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var kustomization map[string]interface{}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if kustomization == nil {
+		kustomization = make(map[string]interface{})
+	}
+
+	resources, _ := kustomization["resources"].([]interface{})
+	for _, resource := range resources {
+		if resource == RenderedFile {
+			return nil
+		}
+	}
+	kustomization["resources"] = append([]interface{}{RenderedFile}, resources...)
+
+	data, err = yaml.Marshal(kustomization)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return memFS.WriteFile(path, data)
+}
+
+// Exec runs an external post-renderer, which reads manifests on stdin and writes them to stdout
+func Exec(manifest []byte, path string, args []string) ([]byte, error) {
+	cmd := execx.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(manifest)
+	cmd.Timeout = execTimeout
+	cmd.Tag = "post-renderer " + filepath.Base(path)
+	return cmd.Output(context.Background())
+}
@@ -0,0 +1,51 @@
+package progress
+
+import "strings"
+
+// Branding names the product in the banner, the progress header and the summary footer, so
+// the same binary can be white-labeled per customer engagement
+type Branding struct {
+	ProductName string
+	Edition     string
+	Tagline     string
+	Logo        string // ASCII art printed instead of the framed product name
+	SupportURL  string
+	Version     string // overrides the version passed to the banner
+}
+
+// DefaultBranding is the installer's own branding
+var DefaultBranding = Branding{
+	ProductName: "Kubernetes Installer",
+	Edition:     "Enterprise Edition",
+	Tagline:     "Enterprise Kubernetes Installation Platform",
+}
+
+var currentBranding = DefaultBranding
+
+// SetBranding replaces the branding; empty fields keep the default
+func SetBranding(branding Branding) {
+	if branding.ProductName == "" {
+		branding.ProductName = DefaultBranding.ProductName
+	}
+	if branding.Edition == "" {
+		branding.Edition = DefaultBranding.Edition
+	}
+	if branding.Tagline == "" {
+		branding.Tagline = DefaultBranding.Tagline
+	}
+	branding.Logo = strings.TrimRight(branding.Logo, "\n")
+	currentBranding = branding
+}
+
+// CurrentBranding returns the branding output is rendered with
+func CurrentBranding() Branding {
+	return currentBranding
+}
+
+// bannerVersion is the configured version string, or the installer's own
+func bannerVersion(version string) string {
+	if currentBranding.Version != "" {
+		return currentBranding.Version
+	}
+	return version
+}
@@ -0,0 +1,250 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// paneRefresh is how often running panes are redrawn for their spinner and elapsed time
+const paneRefresh = 200 * time.Millisecond
+
+var paneFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Panes renders concurrent operations in one live area, a line per operation, so parallel
+// work never fights over the terminal. Each pane is updated independently; finished panes
+// collapse into a summary line while failed ones stay visible with their error. Without a
+// terminal, e.g. in CI logs, every state change is printed as a line instead.
+type Panes struct {
+	title       string
+	panes       []*Pane
+	area        *pterm.AreaPrinter
+	interactive bool
+	frame       int
+	done        chan struct{}
+	stopped     sync.WaitGroup
+	onStop      func()
+	mu          sync.Mutex
+}
+
+// Pane is one operation of a Panes renderer
+type Pane struct {
+	panes    *Panes
+	name     string
+	status   OperationStatus
+	activity string
+	started  time.Time
+	elapsed  time.Duration
+}
+
+// NewPanes creates a renderer; panes are added before or after Start
+func NewPanes(title string) *Panes {
+	return &Panes{title: title, interactive: term.IsTerminal(int(os.Stdout.Fd()))}
+}
+
+// Start begins redrawing the panes
+func (p *Panes) Start() {
+	if !p.interactive {
+		pterm.Info.Println(p.title)
+		return
+	}
+	p.area, _ = pterm.DefaultArea.Start()
+	p.done = make(chan struct{})
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(paneRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.redraw()
+			}
+		}
+	}()
+}
+
+// Stop draws the final state of every pane and releases the terminal
+func (p *Panes) Stop() {
+	if p.done != nil {
+		close(p.done)
+		p.stopped.Wait()
+		p.done = nil
+		p.redraw()
+		p.area.Stop()
+	}
+	if p.onStop != nil {
+		p.onStop()
+	}
+}
+
+// Add creates a queued pane
+func (p *Panes) Add(name string) *Pane {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pane := &Pane{panes: p, name: name, status: StatusPending}
+	p.panes = append(p.panes, pane)
+	return pane
+}
+
+// Run marks the pane as running
+func (pane *Pane) Run(activity string) {
+	pane.update(func() {
+		pane.status, pane.started, pane.activity = StatusRunning, time.Now(), activity
+	}, true)
+}
+
+// SetActivity replaces the text shown next to the pane's name
+func (pane *Pane) SetActivity(activity string) {
+	pane.update(func() { pane.activity = activity }, false)
+}
+
+// Done finishes the pane with status; message is shown for failures
+func (pane *Pane) Done(status OperationStatus, message string) {
+	pane.update(func() {
+		pane.status, pane.activity = status, message
+		if !pane.started.IsZero() {
+			pane.elapsed = time.Since(pane.started)
+		}
+	}, true)
+}
+
+// Writer returns a writer whose last complete line becomes the pane's activity, for output
+// that would otherwise be printed straight to the terminal
+func (pane *Pane) Writer() io.Writer {
+	return &paneWriter{pane: pane}
+}
+
+func (pane *Pane) update(change func(), transition bool) {
+	p := pane.panes
+	p.mu.Lock()
+	change()
+	line := ""
+	if transition && !p.interactive {
+		line = pane.lineLocked()
+	}
+	p.mu.Unlock()
+
+	if line != "" {
+		fmt.Println(line)
+	} else if transition {
+		p.redraw()
+	}
+}
+
+func (p *Panes) redraw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.area == nil {
+		return
+	}
+	p.frame++
+	p.area.Update(p.renderLocked())
+}
+
+// renderLocked draws the title, the summary of finished panes, then a line per failed,
+// running and queued pane
+func (p *Panes) renderLocked() string {
+	var b strings.Builder
+	b.WriteString(pterm.Bold.Sprint(p.title))
+	b.WriteString("\n")
+
+	var completed []string
+	for _, pane := range p.panes {
+		if pane.status == StatusCompleted || pane.status == StatusSkipped {
+			completed = append(completed, pane.name)
+		}
+	}
+	if len(completed) > 0 {
+		fmt.Fprintf(&b, "  %s %d of %d done: %s\n", statusIcon(StatusCompleted), len(completed), len(p.panes), truncate(strings.Join(completed, ", "), paneWidth()-20))
+	}
+	for _, pane := range p.panes {
+		if pane.status != StatusCompleted && pane.status != StatusSkipped {
+			b.WriteString(pane.lineLocked())
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func (pane *Pane) lineLocked() string {
+	icon := pane.panes.iconLocked(pane.status)
+	elapsed := pane.elapsed
+	if pane.status == StatusRunning {
+		elapsed = time.Since(pane.started)
+	}
+	duration := ""
+	if pane.status != StatusPending && (pane.status != StatusRunning || pane.panes.interactive) {
+		duration = formatDuration(elapsed)
+	}
+	line := fmt.Sprintf("  %s %-24s %7s", icon, pane.name, duration)
+	if pane.activity != "" {
+		activity := truncate(pane.activity, paneWidth()-40)
+		if pane.status == StatusFailed {
+			activity = pterm.Red(activity)
+		}
+		line += "  " + activity
+	}
+	return line
+}
+
+func (p *Panes) iconLocked(status OperationStatus) string {
+	if status == StatusRunning && p.interactive {
+		return pterm.Yellow(paneFrames[p.frame%len(paneFrames)])
+	}
+	return statusIcon(status)
+}
+
+func paneWidth() int {
+	if width := pterm.GetTerminalWidth(); width > 40 {
+		return width
+	}
+	return 120
+}
+
+func truncate(s string, width int) string {
+	if width < 4 {
+		width = 4
+	}
+	if runes := []rune(s); len(runes) > width {
+		return string(runes[:width-3]) + "..."
+	}
+	return s
+}
+
+// paneWriter turns written output into pane activity
+type paneWriter struct {
+	pane    *Pane
+	partial []byte
+	mu      sync.Mutex
+}
+
+func (w *paneWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, data...)
+	last := ""
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if line := string(bytes.TrimSpace(w.partial[:i])); line != "" {
+			last = line
+		}
+		w.partial = w.partial[i+1:]
+	}
+	if last != "" {
+		w.pane.SetActivity(last)
+	}
+	return len(data), nil
+}
@@ -3,10 +3,13 @@ package progress
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/judebantony/e2e-k8s-installer/pkg/i18n"
 	"github.com/pterm/pterm"
 )
 
@@ -19,6 +22,9 @@ type ProgressManager struct {
 	mutex          sync.RWMutex
 	startTime      time.Time
 	enterpriseMode bool
+	activePanes    *Panes // the Panes renderer owning the terminal, if any
+
+	refreshInterval time.Duration // how often rate trackers redraw
 }
 
 // OperationProgress tracks detailed progress for enterprise operations
@@ -47,6 +53,26 @@ type SubStep struct {
 	Progress    int
 	Total       int
 	Description string
+	Metadata    map[string]interface{}
+}
+
+// OperationSummary is the state of an operation and its sub-steps as written to reports
+type OperationSummary struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Status      OperationStatus        `json:"status"`
+	DurationMs  int64                  `json:"durationMs"`
+	Description string                 `json:"description,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	SubSteps    []SubStepSummary       `json:"subSteps,omitempty"`
+}
+
+// SubStepSummary is the state of a sub-step as written to reports
+type SubStepSummary struct {
+	Name       string                 `json:"name"`
+	Status     OperationStatus        `json:"status"`
+	DurationMs int64                  `json:"durationMs"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // ServiceHealthStatus represents the health status of a service
@@ -205,6 +231,109 @@ func (pm *ProgressManager) UpdateSubStep(operationID, stepName string, progress
 	}
 }
 
+// SetMetadata attaches a value, such as a namespace or an image count, to an operation. It is
+// shown under the operation in the enterprise display and included in Operations.
+func (pm *ProgressManager) SetMetadata(operationID, key string, value interface{}) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if operation, exists := pm.operations[operationID]; exists {
+		operation.Metadata[key] = value
+
+		if pm.enterpriseMode {
+			pm.displayEnterpriseProgressUnsafe()
+		}
+	}
+}
+
+// SetSubStepMetadata attaches a value to a sub-step of an operation
+func (pm *ProgressManager) SetSubStepMetadata(operationID, stepName, key string, value interface{}) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if operation, exists := pm.operations[operationID]; exists {
+		for i, subStep := range operation.SubSteps {
+			if subStep.Name == stepName {
+				if subStep.Metadata == nil {
+					operation.SubSteps[i].Metadata = make(map[string]interface{})
+				}
+				operation.SubSteps[i].Metadata[key] = value
+				break
+			}
+		}
+
+		if pm.enterpriseMode {
+			pm.displayEnterpriseProgressUnsafe()
+		}
+	}
+}
+
+// Operations returns every tracked operation in start order, with copies of its metadata
+func (pm *ProgressManager) Operations() []OperationSummary {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	operations := make([]*OperationProgress, 0, len(pm.operations))
+	for _, operation := range pm.operations {
+		operations = append(operations, operation)
+	}
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].StartTime.Before(operations[j].StartTime)
+	})
+
+	summaries := make([]OperationSummary, 0, len(operations))
+	for _, operation := range operations {
+		duration := operation.Duration
+		if operation.EndTime == nil {
+			duration = time.Since(operation.StartTime)
+		}
+		summary := OperationSummary{
+			ID:          operation.ID,
+			Name:        operation.Name,
+			Status:      operation.Status,
+			DurationMs:  duration.Milliseconds(),
+			Description: operation.Description,
+			Metadata:    copyMetadata(operation.Metadata),
+		}
+		for _, subStep := range operation.SubSteps {
+			summary.SubSteps = append(summary.SubSteps, SubStepSummary{
+				Name:       subStep.Name,
+				Status:     subStep.Status,
+				DurationMs: subStep.Duration.Milliseconds(),
+				Metadata:   copyMetadata(subStep.Metadata),
+			})
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
+// formatMetadata renders metadata as sorted key=value pairs
+func formatMetadata(metadata map[string]interface{}) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, metadata[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
 // CompleteOperation marks an operation as complete
 func (pm *ProgressManager) CompleteOperation(id string, status OperationStatus, message string) {
 	pm.mutex.Lock()
@@ -281,7 +410,7 @@ func (pm *ProgressManager) getProgressMetricsUnsafe() ProgressMetrics {
 
 // displayEnterpriseProgress displays a comprehensive enterprise progress view
 func (pm *ProgressManager) displayEnterpriseProgress() {
-	if !pm.enterpriseMode {
+	if !pm.enterpriseMode || pm.activePanes != nil {
 		return
 	}
 
@@ -302,7 +431,7 @@ func (pm *ProgressManager) displayEnterpriseProgress() {
 
 // displayEnterpriseProgressUnsafe displays progress without acquiring mutex (for internal use)
 func (pm *ProgressManager) displayEnterpriseProgressUnsafe() {
-	if !pm.enterpriseMode {
+	if !pm.enterpriseMode || pm.activePanes != nil {
 		return
 	}
 
@@ -333,32 +462,32 @@ func (pm *ProgressManager) buildEnterpriseProgressContentUnsafe(metrics Progress
 	var content strings.Builder
 
 	// Header with branding
-	content.WriteString(pterm.DefaultHeader.Sprint("🏢 Enterprise Kubernetes Installer"))
+	content.WriteString(pterm.DefaultHeader.Sprint("🏢 " + currentBranding.ProductName))
 	content.WriteString("\n\n")
 
 	// Overall progress bar
 	progressBar := pm.createProgressBar(int(metrics.OverallProgress), 100)
-	content.WriteString(fmt.Sprintf("📊 Overall Progress: %s %.1f%%\n", progressBar, metrics.OverallProgress))
+	content.WriteString(i18n.Tf("📊 Overall Progress: %s %.1f%%\n", progressBar, metrics.OverallProgress))
 	content.WriteString("\n")
 
 	// Metrics dashboard
-	content.WriteString("📈 Execution Metrics:\n")
-	content.WriteString(fmt.Sprintf("   ⏱️  Elapsed Time: %s\n", formatDuration(metrics.ElapsedTime)))
+	content.WriteString(i18n.T("📈 Execution Metrics:\n"))
+	content.WriteString(i18n.Tf("   ⏱️  Elapsed Time: %s\n", formatDuration(metrics.ElapsedTime)))
 
 	if metrics.EstimatedTimeLeft > 0 {
-		content.WriteString(fmt.Sprintf("   ⏳ Estimated Time Left: %s\n", formatDuration(metrics.EstimatedTimeLeft)))
+		content.WriteString(i18n.Tf("   ⏳ Estimated Time Left: %s\n", formatDuration(metrics.EstimatedTimeLeft)))
 	}
 
-	content.WriteString(fmt.Sprintf("   🎯 Operations: %d total, %d completed, %d failed\n",
+	content.WriteString(i18n.Tf("   🎯 Operations: %d total, %d completed, %d failed\n",
 		metrics.TotalOperations, metrics.CompletedOperations, metrics.FailedOperations))
 
 	if metrics.Throughput > 0 {
-		content.WriteString(fmt.Sprintf("   🚀 Throughput: %.2f ops/sec\n", metrics.Throughput))
+		content.WriteString(i18n.Tf("   🚀 Throughput: %.2f ops/sec\n", metrics.Throughput))
 	}
 	content.WriteString("\n")
 
 	// Operation details
-	content.WriteString("🔄 Operation Status:\n")
+	content.WriteString(i18n.T("🔄 Operation Status:\n"))
 	for _, operation := range pm.operations {
 		content.WriteString(pm.formatOperationLine(operation))
 	}
@@ -401,6 +530,10 @@ func (pm *ProgressManager) formatOperationLine(operation *OperationProgress) str
 
 	line.WriteString("\n")
 
+	if len(operation.Metadata) > 0 {
+		line.WriteString(fmt.Sprintf("        %s\n", pterm.NewStyle(currentTheme.Muted).Sprint(formatMetadata(operation.Metadata))))
+	}
+
 	// Sub-steps (if any)
 	for _, subStep := range operation.SubSteps {
 		subProgressPercent := 0.0
@@ -419,9 +552,17 @@ func (pm *ProgressManager) formatOperationLine(operation *OperationProgress) str
 		if subStep.Status == StatusRunning && subStep.Total > 0 {
 			subProgressBar := pm.createProgressBar(subStep.Progress, subStep.Total)
 			line.WriteString(fmt.Sprintf(" %s %.1f%%", subProgressBar, subProgressPercent))
+			if eta := estimateRemaining(subStep.Progress, subStep.Total, time.Since(subStep.StartTime)); eta > 0 {
+				line.WriteString(fmt.Sprintf(" ETA %s", formatDuration(eta)))
+			}
 		}
 
-		line.WriteString(fmt.Sprintf(" (%s)\n", formatDuration(subDuration)))
+		line.WriteString(fmt.Sprintf(" (%s)", formatDuration(subDuration)))
+
+		if len(subStep.Metadata) > 0 {
+			line.WriteString(" " + pterm.NewStyle(currentTheme.Muted).Sprint(formatMetadata(subStep.Metadata)))
+		}
+		line.WriteString("\n")
 	}
 
 	return line.String()
@@ -451,29 +592,12 @@ func (pm *ProgressManager) createProgressBar(current, total int) string {
 	}
 	bar += "]"
 
-	return pterm.NewStyle(pterm.FgCyan).Sprint(bar)
+	return pterm.NewStyle(currentTheme.Primary).Sprint(bar)
 }
 
-// getStatusIcon returns the appropriate icon for operation status
+// getStatusIcon returns the appropriate icon for operation status in the current theme
 func (pm *ProgressManager) getStatusIcon(status OperationStatus) string {
-	switch status {
-	case StatusCompleted:
-		return pterm.Green("✅")
-	case StatusFailed:
-		return pterm.Red("❌")
-	case StatusRunning:
-		return pterm.Yellow("🔄")
-	case StatusPending:
-		return pterm.LightWhite("⏳")
-	case StatusSkipped:
-		return pterm.Yellow("⏭️")
-	case StatusCancelled:
-		return pterm.Red("🚫")
-	case StatusWarning:
-		return pterm.Yellow("⚠️")
-	default:
-		return pterm.LightWhite("❓")
-	}
+	return statusIcon(status)
 }
 
 // formatDuration formats a duration in a human-readable way
@@ -499,7 +623,7 @@ func (pm *ProgressManager) StartSpinner(id, message string) {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	spinner, _ := pterm.DefaultSpinner.WithText(message).Start()
+	spinner, _ := pterm.DefaultSpinner.WithText(i18n.T(message)).Start()
 	pm.spinners[id] = spinner
 }
 
@@ -509,7 +633,7 @@ func (pm *ProgressManager) UpdateSpinner(id, message string) {
 	defer pm.mutex.RUnlock()
 
 	if spinner, exists := pm.spinners[id]; exists {
-		spinner.UpdateText(message)
+		spinner.UpdateText(i18n.T(message))
 	}
 }
 
@@ -519,7 +643,7 @@ func (pm *ProgressManager) SuccessSpinner(id, message string) {
 	defer pm.mutex.Unlock()
 
 	if spinner, exists := pm.spinners[id]; exists {
-		spinner.Success(message)
+		spinner.Success(i18n.T(message))
 		delete(pm.spinners, id)
 	}
 }
@@ -530,7 +654,7 @@ func (pm *ProgressManager) FailSpinner(id, message string) {
 	defer pm.mutex.Unlock()
 
 	if spinner, exists := pm.spinners[id]; exists {
-		spinner.Fail(message)
+		spinner.Fail(i18n.T(message))
 		delete(pm.spinners, id)
 	}
 }
@@ -541,7 +665,7 @@ func (pm *ProgressManager) WarningSpinner(id, message string) {
 	defer pm.mutex.Unlock()
 
 	if spinner, exists := pm.spinners[id]; exists {
-		spinner.Warning(message)
+		spinner.Warning(i18n.T(message))
 		delete(pm.spinners, id)
 	}
 }
@@ -551,7 +675,7 @@ func (pm *ProgressManager) StartProgressBar(id, title string, total int) {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	progressBar, _ := pterm.DefaultProgressbar.WithTitle(title).WithTotal(total).Start()
+	progressBar, _ := pterm.DefaultProgressbar.WithTitle(i18n.T(title)).WithTotal(total).Start()
 	pm.progressBars[id] = progressBar
 }
 
@@ -586,6 +710,27 @@ func (pm *ProgressManager) CompleteProgressBar(id string) {
 	}
 }
 
+// StartPanes hands the terminal to a Panes renderer for concurrent operations. The
+// enterprise view is frozen at its current state and redrawn below the panes once they stop.
+func (pm *ProgressManager) StartPanes(title string) *Panes {
+	pm.mutex.Lock()
+	if area, exists := pm.areas["enterprise"]; exists {
+		area.Stop()
+		delete(pm.areas, "enterprise")
+	}
+	panes := NewPanes(title)
+	pm.activePanes = panes
+	pm.mutex.Unlock()
+
+	panes.onStop = func() {
+		pm.mutex.Lock()
+		defer pm.mutex.Unlock()
+		pm.activePanes = nil
+	}
+	panes.Start()
+	return panes
+}
+
 // StartArea starts a dynamic text area
 func (pm *ProgressManager) StartArea(id string) {
 	pm.mutex.Lock()
@@ -618,6 +763,13 @@ func (pm *ProgressManager) StopArea(id string) {
 
 // StopAll stops all active progress indicators
 func (pm *ProgressManager) StopAll() {
+	pm.mutex.RLock()
+	panes := pm.activePanes
+	pm.mutex.RUnlock()
+	if panes != nil {
+		panes.Stop()
+	}
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
@@ -673,27 +825,27 @@ func ShowStepProgress(steps []string, currentStep int) {
 	}
 
 	// Add progress header with percentage
-	content += pterm.DefaultHeader.Sprintf("📋 Installation Progress: %.1f%% (%d/%d)",
-		progressPercent, currentStep, len(steps)) + "\n\n"
+	content += pterm.DefaultHeader.Sprint(i18n.Tf("📋 Installation Progress: %.1f%% (%d/%d)",
+		progressPercent, currentStep, len(steps))) + "\n\n"
 
 	for i, step := range steps {
 		var symbol string
 		var color pterm.Color
 		if i < currentStep {
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 		} else if i == currentStep {
-			symbol = "🔄"
+			symbol = themeIcon(StatusRunning)
 			color = pterm.FgYellow
 		} else {
-			symbol = "⏳"
+			symbol = themeIcon(StatusPending)
 			color = pterm.FgLightWhite
 		}
 
 		content += fmt.Sprintf("  %s %s %s\n",
 			symbol,
 			pterm.NewStyle(pterm.FgLightWhite).Sprintf("%d.", i+1),
-			pterm.NewStyle(color).Sprintf("%s", step))
+			pterm.NewStyle(color).Sprintf("%s", i18n.T(step)))
 	}
 
 	// Add visual progress bar
@@ -715,11 +867,11 @@ func ShowImagePullProgress(images []string, completed []bool) {
 		var symbol string
 		var color pterm.Color
 		if len(completed) > i && completed[i] {
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 			completedCount++
 		} else {
-			symbol = "🔄"
+			symbol = themeIcon(StatusRunning)
 			color = pterm.FgYellow
 		}
 
@@ -759,24 +911,24 @@ func ShowHealthCheckProgress(checks map[string]string) {
 
 		switch status {
 		case "healthy":
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 			statusText = "HEALTHY"
 			healthyCount++
 		case "unhealthy":
-			symbol = "❌"
+			symbol = themeIcon(StatusFailed)
 			color = pterm.FgRed
 			statusText = "UNHEALTHY"
 		case "checking":
-			symbol = "🔄"
+			symbol = themeIcon(StatusRunning)
 			color = pterm.FgYellow
 			statusText = "CHECKING"
 		case "degraded":
-			symbol = "⚠️"
+			symbol = themeIcon(StatusWarning)
 			color = pterm.FgYellow
 			statusText = "DEGRADED"
 		default:
-			symbol = "⏳"
+			symbol = themeIcon(StatusPending)
 			color = pterm.FgLightWhite
 			statusText = "PENDING"
 		}
@@ -818,16 +970,16 @@ func ShowTerraformProgress(modules []string, status map[string]string) {
 
 		switch moduleStatus {
 		case "completed":
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 			statusText = "DEPLOYED"
 			completedCount++
 		case "running":
-			symbol = "🔄"
+			symbol = themeIcon(StatusRunning)
 			color = pterm.FgYellow
 			statusText = "DEPLOYING"
 		case "failed":
-			symbol = "❌"
+			symbol = themeIcon(StatusFailed)
 			color = pterm.FgRed
 			statusText = "FAILED"
 			failedCount++
@@ -836,7 +988,7 @@ func ShowTerraformProgress(modules []string, status map[string]string) {
 			color = pterm.FgCyan
 			statusText = "PLANNED"
 		default:
-			symbol = "⏳"
+			symbol = themeIcon(StatusPending)
 			color = pterm.FgLightWhite
 			statusText = "PENDING"
 		}
@@ -885,21 +1037,21 @@ func ShowTestProgress(testSuites []string, results map[string]TestResult) {
 
 		switch result.Status {
 		case "passed":
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 			statusText = fmt.Sprintf("PASSED (%d/%d)", result.Passed, result.Total)
 			completedSuites++
 		case "failed":
-			symbol = "❌"
+			symbol = themeIcon(StatusFailed)
 			color = pterm.FgRed
 			statusText = fmt.Sprintf("FAILED (%d/%d passed)", result.Passed, result.Total)
 			completedSuites++
 		case "running":
-			symbol = "🔄"
+			symbol = themeIcon(StatusRunning)
 			color = pterm.FgYellow
 			statusText = fmt.Sprintf("RUNNING (%d/%d)", result.Passed, result.Total)
 		default:
-			symbol = "⏳"
+			symbol = themeIcon(StatusPending)
 			color = pterm.FgLightWhite
 			statusText = "PENDING"
 		}
@@ -933,7 +1085,7 @@ func ShowTestProgress(testSuites []string, results map[string]TestResult) {
 		testProgress, totalPassed, totalTests)
 
 	if totalFailed > 0 {
-		content += fmt.Sprintf("❌ Failed Tests: %d\n", totalFailed)
+		content += fmt.Sprintf("%s Failed Tests: %d\n", themeIcon(StatusFailed), totalFailed)
 	}
 
 	pm.UpdateArea("tests", content)
@@ -942,7 +1094,7 @@ func ShowTestProgress(testSuites []string, results map[string]TestResult) {
 // createProgressBarString creates a visual progress bar string
 func createProgressBarString(current, total int) string {
 	if total <= 0 {
-		return pterm.NewStyle(pterm.FgCyan).Sprint("[████████████████████] 100%")
+		return pterm.NewStyle(currentTheme.Primary).Sprint("[████████████████████] 100%")
 	}
 
 	percent := float64(current) / float64(total)
@@ -963,7 +1115,7 @@ func createProgressBarString(current, total int) string {
 	}
 	bar += fmt.Sprintf("] %.1f%%", percent*100)
 
-	return pterm.NewStyle(pterm.FgCyan).Sprint(bar)
+	return pterm.NewStyle(currentTheme.Primary).Sprint(bar)
 }
 
 // TestResult represents test execution results
@@ -976,60 +1128,102 @@ type TestResult struct {
 
 // Success messages and formatting
 func ShowSuccess(message string) {
-	pterm.Success.Println(message)
+	pterm.Success.Println(i18n.T(message))
 }
 
 func ShowError(message string) {
-	pterm.Error.Println(message)
+	pterm.Error.Println(i18n.T(message))
 }
 
 func ShowWarning(message string) {
-	pterm.Warning.Println(message)
+	pterm.Warning.Println(i18n.T(message))
 }
 
 func ShowInfo(message string) {
-	pterm.Info.Println(message)
+	pterm.Info.Println(i18n.T(message))
 }
 
-// ShowBanner displays an enhanced enterprise banner with the installer information
+// ShowBanner displays an enhanced enterprise banner with the installer information, framed
+// or as a single line depending on the theme, under the configured branding
 func ShowBanner(version string) {
-	// Create enterprise banner using simple text styling
-	banner := pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("╔══════════════════════════════════════╗\n") +
-		pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("║    ") + pterm.NewStyle(pterm.FgLightMagenta, pterm.Bold).Sprint("KUBERNETES INSTALLER") + pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("        ║\n") +
-		pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("║        ") + pterm.NewStyle(pterm.FgYellow).Sprint("Enterprise Edition") + pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("         ║\n") +
-		pterm.NewStyle(pterm.FgCyan, pterm.Bold).Sprint("╚══════════════════════════════════════╝")
+	frame := pterm.NewStyle(currentTheme.Primary, pterm.Bold)
+	accent := pterm.NewStyle(currentTheme.Accent, pterm.Bold)
+	muted := pterm.NewStyle(currentTheme.Muted)
+	branding := currentBranding
+	version = bannerVersion(version)
+
+	if currentTheme.Banner == "plain" {
+		line := accent.Sprint(branding.Tagline) +
+			muted.Sprint(i18n.Tf("  Version %s | %s", version, time.Now().Format("2006-01-02 15:04:05 MST")))
+		if branding.SupportURL != "" {
+			line += muted.Sprint(i18n.Tf(" | Support: %s", branding.SupportURL))
+		}
+		pterm.Println(line)
+		pterm.Println()
+		return
+	}
 
-	pterm.DefaultCenter.Println(banner)
+	if branding.Logo != "" {
+		pterm.DefaultCenter.Println(accent.Sprint(branding.Logo))
+	} else {
+		pterm.DefaultCenter.Println(framedName(branding, frame, accent))
+	}
 
 	// Enterprise subtitle
-	pterm.DefaultCenter.WithCenterEachLineSeparately().Println(
-		pterm.NewStyle(pterm.FgLightMagenta, pterm.Bold).Sprint("Enterprise Kubernetes Installation Platform") + "\n" +
-			pterm.NewStyle(pterm.FgGray).Sprintf("Version: %s | Build: Enterprise", version) + "\n" +
-			pterm.NewStyle(pterm.FgGray).Sprintf("Runtime: %s", time.Now().Format("2006-01-02 15:04:05 MST")))
+	subtitle := accent.Sprint(branding.Tagline) + "\n" +
+		muted.Sprint(i18n.Tf("Version: %s | Build: %s", version, branding.Edition)) + "\n" +
+		muted.Sprint(i18n.Tf("Runtime: %s", time.Now().Format("2006-01-02 15:04:05 MST")))
+	if branding.SupportURL != "" {
+		subtitle += "\n" + muted.Sprint(i18n.Tf("Support: %s", branding.SupportURL))
+	}
+	pterm.DefaultCenter.WithCenterEachLineSeparately().Println(subtitle)
 
 	// Add separator
 	pterm.Println()
-	pterm.DefaultCenter.Println(pterm.NewStyle(pterm.FgCyan).Sprint("═══════════════════════════════════════"))
+	pterm.DefaultCenter.Println(pterm.NewStyle(currentTheme.Primary).Sprint("═══════════════════════════════════════"))
 	pterm.Println()
 }
 
+// framedName frames the product name and edition, widening the frame for long names
+func framedName(branding Branding, frame, accent *pterm.Style) string {
+	name := strings.ToUpper(branding.ProductName)
+	width := 38
+	for _, text := range []string{name, branding.Edition} {
+		if n := utf8.RuneCountInString(text) + 8; n > width {
+			width = n
+		}
+	}
+	line := func(text string, style *pterm.Style) string {
+		pad := width - utf8.RuneCountInString(text)
+		return frame.Sprint("║"+strings.Repeat(" ", pad/2)) + style.Sprint(text) +
+			frame.Sprint(strings.Repeat(" ", pad-pad/2)+"║\n")
+	}
+	return frame.Sprint("╔"+strings.Repeat("═", width)+"╗\n") +
+		line(name, accent) +
+		line(branding.Edition, pterm.NewStyle(pterm.FgYellow)) +
+		frame.Sprint("╚"+strings.Repeat("═", width)+"╝")
+}
+
 // ShowEnterpriseWelcome displays a comprehensive enterprise welcome screen
 func ShowEnterpriseWelcome(version string, environment string) {
 	ShowBanner(version)
 
 	// Environment information
-	pterm.DefaultSection.Println("Environment Information")
+	pterm.DefaultSection.Println(i18n.T("Environment Information"))
 
 	info := [][]string{
-		{"Environment", environment},
-		{"Installer Version", version},
-		{"Build Type", "Enterprise"},
-		{"Session ID", fmt.Sprintf("k8s-%d", time.Now().Unix())},
-		{"Start Time", time.Now().Format("2006-01-02 15:04:05 MST")},
+		{i18n.T("Environment"), environment},
+		{i18n.T("Installer Version"), bannerVersion(version)},
+		{i18n.T("Build Type"), currentBranding.Edition},
+		{i18n.T("Session ID"), fmt.Sprintf("k8s-%d", time.Now().Unix())},
+		{i18n.T("Start Time"), time.Now().Format("2006-01-02 15:04:05 MST")},
+	}
+	if currentBranding.SupportURL != "" {
+		info = append(info, []string{i18n.T("Support"), currentBranding.SupportURL})
 	}
 
 	pterm.DefaultTable.WithHasHeader().WithData(
-		append([][]string{{"Property", "Value"}}, info...),
+		append([][]string{{i18n.T("Property"), i18n.T("Value")}}, info...),
 	).Render()
 
 	pterm.Println()
@@ -1037,7 +1231,7 @@ func ShowEnterpriseWelcome(version string, environment string) {
 
 // ShowSummary displays an enhanced installation summary with enterprise metrics
 func ShowSummary(steps []string, results map[string]string, duration time.Duration) {
-	pterm.DefaultSection.Println("🏢 Enterprise Installation Summary")
+	pterm.DefaultSection.Println(i18n.T("🏢 Enterprise Installation Summary"))
 
 	successCount := 0
 	failedCount := 0
@@ -1050,30 +1244,30 @@ func ShowSummary(steps []string, results map[string]string, duration time.Durati
 		var color pterm.Color
 		switch result {
 		case "success":
-			symbol = "✅"
+			symbol = themeIcon(StatusCompleted)
 			color = pterm.FgGreen
 			successCount++
 		case "failed":
-			symbol = "❌"
+			symbol = themeIcon(StatusFailed)
 			color = pterm.FgRed
 			failedCount++
 		case "skipped":
-			symbol = "⏭️"
+			symbol = themeIcon(StatusSkipped)
 			color = pterm.FgYellow
 			skippedCount++
 		case "warning":
-			symbol = "⚠️"
+			symbol = themeIcon(StatusWarning)
 			color = pterm.FgYellow
 			warningCount++
 		default:
-			symbol = "❓"
+			symbol = themeIcon("")
 			color = pterm.FgLightWhite
 		}
 
 		pterm.Printf("  %s %-30s %s\n",
 			symbol,
-			step,
-			pterm.NewStyle(color, pterm.Bold).Sprintf("%-10s", strings.ToUpper(result)))
+			i18n.T(step),
+			pterm.NewStyle(color, pterm.Bold).Sprintf("%-10s", i18n.T(strings.ToUpper(result))))
 	}
 
 	pterm.Println()
@@ -1087,44 +1281,48 @@ func ShowSummary(steps []string, results map[string]string, duration time.Durati
 
 	// Create metrics table
 	metrics := [][]string{
-		{"Total Steps", fmt.Sprintf("%d", totalSteps)},
-		{"Successful", fmt.Sprintf("%d (%.1f%%)", successCount, successRate)},
-		{"Failed", fmt.Sprintf("%d", failedCount)},
-		{"Skipped", fmt.Sprintf("%d", skippedCount)},
-		{"Warnings", fmt.Sprintf("%d", warningCount)},
-		{"Duration", formatDuration(duration)},
-		{"Success Rate", fmt.Sprintf("%.1f%%", successRate)},
+		{i18n.T("Total Steps"), fmt.Sprintf("%d", totalSteps)},
+		{i18n.T("Successful"), fmt.Sprintf("%d (%.1f%%)", successCount, successRate)},
+		{i18n.T("Failed"), fmt.Sprintf("%d", failedCount)},
+		{i18n.T("Skipped"), fmt.Sprintf("%d", skippedCount)},
+		{i18n.T("Warnings"), fmt.Sprintf("%d", warningCount)},
+		{i18n.T("Duration"), formatDuration(duration)},
+		{i18n.T("Success Rate"), fmt.Sprintf("%.1f%%", successRate)},
 	}
 
 	if duration.Seconds() > 0 {
 		throughput := float64(successCount) / duration.Seconds()
-		metrics = append(metrics, []string{"Throughput", fmt.Sprintf("%.2f steps/sec", throughput)})
+		metrics = append(metrics, []string{i18n.T("Throughput"), i18n.Tf("%.2f steps/sec", throughput)})
 	}
 
 	pterm.DefaultTable.WithHasHeader().WithData(
-		append([][]string{{"Metric", "Value"}}, metrics...),
+		append([][]string{{i18n.T("Metric"), i18n.T("Value")}}, metrics...),
 	).Render()
 
 	pterm.Println()
 
 	// Final status with enterprise styling
 	if failedCount == 0 {
-		pterm.DefaultBox.WithTitle("🎉 Installation Status").
+		pterm.DefaultBox.WithTitle(i18n.T("🎉 Installation Status")).
 			WithTitleTopCenter().
 			WithBoxStyle(pterm.NewStyle(pterm.FgGreen)).
-			Println(pterm.Green("✅ INSTALLATION COMPLETED SUCCESSFULLY\n\n") +
-				pterm.LightGreen(fmt.Sprintf("All %d steps completed in %s", successCount, formatDuration(duration))))
+			Println(pterm.Green(themeIcon(StatusCompleted)+" "+i18n.T("INSTALLATION COMPLETED SUCCESSFULLY")+"\n\n") +
+				pterm.LightGreen(i18n.Tf("All %d steps completed in %s", successCount, formatDuration(duration))))
 	} else {
-		pterm.DefaultBox.WithTitle("❌ Installation Status").
+		pterm.DefaultBox.WithTitle(themeIcon(StatusFailed) + " " + i18n.T("Installation Status")).
 			WithTitleTopCenter().
 			WithBoxStyle(pterm.NewStyle(pterm.FgRed)).
-			Println(pterm.Red("❌ INSTALLATION COMPLETED WITH ERRORS\n\n") +
-				pterm.LightRed(fmt.Sprintf("%d steps failed out of %d total", failedCount, totalSteps)))
+			Println(pterm.Red(themeIcon(StatusFailed)+" "+i18n.T("INSTALLATION COMPLETED WITH ERRORS")+"\n\n") +
+				pterm.LightRed(i18n.Tf("%d steps failed out of %d total", failedCount, totalSteps)))
 	}
 
 	// Add enterprise footer
 	pterm.Println()
-	pterm.DefaultCenter.Println(pterm.NewStyle(pterm.FgGray).Sprint("Enterprise Kubernetes Installer - Powered by Go"))
+	footer := currentBranding.ProductName + " - " + i18n.T("Powered by Go")
+	if currentBranding.SupportURL != "" {
+		footer = currentBranding.ProductName + " - " + i18n.Tf("Support: %s", currentBranding.SupportURL)
+	}
+	pterm.DefaultCenter.Println(pterm.NewStyle(pterm.FgGray).Sprint(footer))
 }
 
 // DisplayServiceHealthStatus displays service health status with tick marks
@@ -1133,25 +1331,25 @@ func (pm *ProgressManager) DisplayServiceHealthStatus(services []ServiceHealthSt
 		return
 	}
 
-	pterm.DefaultSection.Println(fmt.Sprintf("🏥 %s", title))
+	pterm.DefaultSection.Println(fmt.Sprintf("🏥 %s", i18n.T(title)))
 
 	// Prepare health status table data
-	healthData := [][]string{{"Service", "Status", "Response Time", "Endpoint", "Namespace"}}
+	healthData := [][]string{{i18n.T("Service"), i18n.T("Status"), i18n.T("Response Time"), i18n.T("Endpoint"), i18n.T("Namespace")}}
 
 	for _, service := range services {
 		statusIcon := service.Icon
 		if statusIcon == "" {
 			switch service.Status {
 			case "healthy":
-				statusIcon = "✅ Healthy"
+				statusIcon = themeIcon(StatusCompleted) + " Healthy"
 			case "unhealthy":
-				statusIcon = "❌ Unhealthy"
+				statusIcon = themeIcon(StatusFailed) + " Unhealthy"
 			case "checking":
-				statusIcon = "🔄 Checking"
+				statusIcon = themeIcon(StatusRunning) + " Checking"
 			case "pending":
-				statusIcon = "⏳ Pending"
+				statusIcon = themeIcon(StatusPending) + " Pending"
 			default:
-				statusIcon = "❓ Unknown"
+				statusIcon = themeIcon("") + " Unknown"
 			}
 		}
 
@@ -1189,20 +1387,20 @@ func (pm *ProgressManager) DisplayServiceHealthStatus(services []ServiceHealthSt
 	pendingServices := healthyCounts["pending"]
 
 	summaryData := [][]string{
-		{"Total Services", fmt.Sprintf("%d", totalServices)},
-		{"Healthy", fmt.Sprintf("✅ %d (%.1f%%)", healthyServices, float64(healthyServices)/float64(totalServices)*100)},
-		{"Unhealthy", fmt.Sprintf("❌ %d (%.1f%%)", unhealthyServices, float64(unhealthyServices)/float64(totalServices)*100)},
-		{"Checking", fmt.Sprintf("🔄 %d", checkingServices)},
-		{"Pending", fmt.Sprintf("⏳ %d", pendingServices)},
+		{i18n.T("Total Services"), fmt.Sprintf("%d", totalServices)},
+		{i18n.T("Healthy"), fmt.Sprintf("%s %d (%.1f%%)", themeIcon(StatusCompleted), healthyServices, float64(healthyServices)/float64(totalServices)*100)},
+		{i18n.T("Unhealthy"), fmt.Sprintf("%s %d (%.1f%%)", themeIcon(StatusFailed), unhealthyServices, float64(unhealthyServices)/float64(totalServices)*100)},
+		{i18n.T("Checking"), fmt.Sprintf("%s %d", themeIcon(StatusRunning), checkingServices)},
+		{i18n.T("Pending"), fmt.Sprintf("%s %d", themeIcon(StatusPending), pendingServices)},
 	}
 
 	if totalServices > 0 {
 		successRate := float64(healthyServices) / float64(totalServices) * 100
-		summaryData = append(summaryData, []string{"Success Rate", fmt.Sprintf("%.1f%%", successRate)})
+		summaryData = append(summaryData, []string{i18n.T("Success Rate"), fmt.Sprintf("%.1f%%", successRate)})
 	}
 
 	pterm.DefaultTable.WithHasHeader().WithData(
-		append([][]string{{"Health Metric", "Value"}}, summaryData...),
+		append([][]string{{i18n.T("Health Metric"), i18n.T("Value")}}, summaryData...),
 	).Render()
 
 	pterm.Println()
@@ -1225,29 +1423,29 @@ func CreateMockHealthChecks(chartNames []string, namespace string, isDryRun bool
 			switch i % 4 {
 			case 0:
 				status = "healthy"
-				icon = "✅ Healthy"
+				icon = themeIcon(StatusCompleted) + " Healthy"
 				message = "Service is running and responsive"
 				responseTime = time.Duration(50+rand.Intn(100)) * time.Millisecond
 			case 1:
 				status = "healthy"
-				icon = "✅ Healthy"
+				icon = themeIcon(StatusCompleted) + " Healthy"
 				message = "All health checks passed"
 				responseTime = time.Duration(30+rand.Intn(80)) * time.Millisecond
 			case 2:
 				status = "checking"
-				icon = "🔄 Checking"
+				icon = themeIcon(StatusRunning) + " Checking"
 				message = "Health check in progress"
 				responseTime = 0
 			case 3:
 				status = "healthy"
-				icon = "✅ Healthy"
+				icon = themeIcon(StatusCompleted) + " Healthy"
 				message = "Service operational"
 				responseTime = time.Duration(25+rand.Intn(75)) * time.Millisecond
 			}
 		} else {
 			// For live deployment, all would typically be healthy if deployment succeeded
 			status = "healthy"
-			icon = "✅ Healthy"
+			icon = themeIcon(StatusCompleted) + " Healthy"
 			message = "Service is running and responsive"
 			responseTime = time.Duration(30+rand.Intn(100)) * time.Millisecond
 		}
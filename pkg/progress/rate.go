@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is how often rate trackers redraw when no interval is configured
+const DefaultRefreshInterval = time.Second
+
+// RateTracker redraws the line of a spinner or progress bar with the elapsed time, rate and
+// estimated time left of a long-running sub-step, derived from its progress counters. The
+// rate is shown in MB/s once bytes are reported, in units per minute otherwise.
+type RateTracker struct {
+	pm      *ProgressManager
+	id      string
+	title   string
+	unit    string
+	started time.Time
+	done    int
+	total   int
+	bytes   int64
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// SetRefreshInterval sets how often rate trackers redraw; zero restores the default
+func (pm *ProgressManager) SetRefreshInterval(interval time.Duration) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.refreshInterval = interval
+}
+
+// TrackRate starts redrawing the spinner or progress bar id with the rate of a sub-step that
+// has total units, e.g. images or resources, to go. A total of zero means it is unknown: the
+// count and rate are shown without an ETA.
+func (pm *ProgressManager) TrackRate(id, title, unit string, total int) *RateTracker {
+	pm.mutex.RLock()
+	interval := pm.refreshInterval
+	pm.mutex.RUnlock()
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	t := &RateTracker{pm: pm, id: id, title: title, unit: unit, total: total, started: time.Now(), stop: make(chan struct{})}
+	t.stopped.Add(1)
+	go func() {
+		defer t.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.redraw()
+			}
+		}
+	}()
+	return t
+}
+
+// Add counts n more units as done
+func (t *RateTracker) Add(n int) {
+	t.mu.Lock()
+	t.done += n
+	t.mu.Unlock()
+}
+
+// Set replaces the count of units done
+func (t *RateTracker) Set(done int) {
+	t.mu.Lock()
+	t.done = done
+	t.mu.Unlock()
+}
+
+// AddBytes counts n more bytes as transferred
+func (t *RateTracker) AddBytes(n int64) {
+	t.mu.Lock()
+	t.bytes += n
+	t.mu.Unlock()
+}
+
+// Stop ends the redraws, leaving the line to whoever owns the spinner or progress bar
+func (t *RateTracker) Stop() {
+	close(t.stop)
+	t.stopped.Wait()
+}
+
+// String renders the counters, e.g. "12/40 resources · 2.3m elapsed · 5.2 resources/min · ETA 5.4m"
+func (t *RateTracker) String() string {
+	return t.render(true)
+}
+
+func (t *RateTracker) render(withCount bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elapsed := time.Since(t.started)
+	var parts []string
+	if withCount {
+		if t.total > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d %s", t.done, t.total, t.unit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s", t.done, t.unit))
+		}
+		parts = append(parts, formatDuration(elapsed)+" elapsed")
+	}
+	if rate := formatRate(t.done, t.bytes, t.unit, elapsed); rate != "" {
+		parts = append(parts, rate)
+	}
+	if eta := estimateRemaining(t.done, t.total, elapsed); eta > 0 {
+		parts = append(parts, "ETA "+formatDuration(eta))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// redraw updates the spinner text, or the progress bar title, which already shows the count
+// and elapsed time
+func (t *RateTracker) redraw() {
+	pm := t.pm
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if spinner, exists := pm.spinners[t.id]; exists {
+		spinner.UpdateText(t.title + " " + t.render(true))
+	} else if bar, exists := pm.progressBars[t.id]; exists {
+		if rate := t.render(false); rate != "" {
+			bar.UpdateTitle(fmt.Sprintf("%s (%s)", t.title, rate))
+		}
+	}
+}
+
+// formatRate renders the throughput so far, in MB/s when bytes were transferred
+func formatRate(done int, bytes int64, unit string, elapsed time.Duration) string {
+	if elapsed < time.Second {
+		return ""
+	}
+	if bytes > 0 {
+		return fmt.Sprintf("%.1f MB/s", float64(bytes)/1e6/elapsed.Seconds())
+	}
+	if done > 0 {
+		return fmt.Sprintf("%.1f %s/min", float64(done)/elapsed.Minutes(), unit)
+	}
+	return ""
+}
+
+// estimateRemaining extrapolates the time left from the average time per unit so far
+func estimateRemaining(done, total int, elapsed time.Duration) time.Duration {
+	if done <= 0 || total <= done {
+		return 0
+	}
+	return time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+}
@@ -0,0 +1,192 @@
+package progress
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/pterm/pterm"
+)
+
+// Theme names
+const (
+	ThemeDefault      = "default"
+	ThemeMonochrome   = "monochrome"
+	ThemeHighContrast = "high-contrast"
+	ThemeCorporate    = "corporate"
+)
+
+// Theme controls the colors, status icons and banner of the terminal output, and the level
+// labels of text format logs, so the output can follow a customer's branding or the needs
+// of screen readers and color-blind operators
+type Theme struct {
+	Name    string
+	Color   bool // false prints no ANSI colors at all
+	Emoji   bool // false replaces the status icons with text markers
+	Primary pterm.Color
+	Accent  pterm.Color
+	Muted   pterm.Color
+	Banner  string // "box" frames the product name, "plain" prints a single header line
+
+	// Success, warning, error and info printers; the zero value keeps pterm's styles
+	Printers *pterm.Theme
+	Log      logger.ConsoleStyle
+	Icons    map[OperationStatus]string
+}
+
+var (
+	emojiIcons = map[OperationStatus]string{
+		StatusCompleted: "✅", StatusFailed: "❌", StatusRunning: "🔄", StatusPending: "⏳",
+		StatusSkipped: "⏭️", StatusCancelled: "🚫", StatusWarning: "⚠️",
+	}
+	textIcons = map[OperationStatus]string{
+		StatusCompleted: "[OK]", StatusFailed: "[FAIL]", StatusRunning: "[RUN]", StatusPending: "[WAIT]",
+		StatusSkipped: "[SKIP]", StatusCancelled: "[STOP]", StatusWarning: "[WARN]",
+	}
+	symbolIcons = map[OperationStatus]string{
+		StatusCompleted: "✓", StatusFailed: "✗", StatusRunning: "•", StatusPending: "○",
+		StatusSkipped: "↷", StatusCancelled: "⊘", StatusWarning: "!",
+	}
+)
+
+// defaultPrinters are pterm's own styles, restored when switching back to the default theme
+var defaultPrinters = pterm.ThemeDefault
+
+var themes = map[string]Theme{
+	ThemeDefault: {
+		Name: ThemeDefault, Color: true, Emoji: true,
+		Primary: pterm.FgCyan, Accent: pterm.FgLightMagenta, Muted: pterm.FgGray,
+		Banner: "box",
+		Log:    logger.DefaultConsoleStyle,
+		Icons:  emojiIcons,
+	},
+	ThemeMonochrome: {
+		Name:   ThemeMonochrome,
+		Banner: "plain",
+		Log:    logger.ConsoleStyle{},
+		Icons:  textIcons,
+	},
+	ThemeHighContrast: {
+		Name: ThemeHighContrast, Color: true,
+		Primary: pterm.FgLightWhite, Accent: pterm.FgLightYellow, Muted: pterm.FgLightWhite,
+		Banner:   "box",
+		Printers: highContrastPrinters(),
+		Log: logger.ConsoleStyle{Color: true, Colors: map[string]string{
+			"debug": "1;97", "info": "1;30;106", "warn": "1;30;103", "error": "1;97;101", "field": "1;97",
+		}},
+		Icons: textIcons,
+	},
+	ThemeCorporate: {
+		Name: ThemeCorporate, Color: true,
+		Primary: pterm.FgBlue, Accent: pterm.FgLightBlue, Muted: pterm.FgGray,
+		Banner:   "plain",
+		Printers: corporatePrinters(),
+		Log: logger.ConsoleStyle{Color: true, Colors: map[string]string{
+			"debug": "90", "info": "34", "warn": "33", "error": "31", "field": "34",
+		}},
+		Icons: symbolIcons,
+	},
+}
+
+var currentTheme = themes[ThemeDefault]
+
+// ThemeNames lists the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTheme switches all further output to the named theme
+func SetTheme(name string) error {
+	theme, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q, available themes: %s", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	// The pterm printers point into ThemeDefault, so replacing it restyles all of them
+	pterm.ThemeDefault = defaultPrinters
+	if theme.Printers != nil {
+		pterm.ThemeDefault = *theme.Printers
+	}
+	if theme.Color {
+		pterm.EnableColor()
+	} else {
+		pterm.DisableColor()
+	}
+	logger.SetConsoleStyle(theme.Log)
+	currentTheme = theme
+	return nil
+}
+
+// CurrentTheme returns the theme output is rendered with
+func CurrentTheme() Theme {
+	return currentTheme
+}
+
+// themeIcon returns the uncolored icon of a status in the current theme
+func themeIcon(status OperationStatus) string {
+	if icon, ok := currentTheme.Icons[status]; ok {
+		return icon
+	}
+	if currentTheme.Emoji {
+		return "❓"
+	}
+	return "[?]"
+}
+
+// statusIcon renders the icon of a status in the current theme and its color
+func statusIcon(status OperationStatus) string {
+	icon := themeIcon(status)
+	switch status {
+	case StatusCompleted:
+		return pterm.Green(icon)
+	case StatusFailed, StatusCancelled:
+		return pterm.Red(icon)
+	case StatusRunning, StatusSkipped, StatusWarning:
+		return pterm.Yellow(icon)
+	}
+	return pterm.LightWhite(icon)
+}
+
+// highContrastPrinters labels levels with black text on bright backgrounds and prints
+// messages in bold white
+func highContrastPrinters() *pterm.Theme {
+	theme := pterm.ThemeDefault
+	message := *pterm.NewStyle(pterm.FgLightWhite, pterm.Bold)
+	theme.InfoPrefixStyle = *pterm.NewStyle(pterm.FgBlack, pterm.BgLightCyan, pterm.Bold)
+	theme.InfoMessageStyle = message
+	theme.SuccessPrefixStyle = *pterm.NewStyle(pterm.FgBlack, pterm.BgLightGreen, pterm.Bold)
+	theme.SuccessMessageStyle = message
+	theme.WarningPrefixStyle = *pterm.NewStyle(pterm.FgBlack, pterm.BgLightYellow, pterm.Bold)
+	theme.WarningMessageStyle = message
+	theme.ErrorPrefixStyle = *pterm.NewStyle(pterm.FgLightWhite, pterm.BgLightRed, pterm.Bold)
+	theme.ErrorMessageStyle = message
+	theme.SectionStyle = *pterm.NewStyle(pterm.FgLightYellow, pterm.Bold)
+	theme.TableHeaderStyle = *pterm.NewStyle(pterm.FgLightYellow, pterm.Bold)
+	theme.TableSeparatorStyle = *pterm.NewStyle(pterm.FgLightWhite)
+	theme.SpinnerStyle = *pterm.NewStyle(pterm.FgLightYellow, pterm.Bold)
+	theme.ProgressbarBarStyle = *pterm.NewStyle(pterm.FgLightYellow)
+	return &theme
+}
+
+// corporatePrinters keeps to a blue palette with green and red for outcomes only
+func corporatePrinters() *pterm.Theme {
+	theme := pterm.ThemeDefault
+	theme.InfoPrefixStyle = *pterm.NewStyle(pterm.FgLightWhite, pterm.BgBlue)
+	theme.InfoMessageStyle = *pterm.NewStyle(pterm.FgDefault)
+	theme.SuccessPrefixStyle = *pterm.NewStyle(pterm.FgLightWhite, pterm.BgGreen)
+	theme.SuccessMessageStyle = *pterm.NewStyle(pterm.FgDefault)
+	theme.WarningPrefixStyle = *pterm.NewStyle(pterm.FgBlack, pterm.BgYellow)
+	theme.WarningMessageStyle = *pterm.NewStyle(pterm.FgDefault)
+	theme.SectionStyle = *pterm.NewStyle(pterm.FgBlue, pterm.Bold)
+	theme.TableHeaderStyle = *pterm.NewStyle(pterm.FgBlue, pterm.Bold)
+	theme.SpinnerStyle = *pterm.NewStyle(pterm.FgBlue)
+	theme.ProgressbarBarStyle = *pterm.NewStyle(pterm.FgBlue)
+	theme.HeaderBackgroundStyle = *pterm.NewStyle(pterm.BgBlue)
+	return &theme
+}
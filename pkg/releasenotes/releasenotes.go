@@ -0,0 +1,143 @@
+package releasenotes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// FileNames are the changelog files looked for in a chart or application directory, in order
+var FileNames = []string{"CHANGELOG.md", "CHANGELOG", "RELEASE-NOTES.md", "RELEASE_NOTES.md", "CHANGES.md"}
+
+// versionHeading matches the heading of a version section, e.g. "## [1.4.0] - 2024-05-01",
+// "## v1.4.0" or "# 1.4.0-rc.1"
+var versionHeading = regexp.MustCompile(`^#{1,3}\s+\[?v?(\d+\.\d+\.\d+[0-9A-Za-z.+-]*)\]?`)
+
+// breakingHeading matches a subsection holding breaking changes, e.g. "### Breaking changes"
+var breakingHeading = regexp.MustCompile(`(?i)^#{2,6}\s+.*breaking`)
+
+// Entry is the section of a changelog describing one version
+type Entry struct {
+	Version  string   `json:"version"`
+	Body     string   `json:"body"`
+	Breaking []string `json:"breaking,omitempty"` // lines of breaking-change subsections and BREAKING CHANGE notes
+}
+
+// Notes are the changelog entries of one chart between the installed and the target version
+type Notes struct {
+	Chart   string  `json:"chart"`
+	From    string  `json:"from,omitempty"` // empty for a new install
+	To      string  `json:"to"`
+	Source  string  `json:"source"`
+	Entries []Entry `json:"entries"`
+}
+
+// Breaking returns the breaking changes of every entry, prefixed with their version
+func (n Notes) Breaking() []string {
+	var breaking []string
+	for _, entry := range n.Entries {
+		for _, line := range entry.Breaking {
+			breaking = append(breaking, entry.Version+": "+line)
+		}
+	}
+	return breaking
+}
+
+// Find returns the changelog file of a directory, or "" when it has none
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Parse splits a changelog into version entries, in the order they appear. Text before the
+// first version heading, such as an "Unreleased" section, is ignored.
+func Parse(data []byte) []Entry {
+	var entries []Entry
+	var body []string
+	inBreaking := false
+	flush := func() {
+		if len(entries) > 0 {
+			entries[len(entries)-1].Body = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if match := versionHeading.FindStringSubmatch(line); match != nil {
+			flush()
+			entries = append(entries, Entry{Version: match[1]})
+			inBreaking = false
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		body = append(body, line)
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			inBreaking = breakingHeading.MatchString(trimmed)
+		case trimmed == "":
+		case inBreaking || strings.Contains(trimmed, "BREAKING CHANGE") || strings.Contains(trimmed, "BREAKING:"):
+			current := &entries[len(entries)-1]
+			current.Breaking = append(current.Breaking, strings.TrimLeft(trimmed, "-*+ "))
+		}
+	}
+	flush()
+	return entries
+}
+
+// Between returns the entries newer than from up to and including to, in changelog order. A new
+// install has no from and gets the entry of to alone.
+func Between(entries []Entry, from, to string) ([]Entry, error) {
+	target, err := semver.NewVersion(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target version %q: %w", to, err)
+	}
+	var installed *semver.Version
+	if from != "" {
+		if installed, err = semver.NewVersion(from); err != nil {
+			return nil, fmt.Errorf("invalid installed version %q: %w", from, err)
+		}
+	}
+
+	var selected []Entry
+	for _, entry := range entries {
+		version, err := semver.NewVersion(entry.Version)
+		if err != nil {
+			continue
+		}
+		switch {
+		case version.GreaterThan(target):
+		case installed == nil && !version.Equal(target):
+		case installed != nil && !version.GreaterThan(installed):
+		default:
+			selected = append(selected, entry)
+		}
+	}
+	return selected, nil
+}
+
+// Load reads the changelog at path and returns the notes of an upgrade from one version to another
+func Load(chart, path, from, to string) (*Notes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release notes: %w", err)
+	}
+	entries, err := Between(Parse(data), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Notes{Chart: chart, From: from, To: to, Source: path, Entries: entries}, nil
+}
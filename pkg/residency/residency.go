@@ -0,0 +1,269 @@
+package residency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// Violation is a region or endpoint outside the allowed regions or domains
+type Violation struct {
+	Source string `json:"source"` // configuration field or planned resource address
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Policy holds the allowed regions and domains of a security configuration
+type Policy struct {
+	regions []string
+	domains []string
+}
+
+// NewPolicy returns the residency policy of cfg, nil when it restricts neither regions nor domains
+func NewPolicy(cfg config.SecurityConfig) *Policy {
+	if len(cfg.AllowedRegions) == 0 && len(cfg.AllowedDomains) == 0 {
+		return nil
+	}
+	policy := &Policy{domains: cfg.AllowedDomains}
+	for _, region := range cfg.AllowedRegions {
+		policy.regions = append(policy.regions, normalizeRegion(region))
+	}
+	return policy
+}
+
+// RestrictsRegions reports whether the policy has allowed regions
+func (p *Policy) RestrictsRegions() bool {
+	return len(p.regions) > 0
+}
+
+// zone suffixes: eu-west-1a (AWS) and europe-west1-b (GCP)
+var zoneSuffix = regexp.MustCompile(`^(.*\d)-?[a-z]$`)
+
+// RegionAllowed reports whether a region, location or availability zone matches an allowed
+// region. Zones match through their region, and global resources hold no data in a region.
+func (p *Policy) RegionAllowed(region string) bool {
+	region = normalizeRegion(region)
+	if !p.RestrictsRegions() || region == "" || region == "global" {
+		return true
+	}
+	candidates := []string{region}
+	if match := zoneSuffix.FindStringSubmatch(region); match != nil {
+		candidates = append(candidates, match[1])
+	}
+	for _, pattern := range p.regions {
+		for _, candidate := range candidates {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeRegion lowercases Azure display names such as "West Europe" to westeurope
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(region), " ", ""))
+}
+
+// HostAllowed reports whether a host is an allowed domain or one of its subdomains.
+// Loopback and private addresses never leave the environment and are always allowed.
+func (p *Policy) HostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if len(p.domains) == 0 || host == "" || host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsPrivate()) {
+		return true
+	}
+	for _, domain := range p.domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConfig checks the cloud region, region-like Terraform variables and every registry,
+// repository and endpoint the configuration uses
+func (p *Policy) CheckConfig(cfg *config.InstallerConfig) []Violation {
+	var violations []Violation
+	region := func(source, value string) {
+		if !p.RegionAllowed(value) {
+			violations = append(violations, Violation{Source: source, Value: value, Reason: "region is not allowed"})
+		}
+	}
+	endpoint := func(source, value string) {
+		if value == "" {
+			return
+		}
+		if host := hostOf(value); !p.HostAllowed(host) {
+			violations = append(violations, Violation{Source: source, Value: value, Reason: fmt.Sprintf("%s is outside the allowed domains", host)})
+		}
+	}
+
+	region("cloud.region", cfg.Cloud.Region)
+	for _, name := range sortedKeys(cfg.Infrastructure.Terraform.Variables) {
+		if regionVariable(name) {
+			region("infrastructure.terraform.variables."+name, cfg.Infrastructure.Terraform.Variables[name])
+		}
+	}
+	for _, module := range cfg.Artifacts.Terraform.Modules {
+		for name, value := range module.Variables {
+			if text, ok := value.(string); ok && regionVariable(name) {
+				region(fmt.Sprintf("artifacts.terraform.modules[%s].variables.%s", module.Name, name), text)
+			}
+		}
+	}
+
+	images := cfg.Artifacts.Images
+	endpoint("artifacts.images.vendor.registry", images.Vendor.Registry)
+	endpoint("artifacts.images.client.registry", images.Client.Registry)
+	endpoint("artifacts.helm.vendor.repo", cfg.Artifacts.Helm.Vendor.Repo)
+	endpoint("artifacts.helm.client.repo", cfg.Artifacts.Helm.Client.Repo)
+	endpoint("artifacts.helm.client.chartRepo.url", cfg.Artifacts.Helm.Client.ChartRepo.URL)
+	endpoint("artifacts.terraform.vendor.repo", cfg.Artifacts.Terraform.Vendor.Repo)
+	endpoint("artifacts.terraform.client.repo", cfg.Artifacts.Terraform.Client.Repo)
+	endpoint("monitoring.prometheus.endpoint", cfg.Monitoring.Prometheus.Endpoint)
+	endpoint("monitoring.grafana.endpoint", cfg.Monitoring.Grafana.Endpoint)
+	for i, check := range cfg.Validation.Post.HealthChecks {
+		endpoint(fmt.Sprintf("validation.post.healthChecks[%d].url", i), check.URL)
+	}
+	for _, tx := range cfg.Validation.Post.Synthetics {
+		endpoint(fmt.Sprintf("validation.post.synthetics[%s].baseUrl", tx.Name), tx.BaseURL)
+	}
+	for _, target := range cfg.Validation.Post.Load.Endpoints {
+		endpoint(fmt.Sprintf("validation.post.load.endpoints[%s].url", target.Name), target.URL)
+	}
+	return violations
+}
+
+// regionVariable reports whether a variable name holds a region, location or zone
+func regionVariable(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range []string{"region", "location", "zone"} {
+		if name == suffix || strings.HasSuffix(name, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf returns the host of a URL, or of a registry reference such as registry.example.com/team
+func hostOf(value string) string {
+	if !strings.Contains(value, "://") {
+		value = "//" + value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+	return parsed.Hostname()
+}
+
+// plan is the subset of `terraform show -json` the region check reads
+type plan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string               `json:"actions"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+	Configuration struct {
+		ProviderConfig map[string]struct {
+			Expressions map[string]struct {
+				ConstantValue interface{} `json:"constant_value"`
+			} `json:"expressions"`
+		} `json:"provider_config"`
+	} `json:"configuration"`
+}
+
+// regionAttributes are the resource attributes that place data in a region
+var regionAttributes = map[string]bool{
+	"region": true, "location": true, "availability_zone": true, "availability_zones": true, "zone": true, "zones": true,
+}
+
+// CheckPlan checks the regions of the providers and of every resource a Terraform plan
+// creates or updates
+func (p *Policy) CheckPlan(planJSON []byte) ([]Violation, error) {
+	var parsed plan
+	if err := json.Unmarshal(planJSON, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform plan: %w", err)
+	}
+	var violations []Violation
+
+	for _, name := range sortedKeys(parsed.Configuration.ProviderConfig) {
+		for attribute, expression := range parsed.Configuration.ProviderConfig[name].Expressions {
+			value, ok := expression.ConstantValue.(string)
+			if ok && regionAttributes[attribute] && !p.RegionAllowed(value) {
+				violations = append(violations, Violation{Source: "provider." + name, Value: value, Reason: attribute + " is not allowed"})
+			}
+		}
+	}
+
+	for _, change := range parsed.ResourceChanges {
+		if !createsOrUpdates(change.Change.Actions) {
+			continue
+		}
+		for _, attribute := range sortedKeys(change.Change.After) {
+			if !regionAttributes[attribute] {
+				continue
+			}
+			for _, value := range values(change.Change.After[attribute]) {
+				if !p.RegionAllowed(value) {
+					violations = append(violations, Violation{Source: change.Address, Value: value, Reason: attribute + " is not allowed"})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+func createsOrUpdates(actions []string) bool {
+	for _, action := range actions {
+		if action == "create" || action == "update" {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the strings of an attribute holding a string or a list of strings.
+// Azure zones are plain numbers, which say nothing about the region.
+func values(attribute interface{}) []string {
+	var result []string
+	switch v := attribute.(type) {
+	case string:
+		result = append(result, v)
+	case []interface{}:
+		for _, item := range v {
+			if text, ok := item.(string); ok {
+				result = append(result, text)
+			}
+		}
+	}
+	filtered := result[:0]
+	for _, value := range result {
+		if strings.Trim(value, "0123456789") != "" {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
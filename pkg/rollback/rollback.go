@@ -0,0 +1,223 @@
+package rollback
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/history"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+)
+
+// Kinds of journalled actions
+const (
+	KindHelmRelease    = "helm-release"
+	KindNamespace      = "namespace"
+	KindTerraformApply = "terraform-apply"
+	KindMigration      = "db-migration"
+	KindCheckpoint     = "checkpoint" // a named point of the journal to roll back to, changes nothing
+)
+
+// Action statuses
+const (
+	StatusApplied      = "applied"
+	StatusReverted     = "reverted"
+	StatusIrreversible = "irreversible" // left in place, it cannot be undone automatically
+	StatusFailed       = "failed"       // its undo failed and is retried by the next rollback
+)
+
+// ErrIrreversible is returned, wrapped with the reason, by an undo that cannot revert its action
+var ErrIrreversible = errors.New("cannot be undone automatically")
+
+// JournalFile returns the state file the actions of the run are journalled in, that of the
+// global parameter store
+func JournalFile() string {
+	return params.GetStore().StateFile()
+}
+
+// Record journals an action in the state file of the run, as applied by the current run;
+// failures are logged, never fatal
+func Record(action config.RollbackAction) {
+	action.Status = StatusApplied
+	action.RunID = history.RunID()
+	action.RecordedAt = time.Now().UTC()
+
+	stateFile := JournalFile()
+	if stateFile == "" {
+		logger.Warn("No workspace state file, action not journalled for rollback").
+			Str("kind", action.Kind).
+			Str("name", action.Name).
+			Send()
+		return
+	}
+	err := updateJournal(stateFile, func(actions []config.RollbackAction) ([]config.RollbackAction, error) {
+		action.Sequence = 1
+		if len(actions) > 0 {
+			action.Sequence = actions[len(actions)-1].Sequence + 1
+		}
+		return append(actions, action), nil
+	})
+	if err != nil {
+		logger.Warn("Failed to journal action for rollback").
+			Str("kind", action.Kind).
+			Str("name", action.Name).
+			Err(err).
+			Send()
+	}
+}
+
+// Checkpoint journals a named point, e.g. a completed installation step, that rollback --to
+// undoes the later actions back to
+func Checkpoint(name string) {
+	Record(config.RollbackAction{Kind: KindCheckpoint, Name: name})
+}
+
+// Load reads the journal from the installation state file, oldest action first
+func Load(stateFile string) ([]config.RollbackAction, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state struct {
+		Actions []config.RollbackAction `json:"actions"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", stateFile, err)
+	}
+	return state.Actions, nil
+}
+
+// updateJournal changes the journal in the installation state file, leaving other fields
+// untouched; the state file is locked meanwhile, so parallel steps never lose an action
+func updateJournal(stateFile string, change func([]config.RollbackAction) ([]config.RollbackAction, error)) error {
+	return workspace.UpdateState(stateFile, func(sections map[string]json.RawMessage) error {
+		var actions []config.RollbackAction
+		if raw, ok := sections["actions"]; ok {
+			if err := json.Unmarshal(raw, &actions); err != nil {
+				return fmt.Errorf("failed to parse rollback journal in %s: %w", stateFile, err)
+			}
+		}
+		actions, err := change(actions)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(actions)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rollback journal: %w", err)
+		}
+		sections["actions"] = encoded
+		return nil
+	})
+}
+
+// Undo reverts one journalled action
+type Undo func(action config.RollbackAction) error
+
+// Engine undoes the journal of an installation state file in reverse order
+type Engine struct {
+	stateFile string
+	undo      map[string]Undo
+}
+
+// NewEngine creates an engine for the journal of stateFile; Handle registers how each kind
+// of action is undone
+func NewEngine(stateFile string) *Engine {
+	return &Engine{stateFile: stateFile, undo: make(map[string]Undo)}
+}
+
+// Handle registers the undo of a kind of action
+func (e *Engine) Handle(kind string, undo Undo) {
+	e.undo[kind] = undo
+}
+
+// Plan returns the actions a rollback undoes, newest first: every applied or failed action
+// recorded after the last checkpoint named to, or all of them when to is empty
+func (e *Engine) Plan(to string) ([]config.RollbackAction, error) {
+	actions, err := Load(e.stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if to != "" {
+		start = -1
+		for i := len(actions) - 1; i >= 0; i-- {
+			if actions[i].Kind == KindCheckpoint && actions[i].Name == to && actions[i].Status == StatusApplied {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("no checkpoint %q in the rollback journal of %s", to, e.stateFile)
+		}
+	}
+
+	var plan []config.RollbackAction
+	for i := len(actions) - 1; i >= start; i-- {
+		if actions[i].Status == StatusApplied || actions[i].Status == StatusFailed {
+			plan = append(plan, actions[i])
+		}
+	}
+	return plan, nil
+}
+
+// Run undoes the planned actions in order and journals each outcome. It stops at the first
+// undo that fails, since what was made before it may depend on it; the next run retries it.
+// Irreversible actions are left in place and the rollback continues. done is called with
+// every undone action and its new status.
+func (e *Engine) Run(plan []config.RollbackAction, done func(action config.RollbackAction)) error {
+	for _, action := range plan {
+		var err error
+		if action.Kind != KindCheckpoint {
+			undo, ok := e.undo[action.Kind]
+			if !ok {
+				err = fmt.Errorf("no undo for %s actions", action.Kind)
+			} else {
+				err = undo(action)
+			}
+		}
+
+		now := time.Now().UTC()
+		action.Error = ""
+		switch {
+		case err == nil:
+			action.Status, action.RevertedAt = StatusReverted, &now
+		case errors.Is(err, ErrIrreversible):
+			action.Status, action.Error = StatusIrreversible, err.Error()
+		default:
+			action.Status, action.Error = StatusFailed, err.Error()
+		}
+		if saveErr := e.update(action); saveErr != nil {
+			return saveErr
+		}
+		if done != nil {
+			done(action)
+		}
+		if action.Status == StatusFailed {
+			return fmt.Errorf("failed to undo %s %s: %w", action.Kind, action.Name, err)
+		}
+	}
+	return nil
+}
+
+// update replaces a journalled action with its new status
+func (e *Engine) update(action config.RollbackAction) error {
+	return updateJournal(e.stateFile, func(actions []config.RollbackAction) ([]config.RollbackAction, error) {
+		for i := range actions {
+			if actions[i].Sequence == action.Sequence {
+				actions[i] = action
+				return actions, nil
+			}
+		}
+		return nil, fmt.Errorf("action %d is no longer in the rollback journal of %s", action.Sequence, e.stateFile)
+	})
+}
@@ -0,0 +1,159 @@
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Version and Schema identify the SARIF 2.1.0 format accepted by GitHub and GitLab code
+// scanning and most enterprise scanners
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Result levels
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Log is a SARIF log with one run per tool
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run holds the results of one tool invocation
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the tool and the rules its results refer to
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool component that produced the results
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule is a kind of finding
+type Rule struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name,omitempty"`
+	ShortDescription Message    `json:"shortDescription"`
+	FullDescription  *Message   `json:"fullDescription,omitempty"`
+	Help             *Message   `json:"help,omitempty"`
+	DefaultConfig    *RuleLevel `json:"defaultConfiguration,omitempty"`
+	Properties       *RuleProps `json:"properties,omitempty"`
+}
+
+// RuleLevel is the default level of a rule's results
+type RuleLevel struct {
+	Level string `json:"level"`
+}
+
+// RuleProps carries the tags and the numeric severity GitHub uses to rank security results
+type RuleProps struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity,omitempty"`
+}
+
+// Message is plain text
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one finding
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+}
+
+// Location points at the file and, optionally, the object a result concerns
+type Location struct {
+	PhysicalLocation PhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+// PhysicalLocation is the file a result concerns
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+}
+
+// ArtifactLocation is a file URI, relative to the scanned root when possible
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// LogicalLocation is a named object, such as a Kubernetes resource or an image
+type LogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+// New returns a log with a single run for the named tool
+func New(tool, version, informationURI string) *Log {
+	return &Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs: []Run{{
+			Tool:    Tool{Driver: Driver{Name: tool, Version: version, InformationURI: informationURI, Rules: []Rule{}}},
+			Results: []Result{},
+		}},
+	}
+}
+
+// AddRule registers a rule once; later registrations of the same ID are ignored
+func (l *Log) AddRule(rule Rule) {
+	driver := &l.Runs[0].Tool.Driver
+	for _, existing := range driver.Rules {
+		if existing.ID == rule.ID {
+			return
+		}
+	}
+	driver.Rules = append(driver.Rules, rule)
+}
+
+// AddResult records a finding of a registered rule
+func (l *Log) AddResult(result Result) {
+	l.Runs[0].Results = append(l.Runs[0].Results, result)
+}
+
+// Results returns the findings recorded so far
+func (l *Log) Results() []Result {
+	return l.Runs[0].Results
+}
+
+// Write saves the log as indented JSON with its rules sorted by ID
+func (l *Log) Write(path string) error {
+	rules := l.Runs[0].Tool.Driver.Rules
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF log: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SARIF log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Release channels
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// Files of a release, served under <endpoint>/<channel>/ or at the root of an offline bundle
+const (
+	ManifestName  = "release.json"
+	SignatureName = ManifestName + ".sig"
+)
+
+// Environment variables overriding the build-time endpoint, and providing the signing key to
+// builds without one; the environment never replaces the key a vendor build embeds
+const (
+	EndpointEnv  = "E2E_INSTALLER_RELEASE_URL"
+	PublicKeyEnv = "E2E_INSTALLER_RELEASE_KEY"
+)
+
+// ReleaseEndpoint and PublicKey are set by vendor builds with -ldflags "-X", PublicKey
+// being the base64 ed25519 key that signs release manifests
+var (
+	ReleaseEndpoint string
+	PublicKey       string
+)
+
+// Release is the signed manifest describing one installer release
+type Release struct {
+	Version   string    `json:"version"`
+	Channel   string    `json:"channel"`
+	Published time.Time `json:"published"`
+	Notes     string    `json:"notes,omitempty"`
+	Assets    []Asset   `json:"assets"`
+}
+
+// Asset is the installer binary for one platform
+type Asset struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	URL    string `json:"url"` // absolute, or relative to the manifest
+	SHA256 string `json:"sha256"`
+}
+
+// Updater fetches and installs releases from the vendor endpoint or an offline bundle
+type Updater struct {
+	endpoint  string
+	channel   string
+	bundleDir string
+	publicKey ed25519.PublicKey
+	client    *http.Client
+	cleanup   func()
+}
+
+// New returns an updater reading releases from bundle, a directory or .tar.gz, when set,
+// and from endpoint otherwise
+func New(endpoint, channel, bundle string) (*Updater, error) {
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, fmt.Errorf("unknown channel %q, use %s or %s", channel, ChannelStable, ChannelBeta)
+	}
+
+	// Anyone who can set the environment could otherwise get their own binaries accepted
+	key := PublicKey
+	if key == "" {
+		key = os.Getenv(PublicKeyEnv)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no release signing key in this build, set %s to the vendor's public key", PublicKeyEnv)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("release signing key is not a base64 ed25519 public key")
+	}
+
+	updater := &Updater{
+		channel:   channel,
+		publicKey: ed25519.PublicKey(decoded),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		cleanup:   func() {},
+	}
+
+	if bundle != "" {
+		dir, cleanup, err := openBundle(bundle)
+		if err != nil {
+			return nil, err
+		}
+		updater.bundleDir, updater.cleanup = dir, cleanup
+		return updater, nil
+	}
+
+	if endpoint == "" {
+		endpoint = os.Getenv(EndpointEnv)
+	}
+	if endpoint == "" {
+		endpoint = ReleaseEndpoint
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("no release endpoint configured, pass --endpoint, set %s or use --bundle", EndpointEnv)
+	}
+	updater.endpoint = strings.TrimSuffix(endpoint, "/") + "/" + channel
+	return updater, nil
+}
+
+// Close removes the files unpacked from a bundle archive
+func (u *Updater) Close() {
+	u.cleanup()
+}
+
+// Source describes where releases are read from
+func (u *Updater) Source() string {
+	if u.bundleDir != "" {
+		return u.bundleDir
+	}
+	return u.endpoint
+}
+
+// Latest returns the release manifest after verifying its signature and channel
+func (u *Updater) Latest() (*Release, error) {
+	manifest, err := u.readAll(ManifestName)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := u.readAll(SignatureName)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", SignatureName, err)
+	}
+	if !ed25519.Verify(u.publicKey, manifest, decoded) {
+		return nil, fmt.Errorf("signature of %s does not match the release signing key", ManifestName)
+	}
+
+	var release Release
+	if err := json.Unmarshal(manifest, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestName, err)
+	}
+	if release.Channel != "" && release.Channel != u.channel {
+		return nil, fmt.Errorf("release %s is on the %s channel, not %s", release.Version, release.Channel, u.channel)
+	}
+	if _, err := semver.NewVersion(release.Version); err != nil {
+		return nil, fmt.Errorf("release version %q is not a semantic version", release.Version)
+	}
+	return &release, nil
+}
+
+// Newer reports whether release is newer than current; a current version that is not
+// semantic, such as "dev", is never considered older
+func Newer(current string, release *Release) (bool, error) {
+	have, err := semver.NewVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("current version %q cannot be compared, use --force to install %s", current, release.Version)
+	}
+	want, err := semver.NewVersion(release.Version)
+	if err != nil {
+		return false, err
+	}
+	return want.GreaterThan(have), nil
+}
+
+// PlatformAsset returns the release binary for the running OS and architecture
+func (r *Release) PlatformAsset() (*Asset, error) {
+	for i, asset := range r.Assets {
+		if asset.OS == runtime.GOOS && asset.Arch == runtime.GOARCH {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no binary for %s/%s", r.Version, runtime.GOOS, runtime.GOARCH)
+}
+
+// Apply downloads the asset, verifies its checksum, checks that it runs and atomically
+// replaces the binary at target
+func (u *Updater) Apply(asset *Asset, target string) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", target, err)
+	}
+
+	body, err := u.open(asset.URL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// The new binary is written next to the target so the final rename stays on one filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+"-*.new")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary binary: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to download %s: %w", asset.URL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, asset.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset.URL, asset.SHA256, actual)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return fmt.Errorf("failed to make %s executable: %w", tmp.Name(), err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, tmp.Name(), "--version").CombinedOutput(); err != nil {
+		return fmt.Errorf("downloaded binary does not run: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return replace(tmp.Name(), target)
+}
+
+// replace renames src over dst. Windows refuses to overwrite a running executable,
+// so there the current binary is moved aside first and restored on failure.
+func replace(src, dst string) error {
+	if runtime.GOOS != "windows" {
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", dst, err)
+		}
+		return nil
+	}
+
+	old := dst + ".old"
+	os.Remove(old)
+	if err := os.Rename(dst, old); err != nil {
+		return fmt.Errorf("failed to move %s aside: %w", dst, err)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if restoreErr := os.Rename(old, dst); restoreErr != nil {
+			logger.Error("Failed to restore the previous binary").Str("path", old).Err(restoreErr).Send()
+		}
+		return fmt.Errorf("failed to replace %s: %w", dst, err)
+	}
+	return nil
+}
+
+// Executable returns the path of the running binary with symlinks resolved
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the installer binary: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return resolved, nil
+}
+
+func (u *Updater) readAll(name string) ([]byte, error) {
+	body, err := u.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// open reads a release file from the bundle, or from the endpoint when ref is relative
+func (u *Updater) open(ref string) (io.ReadCloser, error) {
+	if u.bundleDir != "" {
+		if strings.Contains(ref, "://") {
+			ref = ref[strings.LastIndex(ref, "/")+1:]
+		}
+		file, err := os.Open(filepath.Join(u.bundleDir, filepath.Base(ref)))
+		if err != nil {
+			return nil, fmt.Errorf("bundle is missing %s: %w", filepath.Base(ref), err)
+		}
+		return file, nil
+	}
+
+	url := ref
+	if !strings.Contains(ref, "://") {
+		url = u.endpoint + "/" + ref
+	}
+	resp, err := u.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// openBundle returns the directory holding an offline bundle, unpacking .tar.gz archives
+func openBundle(path string) (string, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	if info.IsDir() {
+		return path, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "e2e-installer-bundle-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	if err := extractBundle(path, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+// extractBundle unpacks the regular files of a .tar.gz bundle flat into dir
+func extractBundle(archive, dir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("bundle %s is not a directory or .tar.gz: %w", archive, err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		out, err := os.Create(filepath.Join(dir, filepath.Base(header.Name)))
+		if err != nil {
+			return fmt.Errorf("failed to unpack bundle: %w", err)
+		}
+		_, err = io.Copy(out, reader)
+		out.Close()
+		if err != nil {
+			return fmt.Errorf("failed to unpack %s: %w", header.Name, err)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package simulate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// simulatedUser is who the simulated cluster authenticates every client as
+var simulatedUser = Object{"username": "simulated-admin", "groups": []interface{}{"system:masters", "system:authenticated"}}
+
+// reviewKinds are answered when created rather than stored
+var reviewKinds = []kind{
+	{"SelfSubjectAccessReview", "authorization.k8s.io/v1", "selfsubjectaccessreviews", false},
+	{"SelfSubjectRulesReview", "authorization.k8s.io/v1", "selfsubjectrulesreviews", true},
+	{"SubjectAccessReview", "authorization.k8s.io/v1", "subjectaccessreviews", false},
+	{"SelfSubjectReview", "authentication.k8s.io/v1", "selfsubjectreviews", false},
+	{"TokenReview", "authentication.k8s.io/v1", "tokenreviews", false},
+}
+
+// objectVerbs are the verbs every stored resource supports
+var objectVerbs = []string{"create", "delete", "get", "list", "patch", "update"}
+
+// serveAPI answers the Kubernetes API of the simulated cluster, for the clients the installer
+// builds with client-go: discovery, reading, creating, applying, patching and deleting
+// objects, pod logs, ServiceAccount tokens and access reviews
+func (e *Environment) serveAPI(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "version":
+		minor := strings.Split(strings.TrimPrefix(e.Cluster.Version, "v"), ".")
+		writeObject(w, http.StatusOK, Object{"major": "1", "minor": minor[min(1, len(minor)-1)], "gitVersion": e.Cluster.Version, "platform": "linux/amd64"})
+	case path == "api":
+		writeObject(w, http.StatusOK, Object{"kind": "APIVersions", "versions": []interface{}{"v1"},
+			"serverAddressByClientCIDRs": []interface{}{Object{"clientCIDR": "0.0.0.0/0", "serverAddress": "kubernetes.simulated:443"}}})
+	case path == "apis":
+		writeObject(w, http.StatusOK, Object{"kind": "APIGroupList", "apiVersion": "v1", "groups": e.apiGroups()})
+	case path == "api/v1" || (strings.HasPrefix(path, "apis/") && strings.Count(path, "/") == 2):
+		groupVersion := strings.TrimPrefix(strings.TrimPrefix(path, "apis/"), "api/")
+		resources := e.apiResources(groupVersion)
+		if len(resources) == 0 {
+			apiStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+			return
+		}
+		writeObject(w, http.StatusOK, Object{"kind": "APIResourceList", "apiVersion": "v1", "groupVersion": groupVersion, "resources": resources})
+	default:
+		e.serveObjects(w, r, path)
+	}
+}
+
+// apiKinds returns the kinds the API serves: those stored and the reviews
+func (e *Environment) apiKinds() []kind {
+	return append(e.Cluster.kinds(), reviewKinds...)
+}
+
+// apiGroups lists the named API groups with their versions, as GET /apis does
+func (e *Environment) apiGroups() []interface{} {
+	versions := make(map[string][]string)
+	var names []string
+	for _, k := range e.apiKinds() {
+		group := k.group()
+		if group == "" {
+			continue
+		}
+		if _, ok := versions[group]; !ok {
+			names = append(names, group)
+		}
+		if version := strings.TrimPrefix(k.APIVersion, group+"/"); !slices.Contains(versions[group], version) {
+			versions[group] = append(versions[group], version)
+		}
+	}
+	sort.Strings(names)
+	groups := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		var served []interface{}
+		for _, version := range versions[name] {
+			served = append(served, Object{"groupVersion": name + "/" + version, "version": version})
+		}
+		groups = append(groups, Object{"name": name, "versions": served, "preferredVersion": served[0]})
+	}
+	return groups
+}
+
+// apiResources lists the resources of a group version with their subresources
+func (e *Environment) apiResources(groupVersion string) []interface{} {
+	var resources []interface{}
+	for _, k := range e.apiKinds() {
+		if k.APIVersion != groupVersion {
+			continue
+		}
+		verbs := objectVerbs
+		if isReview(k) {
+			verbs = []string{"create"}
+		}
+		resources = append(resources, Object{"name": k.Plural, "singularName": strings.ToLower(k.Kind), "namespaced": k.Namespaced, "kind": k.Kind, "verbs": verbs})
+		switch k.Kind {
+		case "Pod":
+			resources = append(resources, Object{"name": "pods/log", "namespaced": true, "kind": "Pod", "verbs": []string{"get"}})
+		case "ServiceAccount":
+			resources = append(resources, Object{"name": "serviceaccounts/token", "group": "authentication.k8s.io", "version": "v1",
+				"namespaced": true, "kind": "TokenRequest", "verbs": []string{"create"}})
+		}
+		if !isReview(k) {
+			resources = append(resources, Object{"name": k.Plural + "/status", "namespaced": k.Namespaced, "kind": k.Kind, "verbs": []string{"get", "patch", "update"}})
+		}
+	}
+	return resources
+}
+
+func isReview(k kind) bool {
+	for _, review := range reviewKinds {
+		if review.Kind == k.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// serveObjects answers the requests for the objects of a resource at path, e.g.
+// apis/apps/v1/namespaces/default/deployments/web or api/v1/nodes
+func (e *Environment) serveObjects(w http.ResponseWriter, r *http.Request, path string) {
+	segments := strings.Split(path, "/")
+	var groupVersion string
+	switch {
+	case len(segments) >= 3 && segments[0] == "api":
+		groupVersion, segments = segments[1], segments[2:]
+	case len(segments) >= 4 && segments[0] == "apis":
+		groupVersion, segments = segments[1]+"/"+segments[2], segments[3:]
+	default:
+		apiStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+		return
+	}
+	namespace := ""
+	if len(segments) >= 3 && segments[0] == "namespaces" && segments[2] != "status" && segments[2] != "finalize" {
+		namespace, segments = segments[1], segments[2:]
+	}
+	resource, name, subresource := segments[0], "", ""
+	if len(segments) > 1 {
+		name = segments[1]
+	}
+	if len(segments) > 2 {
+		subresource = segments[2]
+	}
+
+	var k kind
+	found := false
+	for _, candidate := range e.apiKinds() {
+		if candidate.APIVersion == groupVersion && candidate.Plural == resource {
+			k, found = candidate, true
+			break
+		}
+	}
+	if !found {
+		apiStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+		return
+	}
+	if k.Namespaced && namespace == "" && name != "" {
+		namespace = "default"
+	}
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		e.listObjects(w, r, k, namespace)
+	case r.Method == http.MethodGet && subresource == "log" && k.Kind == "Pod":
+		e.podLog(w, r, k, namespace, name)
+	case r.Method == http.MethodGet:
+		if obj := e.Cluster.get(k, namespace, name); obj != nil {
+			writeObject(w, http.StatusOK, obj)
+			return
+		}
+		notFound(w, k, name)
+	case r.Method == http.MethodPost && subresource == "token" && k.Kind == "ServiceAccount":
+		e.createToken(w, r, namespace, name)
+	case r.Method == http.MethodPost && name == "":
+		e.createObject(w, r, k, namespace)
+	case r.Method == http.MethodPatch && name != "":
+		e.patchObject(w, r, k, namespace, name, subresource)
+	case r.Method == http.MethodPut && name != "":
+		e.updateObject(w, r, k, namespace, name, subresource)
+	case r.Method == http.MethodDelete && name != "":
+		obj := e.Cluster.get(k, namespace, name)
+		if obj == nil {
+			notFound(w, k, name)
+			return
+		}
+		e.Cluster.remove(obj)
+		e.releaseChanged(obj)
+		writeObject(w, http.StatusOK, Object{"kind": "Status", "apiVersion": "v1", "metadata": Object{}, "status": "Success",
+			"details": Object{"name": name, "group": k.group(), "kind": k.Plural, "uid": str(obj, "metadata", "uid")}})
+	default:
+		apiStatus(w, http.StatusMethodNotAllowed, "MethodNotAllowed", fmt.Sprintf("%s is not supported on %s", r.Method, path))
+	}
+}
+
+// listObjects lists the objects matching the label and field selectors of a request. A
+// watch ends at once without events, and its client lists again; what it waits for has
+// happened by then, since the simulated controllers act on every change right away.
+func (e *Environment) listObjects(w http.ResponseWriter, r *http.Request, k kind, namespace string) {
+	if watch := r.URL.Query().Get("watch"); watch == "true" || watch == "1" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	selector := parseSelector(r.URL.Query().Get("labelSelector"))
+	if k.Kind == "Node" {
+		e.ensurePools(selector)
+	}
+	fieldSelector := parseSelector(r.URL.Query().Get("fieldSelector"))
+	objects := []Object{}
+	if !isReview(k) {
+		for _, obj := range e.Cluster.list(k.Kind, namespace, selector) {
+			if matches(fieldSelector, fieldsOf(obj)) {
+				objects = append(objects, obj)
+			}
+		}
+	}
+	writeObject(w, http.StatusOK, Object{
+		"apiVersion": k.APIVersion,
+		"kind":       k.Kind + "List",
+		"metadata":   Object{"resourceVersion": strconv.Itoa(e.Cluster.Counter)},
+		"items":      toList(objects),
+	})
+}
+
+// fieldsOf returns the fields of an object field selectors select by
+func fieldsOf(obj Object) map[string]string {
+	return map[string]string{
+		"metadata.name":      str(obj, "metadata", "name"),
+		"metadata.namespace": str(obj, "metadata", "namespace"),
+		"spec.nodeName":      str(obj, "spec", "nodeName"),
+		"status.phase":       str(obj, "status", "phase"),
+		"type":               str(obj, "type"),
+	}
+}
+
+// podLog writes the log the simulated containers of a pod print; a job's pod has completed
+func (e *Environment) podLog(w http.ResponseWriter, r *http.Request, k kind, namespace, name string) {
+	pod := e.Cluster.get(k, namespace, name)
+	if pod == nil {
+		notFound(w, k, name)
+		return
+	}
+	now := time.Now().UTC()
+	lines := []string{
+		fmt.Sprintf("%s INFO starting %s (simulated)", now.Add(-2*time.Second).Format(time.RFC3339), name),
+		fmt.Sprintf("%s INFO %s ready", now.Add(-time.Second).Format(time.RFC3339), name),
+	}
+	if labelsOf(pod)["job-name"] != "" {
+		lines = append(lines, fmt.Sprintf("%s INFO %s completed successfully", now.Format(time.RFC3339), name))
+	}
+	if tail, err := strconv.Atoi(r.URL.Query().Get("tailLines")); err == nil && tail < len(lines) {
+		lines = lines[len(lines)-max(tail, 0):]
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// createToken answers a TokenRequest for a ServiceAccount
+func (e *Environment) createToken(w http.ResponseWriter, r *http.Request, namespace, name string) {
+	request, err := readObject(r)
+	if err != nil {
+		apiStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	expiration := time.Duration(intAt(request, 3600, "spec", "expirationSeconds")) * time.Second
+	expires := time.Now().Add(expiration)
+	request["metadata"] = Object{"name": name, "namespace": namespace, "creationTimestamp": nil}
+	request["status"] = Object{"token": e.token(namespace, name, expires), "expirationTimestamp": expires.UTC().Format(time.RFC3339)}
+	writeObject(w, http.StatusCreated, request)
+}
+
+// token mints a ServiceAccount token: a JWT the simulated cluster alone accepts
+func (e *Environment) token(namespace, serviceAccount string, expires time.Time) string {
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub": fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount),
+		"iss": "https://kubernetes.default.svc",
+		"exp": expires.Unix(),
+	})
+	encode := base64.RawURLEncoding.EncodeToString
+	return fmt.Sprintf("%s.%s.%s", encode([]byte(`{"alg":"RS256","kid":"simulated"}`)), encode(claims), encode([]byte(e.nextID("signature"))))
+}
+
+func (e *Environment) createObject(w http.ResponseWriter, r *http.Request, k kind, namespace string) {
+	obj, err := readObject(r)
+	if err != nil {
+		apiStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	if isReview(k) {
+		// The caller may do anything
+		obj["status"] = reviewStatus(k)
+		writeObject(w, http.StatusCreated, obj)
+		return
+	}
+	metadata := mapAt(obj, "metadata")
+	if metadata == nil {
+		metadata = Object{}
+		obj["metadata"] = metadata
+	}
+	if k.Namespaced && str(metadata, "namespace") == "" {
+		metadata["namespace"] = valueOr(namespace, "default")
+	}
+	if str(metadata, "name") == "" && str(metadata, "generateName") != "" {
+		e.Serial++
+		metadata["name"] = fmt.Sprintf("%s%05x", str(metadata, "generateName"), e.Serial)
+	}
+	name := str(metadata, "name")
+	if e.Cluster.get(k, str(metadata, "namespace"), name) != nil {
+		apiStatus(w, http.StatusConflict, "AlreadyExists", fmt.Sprintf(`%s "%s" already exists`, k.qualifiedPlural(), name))
+		return
+	}
+	if r.URL.Query().Get("dryRun") != "" {
+		// Nothing is stored, the object only gets what the API server would set
+		metadata["uid"] = e.Cluster.uid(name)
+		metadata["creationTimestamp"] = time.Now().UTC().Format(time.RFC3339)
+		writeObject(w, http.StatusCreated, obj)
+		return
+	}
+	e.Cluster.put(obj)
+	e.releaseChanged(obj)
+	writeObject(w, http.StatusCreated, obj)
+}
+
+// reviewStatus is the answer to an access or token review: everything is allowed
+func reviewStatus(k kind) Object {
+	switch k.Kind {
+	case "SelfSubjectReview":
+		return Object{"userInfo": simulatedUser}
+	case "TokenReview":
+		return Object{"authenticated": true, "user": simulatedUser}
+	case "SelfSubjectRulesReview":
+		return Object{"resourceRules": []interface{}{Object{"verbs": []interface{}{"*"}, "apiGroups": []interface{}{"*"}, "resources": []interface{}{"*"}}},
+			"nonResourceRules": []interface{}{Object{"verbs": []interface{}{"*"}, "nonResourceURLs": []interface{}{"*"}}}, "incomplete": false}
+	}
+	return Object{"allowed": true}
+}
+
+// patchObject applies a server-side apply, merge or strategic merge patch. Applying creates a
+// missing object. Strategic merge patches follow the patch strategies of the built-in types;
+// those of other types are merged like JSON merge patches.
+func (e *Environment) patchObject(w http.ResponseWriter, r *http.Request, k kind, namespace, name, subresource string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apiStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	patch, err := decodeObject(data)
+	if err != nil {
+		apiStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	existing := e.Cluster.get(k, namespace, name)
+	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	switch contentType {
+	case "application/apply-patch+yaml":
+		metadata := mapAt(patch, "metadata")
+		if metadata == nil {
+			metadata = Object{}
+			patch["metadata"] = metadata
+		}
+		metadata["name"] = name
+		if k.Namespaced {
+			metadata["namespace"] = namespace
+		}
+		status := http.StatusOK
+		switch {
+		case existing == nil:
+			status = http.StatusCreated
+		case subresource == "status":
+			existing["status"] = patch["status"]
+			patch = existing
+		default:
+			keepMetadata(existing, patch)
+		}
+		if r.URL.Query().Get("dryRun") == "" {
+			e.Cluster.put(patch)
+			e.releaseChanged(patch)
+		}
+		writeObject(w, status, patch)
+	case "application/merge-patch+json", "application/strategic-merge-patch+json":
+		if existing == nil {
+			notFound(w, k, name)
+			return
+		}
+		patched := deepCopy(existing)
+		typed, err := scheme.Scheme.New(schema.FromAPIVersionAndKind(k.APIVersion, k.Kind))
+		if contentType == "application/strategic-merge-patch+json" && err == nil {
+			if patched, err = strategicMerge(existing, data, typed); err != nil {
+				apiStatus(w, http.StatusUnprocessableEntity, "Invalid", err.Error())
+				return
+			}
+		} else {
+			mergePatch(patched, patch)
+		}
+		if r.URL.Query().Get("dryRun") == "" {
+			e.Cluster.put(patched)
+			e.releaseChanged(patched)
+		}
+		writeObject(w, http.StatusOK, patched)
+	default:
+		apiStatus(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", fmt.Sprintf("the patch type %q is not supported", contentType))
+	}
+}
+
+// updateObject replaces an object, or only its status
+func (e *Environment) updateObject(w http.ResponseWriter, r *http.Request, k kind, namespace, name, subresource string) {
+	obj, err := readObject(r)
+	if err != nil {
+		apiStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	existing := e.Cluster.get(k, namespace, name)
+	if existing == nil {
+		notFound(w, k, name)
+		return
+	}
+	if subresource == "status" {
+		existing["status"] = obj["status"]
+		obj = existing
+	}
+	// The object is the one of the URL, whatever the body names
+	metadata := mapAt(obj, "metadata")
+	if metadata == nil {
+		metadata = Object{}
+		obj["metadata"] = metadata
+	}
+	metadata["name"] = name
+	if k.Namespaced {
+		metadata["namespace"] = namespace
+	}
+	e.Cluster.put(obj)
+	e.releaseChanged(obj)
+	writeObject(w, http.StatusOK, obj)
+}
+
+// strategicMerge applies a strategic merge patch to an object of a built-in type
+func strategicMerge(obj Object, patch []byte, typed runtime.Object) (Object, error) {
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, typed)
+	if err != nil {
+		return nil, err
+	}
+	var result Object
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readObject decodes the object of a request body, JSON or YAML
+func readObject(r *http.Request) (Object, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(data)
+}
+
+func decodeObject(data []byte) (Object, error) {
+	var obj Object
+	if err := json.Unmarshal(data, &obj); err != nil {
+		objects, yamlErr := decodeManifest(data)
+		if yamlErr != nil || len(objects) != 1 {
+			return nil, fmt.Errorf("failed to decode the request body: %v", err)
+		}
+		obj = objects[0]
+	}
+	if obj == nil {
+		obj = Object{}
+	}
+	return obj, nil
+}
+
+func writeObject(w http.ResponseWriter, status int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// apiStatus answers with a failure Status, which client-go turns into a StatusError
+func apiStatus(w http.ResponseWriter, code int, reason, message string) {
+	writeObject(w, code, Object{"kind": "Status", "apiVersion": "v1", "metadata": Object{}, "status": "Failure",
+		"message": message, "reason": reason, "code": code})
+}
+
+func notFound(w http.ResponseWriter, k kind, name string) {
+	apiStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf(`%s "%s" not found`, k.qualifiedPlural(), name))
+}
@@ -0,0 +1,130 @@
+package simulate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Charts pulled from simulated repositories and registries are generated: a Deployment and a
+// Service sized and imaged by the values keys renderChart reads too
+
+const chartHelpers = `{{- define "simulated.fullname" -}}
+{{- if contains .Chart.Name .Release.Name }}{{ .Release.Name }}{{ else }}{{ printf "%s-%s" .Release.Name .Chart.Name }}{{ end }}
+{{- end }}
+
+{{- define "simulated.selectorLabels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
+{{- define "simulated.labels" -}}
+{{ include "simulated.selectorLabels" . }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+helm.sh/chart: {{ printf "%s-%s" .Chart.Name .Chart.Version }}
+{{- end }}
+`
+
+const chartDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "simulated.fullname" . }}
+  labels:
+    {{- include "simulated.labels" . | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  selector:
+    matchLabels:
+      {{- include "simulated.selectorLabels" . | nindent 6 }}
+  template:
+    metadata:
+      labels:
+        {{- include "simulated.labels" . | nindent 8 }}
+    spec:
+      containers:
+        - name: {{ .Chart.Name }}
+          image: "{{ .Values.image.repository | default (printf "registry.local/%s" .Chart.Name) }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
+          ports:
+            - name: http
+              containerPort: 8080
+          resources:
+            {{- toYaml .Values.resources | nindent 12 }}
+`
+
+const chartService = `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ include "simulated.fullname" . }}
+  labels:
+    {{- include "simulated.labels" . | nindent 4 }}
+spec:
+  type: {{ .Values.service.type }}
+  selector:
+    {{- include "simulated.selectorLabels" . | nindent 4 }}
+  ports:
+    - name: http
+      port: {{ .Values.service.port }}
+      targetPort: http
+`
+
+const chartValues = `replicaCount: 1
+image:
+  repository: ""
+  tag: ""
+service:
+  type: ClusterIP
+  port: 80
+resources:
+  requests:
+    cpu: 100m
+    memory: 128Mi
+`
+
+// writeChartArchive writes the packaged chart a simulated helm pull saves
+func writeChartArchive(path, name, version, appVersion string) error {
+	files := map[string]string{
+		"Chart.yaml":                fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\nappVersion: %q\n", name, version, appVersion),
+		"values.yaml":               chartValues,
+		"templates/_helpers.tpl":    chartHelpers,
+		"templates/deployment.yaml": chartDeployment,
+		"templates/service.yaml":    chartService,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		content := files[file]
+		header := &tar.Header{Name: name + "/" + file, Mode: 0644, Size: int64(len(content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
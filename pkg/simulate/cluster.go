@@ -0,0 +1,827 @@
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kubernetesVersion is the version the simulated cluster and its nodes run
+const kubernetesVersion = "v1.29.2"
+
+// defaultNodes is the size of the node pool a new simulated cluster starts with
+const defaultNodes = 3
+
+// nodePoolLabels name the node pool of a node on EKS, AKS and GKE; simulated nodes carry all three
+var nodePoolLabels = []string{"eks.amazonaws.com/nodegroup", "kubernetes.azure.com/agentpool", "cloud.google.com/gke-nodepool"}
+
+// Object is a Kubernetes object as kubectl prints it
+type Object = map[string]interface{}
+
+// Cluster is the simulated Kubernetes cluster. Controllers are simulated too: workloads
+// get their pods, scheduled and running, as soon as they are applied.
+type Cluster struct {
+	Version string            `json:"version"`
+	Objects map[string]Object `json:"objects"` // by kind/namespace/name
+	Counter int               `json:"counter"` // last resourceVersion handed out
+}
+
+// kind is a resource type of the cluster
+type kind struct {
+	Kind       string
+	APIVersion string
+	Plural     string
+	Namespaced bool
+}
+
+func (k kind) group() string {
+	if group, _, found := strings.Cut(k.APIVersion, "/"); found {
+		return group
+	}
+	return ""
+}
+
+// qualified names the kind the way kubectl prints it, e.g. deployment.apps
+func (k kind) qualified() string {
+	if group := k.group(); group != "" {
+		return strings.ToLower(k.Kind) + "." + group
+	}
+	return strings.ToLower(k.Kind)
+}
+
+// qualifiedPlural names the resource of the kind the way API errors do, e.g. deployments.apps
+func (k kind) qualifiedPlural() string {
+	if group := k.group(); group != "" {
+		return k.Plural + "." + group
+	}
+	return k.Plural
+}
+
+var builtinKinds = []kind{
+	{"Pod", "v1", "pods", true},
+	{"Service", "v1", "services", true},
+	{"ConfigMap", "v1", "configmaps", true},
+	{"Secret", "v1", "secrets", true},
+	{"ServiceAccount", "v1", "serviceaccounts", true},
+	{"PersistentVolumeClaim", "v1", "persistentvolumeclaims", true},
+	{"Event", "v1", "events", true},
+	{"Endpoints", "v1", "endpoints", true},
+	{"Namespace", "v1", "namespaces", false},
+	{"Node", "v1", "nodes", false},
+	{"PersistentVolume", "v1", "persistentvolumes", false},
+	{"Deployment", "apps/v1", "deployments", true},
+	{"StatefulSet", "apps/v1", "statefulsets", true},
+	{"DaemonSet", "apps/v1", "daemonsets", true},
+	{"ReplicaSet", "apps/v1", "replicasets", true},
+	{"Job", "batch/v1", "jobs", true},
+	{"CronJob", "batch/v1", "cronjobs", true},
+	{"PodDisruptionBudget", "policy/v1", "poddisruptionbudgets", true},
+	{"HorizontalPodAutoscaler", "autoscaling/v2", "horizontalpodautoscalers", true},
+	{"Ingress", "networking.k8s.io/v1", "ingresses", true},
+	{"NetworkPolicy", "networking.k8s.io/v1", "networkpolicies", true},
+	{"IngressClass", "networking.k8s.io/v1", "ingressclasses", false},
+	{"Role", "rbac.authorization.k8s.io/v1", "roles", true},
+	{"RoleBinding", "rbac.authorization.k8s.io/v1", "rolebindings", true},
+	{"ClusterRole", "rbac.authorization.k8s.io/v1", "clusterroles", false},
+	{"ClusterRoleBinding", "rbac.authorization.k8s.io/v1", "clusterrolebindings", false},
+	{"StorageClass", "storage.k8s.io/v1", "storageclasses", false},
+	{"CustomResourceDefinition", "apiextensions.k8s.io/v1", "customresourcedefinitions", false},
+	{"ValidatingAdmissionPolicy", "admissionregistration.k8s.io/v1", "validatingadmissionpolicies", false},
+	{"ValidatingAdmissionPolicyBinding", "admissionregistration.k8s.io/v1", "validatingadmissionpolicybindings", false},
+}
+
+var shortNames = map[string]string{
+	"po": "pods", "svc": "services", "cm": "configmaps", "sa": "serviceaccounts", "pvc": "persistentvolumeclaims",
+	"ev": "events", "ep": "endpoints", "ns": "namespaces", "no": "nodes", "pv": "persistentvolumes",
+	"deploy": "deployments", "sts": "statefulsets", "ds": "daemonsets", "rs": "replicasets", "cj": "cronjobs",
+	"pdb": "poddisruptionbudgets", "hpa": "horizontalpodautoscalers", "ing": "ingresses", "netpol": "networkpolicies",
+	"sc": "storageclasses", "crd": "customresourcedefinitions", "crds": "customresourcedefinitions",
+}
+
+func newCluster() *Cluster {
+	c := &Cluster{Version: kubernetesVersion, Objects: make(map[string]Object)}
+	for _, name := range []string{"default", "kube-system", "kube-public", "kube-node-lease"} {
+		c.put(Object{"apiVersion": "v1", "kind": "Namespace", "metadata": Object{"name": name}})
+	}
+	c.scalePool("default", defaultNodes)
+	return c
+}
+
+// resource finds the kind kubectl means by a resource name: a plural, singular or short
+// name, optionally qualified with its version and group, e.g. widgets.v1.example.com
+func (c *Cluster) resource(name string) (kind, bool) {
+	name = strings.ToLower(name)
+	base, _, _ := strings.Cut(name, ".")
+	if plural, ok := shortNames[base]; ok {
+		base = plural
+	}
+	for _, k := range c.kinds() {
+		if base == k.Plural || base == strings.ToLower(k.Kind) {
+			return k, true
+		}
+	}
+	return kind{}, false
+}
+
+// kindOf finds the kind of an object by its kind name, defaulting to a namespaced custom resource
+func (c *Cluster) kindOf(obj Object) kind {
+	name := str(obj, "kind")
+	for _, k := range c.kinds() {
+		if k.Kind == name {
+			return k
+		}
+	}
+	return kind{Kind: name, APIVersion: str(obj, "apiVersion"), Plural: strings.ToLower(name) + "s", Namespaced: true}
+}
+
+// kinds returns the built-in kinds and those of the custom resource definitions applied
+func (c *Cluster) kinds() []kind {
+	kinds := append([]kind(nil), builtinKinds...)
+	for _, crd := range c.list("CustomResourceDefinition", "", nil) {
+		names := mapAt(crd, "spec", "names")
+		version := ""
+		if versions, ok := at(crd, "spec", "versions").([]interface{}); ok && len(versions) > 0 {
+			if v, ok := versions[0].(map[string]interface{}); ok {
+				version, _ = v["name"].(string)
+			}
+		}
+		kinds = append(kinds, kind{
+			Kind:       str(names, "kind"),
+			APIVersion: str(crd, "spec", "group") + "/" + version,
+			Plural:     str(names, "plural"),
+			Namespaced: str(crd, "spec", "scope") != "Cluster",
+		})
+	}
+	return kinds
+}
+
+func (c *Cluster) key(k kind, namespace, name string) string {
+	if !k.Namespaced {
+		namespace = ""
+	}
+	return k.Kind + "/" + namespace + "/" + name
+}
+
+func (c *Cluster) get(k kind, namespace, name string) Object {
+	return c.Objects[c.key(k, namespace, name)]
+}
+
+// list returns the objects of kindName in namespace, every namespace when it is empty,
+// matching selector, sorted by namespace and name
+func (c *Cluster) list(kindName, namespace string, selector []requirement) []Object {
+	var objects []Object
+	for _, obj := range c.Objects {
+		if str(obj, "kind") != kindName {
+			continue
+		}
+		if namespace != "" && str(obj, "metadata", "namespace") != "" && str(obj, "metadata", "namespace") != namespace {
+			continue
+		}
+		if !matches(selector, labelsOf(obj)) {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if str(a, "metadata", "namespace") != str(b, "metadata", "namespace") {
+			return str(a, "metadata", "namespace") < str(b, "metadata", "namespace")
+		}
+		return str(a, "metadata", "name") < str(b, "metadata", "name")
+	})
+	return objects
+}
+
+// put stores an object, filling in the metadata the API server sets, and runs its
+// controller. It returns created or configured.
+func (c *Cluster) put(obj Object) string {
+	k := c.kindOf(obj)
+	metadata := mapAt(obj, "metadata")
+	if metadata == nil {
+		metadata = Object{}
+		obj["metadata"] = metadata
+	}
+	if !k.Namespaced {
+		delete(metadata, "namespace")
+	} else if str(metadata, "namespace") == "" {
+		metadata["namespace"] = "default"
+	}
+	if obj["apiVersion"] == nil {
+		obj["apiVersion"] = k.APIVersion
+	}
+
+	c.Counter++
+	key := c.key(k, str(metadata, "namespace"), str(metadata, "name"))
+	result := "created"
+	generation := 1
+	if existing, ok := c.Objects[key]; ok {
+		result = "configured"
+		previous := mapAt(existing, "metadata")
+		metadata["uid"] = previous["uid"]
+		metadata["creationTimestamp"] = previous["creationTimestamp"]
+		generation = intAt(existing, 1, "metadata", "generation") + 1
+		if obj["status"] == nil {
+			obj["status"] = existing["status"]
+		}
+	} else {
+		metadata["uid"] = c.uid(key)
+		metadata["creationTimestamp"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	metadata["generation"] = generation
+	metadata["resourceVersion"] = strconv.Itoa(c.Counter)
+	c.Objects[key] = obj
+	c.reconcile(obj)
+	return result
+}
+
+// remove deletes an object with what it owns: the pods of a workload and the contents of
+// a namespace. A deleted pod of a workload is replaced, like its controller would.
+func (c *Cluster) remove(obj Object) {
+	k := c.kindOf(obj)
+	namespace, name := str(obj, "metadata", "namespace"), str(obj, "metadata", "name")
+	delete(c.Objects, c.key(k, namespace, name))
+
+	switch k.Kind {
+	case "Namespace":
+		for key, owned := range c.Objects {
+			if str(owned, "metadata", "namespace") == name {
+				delete(c.Objects, key)
+			}
+		}
+	case "Pod":
+		// Its controller replaces it, and a Deployment counts its ReplicaSet's pods again
+		for owner := c.owner(obj); owner != nil; owner = c.owner(owner) {
+			c.reconcile(owner)
+		}
+	default:
+		for _, replicaSet := range c.owned(obj, "ReplicaSet") {
+			c.remove(replicaSet)
+		}
+		for _, pod := range c.owned(obj, "Pod") {
+			c.drop(pod)
+		}
+	}
+}
+
+func (c *Cluster) uid(seed string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", seed, c.Counter)
+	sum := h.Sum64()
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", uint32(sum>>32), uint16(sum>>16), uint16(sum), c.Counter&0xffff, sum&0xffffffffffff)
+}
+
+// reconcile does what the controller of obj would: workloads get their pods, and every
+// object the status showing it ready
+func (c *Cluster) reconcile(obj Object) {
+	status := Object{}
+	generation := intAt(obj, 1, "metadata", "generation")
+	switch str(obj, "kind") {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		replicas := intAt(obj, 1, "spec", "replicas")
+		var pods []Object
+		if str(obj, "kind") == "Deployment" {
+			pods = c.owned(c.syncReplicaSet(obj, replicas), "Pod")
+		} else {
+			pods = c.syncPods(obj, replicas, nil)
+		}
+		ready := countReady(pods)
+		status = Object{
+			"observedGeneration": generation,
+			"replicas":           len(pods),
+			"readyReplicas":      ready,
+			"availableReplicas":  ready,
+			"updatedReplicas":    len(pods),
+			"currentReplicas":    len(pods),
+			"conditions":         []interface{}{condition("Available", ready >= replicas), condition("Progressing", true)},
+		}
+	case "DaemonSet":
+		nodes := c.schedulableNodes(mapAt(obj, "spec", "template", "spec", "nodeSelector"))
+		pods := c.syncPods(obj, len(nodes), nodes)
+		ready := countReady(pods)
+		status = Object{
+			"observedGeneration":     generation,
+			"desiredNumberScheduled": len(nodes),
+			"currentNumberScheduled": len(pods),
+			"updatedNumberScheduled": len(pods),
+			"numberReady":            ready,
+			"numberAvailable":        ready,
+		}
+	case "Job":
+		completions := intAt(obj, 1, "spec", "completions")
+		pods := c.syncPods(obj, completions, nil)
+		now := time.Now().UTC().Format(time.RFC3339)
+		status = Object{
+			"succeeded":      len(pods),
+			"active":         0,
+			"startTime":      now,
+			"completionTime": now,
+			"conditions":     []interface{}{condition("Complete", true)},
+		}
+	case "Pod":
+		if str(obj, "status", "phase") == "" {
+			c.schedule(obj)
+		}
+		return
+	case "Namespace":
+		status = Object{"phase": "Active"}
+	case "PersistentVolumeClaim":
+		status = Object{"phase": "Bound"}
+	case "CustomResourceDefinition":
+		status = Object{
+			"acceptedNames": at(obj, "spec", "names"),
+			"conditions":    []interface{}{condition("NamesAccepted", true), condition("Established", true)},
+		}
+	case "Service":
+		spec := mapAt(obj, "spec")
+		if spec != nil && spec["clusterIP"] == nil {
+			spec["clusterIP"] = fmt.Sprintf("10.96.%d.%d", c.Counter/250%250, c.Counter%250+1)
+		}
+		if str(obj, "spec", "type") == "LoadBalancer" {
+			status = Object{"loadBalancer": Object{"ingress": []interface{}{Object{"ip": "127.0.0.1"}}}}
+		}
+	case "PodDisruptionBudget":
+		status = c.disruptionStatus(obj)
+	default:
+		if obj["status"] != nil {
+			return
+		}
+	}
+	obj["status"] = status
+}
+
+// syncReplicaSet keeps the ReplicaSet of a Deployment's current template at replicas, named
+// and labelled with the template hash like the deployment controller does, and deletes the
+// ReplicaSets of older templates with their pods
+func (c *Cluster) syncReplicaSet(deployment Object, replicas int) Object {
+	hash := templateHash(deployment)
+	// Pods of Deployments simulated before they had ReplicaSets
+	for _, pod := range c.owned(deployment, "Pod") {
+		c.drop(pod)
+	}
+	for _, replicaSet := range c.owned(deployment, "ReplicaSet") {
+		if str(replicaSet, "metadata", "labels", "pod-template-hash") != hash {
+			c.remove(replicaSet)
+		}
+	}
+
+	template := deepCopy(mapAt(deployment, "spec", "template"))
+	if template == nil {
+		template = Object{}
+	}
+	templateMetadata := mapAt(template, "metadata")
+	if templateMetadata == nil {
+		templateMetadata = Object{}
+		template["metadata"] = templateMetadata
+	}
+	labels := mapAt(templateMetadata, "labels")
+	if labels == nil {
+		labels = Object{}
+		templateMetadata["labels"] = labels
+	}
+	labels["pod-template-hash"] = hash
+	selector := deepCopy(mapAt(deployment, "spec", "selector"))
+	if selector == nil {
+		selector = Object{}
+	}
+	matchLabels := mapAt(selector, "matchLabels")
+	if matchLabels == nil {
+		matchLabels = Object{}
+		selector["matchLabels"] = matchLabels
+	}
+	matchLabels["pod-template-hash"] = hash
+
+	replicaSet := Object{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"metadata": Object{
+			"name":            str(deployment, "metadata", "name") + "-" + hash,
+			"namespace":       str(deployment, "metadata", "namespace"),
+			"labels":          deepCopy(labels),
+			"ownerReferences": []interface{}{controllerRef(deployment)},
+		},
+		"spec": Object{"replicas": replicas, "selector": selector, "template": template},
+	}
+	c.put(replicaSet)
+	return replicaSet
+}
+
+// syncPods keeps count pods of a workload running the current template, or one on each of
+// nodes when they are given. Pods of an older template are replaced, like a rollout does.
+func (c *Cluster) syncPods(owner Object, count int, nodes []Object) []Object {
+	hash := templateHash(owner)
+	eligible := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		eligible[str(node, "metadata", "name")] = true
+	}
+
+	var kept []Object
+	covered := make(map[string]bool)
+	for _, pod := range c.owned(owner, "Pod") {
+		node := str(pod, "spec", "nodeName")
+		stale := str(pod, "metadata", "labels", "pod-template-hash") != hash
+		if nodes != nil {
+			stale = stale || !eligible[node] || covered[node]
+		} else {
+			stale = stale || len(kept) >= count
+		}
+		if stale {
+			c.drop(pod)
+			continue
+		}
+		covered[node] = true
+		kept = append(kept, pod)
+	}
+
+	if nodes != nil {
+		for _, node := range nodes {
+			if !covered[str(node, "metadata", "name")] {
+				kept = append(kept, c.newPod(owner, hash, node))
+			}
+		}
+		return kept
+	}
+	for len(kept) < count {
+		kept = append(kept, c.newPod(owner, hash, nil))
+	}
+	return kept
+}
+
+func (c *Cluster) newPod(owner Object, hash string, node Object) Object {
+	namespace, name := str(owner, "metadata", "namespace"), str(owner, "metadata", "name")
+	podKind, _ := c.resource("pods")
+	if str(owner, "kind") == "StatefulSet" {
+		for ordinal := 0; ; ordinal++ {
+			if candidate := fmt.Sprintf("%s-%d", name, ordinal); c.get(podKind, namespace, candidate) == nil {
+				name = candidate
+				break
+			}
+		}
+	} else {
+		h := fnv.New32a()
+		fmt.Fprintf(h, "%s/%d", name, c.Counter)
+		if str(owner, "kind") != "ReplicaSet" {
+			name += "-" + hash
+		}
+		name = fmt.Sprintf("%s-%05x", name, h.Sum32()&0xfffff)
+	}
+
+	template := mapAt(owner, "spec", "template")
+	spec := deepCopy(mapAt(template, "spec"))
+	if spec == nil {
+		spec = Object{}
+	}
+	if node != nil {
+		spec["nodeName"] = str(node, "metadata", "name")
+	}
+	labels := deepCopy(mapAt(template, "metadata", "labels"))
+	if labels == nil {
+		labels = Object{}
+	}
+	labels["pod-template-hash"] = hash
+	if str(owner, "kind") == "Job" {
+		// The job controller labels its pods with the job, logs and hooks find them by it
+		labels["job-name"] = str(owner, "metadata", "name")
+		labels["batch.kubernetes.io/job-name"] = str(owner, "metadata", "name")
+	}
+	pod := Object{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": Object{
+			"name":            name,
+			"namespace":       namespace,
+			"labels":          labels,
+			"ownerReferences": []interface{}{controllerRef(owner)},
+		},
+		"spec": spec,
+	}
+	c.put(pod)
+	return pod
+}
+
+// controllerRef is the owner reference of an object its controller, owner, created
+func controllerRef(owner Object) Object {
+	return Object{
+		"apiVersion": str(owner, "apiVersion"),
+		"kind":       str(owner, "kind"),
+		"name":       str(owner, "metadata", "name"),
+		"uid":        str(owner, "metadata", "uid"),
+		"controller": true,
+	}
+}
+
+// templateHash identifies the pod template of a workload, like the pod-template-hash label;
+// a ReplicaSet's template carries the hash of its Deployment's
+func templateHash(owner Object) string {
+	if hash := str(owner, "spec", "template", "metadata", "labels", "pod-template-hash"); hash != "" {
+		return hash
+	}
+	data, _ := json.Marshal(at(owner, "spec", "template"))
+	h := fnv.New32a()
+	h.Write(data)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// schedule binds a pod to its node, or the next schedulable node matching its selector, and
+// starts it. A job's pod has run to completion.
+func (c *Cluster) schedule(pod Object) {
+	spec := mapAt(pod, "spec")
+	if spec == nil {
+		spec = Object{}
+		pod["spec"] = spec
+	}
+	nodeKind, _ := c.resource("nodes")
+	node := c.get(nodeKind, "", str(spec, "nodeName"))
+	if node == nil {
+		nodes := c.schedulableNodes(mapAt(spec, "nodeSelector"))
+		if len(nodes) == 0 {
+			pod["status"] = Object{
+				"phase":      "Pending",
+				"conditions": []interface{}{Object{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/0 nodes are available"}},
+			}
+			return
+		}
+		node = nodes[c.Counter%len(nodes)]
+		spec["nodeName"] = str(node, "metadata", "name")
+	}
+
+	completed := false
+	if owner := c.owner(pod); owner != nil && str(owner, "kind") == "Job" {
+		completed = true
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	var containers []interface{}
+	containerList, _ := spec["containers"].([]interface{})
+	for _, item := range containerList {
+		container, _ := item.(map[string]interface{})
+		state := Object{"running": Object{"startedAt": now}}
+		if completed {
+			state = Object{"terminated": Object{"exitCode": 0, "reason": "Completed", "finishedAt": now}}
+		}
+		containers = append(containers, Object{
+			"name":         str(container, "name"),
+			"image":        str(container, "image"),
+			"imageID":      str(container, "image") + "@sha256:" + strings.Repeat("0", 64),
+			"ready":        !completed,
+			"started":      !completed,
+			"restartCount": 0,
+			"state":        state,
+		})
+	}
+	phase := "Running"
+	if completed {
+		phase = "Succeeded"
+	}
+	pod["status"] = Object{
+		"phase":             phase,
+		"hostIP":            str(node, "status", "addresses", "0", "address"),
+		"podIP":             fmt.Sprintf("10.244.%d.%d", c.Counter/250%250, c.Counter%250+1),
+		"startTime":         now,
+		"conditions":        []interface{}{condition("PodScheduled", true), condition("Ready", !completed), condition("ContainersReady", !completed)},
+		"containerStatuses": containers,
+	}
+}
+
+// drop deletes an object without anything following from it
+func (c *Cluster) drop(obj Object) {
+	delete(c.Objects, c.key(c.kindOf(obj), str(obj, "metadata", "namespace"), str(obj, "metadata", "name")))
+}
+
+func (c *Cluster) owner(pod Object) Object {
+	refs, _ := at(pod, "metadata", "ownerReferences").([]interface{})
+	for _, item := range refs {
+		ref, _ := item.(map[string]interface{})
+		for _, obj := range c.Objects {
+			if str(obj, "metadata", "uid") == str(ref, "uid") {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// owned returns the objects of a kind that owner owns
+func (c *Cluster) owned(owner Object, kindName string) []Object {
+	uid := str(owner, "metadata", "uid")
+	var objects []Object
+	for _, obj := range c.list(kindName, str(owner, "metadata", "namespace"), nil) {
+		refs, _ := at(obj, "metadata", "ownerReferences").([]interface{})
+		for _, item := range refs {
+			if ref, _ := item.(map[string]interface{}); str(ref, "uid") == uid {
+				objects = append(objects, obj)
+			}
+		}
+	}
+	return objects
+}
+
+// disruptionStatus counts the healthy pods a PodDisruptionBudget covers and how many may go
+func (c *Cluster) disruptionStatus(pdb Object) Object {
+	selector := selectorOf(mapAt(pdb, "spec", "selector", "matchLabels"))
+	healthy := countReady(c.list("Pod", str(pdb, "metadata", "namespace"), selector))
+	desired := healthy
+	if minAvailable := intAt(pdb, -1, "spec", "minAvailable"); minAvailable >= 0 {
+		desired = minAvailable
+	} else if maxUnavailable := intAt(pdb, -1, "spec", "maxUnavailable"); maxUnavailable >= 0 {
+		desired = healthy - maxUnavailable
+	}
+	return Object{
+		"currentHealthy":     healthy,
+		"desiredHealthy":     desired,
+		"expectedPods":       healthy,
+		"disruptionsAllowed": max(healthy-desired, 0),
+		"observedGeneration": intAt(pdb, 1, "metadata", "generation"),
+	}
+}
+
+// nodes returns the nodes of the cluster
+func (c *Cluster) nodes() []Object {
+	return c.list("Node", "", nil)
+}
+
+func (c *Cluster) schedulableNodes(nodeSelector map[string]interface{}) []Object {
+	selector := selectorOf(nodeSelector)
+	var nodes []Object
+	for _, node := range c.list("Node", "", selector) {
+		if unschedulable, _ := at(node, "spec", "unschedulable").(bool); !unschedulable {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// scalePool resizes a node pool to count nodes, evicting the pods of removed nodes
+func (c *Cluster) scalePool(pool string, count int) {
+	nodes := c.poolNodes(pool)
+	for i := len(nodes); i < count; i++ {
+		c.addNode(pool, i)
+	}
+	for _, node := range nodes[min(count, len(nodes)):] {
+		c.remove(node)
+		c.evict(str(node, "metadata", "name"))
+	}
+}
+
+func (c *Cluster) poolNodes(pool string) []Object {
+	return c.list("Node", "", []requirement{{key: nodePoolLabels[0], op: "=", values: []string{pool}}})
+}
+
+func (c *Cluster) addNode(pool string, index int) {
+	name := fmt.Sprintf("sim-%s-%d", pool, index)
+	labels := Object{
+		"kubernetes.io/hostname":           name,
+		"kubernetes.io/os":                 "linux",
+		"kubernetes.io/arch":               "amd64",
+		"node.kubernetes.io/instance-type": "sim.xlarge",
+	}
+	for _, label := range nodePoolLabels {
+		labels[label] = pool
+	}
+	c.put(Object{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   Object{"name": name, "labels": labels},
+		"spec":       Object{},
+		"status": Object{
+			"capacity":    Object{"cpu": "4", "memory": "16Gi", "pods": "110"},
+			"allocatable": Object{"cpu": "3920m", "memory": "15Gi", "pods": "110"},
+			"conditions":  []interface{}{condition("Ready", true)},
+			"addresses":   []interface{}{Object{"type": "InternalIP", "address": fmt.Sprintf("10.0.%d.%d", len(c.nodes())/250, len(c.nodes())%250+10)}},
+			"nodeInfo": Object{
+				"kubeletVersion":          c.Version,
+				"containerRuntimeVersion": "containerd://1.7.11",
+				"osImage":                 "Simulated Linux",
+				"architecture":            "amd64",
+			},
+		},
+	})
+}
+
+// upgradeNodes moves the nodes of pool, every pool when empty, to version
+func (c *Cluster) upgradeNodes(pool, version string) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	for _, node := range c.nodes() {
+		if pool == "" || str(node, "metadata", "labels", nodePoolLabels[0]) == pool {
+			mapAt(node, "status", "nodeInfo")["kubeletVersion"] = version
+		}
+	}
+}
+
+// evict moves the pods off a node the way a drain does: workloads recreate them elsewhere
+func (c *Cluster) evict(node string) int {
+	evicted := 0
+	for _, pod := range c.list("Pod", "", nil) {
+		if str(pod, "spec", "nodeName") != node {
+			continue
+		}
+		// A drain leaves the pods of daemon sets on the node
+		nodeKind, _ := c.resource("nodes")
+		if owner := c.owner(pod); owner != nil && str(owner, "kind") == "DaemonSet" && c.get(nodeKind, "", node) != nil {
+			continue
+		}
+		c.remove(pod)
+		evicted++
+	}
+	return evicted
+}
+
+func condition(conditionType string, status bool) Object {
+	value := "False"
+	if status {
+		value = "True"
+	}
+	return Object{"type": conditionType, "status": value, "lastTransitionTime": time.Now().UTC().Format(time.RFC3339)}
+}
+
+func countReady(pods []Object) int {
+	ready := 0
+	for _, pod := range pods {
+		if str(pod, "status", "phase") == "Running" || str(pod, "status", "phase") == "Succeeded" {
+			ready++
+		}
+	}
+	return ready
+}
+
+// at returns the value at path in obj; numeric path elements index lists
+func at(obj interface{}, path ...string) interface{} {
+	current := obj
+	for _, element := range path {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			current = value[element]
+		case []interface{}:
+			index, err := strconv.Atoi(element)
+			if err != nil || index >= len(value) {
+				return nil
+			}
+			current = value[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+func str(obj interface{}, path ...string) string {
+	value, _ := at(obj, path...).(string)
+	return value
+}
+
+func mapAt(obj interface{}, path ...string) map[string]interface{} {
+	value, _ := at(obj, path...).(map[string]interface{})
+	return value
+}
+
+// intAt reads a number however it was decoded, fallback when it is missing
+func intAt(obj interface{}, fallback int, path ...string) int {
+	switch value := at(obj, path...).(type) {
+	case int:
+		return value
+	case float64:
+		return int(value)
+	case string:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func labelsOf(obj Object) map[string]string {
+	labels := make(map[string]string)
+	for key, value := range mapAt(obj, "metadata", "labels") {
+		labels[key] = fmt.Sprint(value)
+	}
+	return labels
+}
+
+func deepCopy(obj map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		copied[key] = deepCopyValue(value)
+	}
+	return copied
+}
+
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopy(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,588 @@
+package simulate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmVersion is the version the simulated helm reports
+const helmVersion = "v3.14.2"
+
+// Release is a simulated Helm release and its history
+type Release struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Chart      string            `json:"chart"`
+	Version    string            `json:"version"`
+	AppVersion string            `json:"appVersion"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	History    []Revision        `json:"history"`
+}
+
+// Revision is one revision of a release
+type Revision struct {
+	Revision    int       `json:"revision"`
+	Status      string    `json:"status"`
+	Chart       string    `json:"chart"`
+	AppVersion  string    `json:"appVersion"`
+	Updated     time.Time `json:"updated"`
+	Description string    `json:"description"`
+	Manifest    string    `json:"manifest"`
+	Values      string    `json:"values,omitempty"`
+}
+
+func (r *Release) current() *Revision {
+	return &r.History[len(r.History)-1]
+}
+
+// revision returns a revision of the release, nil when it has none such; Helm prunes the
+// oldest revisions of a long history
+func (r *Release) revision(number int) *Revision {
+	for i := range r.History {
+		if r.History[i].Revision == number {
+			return &r.History[i]
+		}
+	}
+	return nil
+}
+
+// helmValueFlags are the helm flags taking a value
+var helmValueFlags = []string{
+	"n", "namespace", "o", "output", "f", "values", "set", "set-string", "set-file", "labels", "l", "selector",
+	"timeout", "version", "post-renderer", "post-renderer-args", "kube-context", "kubeconfig", "description",
+	"repo", "username", "password", "destination", "d", "max", "filter", "revision",
+}
+
+// helm answers a helm command against the simulated releases and cluster
+func (e *Environment) helm(c *call) error {
+	f := parseFlags(c.Args, helmValueFlags...)
+	switch f.arg(0) {
+	case "version":
+		c.printf(`version.BuildInfo{Version:"%s", GitCommit:"simulated", GitTreeState:"clean", GoVersion:"go1.21.7"}`+"\n", helmVersion)
+	case "template":
+		manifest, _, err := e.renderChart(c, f, f.arg(1), f.arg(2), namespaceOf(f))
+		if err != nil {
+			return err
+		}
+		c.printf("%s", manifest)
+	case "upgrade", "install":
+		return e.helmInstall(c, f)
+	case "uninstall", "delete":
+		return e.helmUninstall(c, f)
+	case "rollback":
+		return e.helmRollback(c, f)
+	case "list", "ls":
+		return e.helmList(c, f)
+	case "status":
+		release, err := e.release(c, f, f.arg(1))
+		if err != nil {
+			return err
+		}
+		if f.get("o", "output") == "json" {
+			return printJSON(c, releaseStatus(release))
+		}
+		printRelease(c, release)
+	case "history", "hist":
+		return e.helmHistory(c, f)
+	case "get":
+		release, err := e.release(c, f, f.arg(2))
+		if err != nil {
+			return err
+		}
+		revision := release.current()
+		if number := f.get("revision"); number != "" {
+			var target int
+			fmt.Sscanf(number, "%d", &target)
+			if revision = release.revision(target); revision == nil {
+				return c.fail("Error: release: not found")
+			}
+		}
+		switch f.arg(1) {
+		case "manifest":
+			c.printf("%s", revision.Manifest)
+		case "values":
+			if f.get("o", "output") == "yaml" {
+				c.printf("%s", revision.Values)
+				break
+			}
+			c.printf("USER-SUPPLIED VALUES:\n%s", revision.Values)
+		case "notes":
+			c.printf("NOTES:\n%s has been deployed (simulated).\n", release.Name)
+		default:
+			printRelease(c, release)
+			c.printf("MANIFEST:\n%s", release.current().Manifest)
+		}
+	case "test":
+		release, err := e.release(c, f, f.arg(1))
+		if err != nil {
+			return err
+		}
+		printRelease(c, release)
+		now := time.Now().Format(time.ANSIC)
+		c.printf("TEST SUITE:     %s-test-connection\nLast Started:   %s\nLast Completed: %s\nPhase:          Succeeded\n", release.Name, now, now)
+		if f.has("logs") {
+			c.printf("\nPOD LOGS: %s-test-connection\nConnecting to %s:80\nConnection succeeded (simulated)\n", release.Name, release.Name)
+		}
+	case "lint":
+		c.printf("==> Linting %s\n[INFO] Chart.yaml: icon is recommended\n\n1 chart(s) linted, 0 chart(s) failed\n", f.arg(1))
+	case "package":
+		name, version, _ := chartMetadata(c, f.arg(1), f.get("version"))
+		destination := valueOr(f.get("d", "destination"), c.Dir)
+		c.printf("Successfully packaged chart and saved it to: %s\n", filepath.Join(destination, fmt.Sprintf("%s-%s.tgz", name, version)))
+	case "pull":
+		name, version, appVersion := chartMetadata(c, f.arg(1), f.get("version"))
+		destination := valueOr(f.get("d", "destination"), ".")
+		if !filepath.IsAbs(destination) && c.Dir != "" {
+			destination = filepath.Join(c.Dir, destination)
+		}
+		if err := writeChartArchive(filepath.Join(destination, fmt.Sprintf("%s-%s.tgz", name, version)), name, version, appVersion); err != nil {
+			return c.fail("Error: failed to save chart: %v", err)
+		}
+		c.printf("Pulled: %s:%s\nDigest: sha256:%064x\n", name, version, len(name))
+	case "push":
+		name, version, _ := chartMetadata(c, f.arg(1), "")
+		c.printf("Pushed: %s/%s:%s\nDigest: sha256:%064x\n", strings.TrimPrefix(f.arg(2), "oci://"), name, version, len(name))
+	case "repo":
+		switch f.arg(1) {
+		case "add":
+			c.printf("\"%s\" has been added to your repositories\n", f.arg(2))
+		case "update":
+			c.printf("Hang tight while we grab the latest from your chart repositories...\nUpdate Complete. ⎈Happy Helming!⎈\n")
+		}
+	case "dependency", "dep":
+		c.printf("Saving 0 charts\nDeleting outdated charts\n")
+	case "registry":
+		c.printf("Login Succeeded\n")
+	case "show", "inspect":
+		name, version, appVersion := chartMetadata(c, f.arg(2), f.get("version"))
+		c.printf("apiVersion: v2\nname: %s\nversion: %s\nappVersion: %s\n", name, version, appVersion)
+	}
+	return nil
+}
+
+func (e *Environment) release(c *call, f flags, name string) (*Release, error) {
+	release := e.Releases[namespaceOf(f)+"/"+name]
+	if release == nil {
+		return nil, c.fail("Error: release: not found")
+	}
+	return release, nil
+}
+
+// helmInstall installs or upgrades a release, applying its manifest to the cluster
+func (e *Environment) helmInstall(c *call, f flags) error {
+	name, chart := f.arg(1), f.arg(2)
+	if f.arg(0) == "install" && chart == "" {
+		name, chart = e.nextID(filepath.Base(f.arg(1))), f.arg(1)
+	}
+	namespace := namespaceOf(f)
+	key := namespace + "/" + name
+	release := e.Releases[key]
+	if release == nil && f.arg(0) == "upgrade" && !f.has("install") {
+		return c.fail(`Error: UPGRADE FAILED: "%s" has no deployed releases`, name)
+	}
+	if release != nil && f.arg(0) == "install" {
+		return c.fail("Error: INSTALLATION FAILED: cannot re-use a name that is still in use")
+	}
+
+	manifest, values, err := e.renderChart(c, f, name, chart, namespace)
+	if err != nil {
+		return err
+	}
+	chartName, version, appVersion := chartMetadata(c, chart, f.get("version"))
+	if f.has("dry-run") {
+		c.printf("NAME: %s\nNAMESPACE: %s\nSTATUS: pending-install\nREVISION: 1\nHOOKS:\nMANIFEST:\n%s", name, namespace, manifest)
+		return nil
+	}
+
+	description, number := "Install complete", 1
+	if release == nil {
+		release = &Release{Name: name, Namespace: namespace}
+		e.Releases[key] = release
+	} else {
+		description, number = "Upgrade complete", release.current().Revision+1
+		release.current().Status = "superseded"
+	}
+	release.Chart, release.Version, release.AppVersion = chartName, version, appVersion
+	if labels := f.get("labels"); labels != "" {
+		release.Labels = make(map[string]string)
+		for _, pair := range strings.Split(labels, ",") {
+			key, value, _ := strings.Cut(pair, "=")
+			release.Labels[key] = value
+		}
+	}
+	release.History = append(release.History, Revision{
+		Revision:    number,
+		Status:      "deployed",
+		Chart:       chartName + "-" + version,
+		AppVersion:  appVersion,
+		Updated:     time.Now(),
+		Description: description,
+		Manifest:    manifest,
+		Values:      values,
+	})
+
+	if f.has("create-namespace") {
+		e.Cluster.put(Object{"apiVersion": "v1", "kind": "Namespace", "metadata": Object{"name": namespace}})
+	}
+	err = e.applyRelease(release, manifest)
+	if err != nil {
+		release.current().Status = "failed"
+	}
+	e.storeRelease(release)
+	if err != nil {
+		return c.fail("Error: UPGRADE FAILED: %v", err)
+	}
+
+	if release.current().Revision == 1 {
+		c.printf("Release \"%s\" does not exist. Installing it now.\n", name)
+	} else {
+		c.printf("Release \"%s\" has been upgraded. Happy Helming!\n", name)
+	}
+	printRelease(c, release)
+	return nil
+}
+
+// applyRelease puts the objects of a release manifest in the cluster and removes those
+// the previous revision had that this one no longer has
+func (e *Environment) applyRelease(release *Release, manifest string) error {
+	objects, err := decodeManifest([]byte(manifest))
+	if err != nil {
+		return err
+	}
+	kept := make(map[string]bool)
+	for _, obj := range objects {
+		metadata := mapAt(obj, "metadata")
+		if metadata == nil {
+			continue
+		}
+		if str(metadata, "namespace") == "" {
+			metadata["namespace"] = release.Namespace
+		}
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations == nil {
+			annotations = Object{}
+			metadata["annotations"] = annotations
+		}
+		annotations["meta.helm.sh/release-name"] = release.Name
+		annotations["meta.helm.sh/release-namespace"] = release.Namespace
+		e.Cluster.put(obj)
+		kept[e.Cluster.key(e.Cluster.kindOf(obj), str(metadata, "namespace"), str(metadata, "name"))] = true
+	}
+	for key, obj := range e.Cluster.Objects {
+		if !kept[key] && e.ownedByRelease(obj, release) && str(obj, "kind") != "Pod" {
+			e.Cluster.remove(obj)
+		}
+	}
+	return nil
+}
+
+func (e *Environment) ownedByRelease(obj Object, release *Release) bool {
+	return str(obj, "metadata", "annotations", "meta.helm.sh/release-name") == release.Name &&
+		str(obj, "metadata", "annotations", "meta.helm.sh/release-namespace") == release.Namespace
+}
+
+func (e *Environment) helmUninstall(c *call, f flags) error {
+	for _, name := range f.positional[1:] {
+		key := namespaceOf(f) + "/" + name
+		release := e.Releases[key]
+		if release == nil {
+			if f.has("ignore-not-found") {
+				continue
+			}
+			return c.fail("Error: uninstall: Release not loaded: %s: release: not found", name)
+		}
+		for _, obj := range e.Cluster.Objects {
+			if e.ownedByRelease(obj, release) {
+				e.Cluster.remove(obj)
+			}
+		}
+		delete(e.Releases, key)
+		e.deleteStoredRelease(release)
+		c.printf("release \"%s\" uninstalled\n", name)
+	}
+	return nil
+}
+
+func (e *Environment) helmRollback(c *call, f flags) error {
+	release, err := e.release(c, f, f.arg(1))
+	if err != nil {
+		return err
+	}
+	target := release.current().Revision - 1
+	if revision := f.arg(2); revision != "" && revision != "0" {
+		fmt.Sscanf(revision, "%d", &target)
+	}
+	previous := release.revision(target)
+	if previous == nil {
+		return c.fail("Error: release has no %d version", target)
+	}
+	rollback := *previous
+	rollback.Revision = release.current().Revision + 1
+	release.current().Status = "superseded"
+	rollback.Status = "deployed"
+	rollback.Updated = time.Now()
+	rollback.Description = fmt.Sprintf("Rollback to %d", target)
+	release.History = append(release.History, rollback)
+	err = e.applyRelease(release, rollback.Manifest)
+	e.storeRelease(release)
+	if err != nil {
+		return c.fail("Error: %v", err)
+	}
+	c.printf("Rollback was a success! Happy Helming!\n")
+	return nil
+}
+
+func (e *Environment) helmList(c *call, f flags) error {
+	namespace := namespaceOf(f)
+	selector := parseSelector(f.get("l", "selector"))
+	filter, err := regexp.Compile(f.get("filter"))
+	if err != nil {
+		return c.fail("Error: invalid filter: %v", err)
+	}
+	var releases []*Release
+	for _, release := range e.Releases {
+		if namespace != "" && release.Namespace != namespace {
+			continue
+		}
+		if !matches(selector, release.Labels) || !filter.MatchString(release.Name) {
+			continue
+		}
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Namespace+"/"+releases[i].Name < releases[j].Namespace+"/"+releases[j].Name
+	})
+
+	if f.get("o", "output") == "json" {
+		items := make([]map[string]string, 0, len(releases))
+		for _, release := range releases {
+			current := release.current()
+			items = append(items, map[string]string{
+				"name":        release.Name,
+				"namespace":   release.Namespace,
+				"revision":    fmt.Sprint(current.Revision),
+				"updated":     current.Updated.Format("2006-01-02 15:04:05.000000000 -0700 MST"),
+				"status":      current.Status,
+				"chart":       current.Chart,
+				"app_version": current.AppVersion,
+			})
+		}
+		return printJSON(c, items)
+	}
+	if f.has("q", "short") {
+		for _, release := range releases {
+			c.printf("%s\n", release.Name)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNAMESPACE\tREVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION")
+	for _, release := range releases {
+		current := release.current()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", release.Name, release.Namespace, current.Revision,
+			current.Updated.Format("2006-01-02 15:04:05 -0700 MST"), current.Status, current.Chart, current.AppVersion)
+	}
+	return w.Flush()
+}
+
+func (e *Environment) helmHistory(c *call, f flags) error {
+	release, err := e.release(c, f, f.arg(1))
+	if err != nil {
+		return err
+	}
+	if f.get("o", "output") == "json" {
+		items := make([]map[string]interface{}, 0, len(release.History))
+		for _, revision := range release.History {
+			items = append(items, map[string]interface{}{
+				"revision":    revision.Revision,
+				"updated":     revision.Updated.Format(time.RFC3339),
+				"status":      revision.Status,
+				"chart":       revision.Chart,
+				"app_version": revision.AppVersion,
+				"description": revision.Description,
+			})
+		}
+		return printJSON(c, items)
+	}
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION\tDESCRIPTION")
+	for _, revision := range release.History {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", revision.Revision, revision.Updated.Format(time.ANSIC), revision.Status,
+			revision.Chart, revision.AppVersion, revision.Description)
+	}
+	return w.Flush()
+}
+
+func releaseStatus(release *Release) map[string]interface{} {
+	current := release.current()
+	return map[string]interface{}{
+		"name":      release.Name,
+		"namespace": release.Namespace,
+		"version":   current.Revision,
+		"info": map[string]interface{}{
+			"status":         current.Status,
+			"description":    current.Description,
+			"first_deployed": release.History[0].Updated.Format(time.RFC3339),
+			"last_deployed":  current.Updated.Format(time.RFC3339),
+		},
+		"chart": map[string]interface{}{
+			"metadata": map[string]string{"name": release.Chart, "version": release.Version, "appVersion": release.AppVersion},
+		},
+		"manifest": current.Manifest,
+	}
+}
+
+func printRelease(c *call, release *Release) {
+	current := release.current()
+	c.printf("NAME: %s\nLAST DEPLOYED: %s\nNAMESPACE: %s\nSTATUS: %s\nREVISION: %d\n",
+		release.Name, current.Updated.Format(time.ANSIC), release.Namespace, current.Status, current.Revision)
+}
+
+func printJSON(c *call, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.printf("%s\n", data)
+	return nil
+}
+
+// chartMetadata reads the name and versions of a chart from its Chart.yaml when the chart
+// is a local directory, and derives them from the reference otherwise
+func chartMetadata(c *call, chart, version string) (string, string, string) {
+	path := chart
+	if !filepath.IsAbs(path) && c.Dir != "" {
+		path = filepath.Join(c.Dir, path)
+	}
+	var metadata struct {
+		Name       string `yaml:"name"`
+		Version    string `yaml:"version"`
+		AppVersion string `yaml:"appVersion"`
+	}
+	if data, err := os.ReadFile(filepath.Join(path, "Chart.yaml")); err == nil {
+		yaml.Unmarshal(data, &metadata)
+	}
+	if metadata.Name == "" {
+		metadata.Name = strings.TrimSuffix(filepath.Base(strings.TrimPrefix(chart, "oci://")), ".tgz")
+	}
+	if version != "" {
+		metadata.Version = version
+	}
+	if metadata.Version == "" {
+		metadata.Version = "1.0.0"
+	}
+	if metadata.AppVersion == "" {
+		metadata.AppVersion = metadata.Version
+	}
+	return metadata.Name, metadata.Version, metadata.AppVersion
+}
+
+// renderChart renders a chart the way its templates typically would: a Deployment and a
+// Service named after the release, sized and imaged by the common values keys
+// (replicaCount, image.repository, image.tag, resources, service.port). Values come from
+// --values files or stdin and --set flags. It returns the manifest and the user values.
+func (e *Environment) renderChart(c *call, f flags, release, chart, namespace string) (string, string, error) {
+	values := Object{}
+	for _, source := range append(f.values["f"], f.values["values"]...) {
+		for _, data := range e.readSource(c, source) {
+			var fileValues Object
+			if err := yaml.Unmarshal(data, &fileValues); err != nil {
+				return "", "", c.fail("Error: failed to parse %s: %v", source, err)
+			}
+			mergePatch(values, fileValues)
+		}
+	}
+	for _, assignment := range append(f.values["set"], f.values["set-string"]...) {
+		for _, pair := range strings.Split(assignment, ",") {
+			key, value, _ := strings.Cut(pair, "=")
+			setValue(values, strings.Split(key, "."), value)
+		}
+	}
+
+	name, version, appVersion := chartMetadata(c, chart, f.get("version"))
+	fullname := release
+	if !strings.Contains(release, name) {
+		fullname = release + "-" + name
+	}
+	labels := map[string]interface{}{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/instance":   release,
+		"app.kubernetes.io/version":    appVersion,
+		"app.kubernetes.io/managed-by": "Helm",
+		"helm.sh/chart":                name + "-" + version,
+	}
+	selector := map[string]interface{}{"app.kubernetes.io/name": name, "app.kubernetes.io/instance": release}
+
+	image := valueOr(str(values, "image", "repository"), "registry.local/"+name) + ":" + valueOr(str(values, "image", "tag"), appVersion)
+	resources := mapAt(values, "resources")
+	if resources == nil {
+		resources = Object{"requests": Object{"cpu": "100m", "memory": "128Mi"}}
+	}
+	port := intAt(values, 80, "service", "port")
+
+	deployment := Object{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   Object{"name": fullname, "namespace": namespace, "labels": labels},
+		"spec": Object{
+			"replicas": intAt(values, 1, "replicaCount"),
+			"selector": Object{"matchLabels": selector},
+			"template": Object{
+				"metadata": Object{"labels": labels},
+				"spec": Object{"containers": []interface{}{Object{
+					"name":      name,
+					"image":     image,
+					"ports":     []interface{}{Object{"name": "http", "containerPort": 8080}},
+					"resources": resources,
+				}}},
+			},
+		},
+	}
+	service := Object{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   Object{"name": fullname, "namespace": namespace, "labels": labels},
+		"spec": Object{
+			"type":     valueOr(str(values, "service", "type"), "ClusterIP"),
+			"selector": selector,
+			"ports":    []interface{}{Object{"name": "http", "port": port, "targetPort": "http"}},
+		},
+	}
+
+	var manifest bytes.Buffer
+	for _, doc := range []struct {
+		template string
+		object   Object
+	}{{"service.yaml", service}, {"deployment.yaml", deployment}} {
+		data, err := yaml.Marshal(doc.object)
+		if err != nil {
+			return "", "", err
+		}
+		fmt.Fprintf(&manifest, "---\n# Source: %s/templates/%s\n%s", name, doc.template, data)
+	}
+
+	userValues, _ := yaml.Marshal(values)
+	return manifest.String(), string(userValues), nil
+}
+
+func setValue(values Object, path []string, value string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = Object{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = value
+}
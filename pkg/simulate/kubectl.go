@@ -0,0 +1,847 @@
+package simulate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// kubectlValueFlags are the kubectl flags taking a value
+var kubectlValueFlags = []string{
+	"n", "namespace", "o", "output", "l", "selector", "f", "filename", "kubeconfig", "context",
+	"request-timeout", "field-manager", "replicas", "for", "timeout", "duration", "tail", "p", "patch",
+	"type", "c", "container", "since", "grace-period", "field-selector", "from-literal", "address",
+}
+
+// kubectl answers a kubectl command against the simulated cluster
+func (e *Environment) kubectl(c *call) error {
+	f := parseFlags(c.Args, kubectlValueFlags...)
+	cluster := e.Cluster
+	switch f.arg(0) {
+	case "version":
+		if f.get("o", "output") == "json" {
+			c.printf(`{"clientVersion":{"major":"1","minor":"29","gitVersion":"%[1]s"},"serverVersion":{"major":"1","minor":"29","gitVersion":"%[1]s","platform":"linux/amd64"}}`+"\n", cluster.Version)
+			return nil
+		}
+		c.printf("Client Version: %s\nServer Version: %s\n", cluster.Version, cluster.Version)
+	case "cluster-info":
+		c.printf("Kubernetes control plane is running at %s\nCoreDNS is running at %s/api/v1/namespaces/kube-system/services/kube-dns:dns/proxy\n", e.endpoint.URL, e.endpoint.URL)
+	case "config":
+		return e.kubeconfig(c, f)
+	case "get":
+		return e.kubectlGet(c, f)
+	case "apply", "create":
+		if f.has("f", "filename") {
+			return e.kubectlApply(c, f)
+		}
+		return e.kubectlCreate(c, f)
+	case "replace":
+		return e.kubectlApply(c, f)
+	case "delete":
+		return e.kubectlDelete(c, f)
+	case "label", "annotate":
+		return e.kubectlLabel(c, f)
+	case "scale":
+		objects, err := e.targets(c, f, 1)
+		if err != nil {
+			return err
+		}
+		replicas, err := strconv.Atoi(f.get("replicas"))
+		if err != nil {
+			return c.fail("error: --replicas must be a number")
+		}
+		for _, obj := range objects {
+			if spec := mapAt(obj, "spec"); spec != nil {
+				spec["replicas"] = replicas
+			}
+			cluster.put(obj)
+			c.printf("%s/%s scaled\n", cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"))
+		}
+	case "patch":
+		return e.kubectlPatch(c, f)
+	case "rollout":
+		return e.kubectlRollout(c, f)
+	case "wait":
+		objects, err := e.targets(c, f, 1)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			c.printf("%s/%s condition met\n", cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"))
+		}
+	case "cordon", "uncordon", "drain":
+		nodeKind, _ := cluster.resource("nodes")
+		for _, name := range f.positional[1:] {
+			node := cluster.get(nodeKind, "", name)
+			if node == nil {
+				return c.fail(`Error from server (NotFound): nodes "%s" not found`, name)
+			}
+			mapAt(node, "spec")["unschedulable"] = f.arg(0) != "uncordon"
+			if f.arg(0) == "drain" {
+				c.printf("node/%s cordoned\n", name)
+				evicted := cluster.evict(name)
+				c.printf("evicted %d pods\nnode/%s drained\n", evicted, name)
+				continue
+			}
+			c.printf("node/%s %sed\n", name, f.arg(0))
+		}
+	case "logs":
+		name := strings.TrimPrefix(strings.TrimPrefix(f.arg(1), "job/"), "pod/")
+		now := time.Now().UTC()
+		c.printf("%s INFO starting %s (simulated)\n", now.Add(-2*time.Second).Format(time.RFC3339), name)
+		c.printf("%s INFO %s ready\n", now.Add(-time.Second).Format(time.RFC3339), name)
+		if strings.HasPrefix(f.arg(1), "job/") {
+			c.printf("%s INFO %s completed successfully\n", now.Format(time.RFC3339), name)
+		}
+	case "port-forward":
+		// Nothing listens locally, the forward ends right after it is set up
+		resource, name, _ := strings.Cut(f.arg(1), "/")
+		k, ok := cluster.resource(resource)
+		if !ok || cluster.get(k, namespaceOf(f), name) == nil {
+			return c.fail(`Error from server (NotFound): %s "%s" not found`, valueOr(k.Plural, resource), name)
+		}
+		local, remote, _ := strings.Cut(f.arg(2), ":")
+		c.printf("Forwarding from %s:%s -> %s\n", valueOr(f.get("address"), "127.0.0.1"), local, remote)
+	case "auth":
+		if f.arg(1) == "whoami" {
+			c.printf("ATTRIBUTE   VALUE\nUsername    simulated-admin\nGroups      [system:masters system:authenticated]\n")
+			return nil
+		}
+		c.printf("yes\n")
+	case "api-resources":
+		w := tabwriter.NewWriter(c.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tAPIVERSION\tNAMESPACED\tKIND")
+		for _, k := range cluster.kinds() {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", k.Plural, k.APIVersion, k.Namespaced, k.Kind)
+		}
+		w.Flush()
+	case "api-versions":
+		seen := make(map[string]bool)
+		for _, k := range cluster.kinds() {
+			if !seen[k.APIVersion] {
+				seen[k.APIVersion] = true
+				c.printf("%s\n", k.APIVersion)
+			}
+		}
+	}
+	// diff finds no differences; exec, top, port-forward and the rest only need to succeed
+	return nil
+}
+
+// namespaceOf returns the namespace a command addresses, empty for every namespace
+func namespaceOf(f flags) string {
+	if f.has("A", "all-namespaces") {
+		return ""
+	}
+	if namespace := f.get("n", "namespace"); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
+// targets resolves the objects a command names, either "<resource> <name>..." or
+// "<resource>/<name>", starting at positional argument from
+func (e *Environment) targets(c *call, f flags, from int) ([]Object, error) {
+	cluster := e.Cluster
+	namespace := namespaceOf(f)
+	var objects []Object
+	args := f.positional[min(from, len(f.positional)):]
+	for i := 0; i < len(args); i++ {
+		resource, name, qualified := strings.Cut(args[i], "/")
+		k, ok := cluster.resource(resource)
+		if !ok {
+			return nil, c.fail(`error: the server doesn't have a resource type "%s"`, resource)
+		}
+		var names []string
+		if qualified {
+			names = []string{name}
+		} else {
+			names = args[i+1:]
+			i = len(args)
+		}
+		if len(names) == 0 {
+			objects = append(objects, cluster.list(k.Kind, namespace, parseSelector(f.get("l", "selector")))...)
+			continue
+		}
+		for _, name := range names {
+			obj := cluster.get(k, namespace, name)
+			if obj == nil {
+				if f.has("ignore-not-found") {
+					continue
+				}
+				return nil, c.fail(`Error from server (NotFound): %s "%s" not found`, k.qualified(), name)
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+func (e *Environment) kubectlGet(c *call, f flags) error {
+	cluster := e.Cluster
+	resources := strings.Split(f.arg(1), ",")
+	namespace := namespaceOf(f)
+	selector := parseSelector(f.get("l", "selector"))
+
+	var objects []Object
+	single := false
+	if strings.Contains(f.arg(1), "/") || len(f.positional) > 2 {
+		found, err := e.targets(c, f, 1)
+		if err != nil {
+			return err
+		}
+		objects = found
+		single = len(f.positional) == 3 || (len(f.positional) == 2 && len(found) == 1)
+	} else {
+		for _, resource := range resources {
+			k, ok := cluster.resource(resource)
+			if !ok {
+				return c.fail(`error: the server doesn't have a resource type "%s"`, resource)
+			}
+			if k.Kind == "Node" {
+				e.ensurePools(selector)
+			}
+			objects = append(objects, cluster.list(k.Kind, namespace, selector)...)
+		}
+	}
+	if single && len(objects) == 0 {
+		// --ignore-not-found prints nothing for a missing object
+		return nil
+	}
+
+	var document interface{} = Object{"apiVersion": "v1", "kind": "List", "metadata": Object{"resourceVersion": ""}, "items": toList(objects)}
+	if single {
+		document = objects[0]
+	}
+	return e.print(c, f.get("o", "output"), document, objects)
+}
+
+// ensurePools creates a node pool the first time nodes are selected by its pool label, so
+// a configured node pool exists in the simulated cluster
+func (e *Environment) ensurePools(selector []requirement) {
+	for _, req := range selector {
+		for _, label := range nodePoolLabels {
+			if req.key == label && req.op == "=" && len(req.values) == 1 && len(e.Cluster.poolNodes(req.values[0])) == 0 {
+				e.Cluster.scalePool(req.values[0], defaultNodes)
+			}
+		}
+	}
+}
+
+// print writes objects in the output format kubectl was asked for
+func (e *Environment) print(c *call, output string, document interface{}, objects []Object) error {
+	switch {
+	case output == "json":
+		data, err := json.MarshalIndent(document, "", "    ")
+		if err != nil {
+			return err
+		}
+		c.printf("%s\n", data)
+	case output == "yaml":
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return err
+		}
+		c.printf("%s", data)
+	case output == "name":
+		for _, obj := range objects {
+			c.printf("%s/%s\n", e.Cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"))
+		}
+	case strings.HasPrefix(output, "jsonpath="):
+		c.printf("%s", jsonPath(document, strings.TrimPrefix(output, "jsonpath=")))
+	default:
+		if len(objects) == 0 {
+			fmt.Fprintln(c.Stderr, "No resources found")
+			return nil
+		}
+		w := tabwriter.NewWriter(c.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tSTATUS\tAGE")
+		for _, obj := range objects {
+			status := str(obj, "status", "phase")
+			if status == "" {
+				status = "Ready"
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\n", valueOr(str(obj, "metadata", "namespace"), "-"), e.Cluster.kindOf(obj).qualified(),
+				str(obj, "metadata", "name"), status, age(str(obj, "metadata", "creationTimestamp")))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// jsonPath evaluates the simple templates the installer uses, e.g. {.status.readyReplicas}
+// or {.items[*].metadata.name}
+func jsonPath(document interface{}, template string) string {
+	expression := strings.TrimSuffix(strings.TrimPrefix(template, "{"), "}")
+	values := []interface{}{document}
+	for _, element := range strings.Split(strings.TrimPrefix(expression, "."), ".") {
+		all := strings.HasSuffix(element, "[*]")
+		element = strings.TrimSuffix(element, "[*]")
+		var next []interface{}
+		for _, value := range values {
+			child := at(value, element)
+			if items, ok := child.([]interface{}); ok && all {
+				next = append(next, items...)
+			} else if child != nil {
+				next = append(next, child)
+			}
+		}
+		values = next
+	}
+	var parts []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			parts = append(parts, v)
+		case map[string]interface{}, []interface{}:
+			data, _ := json.Marshal(v)
+			parts = append(parts, string(data))
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// manifests reads the objects of -f: stdin with -, a file, or the YAML files of a directory
+func (e *Environment) manifests(c *call, f flags) ([]Object, error) {
+	var data [][]byte
+	for _, source := range f.values["f"] {
+		data = append(data, e.readSource(c, source)...)
+	}
+	for _, source := range f.values["filename"] {
+		data = append(data, e.readSource(c, source)...)
+	}
+
+	var objects []Object
+	for _, content := range data {
+		docs, err := decodeManifest(content)
+		if err != nil {
+			return nil, c.fail("error: error parsing manifest: %v", err)
+		}
+		objects = append(objects, docs...)
+	}
+	return objects, nil
+}
+
+func (e *Environment) readSource(c *call, source string) [][]byte {
+	if source == "-" {
+		return [][]byte{c.Stdin}
+	}
+	if !filepath.IsAbs(source) && c.Dir != "" {
+		source = filepath.Join(c.Dir, source)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil
+	}
+	if !info.IsDir() {
+		data, _ := os.ReadFile(source)
+		return [][]byte{data}
+	}
+	var data [][]byte
+	entries, _ := os.ReadDir(source)
+	for _, entry := range entries {
+		if ext := filepath.Ext(entry.Name()); ext == ".yaml" || ext == ".yml" || ext == ".json" {
+			content, _ := os.ReadFile(filepath.Join(source, entry.Name()))
+			data = append(data, content)
+		}
+	}
+	return data
+}
+
+// decodeManifest splits a YAML or JSON stream into objects, unpacking lists
+func decodeManifest(data []byte) ([]Object, error) {
+	var objects []Object
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var obj Object
+		if err := decoder.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if obj == nil || obj["kind"] == nil {
+			continue
+		}
+		if items, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(str(obj, "kind"), "List") {
+			for _, item := range items {
+				if itemObj, ok := item.(map[string]interface{}); ok {
+					objects = append(objects, itemObj)
+				}
+			}
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// keepMetadata carries the labels and annotations of an object over to the manifest applied
+// on it, as the apply merge leaves metadata set by others, e.g. with kubectl label, alone
+func keepMetadata(existing, obj Object) {
+	for _, field := range []string{"labels", "annotations"} {
+		previous := mapAt(existing, "metadata", field)
+		if len(previous) == 0 {
+			continue
+		}
+		metadata := mapAt(obj, "metadata")
+		current, _ := metadata[field].(map[string]interface{})
+		if current == nil {
+			current = make(map[string]interface{})
+			metadata[field] = current
+		}
+		for key, value := range previous {
+			if _, ok := current[key]; !ok {
+				current[key] = value
+			}
+		}
+	}
+}
+
+func (e *Environment) kubectlApply(c *call, f flags) error {
+	objects, err := e.manifests(c, f)
+	if err != nil {
+		return err
+	}
+	cluster := e.Cluster
+	namespace := f.get("n", "namespace")
+	dryRun := f.get("dry-run")
+	var applied []Object
+	for _, obj := range objects {
+		if namespace != "" && str(obj, "metadata", "namespace") == "" {
+			mapAt(obj, "metadata")["namespace"] = namespace
+		}
+		if strings.HasSuffix(str(obj, "kind"), "Review") {
+			// Access and token reviews are answered rather than stored: the caller may do anything
+			obj["status"] = Object{"allowed": true, "authenticated": true}
+			applied = append(applied, obj)
+			continue
+		}
+		k := cluster.kindOf(obj)
+		name := str(obj, "metadata", "name")
+		existing := cluster.get(k, valueOr(str(obj, "metadata", "namespace"), "default"), name)
+		if f.arg(0) == "create" && existing != nil {
+			return c.fail(`Error from server (AlreadyExists): %s "%s" already exists`, k.qualified(), name)
+		}
+		if existing != nil {
+			keepMetadata(existing, obj)
+		}
+
+		result := "created"
+		switch {
+		case dryRun != "" && dryRun != "none":
+			if existing != nil {
+				result = "configured"
+			}
+			result += fmt.Sprintf(" (%s dry run)", dryRun)
+		case existing != nil && sameSpec(existing, obj):
+			result = "unchanged"
+		default:
+			result = cluster.put(obj)
+			if f.has("server-side") {
+				result = "serverside-applied"
+			}
+		}
+		applied = append(applied, obj)
+		if output := f.get("o", "output"); output == "" {
+			c.printf("%s/%s %s\n", k.qualified(), name, result)
+		}
+	}
+
+	if output := f.get("o", "output"); output != "" {
+		var document interface{} = Object{"apiVersion": "v1", "kind": "List", "items": toList(applied)}
+		if len(applied) == 1 {
+			document = applied[0]
+		}
+		return e.print(c, output, document, applied)
+	}
+	return nil
+}
+
+// kubectlCreate creates an object from the command line, e.g. create namespace or create token
+func (e *Environment) kubectlCreate(c *call, f flags) error {
+	cluster := e.Cluster
+	resource, name := f.arg(1), f.arg(2)
+	if resource == "token" {
+		c.printf("%s\n", e.token(namespaceOf(f), name, time.Now().Add(time.Hour)))
+		return nil
+	}
+	if resource == "secret" {
+		// create secret generic NAME
+		name = f.arg(3)
+	}
+	k, ok := cluster.resource(resource)
+	if !ok {
+		return c.fail(`error: unknown resource type "%s"`, resource)
+	}
+	if cluster.get(k, namespaceOf(f), name) != nil {
+		return c.fail(`Error from server (AlreadyExists): %s "%s" already exists`, k.qualified(), name)
+	}
+	obj := Object{"apiVersion": k.APIVersion, "kind": k.Kind, "metadata": Object{"name": name, "namespace": namespaceOf(f)}}
+	if literals := f.values["from-literal"]; len(literals) > 0 {
+		data := Object{}
+		for _, literal := range literals {
+			key, value, _ := strings.Cut(literal, "=")
+			if resource == "secret" {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+			data[key] = value
+		}
+		obj["data"] = data
+	}
+	cluster.put(obj)
+	c.printf("%s/%s created\n", k.qualified(), name)
+	return nil
+}
+
+func (e *Environment) kubectlDelete(c *call, f flags) error {
+	cluster := e.Cluster
+	var objects []Object
+	if f.has("f", "filename") {
+		manifests, err := e.manifests(c, f)
+		if err != nil {
+			return err
+		}
+		for _, manifest := range manifests {
+			k := cluster.kindOf(manifest)
+			if obj := cluster.get(k, valueOr(str(manifest, "metadata", "namespace"), namespaceOf(f)), str(manifest, "metadata", "name")); obj != nil {
+				objects = append(objects, obj)
+			} else if !f.has("ignore-not-found") {
+				return c.fail(`Error from server (NotFound): %s "%s" not found`, k.qualified(), str(manifest, "metadata", "name"))
+			}
+		}
+	} else {
+		found, err := e.targets(c, f, 1)
+		if err != nil {
+			return err
+		}
+		objects = found
+	}
+	for _, obj := range objects {
+		cluster.remove(obj)
+		c.printf("%s \"%s\" deleted\n", cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"))
+	}
+	return nil
+}
+
+// kubectlLabel sets or removes labels or annotations: key=value sets, key- removes
+func (e *Environment) kubectlLabel(c *call, f flags) error {
+	field, verb := "labels", "labeled"
+	if f.arg(0) == "annotate" {
+		field, verb = "annotations", "annotated"
+	}
+	var targets, changes []string
+	for _, arg := range f.positional[1:] {
+		if strings.Contains(arg, "=") || strings.HasSuffix(arg, "-") {
+			changes = append(changes, arg)
+		} else {
+			targets = append(targets, arg)
+		}
+	}
+	objects, err := e.targets(c, flags{positional: append([]string{f.arg(0)}, targets...), values: f.values}, 1)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		metadata := mapAt(obj, "metadata")
+		values, _ := metadata[field].(map[string]interface{})
+		if values == nil {
+			values = Object{}
+			metadata[field] = values
+		}
+		for _, change := range changes {
+			if key, value, ok := strings.Cut(change, "="); ok {
+				values[key] = value
+			} else {
+				delete(values, strings.TrimSuffix(change, "-"))
+			}
+		}
+		c.printf("%s/%s %s\n", e.Cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"), verb)
+	}
+	return nil
+}
+
+// kubectlPatch applies a JSON merge patch
+func (e *Environment) kubectlPatch(c *call, f flags) error {
+	objects, err := e.targets(c, f, 1)
+	if err != nil {
+		return err
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal([]byte(f.get("p", "patch")), &patch); err != nil {
+		return c.fail("error: unable to parse patch: %v", err)
+	}
+	for _, obj := range objects {
+		mergePatch(obj, patch)
+		e.Cluster.put(obj)
+		c.printf("%s/%s patched\n", e.Cluster.kindOf(obj).qualified(), str(obj, "metadata", "name"))
+	}
+	return nil
+}
+
+func mergePatch(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchMap, ok := value.(map[string]interface{}); ok {
+			if targetMap, ok := target[key].(map[string]interface{}); ok {
+				mergePatch(targetMap, patchMap)
+				continue
+			}
+		}
+		target[key] = value
+	}
+}
+
+func (e *Environment) kubectlRollout(c *call, f flags) error {
+	objects, err := e.targets(c, f, 2)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		k, name := e.Cluster.kindOf(obj), str(obj, "metadata", "name")
+		switch f.arg(1) {
+		case "status":
+			c.printf("%s \"%s\" successfully rolled out\n", strings.ToLower(k.Kind), name)
+		case "restart":
+			annotations := mapAt(obj, "spec", "template", "metadata", "annotations")
+			if annotations == nil {
+				template := mapAt(obj, "spec", "template")
+				if template == nil {
+					continue
+				}
+				metadata := mapAt(template, "metadata")
+				if metadata == nil {
+					metadata = Object{}
+					template["metadata"] = metadata
+				}
+				annotations = Object{}
+				metadata["annotations"] = annotations
+			}
+			annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().UTC().Format(time.RFC3339)
+			e.Cluster.put(obj)
+			c.printf("%s/%s restarted\n", k.qualified(), name)
+		case "undo":
+			c.printf("%s/%s rolled back\n", k.qualified(), name)
+		case "history":
+			c.printf("%s/%s \nREVISION  CHANGE-CAUSE\n%d         <none>\n", k.qualified(), name, intAt(obj, 1, "metadata", "generation"))
+		}
+	}
+	return nil
+}
+
+// kubeconfig answers kubectl config: the simulated cluster is the only context
+func (e *Environment) kubeconfig(c *call, f flags) error {
+	switch f.arg(1) {
+	case "current-context", "get-contexts":
+		c.printf("simulated\n")
+		return nil
+	case "view":
+	default:
+		return nil
+	}
+	return e.print(c, valueOr(f.get("o", "output"), "yaml"), e.kubeconfigDocument(), nil)
+}
+
+// kubeconfigDocument is a kubeconfig for the simulated cluster
+func (e *Environment) kubeconfigDocument() Object {
+	return Object{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": "simulated",
+		"clusters": []interface{}{Object{"name": "simulated", "cluster": Object{
+			"server":                     e.endpoint.URL,
+			"certificate-authority-data": e.certificateAuthority(),
+		}}},
+		"contexts": []interface{}{Object{"name": "simulated", "context": Object{"cluster": "simulated", "user": "simulated-admin"}}},
+		"users":    []interface{}{Object{"name": "simulated-admin", "user": Object{"token": "simulated"}}},
+	}
+}
+
+// kubeconfigFile writes the kubeconfig of the simulated cluster into the simulation
+// directory, for the kubeconfig outputs, and returns its path
+func (e *Environment) kubeconfigFile() string {
+	path := filepath.Join(filepath.Dir(e.path), "kubeconfig")
+	data, err := yaml.Marshal(e.kubeconfigDocument())
+	if err == nil && os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		err = os.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		logger.Warn("Failed to write the simulated kubeconfig").Str("path", path).Err(err).Send()
+	}
+	return path
+}
+
+// certificateAuthority is the CA bundle of the simulated endpoints, base64-encoded like in a kubeconfig
+func (e *Environment) certificateAuthority() string {
+	certificate := e.endpoint.Certificate()
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}))
+}
+
+// sameSpec reports whether applying obj would change nothing in existing
+func sameSpec(existing, obj Object) bool {
+	for _, field := range []string{"spec", "data", "stringData", "rules", "subjects", "roleRef"} {
+		a, _ := json.Marshal(existing[field])
+		b, _ := json.Marshal(obj[field])
+		if !bytes.Equal(a, b) {
+			return false
+		}
+	}
+	a, _ := json.Marshal(mapAt(existing, "metadata", "labels"))
+	b, _ := json.Marshal(mapAt(obj, "metadata", "labels"))
+	return bytes.Equal(a, b)
+}
+
+func toList(objects []Object) []interface{} {
+	items := make([]interface{}, len(objects))
+	for i, obj := range objects {
+		items[i] = obj
+	}
+	return items
+}
+
+func age(created string) string {
+	t, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return "<unknown>"
+	}
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return fmt.Sprintf("%ds", int(elapsed.Seconds()))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm", int(elapsed.Minutes()))
+	case elapsed < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(elapsed.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(elapsed.Hours()/24))
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// requirement is one term of a label selector
+type requirement struct {
+	key    string
+	op     string // =, !=, in, notin, exists, !exists
+	values []string
+}
+
+// parseSelector parses a label selector such as app=web,tier!=cache,env in (a,b),!legacy
+func parseSelector(selector string) []requirement {
+	var reqs []requirement
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, selector[start:])
+
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		switch {
+		case term == "":
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			reqs = append(reqs, requirement{key: strings.TrimSpace(key), op: "!=", values: []string{strings.TrimSpace(value)}})
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			reqs = append(reqs, requirement{key: strings.TrimSpace(key), op: "=", values: []string{strings.Trim(strings.TrimSpace(value), "=")}})
+		case strings.Contains(term, " notin ") || strings.Contains(term, " in "):
+			op := "in"
+			key, list, found := strings.Cut(term, " notin ")
+			if found {
+				op = "notin"
+			} else {
+				key, list, _ = strings.Cut(term, " in ")
+			}
+			var values []string
+			for _, value := range strings.Split(strings.Trim(strings.TrimSpace(list), "()"), ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+			reqs = append(reqs, requirement{key: strings.TrimSpace(key), op: op, values: values})
+		case strings.HasPrefix(term, "!"):
+			reqs = append(reqs, requirement{key: strings.TrimPrefix(term, "!"), op: "!exists"})
+		default:
+			reqs = append(reqs, requirement{key: term, op: "exists"})
+		}
+	}
+	return reqs
+}
+
+// selectorOf turns matchLabels or a nodeSelector into requirements
+func selectorOf(labels map[string]interface{}) []requirement {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	reqs := make([]requirement, 0, len(keys))
+	for _, key := range keys {
+		reqs = append(reqs, requirement{key: key, op: "=", values: []string{fmt.Sprint(labels[key])}})
+	}
+	return reqs
+}
+
+func matches(reqs []requirement, labels map[string]string) bool {
+	for _, req := range reqs {
+		value, ok := labels[req.key]
+		switch req.op {
+		case "=":
+			if !ok || value != req.values[0] {
+				return false
+			}
+		case "!=":
+			if ok && value == req.values[0] {
+				return false
+			}
+		case "in", "notin":
+			found := false
+			for _, candidate := range req.values {
+				found = found || (ok && value == candidate)
+			}
+			if found != (req.op == "in") {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
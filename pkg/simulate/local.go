@@ -0,0 +1,166 @@
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LocalCluster is a simulated kind or k3d cluster
+type LocalCluster struct {
+	Tool    string `json:"tool"`
+	Workers int    `json:"workers"`
+}
+
+// kind answers kind's cluster commands; the clusters are only names, their API is the
+// simulated cluster
+func (e *Environment) kind(c *call) error {
+	f := parseFlags(c.Args, "name", "config", "wait", "image", "kubeconfig")
+	name := valueOr(f.get("name"), "kind")
+	command := strings.Join(f.positional[:min(len(f.positional), 2)], " ")
+
+	switch command {
+	case "get clusters":
+		names := e.localClusters("kind")
+		if len(names) == 0 {
+			fmt.Fprintln(c.Stderr, "No kind clusters found.")
+		}
+		for _, name := range names {
+			c.printf("%s\n", name)
+		}
+	case "create cluster":
+		if _, ok := e.Local[name]; ok {
+			return c.fail("ERROR: failed to create cluster: node(s) already exist for a cluster with the name %q", name)
+		}
+		e.Local[name] = &LocalCluster{Tool: "kind", Workers: strings.Count(string(c.Stdin), "role: worker")}
+		fmt.Fprintf(c.Stderr, "Creating cluster %q ...\nSet kubectl context to \"kind-%s\"\n", name, name)
+	case "get nodes":
+		cluster, ok := e.Local[name]
+		if !ok {
+			fmt.Fprintf(c.Stderr, "No kind nodes found for cluster %q.\n", name)
+			return nil
+		}
+		c.printf("%s-control-plane\n", name)
+		for i := 1; i <= cluster.Workers; i++ {
+			suffix := ""
+			if i > 1 {
+				suffix = fmt.Sprint(i)
+			}
+			c.printf("%s-worker%s\n", name, suffix)
+		}
+	case "delete cluster":
+		delete(e.Local, name)
+		fmt.Fprintf(c.Stderr, "Deleting cluster %q ...\n", name)
+	default:
+		return c.fail("ERROR: unknown command %q for \"kind\"", command)
+	}
+	return nil
+}
+
+// k3d answers k3d's cluster commands
+func (e *Environment) k3d(c *call) error {
+	f := parseFlags(c.Args, "agents", "image", "registry-create", "registry-config", "o", "output")
+	command := strings.Join(f.positional[:min(len(f.positional), 2)], " ")
+	name := valueOr(f.arg(2), "k3s-default")
+
+	switch command {
+	case "cluster list":
+		clusters := []map[string]interface{}{}
+		for _, name := range e.localClusters("k3d") {
+			clusters = append(clusters, map[string]interface{}{"name": name, "agentsCount": e.Local[name].Workers, "serversCount": 1})
+		}
+		return printJSON(c, clusters)
+	case "cluster create":
+		if _, ok := e.Local[name]; ok {
+			return c.fail("FATA[0000] Failed to create cluster '%s' because a cluster with that name already exists", name)
+		}
+		var workers int
+		fmt.Sscanf(f.get("agents"), "%d", &workers)
+		e.Local[name] = &LocalCluster{Tool: "k3d", Workers: workers}
+		c.printf("INFO[0000] Cluster '%s' created successfully!\n", name)
+	case "cluster delete":
+		delete(e.Local, name)
+		c.printf("INFO[0000] Successfully deleted cluster %s!\n", name)
+	default:
+		return c.fail("Error: unknown command %q for \"k3d\"", command)
+	}
+	return nil
+}
+
+// localClusters returns the names of the simulated clusters of a tool, sorted
+func (e *Environment) localClusters(tool string) []string {
+	var names []string
+	for name, cluster := range e.Local {
+		if cluster.Tool == tool {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// docker answers the container commands of the local registry; exec and network changes
+// only need to succeed
+func (e *Environment) docker(c *call) error {
+	f := parseFlags(c.Args, "f", "format", "p", "publish", "network", "name", "restart")
+	switch f.arg(0) {
+	case "inspect":
+		name := f.arg(1)
+		if _, ok := e.Containers[name]; !ok {
+			return c.fail("Error: No such object: %s", name)
+		}
+		c.printf("true\n")
+	case "run":
+		name := valueOr(f.get("name"), e.nextID("container"))
+		if _, ok := e.Containers[name]; ok {
+			return c.fail("docker: Error response from daemon: Conflict. The container name \"/%s\" is already in use.", name)
+		}
+		e.Containers[name] = f.arg(1)
+		c.printf("%s\n", strings.ReplaceAll(e.Cluster.uid(name), "-", ""))
+	case "rm":
+		for _, name := range f.positional[1:] {
+			delete(e.Containers, name)
+			c.printf("%s\n", name)
+		}
+	}
+	return nil
+}
+
+// k6 writes the summary export of a load test every request of which succeeded quickly
+func (e *Environment) k6(c *call) error {
+	f := parseFlags(c.Args, "vus", "duration", "summary-trend-stats", "summary-export")
+	var users int
+	fmt.Sscanf(f.get("vus"), "%d", &users)
+	users = max(users, 1)
+	duration, err := time.ParseDuration(f.get("duration"))
+	if err != nil || duration <= 0 {
+		duration = 30 * time.Second
+	}
+
+	path := f.get("summary-export")
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) && c.Dir != "" {
+		path = filepath.Join(c.Dir, path)
+	}
+	requests := float64(users) * 5 * duration.Seconds()
+	data, err := json.Marshal(map[string]interface{}{
+		"metrics": map[string]interface{}{
+			"http_req_duration": map[string]float64{"avg": 21.4, "min": 4.1, "med": 18.2, "max": 96.5, "p(95)": 42.7, "p(99)": 71.3},
+			"http_req_failed":   map[string]float64{"value": 0},
+			"http_reqs":         map[string]float64{"count": requests, "rate": requests / duration.Seconds()},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return c.fail("level=error msg=\"failed to write summary: %v\"", err)
+	}
+	return nil
+}
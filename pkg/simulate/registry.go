@@ -0,0 +1,91 @@
+package simulate
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// manifestPath matches the manifest requests of the registry API
+var manifestPath = regexp.MustCompile(`^/v2/(.+)/manifests/([^/]+)$`)
+
+// seedingRegistry is an in-memory registry standing in for every registry host. An image
+// is pushed the first time its tag is asked for, so the vendor images a package lists exist.
+type seedingRegistry struct {
+	registry http.Handler
+}
+
+func newRegistry() http.Handler {
+	return &seedingRegistry{registry: registry.New(registry.Logger(log.New(io.Discard, "", 0)))}
+}
+
+func (s *seedingRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if match := manifestPath.FindStringSubmatch(r.URL.Path); match != nil && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		s.seed(r.Host, match[1], match[2])
+	}
+	s.registry.ServeHTTP(w, r)
+}
+
+// seed pushes an image to repository:tag unless it is there. The image is derived from the
+// last path element of the repository and the tag, so a mirror of an image has its digest.
+func (s *seedingRegistry) seed(host, repository, tag string) {
+	if strings.HasPrefix(tag, "sha256:") {
+		return
+	}
+	probe := httptest.NewRecorder()
+	s.registry.ServeHTTP(probe, httptest.NewRequest(http.MethodHead, "/v2/"+repository+"/manifests/"+tag, nil))
+	if probe.Code == http.StatusOK {
+		return
+	}
+
+	ref, err := name.ParseReference(host + "/" + repository + ":" + tag)
+	if err != nil {
+		return
+	}
+	content := path.Base(repository) + ":" + tag
+	image, err := mutate.AppendLayers(empty.Image, static.NewLayer([]byte(content), types.OCILayer))
+	if err != nil {
+		return
+	}
+	remote.Write(ref, image, remote.WithTransport(handlerTransport{s.registry}))
+}
+
+// handlerTransport serves requests with a handler in the process
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Handlers read the body of every request, as a server always hands them one
+	served := r
+	if r.Body == nil {
+		served = r.Clone(r.Context())
+		served.Body = http.NoBody
+	}
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, served)
+	response := recorder.Result()
+	response.Request = r
+	return response, nil
+}
+
+// RoundTrip answers the requests of the process: registry API calls go to the simulated
+// registry and everything else, such as health checks and chart repositories, succeeds
+func (e *Environment) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/") {
+		return handlerTransport{e.registry}.RoundTrip(r)
+	}
+	return handlerTransport{http.HandlerFunc(serveEndpoint)}.RoundTrip(r)
+}
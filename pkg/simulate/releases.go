@@ -0,0 +1,197 @@
+package simulate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+// Helm keeps each revision of a release in a Secret of the release's namespace, which the
+// Helm SDK reads and writes through the API. The simulated releases are kept there too, so
+// the SDK and the simulated helm CLI see the same releases.
+
+// releaseSystemLabels are the labels of a release Secret Helm sets itself; the others are
+// the release's labels
+var releaseSystemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt"}
+
+// releaseChanged updates the simulated release whose storage Secret obj is, after the Helm
+// SDK wrote or deleted it through the API
+func (e *Environment) releaseChanged(obj Object) {
+	labels := labelsOf(obj)
+	if str(obj, "kind") != "Secret" || labels["owner"] != "helm" || labels["name"] == "" {
+		return
+	}
+	e.loadRelease(str(obj, "metadata", "namespace"), labels["name"])
+}
+
+// loadRelease reads a release from its storage Secrets, removing it when it has none
+func (e *Environment) loadRelease(namespace, name string) {
+	key := namespace + "/" + name
+	var revisions []*helmrelease.Release
+	labelsOfRevision := make(map[int]map[string]string)
+	for _, secret := range e.Cluster.list("Secret", namespace, parseSelector("owner=helm,name="+name)) {
+		rel, err := decodeRelease(str(secret, "data", "release"))
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, rel)
+		labelsOfRevision[rel.Version] = labelsOf(secret)
+	}
+	if len(revisions) == 0 {
+		delete(e.Releases, key)
+		return
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Version < revisions[j].Version })
+
+	release := &Release{Name: name, Namespace: namespace}
+	for _, rel := range revisions {
+		var metadata chart.Metadata
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			metadata = *rel.Chart.Metadata
+		}
+		release.Chart, release.Version, release.AppVersion = metadata.Name, metadata.Version, metadata.AppVersion
+		values := ""
+		if len(rel.Config) > 0 {
+			data, _ := yaml.Marshal(rel.Config)
+			values = string(data)
+		}
+		revision := Revision{
+			Revision:   rel.Version,
+			Chart:      metadata.Name + "-" + metadata.Version,
+			AppVersion: metadata.AppVersion,
+			Manifest:   rel.Manifest,
+			Values:     values,
+		}
+		if rel.Info != nil {
+			revision.Status = rel.Info.Status.String()
+			revision.Updated = rel.Info.LastDeployed.Time
+			revision.Description = rel.Info.Description
+		}
+		release.History = append(release.History, revision)
+	}
+	labels := labelsOfRevision[release.current().Revision]
+	for _, label := range releaseSystemLabels {
+		delete(labels, label)
+	}
+	if len(labels) > 0 {
+		release.Labels = labels
+	}
+	e.Releases[key] = release
+}
+
+// storeRelease writes the storage Secrets of a release the simulated helm CLI changed
+func (e *Environment) storeRelease(release *Release) {
+	for _, revision := range release.History {
+		version := strings.TrimPrefix(revision.Chart, release.Chart+"-")
+		rel := &helmrelease.Release{
+			Name:      release.Name,
+			Namespace: release.Namespace,
+			Version:   revision.Revision,
+			Info: &helmrelease.Info{
+				FirstDeployed: helmtime.Time{Time: release.History[0].Updated},
+				LastDeployed:  helmtime.Time{Time: revision.Updated},
+				Description:   revision.Description,
+				Status:        helmrelease.Status(revision.Status),
+			},
+			Chart:    &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: release.Chart, Version: version, AppVersion: revision.AppVersion}},
+			Manifest: revision.Manifest,
+		}
+		yaml.Unmarshal([]byte(revision.Values), &rel.Config)
+		data, err := encodeRelease(rel)
+		if err != nil {
+			continue
+		}
+
+		labels := Object{}
+		for key, value := range release.Labels {
+			labels[key] = value
+		}
+		labels["name"] = release.Name
+		labels["owner"] = "helm"
+		labels["status"] = revision.Status
+		labels["version"] = strconv.Itoa(revision.Revision)
+		e.Cluster.put(Object{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "helm.sh/release.v1",
+			"metadata": Object{
+				"name":      releaseSecretName(release.Name, revision.Revision),
+				"namespace": release.Namespace,
+				"labels":    labels,
+			},
+			"data": Object{"release": base64.StdEncoding.EncodeToString([]byte(data))},
+		})
+	}
+}
+
+// deleteStoredRelease deletes the storage Secrets of an uninstalled release
+func (e *Environment) deleteStoredRelease(release *Release) {
+	for _, secret := range e.Cluster.list("Secret", release.Namespace, parseSelector("owner=helm,name="+release.Name)) {
+		e.Cluster.remove(secret)
+	}
+}
+
+// releaseStored reports whether the current revision of a release has its storage Secret
+func (e *Environment) releaseStored(release *Release) bool {
+	secretKind, _ := e.Cluster.resource("secrets")
+	return e.Cluster.get(secretKind, release.Namespace, releaseSecretName(release.Name, release.current().Revision)) != nil
+}
+
+func releaseSecretName(name string, revision int) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, revision)
+}
+
+// encodeRelease encodes a release like Helm's storage drivers: gzipped JSON in base64
+func encodeRelease(rel *helmrelease.Release) (string, error) {
+	data, err := json.Marshal(rel)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeRelease decodes the release of a storage Secret's data, itself base64 in the Secret
+func decodeRelease(data string) (*helmrelease.Release, error) {
+	secret, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := base64.StdEncoding.DecodeString(string(secret))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(payload, []byte{0x1f, 0x8b, 0x08}) {
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if payload, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	var rel helmrelease.Release
+	if err := json.Unmarshal(payload, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
@@ -0,0 +1,350 @@
+// Package simulate is the fake environment behind --simulate. A Kubernetes cluster, Helm
+// releases, Terraform state, managed cloud services and OCI registries are held in memory
+// and answer the commands and requests the installer makes, so the whole pipeline runs,
+// reports and records state without any infrastructure. The environment is saved between
+// runs, so a deploy finds the cluster an earlier provision-infra created.
+package simulate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// StateFile is the file in the simulation directory keeping the environment between runs
+const StateFile = "environment.json"
+
+// Environment is the simulated infrastructure
+type Environment struct {
+	Cluster   *Cluster                   `json:"cluster"`
+	Releases  map[string]*Release        `json:"releases"`        // by namespace/name
+	Terraform map[string]*TerraformState `json:"terraform"`       // by working directory
+	Plans     map[string][]string        `json:"plans,omitempty"` // arguments of the saved plans, by path
+	Services  map[string]*Service        `json:"services"`        // by name
+	Serial    int                        `json:"serial"`          // last identifier handed out
+
+	Local      map[string]*LocalCluster `json:"local,omitempty"`      // kind and k3d clusters by name
+	Containers map[string]string        `json:"containers,omitempty"` // docker containers, name to image
+
+	mu       sync.Mutex
+	path     string
+	endpoint *httptest.Server
+	registry http.Handler
+
+	previousTransport http.RoundTripper
+	previousRemote    http.RoundTripper
+}
+
+// Start loads the environment kept in dir, or creates an empty one, and routes every command
+// and HTTP request of the process to it until Stop
+func Start(dir string) (*Environment, error) {
+	env, err := load(filepath.Join(dir, StateFile))
+	if err != nil {
+		return nil, err
+	}
+
+	// Endpoints handed out in outputs are served for real, so health checks dialling them pass
+	env.endpoint = httptest.NewTLSServer(http.HandlerFunc(serveEndpoint))
+	env.registry = newRegistry()
+
+	env.previousTransport, env.previousRemote = http.DefaultTransport, remote.DefaultTransport
+	http.DefaultTransport, remote.DefaultTransport = env, env
+	execx.Simulate(env.run)
+	k8s.Simulate(handlerTransport{http.HandlerFunc(env.serveAPI)})
+
+	logger.Info("Simulating the environment").Str("state", env.path).Int("nodes", len(env.Cluster.nodes())).Send()
+	return env, nil
+}
+
+// load reads the environment kept in path, or creates an empty one
+func load(path string) (*Environment, error) {
+	env := &Environment{path: path}
+	data, err := os.ReadFile(env.path)
+	if err == nil {
+		if err := json.Unmarshal(data, env); err != nil {
+			return nil, fmt.Errorf("failed to read simulated environment %s: %w", env.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read simulated environment: %w", err)
+	}
+	if env.Cluster == nil {
+		env.Cluster = newCluster()
+	}
+	if env.Releases == nil {
+		env.Releases = make(map[string]*Release)
+	}
+	if env.Terraform == nil {
+		env.Terraform = make(map[string]*TerraformState)
+	}
+	if env.Plans == nil {
+		env.Plans = make(map[string][]string)
+	}
+	if env.Services == nil {
+		env.Services = make(map[string]*Service)
+	}
+	if env.Local == nil {
+		env.Local = make(map[string]*LocalCluster)
+	}
+	if env.Containers == nil {
+		env.Containers = make(map[string]string)
+	}
+	// Releases of simulations saved before they were kept in Secrets, as Helm keeps them
+	for _, release := range env.Releases {
+		if !env.releaseStored(release) {
+			env.storeRelease(release)
+		}
+	}
+	return env, nil
+}
+
+// Stop routes commands and requests to the real tools and services again and saves the
+// environment for the next run
+func (e *Environment) Stop() error {
+	execx.Simulate(nil)
+	k8s.Simulate(nil)
+	http.DefaultTransport, remote.DefaultTransport = e.previousTransport, e.previousRemote
+	e.endpoint.Close()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.save()
+}
+
+// Handoff saves the environment, runs fn, an installer process simulating the same
+// environment, and continues with the environment that process left behind. Commands and
+// requests of this process wait meanwhile, so the two never change the environment at once.
+func (e *Environment) Handoff(fn func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.save(); err != nil {
+		return err
+	}
+	runErr := fn()
+	loaded, err := load(e.path)
+	if err != nil {
+		return errors.Join(runErr, err)
+	}
+	e.Cluster, e.Releases, e.Terraform, e.Plans = loaded.Cluster, loaded.Releases, loaded.Terraform, loaded.Plans
+	e.Services, e.Serial, e.Local, e.Containers = loaded.Services, loaded.Serial, loaded.Local, loaded.Containers
+	return runErr
+}
+
+// save writes the environment for the next run; callers hold e.mu
+func (e *Environment) save() error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode simulated environment: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return fmt.Errorf("failed to create simulation directory: %w", err)
+	}
+	if err := os.WriteFile(e.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save simulated environment: %w", err)
+	}
+	return nil
+}
+
+// latency is how long the slower simulated operations take, so progress is visible in demos
+var latency = map[string]time.Duration{
+	"terraform apply":   1500 * time.Millisecond,
+	"terraform destroy": 1000 * time.Millisecond,
+	"terraform init":    300 * time.Millisecond,
+	"terraform plan":    500 * time.Millisecond,
+	"helm upgrade":      800 * time.Millisecond,
+	"helm install":      800 * time.Millisecond,
+	"helm uninstall":    300 * time.Millisecond,
+	"make":              200 * time.Millisecond,
+	"ansible-playbook":  500 * time.Millisecond,
+	"kind create":       1000 * time.Millisecond,
+	"k3d cluster":       800 * time.Millisecond,
+	"k6 run":            1000 * time.Millisecond,
+}
+
+// run answers a command the way its tool would
+func (e *Environment) run(c *execx.Cmd, stdout, stderr io.Writer) error {
+	tool := filepath.Base(c.Name)
+	args := c.Args
+	if tool == "terragrunt" {
+		tool = "terraform"
+		args = dropArgs(args, "run-all", "--terragrunt-non-interactive")
+	}
+	verb := tool
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		verb += " " + args[0]
+	}
+	if delay, ok := latency[verb]; ok {
+		time.Sleep(delay)
+	} else if delay, ok := latency[tool]; ok {
+		time.Sleep(delay)
+	}
+
+	var stdin []byte
+	if c.Stdin != nil {
+		data, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return err
+		}
+		stdin = data
+	}
+	call := &call{Dir: c.Dir, Args: args, Stdin: stdin, Stdout: stdout, Stderr: stderr}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch tool {
+	case "kubectl":
+		return e.kubectl(call)
+	case "helm":
+		return e.helm(call)
+	case "terraform":
+		return e.terraform(call)
+	case "make":
+		return e.make(call)
+	case "ansible-playbook":
+		return e.ansible(call)
+	case "aws", "az", "gcloud":
+		return e.cloud(tool, call)
+	case "infracost":
+		return e.infracost(call)
+	case "kind":
+		return e.kind(call)
+	case "k3d":
+		return e.k3d(call)
+	case "docker":
+		return e.docker(call)
+	case "k6":
+		return e.k6(call)
+	}
+	// Database clients, hooks and the other tools only need to succeed; filters such as
+	// post-renderers pass their input through unchanged
+	if stdin != nil {
+		_, err := stdout.Write(stdin)
+		return err
+	}
+	return nil
+}
+
+// call is one simulated command
+type call struct {
+	Dir    string
+	Args   []string
+	Stdin  []byte
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (c *call) printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.Stdout, format, args...)
+}
+
+// fail prints the error the way the tool does and exits 1
+func (c *call) fail(format string, args ...interface{}) error {
+	fmt.Fprintf(c.Stderr, format+"\n", args...)
+	return exitStatus(1)
+}
+
+// exitStatus is a failed simulated command, reported like a failed process
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// nextID hands out identifiers unique across runs, e.g. sim-0001a
+func (e *Environment) nextID(prefix string) string {
+	e.Serial++
+	return fmt.Sprintf("%s-%05x", prefix, e.Serial)
+}
+
+// flags is a parsed command line: positional arguments and flag values. Flags named in the
+// value list take the next argument unless written as --flag=value.
+type flags struct {
+	positional []string
+	values     map[string][]string
+}
+
+func parseFlags(args []string, valueFlags ...string) flags {
+	takesValue := make(map[string]bool, len(valueFlags))
+	for _, name := range valueFlags {
+		takesValue[name] = true
+	}
+	parsed := flags{values: make(map[string][]string)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			parsed.positional = append(parsed.positional, arg)
+			continue
+		}
+		name, value, inline := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !inline && takesValue[name] && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		parsed.values[name] = append(parsed.values[name], value)
+	}
+	return parsed
+}
+
+// get returns the last value of the first of names that was given
+func (f flags) get(names ...string) string {
+	for _, name := range names {
+		if values := f.values[name]; len(values) > 0 {
+			return values[len(values)-1]
+		}
+	}
+	return ""
+}
+
+func (f flags) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := f.values[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f flags) arg(i int) string {
+	if i < len(f.positional) {
+		return f.positional[i]
+	}
+	return ""
+}
+
+func dropArgs(args []string, drop ...string) []string {
+	var kept []string
+	for _, arg := range args {
+		keep := true
+		for _, d := range drop {
+			if arg == d {
+				keep = false
+			}
+		}
+		if keep {
+			kept = append(kept, arg)
+		}
+	}
+	return kept
+}
+
+// serveEndpoint answers the health checks of the simulated endpoints
+func serveEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/version" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"major":"1","minor":"29","gitVersion":"%s","platform":"linux/amd64"}`, kubernetesVersion)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
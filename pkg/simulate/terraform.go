@@ -0,0 +1,425 @@
+package simulate
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// terraformVersion is the version the simulated terraform reports
+const terraformVersion = "1.7.4"
+
+// TerraformState is the simulated state of one working directory
+type TerraformState struct {
+	Resources []string          `json:"resources"` // addresses
+	Outputs   map[string]string `json:"outputs"`
+	Serial    int               `json:"serial"`
+	Lineage   string            `json:"lineage"`
+}
+
+var (
+	resourceBlock = regexp.MustCompile(`(?m)^\s*resource\s+"([^"]+)"\s+"([^"]+)"`)
+	outputBlock   = regexp.MustCompile(`(?m)^\s*output\s+"([^"]+)"`)
+)
+
+// defaultResources stand in for the resources of a configuration with no .tf files to read
+var defaultResources = []string{"simulated_network.main", "simulated_cluster.main", "simulated_node_pool.default"}
+
+// terraformValueFlags are the terraform flags taking a value. Terraform writes values as
+// -flag=value, so these only matter for the sub-commands that take positional arguments.
+var terraformValueFlags = []string{"chdir"}
+
+// terraform answers a terraform command for the working directory of c
+func (e *Environment) terraform(c *call) error {
+	f := parseFlags(c.Args, terraformValueFlags...)
+	dir := c.Dir
+	if chdir := f.get("chdir"); chdir != "" {
+		dir = filepath.Join(dir, chdir)
+	}
+	resources, outputs := scanConfiguration(dir)
+	state := e.Terraform[dir]
+
+	// A saved plan is applied with the targets, variables and mode it was made with
+	command := f.arg(0)
+	if command == "apply" && f.arg(1) != "" {
+		planned, ok := e.Plans[filepath.Join(dir, f.arg(1))]
+		if !ok {
+			return c.fail("╷\n│ Error: Failed to load \"%s\" as a plan file\n╵", f.arg(1))
+		}
+		delete(e.Plans, filepath.Join(dir, f.arg(1)))
+		f = parseFlags(planned, terraformValueFlags...)
+		if f.has("destroy") {
+			command = "destroy"
+		}
+	}
+
+	switch command {
+	case "version":
+		if f.has("json") {
+			c.printf(`{"terraform_version":"%s","platform":"linux_amd64"}`+"\n", terraformVersion)
+		} else {
+			c.printf("Terraform v%s\non linux_amd64\n", terraformVersion)
+		}
+	case "init":
+		c.printf("Initializing the backend...\nInitializing provider plugins...\n\nTerraform has been successfully initialized!\n")
+	case "validate":
+		c.printf("Success! The configuration is valid.\n")
+	case "fmt":
+	case "workspace":
+		if f.arg(1) == "show" {
+			c.printf("default\n")
+		}
+	case "plan":
+		if out := f.get("out"); out != "" {
+			e.Plans[filepath.Join(dir, out)] = c.Args
+		}
+		existing := stateResources(state)
+		if f.has("destroy") {
+			printPlan(c, nil, targeted(existing, f))
+		} else {
+			printPlan(c, missing(targeted(resources, f), existing), nil)
+		}
+	case "apply":
+		return e.terraformApply(c, f, dir, resources, outputs)
+	case "destroy":
+		if state == nil {
+			c.printf("\nNo changes. No objects need to be destroyed.\n")
+			return nil
+		}
+		destroyed := targeted(state.Resources, f)
+		for _, address := range destroyed {
+			c.printf("%s: Destroying...\n%s: Destruction complete after 1s\n", address, address)
+		}
+		state.Resources = missing(state.Resources, destroyed)
+		state.Serial++
+		if len(state.Resources) == 0 {
+			state.Outputs = map[string]string{}
+		}
+		c.printf("\nDestroy complete! Resources: %d destroyed.\n", len(destroyed))
+	case "output":
+		values := e.resolvedOutputs(state)
+		if name := f.arg(1); name != "" {
+			value, ok := values[name]
+			if !ok {
+				return c.fail("╷\n│ Error: Output \"%s\" not found\n╵", name)
+			}
+			c.printf("%s\n", value)
+			return nil
+		}
+		document := make(map[string]interface{}, len(values))
+		for name, value := range values {
+			document[name] = map[string]interface{}{"sensitive": strings.Contains(name, "password"), "type": "string", "value": value}
+		}
+		return printJSON(c, document)
+	case "show":
+		// show -json of a saved plan: what an apply would create
+		changes := []interface{}{}
+		for _, address := range missing(resources, stateResources(state)) {
+			resourceType, name := splitAddress(address)
+			changes = append(changes, map[string]interface{}{
+				"address": address,
+				"type":    resourceType,
+				"name":    name,
+				"change":  map[string]interface{}{"actions": []string{"create"}},
+			})
+		}
+		return printJSON(c, map[string]interface{}{"format_version": "1.2", "terraform_version": terraformVersion, "resource_changes": changes})
+	case "state":
+		switch f.arg(1) {
+		case "pull":
+			if state == nil {
+				return nil
+			}
+			return printJSON(c, e.pulledState(state))
+		case "list":
+			for _, address := range stateResources(state) {
+				c.printf("%s\n", address)
+			}
+		}
+	case "import":
+		if state == nil {
+			state = e.newTerraformState(dir)
+		}
+		state.Resources = append(missing(state.Resources, []string{f.arg(1)}), f.arg(1))
+		state.Serial++
+		c.printf("%s: Import prepared!\n\nImport successful!\n", f.arg(1))
+	}
+	return nil
+}
+
+func (e *Environment) newTerraformState(dir string) *TerraformState {
+	state := &TerraformState{Outputs: map[string]string{}, Lineage: e.nextID("lineage")}
+	e.Terraform[dir] = state
+	return state
+}
+
+// terraformApply creates the resources of the configuration missing from the state and
+// sets its outputs. A node count variable resizes the simulated node pool, which is how
+// deploy scales the pool with the terraform method.
+func (e *Environment) terraformApply(c *call, f flags, dir string, resources, outputs []string) error {
+	state := e.Terraform[dir]
+	if state == nil {
+		state = e.newTerraformState(dir)
+	}
+	created := missing(targeted(resources, f), state.Resources)
+	for _, address := range created {
+		c.printf("%s: Creating...\n%s: Creation complete after 2s [id=%s]\n", address, address, e.nextID("sim"))
+	}
+	state.Resources = append(state.Resources, created...)
+	state.Serial++
+
+	for _, assignment := range f.values["var"] {
+		name, value, _ := strings.Cut(assignment, "=")
+		if count, err := strconv.Atoi(value); err == nil && strings.HasSuffix(name, "node_count") {
+			pool := strings.TrimSuffix(strings.TrimSuffix(name, "node_count"), "_")
+			e.Cluster.scalePool(e.scaledPool(pool), count)
+		}
+	}
+
+	for _, name := range outputs {
+		if _, ok := state.Outputs[name]; !ok {
+			state.Outputs[name] = e.outputValue(name)
+		}
+	}
+	c.printf("\nApply complete! Resources: %d added, 0 changed, 0 destroyed.\n", len(created))
+	if values := e.resolvedOutputs(state); len(values) > 0 {
+		c.printf("\nOutputs:\n\n")
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := values[name]
+			if strings.Contains(name, "password") {
+				value = "<sensitive>"
+			}
+			c.printf("%s = %q\n", name, value)
+		}
+	}
+	return nil
+}
+
+// scaledPool is the node pool a node count variable sizes: the pool it is prefixed with,
+// or the only pool other than the default one when there is exactly one
+func (e *Environment) scaledPool(prefix string) string {
+	if prefix != "" {
+		return prefix
+	}
+	pools := make(map[string]bool)
+	for _, node := range e.Cluster.nodes() {
+		if pool := str(node, "metadata", "labels", nodePoolLabels[0]); pool != "default" {
+			pools[pool] = true
+		}
+	}
+	if len(pools) == 1 {
+		for pool := range pools {
+			return pool
+		}
+	}
+	return "default"
+}
+
+// Placeholders in output values for the simulated endpoint, which listens on a new port
+// every run. They are resolved when the outputs are read.
+const (
+	endpointURL     = "${simulated.endpoint}"
+	endpointHost    = "${simulated.host}"
+	endpointPort    = "${simulated.port}"
+	endpointAddress = "${simulated.address}"
+	endpointCA      = "${simulated.ca}"
+	kubeconfigPath  = "${simulated.kubeconfig}"
+)
+
+// outputValue makes up the value of an output from its name, pointing endpoints and
+// certificates at the simulated endpoint so the health checks reach something real
+func (e *Environment) outputValue(name string) string {
+	switch {
+	case strings.Contains(name, "kubeconfig"):
+		return kubeconfigPath
+	case strings.Contains(name, "ca_certificate") || strings.HasSuffix(name, "_ca"):
+		return endpointCA
+	case strings.HasPrefix(name, "database_endpoint"):
+		return endpointAddress
+	case strings.HasSuffix(name, "endpoint") || strings.HasSuffix(name, "url"):
+		return endpointURL
+	case strings.HasSuffix(name, "host") || strings.HasSuffix(name, "address"):
+		return endpointHost
+	case strings.HasSuffix(name, "port"):
+		return endpointPort
+	case strings.Contains(name, "password"):
+		return e.nextID("password")
+	case strings.HasSuffix(name, "username"):
+		return "simulated"
+	case strings.HasSuffix(name, "region"):
+		return "sim-region-1"
+	case strings.HasSuffix(name, "version"):
+		return strings.TrimPrefix(e.Cluster.Version, "v")
+	}
+	return e.nextID(strings.ReplaceAll(name, "_", "-"))
+}
+
+// resolve replaces the endpoint placeholders of an output value
+func (e *Environment) resolve(value string) string {
+	endpoint, _ := url.Parse(e.endpoint.URL)
+	host, port, _ := net.SplitHostPort(endpoint.Host)
+	return strings.NewReplacer(
+		endpointURL, e.endpoint.URL,
+		endpointHost, host,
+		endpointPort, port,
+		endpointAddress, endpoint.Host,
+		endpointCA, e.certificateAuthority(),
+		kubeconfigPath, e.kubeconfigFile(),
+	).Replace(value)
+}
+
+// resolvedOutputs returns the outputs of state with their placeholders resolved
+func (e *Environment) resolvedOutputs(state *TerraformState) map[string]string {
+	outputs := make(map[string]string)
+	if state == nil {
+		return outputs
+	}
+	for name, value := range state.Outputs {
+		outputs[name] = e.resolve(value)
+	}
+	return outputs
+}
+
+// scanConfiguration reads the resource addresses and output names of the .tf files of dir.
+// Files in a sub-directory are taken as a module of that name.
+func scanConfiguration(dir string) ([]string, []string) {
+	var resources, outputs []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != dir {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || filepath.Ext(path) != ".tf" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		prefix := ""
+		if rel, _ := filepath.Rel(dir, filepath.Dir(path)); rel != "." {
+			prefix = "module." + strings.ReplaceAll(rel, string(filepath.Separator), ".module.") + "."
+		}
+		for _, match := range resourceBlock.FindAllStringSubmatch(string(data), -1) {
+			resources = append(resources, prefix+match[1]+"."+match[2])
+		}
+		if prefix == "" {
+			for _, match := range outputBlock.FindAllStringSubmatch(string(data), -1) {
+				outputs = append(outputs, match[1])
+			}
+		}
+		return nil
+	})
+	if len(resources) == 0 {
+		resources = defaultResources
+	}
+	return resources, outputs
+}
+
+// targeted keeps the addresses a -target flag selects, all of them without one
+func targeted(addresses []string, f flags) []string {
+	targets := f.values["target"]
+	if len(targets) == 0 {
+		return addresses
+	}
+	var kept []string
+	for _, address := range addresses {
+		for _, target := range targets {
+			if address == target || strings.HasPrefix(address, target+".") || strings.HasPrefix(address, target+"[") {
+				kept = append(kept, address)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// missing returns the addresses not in existing
+func missing(addresses, existing []string) []string {
+	present := make(map[string]bool, len(existing))
+	for _, address := range existing {
+		present[address] = true
+	}
+	var result []string
+	for _, address := range addresses {
+		if !present[address] {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+func stateResources(state *TerraformState) []string {
+	if state == nil {
+		return nil
+	}
+	return state.Resources
+}
+
+func printPlan(c *call, create, destroy []string) {
+	if len(create) == 0 && len(destroy) == 0 {
+		c.printf("\nNo changes. Your infrastructure matches the configuration.\n")
+		return
+	}
+	c.printf("\nTerraform will perform the following actions:\n\n")
+	for _, address := range create {
+		resourceType, name := splitAddress(address)
+		c.printf("  # %s will be created\n  + resource \"%s\" \"%s\" {}\n\n", address, resourceType, name)
+	}
+	for _, address := range destroy {
+		c.printf("  # %s will be destroyed\n\n", address)
+	}
+	c.printf("Plan: %d to add, 0 to change, %d to destroy.\n", len(create), len(destroy))
+}
+
+// splitAddress returns the type and name of a resource address
+func splitAddress(address string) (string, string) {
+	parts := strings.Split(address, ".")
+	if len(parts) < 2 {
+		return address, ""
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// pulledState renders the state the way terraform state pull does
+func (e *Environment) pulledState(state *TerraformState) map[string]interface{} {
+	resources := make([]interface{}, 0, len(state.Resources))
+	for _, address := range state.Resources {
+		resourceType, name := splitAddress(address)
+		resource := map[string]interface{}{
+			"mode":      "managed",
+			"type":      resourceType,
+			"name":      name,
+			"provider":  `provider["registry.terraform.io/hashicorp/simulated"]`,
+			"instances": []interface{}{map[string]interface{}{"attributes": map[string]string{"id": address}}},
+		}
+		if module := strings.TrimSuffix(address, "."+resourceType+"."+name); module != address {
+			resource["module"] = module
+		}
+		resources = append(resources, resource)
+	}
+	outputs := make(map[string]interface{}, len(state.Outputs))
+	for name, value := range e.resolvedOutputs(state) {
+		outputs[name] = map[string]interface{}{"value": value, "type": "string"}
+	}
+	return map[string]interface{}{
+		"version":           4,
+		"terraform_version": terraformVersion,
+		"serial":            state.Serial,
+		"lineage":           state.Lineage,
+		"outputs":           outputs,
+		"resources":         resources,
+	}
+}
@@ -0,0 +1,349 @@
+package simulate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// make prints the recipe of each target it is asked for, and the database of the Makefile for -qp
+func (e *Environment) make(c *call) error {
+	f := parseFlags(c.Args, "f", "file", "C", "directory", "j")
+	makefile := valueOr(f.get("f", "file"), "Makefile")
+	if !filepath.IsAbs(makefile) {
+		makefile = filepath.Join(c.Dir, makefile)
+	}
+	data, err := os.ReadFile(makefile)
+	if err != nil {
+		return c.fail("make: %s: No such file or directory", makefile)
+	}
+	recipes, order := makeRecipes(data)
+
+	if f.has("qp", "p") {
+		for _, target := range order {
+			c.printf("%s:\n", target)
+			for _, line := range recipes[target] {
+				c.printf("\t%s\n", line)
+			}
+			c.printf("\n")
+		}
+		return nil
+	}
+
+	var goals []string
+	for _, arg := range f.positional {
+		if !strings.Contains(arg, "=") {
+			goals = append(goals, arg)
+		}
+	}
+	if len(goals) == 0 && len(order) > 0 {
+		goals = order[:1]
+	}
+	for _, goal := range goals {
+		lines, ok := recipes[goal]
+		if !ok {
+			return c.fail("make: *** No rule to make target '%s'.  Stop.", goal)
+		}
+		for _, line := range lines {
+			c.printf("%s\n", strings.TrimLeft(line, "@-"))
+		}
+	}
+	return nil
+}
+
+// makeRecipes reads the explicit targets of a Makefile and their recipe lines
+func makeRecipes(data []byte) (map[string][]string, []string) {
+	recipes := make(map[string][]string)
+	var order []string
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			if current != "" {
+				recipes[current] = append(recipes[current], strings.TrimSpace(line))
+			}
+			continue
+		}
+		current = ""
+		name, rest, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(rest, "=") || strings.HasPrefix(name, "#") || strings.ContainsAny(name, "=%") {
+			continue
+		}
+		for _, target := range strings.Fields(name) {
+			if strings.HasPrefix(target, ".") {
+				continue
+			}
+			if _, ok := recipes[target]; !ok {
+				order = append(order, target)
+				recipes[target] = nil
+			}
+			current = target
+		}
+	}
+	return recipes, order
+}
+
+// ansible plays a playbook as one task that succeeds on every host of the inventory
+func (e *Environment) ansible(c *call) error {
+	f := parseFlags(c.Args, "i", "inventory", "e", "extra-vars", "l", "limit", "t", "tags")
+	hosts := inventoryHosts(c, f.get("i", "inventory"))
+	if limit := f.get("l", "limit"); limit != "" {
+		hosts = strings.Split(limit, ",")
+	}
+	for _, playbook := range f.positional {
+		c.printf("\nPLAY [%s] %s\n", strings.TrimSuffix(filepath.Base(playbook), filepath.Ext(playbook)), strings.Repeat("*", 40))
+		c.printf("\nTASK [Gathering Facts] %s\n", strings.Repeat("*", 40))
+		for _, host := range hosts {
+			c.printf("ok: [%s]\n", host)
+		}
+		c.printf("\nTASK [Apply configuration] %s\n", strings.Repeat("*", 36))
+		for _, host := range hosts {
+			c.printf("changed: [%s]\n", host)
+		}
+	}
+	c.printf("\nPLAY RECAP %s\n", strings.Repeat("*", 50))
+	for _, host := range hosts {
+		c.printf("%-26s : ok=%d    changed=%d    unreachable=0    failed=0    skipped=0    rescued=0    ignored=0\n",
+			host, 2*len(f.positional), len(f.positional))
+	}
+	return nil
+}
+
+// inventoryHosts lists the hosts of an INI inventory, localhost without one
+func inventoryHosts(c *call, inventory string) []string {
+	if inventory == "" {
+		return []string{"localhost"}
+	}
+	if !filepath.IsAbs(inventory) {
+		inventory = filepath.Join(c.Dir, inventory)
+	}
+	data, err := os.ReadFile(inventory)
+	if err != nil {
+		// A comma-separated host list
+		return strings.Split(strings.TrimSuffix(filepath.Base(inventory), ","), ",")
+	}
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "#") || strings.ContainsAny(fields[0], "=:") {
+			continue
+		}
+		if !seen[fields[0]] {
+			seen[fields[0]] = true
+			hosts = append(hosts, fields[0])
+		}
+	}
+	if len(hosts) == 0 {
+		return []string{"localhost"}
+	}
+	return hosts
+}
+
+// infracost prices each resource of the simulated plans at a flat monthly cost
+func (e *Environment) infracost(c *call) error {
+	resources := 0
+	for _, state := range e.Terraform {
+		resources += len(state.Resources)
+	}
+	resources = max(resources, len(defaultResources))
+	return printJSON(c, map[string]interface{}{
+		"currency":             "USD",
+		"totalMonthlyCost":     fmt.Sprintf("%.2f", float64(resources)*73),
+		"pastTotalMonthlyCost": "0",
+		"projects":             []interface{}{},
+	})
+}
+
+// Service is a simulated managed database or cache
+type Service struct {
+	Kind     string `json:"kind"` // database or cache
+	Provider string `json:"provider"`
+	Engine   string `json:"engine"`
+}
+
+// cloud answers the aws, az and gcloud commands the installer makes: managed services
+// and the node pool and control plane operations of cluster upgrades and scaling
+func (e *Environment) cloud(tool string, c *call) error {
+	f := parseFlags(c.Args, "db-instance-identifier", "cache-cluster-id", "engine", "name", "nodegroup-name",
+		"cluster-name", "cluster", "kubernetes-version", "cluster-version", "node-count", "num-nodes", "node-pool",
+		"scaling-config", "resource-group", "region", "output", "profile", "subscription", "project", "cli-input-json")
+	endpoint, _ := url.Parse(e.endpoint.URL)
+	host, portText, _ := net.SplitHostPort(endpoint.Host)
+	port, _ := strconv.Atoi(portText)
+	command := strings.Join(f.positional[:min(len(f.positional), 3)], " ")
+
+	switch tool {
+	case "aws":
+		switch command {
+		case "rds create-db-instance":
+			return e.createService(c, "database", tool, cliInputName(f.get("cli-input-json"), "DBInstanceIdentifier"), "")
+		case "elasticache create-cache-cluster":
+			return e.createService(c, "cache", tool, f.get("cache-cluster-id"), f.get("engine"))
+		case "rds delete-db-instance":
+			return e.deleteService(c, f.get("db-instance-identifier"), "DBInstanceNotFound")
+		case "elasticache delete-cache-cluster":
+			return e.deleteService(c, f.get("cache-cluster-id"), "CacheClusterNotFound")
+		case "rds describe-db-instances":
+			if e.Services[f.get("db-instance-identifier")] == nil {
+				return c.fail("An error occurred (DBInstanceNotFound) when calling the DescribeDBInstances operation: DBInstance %s not found.", f.get("db-instance-identifier"))
+			}
+			return printJSON(c, map[string]interface{}{"DBInstances": []interface{}{map[string]interface{}{
+				"DBInstanceStatus": "available",
+				"Endpoint":         map[string]interface{}{"Address": host, "Port": port},
+			}}})
+		case "elasticache describe-cache-clusters":
+			if e.Services[f.get("cache-cluster-id")] == nil {
+				return c.fail("An error occurred (CacheClusterNotFound) when calling the DescribeCacheClusters operation: CacheCluster %s not found.", f.get("cache-cluster-id"))
+			}
+			return printJSON(c, map[string]interface{}{"CacheClusters": []interface{}{map[string]interface{}{
+				"CacheClusterStatus": "available",
+				"CacheNodes":         []interface{}{map[string]interface{}{"Endpoint": map[string]interface{}{"Address": host, "Port": port}}},
+			}}})
+		}
+		switch f.arg(0) + " " + f.arg(1) {
+		case "eks update-cluster-version":
+			e.Cluster.Version = version(f.get("kubernetes-version"))
+			return e.eksUpdate(c)
+		case "eks update-nodegroup-version":
+			e.Cluster.upgradeNodes(f.get("nodegroup-name"), f.get("kubernetes-version"))
+			return e.eksUpdate(c)
+		case "eks update-nodegroup-config":
+			for _, setting := range strings.Split(f.get("scaling-config"), ",") {
+				if key, value, _ := strings.Cut(setting, "="); key == "desiredSize" {
+					count, _ := strconv.Atoi(value)
+					e.Cluster.scalePool(f.get("nodegroup-name"), count)
+				}
+			}
+			return e.eksUpdate(c)
+		case "eks describe-update":
+			return printJSON(c, map[string]interface{}{"update": map[string]interface{}{"status": "Successful", "errors": []interface{}{}}})
+		case "eks describe-nodegroup":
+			nodes := len(e.Cluster.poolNodes(f.get("nodegroup-name")))
+			return printJSON(c, map[string]interface{}{"nodegroup": map[string]interface{}{
+				"status":        "ACTIVE",
+				"scalingConfig": map[string]interface{}{"minSize": min(nodes, 1), "maxSize": max(nodes, defaultNodes), "desiredSize": nodes},
+			}})
+		}
+	case "az":
+		switch {
+		case f.arg(1) == "flexible-server" || f.arg(0) == "redis":
+			kind := "database"
+			verb := f.arg(2)
+			if f.arg(0) == "redis" {
+				kind, verb = "cache", f.arg(1)
+			}
+			name := f.get("name")
+			switch verb {
+			case "create":
+				return e.createService(c, kind, tool, name, f.arg(0))
+			case "delete":
+				return e.deleteService(c, name, "ResourceNotFound")
+			case "show":
+				if e.Services[name] == nil {
+					return c.fail("(ResourceNotFound) The Resource '%s' under resource group '%s' was not found.", name, f.get("resource-group"))
+				}
+				if kind == "cache" {
+					return printJSON(c, map[string]interface{}{"provisioningState": "Succeeded", "hostName": host, "sslPort": port})
+				}
+				return printJSON(c, map[string]interface{}{"state": "Ready", "fullyQualifiedDomainName": host})
+			}
+		case command == "aks nodepool scale":
+			count, _ := strconv.Atoi(f.get("node-count"))
+			e.Cluster.scalePool(f.get("name"), count)
+		case command == "aks nodepool upgrade":
+			e.Cluster.upgradeNodes(f.get("name"), f.get("kubernetes-version"))
+		case command == "aks upgrade":
+			e.Cluster.Version = version(f.get("kubernetes-version"))
+		}
+	case "gcloud":
+		switch {
+		case (f.arg(0) == "sql" || f.arg(0) == "redis") && f.arg(1) == "instances":
+			kind := "database"
+			if f.arg(0) == "redis" {
+				kind = "cache"
+			}
+			name := f.arg(3)
+			switch f.arg(2) {
+			case "create":
+				return e.createService(c, kind, tool, name, "")
+			case "delete":
+				return e.deleteService(c, name, "NOT_FOUND")
+			case "describe":
+				if e.Services[name] == nil {
+					return c.fail("ERROR: (gcloud.%s.instances.describe) NOT_FOUND: The resource '%s' was not found", f.arg(0), name)
+				}
+				if kind == "cache" {
+					return printJSON(c, map[string]interface{}{"state": "READY", "host": host, "port": port})
+				}
+				return printJSON(c, map[string]interface{}{"state": "RUNNABLE", "ipAddresses": []interface{}{map[string]interface{}{"ipAddress": host, "type": "PRIMARY"}}})
+			}
+		case command == "container clusters resize":
+			count, _ := strconv.Atoi(f.get("num-nodes"))
+			e.Cluster.scalePool(f.get("node-pool"), count)
+		case command == "container clusters upgrade":
+			if f.has("master") {
+				e.Cluster.Version = version(f.get("cluster-version"))
+			} else {
+				e.Cluster.upgradeNodes(f.get("node-pool"), f.get("cluster-version"))
+			}
+		}
+	}
+	c.printf("{}\n")
+	return nil
+}
+
+// createService records a managed service, failing like the provider does when it exists
+func (e *Environment) createService(c *call, kind, provider, name, engine string) error {
+	if name == "" {
+		return c.fail("error: the service name is required")
+	}
+	if e.Services[name] != nil {
+		return c.fail("error: %s %s already exists", kind, name)
+	}
+	e.Services[name] = &Service{Kind: kind, Provider: provider, Engine: engine}
+	c.printf("{}\n")
+	return nil
+}
+
+func (e *Environment) deleteService(c *call, name, notFound string) error {
+	if e.Services[name] == nil {
+		return c.fail("error: (%s) %s not found", notFound, name)
+	}
+	delete(e.Services, name)
+	c.printf("{}\n")
+	return nil
+}
+
+// eksUpdate prints the response of an EKS update call, which describe-update then reports done
+func (e *Environment) eksUpdate(c *call) error {
+	return printJSON(c, map[string]interface{}{"update": map[string]interface{}{"id": e.nextID("update"), "status": "InProgress"}})
+}
+
+// cliInputName reads the identifier from a --cli-input-json file://path argument
+func cliInputName(input, field string) string {
+	data, err := os.ReadFile(strings.TrimPrefix(input, "file://"))
+	if err != nil {
+		return ""
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return ""
+	}
+	return str(document, field)
+}
+
+func version(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
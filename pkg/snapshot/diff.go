@@ -0,0 +1,221 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ChangeType describes how an item differs between two snapshots
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a single difference between two snapshots
+type Change struct {
+	Type     ChangeType `json:"type"`
+	Object   string     `json:"object"`
+	Field    string     `json:"field"`
+	OldValue string     `json:"oldValue,omitempty"`
+	NewValue string     `json:"newValue,omitempty"`
+}
+
+// Diff compares two snapshots and returns the changes from a to b
+func Diff(a, b *Snapshot) []Change {
+	var changes []Change
+
+	changes = append(changes, diffReleases(a.Releases, b.Releases)...)
+	changes = append(changes, diffResources(a.Resources, b.Resources)...)
+
+	return changes
+}
+
+func diffReleases(oldReleases, newReleases []ReleaseSnapshot) []Change {
+	var changes []Change
+
+	oldByKey := make(map[string]ReleaseSnapshot)
+	for _, r := range oldReleases {
+		oldByKey[r.Namespace+"/"+r.Name] = r
+	}
+	newByKey := make(map[string]ReleaseSnapshot)
+	for _, r := range newReleases {
+		newByKey[r.Namespace+"/"+r.Name] = r
+	}
+
+	for _, key := range sortedKeys(oldByKey, newByKey) {
+		object := "release/" + key
+		oldRel, inOld := oldByKey[key]
+		newRel, inNew := newByKey[key]
+
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Object: object, Field: "release", NewValue: newRel.Version})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Object: object, Field: "release", OldValue: oldRel.Version})
+		default:
+			if oldRel.Version != newRel.Version {
+				changes = append(changes, modified(object, "version", oldRel.Version, newRel.Version))
+			}
+			if oldRel.Chart != newRel.Chart {
+				changes = append(changes, modified(object, "chart", oldRel.Chart, newRel.Chart))
+			}
+			changes = append(changes, diffValues(object, "values", oldRel.Values, newRel.Values)...)
+		}
+	}
+
+	return changes
+}
+
+func diffResources(oldResources, newResources []Resource) []Change {
+	var changes []Change
+
+	oldByKey := make(map[string]Resource)
+	for _, r := range oldResources {
+		oldByKey[r.Key()] = r
+	}
+	newByKey := make(map[string]Resource)
+	for _, r := range newResources {
+		newByKey[r.Key()] = r
+	}
+
+	for _, key := range sortedKeys(oldByKey, newByKey) {
+		oldRes, inOld := oldByKey[key]
+		newRes, inNew := newByKey[key]
+
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Object: key, Field: "resource"})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Object: key, Field: "resource"})
+		default:
+			if formatReplicas(oldRes.Replicas) != formatReplicas(newRes.Replicas) {
+				changes = append(changes, modified(key, "replicas", formatReplicas(oldRes.Replicas), formatReplicas(newRes.Replicas)))
+			}
+			changes = append(changes, diffContainers(key, oldRes.Containers, newRes.Containers)...)
+		}
+	}
+
+	return changes
+}
+
+func diffContainers(object string, oldContainers, newContainers []Container) []Change {
+	var changes []Change
+
+	oldByName := make(map[string]Container)
+	for _, c := range oldContainers {
+		oldByName[c.Name] = c
+	}
+	newByName := make(map[string]Container)
+	for _, c := range newContainers {
+		newByName[c.Name] = c
+	}
+
+	for _, name := range sortedKeys(oldByName, newByName) {
+		field := "container/" + name
+		oldC, inOld := oldByName[name]
+		newC, inNew := newByName[name]
+
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Object: object, Field: field, NewValue: newC.Image})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Object: object, Field: field, OldValue: oldC.Image})
+		default:
+			if oldC.Image != newC.Image {
+				changes = append(changes, modified(object, field+"/image", oldC.Image, newC.Image))
+			}
+			for _, envName := range sortedKeys(oldC.Env, newC.Env) {
+				oldVal, inOldEnv := oldC.Env[envName]
+				newVal, inNewEnv := newC.Env[envName]
+				envField := field + "/env/" + envName
+				switch {
+				case !inOldEnv:
+					changes = append(changes, Change{Type: ChangeAdded, Object: object, Field: envField, NewValue: newVal})
+				case !inNewEnv:
+					changes = append(changes, Change{Type: ChangeRemoved, Object: object, Field: envField, OldValue: oldVal})
+				case oldVal != newVal:
+					changes = append(changes, modified(object, envField, oldVal, newVal))
+				}
+			}
+		}
+	}
+
+	return changes
+}
+
+// DiffValues compares two sets of chart values of an object, reporting leaf changes with
+// dotted paths under values
+func DiffValues(object string, oldValues, newValues map[string]interface{}) []Change {
+	return diffValues(object, "values", oldValues, newValues)
+}
+
+// diffValues compares chart values recursively, reporting leaf changes with dotted paths
+func diffValues(object, path string, oldValues, newValues map[string]interface{}) []Change {
+	var changes []Change
+
+	for _, key := range sortedKeys(oldValues, newValues) {
+		field := path + "." + key
+		oldVal, inOld := oldValues[key]
+		newVal, inNew := newValues[key]
+
+		oldMap, oldIsMap := oldVal.(map[string]interface{})
+		newMap, newIsMap := newVal.(map[string]interface{})
+
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Object: object, Field: field, NewValue: formatValue(newVal)})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Object: object, Field: field, OldValue: formatValue(oldVal)})
+		case oldIsMap && newIsMap:
+			changes = append(changes, diffValues(object, field, oldMap, newMap)...)
+		case formatValue(oldVal) != formatValue(newVal):
+			changes = append(changes, modified(object, field, formatValue(oldVal), formatValue(newVal)))
+		}
+	}
+
+	return changes
+}
+
+func modified(object, field, oldValue, newValue string) Change {
+	return Change{Type: ChangeModified, Object: object, Field: field, OldValue: oldValue, NewValue: newValue}
+}
+
+func formatReplicas(replicas *int) string {
+	if replicas == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *replicas)
+}
+
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// sortedKeys returns the union of keys of both maps in sorted order
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
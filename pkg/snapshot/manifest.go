@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// volatileLabels change on every run and would otherwise show every object as modified. They
+// are only set on the metadata of objects, so pod templates are compared in full.
+var volatileLabels = []string{"installer-run-id"}
+
+// DiffManifests compares the live manifest of a release with a newly rendered one and
+// returns the changes needed to go from live to rendered, field by field
+func DiffManifests(live, rendered []byte) ([]Change, error) {
+	oldObjects, err := parseManifest(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse live manifest: %w", err)
+	}
+	newObjects, err := parseManifest(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rendered manifest: %w", err)
+	}
+
+	var changes []Change
+	for _, key := range sortedKeys(oldObjects, newObjects) {
+		oldObj, inOld := oldObjects[key]
+		newObj, inNew := newObjects[key]
+
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Object: key, Field: "resource"})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Object: key, Field: "resource"})
+		default:
+			for _, change := range diffValues(key, "", oldObj, newObj) {
+				change.Field = strings.TrimPrefix(change.Field, ".")
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// parseManifest splits a multi-document manifest into objects keyed kind/namespace/name
+func parseManifest(manifest []byte) (map[string]map[string]interface{}, error) {
+	objects := make(map[string]map[string]interface{})
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(object) == 0 {
+			continue
+		}
+
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			stripVolatileLabels(metadata)
+		}
+
+		metadata, _ := object["metadata"].(map[string]interface{})
+		kind, _ := object["kind"].(string)
+		name, _ := metadata["name"].(string)
+		namespace, _ := metadata["namespace"].(string)
+		// Charts usually leave the namespace to the release, so objects may have none
+		objects[strings.TrimPrefix(Resource{Namespace: namespace, Kind: kind, Name: name}.Key(), "/")] = object
+	}
+	return objects, nil
+}
+
+// stripVolatileLabels removes volatile labels from the metadata of an object
+func stripVolatileLabels(metadata map[string]interface{}) {
+	labels, ok := metadata["labels"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, label := range volatileLabels {
+		delete(labels, label)
+	}
+	if len(labels) == 0 {
+		delete(metadata, "labels")
+	}
+}
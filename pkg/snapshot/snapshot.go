@@ -0,0 +1,241 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// workloadKinds are the resource kinds captured in a snapshot
+var workloadKinds = []string{"deployments", "statefulsets", "daemonsets"}
+
+// Snapshot is a normalized record of the resources deployed by one run
+type Snapshot struct {
+	RunID      string            `json:"runId"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Namespaces []string          `json:"namespaces"`
+	Releases   []ReleaseSnapshot `json:"releases"`
+	Resources  []Resource        `json:"resources"`
+}
+
+// ReleaseSnapshot records the chart and values used for a release
+type ReleaseSnapshot struct {
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Chart     string                 `json:"chart,omitempty"`
+	Version   string                 `json:"version,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+// Resource is a normalized workload spec
+type Resource struct {
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace"`
+	Name       string      `json:"name"`
+	Replicas   *int        `json:"replicas,omitempty"`
+	Containers []Container `json:"containers"`
+}
+
+// Container is a normalized container spec
+type Container struct {
+	Name  string            `json:"name"`
+	Image string            `json:"image"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+// Key returns the unique identifier of the resource within a snapshot
+func (r Resource) Key() string {
+	return fmt.Sprintf("%s/%s/%s", r.Namespace, strings.ToLower(r.Kind), r.Name)
+}
+
+// kubeList mirrors the subset of a kubectl JSON list needed for normalization
+type kubeList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+			Template struct {
+				Spec struct {
+					InitContainers []kubeContainer `json:"initContainers"`
+					Containers     []kubeContainer `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type kubeContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Env   []struct {
+		Name      string                 `json:"name"`
+		Value     string                 `json:"value"`
+		ValueFrom map[string]interface{} `json:"valueFrom"`
+	} `json:"env"`
+}
+
+// New creates an empty snapshot for the given run
+func New(runID string) *Snapshot {
+	return &Snapshot{
+		RunID:      runID,
+		Timestamp:  time.Now().UTC(),
+		Namespaces: []string{},
+		Releases:   []ReleaseSnapshot{},
+		Resources:  []Resource{},
+	}
+}
+
+// AddRelease records a deployed release in the snapshot
+func (s *Snapshot) AddRelease(release ReleaseSnapshot) {
+	s.Releases = append(s.Releases, release)
+	s.addNamespace(release.Namespace)
+}
+
+// CaptureNamespace reads workloads from the cluster and adds their normalized specs
+func (s *Snapshot) CaptureNamespace(k8sMgr *k8s.Manager, namespace string) error {
+	output, err := k8sMgr.GetResourcesJSON(namespace, workloadKinds)
+	if err != nil {
+		return fmt.Errorf("failed to capture resources in namespace %s: %w", namespace, err)
+	}
+
+	resources, err := normalize(output)
+	if err != nil {
+		return fmt.Errorf("failed to normalize resources in namespace %s: %w", namespace, err)
+	}
+
+	s.Resources = append(s.Resources, resources...)
+	s.addNamespace(namespace)
+
+	logger.Info("Captured namespace snapshot").
+		Str("namespace", namespace).
+		Int("resources", len(resources)).
+		Send()
+
+	return nil
+}
+
+// Save writes the snapshot as JSON into the given directory
+func (s *Snapshot) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	s.sort()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, s.RunID+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return path, nil
+}
+
+// Load reads a snapshot by run ID from the given directory, or from a direct file path
+func Load(dir, runID string) (*Snapshot, error) {
+	path := runID
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(dir, runID+".json")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot for run %s not found: %w", runID, err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+
+	return &snap, nil
+}
+
+// normalize converts kubectl list output into stable resource specs
+func normalize(data []byte) ([]Resource, error) {
+	var list kubeList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	resources := make([]Resource, 0, len(list.Items))
+	for _, item := range list.Items {
+		resource := Resource{
+			Kind:      item.Kind,
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Replicas:  item.Spec.Replicas,
+		}
+
+		podSpec := item.Spec.Template.Spec
+		for _, c := range append(podSpec.InitContainers, podSpec.Containers...) {
+			container := Container{Name: c.Name, Image: c.Image}
+			if len(c.Env) > 0 {
+				container.Env = make(map[string]string, len(c.Env))
+				for _, env := range c.Env {
+					value := env.Value
+					if env.ValueFrom != nil {
+						value = describeValueFrom(env.ValueFrom)
+					}
+					container.Env[env.Name] = value
+				}
+			}
+			resource.Containers = append(resource.Containers, container)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// describeValueFrom renders an env valueFrom reference without resolving secrets
+func describeValueFrom(valueFrom map[string]interface{}) string {
+	for source, ref := range valueFrom {
+		if refMap, ok := ref.(map[string]interface{}); ok {
+			name, _ := refMap["name"].(string)
+			key, _ := refMap["key"].(string)
+			if fieldPath, ok := refMap["fieldPath"].(string); ok {
+				return fmt.Sprintf("<%s:%s>", source, fieldPath)
+			}
+			return fmt.Sprintf("<%s:%s/%s>", source, name, key)
+		}
+		return fmt.Sprintf("<%s>", source)
+	}
+	return "<valueFrom>"
+}
+
+func (s *Snapshot) addNamespace(namespace string) {
+	for _, ns := range s.Namespaces {
+		if ns == namespace {
+			return
+		}
+	}
+	s.Namespaces = append(s.Namespaces, namespace)
+}
+
+// sort orders snapshot contents so saved files are stable between runs
+func (s *Snapshot) sort() {
+	sort.Strings(s.Namespaces)
+	sort.Slice(s.Releases, func(i, j int) bool {
+		return s.Releases[i].Namespace+"/"+s.Releases[i].Name < s.Releases[j].Namespace+"/"+s.Releases[j].Name
+	})
+	sort.Slice(s.Resources, func(i, j int) bool {
+		return s.Resources[i].Key() < s.Resources[j].Key()
+	})
+}
@@ -0,0 +1,344 @@
+package soak
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Result is an SLO evaluated over the samples taken so far
+type Result struct {
+	Name            string  `json:"name"`
+	Source          string  `json:"source"` // prometheus or probe
+	Objective       float64 `json:"objective"`
+	MaxBurnRate     float64 `json:"maxBurnRate"`
+	Samples         int     `json:"samples"`
+	FailedSamples   int     `json:"failedSamples"`
+	ErrorRatio      float64 `json:"errorRatio"`
+	SlowSamples     int     `json:"slowSamples"`
+	MaxLatency      string  `json:"maxObservedLatency,omitempty"`
+	ErrorBurnRate   float64 `json:"errorBurnRate"`
+	LatencyBurnRate float64 `json:"latencyBurnRate"`
+	Passed          bool    `json:"passed"`
+	Reason          string  `json:"reason,omitempty"`
+}
+
+// BurnRate is the larger of the error and latency burn rates
+func (r Result) BurnRate() float64 {
+	if r.LatencyBurnRate > r.ErrorBurnRate {
+		return r.LatencyBurnRate
+	}
+	return r.ErrorBurnRate
+}
+
+// slo is a configured objective with its accumulated samples
+type slo struct {
+	config.SLO
+	maxLatency time.Duration
+
+	samples    int
+	failed     int // probe errors, or failed Prometheus queries
+	errorSum   float64
+	errorCount int
+	slow       int
+	latencies  int
+	worst      time.Duration
+	lastError  string
+}
+
+// Runner samples the SLOs of a soak configuration
+type Runner struct {
+	prometheus string
+	interval   time.Duration
+	client     *http.Client
+	slos       []*slo
+}
+
+// NewRunner creates a runner, querying prometheus for SLOs that do not probe a URL
+func NewRunner(cfg config.SoakConfig, prometheus string) (*Runner, error) {
+	if cfg.Prometheus != "" {
+		prometheus = cfg.Prometheus
+	}
+	runner := &Runner{
+		prometheus: strings.TrimSuffix(prometheus, "/"),
+		interval:   30 * time.Second,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg.Interval != "" {
+		interval, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid soak interval: %w", err)
+		}
+		runner.interval = interval
+	}
+	if len(cfg.SLOs) == 0 {
+		return nil, fmt.Errorf("the soak phase needs at least one SLO")
+	}
+
+	for _, objective := range cfg.SLOs {
+		s := &slo{SLO: objective}
+		if s.MaxBurnRate == 0 {
+			s.MaxBurnRate = 1
+		}
+		switch {
+		case s.URL != "" && (s.ErrorQuery != "" || s.LatencyQuery != ""):
+			return nil, fmt.Errorf("SLO %s sets both a url and Prometheus queries", s.Name)
+		case s.URL == "" && s.ErrorQuery == "" && s.LatencyQuery == "":
+			return nil, fmt.Errorf("SLO %s needs a url, an errorQuery or a latencyQuery", s.Name)
+		case s.URL == "" && runner.prometheus == "":
+			return nil, fmt.Errorf("SLO %s queries Prometheus but no Prometheus endpoint is configured", s.Name)
+		case s.LatencyQuery != "" && s.MaxLatency == "":
+			return nil, fmt.Errorf("SLO %s has a latencyQuery without maxLatency", s.Name)
+		}
+		if s.MaxLatency != "" {
+			maxLatency, err := time.ParseDuration(s.MaxLatency)
+			if err != nil {
+				return nil, fmt.Errorf("invalid maxLatency for SLO %s: %w", s.Name, err)
+			}
+			s.maxLatency = maxLatency
+		}
+		runner.slos = append(runner.slos, s)
+	}
+	return runner, nil
+}
+
+// Run samples every SLO each interval for duration, calling progress after each round. It
+// returns early once an SLO has burned more than its whole budget for the soak, since the
+// remaining samples could no longer bring it back under the threshold.
+func (r *Runner) Run(ctx context.Context, duration time.Duration, progress func(elapsed time.Duration, results []Result)) []Result {
+	start := time.Now()
+	deadline := start.Add(duration)
+	rounds := int(duration / r.interval)
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	for {
+		r.sample(ctx)
+		results := r.Results()
+		if progress != nil {
+			progress(time.Since(start), results)
+		}
+		if r.exhausted(rounds) || !time.Now().Add(r.interval).Before(deadline) {
+			return results
+		}
+
+		select {
+		case <-ctx.Done():
+			return results
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// sample takes one sample of every SLO concurrently
+func (r *Runner) sample(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range r.slos {
+		wg.Add(1)
+		go func(s *slo) {
+			defer wg.Done()
+			if s.URL != "" {
+				r.probe(ctx, s)
+			} else {
+				r.query(ctx, s)
+			}
+			s.samples++
+		}(s)
+	}
+	wg.Wait()
+}
+
+// probe requests the SLO's URL, counting transport errors and 5xx responses as failures
+func (r *Runner) probe(ctx context.Context, s *slo) {
+	start := time.Now()
+	err := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}()
+	s.observeLatency(time.Since(start))
+	if err != nil {
+		s.failed++
+		s.lastError = err.Error()
+		logger.Debug("Soak probe failed").Str("slo", s.Name).Err(err).Send()
+	}
+}
+
+// query evaluates the SLO's PromQL queries
+func (r *Runner) query(ctx context.Context, s *slo) {
+	if s.ErrorQuery != "" {
+		ratio, err := Query(ctx, r.client, r.prometheus, s.ErrorQuery)
+		if err != nil {
+			s.failed++
+			s.lastError = err.Error()
+			logger.Warn("Soak error query failed").Str("slo", s.Name).Err(err).Send()
+		} else {
+			s.errorSum += ratio
+			s.errorCount++
+		}
+	}
+	if s.LatencyQuery != "" {
+		seconds, err := Query(ctx, r.client, r.prometheus, s.LatencyQuery)
+		if err != nil {
+			s.lastError = err.Error()
+			logger.Warn("Soak latency query failed").Str("slo", s.Name).Err(err).Send()
+			return
+		}
+		s.observeLatency(time.Duration(seconds * float64(time.Second)))
+	}
+}
+
+func (s *slo) observeLatency(latency time.Duration) {
+	s.latencies++
+	if latency > s.worst {
+		s.worst = latency
+	}
+	if s.maxLatency > 0 && latency > s.maxLatency {
+		s.slow++
+	}
+}
+
+// exhausted reports whether an SLO already has more bad samples than the whole soak may hold
+func (r *Runner) exhausted(rounds int) bool {
+	for _, s := range r.slos {
+		budget := (1 - s.Objective) * s.MaxBurnRate * float64(rounds)
+		if s.URL != "" && float64(s.failed) > budget && s.failed > 0 {
+			return true
+		}
+		if float64(s.slow) > budget && s.slow > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Results evaluates every SLO over the samples taken so far
+func (r *Runner) Results() []Result {
+	results := make([]Result, 0, len(r.slos))
+	for _, s := range r.slos {
+		budget := 1 - s.Objective
+		result := Result{
+			Name:          s.Name,
+			Source:        "prometheus",
+			Objective:     s.Objective,
+			MaxBurnRate:   s.MaxBurnRate,
+			Samples:       s.samples,
+			FailedSamples: s.failed,
+			SlowSamples:   s.slow,
+		}
+		if s.URL != "" {
+			result.Source = "probe"
+			if s.samples > 0 {
+				result.ErrorRatio = float64(s.failed) / float64(s.samples)
+			}
+		} else if s.errorCount > 0 {
+			result.ErrorRatio = s.errorSum / float64(s.errorCount)
+		}
+		if s.latencies > 0 {
+			result.MaxLatency = s.worst.Round(time.Millisecond).String()
+			result.LatencyBurnRate = float64(s.slow) / float64(s.latencies) / budget
+		}
+		result.ErrorBurnRate = result.ErrorRatio / budget
+
+		switch {
+		case s.URL == "" && s.ErrorQuery != "" && s.errorCount == 0 && s.samples > 0:
+			result.Reason = fmt.Sprintf("no successful Prometheus query: %s", s.lastError)
+		case result.ErrorBurnRate > s.MaxBurnRate:
+			result.Reason = fmt.Sprintf("error burn rate %.2f exceeds %.2f", result.ErrorBurnRate, s.MaxBurnRate)
+		case result.LatencyBurnRate > s.MaxBurnRate:
+			result.Reason = fmt.Sprintf("latency burn rate %.2f exceeds %.2f (%d samples over %s)", result.LatencyBurnRate, s.MaxBurnRate, s.slow, s.maxLatency)
+		default:
+			result.Passed = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Query runs an instant PromQL query and returns the first value of its vector or scalar
+// result. An empty vector means nothing matched, as when no request failed, and returns 0.
+func Query(ctx context.Context, client *http.Client, prometheus, query string) (float64, error) {
+	endpoint := prometheus + "/api/v1/query?query=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("prometheus query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to parse prometheus response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed: %s", body.Error)
+	}
+
+	var value []interface{}
+	switch body.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(body.Data.Result, &value); err != nil {
+			return 0, fmt.Errorf("failed to parse prometheus scalar: %w", err)
+		}
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(body.Data.Result, &vector); err != nil {
+			return 0, fmt.Errorf("failed to parse prometheus vector: %w", err)
+		}
+		if len(vector) == 0 {
+			return 0, nil
+		}
+		value = vector[0].Value
+	default:
+		return 0, fmt.Errorf("unsupported prometheus result type %s", body.Data.ResultType)
+	}
+
+	if len(value) != 2 {
+		return 0, fmt.Errorf("unexpected prometheus sample %v", value)
+	}
+	text, _ := value[1].(string)
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("prometheus returned a non-numeric sample %q", text)
+	}
+	// A ratio over no requests is NaN; without traffic nothing failed
+	if math.IsNaN(number) {
+		return 0, nil
+	}
+	return number, nil
+}
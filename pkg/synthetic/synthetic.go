@@ -0,0 +1,309 @@
+package synthetic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// StepResult is the outcome of one HTTP call of a transaction
+type StepResult struct {
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Status   int    `json:"status,omitempty"`
+	Duration string `json:"duration"`
+	Passed   bool   `json:"passed"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result is the outcome of a transaction
+type Result struct {
+	Name     string       `json:"name"`
+	Passed   bool         `json:"passed"`
+	Duration string       `json:"duration"`
+	Steps    []StepResult `json:"steps"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// Runner executes synthetic transactions
+type Runner struct {
+	params  map[string]interface{}
+	secrets []string
+}
+
+// NewRunner creates a runner resolving {{param}} from params and masking secrets in errors
+func NewRunner(params map[string]interface{}, secrets []string) *Runner {
+	return &Runner{params: params, secrets: secrets}
+}
+
+// Run executes the steps of a transaction in order with a cookie jar of its own, so a
+// session started by a login step carries over to the steps after it
+func (r *Runner) Run(ctx context.Context, tx config.SyntheticTransaction) Result {
+	result := Result{Name: tx.Name}
+	start := time.Now()
+
+	timeout := 30 * time.Second
+	if tx.Timeout != "" {
+		parsed, err := time.ParseDuration(tx.Timeout)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid timeout: %v", err)
+			return result
+		}
+		timeout = parsed
+	}
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Timeout: timeout, Jar: jar}
+	vars := make(map[string]string)
+
+	for _, step := range tx.Steps {
+		if result.Error != "" && !step.Always {
+			result.Steps = append(result.Steps, StepResult{Name: step.Name, Method: method(step), Skipped: true})
+			continue
+		}
+
+		req, err := r.request(ctx, tx, step, vars)
+		if err != nil && result.Error != "" {
+			// A cleanup step that needs values the failed steps never extracted has nothing to clean up
+			result.Steps = append(result.Steps, StepResult{Name: step.Name, Method: method(step), Skipped: true, Error: r.mask(err.Error())})
+			continue
+		}
+		var stepResult StepResult
+		if err != nil {
+			stepResult = StepResult{Name: step.Name, Method: method(step), Error: r.mask(err.Error())}
+		} else {
+			stepResult = r.runStep(client, req, step, vars)
+		}
+		result.Steps = append(result.Steps, stepResult)
+		if !stepResult.Passed && result.Error == "" {
+			result.Error = fmt.Sprintf("step %s: %s", step.Name, stepResult.Error)
+		}
+		logger.Debug("Synthetic step finished").
+			Str("transaction", tx.Name).
+			Str("step", step.Name).
+			Int("status", stepResult.Status).
+			Bool("passed", stepResult.Passed).
+			Str("duration", stepResult.Duration).
+			Send()
+	}
+
+	result.Passed = result.Error == ""
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	return result
+}
+
+// request renders the URL, headers and body of a step
+func (r *Runner) request(ctx context.Context, tx config.SyntheticTransaction, step config.SyntheticStep, vars map[string]string) (*http.Request, error) {
+	target, err := r.render(step.Path, vars)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(target, "://") {
+		target = strings.TrimSuffix(tx.BaseURL, "/") + "/" + strings.TrimPrefix(target, "/")
+	}
+	body, err := r.render(step.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method(step), target, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	for _, headers := range []map[string]string{tx.Headers, step.Headers} {
+		for key, value := range headers {
+			rendered, err := r.render(value, vars)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set(key, rendered)
+		}
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// runStep sends a step's request and checks the response, extracting its variables on success
+func (r *Runner) runStep(client *http.Client, req *http.Request, step config.SyntheticStep, vars map[string]string) StepResult {
+	result := StepResult{Name: step.Name, Method: req.Method, URL: req.URL.String()}
+	fail := func(format string, args ...interface{}) StepResult {
+		result.Error = r.mask(fmt.Sprintf(format, args...))
+		return result
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Duration = time.Since(start).Round(time.Millisecond).String()
+		return fail("%v", err)
+	}
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Round(time.Millisecond).String()
+	result.Status = resp.StatusCode
+	if err != nil {
+		return fail("failed to read response: %v", err)
+	}
+
+	if step.ExpectedStatus != 0 && resp.StatusCode != step.ExpectedStatus {
+		return fail("expected status %d, got %d", step.ExpectedStatus, resp.StatusCode)
+	}
+	if step.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fail("expected a 2xx status, got %d", resp.StatusCode)
+	}
+	if step.MaxLatency != "" {
+		maxLatency, err := time.ParseDuration(step.MaxLatency)
+		if err != nil {
+			return fail("invalid maxLatency: %v", err)
+		}
+		if elapsed > maxLatency {
+			return fail("took %s, more than %s", result.Duration, step.MaxLatency)
+		}
+	}
+
+	response := newResponse(resp.Header, payload)
+	for _, assertion := range step.Assertions {
+		expected, err := r.render(assertion.Equals, vars)
+		if err != nil {
+			return fail("%v", err)
+		}
+		value, ok := response.lookup(assertion.Path)
+		switch {
+		case !ok:
+			return fail("%s is missing from the response", assertion.Path)
+		case assertion.Equals != "" && value != expected:
+			return fail("%s is %q, expected %q", assertion.Path, value, expected)
+		case assertion.Contains != "" && !strings.Contains(value, assertion.Contains):
+			return fail("%s does not contain %q", assertion.Path, assertion.Contains)
+		}
+	}
+	for name, path := range step.Extract {
+		value, ok := response.lookup(path)
+		if !ok {
+			return fail("cannot extract %s: %s is missing from the response", name, path)
+		}
+		vars[name] = value
+	}
+
+	result.Passed = true
+	return result
+}
+
+// render resolves the transaction variables and the env and param functions in text
+func (r *Runner) render(text string, vars map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("synthetic").Option("missingkey=error").Funcs(template.FuncMap{
+		"env": os.Getenv,
+		"param": func(key string) (interface{}, error) {
+			value, ok := r.params[key]
+			if !ok {
+				return nil, fmt.Errorf("parameter %s is not defined", key)
+			}
+			return value, nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %w", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+func (r *Runner) mask(text string) string {
+	for _, secret := range r.secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+func method(step config.SyntheticStep) string {
+	if step.Method == "" {
+		return http.MethodGet
+	}
+	return step.Method
+}
+
+// response gives access to the headers and the decoded JSON body of a step's response
+type response struct {
+	header http.Header
+	body   []byte
+	json   interface{}
+	isJSON bool
+}
+
+func newResponse(header http.Header, body []byte) *response {
+	r := &response{header: header, body: body}
+	r.isJSON = json.Unmarshal(body, &r.json) == nil
+	return r
+}
+
+// lookup resolves body, header:Name or a dotted JSON path, where numeric segments index arrays
+func (r *response) lookup(path string) (string, bool) {
+	if path == "body" {
+		return string(r.body), true
+	}
+	if name, ok := strings.CutPrefix(path, "header:"); ok {
+		values := r.header.Values(name)
+		return strings.Join(values, ", "), len(values) > 0
+	}
+	if !r.isJSON {
+		return "", false
+	}
+
+	node := r.json
+	for _, segment := range strings.Split(path, ".") {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			child, ok := v[segment]
+			if !ok {
+				return "", false
+			}
+			node = child
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", false
+			}
+			node = v[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := node.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded), true
+	}
+}
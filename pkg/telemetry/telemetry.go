@@ -0,0 +1,314 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/version"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+)
+
+// Environment variables controlling telemetry. Setting DisableEnv to a false value, or the
+// common DO_NOT_TRACK to 1, turns telemetry off regardless of the saved choice.
+const (
+	DisableEnv  = "E2E_INSTALLER_TELEMETRY"
+	EndpointEnv = "E2E_INSTALLER_TELEMETRY_URL"
+)
+
+// maxQueued bounds the events kept while the endpoint is unreachable; the oldest are dropped
+const maxQueued = 200
+
+// sendTimeout keeps an unreachable endpoint from delaying the end of a run
+const sendTimeout = 3 * time.Second
+
+// Endpoint is the vendor collector events are posted to, set by vendor builds with -ldflags "-X"
+var Endpoint string
+
+// Failure categories; error messages are never sent, only these
+const (
+	CategoryTimeout       = "timeout"
+	CategoryNetwork       = "network"
+	CategoryAuth          = "auth"
+	CategoryLicense       = "license"
+	CategoryConfiguration = "configuration"
+	CategoryTool          = "tool"
+	CategoryOther         = "other"
+)
+
+// Settings is the opt-in choice saved per user, outside any workspace
+type Settings struct {
+	Enabled   bool      `json:"enabled"`
+	InstallID string    `json:"installId,omitempty"` // random, identifies nothing but this installation
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+// Event is one anonymized run outcome. It holds no configuration, hostnames, paths or messages.
+type Event struct {
+	InstallID       string    `json:"installId"`
+	Version         string    `json:"version"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	Command         string    `json:"command"`
+	Status          string    `json:"status"`
+	FailureCategory string    `json:"failureCategory,omitempty"`
+	DurationMs      int64     `json:"durationMs"`
+	Steps           []Step    `json:"steps,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Step is the outcome of one installation step within a run
+type Step struct {
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	FailureCategory string `json:"failureCategory,omitempty"`
+	DurationMs      int64  `json:"durationMs"`
+}
+
+var (
+	stepsMu sync.Mutex
+	steps   []Step
+)
+
+// SettingsPath is where the opt-in choice is saved
+func SettingsPath() string {
+	return filepath.Join(workspace.Home(), "telemetry.json")
+}
+
+// QueueDir holds events not yet delivered
+func QueueDir() string {
+	return filepath.Join(workspace.Home(), "telemetry-queue")
+}
+
+// LoadSettings returns the saved choice, disabled when none was made
+func LoadSettings() (*Settings, error) {
+	data, err := os.ReadFile(SettingsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return &Settings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry settings: %w", err)
+	}
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry settings %s: %w", SettingsPath(), err)
+	}
+	return &settings, nil
+}
+
+// SetEnabled saves the opt-in choice. Enabling creates the random installation ID;
+// disabling discards it together with any queued events.
+func SetEnabled(enabled bool) (*Settings, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings.Enabled = enabled
+	settings.DecidedAt = time.Now().UTC()
+	if enabled && settings.InstallID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("failed to generate installation ID: %w", err)
+		}
+		settings.InstallID = hex.EncodeToString(id)
+	}
+	if !enabled {
+		settings.InstallID = ""
+		if err := os.RemoveAll(QueueDir()); err != nil {
+			return nil, fmt.Errorf("failed to clear telemetry queue: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal telemetry settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(SettingsPath()), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(SettingsPath()), err)
+	}
+	if err := os.WriteFile(SettingsPath(), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write telemetry settings: %w", err)
+	}
+	return settings, nil
+}
+
+// DisabledByEnvironment reports whether the environment overrides an opt-in
+func DisabledByEnvironment() bool {
+	if os.Getenv("DO_NOT_TRACK") == "1" {
+		return true
+	}
+	switch strings.ToLower(os.Getenv(DisableEnv)) {
+	case "0", "false", "off", "no":
+		return true
+	}
+	return false
+}
+
+// CollectorURL returns the endpoint events are sent to, empty when none is configured
+func CollectorURL() string {
+	if url := os.Getenv(EndpointEnv); url != "" {
+		return url
+	}
+	return Endpoint
+}
+
+// RecordStep adds an installation step outcome to the event of the current run
+func RecordStep(name string, duration time.Duration, err error) {
+	step := Step{Name: name, Status: "success", DurationMs: duration.Milliseconds()}
+	if err != nil {
+		step.Status, step.FailureCategory = "failure", Categorize(err)
+	}
+	stepsMu.Lock()
+	steps = append(steps, step)
+	stepsMu.Unlock()
+}
+
+// RecordRun queues the outcome of a command and tries to deliver every queued event. It does
+// nothing unless the user opted in, and never fails the run: problems are only logged at debug.
+func RecordRun(command string, duration time.Duration, err error) {
+	if DisabledByEnvironment() || CollectorURL() == "" {
+		return
+	}
+	settings, loadErr := LoadSettings()
+	if loadErr != nil || !settings.Enabled || settings.InstallID == "" {
+		return
+	}
+
+	stepsMu.Lock()
+	event := Event{
+		InstallID:  settings.InstallID,
+		Version:    version.Version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Command:    command,
+		Status:     "success",
+		DurationMs: duration.Milliseconds(),
+		Steps:      steps,
+		Timestamp:  time.Now().UTC(),
+	}
+	steps = nil
+	stepsMu.Unlock()
+	if err != nil {
+		event.Status, event.FailureCategory = "failure", Categorize(err)
+	}
+
+	if queueErr := enqueue(event); queueErr != nil {
+		logger.Debug("Failed to queue telemetry event").Err(queueErr).Send()
+		return
+	}
+	if _, flushErr := Flush(); flushErr != nil {
+		logger.Debug("Telemetry events queued for a later run").Err(flushErr).Send()
+	}
+}
+
+// Flush posts the queued events oldest first, stopping at the first failure so the rest
+// stay queued for the next run
+func Flush() (int, error) {
+	files, err := queuedFiles()
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	sent := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return sent, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, CollectorURL(), bytes.NewReader(data))
+		if err != nil {
+			return sent, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return sent, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return sent, fmt.Errorf("collector returned %s", resp.Status)
+		}
+		os.Remove(file)
+		sent++
+	}
+	return sent, nil
+}
+
+// Pending returns the number of queued events
+func Pending() int {
+	files, _ := queuedFiles()
+	return len(files)
+}
+
+func enqueue(event Event) error {
+	if err := os.MkdirAll(QueueDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.json", event.Timestamp.UnixNano())
+	if err := os.WriteFile(filepath.Join(QueueDir(), name), data, 0600); err != nil {
+		return err
+	}
+
+	files, err := queuedFiles()
+	if err != nil {
+		return err
+	}
+	for len(files) > maxQueued {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// queuedFiles lists the queued events, oldest first
+func queuedFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(QueueDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Categorize maps an error to a failure category without looking past well-known wording
+func Categorize(err error) string {
+	var netErr net.Error
+	var exitErr *exec.ExitError
+	message := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(message, "timed out") || strings.Contains(message, "timeout"):
+		return CategoryTimeout
+	case errors.As(err, &netErr) || strings.Contains(message, "connection refused") || strings.Contains(message, "no such host"):
+		return CategoryNetwork
+	case strings.Contains(message, "unauthorized") || strings.Contains(message, "forbidden") || strings.Contains(message, "permission"):
+		return CategoryAuth
+	case strings.Contains(message, "license"):
+		return CategoryLicense
+	case strings.Contains(message, "configuration") || strings.Contains(message, "validation failed"):
+		return CategoryConfiguration
+	case errors.As(err, &exitErr) || strings.Contains(message, "exit status"):
+		return CategoryTool
+	}
+	return CategoryOther
+}
@@ -0,0 +1,134 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// PlanFile is the saved plan cost estimation reads, kept in the working directory
+const PlanFile = "installer.tfplan"
+
+// CostEstimate is the projected monthly cost of a plan
+type CostEstimate struct {
+	Currency    string         `json:"currency"`
+	PastMonthly float64        `json:"pastMonthly"`
+	Monthly     float64        `json:"monthly"`
+	Delta       float64        `json:"delta"`
+	Resources   []ResourceCost `json:"resources,omitempty"`
+}
+
+// ResourceCost is the projected monthly cost of one resource
+type ResourceCost struct {
+	Name    string  `json:"name"`
+	Monthly float64 `json:"monthly"`
+}
+
+// infracostBreakdown mirrors the subset of `infracost breakdown --format json` used here
+type infracostBreakdown struct {
+	Currency             string `json:"currency"`
+	TotalMonthlyCost     string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost string `json:"pastTotalMonthlyCost"`
+	DiffTotalMonthlyCost string `json:"diffTotalMonthlyCost"`
+	Projects             []struct {
+		Breakdown struct {
+			Resources []struct {
+				Name        string `json:"name"`
+				MonthlyCost string `json:"monthlyCost"`
+			} `json:"resources"`
+		} `json:"breakdown"`
+	} `json:"projects"`
+}
+
+// CostEstimationEnabled reports whether plans are saved for cost estimation
+func (m *Manager) CostEstimationEnabled() bool {
+	return m.config.Terraform.CostEstimation.Enabled
+}
+
+// EstimateCost runs Infracost on the last saved plan
+func (m *Manager) EstimateCost() (*CostEstimate, error) {
+	infracost, err := execx.LookPath("infracost")
+	if err != nil {
+		return nil, fmt.Errorf("infracost not found in PATH: %w", err)
+	}
+
+	// Infracost evaluates a terragrunt tree itself; a terraform plan is exported first
+	planPath := m.workingDir
+	if !m.terragrunt() {
+		planJSON, err := m.PlanJSON()
+		if err != nil {
+			return nil, err
+		}
+		planPath = filepath.Join(m.workingDir, PlanFile+".json")
+		if err := os.WriteFile(planPath, planJSON, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write plan JSON: %w", err)
+		}
+		defer os.Remove(planPath)
+	}
+
+	logger.Info("Estimating infrastructure cost").Str("plan", planPath).Send()
+
+	cmd := execx.Command(infracost, "breakdown", "--path", planPath, "--format", "json", "--no-color")
+	cmd.Dir = m.workingDir
+	cmd.Timeout = m.timeout
+	output, err := cmd.Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("infracost breakdown failed: %w", err)
+	}
+	return parseInfracost(output)
+}
+
+func parseInfracost(output []byte) (*CostEstimate, error) {
+	var breakdown infracostBreakdown
+	if err := json.Unmarshal(output, &breakdown); err != nil {
+		return nil, fmt.Errorf("failed to parse infracost output: %w", err)
+	}
+
+	estimate := &CostEstimate{
+		Currency:    breakdown.Currency,
+		Monthly:     parseCost(breakdown.TotalMonthlyCost),
+		PastMonthly: parseCost(breakdown.PastTotalMonthlyCost),
+	}
+	estimate.Delta = estimate.Monthly - estimate.PastMonthly
+	if breakdown.DiffTotalMonthlyCost != "" {
+		estimate.Delta = parseCost(breakdown.DiffTotalMonthlyCost)
+	}
+
+	for _, project := range breakdown.Projects {
+		for _, resource := range project.Breakdown.Resources {
+			if cost := parseCost(resource.MonthlyCost); cost > 0 {
+				estimate.Resources = append(estimate.Resources, ResourceCost{Name: resource.Name, Monthly: cost})
+			}
+		}
+	}
+	sort.Slice(estimate.Resources, func(i, j int) bool {
+		return estimate.Resources[i].Monthly > estimate.Resources[j].Monthly
+	})
+	return estimate, nil
+}
+
+// parseCost reads an Infracost amount; free and usage-based resources have none
+func parseCost(value string) float64 {
+	cost, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return cost
+}
+
+// OverBudget reports whether the monthly cost increase exceeds the configured budget
+func (m *Manager) OverBudget(estimate *CostEstimate) bool {
+	return m.Budget() > 0 && estimate.Delta > m.Budget()
+}
+
+// Budget returns the monthly cost increase allowed without approval, 0 when unlimited
+func (m *Manager) Budget() float64 {
+	return m.config.Terraform.CostEstimation.MonthlyBudget
+}
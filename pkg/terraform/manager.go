@@ -1,22 +1,50 @@
 package terraform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/healthcheck"
+	"github.com/judebantony/e2e-k8s-installer/pkg/labels"
 	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/params"
 )
 
+// VariablesFile holds the configured variables; Terraform loads *.auto.tfvars.json files automatically
+const VariablesFile = "installer.auto.tfvars.json"
+
+// interruptGrace is how long an interrupted terraform has to finish the resource operations
+// in flight, write its state and release the state lock before it is killed
+const interruptGrace = 2 * time.Minute
+
 // Manager handles Terraform operations
 type Manager struct {
 	config      *config.InfrastructureConfig
 	workingDir  string
+	timeout     time.Duration
+	secretEnv   []string
+	targets     []string
+	backupDir   string
+	savePlan    bool
 	initialized bool
+
+	moduleStates  map[string]config.ModuleState
+	healthResults []healthcheck.Result
+
+	planned         int // resources changed by the last plan
+	plannedDestroy  bool
+	planSaved       bool // the last plan is in PlanFile, for Apply to apply as it was checked
+	onApplyProgress ApplyProgressFunc
 }
 
 // NewManager creates a new Terraform manager
@@ -30,6 +58,15 @@ func NewManager(infraConfig *config.InfrastructureConfig) (*Manager, error) {
 		workingDir = "./terraform"
 	}
 
+	var timeout time.Duration
+	if infraConfig.Terraform.Timeout != "" {
+		parsed, err := time.ParseDuration(infraConfig.Terraform.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid terraform timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
 	// Ensure working directory exists
 	if err := os.MkdirAll(workingDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create terraform working directory: %w", err)
@@ -38,6 +75,7 @@ func NewManager(infraConfig *config.InfrastructureConfig) (*Manager, error) {
 	return &Manager{
 		config:     infraConfig,
 		workingDir: workingDir,
+		timeout:    timeout,
 	}, nil
 }
 
@@ -46,29 +84,36 @@ func (m *Manager) Init() error {
 	logger.Info("Initializing Terraform").Str("workingDir", m.workingDir).Send()
 
 	// Check if terraform binary exists
-	if _, err := exec.LookPath("terraform"); err != nil {
-		return fmt.Errorf("terraform not found in PATH: %w", err)
+	if _, err := execx.LookPath(m.binary()); err != nil {
+		return fmt.Errorf("%s not found in PATH: %w", m.binary(), err)
 	}
 
-	// Create main.tf if it doesn't exist
-	if err := m.ensureMainTerraformFile(); err != nil {
-		return fmt.Errorf("failed to create main terraform file: %w", err)
-	}
+	// A terragrunt tree brings its own modules
+	if !m.terragrunt() {
+		// Create main.tf if it doesn't exist
+		if err := m.ensureMainTerraformFile(); err != nil {
+			return fmt.Errorf("failed to create main terraform file: %w", err)
+		}
 
-	// Initialize terraform
-	cmd := exec.Command("terraform", "init")
-	cmd.Dir = m.workingDir
+		if err := m.writeVariablesFile(); err != nil {
+			return err
+		}
+	}
 
-	// Set environment variables
-	cmd.Env = append(os.Environ(), m.getTerraformEnvVars()...)
+	secretEnv, err := m.resolveSecretVariables()
+	if err != nil {
+		return err
+	}
+	m.secretEnv = secretEnv
 
-	output, err := cmd.CombinedOutput()
+	// Initialize terraform
+	output, err := m.run("init")
 	if err != nil {
 		logger.Error("Terraform init failed").
 			Str("output", string(output)).
 			Err(err).
 			Send()
-		return fmt.Errorf("terraform init failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("terraform init failed: %w", err)
 	}
 
 	logger.Info("Terraform initialized successfully").Send()
@@ -88,36 +133,57 @@ func (m *Manager) Plan(destroy bool) (string, error) {
 	if destroy {
 		args = append(args, "-destroy")
 	}
+	args = append(args, m.parallelismArgs()...)
+	args = append(args, m.targetArgs()...)
+	saved := (m.CostEstimationEnabled() || m.savePlan) && !m.terragrunt()
+	if saved {
+		args = append(args, "-out="+PlanFile)
+	}
 
 	// Add variables file if specified
-	if len(m.config.Terraform.VarFiles) > 0 {
-		for _, varFile := range m.config.Terraform.VarFiles {
-			args = append(args, "-var-file="+varFile)
-		}
-	}
+	args = append(args, m.varFileArgs()...)
 
 	// Add provider-specific variables
 	varArgs := m.getProviderVariables()
 	args = append(args, varArgs...)
 
-	cmd := exec.Command("terraform", args...)
-	cmd.Dir = m.workingDir
-	cmd.Env = append(os.Environ(), m.getTerraformEnvVars()...)
-
-	output, err := cmd.CombinedOutput()
+	m.planSaved = false
+	output, err := m.run(args...)
 	if err != nil {
 		logger.Error("Terraform plan failed").
 			Str("output", string(output)).
 			Err(err).
 			Send()
-		return "", fmt.Errorf("terraform plan failed: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("terraform plan failed: %w", err)
 	}
 
-	logger.Info("Terraform plan completed").Send()
+	m.planned, m.plannedDestroy, m.planSaved = plannedChanges(output), destroy, saved
+
+	logger.Info("Terraform plan completed").Int("changes", m.planned).Send()
 	return string(output), nil
 }
 
-// Apply applies the Terraform configuration
+// SavePlan makes Plan keep the plan in PlanFile for the checks that read it with PlanJSON
+func (m *Manager) SavePlan() {
+	m.savePlan = true
+}
+
+// PlanJSON returns the saved plan as `terraform show -json` renders it. Terragrunt plans
+// are not saved, so there is none to return.
+func (m *Manager) PlanJSON() ([]byte, error) {
+	if m.terragrunt() {
+		return nil, fmt.Errorf("terragrunt plans are not saved")
+	}
+	output, err := m.runOutput("show", "-json", PlanFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export plan: %w", err)
+	}
+	return output, nil
+}
+
+// Apply applies the Terraform configuration. When the last Plan saved its plan for the same
+// operation, exactly that plan is applied, so the changes the cost, region and domain checks
+// read from it are the ones made; variables and targets are already part of it.
 func (m *Manager) Apply(destroy bool) error {
 	if !m.initialized {
 		return fmt.Errorf("terraform not initialized")
@@ -125,35 +191,65 @@ func (m *Manager) Apply(destroy bool) error {
 
 	logger.Info("Applying Terraform configuration").Bool("destroy", destroy).Send()
 
+	reason := "apply"
+	if destroy {
+		reason = "destroy"
+	}
+	snapshot, err := m.SnapshotState(reason)
+	if err != nil {
+		return err
+	}
+
 	var args []string
 	if destroy {
 		args = []string{"destroy", "-auto-approve", "-no-color"}
 	} else {
 		args = []string{"apply", "-auto-approve", "-no-color"}
 	}
+	args = append(args, m.parallelismArgs()...)
+	if m.planSaved && m.plannedDestroy == destroy {
+		// A saved destroy plan is applied with apply too
+		args[0] = "apply"
+		args = append(args, PlanFile)
+		logger.Info("Applying the saved plan").Str("plan", PlanFile).Send()
+	} else {
+		args = append(args, m.targetArgs()...)
 
-	// Add variables file if specified
-	if len(m.config.Terraform.VarFiles) > 0 {
-		for _, varFile := range m.config.Terraform.VarFiles {
-			args = append(args, "-var-file="+varFile)
-		}
-	}
-
-	// Add provider-specific variables
-	varArgs := m.getProviderVariables()
-	args = append(args, varArgs...)
+		// Add variables file if specified
+		args = append(args, m.varFileArgs()...)
 
-	cmd := exec.Command("terraform", args...)
-	cmd.Dir = m.workingDir
-	cmd.Env = append(os.Environ(), m.getTerraformEnvVars()...)
+		// Add provider-specific variables
+		varArgs := m.getProviderVariables()
+		args = append(args, varArgs...)
+	}
 
-	output, err := cmd.CombinedOutput()
+	cmd := m.command(args...)
+	if m.onApplyProgress != nil {
+		cmd.Stdout = &resourceCounter{total: m.PlannedChanges(destroy), report: m.onApplyProgress}
+	}
+	cmd.Audit, cmd.AuditTarget = "terraform.apply", m.workingDir
+	if destroy {
+		cmd.Audit = "terraform.destroy"
+	}
+	cmd.AuditDetails = map[string]interface{}{
+		"workspace": m.config.Terraform.Workspace,
+		"varFiles":  m.config.Terraform.VarFiles,
+		"variables": m.variableNames(),
+		"targets":   m.targets,
+	}
+	output, err := cmd.Run(context.Background())
+	// A saved plan is stale once applied, or once an apply of it failed half way
+	m.planSaved = false
+	if !destroy {
+		m.recordModuleStates(output, err)
+		m.journalApply(snapshot)
+	}
 	if err != nil {
 		logger.Error("Terraform apply failed").
 			Str("output", string(output)).
 			Err(err).
 			Send()
-		return fmt.Errorf("terraform apply failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("terraform apply failed: %w", err)
 	}
 
 	logger.Info("Terraform configuration applied successfully").Send()
@@ -168,11 +264,7 @@ func (m *Manager) GetOutputs() (map[string]interface{}, error) {
 
 	logger.Info("Retrieving Terraform outputs").Send()
 
-	cmd := exec.Command("terraform", "output", "-json")
-	cmd.Dir = m.workingDir
-	cmd.Env = append(os.Environ(), m.getTerraformEnvVars()...)
-
-	output, err := cmd.Output()
+	output, err := m.runOutput("output", "-json")
 	if err != nil {
 		// If no outputs exist, return empty map instead of error
 		if strings.Contains(err.Error(), "no outputs") {
@@ -183,8 +275,8 @@ func (m *Manager) GetOutputs() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("failed to get terraform outputs: %w", err)
 	}
 
-	var outputs map[string]interface{}
-	if err := json.Unmarshal(output, &outputs); err != nil {
+	outputs, err := mergeOutputs(output)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse terraform outputs: %w", err)
 	}
 
@@ -192,35 +284,122 @@ func (m *Manager) GetOutputs() (map[string]interface{}, error) {
 	return outputs, nil
 }
 
-// RunHealthChecks runs health checks on the infrastructure
+// RunHealthChecks probes the cluster API, the database and the configured URLs found in the
+// outputs, recording a result per check. Database failures are only logged.
 func (m *Manager) RunHealthChecks() error {
 	logger.Info("Running infrastructure health checks").Send()
 
-	// Get outputs to check infrastructure health
 	outputs, err := m.GetOutputs()
 	if err != nil {
 		return fmt.Errorf("failed to get outputs for health checks: %w", err)
 	}
+	prober, err := healthcheck.NewProber(m.config.HealthCheck)
+	if err != nil {
+		return err
+	}
 
-	// Check Kubernetes cluster endpoint if available
-	if kubeEndpoint, exists := outputs["kubernetes_endpoint"]; exists {
-		if err := m.checkKubernetesHealth(kubeEndpoint); err != nil {
-			return fmt.Errorf("kubernetes health check failed: %w", err)
+	m.healthResults = nil
+	var failed []string
+	record := func(result healthcheck.Result, required bool) {
+		m.healthResults = append(m.healthResults, result)
+		if result.Passed {
+			logger.Info("Health check passed").
+				Str("check", result.Name).
+				Str("target", result.Target).
+				Str("detail", result.Detail).
+				Send()
+			return
 		}
+		if !required {
+			logger.Warn("Health check failed").Str("check", result.Name).Str("target", result.Target).Str("error", result.Error).Send()
+			return
+		}
+		logger.Error("Health check failed").Str("check", result.Name).Str("target", result.Target).Str("error", result.Error).Send()
+		failed = append(failed, result.Name)
 	}
 
-	// Check database endpoint if available
-	if dbEndpoint, exists := outputs["database_endpoint"]; exists {
-		if err := m.checkDatabaseHealth(dbEndpoint); err != nil {
-			logger.Warn("Database health check failed").Err(err).Send()
-			// Don't fail the entire operation for database health check
+	if endpoint := outputString(outputs, "kubernetes_endpoint"); endpoint != "" {
+		ca := outputString(outputs, "cluster_ca_certificate")
+		if ca == "" {
+			ca = outputString(outputs, "kubernetes_ca_certificate")
 		}
+		record(prober.KubernetesAPI(endpoint, ca), true)
 	}
 
-	logger.Info("Infrastructure health checks completed").Send()
+	if db, ok := outputDatabase(outputs); ok {
+		record(prober.Database(db), false)
+	}
+
+	urls := m.config.HealthCheck.URLs
+	if m.config.HealthCheck.URL != "" {
+		urls = append([]string{m.config.HealthCheck.URL}, urls...)
+	}
+	for i, target := range urls {
+		record(prober.HTTP(fmt.Sprintf("url-%d", i+1), target, m.config.HealthCheck), true)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d health checks failed: %s", len(failed), len(m.healthResults), strings.Join(failed, ", "))
+	}
+	logger.Info("Infrastructure health checks completed").Int("checks", len(m.healthResults)).Send()
 	return nil
 }
 
+// HealthResults returns the results of the last RunHealthChecks
+func (m *Manager) HealthResults() []healthcheck.Result {
+	return m.healthResults
+}
+
+// outputString returns a string output, unwrapping the value of terraform output -json
+func outputString(outputs map[string]interface{}, name string) string {
+	value, ok := outputs[name]
+	if !ok {
+		return ""
+	}
+	if wrapped, ok := value.(map[string]interface{}); ok {
+		value = wrapped["value"]
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// outputDatabase builds the database probe from database_endpoint, which RDS reports as
+// host:port, or database_host and database_port, with the optional credential outputs
+func outputDatabase(outputs map[string]interface{}) (healthcheck.Database, bool) {
+	db := healthcheck.Database{
+		Engine:   outputString(outputs, "database_engine"),
+		Host:     outputString(outputs, "database_host"),
+		Name:     outputString(outputs, "database_name"),
+		Username: outputString(outputs, "database_username"),
+		Password: outputString(outputs, "database_password"),
+	}
+	db.Port, _ = strconv.Atoi(outputString(outputs, "database_port"))
+
+	if endpoint := outputString(outputs, "database_endpoint"); endpoint != "" {
+		if host, port, err := net.SplitHostPort(endpoint); err == nil {
+			db.Host = host
+			if db.Port == 0 {
+				db.Port, _ = strconv.Atoi(port)
+			}
+		} else if db.Host == "" {
+			db.Host = endpoint
+		}
+	}
+	if db.Host == "" {
+		return db, false
+	}
+	if db.Port == 0 {
+		db.Port = 5432
+	}
+	return db, true
+}
+
 // ensureMainTerraformFile creates a basic main.tf if it doesn't exist
 func (m *Manager) ensureMainTerraformFile() error {
 	mainTfPath := filepath.Join(m.workingDir, "main.tf")
@@ -264,16 +443,166 @@ func (m *Manager) getTerraformEnvVars() []string {
 	// TODO: Add backend configuration support when needed
 	// Backend configuration would be added here
 
+	// Label policy for cloud resources, see the default_tags variable in the generated configuration
+	envVars = append(envVars, labels.TerraformEnv()...)
+
+	envVars = append(envVars, m.variableEnv()...)
+
+	// Secret variables travel only through the environment, never on the command line or disk
+	envVars = append(envVars, m.secretEnv...)
+
 	return envVars
 }
 
+// run executes a terraform command in the working directory, returning its combined output
+func (m *Manager) run(args ...string) ([]byte, error) {
+	return m.command(args...).Run(context.Background())
+}
+
+// runOutput is run with stdout alone, for commands whose output is data rather than a log
+func (m *Manager) runOutput(args ...string) ([]byte, error) {
+	output, err := m.command(args...).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("terraform %s failed: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// command prepares a terraform command in the working directory, bounded by the configured
+// timeout and run through terragrunt run-all for the terragrunt flavor
+func (m *Manager) command(args ...string) *execx.Cmd {
+	cmd := execx.Command(m.binary(), m.commandArgs(args)...)
+	cmd.Dir = m.workingDir
+	cmd.Env = m.getTerraformEnvVars()
+	cmd.Timeout = m.timeout
+	cmd.Grace = interruptGrace
+	cmd.Tag = m.binary() + " " + args[0]
+	cmd.Console = true
+	cmd.Secrets = m.secretValues()
+	return cmd
+}
+
+// secretValues are the values of the secret variables, masked wherever a command is recorded
+func (m *Manager) secretValues() []string {
+	values := make([]string, 0, len(m.secretEnv))
+	for _, variable := range m.secretEnv {
+		if _, value, ok := strings.Cut(variable, "="); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// varFileArgs passes the configured variable files; terragrunt runs each module in its own
+// directory, so relative paths are made absolute
+func (m *Manager) varFileArgs() []string {
+	args := make([]string, 0, len(m.config.Terraform.VarFiles))
+	for _, varFile := range m.config.Terraform.VarFiles {
+		if m.terragrunt() {
+			if abs, err := filepath.Abs(varFile); err == nil {
+				varFile = abs
+			}
+		}
+		args = append(args, "-var-file="+varFile)
+	}
+	return args
+}
+
+// parallelismArgs limits concurrent resource operations when parallelism is configured
+func (m *Manager) parallelismArgs() []string {
+	if m.config.Terraform.Parallelism <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("-parallelism=%d", m.config.Terraform.Parallelism)}
+}
+
+// writeVariablesFile writes the configured variables to VariablesFile, or removes a stale
+// file when none are configured. Values holding JSON lists or objects are written as such
+// so they can feed list and map variables.
+func (m *Manager) writeVariablesFile() error {
+	path := filepath.Join(m.workingDir, VariablesFile)
+	if len(m.config.Terraform.Variables) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+
+	variables := make(map[string]interface{}, len(m.config.Terraform.Variables))
+	for name, value := range m.config.Terraform.Variables {
+		variables[name] = value
+		if trimmed := strings.TrimSpace(value); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(trimmed), &decoded); err == nil {
+				variables[name] = decoded
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(variables, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal terraform variables: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	logger.Info("Terraform variables written").Str("path", path).Int("count", len(variables)).Send()
+	return nil
+}
+
+// resolveSecretVariables looks up secret variables in the parameter store, where credentials
+// from the configured secret backend are published, and returns them as TF_VAR_ entries
+func (m *Manager) resolveSecretVariables() ([]string, error) {
+	var env, missing []string
+	for _, name := range sortedNames(m.config.Terraform.SecretVars) {
+		key := m.config.Terraform.SecretVars[name]
+		value, ok := params.GetStore().GetString(key)
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%s (%s)", name, key))
+			continue
+		}
+		env = append(env, fmt.Sprintf("TF_VAR_%s=%s", name, value))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("secret terraform variables have no value: %s", strings.Join(missing, ", "))
+	}
+	return env, nil
+}
+
+// variableNames lists the configured variables for the audit log, without their values
+func (m *Manager) variableNames() []string {
+	names := sortedNames(m.config.Terraform.Variables)
+	for _, name := range sortedNames(m.config.Terraform.SecretVars) {
+		names = append(names, name+" (secret)")
+	}
+	return names
+}
+
+func sortedNames(variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // getProviderVariables returns provider-specific variables for Terraform
 func (m *Manager) getProviderVariables() []string {
 	var vars []string
 
+	// -var fails in terragrunt modules that do not declare the variable
+	if m.terragrunt() {
+		return vars
+	}
+
 	// TODO: Add region variable from cloud configuration
-	region := "us-west-2" // Default region
-	vars = append(vars, "-var=region="+region)
+	// -var takes precedence over VariablesFile, so leave a configured region alone
+	if _, configured := m.config.Terraform.Variables["region"]; !configured {
+		region := "us-west-2" // Default region
+		vars = append(vars, "-var=region="+region)
+	}
 
 	// Add provider-specific variables
 	// TODO: Make provider configurable
@@ -290,33 +619,6 @@ func (m *Manager) getProviderVariables() []string {
 	return vars
 }
 
-// checkKubernetesHealth checks if Kubernetes cluster is healthy
-func (m *Manager) checkKubernetesHealth(endpoint interface{}) error {
-	logger.Info("Checking Kubernetes cluster health").Send()
-
-	// Basic health check - try to connect to the cluster
-	// This is a simple implementation - in production you'd want more sophisticated checks
-	if endpointStr, ok := endpoint.(string); ok && endpointStr != "" {
-		logger.Info("Kubernetes endpoint available").Str("endpoint", endpointStr).Send()
-		return nil
-	}
-
-	return fmt.Errorf("kubernetes endpoint not available")
-}
-
-// checkDatabaseHealth checks if database is healthy
-func (m *Manager) checkDatabaseHealth(endpoint interface{}) error {
-	logger.Info("Checking database health").Send()
-
-	// Basic health check for database
-	if endpointStr, ok := endpoint.(string); ok && endpointStr != "" {
-		logger.Info("Database endpoint available").Str("endpoint", endpointStr).Send()
-		return nil
-	}
-
-	return fmt.Errorf("database endpoint not available")
-}
-
 // generateAWSTerraform generates AWS-specific Terraform configuration
 func (m *Manager) generateAWSTerraform() string {
 	return `terraform {
@@ -331,6 +633,10 @@ func (m *Manager) generateAWSTerraform() string {
 
 provider "aws" {
   region = var.region
+
+  default_tags {
+    tags = var.default_tags
+  }
 }
 
 variable "region" {
@@ -339,6 +645,12 @@ variable "region" {
   default     = "us-west-2"
 }
 
+variable "default_tags" {
+  description = "Tags applied to every resource, set by the installer label policy"
+  type        = map(string)
+  default     = {}
+}
+
 # Basic EKS cluster configuration
 # This is a minimal example - customize based on your needs
 resource "aws_eks_cluster" "main" {
@@ -446,10 +758,17 @@ variable "region" {
   default     = "East US"
 }
 
+variable "default_tags" {
+  description = "Tags applied to every resource, set by the installer label policy"
+  type        = map(string)
+  default     = {}
+}
+
 # Resource group
 resource "azurerm_resource_group" "main" {
   name     = "k8s-installer-rg"
   location = var.region
+  tags     = var.default_tags
 }
 
 # AKS cluster
@@ -469,10 +788,10 @@ resource "azurerm_kubernetes_cluster" "main" {
     type = "SystemAssigned"
   }
 
-  tags = {
+  tags = merge({
     Environment = "Development"
     Purpose     = "K8s-Installer"
-  }
+  }, var.default_tags)
 }
 
 # Outputs
@@ -515,6 +834,12 @@ variable "project_id" {
   type        = string
 }
 
+variable "default_tags" {
+  description = "Labels applied to every resource, set by the installer label policy"
+  type        = map(string)
+  default     = {}
+}
+
 # GKE cluster
 resource "google_container_cluster" "main" {
   name     = "k8s-installer-cluster"
@@ -528,6 +853,8 @@ resource "google_container_cluster" "main" {
 
   network    = google_compute_network.vpc.name
   subnetwork = google_compute_subnetwork.subnet.name
+
+  resource_labels = var.default_tags
 }
 
 # Separately Managed Node Pool
@@ -0,0 +1,159 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/workspace"
+)
+
+// Module statuses recorded in the installation state
+const (
+	ModuleApplied = "applied"
+	ModuleFailed  = "failed"
+)
+
+// diagnosticModule finds the module of a resource named in an error diagnostic,
+// e.g. "with module.database.aws_db_instance.main,"
+var diagnosticModule = regexp.MustCompile(`with module\.([A-Za-z0-9_-]+)`)
+
+// SetTargets limits plan and apply to the given resource addresses, e.g. module.database.
+// With terragrunt only whole modules can be targeted.
+func (m *Manager) SetTargets(targets []string) error {
+	if err := m.validateTargets(targets); err != nil {
+		return err
+	}
+	m.targets = targets
+	return nil
+}
+
+// ModuleStates returns the outcome of the last apply for each module it touched
+func (m *Manager) ModuleStates() map[string]config.ModuleState {
+	return m.moduleStates
+}
+
+// ModuleTarget returns the -target address of a configured module
+func ModuleTarget(module string) string {
+	return "module." + module
+}
+
+func (m *Manager) targetArgs() []string {
+	if m.terragrunt() {
+		return nil
+	}
+	args := make([]string, 0, len(m.targets))
+	for _, target := range m.targets {
+		args = append(args, "-target="+target)
+	}
+	return args
+}
+
+// affectedModules returns the configured modules an apply with the current targets touches
+func (m *Manager) affectedModules() []string {
+	if len(m.targets) == 0 {
+		return m.config.Terraform.Modules
+	}
+
+	var affected []string
+	for _, module := range m.config.Terraform.Modules {
+		prefix := ModuleTarget(module)
+		for _, target := range m.targets {
+			if target == prefix || strings.HasPrefix(target, prefix+".") || strings.HasPrefix(target, prefix+"[") {
+				affected = append(affected, module)
+				break
+			}
+		}
+	}
+	return affected
+}
+
+// recordModuleStates derives per-module outcomes from an apply. Modules named in error
+// diagnostics failed and the rest succeeded; when the output names none, every affected
+// module is marked failed so a resume re-applies all of them.
+func (m *Manager) recordModuleStates(output []byte, applyErr error) {
+	now := time.Now().UTC()
+	affected := m.affectedModules()
+	m.moduleStates = make(map[string]config.ModuleState, len(affected))
+
+	failed := make(map[string]bool)
+	if applyErr != nil {
+		for _, match := range diagnosticModule.FindAllStringSubmatch(string(output), -1) {
+			failed[match[1]] = true
+		}
+		for module := range terragruntFailures(output) {
+			failed[module] = true
+		}
+	}
+
+	for _, module := range affected {
+		state := config.ModuleState{Status: ModuleApplied, UpdatedAt: now}
+		if applyErr != nil && (len(failed) == 0 || failed[module]) {
+			state.Status = ModuleFailed
+			state.Error = applyErr.Error()
+		}
+		m.moduleStates[module] = state
+	}
+}
+
+// LoadModuleStates reads the module states from the installation state file
+func LoadModuleStates(stateFile string) (map[string]config.ModuleState, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]config.ModuleState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state struct {
+		Modules map[string]config.ModuleState `json:"modules"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", stateFile, err)
+	}
+	if state.Modules == nil {
+		state.Modules = map[string]config.ModuleState{}
+	}
+	return state.Modules, nil
+}
+
+// SaveModuleStates merges module states into the installation state file, leaving other fields untouched
+func SaveModuleStates(stateFile string, modules map[string]config.ModuleState) error {
+	return workspace.UpdateState(stateFile, func(sections map[string]json.RawMessage) error {
+		existing := make(map[string]config.ModuleState)
+		if raw, ok := sections["modules"]; ok {
+			if err := json.Unmarshal(raw, &existing); err != nil {
+				return fmt.Errorf("failed to parse module states in %s: %w", stateFile, err)
+			}
+		}
+		for name, module := range modules {
+			existing[name] = module
+		}
+
+		encoded, err := json.Marshal(existing)
+		if err != nil {
+			return fmt.Errorf("failed to marshal module states: %w", err)
+		}
+		sections["modules"] = encoded
+		return nil
+	})
+}
+
+// PendingModules returns the configured modules a resume must re-apply: those that
+// failed or have never been applied
+func PendingModules(modules []string, states map[string]config.ModuleState) []string {
+	var pending []string
+	for _, module := range modules {
+		if states[module].Status != ModuleApplied {
+			pending = append(pending, module)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
@@ -0,0 +1,77 @@
+package terraform
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// ApplyProgressFunc receives the resources an apply or destroy has finished so far, out of
+// the total the plan announced, or zero when no plan preceded it
+type ApplyProgressFunc func(done, total int)
+
+var (
+	// planSummary is the last line of a plan; terragrunt prints one per module
+	planSummary = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+	// resourceComplete is printed once per resource an apply created, updated or destroyed
+	resourceComplete = regexp.MustCompile(`: (Creation|Modifications|Destruction) complete after`)
+)
+
+// OnApplyProgress reports the progress of the following applies and destroys as Terraform
+// prints it
+func (m *Manager) OnApplyProgress(report ApplyProgressFunc) {
+	m.onApplyProgress = report
+}
+
+// PlannedChanges returns the resources the last plan changes, or zero when there was no plan
+// of the kind, apply or destroy, that follows
+func (m *Manager) PlannedChanges(destroy bool) int {
+	if m.plannedDestroy != destroy {
+		return 0
+	}
+	return m.planned
+}
+
+// plannedChanges sums the resources the plan summaries in output add, change and destroy
+func plannedChanges(output []byte) int {
+	total := 0
+	for _, match := range planSummary.FindAllSubmatch(output, -1) {
+		for _, count := range match[1:] {
+			n, _ := strconv.Atoi(string(count))
+			total += n
+		}
+	}
+	return total
+}
+
+// resourceCounter counts the resources completed in the output of an apply
+type resourceCounter struct {
+	total   int
+	done    int
+	report  ApplyProgressFunc
+	partial []byte
+	mu      sync.Mutex
+}
+
+func (c *resourceCounter) Write(data []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.partial = append(c.partial, data...)
+	completed := 0
+	for {
+		i := bytes.IndexByte(c.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if resourceComplete.Match(c.partial[:i]) {
+			completed++
+		}
+		c.partial = c.partial[i+1:]
+	}
+	if completed > 0 {
+		c.done += completed
+		c.report(c.done, c.total)
+	}
+	return len(data), nil
+}
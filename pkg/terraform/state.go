@@ -0,0 +1,194 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+	"github.com/judebantony/e2e-k8s-installer/pkg/rollback"
+)
+
+// DefaultStateBackups is the number of state snapshots kept when none is configured
+const DefaultStateBackups = 10
+
+// stateBackupSuffix marks state snapshots in the backup directory
+const stateBackupSuffix = ".tfstate"
+
+// SetStateBackupDir enables a state snapshot in dir before every apply, destroy and import
+func (m *Manager) SetStateBackupDir(dir string) {
+	m.backupDir = dir
+}
+
+// SnapshotState saves the current state, pulled from whichever backend is configured, into
+// the backup directory and prunes the oldest snapshots beyond the retention limit. It
+// returns the snapshot path, or "" when snapshots are disabled or there is no state yet.
+func (m *Manager) SnapshotState(reason string) (string, error) {
+	if m.backupDir == "" {
+		return "", nil
+	}
+	if m.terragrunt() {
+		return m.snapshotModuleStates(reason)
+	}
+
+	output, err := m.runOutput("state", "pull")
+	if err != nil {
+		return "", fmt.Errorf("failed to pull terraform state: %w", err)
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		logger.Debug("No terraform state to snapshot").Send()
+		return "", nil
+	}
+
+	if err := os.MkdirAll(m.backupDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create state backup directory: %w", err)
+	}
+	path := filepath.Join(m.backupDir, fmt.Sprintf("%s-%s%s", time.Now().UTC().Format("20060102-150405.000"), reason, stateBackupSuffix))
+	// State holds every resource attribute, including secrets
+	if err := os.WriteFile(path, output, 0600); err != nil {
+		return "", fmt.Errorf("failed to write state snapshot: %w", err)
+	}
+	logger.Info("Terraform state snapshot saved").Str("path", path).Send()
+
+	if err := m.pruneStateBackups(); err != nil {
+		logger.Warn("Failed to prune old state snapshots").Err(err).Send()
+	}
+	return path, nil
+}
+
+// journalApply journals an apply for rollback. An apply to an empty state, one no snapshot
+// was taken before, is undone by destroying what it targeted; an apply changing existing
+// infrastructure cannot be, and the snapshot of the state before it is journalled instead.
+func (m *Manager) journalApply(snapshot string) {
+	details := make(map[string]string)
+	if len(m.targets) > 0 {
+		details["targets"] = strings.Join(m.targets, ",")
+	}
+	if snapshot != "" {
+		details["snapshot"] = snapshot
+	} else if m.backupDir != "" {
+		details["fresh"] = "true"
+	}
+	rollback.Record(config.RollbackAction{Kind: rollback.KindTerraformApply, Name: m.workingDir, Details: details})
+}
+
+// snapshotModuleStates saves the state of each terragrunt module into one snapshot directory
+func (m *Manager) snapshotModuleStates(reason string) (string, error) {
+	dir := filepath.Join(m.backupDir, fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102-150405.000"), reason))
+
+	saved := 0
+	for _, module := range m.moduleDirs() {
+		cmd := m.command("state", "pull")
+		cmd.Dir = filepath.Join(m.workingDir, module)
+		output, err := cmd.Output(context.Background())
+		if err != nil {
+			return "", fmt.Errorf("failed to pull terraform state of module %s: %w", module, err)
+		}
+		if len(bytes.TrimSpace(output)) == 0 {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", fmt.Errorf("failed to create state backup directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, module+stateBackupSuffix), output, 0600); err != nil {
+			return "", fmt.Errorf("failed to write state snapshot: %w", err)
+		}
+		saved++
+	}
+	if saved == 0 {
+		logger.Debug("No terraform state to snapshot").Send()
+		return "", nil
+	}
+	logger.Info("Terraform state snapshot saved").Str("path", dir).Int("modules", saved).Send()
+
+	if err := m.pruneStateBackups(); err != nil {
+		logger.Warn("Failed to prune old state snapshots").Err(err).Send()
+	}
+	return dir, nil
+}
+
+// StateBackups lists the state snapshots in the backup directory, newest first
+func (m *Manager) StateBackups() ([]string, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state backup directory: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		// Terragrunt snapshots are directories holding one state per module
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), stateBackupSuffix) {
+			backups = append(backups, filepath.Join(m.backupDir, entry.Name()))
+		}
+	}
+	// Names start with a UTC timestamp, so they sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+func (m *Manager) pruneStateBackups() error {
+	keep := m.config.Terraform.StateBackups
+	if keep <= 0 {
+		keep = DefaultStateBackups
+	}
+
+	backups, err := m.StateBackups()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(keep, len(backups)):] {
+		if err := os.RemoveAll(backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import adopts an existing resource into the managed state at address
+func (m *Manager) Import(address, id string) error {
+	if !m.initialized {
+		return fmt.Errorf("terraform not initialized")
+	}
+	if m.terragrunt() {
+		return fmt.Errorf("state import is not supported for terragrunt; run terragrunt import in the module directory")
+	}
+
+	if _, err := m.SnapshotState("import"); err != nil {
+		return err
+	}
+
+	logger.Info("Importing resource into Terraform state").Str("address", address).Str("id", id).Send()
+
+	args := []string{"import", "-no-color"}
+	args = append(args, m.varFileArgs()...)
+	args = append(args, m.getProviderVariables()...)
+	args = append(args, address, id)
+
+	cmd := m.command(args...)
+	cmd.Audit, cmd.AuditTarget = "terraform.import", address
+	cmd.AuditDetails = map[string]interface{}{
+		"id":        id,
+		"workspace": m.config.Terraform.Workspace,
+	}
+	output, err := cmd.Run(context.Background())
+	if err != nil {
+		logger.Error("Terraform import failed").
+			Str("output", string(output)).
+			Err(err).
+			Send()
+		return fmt.Errorf("terraform import failed: %w", err)
+	}
+
+	logger.Info("Resource imported").Str("address", address).Send()
+	return nil
+}
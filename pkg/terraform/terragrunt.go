@@ -0,0 +1,140 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Flavors of TerraformExecution
+const (
+	FlavorTerraform  = "terraform"
+	FlavorTerragrunt = "terragrunt"
+)
+
+// runAllCommands are run across every module with terragrunt run-all
+var runAllCommands = map[string]bool{
+	"init":     true,
+	"validate": true,
+	"plan":     true,
+	"apply":    true,
+	"destroy":  true,
+	"output":   true,
+}
+
+// terragruntFailedModule matches the summary terragrunt logs for each module that failed
+var terragruntFailedModule = regexp.MustCompile(`Module (\S+) has finished with an error`)
+
+// terragrunt reports whether the working directory is a Terragrunt tree of modules
+func (m *Manager) terragrunt() bool {
+	return m.config.Terraform.Flavor == FlavorTerragrunt
+}
+
+// binary is the CLI the configured flavor runs
+func (m *Manager) binary() string {
+	if m.terragrunt() {
+		return FlavorTerragrunt
+	}
+	return FlavorTerraform
+}
+
+// commandArgs turns a terraform command into its terragrunt run-all form. Module targets
+// become include directories, since terragrunt runs whole modules.
+func (m *Manager) commandArgs(args []string) []string {
+	if !m.terragrunt() || len(args) == 0 || !runAllCommands[args[0]] {
+		return args
+	}
+
+	runAll := append([]string{"run-all"}, args...)
+	runAll = append(runAll, "--terragrunt-non-interactive")
+	// Outputs of every module feed the report and parameters, whatever was applied
+	if args[0] == "output" {
+		return runAll
+	}
+	for _, target := range m.targets {
+		runAll = append(runAll, "--terragrunt-include-dir", strings.TrimPrefix(target, "module."))
+	}
+	if len(m.targets) > 0 {
+		runAll = append(runAll, "--terragrunt-strict-include")
+	}
+	return runAll
+}
+
+// validateTargets rejects targets terragrunt cannot honour
+func (m *Manager) validateTargets(targets []string) error {
+	if !m.terragrunt() {
+		return nil
+	}
+	for _, target := range targets {
+		name := strings.TrimPrefix(target, "module.")
+		if name == target || name == "" || strings.ContainsAny(name, ".[") {
+			return fmt.Errorf("terragrunt applies whole modules: target %q must be module.<directory>", target)
+		}
+	}
+	return nil
+}
+
+// variableEnv passes the configured variables through the environment, since terragrunt runs
+// each module from its own cache directory where a root tfvars file is not read
+func (m *Manager) variableEnv() []string {
+	if !m.terragrunt() {
+		return nil
+	}
+	env := make([]string, 0, len(m.config.Terraform.Variables))
+	for _, name := range sortedNames(m.config.Terraform.Variables) {
+		env = append(env, fmt.Sprintf("TF_VAR_%s=%s", name, m.config.Terraform.Variables[name]))
+	}
+	return env
+}
+
+// terragruntFailures returns the modules terragrunt reported as failed, by directory name
+func terragruntFailures(output []byte) map[string]bool {
+	failed := make(map[string]bool)
+	for _, match := range terragruntFailedModule.FindAllSubmatch(output, -1) {
+		failed[filepath.Base(strings.Trim(string(match[1]), `"'`))] = true
+	}
+	return failed
+}
+
+// mergeOutputs combines the output -json documents run-all prints, one per module.
+// Later modules win when two export the same name.
+func mergeOutputs(data []byte) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var outputs map[string]interface{}
+		if err := decoder.Decode(&outputs); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		for name, value := range outputs {
+			merged[name] = value
+		}
+	}
+	return merged, nil
+}
+
+// moduleDirs lists the configured modules that exist as directories of the working directory
+func (m *Manager) moduleDirs() []string {
+	var dirs []string
+	for _, module := range m.config.Terraform.Modules {
+		if stat, err := os.Stat(filepath.Join(m.workingDir, module)); err == nil && stat.IsDir() {
+			dirs = append(dirs, module)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Flavor returns the configured flavor, terraform unless terragrunt is selected
+func (m *Manager) Flavor() string {
+	return m.binary()
+}
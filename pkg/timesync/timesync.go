@@ -0,0 +1,223 @@
+package timesync
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+)
+
+// Defaults of installer.clock
+const (
+	DefaultNTPServer = "pool.ntp.org"
+	DefaultWarnSkew  = 2 * time.Second
+	DefaultMaxSkew   = 30 * time.Second
+)
+
+// Kinds of time references
+const (
+	KindNTP       = "ntp"
+	KindMetadata  = "metadata"
+	KindAPIServer = "apiserver"
+)
+
+// Measurement statuses
+const (
+	StatusOK          = "ok"
+	StatusWarning     = "warning"
+	StatusFailed      = "failed"
+	StatusUnavailable = "unavailable" // the reference could not be queried
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// Measurement is the offset of one time reference from the local clock. A positive offset
+// means the local clock is behind the reference.
+type Measurement struct {
+	Source       string        `json:"source"`
+	Kind         string        `json:"kind"`
+	Offset       time.Duration `json:"-"`
+	OffsetMs     int64         `json:"offsetMs"`
+	ResolutionMs int64         `json:"resolutionMs,omitempty"` // precision of the reference's time
+	Status       string        `json:"status"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Limits are the skews beyond which the check warns and fails
+type Limits struct {
+	Warn time.Duration
+	Max  time.Duration
+}
+
+// ParseLimits reads the skew limits of the configuration, defaulting unset ones
+func ParseLimits(cfg config.ClockConfig) (Limits, error) {
+	limits := Limits{Warn: DefaultWarnSkew, Max: DefaultMaxSkew}
+	if cfg.WarnSkew != "" {
+		warn, err := time.ParseDuration(cfg.WarnSkew)
+		if err != nil {
+			return Limits{}, fmt.Errorf("installer.clock.warnSkew: %w", err)
+		}
+		limits.Warn = warn
+	}
+	if cfg.MaxSkew != "" {
+		max, err := time.ParseDuration(cfg.MaxSkew)
+		if err != nil {
+			return Limits{}, fmt.Errorf("installer.clock.maxSkew: %w", err)
+		}
+		limits.Max = max
+	}
+	if limits.Warn > limits.Max {
+		return Limits{}, fmt.Errorf("installer.clock.warnSkew %s exceeds maxSkew %s", limits.Warn, limits.Max)
+	}
+	return limits, nil
+}
+
+// Evaluate sets the status of a measurement. The resolution of the reference is given to
+// the local clock, so a second-precision reference never fails a clock within a second.
+func (l Limits) Evaluate(m *Measurement) {
+	if m.Error != "" {
+		m.Status = StatusUnavailable
+		return
+	}
+	skew := max(m.Offset.Abs()-time.Duration(m.ResolutionMs)*time.Millisecond, 0)
+	switch {
+	case skew > l.Max:
+		m.Status = StatusFailed
+	case skew > l.Warn:
+		m.Status = StatusWarning
+	default:
+		m.Status = StatusOK
+	}
+}
+
+// Compare measures a reference time against the local time it was read at
+func Compare(source, kind string, reference, local time.Time, resolution time.Duration) Measurement {
+	offset := reference.Sub(local)
+	return Measurement{
+		Source:       source,
+		Kind:         kind,
+		Offset:       offset,
+		OffsetMs:     offset.Milliseconds(),
+		ResolutionMs: resolution.Milliseconds(),
+	}
+}
+
+// Unavailable records a reference that could not be queried
+func Unavailable(source, kind string, err error) Measurement {
+	return Measurement{Source: source, Kind: kind, Status: StatusUnavailable, Error: err.Error()}
+}
+
+// NTP queries an NTP server with a single SNTP request
+func NTP(ctx context.Context, server string) Measurement {
+	offset, err := ntpOffset(ctx, server)
+	if err != nil {
+		return Unavailable(server, KindNTP, err)
+	}
+	return Measurement{Source: server, Kind: KindNTP, Offset: offset, OffsetMs: offset.Milliseconds()}
+}
+
+func ntpOffset(ctx context.Context, server string) (time.Duration, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", net.JoinHostPort(server, "123"))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Leap indicator 0, version 4, client mode
+	request := make([]byte, 48)
+	request[0] = 0x23
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	if n < 48 || response[0]&0x07 != 4 {
+		return 0, fmt.Errorf("invalid NTP response from %s", server)
+	}
+	if response[1] == 0 {
+		return 0, fmt.Errorf("%s refused the request (kiss code %q)", server, response[12:16])
+	}
+	if response[0]>>6 == 3 {
+		return 0, fmt.Errorf("%s is not synchronized", server)
+	}
+
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp
+func ntpTime(data []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(data[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(data[4:8]))
+	return time.Unix(seconds, fraction*int64(time.Second)>>32)
+}
+
+// metadataEndpoints are the instance metadata services of the cloud providers. Any response
+// carries the service's Date header, so the requests need no credentials.
+var metadataEndpoints = map[string]struct {
+	URL    string
+	Header [2]string
+}{
+	"aws":   {URL: "http://169.254.169.254/latest/meta-data/"},
+	"azure": {URL: "http://169.254.169.254/metadata/instance?api-version=2021-02-01", Header: [2]string{"Metadata", "true"}},
+	"gcp":   {URL: "http://metadata.google.internal/computeMetadata/v1/", Header: [2]string{"Metadata-Flavor", "Google"}},
+}
+
+// Metadata reads the time of the provider's instance metadata service, from the Date header
+// of its response. It returns false for providers without one.
+func Metadata(ctx context.Context, provider string) (Measurement, bool) {
+	endpoint, ok := metadataEndpoints[provider]
+	if !ok {
+		return Measurement{}, false
+	}
+	source := provider + " instance metadata"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		return Unavailable(source, KindMetadata, err), true
+	}
+	if endpoint.Header[0] != "" {
+		request.Header.Set(endpoint.Header[0], endpoint.Header[1])
+	}
+
+	// The metadata service is link-local and never reached through a proxy
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	sent := time.Now()
+	response, err := client.Do(request)
+	received := time.Now()
+	if err != nil {
+		return Unavailable(source, KindMetadata, fmt.Errorf("not running on %s or the service is unreachable: %w", provider, err)), true
+	}
+	response.Body.Close()
+	date, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		return Unavailable(source, KindMetadata, fmt.Errorf("no Date header in the response")), true
+	}
+	return Compare(source, KindMetadata, date, sent.Add(received.Sub(sent)/2), time.Second), true
+}
+
+// Worst returns the measurements that failed and those that warned
+func Worst(measurements []Measurement) (failed, warned []Measurement) {
+	for _, m := range measurements {
+		switch m.Status {
+		case StatusFailed:
+			failed = append(failed, m)
+		case StatusWarning:
+			warned = append(warned, m)
+		}
+	}
+	return failed, warned
+}
@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/audit"
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// Versions installed when the configuration does not pin one
+const (
+	DefaultKubectlVersion   = "v1.31.1"
+	DefaultHelmVersion      = "v3.16.1"
+	DefaultTerraformVersion = "1.9.5"
+)
+
+// Tool is a pinned CLI binary and where to download it from
+type Tool struct {
+	Name        string
+	Version     string
+	URL         string
+	ChecksumURL string
+	// Member is the path of the binary inside the downloaded archive, empty for a plain binary
+	Member string
+}
+
+// Pinned returns the kubectl, helm and terraform releases for this platform
+func Pinned(cfg config.ToolsConfig) []Tool {
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	exe := ""
+	if goos == "windows" {
+		exe = ".exe"
+	}
+
+	kubectl := valueOr(cfg.Kubectl, DefaultKubectlVersion)
+	helm := valueOr(cfg.Helm, DefaultHelmVersion)
+	terraform := strings.TrimPrefix(valueOr(cfg.Terraform, DefaultTerraformVersion), "v")
+
+	kubectlURL := fmt.Sprintf("https://dl.k8s.io/release/%s/bin/%s/%s/kubectl%s", kubectl, goos, goarch, exe)
+	helmURL := fmt.Sprintf("https://get.helm.sh/helm-%s-%s-%s.tar.gz", helm, goos, goarch)
+	if goos == "windows" {
+		helmURL = fmt.Sprintf("https://get.helm.sh/helm-%s-%s-%s.zip", helm, goos, goarch)
+	}
+
+	return []Tool{
+		{
+			Name:        "kubectl",
+			Version:     kubectl,
+			URL:         kubectlURL,
+			ChecksumURL: kubectlURL + ".sha256",
+		},
+		{
+			Name:        "helm",
+			Version:     helm,
+			URL:         helmURL,
+			ChecksumURL: helmURL + ".sha256sum",
+			Member:      fmt.Sprintf("%s-%s/helm%s", goos, goarch, exe),
+		},
+		{
+			Name:    "terraform",
+			Version: terraform,
+			URL: fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip",
+				terraform, terraform, goos, goarch),
+			ChecksumURL: fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s_SHA256SUMS",
+				terraform, terraform),
+			Member: "terraform" + exe,
+		},
+	}
+}
+
+// Install downloads the tool into cacheDir, verifies its published checksum
+// and places the binary in binDir. A verified download already in the cache is reused.
+func Install(tool Tool, binDir, cacheDir string) (string, error) {
+	for _, dir := range []string{binDir, cacheDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	fileName := path.Base(tool.URL)
+	cached := filepath.Join(cacheDir, fmt.Sprintf("%s-%s-%s", tool.Name, tool.Version, fileName))
+
+	expected, err := fetchChecksum(client, tool.ChecksumURL, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	if actual, err := fileSHA256(cached); err != nil || actual != expected {
+		logger.Info("Downloading tool").Str("tool", tool.Name).Str("version", tool.Version).Str("url", tool.URL).Send()
+		if err := download(client, tool.URL, cached); err != nil {
+			return "", err
+		}
+		actual, err := fileSHA256(cached)
+		if err != nil {
+			return "", err
+		}
+		if actual != expected {
+			os.Remove(cached)
+			return "", fmt.Errorf("checksum mismatch for %s %s: expected %s, got %s", tool.Name, tool.Version, expected, actual)
+		}
+	}
+
+	var target string
+	switch {
+	case tool.Member == "":
+		target = filepath.Join(binDir, fileName)
+		err = copyFile(cached, target)
+	case strings.HasSuffix(fileName, ".zip"):
+		target = filepath.Join(binDir, path.Base(tool.Member))
+		err = extractZip(cached, tool.Member, target)
+	default:
+		target = filepath.Join(binDir, path.Base(tool.Member))
+		err = extractTarGz(cached, tool.Member, target)
+	}
+
+	audit.Record("tool.install", tool.Name, map[string]interface{}{
+		"version": tool.Version,
+		"sha256":  expected,
+		"path":    target,
+	}, err)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Tool installed").Str("tool", tool.Name).Str("version", tool.Version).Str("path", target).Send()
+	return target, nil
+}
+
+// PrependPath puts binDir first on PATH so installed tools take precedence for this process
+func PrependPath(binDir string) error {
+	abs, err := filepath.Abs(binDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", binDir, err)
+	}
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry == abs {
+			return nil
+		}
+	}
+	return os.Setenv("PATH", abs+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// fetchChecksum reads a sha256 file holding either a bare hash or "hash  filename" lines
+func fetchChecksum(client *http.Client, url, fileName string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download checksum %s: %s", url, resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 1:
+			return strings.ToLower(fields[0]), nil
+		case len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == fileName:
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum %s: %w", url, err)
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", fileName, url)
+}
+
+func download(client *http.Client, url, dest string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	tmp := dest + ".partial"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeExecutable(in, dest)
+}
+
+func extractTarGz(archive, member, dest string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archive, err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in %s", member, archive)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archive, err)
+		}
+		if header.Name == member {
+			return writeExecutable(reader, dest)
+		}
+	}
+}
+
+func extractZip(archive, member, dest string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archive, err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != member {
+			continue
+		}
+		in, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from %s: %w", member, archive, err)
+		}
+		defer in.Close()
+		return writeExecutable(in, dest)
+	}
+	return fmt.Errorf("%s not found in %s", member, archive)
+}
+
+// writeExecutable writes through a temporary file so a running binary is never left half written
+func writeExecutable(in io.Reader, dest string) error {
+	tmp := dest + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,244 @@
+package tuning
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/config"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// DefaultImageSyncWorkers is the number of images copied at once on hosts with resources to spare
+const DefaultImageSyncWorkers = 5
+
+// Memory budgeted per concurrent worker: an image copy buffers layers in flight, and every
+// helm operation is a process holding its rendered chart
+const (
+	imageWorkerMemory = 384 << 20
+	helmWorkerMemory  = 256 << 20
+)
+
+// memoryLimitShare is the share of the detected memory the Go runtime is asked to stay under,
+// leaving the rest to helm, terraform and kubectl child processes
+const memoryLimitShare = 0.5
+
+// Host is the CPU and memory available to the installer process, within its cgroup limits
+type Host struct {
+	CPUs          int    `json:"cpus"`
+	MemoryBytes   uint64 `json:"memoryBytes,omitempty"` // 0 when unknown
+	CgroupLimited bool   `json:"cgroupLimited,omitempty"`
+}
+
+// Settings are the effective runtime and concurrency settings of a run
+type Settings struct {
+	Host             Host     `json:"host"`
+	GOMAXPROCS       int      `json:"gomaxprocs"`
+	MemoryLimit      int64    `json:"memoryLimit,omitempty"` // soft limit of the Go runtime, 0 for none
+	ImageSyncWorkers int      `json:"imageSyncWorkers"`
+	HelmWorkers      int      `json:"helmWorkers"`          // cap on deployment.helm.maxParallel, not applied to --max-parallel
+	Overridden       []string `json:"overridden,omitempty"` // settings taken from installer.resources
+}
+
+var (
+	mutex   sync.Mutex
+	current *Settings
+)
+
+// Apply detects the host resources, sizes the worker pools to them and sets GOMAXPROCS and
+// the memory limit of the Go runtime. Settings given in cfg take precedence, and the
+// GOMAXPROCS and GOMEMLIMIT environment variables over the detected values.
+func Apply(cfg config.ResourcesConfig) (Settings, error) {
+	settings, err := Plan(DetectHost(), cfg)
+	if err != nil {
+		return Settings{}, err
+	}
+	if cfg.MaxProcs == 0 && os.Getenv("GOMAXPROCS") != "" {
+		settings.GOMAXPROCS = runtime.GOMAXPROCS(0)
+	} else {
+		runtime.GOMAXPROCS(settings.GOMAXPROCS)
+	}
+	if cfg.MemoryLimit == "" && os.Getenv("GOMEMLIMIT") != "" {
+		settings.MemoryLimit = debug.SetMemoryLimit(-1)
+	} else if settings.MemoryLimit > 0 {
+		debug.SetMemoryLimit(settings.MemoryLimit)
+	}
+
+	mutex.Lock()
+	current = &settings
+	mutex.Unlock()
+	logger.Debug("Resource limits applied").
+		Int("cpus", settings.Host.CPUs).
+		Int64("memory_bytes", int64(settings.Host.MemoryBytes)).
+		Int("gomaxprocs", settings.GOMAXPROCS).
+		Int("image_sync_workers", settings.ImageSyncWorkers).
+		Int("helm_workers", settings.HelmWorkers).
+		Send()
+	return settings, nil
+}
+
+// Current returns the settings in effect, planned from the detected host with no overrides
+// when Apply has not been called
+func Current() Settings {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if current == nil {
+		settings, _ := Plan(DetectHost(), config.ResourcesConfig{})
+		current = &settings
+	}
+	return *current
+}
+
+// Plan sizes the worker pools for a host: each pool is capped by twice the CPUs and by the
+// memory budget of its workers. Image sync never exceeds its default.
+func Plan(host Host, cfg config.ResourcesConfig) (Settings, error) {
+	settings := Settings{
+		Host:             host,
+		GOMAXPROCS:       host.CPUs,
+		ImageSyncWorkers: autoWorkers(min(DefaultImageSyncWorkers, 2*host.CPUs), host.MemoryBytes, imageWorkerMemory),
+		HelmWorkers:      autoWorkers(2*host.CPUs, host.MemoryBytes, helmWorkerMemory),
+	}
+	if host.MemoryBytes > 0 {
+		settings.MemoryLimit = int64(float64(host.MemoryBytes) * memoryLimitShare)
+	}
+
+	if cfg.MaxProcs > 0 {
+		settings.GOMAXPROCS = cfg.MaxProcs
+		settings.Overridden = append(settings.Overridden, "maxProcs")
+	}
+	if cfg.MemoryLimit != "" {
+		limit, err := ParseSize(cfg.MemoryLimit)
+		if err != nil {
+			return Settings{}, fmt.Errorf("installer.resources.memoryLimit: %w", err)
+		}
+		settings.MemoryLimit = limit
+		settings.Overridden = append(settings.Overridden, "memoryLimit")
+	}
+	if cfg.ImageSyncWorkers > 0 {
+		settings.ImageSyncWorkers = cfg.ImageSyncWorkers
+		settings.Overridden = append(settings.Overridden, "imageSyncWorkers")
+	}
+	if cfg.HelmWorkers > 0 {
+		settings.HelmWorkers = cfg.HelmWorkers
+		settings.Overridden = append(settings.Overridden, "helmWorkers")
+	}
+	return settings, nil
+}
+
+func autoWorkers(workers int, memory, perWorker uint64) int {
+	if memory > 0 {
+		workers = min(workers, int(memory/perWorker))
+	}
+	return max(workers, 1)
+}
+
+// DetectHost reads the CPUs and memory available to the process. On Linux the cgroup CPU
+// quota and memory limit count, and memory is what the kernel reports available; elsewhere
+// memory is unknown.
+func DetectHost() Host {
+	host := Host{CPUs: runtime.NumCPU()}
+	if quota := cgroupCPUs(); quota > 0 && quota < host.CPUs {
+		host.CPUs = quota
+		host.CgroupLimited = true
+	}
+	host.MemoryBytes = meminfoAvailable()
+	if limit := cgroupMemory(); limit > 0 && (host.MemoryBytes == 0 || limit < host.MemoryBytes) {
+		host.MemoryBytes = limit
+		host.CgroupLimited = true
+	}
+	return host
+}
+
+// cgroupCPUs returns the CPU quota of the cgroup rounded up, 0 when there is none
+func cgroupCPUs() int {
+	var quota, period float64
+	if data, err := os.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0
+		}
+		quota, _ = strconv.ParseFloat(fields[0], 64)
+		period, _ = strconv.ParseFloat(fields[1], 64)
+	} else {
+		quota = readNumber("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+		period = readNumber("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	}
+	if quota <= 0 || period <= 0 {
+		return 0
+	}
+	return int(math.Ceil(quota / period))
+}
+
+// cgroupMemory returns the memory limit of the cgroup, 0 when there is none
+func cgroupMemory() uint64 {
+	for _, path := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		// cgroup v1 reports no limit as a number close to the maximum int64
+		if limit := readNumber(path); limit > 0 && limit < 1<<60 {
+			return uint64(limit)
+		}
+	}
+	return 0
+}
+
+// meminfoAvailable returns MemAvailable of /proc/meminfo in bytes, 0 when it cannot be read
+func meminfoAvailable() uint64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kib, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kib << 10
+		}
+	}
+	return 0
+}
+
+func readNumber(path string) float64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// sizeSuffixes are the binary suffixes accepted by ParseSize
+var sizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+}
+
+// ParseSize parses a byte size such as 1536Mi, 2Gi or 1073741824
+func ParseSize(value string) (int64, error) {
+	number, multiplier := value, int64(1)
+	for _, s := range sizeSuffixes {
+		if trimmed, ok := strings.CutSuffix(value, s.suffix); ok {
+			number, multiplier = trimmed, s.multiplier
+			break
+		}
+	}
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 512Mi or 2Gi", value)
+	}
+	return int64(parsed * float64(multiplier)), nil
+}
@@ -0,0 +1,273 @@
+package values
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/judebantony/e2e-k8s-installer/pkg/k8s"
+	"gopkg.in/yaml.v3"
+)
+
+// Context is the data available to values templates
+type Context struct {
+	Outputs   map[string]interface{}
+	Params    map[string]interface{}
+	Installer map[string]interface{}
+	Chart     map[string]interface{}
+	Env       map[string]string
+
+	// Capabilities of the target cluster, e.g. {{ .Capabilities.HasIngressClass "nginx" }}
+	Capabilities k8s.Capabilities
+}
+
+// Renderer resolves templates in chart values
+type Renderer struct {
+	context Context
+	strict  bool
+}
+
+// NewRenderer creates a renderer; in strict mode undefined keys and unset env vars are errors
+func NewRenderer(context Context, strict bool) *Renderer {
+	if context.Env == nil {
+		context.Env = environMap()
+	}
+	return &Renderer{
+		context: context,
+		strict:  strict,
+	}
+}
+
+// WithChart returns a renderer whose .Chart data is set to the given chart fields
+func (r *Renderer) WithChart(chart map[string]interface{}) *Renderer {
+	context := r.context
+	context.Chart = chart
+	return &Renderer{context: context, strict: r.strict}
+}
+
+// RenderString resolves a single template string
+func (r *Renderer) RenderString(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl := template.New(name).Funcs(r.funcMap())
+	if r.strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.context); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	// Non-strict rendering of missing map keys yields "<no value>"; treat it as empty like Helm does
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+// RenderValues resolves templates in every string of a values tree
+func (r *Renderer) RenderValues(name string, values map[string]interface{}) (map[string]interface{}, error) {
+	rendered, err := r.renderNode(name, values)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := rendered.(map[string]interface{})
+	return result, nil
+}
+
+// RenderFile renders a values file as a whole and parses the resulting YAML
+func (r *Renderer) RenderFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	rendered, err := r.RenderString(path, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(rendered), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse rendered values file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Merge overlays src onto dst recursively, with src taking precedence
+func Merge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+	for key, srcVal := range src {
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[key] = Merge(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcVal
+	}
+	return dst
+}
+
+// Redact returns a copy of values with every occurrence of the given secrets masked
+func Redact(values map[string]interface{}, secrets []string) map[string]interface{} {
+	if len(secrets) == 0 || values == nil {
+		return values
+	}
+	redacted, _ := redactNode(values, secrets).(map[string]interface{})
+	return redacted
+}
+
+func redactNode(node interface{}, secrets []string) interface{} {
+	switch v := node.(type) {
+	case string:
+		for _, secret := range secrets {
+			v = strings.ReplaceAll(v, secret, "<redacted>")
+		}
+		return v
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			redacted[key] = redactNode(value, secrets)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactNode(item, secrets)
+		}
+		return redacted
+	default:
+		return node
+	}
+}
+
+// LoadOutputs reads infrastructure outputs from a provision-infra report.
+// Terraform's {"name": {"value": ...}} shape is flattened to {"name": ...}.
+func LoadOutputs(reportPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var report struct {
+		Outputs map[string]interface{} `json:"outputs"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse infrastructure report %s: %w", reportPath, err)
+	}
+
+	return FlattenOutputs(report.Outputs), nil
+}
+
+// FlattenOutputs unwraps terraform output objects to their values
+func FlattenOutputs(raw map[string]interface{}) map[string]interface{} {
+	outputs := make(map[string]interface{}, len(raw))
+	for name, output := range raw {
+		if wrapped, ok := output.(map[string]interface{}); ok {
+			if value, ok := wrapped["value"]; ok {
+				outputs[name] = value
+				continue
+			}
+		}
+		outputs[name] = output
+	}
+	return outputs
+}
+
+func (r *Renderer) renderNode(path string, node interface{}) (interface{}, error) {
+	switch v := node.(type) {
+	case string:
+		return r.RenderString(path, v)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := r.renderNode(path+"."+key, v[key])
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = value
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := r.renderNode(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = value
+		}
+		return rendered, nil
+	default:
+		return node, nil
+	}
+}
+
+func (r *Renderer) funcMap() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+
+	funcs["env"] = func(name string) (string, error) {
+		value, ok := r.context.Env[name]
+		if !ok && r.strict {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	funcs["output"] = func(name string) (interface{}, error) {
+		value, ok := r.context.Outputs[name]
+		if !ok && r.strict {
+			return nil, fmt.Errorf("infrastructure output %s is not defined", name)
+		}
+		return value, nil
+	}
+	funcs["param"] = func(name string) (interface{}, error) {
+		value, ok := r.context.Params[name]
+		if !ok && r.strict {
+			return nil, fmt.Errorf("parameter %s is not defined", name)
+		}
+		return value, nil
+	}
+	funcs["required"] = func(message string, value interface{}) (interface{}, error) {
+		if value == nil || value == "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return value, nil
+	}
+	funcs["toYaml"] = func(value interface{}) string {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(string(data), "\n")
+	}
+
+	return funcs
+}
+
+func environMap() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, found := strings.Cut(kv, "="); found {
+			env[key] = value
+		}
+	}
+	return env
+}
@@ -0,0 +1,5 @@
+package version
+
+// Version is the release of this installer binary, "dev" for local builds. Release builds set it with
+// -ldflags "-X github.com/judebantony/e2e-k8s-installer/pkg/version.Version=<version>"
+var Version = "dev"
@@ -0,0 +1,121 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateMutex serializes every change to installation state files of the process. Steps
+// of a parallel install journal actions, store parameters and save module states at the
+// same time; without it one read-modify-write would drop another's section.
+var stateMutex sync.Mutex
+
+// Install runs its steps as installer processes of their own, so a change also holds the
+// lock file next to the state file. A lock older than stateLockStale was left behind by a
+// process that died while writing, since changes take milliseconds.
+const (
+	stateLockTimeout = 30 * time.Second
+	stateLockStale   = 10 * time.Second
+)
+
+// UpdateState reads the top-level sections of an installation state file, lets change
+// modify them and writes the file back. A missing file starts with no sections. The file
+// is replaced in one rename, so readers and an installer killed while writing see either
+// the previous or the new state. State may hold sensitive parameters and is private to
+// the installer user.
+func UpdateState(path string, change func(sections map[string]json.RawMessage) error) error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sections := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &sections); err != nil {
+			return fmt.Errorf("failed to parse state file %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := change(sections); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// ReplaceState replaces all sections with the fields of state, for writers owning the
+// whole file such as the install state of a run
+func ReplaceState(sections map[string]json.RawMessage, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	clear(sections)
+	for key, value := range fields {
+		sections[key] = value
+	}
+	return nil
+}
+
+// lockState takes the lock file of the state file at path, waiting for other processes
+// changing it, and returns its release
+func lockState(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock state file: %w", err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > stateLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("state file %s is locked by another process (remove %s if none is running)", path, lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,217 @@
+package workspace
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/judebantony/e2e-k8s-installer/pkg/execx"
+	"github.com/judebantony/e2e-k8s-installer/pkg/logger"
+)
+
+// StoreEnv names the object store a workspace is kept in when --workspace-store is not given
+const StoreEnv = "E2E_INSTALLER_WORKSPACE_STORE"
+
+// StateKeyEnv holds the base64 AES-256 key the installation state is encrypted with in the
+// store. Without it the state, which holds the secret parameters, is never uploaded.
+const StateKeyEnv = "E2E_INSTALLER_STATE_KEY"
+
+// sealedStateName is the encrypted installation state kept in the store
+const sealedStateName = StateFileName + ".enc"
+
+// syncTimeout bounds one pull or push of a workspace
+const syncTimeout = 30 * time.Minute
+
+// Store is an object store prefix a workspace is kept in between runs, for installer pods
+// that have no persistent volume: s3://bucket/prefix, gs://bucket/prefix or
+// azblob://account/container/prefix. It is synced with the CLI of the cloud, which
+// authenticates the way it does anywhere else, e.g. through workload identity.
+type Store struct {
+	URL    string
+	scheme string
+	host   string
+	path   string
+}
+
+// ParseStore parses an object store URL
+func ParseStore(raw string) (*Store, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace store %q: %w", raw, err)
+	}
+	store := &Store{URL: raw, scheme: parsed.Scheme, host: parsed.Host, path: strings.Trim(parsed.Path, "/")}
+	switch {
+	case store.scheme != "s3" && store.scheme != "gs" && store.scheme != "azblob":
+		return nil, fmt.Errorf("unsupported workspace store %q, use s3://, gs:// or azblob://", raw)
+	case store.host == "":
+		return nil, fmt.Errorf("workspace store %q names no bucket", raw)
+	case store.scheme == "azblob" && store.path == "":
+		return nil, fmt.Errorf("workspace store %q names no container, use azblob://account/container/prefix", raw)
+	}
+	return store, nil
+}
+
+// Pull downloads the workspace into dir, adding to and replacing what dir holds
+func (s *Store) Pull(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	if _, err := s.sync(s.location(), dir, "workspace pull", false, lockFileName).Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to pull workspace from %s: %w", s.URL, err)
+	}
+	return openState(dir)
+}
+
+// PullReports downloads only the reports directories below the store prefix into dir: those of
+// the workspace kept there, or of every workspace kept under it
+func (s *Store) PullReports(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if _, err := s.sync(s.location(), dir, "reports pull", true, lockFileName).Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to pull reports from %s: %w", s.URL, err)
+	}
+	return nil
+}
+
+// Push uploads dir to the store. The run lock is left out: it is released by the time the
+// workspace is pushed, and the next pod must not find it. The installation state is only
+// uploaded encrypted with the key of StateKeyEnv; without a key the next pod starts over.
+func (s *Store) Push(dir string) error {
+	if err := sealState(dir); err != nil {
+		return err
+	}
+	cmd := s.sync(dir, s.location(), "workspace push", false, lockFileName, StateFileName)
+	cmd.Audit, cmd.AuditTarget = "workspace.push", s.URL
+	if _, err := cmd.Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to push workspace to %s: %w", s.URL, err)
+	}
+	return nil
+}
+
+// sync returns the command copying what changed from source to destination, leaving out the
+// files named excluded and keeping only the files in reports directories with reportsOnly
+func (s *Store) sync(source, destination, tag string, reportsOnly bool, excluded ...string) *execx.Cmd {
+	var cmd *execx.Cmd
+	switch s.scheme {
+	case "s3":
+		cmd = execx.Command("aws", "s3", "sync", source, destination, "--only-show-errors")
+		for _, name := range excluded {
+			cmd.Args = append(cmd.Args, "--exclude", "*"+name)
+		}
+		if reportsOnly {
+			cmd.Args = append(cmd.Args, "--exclude", "*", "--include", "reports/*", "--include", "*/reports/*")
+		}
+	case "gs":
+		quoted := make([]string, len(excluded))
+		for i, name := range excluded {
+			quoted[i] = regexp.QuoteMeta(name)
+		}
+		exclude := "(^|/)(" + strings.Join(quoted, "|") + ")$"
+		if reportsOnly {
+			exclude = "^(?!(.*/)?reports/).*"
+		}
+		cmd = execx.Command("gcloud", "storage", "rsync", source, destination, "--recursive", "--exclude", exclude)
+	default:
+		cmd = execx.Command("azcopy", "sync", source, destination, "--recursive", "--exclude-pattern", strings.Join(excluded, ";"))
+		if reportsOnly {
+			cmd.Args = append(cmd.Args, "--include-regex", "(^|/)reports/")
+		}
+	}
+	cmd.Tag = tag
+	cmd.Timeout = syncTimeout
+	return cmd
+}
+
+// location is the store in the form its CLI takes
+func (s *Store) location() string {
+	if s.scheme == "azblob" {
+		return fmt.Sprintf("https://%s.blob.core.windows.net/%s", s.host, s.path)
+	}
+	return fmt.Sprintf("%s://%s/%s", s.scheme, s.host, s.path)
+}
+
+// sealState writes the installation state of dir encrypted for the store, or warns that it
+// stays behind when no key is set
+func sealState(dir string) error {
+	plain, err := os.ReadFile(filepath.Join(dir, StateFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read installation state: %w", err)
+	}
+	aead, err := stateCipher()
+	if err != nil {
+		return err
+	}
+	if aead == nil {
+		logger.Warn("Installation state not pushed, it holds secret parameters; set "+StateKeyEnv+" to push it encrypted").
+			Str("dir", dir).
+			Send()
+		return nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to encrypt installation state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, sealedStateName), aead.Seal(nonce, nonce, plain, nil), 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted installation state: %w", err)
+	}
+	return nil
+}
+
+// openState decrypts the installation state a pull brought into dir
+func openState(dir string) error {
+	sealed, err := os.ReadFile(filepath.Join(dir, sealedStateName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read encrypted installation state: %w", err)
+	}
+	aead, err := stateCipher()
+	if err != nil {
+		return err
+	}
+	if aead == nil {
+		return fmt.Errorf("the workspace store holds an encrypted installation state, set %s to its key", StateKeyEnv)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return fmt.Errorf("encrypted installation state %s is truncated", sealedStateName)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt installation state, %s does not hold its key: %w", StateKeyEnv, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, StateFileName), plain, 0600); err != nil {
+		return fmt.Errorf("failed to write installation state: %w", err)
+	}
+	return nil
+}
+
+// stateCipher returns the AES-GCM cipher of the key in StateKeyEnv, nil when none is set
+func stateCipher() (cipher.AEAD, error) {
+	encoded := os.Getenv(StateKeyEnv)
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s must hold 32 bytes base64 encoded, e.g. from openssl rand -base64 32", StateKeyEnv)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,224 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HomeEnv overrides the directory holding named workspaces
+const HomeEnv = "E2E_INSTALLER_HOME"
+
+// ConfigFileName is the configuration file setup writes into a workspace
+const ConfigFileName = "installer-config.json"
+
+// StateFileName is the installation state file kept at the workspace root
+const StateFileName = "install-state.json"
+
+// lockFileName is the run lock kept in the workspace state directory
+const lockFileName = "installer.lock"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Info describes a named workspace
+type Info struct {
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	Status    string     `json:"status"`
+	HasConfig bool       `json:"hasConfig"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	LockedBy  *LockOwner `json:"lockedBy,omitempty"`
+}
+
+// LockOwner identifies the process holding a workspace run lock
+type LockOwner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// Lock is a held workspace run lock
+type Lock struct {
+	path   string
+	shared bool // held by the parent process, which releases it
+}
+
+// Home returns the directory holding named workspaces, ~/.e2e-k8s-installer by default
+func Home() string {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return home
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return ".e2e-k8s-installer"
+	}
+	return filepath.Join(userHome, ".e2e-k8s-installer")
+}
+
+// IsName reports whether value is a workspace name rather than a directory path
+func IsName(value string) bool {
+	return namePattern.MatchString(value) && !strings.ContainsAny(value, `/\`) && value != "." && value != ".."
+}
+
+// Resolve maps a workspace name to its directory under Home; paths are returned unchanged
+func Resolve(value string) string {
+	if IsName(value) {
+		return filepath.Join(Home(), "workspaces", value)
+	}
+	return value
+}
+
+// List returns the named workspaces under Home sorted by name
+func List() ([]Info, error) {
+	root := filepath.Join(Home(), "workspaces")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read workspaces directory: %w", err)
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if !entry.IsDir() || !IsName(entry.Name()) {
+			continue
+		}
+		infos = append(infos, Describe(entry.Name(), filepath.Join(root, entry.Name())))
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// Describe reads the state, config and lock of a workspace directory
+func Describe(name, path string) Info {
+	info := Info{Name: name, Path: path, Status: "new"}
+
+	if _, err := os.Stat(filepath.Join(path, ConfigFileName)); err == nil {
+		info.HasConfig = true
+	}
+
+	statePath := filepath.Join(path, StateFileName)
+	if stat, err := os.Stat(statePath); err == nil {
+		modified := stat.ModTime()
+		info.LastRun = &modified
+
+		var state struct {
+			Status string `json:"status"`
+		}
+		if data, err := os.ReadFile(statePath); err == nil && json.Unmarshal(data, &state) == nil && state.Status != "" {
+			info.Status = state.Status
+		}
+	}
+
+	if owner, err := readLock(filepath.Join(path, "state", lockFileName)); err == nil && owner.alive() {
+		info.LockedBy = owner
+	}
+	return info
+}
+
+// Acquire takes the run lock of a workspace so two installer runs cannot mutate it at once.
+// A lock left behind by a process that is no longer running on this host is taken over. A
+// lock held by the parent process, an install running its steps, is shared with it.
+func Acquire(path, command string) (*Lock, error) {
+	lockPath := filepath.Join(path, "state", lockFileName)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace state directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	owner := LockOwner{PID: os.Getpid(), Host: host, Command: command, Started: time.Now().UTC()}
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workspace lock: %w", err)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, writeErr := file.Write(data)
+			closeErr := file.Close()
+			if err := errors.Join(writeErr, closeErr); err != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("failed to write workspace lock: %w", err)
+			}
+			return &Lock{path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create workspace lock: %w", err)
+		}
+
+		existing, readErr := readLock(lockPath)
+		if readErr == nil && existing.parent() {
+			return &Lock{path: lockPath, shared: true}, nil
+		}
+		if readErr == nil && existing.alive() {
+			return nil, fmt.Errorf("workspace %s is in use by %s (pid %d on %s since %s)",
+				path, existing.Command, existing.PID, existing.Host, existing.Started.Format(time.RFC3339))
+		}
+		// Stale or unreadable lock from a crashed run
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale workspace lock: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire workspace lock %s", lockPath)
+}
+
+// Release removes the run lock
+func (l *Lock) Release() error {
+	if l.shared {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release workspace lock: %w", err)
+	}
+	return nil
+}
+
+func readLock(path string) (*LockOwner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var owner LockOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace lock: %w", err)
+	}
+	return &owner, nil
+}
+
+// alive reports whether the owning process still runs; locks from other hosts are assumed live
+// HostAlive reports whether a lock taken on another host may still be held. Nothing is known
+// about other hosts by default, so their locks are honored; in-cluster runs look up whether
+// the pod that took the lock still exists.
+var HostAlive = func(host string) bool {
+	return true
+}
+
+// parent reports whether the lock is held by the process that started this one
+func (o *LockOwner) parent() bool {
+	host, _ := os.Hostname()
+	return o.Host == host && o.PID == os.Getppid()
+}
+
+func (o *LockOwner) alive() bool {
+	if host, _ := os.Hostname(); o.Host != host {
+		return HostAlive(o.Host)
+	}
+	process, err := os.FindProcess(o.PID)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}